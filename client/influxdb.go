@@ -32,6 +32,15 @@ const (
 type Query struct {
 	Command  string
 	Database string
+
+	// PageSize limits the number of values returned by a SELECT query. The
+	// Cursor of the response is passed in the next query to get the next page.
+	PageSize int
+	Cursor   string
+
+	// Types requests the data type of each column of the rows, which is set
+	// in the Types of the rows.
+	Types bool
 }
 
 // ParseConnectionString will parse a string to create a valid connection URL
@@ -133,6 +142,15 @@ func (c *Client) Query(q Query) (*Response, error) {
 	values := u.Query()
 	values.Set("q", q.Command)
 	values.Set("db", q.Database)
+	if q.PageSize > 0 {
+		values.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	if q.Cursor != "" {
+		values.Set("cursor", q.Cursor)
+	}
+	if q.Types {
+		values.Set("types", "true")
+	}
 	u.RawQuery = values.Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
@@ -230,7 +248,7 @@ func (c *Client) Write(bp BatchPoints) (*Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		var err = fmt.Errorf(string(body))
+		var err = errors.New(string(body))
 		response.Err = err
 		return &response, err
 	}
@@ -238,6 +256,56 @@ func (c *Client) Write(bp BatchPoints) (*Response, error) {
 	return nil, nil
 }
 
+// WriteMulti writes batches for any number of databases and retention
+// policies in a single request. Points of the same destination are written
+// together: either all of them are accepted or none are. The response holds
+// the result of each destination; use its Error method to check whether all
+// destinations succeeded. Raw points are not supported.
+func (c *Client) WriteMulti(mbp MultiBatchPoints) (*MultiWriteResponse, error) {
+	u := c.url
+	u.Path = "write/multi"
+
+	b, err := json.Marshal(&mbp)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	if mbp.WriteConsistency != "" {
+		params := req.URL.Query()
+		params.Set("consistency", mbp.WriteConsistency)
+		req.URL.RawQuery = params.Encode()
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MultiWriteResponse
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
 // WriteLineProtocol takes a string with line returns to delimit each write
 // If successful, error is nil and Response is nil
 // If an error occurs, Response may contain additional information if populated.
@@ -276,7 +344,7 @@ func (c *Client) WriteLineProtocol(data, database, retentionPolicy, precision, w
 	}
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf(string(body))
+		err := errors.New(string(body))
 		response.Err = err
 		return &response, err
 	}
@@ -316,14 +384,18 @@ func (c *Client) Ping() (time.Duration, string, error) {
 type Result struct {
 	Series []influxql.Row
 	Err    error
+
+	// Partial is true if the server truncated the results at its row limit.
+	Partial bool
 }
 
 // MarshalJSON encodes the result into JSON.
 func (r *Result) MarshalJSON() ([]byte, error) {
 	// Define a struct that outputs "error" as a string.
 	var o struct {
-		Series []influxql.Row `json:"series,omitempty"`
-		Err    string         `json:"error,omitempty"`
+		Series  []influxql.Row `json:"series,omitempty"`
+		Err     string         `json:"error,omitempty"`
+		Partial bool           `json:"partial,omitempty"`
 	}
 
 	// Copy fields to output struct.
@@ -331,6 +403,7 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
+	o.Partial = r.Partial
 
 	return json.Marshal(&o)
 }
@@ -338,8 +411,9 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON decodes the data into the Result struct
 func (r *Result) UnmarshalJSON(b []byte) error {
 	var o struct {
-		Series []influxql.Row `json:"series,omitempty"`
-		Err    string         `json:"error,omitempty"`
+		Series  []influxql.Row `json:"series,omitempty"`
+		Err     string         `json:"error,omitempty"`
+		Partial bool           `json:"partial,omitempty"`
 	}
 
 	dec := json.NewDecoder(bytes.NewBuffer(b))
@@ -349,6 +423,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Series = o.Series
+	r.Partial = o.Partial
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -358,6 +433,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 // Response represents a list of statement results.
 type Response struct {
 	Results []Result
+	Cursor  string
 	Err     error
 }
 
@@ -366,11 +442,13 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 	// Define a struct that outputs "error" as a string.
 	var o struct {
 		Results []Result `json:"results,omitempty"`
+		Cursor  string   `json:"cursor,omitempty"`
 		Err     string   `json:"error,omitempty"`
 	}
 
 	// Copy fields to output struct.
 	o.Results = r.Results
+	o.Cursor = r.Cursor
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
@@ -382,6 +460,7 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 func (r *Response) UnmarshalJSON(b []byte) error {
 	var o struct {
 		Results []Result `json:"results,omitempty"`
+		Cursor  string   `json:"cursor,omitempty"`
 		Err     string   `json:"error,omitempty"`
 	}
 
@@ -392,6 +471,7 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Results = o.Results
+	r.Cursor = o.Cursor
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -449,7 +529,7 @@ func (p *Point) MarshalJSON() ([]byte, error) {
 }
 
 func (p *Point) MarshalString() string {
-	return tsdb.NewPoint(p.Measurement, p.Tags, p.Fields, p.Time).String()
+	return tsdb.NewPoint(p.Measurement, tsdb.NewTags(p.Tags), p.Fields, p.Time).String()
 }
 
 // UnmarshalJSON decodes the data into the Point struct
@@ -603,6 +683,42 @@ func (bp *BatchPoints) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MultiBatchPoints is used to send batches for several databases and
+// retention policies in a single write. Batches for the same database and
+// retention policy are merged.
+type MultiBatchPoints struct {
+	Batches          []BatchPoints `json:"batches"`
+	WriteConsistency string        `json:"-"`
+}
+
+// WriteResult is the result of writing the points of a single destination
+// of a MultiBatchPoints.
+type WriteResult struct {
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"retentionPolicy,omitempty"`
+	Points          int    `json:"points"`
+	Err             string `json:"error,omitempty"`
+}
+
+// MultiWriteResponse holds the results of a multi-destination write, one per
+// destination.
+type MultiWriteResponse struct {
+	Results []WriteResult `json:"results"`
+}
+
+// Error returns the first destination error encountered, if any.
+func (r MultiWriteResponse) Error() error {
+	for _, result := range r.Results {
+		if result.Err != "" {
+			if result.RetentionPolicy != "" {
+				return fmt.Errorf("%s.%s: %s", result.Database, result.RetentionPolicy, result.Err)
+			}
+			return fmt.Errorf("%s: %s", result.Database, result.Err)
+		}
+	}
+	return nil
+}
+
 // utility functions
 
 // Addr provides the current url as a string of the server the client is connected to.
@@ -191,6 +191,52 @@ func TestClient_Write(t *testing.T) {
 	}
 }
 
+func TestClient_WriteMulti(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/write/multi" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		} else if r.URL.Query().Get("consistency") != "all" {
+			t.Fatalf("unexpected consistency: %s", r.URL.Query().Get("consistency"))
+		}
+
+		var mbp client.MultiBatchPoints
+		if err := json.NewDecoder(r.Body).Decode(&mbp); err != nil {
+			t.Fatal(err)
+		} else if len(mbp.Batches) != 2 || mbp.Batches[1].Database != "db1" || mbp.Batches[1].Points[0].Measurement != "cpu" {
+			t.Fatalf("unexpected batches: %+v", mbp.Batches)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(client.MultiWriteResponse{Results: []client.WriteResult{
+			{Database: "db0", Points: 1},
+			{Database: "db1", Err: "database not found"},
+		}})
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c, err := client.NewClient(client.Config{URL: *u})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	pt := client.Point{Measurement: "cpu", Fields: map[string]interface{}{"value": 1.0}}
+	r, err := c.WriteMulti(client.MultiBatchPoints{
+		Batches: []client.BatchPoints{
+			{Database: "db0", Points: []client.Point{pt}},
+			{Database: "db1", Points: []client.Point{pt}},
+		},
+		WriteConsistency: "all",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	} else if len(r.Results) != 2 {
+		t.Fatalf("unexpected results: %+v", r.Results)
+	} else if err := r.Error(); err == nil || err.Error() != "db1: database not found" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestClient_UserAgent(t *testing.T) {
 	receivedUserAgent := ""
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
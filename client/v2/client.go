@@ -0,0 +1,281 @@
+// Package client implements a Go client for writing and querying InfluxDB
+// servers. Points are built with the tsdb package and encoded with its line
+// protocol encoder. Requests fail over across a list of server URLs.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+const (
+	// DefaultTimeout is the default request timeout. Zero means no timeout.
+	DefaultTimeout = 0
+
+	// DefaultUserAgent is the user agent sent when none is configured.
+	DefaultUserAgent = "InfluxDBClient"
+)
+
+var (
+	// ErrNoURLs is returned when a client is created without any server URLs.
+	ErrNoURLs = errors.New("at least one server URL required")
+)
+
+// Config is used to configure a Client.
+type Config struct {
+	// URLs of the servers in the cluster. Requests are sent to one server at a
+	// time and move on to the next one when a server can't be reached.
+	URLs []url.URL
+
+	// Username and Password are sent via basic auth, if provided.
+	Username string
+	Password string
+
+	// UserAgent defaults to DefaultUserAgent.
+	UserAgent string
+
+	// Timeout for each request to a single server.
+	Timeout time.Duration
+}
+
+// NewConfig returns a Config with default settings for the given server URLs.
+func NewConfig(urls ...url.URL) Config {
+	return Config{
+		URLs:    urls,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// Client writes and queries points against a list of servers.
+type Client struct {
+	mu      sync.Mutex
+	urls    []url.URL
+	current int // index of the server that last responded
+
+	username   string
+	password   string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// NewClient returns a new Client for the given configuration.
+func NewClient(c Config) (*Client, error) {
+	if len(c.URLs) == 0 {
+		return nil, ErrNoURLs
+	}
+
+	cl := &Client{
+		urls:       c.URLs,
+		username:   c.Username,
+		password:   c.Password,
+		userAgent:  c.UserAgent,
+		httpClient: &http.Client{Timeout: c.Timeout},
+	}
+	if cl.userAgent == "" {
+		cl.userAgent = DefaultUserAgent
+	}
+	return cl, nil
+}
+
+// BatchPoints is a set of points written to the same database and retention policy.
+type BatchPoints struct {
+	Points           []tsdb.Point
+	Database         string
+	RetentionPolicy  string
+	WriteConsistency string
+}
+
+// AddPoint appends a point to the batch.
+func (bp *BatchPoints) AddPoint(p tsdb.Point) { bp.Points = append(bp.Points, p) }
+
+// Write writes a batch of points. Points are encoded in line protocol with
+// nanosecond timestamps.
+func (c *Client) Write(bp BatchPoints) error {
+	var buf bytes.Buffer
+	for _, p := range bp.Points {
+		buf.WriteString(p.String())
+		buf.WriteByte('\n')
+	}
+
+	params := url.Values{}
+	params.Set("db", bp.Database)
+	params.Set("rp", bp.RetentionPolicy)
+	params.Set("precision", "n")
+	params.Set("consistency", bp.WriteConsistency)
+
+	resp, err := c.do("POST", "write", params, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("write failed: status=%d, body=%s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// Query is a command to execute on the server.
+type Query struct {
+	Command  string
+	Database string
+
+	// ChunkSize is the number of points per chunk in a chunked query.
+	// The server default is used when zero.
+	ChunkSize int
+}
+
+// Query executes a query and returns the full response.
+func (c *Client) Query(q Query) (*client.Response, error) {
+	resp, err := c.do("GET", "query", q.values(false), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response client.Response
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&response); err != nil && !(err == io.EOF && resp.StatusCode != http.StatusOK) {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && response.Error() == nil {
+		return &response, fmt.Errorf("received status code %d from server", resp.StatusCode)
+	}
+	return &response, nil
+}
+
+// QueryChunked executes a query and returns a ChunkedResponse that reads the
+// results as the server streams them. The caller must close the response.
+func (c *Client) QueryChunked(q Query) (*ChunkedResponse, error) {
+	resp, err := c.do("GET", "query", q.values(true), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("query failed: status=%d, body=%s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	return &ChunkedResponse{body: resp.Body, dec: dec}, nil
+}
+
+// values returns the URL parameters for the query.
+func (q *Query) values(chunked bool) url.Values {
+	params := url.Values{}
+	params.Set("q", q.Command)
+	params.Set("db", q.Database)
+	if chunked {
+		params.Set("chunked", "true")
+		if q.ChunkSize > 0 {
+			params.Set("chunk_size", strconv.Itoa(q.ChunkSize))
+		}
+	}
+	return params
+}
+
+// ChunkedResponse reads the responses of a chunked query.
+type ChunkedResponse struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// NextResponse returns the next chunk. Returns io.EOF when all chunks have been read.
+func (r *ChunkedResponse) NextResponse() (*client.Response, error) {
+	var response client.Response
+	if err := r.dec.Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Close closes the underlying connection.
+func (r *ChunkedResponse) Close() error { return r.body.Close() }
+
+// Ping checks that a server is up. It returns the round trip time and the
+// version of the server that responded.
+func (c *Client) Ping() (time.Duration, string, error) {
+	now := time.Now()
+	resp, err := c.do("GET", "ping", nil, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	return time.Since(now), resp.Header.Get("X-Influxdb-Version"), nil
+}
+
+// do sends a request to the current server. If the server can't be reached or
+// is unavailable, the request is retried against the next servers in the list.
+func (c *Client) do(method, path string, params url.Values, body []byte) (*http.Response, error) {
+	c.mu.Lock()
+	start := c.current
+	c.mu.Unlock()
+
+	var err error
+	for i := 0; i < len(c.urls); i++ {
+		n := (start + i) % len(c.urls)
+
+		var resp *http.Response
+		resp, err = c.doURL(c.urls[n], method, path, params, body)
+		if err != nil {
+			continue
+		}
+		if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusBadGateway {
+			resp.Body.Close()
+			err = fmt.Errorf("server %s unavailable: status=%d", c.urls[n].Host, resp.StatusCode)
+			continue
+		}
+
+		// Stick to this server until it fails.
+		c.mu.Lock()
+		c.current = n
+		c.mu.Unlock()
+
+		return resp, nil
+	}
+	return nil, err
+}
+
+// doURL sends a single request to the server at u.
+func (c *Client) doURL(u url.URL, method, path string, params url.Values, body []byte) (*http.Response, error) {
+	// Servers behind a proxy may be under a path, such as /influxdb.
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + path
+	u.RawQuery = params.Encode()
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	return c.httpClient.Do(req)
+}
@@ -0,0 +1,201 @@
+package client_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+func TestNewClient_NoURLs(t *testing.T) {
+	if _, err := client.NewClient(client.Config{}); err != client.ErrNoURLs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure points are written in line protocol.
+func TestClient_Write(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/write" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		} else if db := r.URL.Query().Get("db"); db != "db0" {
+			t.Fatalf("unexpected db: %s", db)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := MustNewClient(ts.URL)
+	bp := client.BatchPoints{Database: "db0"}
+	bp.AddPoint(tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server01"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 10)))
+	if err := c.Write(bp); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := "cpu,host=server01 value=1.0 10\n"; body != exp {
+		t.Fatalf("unexpected body:\n\nexp=%q\n\ngot=%q", exp, body)
+	}
+}
+
+// Ensure the paths of requests are joined to the path of the server URL.
+func TestClient_Write_URLPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/influxdb/write" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	for _, path := range []string{"/influxdb", "/influxdb/"} {
+		c := MustNewClient(ts.URL + path)
+		bp := client.BatchPoints{Database: "db0"}
+		bp.AddPoint(tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, time.Unix(0, 10)))
+		if err := c.Write(bp); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Ensure requests fail over to the next server when one is unavailable.
+func TestClient_Failover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	var n int
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("X-Influxdb-Version", "x.x")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer up.Close()
+
+	// A server that isn't listening at all.
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	c := MustNewClient(closed.URL, down.URL, up.URL)
+	if _, v, err := c.Ping(); err != nil {
+		t.Fatal(err)
+	} else if v != "x.x" {
+		t.Fatalf("unexpected version: %s", v)
+	}
+
+	// The client should stick to the server that responded.
+	if err := c.Write(client.BatchPoints{Database: "db0"}); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("unexpected request count: %d", n)
+	}
+}
+
+// Ensure a chunked query returns each chunk separately.
+func TestClient_QueryChunked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") != "true" {
+			t.Fatal("expected chunked query")
+		} else if r.URL.Query().Get("chunk_size") != "1" {
+			t.Fatalf("unexpected chunk size: %s", r.URL.Query().Get("chunk_size"))
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"results":[{"series":[{"name":"cpu","columns":["time","value"],"values":[[1,1]]}]}]}`)
+		fmt.Fprint(w, `{"results":[{"series":[{"name":"cpu","columns":["time","value"],"values":[[2,2]]}]}]}`)
+	}))
+	defer ts.Close()
+
+	c := MustNewClient(ts.URL)
+	resp, err := c.QueryChunked(client.Query{Command: "SELECT value FROM cpu", Database: "db0", ChunkSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+
+	for i := 0; i < 2; i++ {
+		r, err := resp.NextResponse()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(r.Results) != 1 || len(r.Results[0].Series) != 1 || len(r.Results[0].Series[0].Values) != 1 {
+			t.Fatalf("unexpected response: %#v", r)
+		}
+	}
+	if _, err := resp.NextResponse(); err != io.EOF {
+		t.Fatalf("expected EOF, got: %v", err)
+	}
+}
+
+// MustNewClient returns a client for the given URLs. Panic on error.
+func MustNewClient(rawurls ...string) *client.Client {
+	var urls []url.URL
+	for _, s := range rawurls {
+		u, err := url.Parse(s)
+		if err != nil {
+			panic(err)
+		}
+		urls = append(urls, *u)
+	}
+
+	c, err := client.NewClient(client.NewConfig(urls...))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Ensure UDP writes are split into payloads under the configured size.
+func TestUDPClient_Write(t *testing.T) {
+	addr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	c, err := client.NewUDPClient(client.UDPConfig{Addr: conn.LocalAddr().String(), PayloadSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Each line is 30 bytes so two fit in a payload.
+	var bp client.BatchPoints
+	for i := 0; i < 5; i++ {
+		bp.AddPoint(tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server01"}), tsdb.Fields{"value": 1.0}, time.Unix(0, int64(i))))
+	}
+	if err := c.Write(bp); err != nil {
+		t.Fatal(err)
+	}
+
+	var payloads []string
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for len(payloads) < 3 {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payloads = append(payloads, string(buf[:n]))
+	}
+
+	if exp := "cpu,host=server01 value=1.0 0\ncpu,host=server01 value=1.0 1\n"; payloads[0] != exp {
+		t.Fatalf("unexpected payload:\n\nexp=%q\n\ngot=%q", exp, payloads[0])
+	}
+	if exp := "cpu,host=server01 value=1.0 4\n"; payloads[2] != exp {
+		t.Fatalf("unexpected payload:\n\nexp=%q\n\ngot=%q", exp, payloads[2])
+	}
+}
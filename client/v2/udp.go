@@ -0,0 +1,83 @@
+package client
+
+import (
+	"bytes"
+	"net"
+)
+
+const (
+	// DefaultUDPPayloadSize is the default maximum size of a UDP payload. It
+	// is small enough to fit in a single packet on most networks.
+	DefaultUDPPayloadSize = 512
+)
+
+// UDPConfig is used to configure a UDPClient.
+type UDPConfig struct {
+	// Addr is the address of the server's UDP listener.
+	Addr string
+
+	// PayloadSize is the maximum size of a single UDP payload. Batches are
+	// split so each payload stays under it. Defaults to DefaultUDPPayloadSize.
+	PayloadSize int
+}
+
+// UDPClient writes points to a UDP listener. Writes are fire-and-forget:
+// the server doesn't acknowledge them and delivery isn't guaranteed.
+type UDPClient struct {
+	conn        net.Conn
+	payloadSize int
+}
+
+// NewUDPClient returns a client that writes to the UDP listener at c.Addr.
+func NewUDPClient(c UDPConfig) (*UDPClient, error) {
+	addr, err := net.ResolveUDPAddr("udp", c.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadSize := c.PayloadSize
+	if payloadSize <= 0 {
+		payloadSize = DefaultUDPPayloadSize
+	}
+
+	return &UDPClient{conn: conn, payloadSize: payloadSize}, nil
+}
+
+// Write writes a batch of points. The database, retention policy and write
+// consistency are set by the UDP listener's configuration and are ignored.
+//
+// Points are packed into as few payloads as possible. A point that is larger
+// than the payload size on its own is sent in a payload by itself.
+func (c *UDPClient) Write(bp BatchPoints) error {
+	var buf bytes.Buffer
+	for _, p := range bp.Points {
+		line := p.String() + "\n"
+
+		if buf.Len() > 0 && buf.Len()+len(line) > c.payloadSize {
+			if err := c.send(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		buf.WriteString(line)
+	}
+
+	if buf.Len() > 0 {
+		return c.send(buf.Bytes())
+	}
+	return nil
+}
+
+// send writes a single payload to the connection.
+func (c *UDPClient) send(b []byte) error {
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// Close closes the underlying connection.
+func (c *UDPClient) Close() error { return c.conn.Close() }
@@ -0,0 +1,108 @@
+// Package clock abstracts the current time.
+//
+// Code that reads the time or waits on it through a Clock can be run
+// against a Mock, whose time only moves when it's told to, so tests and
+// simulations don't depend on the system clock or on sleeping.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it to pass.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel receiving the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// New returns a Clock reading the system clock.
+func New() Clock { return clock{} }
+
+type clock struct{}
+
+func (clock) Now() time.Time                         { return time.Now() }
+func (clock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Mock is a Clock whose time only changes with Add and Set.
+type Mock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters waiters
+}
+
+// NewMock returns a new instance of Mock set to t.
+func NewMock(t time.Time) *Mock {
+	return &Mock{now: t}
+}
+
+// Now returns the time of the clock.
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// After returns a channel receiving the time of the clock once it's been
+// moved forward by d.
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	w := &waiter{t: m.now.Add(d), ch: ch}
+	if d <= 0 {
+		w.fire(m.now)
+		return ch
+	}
+	m.waiters = append(m.waiters, w)
+	return ch
+}
+
+// Add moves the clock forward by d.
+func (m *Mock) Add(d time.Duration) { m.Set(m.Now().Add(d)) }
+
+// Set sets the time of the clock. Channels returned by After whose time has
+// come receive it, in the order of their deadlines.
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	m.now = t
+	sort.Stable(m.waiters)
+	var fired waiters
+	for len(m.waiters) > 0 && !m.waiters[0].t.After(t) {
+		fired = append(fired, m.waiters[0])
+		m.waiters = m.waiters[1:]
+	}
+	m.mu.Unlock()
+
+	for _, w := range fired {
+		w.fire(t)
+	}
+}
+
+// Waiters returns the number of channels returned by After that haven't
+// received the time yet. Tests use it to wait until a goroutine is waiting
+// on the clock before moving it.
+func (m *Mock) Waiters() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.waiters)
+}
+
+// waiter is a channel returned by Mock.After and its deadline.
+type waiter struct {
+	t  time.Time
+	ch chan time.Time
+}
+
+func (w *waiter) fire(t time.Time) { w.ch <- t }
+
+type waiters []*waiter
+
+func (a waiters) Len() int           { return len(a) }
+func (a waiters) Less(i, j int) bool { return a[i].t.Before(a[j].t) }
+func (a waiters) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
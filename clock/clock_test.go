@@ -0,0 +1,54 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/clock"
+)
+
+// Ensure the mock clock only fires channels once their time has come.
+func TestMock_After(t *testing.T) {
+	start := time.Unix(100, 0)
+	m := clock.NewMock(start)
+
+	a, b := m.After(2*time.Second), m.After(time.Second)
+	if n := m.Waiters(); n != 2 {
+		t.Fatalf("unexpected waiters: %d", n)
+	}
+
+	m.Add(time.Second)
+	if got := m.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("unexpected time: %s", got)
+	}
+	select {
+	case got := <-b:
+		if !got.Equal(start.Add(time.Second)) {
+			t.Fatalf("unexpected time: %s", got)
+		}
+	default:
+		t.Fatal("expected channel to fire")
+	}
+	select {
+	case <-a:
+		t.Fatal("unexpected fire")
+	default:
+	}
+
+	m.Set(start.Add(time.Minute))
+	select {
+	case <-a:
+	default:
+		t.Fatal("expected channel to fire")
+	}
+	if n := m.Waiters(); n != 0 {
+		t.Fatalf("unexpected waiters: %d", n)
+	}
+
+	// Waiting for no time fires right away.
+	select {
+	case <-m.After(0):
+	default:
+		t.Fatal("expected channel to fire")
+	}
+}
@@ -1,26 +1,35 @@
 package cluster
 
 import (
+	"errors"
 	"net"
 	"sync"
 
+	"github.com/influxdb/influxdb/meta"
 	"gopkg.in/fatih/pool.v2"
 )
 
+// ErrTopologyChanged is returned for requests aborted because their node was
+// removed from the cluster or moved to another address.
+var ErrTopologyChanged = errors.New("node removed or moved by a topology change")
+
 type clientPool struct {
-	mu   sync.RWMutex
-	pool map[uint64]pool.Pool
+	mu    sync.RWMutex
+	pool  map[uint64]pool.Pool
+	conns map[uint64]*nodeConns
 }
 
 func newClientPool() *clientPool {
 	return &clientPool{
-		pool: make(map[uint64]pool.Pool),
+		pool:  make(map[uint64]pool.Pool),
+		conns: make(map[uint64]*nodeConns),
 	}
 }
 
-func (c *clientPool) setPool(nodeID uint64, p pool.Pool) {
+func (c *clientPool) setPool(nodeID uint64, p pool.Pool, nc *nodeConns) {
 	c.mu.Lock()
 	c.pool[nodeID] = p
+	c.conns[nodeID] = nc
 	c.mu.Unlock()
 }
 
@@ -43,9 +52,41 @@ func (c *clientPool) size() int {
 
 func (c *clientPool) conn(nodeID uint64) (net.Conn, error) {
 	c.mu.RLock()
-	conn, err := c.pool[nodeID].Get()
+	p, ok := c.pool[nodeID]
 	c.mu.RUnlock()
-	return conn, err
+	if !ok {
+		// The pool was evicted since it was looked up.
+		return nil, ErrTopologyChanged
+	}
+	return p.Get()
+}
+
+// evictStale closes the pools of nodes that aren't in nodes anymore or whose
+// address has changed. Requests in flight on their connections are aborted.
+func (c *clientPool) evictStale(nodes []meta.NodeInfo) {
+	hosts := make(map[uint64]string, len(nodes))
+	for _, n := range nodes {
+		hosts[n.ID] = n.Host
+	}
+
+	var pools []pool.Pool
+	var conns []*nodeConns
+	c.mu.Lock()
+	for nodeID, nc := range c.conns {
+		if host, ok := hosts[nodeID]; ok && !nc.dialedOther(host) {
+			continue
+		}
+		pools = append(pools, c.pool[nodeID])
+		conns = append(conns, nc)
+		delete(c.pool, nodeID)
+		delete(c.conns, nodeID)
+	}
+	c.mu.Unlock()
+
+	for i := range pools {
+		conns[i].evict()
+		pools[i].Close()
+	}
 }
 
 func (c *clientPool) close() {
@@ -55,3 +96,86 @@ func (c *clientPool) close() {
 	}
 	c.mu.Unlock()
 }
+
+// nodeConns tracks the connections dialed for the pool of a node so they can
+// be closed while they're in use.
+type nodeConns struct {
+	mu      sync.Mutex
+	host    string // address of the last connection dialed
+	conns   map[*trackedConn]struct{}
+	evicted bool
+}
+
+func newNodeConns() *nodeConns {
+	return &nodeConns{conns: make(map[*trackedConn]struct{})}
+}
+
+// add starts tracking a connection dialed to host.
+func (nc *nodeConns) add(conn net.Conn, host string) (net.Conn, error) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.evicted {
+		conn.Close()
+		return nil, ErrTopologyChanged
+	}
+	nc.host = host
+	tc := &trackedConn{Conn: conn, nc: nc}
+	nc.conns[tc] = struct{}{}
+	return tc, nil
+}
+
+func (nc *nodeConns) remove(tc *trackedConn) {
+	nc.mu.Lock()
+	delete(nc.conns, tc)
+	nc.mu.Unlock()
+}
+
+// dialedOther returns true if connections were dialed to another host.
+func (nc *nodeConns) dialedOther(host string) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.host != "" && nc.host != host
+}
+
+// evict closes every tracked connection and refuses new ones.
+func (nc *nodeConns) evict() {
+	nc.mu.Lock()
+	nc.evicted = true
+	conns := nc.conns
+	nc.conns = make(map[*trackedConn]struct{})
+	nc.mu.Unlock()
+
+	for tc := range conns {
+		tc.Conn.Close()
+	}
+}
+
+func (nc *nodeConns) isEvicted() bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.evicted
+}
+
+// trackedConn is a connection of a node pool that stops being tracked once
+// it's closed.
+type trackedConn struct {
+	net.Conn
+	nc *nodeConns
+}
+
+func (tc *trackedConn) Close() error {
+	tc.nc.remove(tc)
+	return tc.Conn.Close()
+}
+
+// connError returns ErrTopologyChanged if a request on conn failed because the
+// pool of its node was evicted. Otherwise err is returned.
+func connError(conn interface{}, err error) error {
+	if pc, ok := conn.(*pool.PoolConn); ok {
+		conn = pc.Conn
+	}
+	if tc, ok := conn.(*trackedConn); ok && tc.nc.isEvicted() {
+		return ErrTopologyChanged
+	}
+	return err
+}
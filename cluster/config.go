@@ -15,6 +15,10 @@ const (
 
 	// DefaultShardMapperTimeout is the default timeout set on shard mappers.
 	DefaultShardMapperTimeout = 5 * time.Second
+
+	// DefaultShardWriterRetryBudget is the default ratio of remote writes
+	// that may be retried.
+	DefaultShardWriterRetryBudget = 0.1
 )
 
 // Config represents the configuration for the clustering service.
@@ -23,13 +27,112 @@ type Config struct {
 	WriteTimeout            toml.Duration `toml:"write-timeout"`
 	ShardWriterTimeout      toml.Duration `toml:"shard-writer-timeout"`
 	ShardMapperTimeout      toml.Duration `toml:"shard-mapper-timeout"`
+
+	// ShardWriterRetries is how many times a write that failed to reach a
+	// remote node is sent again before it's queued in hinted handoff. At most
+	// ShardWriterRetryBudget of the writes are retried.
+	ShardWriterRetries     int     `toml:"shard-writer-retries"`
+	ShardWriterRetryBudget float64 `toml:"shard-writer-retry-budget"`
+
+	// DeadLetterDir is the directory where points rejected by shards are
+	// stored. Rejected points are dropped when it's empty.
+	DeadLetterDir string `toml:"dead-letter-dir"`
+
+	// TimestampPolicy is applied to points with timestamps that are likely
+	// in the wrong precision: "accept", "fix" or "reject". Timestamps aren't
+	// checked when it's empty and no database has its own policy.
+	TimestampPolicy string `toml:"timestamp-policy"`
+
+	// TimestampPolicies overrides the timestamp policy per database.
+	TimestampPolicies map[string]string `toml:"timestamp-policies"`
+
+	// MaxFutureSkew is how far ahead of the server's clock point timestamps
+	// may be. FutureSkewPolicy is "reject" to fail writes with later points
+	// or "clamp" to move them back to the limit. No limit when zero.
+	MaxFutureSkew    toml.Duration `toml:"max-future-skew"`
+	FutureSkewPolicy string        `toml:"future-skew-policy"`
+
+	// DenyMeasurements and DenySeries are regular expressions matched against
+	// the measurement name and series key of written points. Matching points
+	// are dropped without failing the write.
+	DenyMeasurements []string `toml:"deny-measurements"`
+	DenySeries       []string `toml:"deny-series"`
+
+	// FixedSchemaDatabases are databases whose measurements aren't created
+	// on write. Writes to measurements that don't exist are rejected.
+	FixedSchemaDatabases []string `toml:"fixed-schema-databases"`
+
+	// TagLimit is the number of tags a point may have. TagLimitPolicy is
+	// "reject" to fail writes with more tags or "fold" to move the least
+	// important tags into the FoldedTagsField of the point. Tags listed in
+	// TagPriority are kept first, in order, then the others by key. No limit
+	// when zero and no database has its own limit.
+	TagLimit       int      `toml:"tag-limit"`
+	TagLimitPolicy string   `toml:"tag-limit-policy"`
+	TagPriority    []string `toml:"tag-priority"`
+
+	// TagLimits overrides the tag limit per database.
+	TagLimits map[string]int `toml:"tag-limits"`
+
+	// SeriesCreationRate is the number of new series per second a database
+	// may create. Points of new series over the rate are rejected while the
+	// points of existing series are written. No limit when zero and no
+	// database has its own rate.
+	SeriesCreationRate int `toml:"series-creation-rate"`
+
+	// SeriesCreationRates overrides the series creation rate per database.
+	SeriesCreationRates map[string]int `toml:"series-creation-rates"`
+
+	// WriteAck is "sync" to wait for remote replicas to acknowledge writes or
+	// "async" to queue them in hinted handoff and respond immediately.
+	// Writes are synchronous when it's empty.
+	WriteAck string `toml:"write-ack"`
+
+	// WriteAcks overrides the write ack mode per database.
+	WriteAcks map[string]string `toml:"write-acks"`
+
+	// WireCompression is the name of the codec that compresses writes to
+	// remote nodes that can decode it. Writes aren't compressed when it's empty.
+	WireCompression string `toml:"wire-compression"`
+
+	// Transport is how requests are sent to other nodes: "tcp" over pooled
+	// TCP connections or "http2" over HTTP/2 streams. Nodes accept both.
+	// Requests are sent over TCP when it's empty.
+	Transport string `toml:"transport"`
+
+	// ShardHasher is the name of the hasher of series keys that routes points
+	// to the shards of a shard group, "fnv64a" by default or "xxhash" if
+	// built with the xxhash tag. ConsistentShardHashing routes them with a
+	// consistent hash of the keys instead of their hash modulo the number of
+	// shards. Both must be the same on every node.
+	ShardHasher            string `toml:"shard-hasher"`
+	ConsistentShardHashing bool   `toml:"consistent-shard-hashing"`
+
+	// MaxMessageSize is the largest message, in bytes, accepted from other
+	// nodes. Larger messages are rejected and their connection is closed.
+	MaxMessageSize int64 `toml:"max-message-size"`
+
+	// MessageChecksums sends messages to other nodes with a header and
+	// checksum. Nodes older than this release can't read them, so it should
+	// only be enabled once every node has been upgraded.
+	MessageChecksums bool `toml:"message-checksums"`
+
+	// MaxRowLimit is the number of values a SELECT statement returns before
+	// its results are truncated and marked partial. No limit when zero.
+	MaxRowLimit int `toml:"max-row-limit"`
+
+	// NumericTagOrder sorts tag values that are numbers numerically in
+	// GROUP BY results and SHOW TAG VALUES, so "9" comes before "10".
+	NumericTagOrder bool `toml:"numeric-tag-order"`
 }
 
 // NewConfig returns an instance of Config with defaults.
 func NewConfig() Config {
 	return Config{
-		WriteTimeout:       toml.Duration(DefaultWriteTimeout),
-		ShardWriterTimeout: toml.Duration(DefaultShardWriterTimeout),
-		ShardMapperTimeout: toml.Duration(DefaultShardMapperTimeout),
+		WriteTimeout:           toml.Duration(DefaultWriteTimeout),
+		ShardWriterTimeout:     toml.Duration(DefaultShardWriterTimeout),
+		ShardMapperTimeout:     toml.Duration(DefaultShardMapperTimeout),
+		ShardWriterRetryBudget: DefaultShardWriterRetryBudget,
+		MaxMessageSize:         MaxMessageSize,
 	}
 }
@@ -14,6 +14,9 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 shard-writer-timeout = "10s"
 write-timeout = "20s"
+shard-writer-retries = 3
+shard-writer-retry-budget = 0.2
+fixed-schema-databases = ["prod"]
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +26,11 @@ write-timeout = "20s"
 		t.Fatalf("unexpected shard-writer timeout: %s", c.ShardWriterTimeout)
 	} else if time.Duration(c.WriteTimeout) != 20*time.Second {
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
+	} else if c.ShardWriterRetries != 3 {
+		t.Fatalf("unexpected shard-writer retries: %d", c.ShardWriterRetries)
+	} else if c.ShardWriterRetryBudget != 0.2 {
+		t.Fatalf("unexpected shard-writer retry budget: %f", c.ShardWriterRetryBudget)
+	} else if len(c.FixedSchemaDatabases) != 1 || c.FixedSchemaDatabases[0] != "prod" {
+		t.Fatalf("unexpected fixed schema databases: %v", c.FixedSchemaDatabases)
 	}
 }
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// DeadLetterQueue stores points that were rejected by a shard so they can be
+// inspected and replayed later.
+//
+// Points are appended in line protocol to one file per database and retention
+// policy. Each batch is preceded by a comment line holding the time and the
+// reason it was rejected. Comments are ignored by the line protocol parser so
+// the files can be written back to the database as-is once the cause is fixed.
+type DeadLetterQueue struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewDeadLetterQueue returns a new DeadLetterQueue that writes to the directory at path.
+func NewDeadLetterQueue(path string) *DeadLetterQueue {
+	return &DeadLetterQueue{path: path}
+}
+
+// Path returns the directory the queue writes to.
+func (q *DeadLetterQueue) Path() string { return q.path }
+
+// FilePath returns the path of the file that holds rejected points for a
+// database and retention policy.
+func (q *DeadLetterQueue) FilePath(database, retentionPolicy string) string {
+	return filepath.Join(q.path, database, retentionPolicy+".txt")
+}
+
+// Add appends points rejected for reason to the queue.
+func (q *DeadLetterQueue) Add(database, retentionPolicy string, points []tsdb.Point, reason error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	path := q.FilePath(database, retentionPolicy)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Keep the reason on a single comment line.
+	msg := strings.Replace(reason.Error(), "\n", " ", -1)
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# %s rejected %d points: %s\n", time.Now().UTC().Format(time.RFC3339Nano), len(points), msg)
+	for _, p := range points {
+		w.WriteString(p.String())
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
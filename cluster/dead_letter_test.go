@@ -0,0 +1,48 @@
+package cluster_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensures rejected points are written in line protocol after their reason.
+func TestDeadLetterQueue_Add(t *testing.T) {
+	path, _ := ioutil.TempDir("", "dead_letter_test")
+	defer os.RemoveAll(path)
+
+	q := cluster.NewDeadLetterQueue(path)
+	points := []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 10)),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverB"}), tsdb.Fields{"value": 2.0}, time.Unix(0, 20)),
+	}
+	if err := q.Add("db0", "rp0", points, errors.New("field type conflict:\nvalue")); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(q.FilePath("db0", "rp0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected line count: %d", len(lines))
+	} else if !strings.HasPrefix(lines[0], "# ") || !strings.HasSuffix(lines[0], "rejected 2 points: field type conflict: value") {
+		t.Fatalf("unexpected header: %s", lines[0])
+	}
+
+	// The file should parse back into the original points.
+	parsed, err := tsdb.ParsePoints(b)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(parsed) != 2 || parsed[1].String() != points[1].String() {
+		t.Fatalf("unexpected points: %v", parsed)
+	}
+}
@@ -0,0 +1,246 @@
+// +build http2
+
+package cluster
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/meta"
+	"golang.org/x/net/http2"
+)
+
+// HTTP2Supported is true since the binary was built with the "http2" build
+// tag.
+const HTTP2Supported = true
+
+// httpTransportPath is the path of the requests of the HTTP/2 transport.
+const httpTransportPath = "/cluster"
+
+// serveHTTP accepts connections of the HTTP/2 transport and serves them.
+// Connections are unencrypted since they come through the TCP mux.
+func (s *Service) serveHTTP() {
+	defer s.wg.Done()
+
+	srv := &http2.Server{}
+	for {
+		conn, err := s.HTTPListener.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "connection closed") {
+				s.Logger.Printf("cluster service http accept error: %s", err)
+				return
+			}
+			s.Logger.Printf("http accept error: %s", err)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleHTTPConn(srv, conn)
+		}()
+	}
+}
+
+// handleHTTPConn serves the HTTP/2 streams of an individual connection.
+func (s *Service) handleHTTPConn(srv *http2.Server, conn net.Conn) {
+	// Ensure connection is closed when service is closed.
+	closing := make(chan struct{})
+	defer close(closing)
+	go func() {
+		select {
+		case <-closing:
+		case <-s.closing:
+		}
+		conn.Close()
+	}()
+
+	srv.ServeConn(conn, &http2.ServeConnOpts{Handler: s})
+}
+
+// ServeHTTP serves the requests of nodes using the HTTP/2 transport. The
+// bodies of the request and the response are streams of the same
+// type-length-value records sent over TCP connections.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.URL.Path != httpTransportPath {
+		http.NotFound(w, r)
+		return
+	} else if r.ProtoMajor != 2 {
+		http.Error(w, "HTTP/2 required", http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	fw := &flushWriter{w: w}
+	fw.flush()
+
+	s.serveMessages(struct {
+		io.Reader
+		io.Writer
+	}{r.Body, fw}, r.RemoteAddr)
+}
+
+// flushWriter flushes every write to the response so records are sent as
+// soon as they're written.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.flush()
+	return n, err
+}
+
+func (fw *flushWriter) flush() {
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// httpClient opens HTTP/2 streams to other nodes.
+type httpClient struct {
+	timeout   time.Duration
+	transport *http2.Transport
+}
+
+// newHTTPClient returns a client whose streams are closed after timeout.
+func newHTTPClient(timeout time.Duration) *httpClient {
+	return &httpClient{
+		timeout: timeout,
+		transport: &http2.Transport{
+			// Connections are unencrypted since they go through the TCP mux
+			// of the node, so the TLS dialer only dials a plain connection.
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				conn, err := net.DialTimeout(network, addr, timeout)
+				if err != nil {
+					return nil, err
+				}
+
+				// Write a marker byte for the HTTP/2 transport.
+				if _, err := conn.Write([]byte{HTTPMuxHeader}); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return conn, nil
+			},
+		},
+	}
+}
+
+// open starts a request on a new stream to a node.
+func (c *httpClient) open(metaStore interface {
+	Node(id uint64) (ni *meta.NodeInfo, err error)
+}, nodeID uint64) (*httpStream, error) {
+	ni, err := metaStore.Node(nodeID)
+	if err != nil {
+		return nil, err
+	} else if ni == nil {
+		return nil, fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest("POST", "http://"+ni.Host+httpTransportPath, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	s := &httpStream{
+		pr:    pr,
+		pw:    pw,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	s.timer = time.AfterFunc(c.timeout, func() { s.abort(ErrTimeout) })
+	go func() {
+		defer close(s.ready)
+		resp, err := c.transport.RoundTrip(req)
+		if err == nil && resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status from node %d: %s", nodeID, resp.Status)
+		}
+		if err != nil {
+			// Fail writes waiting for the request body to be read.
+			pr.CloseWithError(err)
+		}
+		s.resp, s.err = resp, err
+	}()
+	return s, nil
+}
+
+// close closes the idle connections to other nodes.
+func (c *httpClient) close() {
+	c.transport.CloseIdleConnections()
+}
+
+// httpStream is a request and its response on an HTTP/2 stream. Writes go to
+// the body of the request and reads come from the body of the response, once
+// the node starts sending it. The stream is closed once the client's timeout
+// has elapsed, so the deadlines set on it are ignored.
+type httpStream struct {
+	pr    *io.PipeReader
+	pw    *io.PipeWriter
+	timer *time.Timer
+
+	ready chan struct{} // closed once the response arrived or failed
+	resp  *http.Response
+	err   error
+
+	done chan struct{} // closed once the stream is closed or timed out
+	once sync.Once
+}
+
+func (s *httpStream) Write(p []byte) (int, error) { return s.pw.Write(p) }
+
+func (s *httpStream) Read(p []byte) (int, error) {
+	select {
+	case <-s.ready:
+	case <-s.done:
+		return 0, io.ErrClosedPipe
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.resp.Body.Read(p)
+}
+
+// Close ends the request and discards the rest of the response.
+func (s *httpStream) Close() error {
+	s.timer.Stop()
+	s.pw.Close()
+	s.abort(nil)
+	return nil
+}
+
+// abort fails pending and later reads of the stream and closes the response
+// once it arrives. If err is not nil, the request body fails with it too.
+func (s *httpStream) abort(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		if err != nil {
+			s.pr.CloseWithError(err)
+		}
+		go func() {
+			<-s.ready
+			if s.err == nil {
+				s.resp.Body.Close()
+			}
+		}()
+	})
+}
+
+// MarkUnusable does nothing since streams are never reused.
+func (s *httpStream) MarkUnusable() {}
+
+func (s *httpStream) SetDeadline(t time.Time) error      { return nil }
+func (s *httpStream) SetReadDeadline(t time.Time) error  { return nil }
+func (s *httpStream) SetWriteDeadline(t time.Time) error { return nil }
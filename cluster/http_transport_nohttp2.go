@@ -0,0 +1,57 @@
+// +build !http2
+
+package cluster
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/meta"
+)
+
+// HTTP2Supported is false since the binary was built without the "http2"
+// build tag.
+const HTTP2Supported = false
+
+// serveHTTP closes the connections of nodes using the HTTP/2 transport so
+// they fail right away instead of waiting for a timeout.
+func (s *Service) serveHTTP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.HTTPListener.Accept()
+		if err != nil {
+			if strings.Contains(err.Error(), "connection closed") {
+				s.Logger.Printf("cluster service http accept error: %s", err)
+				return
+			}
+			s.Logger.Printf("http accept error: %s", err)
+			continue
+		}
+		s.Logger.Printf("closing http connection from %s: %s", conn.RemoteAddr(), ErrHTTP2NotSupported)
+		conn.Close()
+	}
+}
+
+// ServeHTTP returns ErrHTTP2NotSupported to every request.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, ErrHTTP2NotSupported.Error(), http.StatusNotImplemented)
+}
+
+// httpClient is the HTTP/2 client of binaries built without HTTP/2 support.
+// It fails to open streams.
+type httpClient struct{}
+
+// newHTTPClient returns a new instance of httpClient.
+func newHTTPClient(timeout time.Duration) *httpClient { return &httpClient{} }
+
+// open returns ErrHTTP2NotSupported.
+func (c *httpClient) open(metaStore interface {
+	Node(id uint64) (ni *meta.NodeInfo, err error)
+}, nodeID uint64) (clusterConn, error) {
+	return nil, ErrHTTP2NotSupported
+}
+
+// close does nothing.
+func (c *httpClient) close() {}
@@ -0,0 +1,24 @@
+// +build !http2
+
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the shard writer fails to use the HTTP/2 transport when the binary
+// is built without it.
+func TestShardWriter_WriteShard_HTTP2NotSupported(t *testing.T) {
+	w := cluster.NewShardWriter(time.Minute)
+	w.Transport = cluster.TransportHTTP2
+	w.MetaStore = &metaStore{host: "127.0.0.1:0"}
+
+	points := []tsdb.Point{tsdb.NewPoint("cpu", nil, map[string]interface{}{"value": int64(100)}, time.Now())}
+	if err := w.WriteShard(1, 2, points); err != cluster.ErrHTTP2NotSupported {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+// +build http2
+
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the shard writer can write over HTTP/2 streams.
+func TestShardWriter_WriteShard_HTTP2(t *testing.T) {
+	ts := newTestWriteService(writeShardSuccess)
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.HTTPListener = ts.httpln
+	s.TSDBStore = ts
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.Transport = cluster.TransportHTTP2
+	w.MetaStore = &metaStore{host: ts.ln.Addr().String()}
+
+	now := time.Now()
+	points := []tsdb.Point{tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, now)}
+
+	// Write twice so the second request uses another stream of the connection.
+	for i := 0; i < 2; i++ {
+		if err := w.WriteShard(uint64(i+1), 2, points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses, err := ts.ResponseN(2)
+	if err != nil {
+		t.Fatal(err)
+	} else if responses[0].shardID != 1 || responses[1].shardID != 2 {
+		t.Fatalf("unexpected shard ids: %d, %d", responses[0].shardID, responses[1].shardID)
+	} else if p := responses[1].points[0]; p.Fields()["value"] != int64(100) || p.Time().UnixNano() != now.UnixNano() {
+		t.Fatalf("unexpected point: %s", p)
+	}
+}
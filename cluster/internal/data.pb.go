@@ -10,12 +10,18 @@ It is generated from these files:
 
 It has these top-level messages:
 	WriteShardRequest
+	ShardPoints
 	Field
 	Tag
 	Point
 	WriteShardResponse
+	ShardStatus
 	MapShardRequest
 	MapShardResponse
+	DropSeriesRequest
+	DropSeriesResponse
+	ShardHasDataRequest
+	ShardHasDataResponse
 */
 package internal
 
@@ -27,9 +33,11 @@ var _ = proto.Marshal
 var _ = math.Inf
 
 type WriteShardRequest struct {
-	ShardID          *uint64  `protobuf:"varint,1,req" json:"ShardID,omitempty"`
-	Points           []*Point `protobuf:"bytes,2,rep" json:"Points,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	ShardID          *uint64        `protobuf:"varint,1,req" json:"ShardID,omitempty"`
+	Points           []*Point       `protobuf:"bytes,2,rep" json:"Points,omitempty"`
+	TraceID          *string        `protobuf:"bytes,3,opt" json:"TraceID,omitempty"`
+	Shards           []*ShardPoints `protobuf:"bytes,4,rep" json:"Shards,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
 }
 
 func (m *WriteShardRequest) Reset()         { *m = WriteShardRequest{} }
@@ -50,6 +58,44 @@ func (m *WriteShardRequest) GetPoints() []*Point {
 	return nil
 }
 
+func (m *WriteShardRequest) GetTraceID() string {
+	if m != nil && m.TraceID != nil {
+		return *m.TraceID
+	}
+	return ""
+}
+
+func (m *WriteShardRequest) GetShards() []*ShardPoints {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+type ShardPoints struct {
+	ShardID          *uint64  `protobuf:"varint,1,req" json:"ShardID,omitempty"`
+	Points           []*Point `protobuf:"bytes,2,rep" json:"Points,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *ShardPoints) Reset()         { *m = ShardPoints{} }
+func (m *ShardPoints) String() string { return proto.CompactTextString(m) }
+func (*ShardPoints) ProtoMessage()    {}
+
+func (m *ShardPoints) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *ShardPoints) GetPoints() []*Point {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
 type Field struct {
 	Name             *string  `protobuf:"bytes,1,req" json:"Name,omitempty"`
 	Int32            *int32   `protobuf:"varint,2,opt" json:"Int32,omitempty"`
@@ -179,9 +225,12 @@ func (m *Point) GetTags() []*Tag {
 }
 
 type WriteShardResponse struct {
-	Code             *int32  `protobuf:"varint,1,req" json:"Code,omitempty"`
-	Message          *string `protobuf:"bytes,2,opt" json:"Message,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Code             *int32         `protobuf:"varint,1,req" json:"Code,omitempty"`
+	Message          *string        `protobuf:"bytes,2,opt" json:"Message,omitempty"`
+	Codecs           *uint64        `protobuf:"varint,3,opt" json:"Codecs,omitempty"`
+	Shards           []*ShardStatus `protobuf:"bytes,4,rep" json:"Shards,omitempty"`
+	MultiShard       *bool          `protobuf:"varint,5,opt" json:"MultiShard,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
 }
 
 func (m *WriteShardResponse) Reset()         { *m = WriteShardResponse{} }
@@ -202,10 +251,65 @@ func (m *WriteShardResponse) GetMessage() string {
 	return ""
 }
 
+func (m *WriteShardResponse) GetCodecs() uint64 {
+	if m != nil && m.Codecs != nil {
+		return *m.Codecs
+	}
+	return 0
+}
+
+func (m *WriteShardResponse) GetShards() []*ShardStatus {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+func (m *WriteShardResponse) GetMultiShard() bool {
+	if m != nil && m.MultiShard != nil {
+		return *m.MultiShard
+	}
+	return false
+}
+
+type ShardStatus struct {
+	ShardID          *uint64 `protobuf:"varint,1,req" json:"ShardID,omitempty"`
+	Code             *int32  `protobuf:"varint,2,req" json:"Code,omitempty"`
+	Message          *string `protobuf:"bytes,3,opt" json:"Message,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardStatus) Reset()         { *m = ShardStatus{} }
+func (m *ShardStatus) String() string { return proto.CompactTextString(m) }
+func (*ShardStatus) ProtoMessage()    {}
+
+func (m *ShardStatus) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetMessage() string {
+	if m != nil && m.Message != nil {
+		return *m.Message
+	}
+	return ""
+}
+
 type MapShardRequest struct {
 	ShardID          *uint64 `protobuf:"varint,1,req" json:"ShardID,omitempty"`
 	Query            *string `protobuf:"bytes,2,req" json:"Query,omitempty"`
 	ChunkSize        *int32  `protobuf:"varint,3,req" json:"ChunkSize,omitempty"`
+	ResumeAfter      *uint64 `protobuf:"varint,4,opt" json:"ResumeAfter,omitempty"`
+	TraceID          *string `protobuf:"bytes,5,opt" json:"TraceID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -234,12 +338,27 @@ func (m *MapShardRequest) GetChunkSize() int32 {
 	return 0
 }
 
+func (m *MapShardRequest) GetResumeAfter() uint64 {
+	if m != nil && m.ResumeAfter != nil {
+		return *m.ResumeAfter
+	}
+	return 0
+}
+
+func (m *MapShardRequest) GetTraceID() string {
+	if m != nil && m.TraceID != nil {
+		return *m.TraceID
+	}
+	return ""
+}
+
 type MapShardResponse struct {
 	Code             *int32   `protobuf:"varint,1,req" json:"Code,omitempty"`
 	Message          *string  `protobuf:"bytes,2,opt" json:"Message,omitempty"`
 	Data             []byte   `protobuf:"bytes,3,opt" json:"Data,omitempty"`
 	TagSets          []string `protobuf:"bytes,4,rep" json:"TagSets,omitempty"`
 	Fields           []string `protobuf:"bytes,5,rep" json:"Fields,omitempty"`
+	Seq              *uint64  `protobuf:"varint,6,opt" json:"Seq,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -282,5 +401,116 @@ func (m *MapShardResponse) GetFields() []string {
 	return nil
 }
 
+func (m *MapShardResponse) GetSeq() uint64 {
+	if m != nil && m.Seq != nil {
+		return *m.Seq
+	}
+	return 0
+}
+
+type DropSeriesRequest struct {
+	Database         *string  `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	SeriesKeys       []string `protobuf:"bytes,2,rep" json:"SeriesKeys,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *DropSeriesRequest) Reset()         { *m = DropSeriesRequest{} }
+func (m *DropSeriesRequest) String() string { return proto.CompactTextString(m) }
+func (*DropSeriesRequest) ProtoMessage()    {}
+
+func (m *DropSeriesRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropSeriesRequest) GetSeriesKeys() []string {
+	if m != nil {
+		return m.SeriesKeys
+	}
+	return nil
+}
+
+type DropSeriesResponse struct {
+	Code             *int32  `protobuf:"varint,1,req" json:"Code,omitempty"`
+	Message          *string `protobuf:"bytes,2,opt" json:"Message,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropSeriesResponse) Reset()         { *m = DropSeriesResponse{} }
+func (m *DropSeriesResponse) String() string { return proto.CompactTextString(m) }
+func (*DropSeriesResponse) ProtoMessage()    {}
+
+func (m *DropSeriesResponse) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
+func (m *DropSeriesResponse) GetMessage() string {
+	if m != nil && m.Message != nil {
+		return *m.Message
+	}
+	return ""
+}
+
+type ShardHasDataRequest struct {
+	ShardID          *uint64 `protobuf:"varint,1,req" json:"ShardID,omitempty"`
+	Query            *string `protobuf:"bytes,2,req" json:"Query,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardHasDataRequest) Reset()         { *m = ShardHasDataRequest{} }
+func (m *ShardHasDataRequest) String() string { return proto.CompactTextString(m) }
+func (*ShardHasDataRequest) ProtoMessage()    {}
+
+func (m *ShardHasDataRequest) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *ShardHasDataRequest) GetQuery() string {
+	if m != nil && m.Query != nil {
+		return *m.Query
+	}
+	return ""
+}
+
+type ShardHasDataResponse struct {
+	Code             *int32  `protobuf:"varint,1,req" json:"Code,omitempty"`
+	Message          *string `protobuf:"bytes,2,opt" json:"Message,omitempty"`
+	HasData          *bool   `protobuf:"varint,3,opt" json:"HasData,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardHasDataResponse) Reset()         { *m = ShardHasDataResponse{} }
+func (m *ShardHasDataResponse) String() string { return proto.CompactTextString(m) }
+func (*ShardHasDataResponse) ProtoMessage()    {}
+
+func (m *ShardHasDataResponse) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
+func (m *ShardHasDataResponse) GetMessage() string {
+	if m != nil && m.Message != nil {
+		return *m.Message
+	}
+	return ""
+}
+
+func (m *ShardHasDataResponse) GetHasData() bool {
+	if m != nil && m.HasData != nil {
+		return *m.HasData
+	}
+	return false
+}
+
 func init() {
 }
@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// MeasurementCreationError is returned when a write references measurements
+// that don't exist in a database that doesn't allow creating them.
+type MeasurementCreationError struct {
+	Database     string
+	Measurements []string // sorted
+}
+
+func (e *MeasurementCreationError) Error() string {
+	return fmt.Sprintf("%s: database %s has no measurement %s",
+		influxdb.ErrMeasurementCreationDisabled, e.Database, strings.Join(e.Measurements, ", "))
+}
+
+// MeasurementCreationChecker rejects writes that would implicitly create
+// measurements in databases with a fixed schema.
+type MeasurementCreationChecker struct {
+	rejected uint64
+
+	// Databases holds the databases that don't allow new measurements.
+	Databases map[string]bool
+
+	// TSDBStore looks up the measurements that exist.
+	TSDBStore interface {
+		Measurement(database, name string) *tsdb.Measurement
+	}
+}
+
+// NewMeasurementCreationChecker returns a new MeasurementCreationChecker from
+// the cluster configuration.
+func NewMeasurementCreationChecker(c Config) *MeasurementCreationChecker {
+	mc := &MeasurementCreationChecker{Databases: make(map[string]bool)}
+	for _, db := range c.FixedSchemaDatabases {
+		mc.Databases[db] = true
+	}
+	return mc
+}
+
+// Check returns a *MeasurementCreationError if the database doesn't allow
+// new measurements and any of the points is in a measurement that doesn't exist.
+func (c *MeasurementCreationChecker) Check(database string, points []tsdb.Point) error {
+	if !c.Databases[database] {
+		return nil
+	}
+
+	var unknown map[string]struct{}
+	for _, p := range points {
+		name := p.Name()
+		if _, ok := unknown[name]; ok {
+			continue
+		} else if c.TSDBStore.Measurement(database, name) != nil {
+			continue
+		}
+
+		if unknown == nil {
+			unknown = make(map[string]struct{})
+		}
+		unknown[name] = struct{}{}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	atomic.AddUint64(&c.rejected, 1)
+	e := &MeasurementCreationError{Database: database}
+	for name := range unknown {
+		e.Measurements = append(e.Measurements, name)
+	}
+	sort.Strings(e.Measurements)
+	return e
+}
+
+// Rejected returns the number of writes rejected by the checker.
+func (c *MeasurementCreationChecker) Rejected() uint64 {
+	return atomic.LoadUint64(&c.rejected)
+}
@@ -0,0 +1,53 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensures writes to unknown measurements are rejected only in fixed schema databases.
+func TestMeasurementCreationChecker_Check(t *testing.T) {
+	c := cluster.NewConfig()
+	c.FixedSchemaDatabases = []string{"fixed"}
+	mc := cluster.NewMeasurementCreationChecker(c)
+	mc.TSDBStore = &measurementStore{"fixed": {"cpu": true}}
+
+	now := time.Unix(0, 0)
+	cpu := tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now)
+	mem := tsdb.NewPoint("mem", nil, tsdb.Fields{"value": 1.0}, now)
+	disk := tsdb.NewPoint("disk", nil, tsdb.Fields{"value": 1.0}, now)
+
+	if err := mc.Check("fixed", []tsdb.Point{cpu, cpu}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := mc.Check("other", []tsdb.Point{mem}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := mc.Check("fixed", []tsdb.Point{mem, cpu, disk, mem})
+	if e, ok := err.(*cluster.MeasurementCreationError); !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if e.Database != "fixed" || !reflect.DeepEqual(e.Measurements, []string{"disk", "mem"}) {
+		t.Fatalf("unexpected error: %#v", e)
+	} else if !influxdb.IsClientError(err) {
+		t.Fatalf("expected client error: %s", err)
+	}
+
+	if n := mc.Rejected(); n != 1 {
+		t.Fatalf("unexpected rejected count: %d", n)
+	}
+}
+
+// measurementStore is a TSDB store with the given measurements by database.
+type measurementStore map[string]map[string]bool
+
+func (s measurementStore) Measurement(database, name string) *tsdb.Measurement {
+	if !s[database][name] {
+		return nil
+	}
+	return tsdb.NewMeasurement(name, nil)
+}
@@ -46,8 +46,67 @@ var (
 	// ErrInvalidConsistencyLevel is returned when parsing the string version
 	// of a consistency level.
 	ErrInvalidConsistencyLevel = errors.New("invalid consistency level")
+
+	// ErrShardGroupDeleted is returned when points are written to a shard
+	// whose shard group was deleted, such as by the retention policy.
+	ErrShardGroupDeleted = errors.New("shard group deleted")
+)
+
+// AckMode controls whether writes to remote replicas must be acknowledged
+// before a write returns.
+type AckMode int
+
+const (
+	// AckModeDefault uses the ack mode configured for the database.
+	AckModeDefault AckMode = iota
+
+	// AckModeSync waits for remote replicas to acknowledge the write.
+	AckModeSync
+
+	// AckModeAsync queues writes to remote replicas in hinted handoff without
+	// waiting for them. A queued write counts towards the consistency level.
+	AckModeAsync
 )
 
+// shardTimeRangeResolution is the granularity of the recorded time ranges of
+// shards. Ranges are rounded out to it so a steady stream of points only
+// extends a shard's range, through the meta store, once per interval.
+const shardTimeRangeResolution = 10 * time.Minute
+
+// ErrInvalidAckMode is returned when parsing an unknown ack mode.
+var ErrInvalidAckMode = errors.New("invalid ack mode")
+
+// ParseAckMode parses an ack mode: "sync", "async" or empty for the default.
+func ParseAckMode(mode string) (AckMode, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return AckModeDefault, nil
+	case "sync":
+		return AckModeSync, nil
+	case "async":
+		return AckModeAsync, nil
+	default:
+		return 0, ErrInvalidAckMode
+	}
+}
+
+// ParseAckModes returns the default ack mode and the per-database ack modes
+// from the cluster configuration.
+func ParseAckModes(c Config) (AckMode, map[string]AckMode, error) {
+	mode, err := ParseAckMode(c.WriteAck)
+	if err != nil {
+		return 0, nil, fmt.Errorf("write-ack %q: %s", c.WriteAck, err)
+	}
+
+	modes := make(map[string]AckMode, len(c.WriteAcks))
+	for db, s := range c.WriteAcks {
+		if modes[db], err = ParseAckMode(s); err != nil {
+			return 0, nil, fmt.Errorf("write-acks %s %q: %s", db, s, err)
+		}
+	}
+	return mode, modes, nil
+}
+
 func ParseConsistencyLevel(level string) (ConsistencyLevel, error) {
 	switch strings.ToLower(level) {
 	case "any":
@@ -90,15 +149,76 @@ type PointsWriter struct {
 	HintedHandoff interface {
 		WriteShard(shardID, ownerID uint64, points []tsdb.Point) error
 	}
+
+	// DeadLetters stores points that an owner of a shard rejected, such as
+	// points with a field type conflict. Optional.
+	DeadLetters interface {
+		Add(database, retentionPolicy string, points []tsdb.Point, reason error) error
+	}
+
+	// SeriesCreationLimiter leaves out the points of new series created
+	// faster than their database allows. Optional.
+	SeriesCreationLimiter interface {
+		Limit(database string, points []tsdb.Point) ([]tsdb.Point, error)
+	}
+
+	// TimestampChecker applies a policy to points with implausible timestamps. Optional.
+	TimestampChecker interface {
+		Check(database string, points []tsdb.Point) (TimestampCheckerStats, error)
+	}
+
+	// TagLimiter applies a policy to points with too many tags. Optional.
+	TagLimiter interface {
+		Check(database string, points []tsdb.Point) (TagLimiterStats, error)
+	}
+
+	// FutureSkewChecker applies a policy to points too far in the future. Optional.
+	FutureSkewChecker interface {
+		Check(points []tsdb.Point) (FutureSkewCheckerStats, error)
+	}
+
+	// MeasurementCreationChecker rejects writes that would create
+	// measurements in databases with a fixed schema. Optional.
+	MeasurementCreationChecker interface {
+		Check(database string, points []tsdb.Point) error
+	}
+
+	// WriteFilter drops denied points before they're written. Optional.
+	WriteFilter interface {
+		Filter(points []tsdb.Point) []tsdb.Point
+	}
+
+	// ShardTimeRanges records the time range of the points written to each
+	// shard so queries can skip shards without matching points. Optional.
+	ShardTimeRanges interface {
+		UpdateShardTimeRange(database, policy string, shardID uint64, min, max time.Time) error
+	}
+
+	// AckMode is used for requests without an ack mode when their database
+	// isn't in AckModes. Writes are synchronous if neither is set.
+	AckMode  AckMode
+	AckModes map[string]AckMode
+
+	stats   *tsdb.Statistics
+	latency map[string]*tsdb.LatencyHistogram // by write phase
 }
 
+// Write phases with a latency histogram.
+var writePhases = []string{"parse", "validate", "shardMap", "wal", "replicate", "total"}
+
 // NewPointsWriter returns a new instance of PointsWriter for a node.
 func NewPointsWriter() *PointsWriter {
-	return &PointsWriter{
+	w := &PointsWriter{
 		closing:      make(chan struct{}),
 		WriteTimeout: DefaultWriteTimeout,
 		Logger:       log.New(os.Stderr, "[write] ", log.LstdFlags),
+		stats:        tsdb.NewStatistics("cluster", "write", nil),
+		latency:      make(map[string]*tsdb.LatencyHistogram, len(writePhases)),
+	}
+	for _, phase := range writePhases {
+		w.latency[phase] = tsdb.NewLatencyHistogram("cluster", "writeLatency", map[string]string{"phase": phase})
 	}
+	return w
 }
 
 // ShardMapping contains a mapping of a shards to a points.
@@ -145,64 +265,183 @@ func (w *PointsWriter) Close() error {
 	return nil
 }
 
+// renameToStored renames the measurement and tags of a point to the names
+// they're stored under.
+func renameToStored(di *meta.DatabaseInfo, p tsdb.Point) {
+	if name := di.StoredMeasurementName(p.Name()); name != p.Name() {
+		p.SetName(name)
+	}
+	if !di.HasTagRenames(p.Name()) {
+		return
+	}
+
+	var changed bool
+	var tags tsdb.Tags
+	for _, t := range p.Tags() {
+		key := di.StoredTagKey(p.Name(), t.Key)
+		value := di.StoredTagValue(p.Name(), key, t.Value)
+		if key != t.Key || value != t.Value {
+			changed = true
+		}
+		tags.Set(key, value)
+	}
+	if changed {
+		p.SetTags(tags)
+	}
+}
+
 // MapShards maps the points contained in wp to a ShardMapping.  If a point
 // maps to a shard group or shard that does not currently exist, it will be
 // created before returning the mapping.
 func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error) {
 
-	// holds the start time ranges for required shard groups
-	timeRanges := map[time.Time]*meta.ShardGroupInfo{}
-
 	rp, err := w.MetaStore.RetentionPolicy(wp.Database, wp.RetentionPolicy)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, p := range wp.Points {
-		timeRanges[p.Time().Truncate(rp.ShardGroupDuration)] = nil
-	}
-
-	// holds all the shard groups and shards that are required for writes
-	for t := range timeRanges {
-		sg, err := w.MetaStore.CreateShardGroupIfNotExists(wp.Database, wp.RetentionPolicy, t)
+	// Split the points by the time range of the shard group they belong to
+	// and create the shard groups required for the writes.
+	mapping := NewShardMapping()
+	for k, points := range tsdb.SplitPoints(wp.Points, rp.ShardGroupDuration, 1) {
+		sg, err := w.MetaStore.CreateShardGroupIfNotExists(wp.Database, wp.RetentionPolicy, k.Start)
 		if err != nil {
 			return nil, err
 		}
-		timeRanges[t] = sg
-	}
 
-	mapping := NewShardMapping()
-	for _, p := range wp.Points {
-		sg := timeRanges[p.Time().Truncate(rp.ShardGroupDuration)]
-		sh := sg.ShardFor(p.HashID())
-		mapping.MapPoint(&sh, p)
+		for _, p := range points {
+			sh := sg.Shards[p.BucketID(len(sg.Shards))]
+			mapping.MapPoint(&sh, p)
+		}
 	}
 	return mapping, nil
 }
 
 // WritePoints writes across multiple local and remote data nodes according the consistency level.
 func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
+	w.stats.Add("req", 1)
+	w.stats.Add("pointReq", int64(len(p.Points)))
+
+	if p.Timings == nil {
+		p.Timings = &WriteTimings{}
+	}
+	start := time.Now()
+
+	// Shard writes still running when WritePoints returns, such as those to
+	// replicas a consistency level of one doesn't wait for, hold on to the
+	// points until they finish. The timings are observed once they have so
+	// the durations of their phases are complete.
+	var wg sync.WaitGroup
+	defer func() {
+		total := time.Since(start)
+		go func() {
+			wg.Wait()
+			w.observeTimings(p.Timings, total)
+			if p.Done != nil {
+				p.Done()
+			}
+		}()
+	}()
+
+	if w.WriteFilter != nil {
+		if p.Points = w.WriteFilter.Filter(p.Points); len(p.Points) == 0 {
+			return nil
+		}
+	}
+
+	db, err := w.MetaStore.Database(p.Database)
+	if err != nil {
+		return err
+	}
+
 	if p.RetentionPolicy == "" {
-		db, err := w.MetaStore.Database(p.Database)
-		if err != nil {
-			return err
-		} else if db == nil {
+		if db == nil {
 			return influxdb.ErrDatabaseNotFound(p.Database)
 		}
 		p.RetentionPolicy = db.DefaultRetentionPolicy
 	}
 
+	// Points written to a renamed measurement or tag are stored under the
+	// original names.
+	if db != nil && (len(db.MeasurementRenames) > 0 || len(db.TagRenames) > 0) {
+		for _, pt := range p.Points {
+			renameToStored(db, pt)
+		}
+	}
+
+	if w.MeasurementCreationChecker != nil {
+		if err := w.MeasurementCreationChecker.Check(p.Database, p.Points); err != nil {
+			return err
+		}
+	}
+
+	if w.TagLimiter != nil {
+		s, err := w.TagLimiter.Check(p.Database, p.Points)
+		w.stats.Add("tagLimitFolded", int64(s.Folded))
+		w.stats.Add("tagLimitRejected", int64(s.Rejected))
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.TimestampChecker != nil {
+		s, err := w.TimestampChecker.Check(p.Database, p.Points)
+		w.stats.Add("timestampFlagged", int64(s.Flagged))
+		w.stats.Add("timestampFixed", int64(s.Fixed))
+		w.stats.Add("timestampRejected", int64(s.Rejected))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check the skew before mapping shards so no shard group is created for
+	// the rejected points.
+	if w.FutureSkewChecker != nil {
+		s, err := w.FutureSkewChecker.Check(p.Points)
+		w.stats.Add("futureSkewClamped", int64(s.Clamped))
+		w.stats.Add("futureSkewRejected", int64(s.Rejected))
+		if err != nil {
+			return err
+		}
+	}
+
+	// The points of existing series are still written when new series are
+	// over the limit, and the limit error is returned once they are.
+	var limitErr error
+	if w.SeriesCreationLimiter != nil {
+		if p.Points, limitErr = w.SeriesCreationLimiter.Limit(p.Database, p.Points); len(p.Points) == 0 {
+			return limitErr
+		}
+	}
+
+	p.Timings.Validate = time.Since(start)
+
+	mapStart := time.Now()
 	shardMappings, err := w.MapShards(p)
 	if err != nil {
 		return err
 	}
+	p.Timings.ShardMap = time.Since(mapStart)
+
+	// Extend the time ranges of the shards before writing so a query never
+	// skips a shard holding matching points.
+	if err := w.updateShardTimeRanges(p, shardMappings); err != nil {
+		return err
+	}
+
+	ack := w.ackMode(p)
+
+	// Shards owned by the same remote node are written in one request.
+	remote := w.coalesceRemoteWrites(shardMappings, ack, p.TraceID, &wg)
 
 	// Write each shard in it's own goroutine and return as soon
 	// as one fails.
 	ch := make(chan error, len(shardMappings.Points))
 	for shardID, points := range shardMappings.Points {
+		wg.Add(1)
 		go func(shard *meta.ShardInfo, database, retentionPolicy string, points []tsdb.Point) {
-			ch <- w.writeToShard(shard, p.Database, p.RetentionPolicy, p.ConsistencyLevel, points)
+			defer wg.Done()
+			ch <- w.writeToShard(shard, p.Database, p.RetentionPolicy, p.ConsistencyLevel, ack, points, p.TraceID, p.Timings, remote, &wg)
 		}(shardMappings.Shards[shardID], p.Database, p.RetentionPolicy, points)
 	}
 
@@ -216,13 +455,142 @@ func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
 			}
 		}
 	}
+	return limitErr
+}
+
+// updateShardTimeRanges extends the recorded time ranges of shards that the
+// mapped points fall outside of.
+func (w *PointsWriter) updateShardTimeRanges(p *WritePointsRequest, m *ShardMapping) error {
+	if w.ShardTimeRanges == nil {
+		return nil
+	}
+
+	for id, points := range m.Points {
+		sh := m.Shards[id]
+		if !sh.HasTimeRange || len(points) == 0 {
+			continue
+		}
+
+		min, max := points[0].Time(), points[0].Time()
+		for _, pt := range points[1:] {
+			if t := pt.Time(); t.Before(min) {
+				min = t
+			} else if t.After(max) {
+				max = t
+			}
+		}
+		if !sh.MinTime.IsZero() && !min.Before(sh.MinTime) && !max.After(sh.MaxTime) {
+			continue
+		}
+
+		min = min.Truncate(shardTimeRangeResolution)
+		max = max.Truncate(shardTimeRangeResolution).Add(shardTimeRangeResolution - 1)
+		if err := w.ShardTimeRanges.UpdateShardTimeRange(p.Database, p.RetentionPolicy, id, min, max); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// observeTimings adds the durations of the phases of a write to the latency
+// histograms. Phases the write didn't reach are skipped. The total is the time
+// until WritePoints returned.
+func (w *PointsWriter) observeTimings(t *WriteTimings, total time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for phase, d := range map[string]time.Duration{
+		"parse":     t.Parse,
+		"validate":  t.Validate,
+		"shardMap":  t.ShardMap,
+		"wal":       t.WAL,
+		"replicate": t.Replicate,
+	} {
+		if d > 0 {
+			w.latency[phase].Observe(d)
+		}
+	}
+	w.latency["total"].Observe(t.Parse + total)
+}
+
+// ackMode returns the ack mode for a write request.
+func (w *PointsWriter) ackMode(p *WritePointsRequest) AckMode {
+	if p.AckMode != AckModeDefault {
+		return p.AckMode
+	} else if mode := w.AckModes[p.Database]; mode != AckModeDefault {
+		return mode
+	} else if w.AckMode != AckModeDefault {
+		return w.AckMode
+	}
+	return AckModeSync
+}
+
+// traceShardWriter is implemented by shard writers that can pass a trace ID to
+// the remote node.
+type traceShardWriter interface {
+	WriteShardWithTrace(shardID, ownerID uint64, points []tsdb.Point, traceID string) error
+}
+
+// multiShardWriter is implemented by shard writers that can write to several
+// shards of a node in one request.
+type multiShardWriter interface {
+	WriteShards(ownerID uint64, shards []ShardPoints, traceID string) []error
+}
+
+// remoteWrite is a request writing to several shards of a remote node.
+// Errors are set by shard ID before done is closed.
+type remoteWrite struct {
+	done chan struct{}
+	errs map[uint64]error
+}
+
+// coalesceRemoteWrites starts one request per remote node owning more than
+// one of the mapped shards and returns the requests by node ID. Asynchronous
+// writes aren't coalesced since they're queued in hinted handoff. The requests
+// are added to wg.
+func (w *PointsWriter) coalesceRemoteWrites(m *ShardMapping, ack AckMode, traceID string, wg *sync.WaitGroup) map[uint64]*remoteWrite {
+	mw, ok := w.ShardWriter.(multiShardWriter)
+	if !ok || ack == AckModeAsync {
+		return nil
+	}
+
+	// Group the shards by remote owner.
+	nodeShards := make(map[uint64][]ShardPoints)
+	for shardID, points := range m.Points {
+		for _, nodeID := range m.Shards[shardID].OwnerIDs {
+			if nodeID != w.MetaStore.NodeID() {
+				nodeShards[nodeID] = append(nodeShards[nodeID], ShardPoints{ShardID: shardID, Points: points})
+			}
+		}
+	}
+
+	remote := make(map[uint64]*remoteWrite)
+	for nodeID, shards := range nodeShards {
+		if len(shards) < 2 {
+			continue
+		}
+
+		rw := &remoteWrite{done: make(chan struct{}), errs: make(map[uint64]error, len(shards))}
+		remote[nodeID] = rw
+		w.stats.Add("writeShardsReq", 1)
+
+		wg.Add(1)
+		go func(nodeID uint64, shards []ShardPoints, rw *remoteWrite) {
+			defer wg.Done()
+			for i, err := range mw.WriteShards(nodeID, shards, traceID) {
+				rw.errs[shards[i].ShardID] = err
+			}
+			close(rw.done)
+		}(nodeID, shards, rw)
+	}
+	return remote
+}
+
 // writeToShards writes points to a shard and ensures a write consistency level has been met.  If the write
-// partially succeeds, ErrPartialWrite is returned.
+// partially succeeds, ErrPartialWrite is returned. Writes to nodes in remote wait for the coalesced
+// request instead of sending their own. The write to each owner is added to wg.
 func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string,
-	consistency ConsistencyLevel, points []tsdb.Point) error {
+	consistency ConsistencyLevel, ack AckMode, points []tsdb.Point, traceID string, timings *WriteTimings,
+	remote map[uint64]*remoteWrite, wg *sync.WaitGroup) error {
 	// The required number of writes to achieve the requested consistency level
 	required := len(shard.OwnerIDs)
 	switch consistency {
@@ -236,41 +604,82 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 	ch := make(chan error, len(shard.OwnerIDs))
 
 	for _, nodeID := range shard.OwnerIDs {
+		wg.Add(1)
 		go func(shardID, nodeID uint64, points []tsdb.Point) {
+			defer wg.Done()
+
+			// Record how long the write took before responding so it's
+			// observed once every shard write of the request finished.
+			start := time.Now()
+			done := func(phase *time.Duration, err error) {
+				timings.max(phase, time.Since(start))
+				ch <- err
+			}
+
 			if w.MetaStore.NodeID() == nodeID {
+				w.stats.Add("pointReqLocal", int64(len(points)))
 				err := w.TSDBStore.WriteToShard(shardID, points)
 				// If we've written to shard that should exist on the current node, but the store has
 				// not actually created this shard, tell it to create it and retry the write
 				if err == tsdb.ErrShardNotFound {
 					err = w.TSDBStore.CreateShard(database, retentionPolicy, shardID)
 					if err != nil {
-						ch <- err
+						done(&timings.WAL, err)
 						return
 					}
 					err = w.TSDBStore.WriteToShard(shardID, points)
 				}
-				ch <- err
+				done(&timings.WAL, err)
 				return
 			}
 
-			err := w.ShardWriter.WriteShard(shardID, nodeID, points)
+			// Don't wait for remote replicas if the write is asynchronous.
+			if ack == AckModeAsync {
+				w.stats.Add("pointReqHH", int64(len(points)))
+				done(&timings.Replicate, w.HintedHandoff.WriteShard(shardID, nodeID, points))
+				return
+			}
+
+			w.stats.Add("pointReqRemote", int64(len(points)))
+			var err error
+			if rw := remote[nodeID]; rw != nil {
+				<-rw.done
+				err = rw.errs[shardID]
+			} else if tw, ok := w.ShardWriter.(traceShardWriter); ok && traceID != "" {
+				err = tw.WriteShardWithTrace(shardID, nodeID, points, traceID)
+			} else {
+				err = w.ShardWriter.WriteShard(shardID, nodeID, points)
+			}
 			if err != nil && tsdb.IsRetryable(err) {
 				// The remote write failed so queue it via hinted handoff
+				w.stats.Add("pointReqHH", int64(len(points)))
 				hherr := w.HintedHandoff.WriteShard(shardID, nodeID, points)
 
 				// If the write consistency level is ANY, then a successful hinted handoff can
 				// be considered a successful write so send nil to the response channel
 				// otherwise, let the original error propogate to the response channel
 				if hherr == nil && consistency == ConsistencyLevelAny {
-					ch <- nil
+					done(&timings.Replicate, nil)
 					return
 				}
 			}
-			ch <- err
+			done(&timings.Replicate, err)
 
 		}(shard.ID, nodeID, points)
 	}
 
+	// The points will never be accepted as they are by an owner that
+	// rejected them, so keep them aside once.
+	var rejected error
+	defer func() {
+		if rejected == nil || w.DeadLetters == nil {
+			return
+		}
+		if err := w.DeadLetters.Add(database, retentionPolicy, points, rejected); err != nil {
+			w.Logger.Printf("failed to store rejected points for shard %d: %v%s", shard.ID, err, traceSuffix(traceID))
+		}
+	}()
+
 	var wrote int
 	timeout := time.After(w.WriteTimeout)
 	var writeError error
@@ -280,16 +689,20 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 			return ErrWriteFailed
 		case <-timeout:
 			// return timeout error to caller
+			w.stats.Add("writeTimeout", 1)
 			return ErrTimeout
 		case err := <-ch:
 			// If the write returned an error, continue to the next response
 			if err != nil {
-				w.Logger.Printf("write failed for shard %d on node %d: %v", shard.ID, nodeID, err)
+				w.Logger.Printf("write failed for shard %d on node %d: %v%s", shard.ID, nodeID, err, traceSuffix(traceID))
 
 				// Keep track of the first error we see to return back to the client
 				if writeError == nil {
 					writeError = err
 				}
+				if rejected == nil && !tsdb.IsRetryable(err) {
+					rejected = err
+				}
 				continue
 			}
 
@@ -299,13 +712,17 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 
 	// We wrote the required consistency level
 	if wrote >= required {
+		w.stats.Add("writeOk", 1)
 		return nil
 	}
 
 	if wrote > 0 {
+		w.stats.Add("writePartial", 1)
 		return ErrPartialWrite
 	}
 
+	w.stats.Add("writeError", 1)
+
 	if writeError != nil {
 		return fmt.Errorf("write failed: %v", writeError)
 	}
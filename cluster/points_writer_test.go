@@ -1,12 +1,16 @@
 package cluster_test
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
@@ -308,8 +312,469 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 	}
 }
 
+// Ensures points rejected by every shard owner are sent to the dead letter queue.
+func TestPointsWriter_WritePoints_DeadLetters(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+	}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+
+	conflict := fmt.Errorf("field type conflict: input field \"value\" on measurement \"cpu\" is type float64, already exists as type string")
+	sw := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return conflict },
+	}
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error { return conflict },
+	}
+
+	var mu sync.Mutex
+	var rejected []tsdb.Point
+	dl := &fakeDeadLetters{
+		AddFn: func(database, retentionPolicy string, points []tsdb.Point, reason error) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if database != "mydb" || retentionPolicy != "myrp" {
+				t.Errorf("unexpected database/retention policy: %s/%s", database, retentionPolicy)
+			} else if reason != conflict {
+				t.Errorf("unexpected reason: %v", reason)
+			}
+			rejected = append(rejected, points...)
+			return nil
+		},
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.ShardWriter = sw
+	c.TSDBStore = store
+	c.DeadLetters = dl
+
+	if err := c.WritePoints(pr); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(rejected) != 1 || rejected[0] != pr.Points[0] {
+		t.Fatalf("unexpected rejected points: %v", rejected)
+	}
+}
+
+// Ensures points rejected by any shard owner are sent to the dead letter queue
+// once, even if other owners accepted them or failed first.
+func TestPointsWriter_WritePoints_DeadLetters_Rejected(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+	}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+
+	rejection := &tsdb.PointsRejectedError{Err: cluster.ErrShardGroupDeleted}
+	release := make(chan struct{})
+	sw := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+			if nodeID == 2 {
+				return errors.New("connection refused")
+			}
+			// Respond after the retryable error so it's the first error seen.
+			<-release
+			return rejection
+		},
+	}
+	hh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+			close(release)
+			return nil
+		},
+	}
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error { return nil },
+	}
+
+	var mu sync.Mutex
+	var reasons []error
+	dl := &fakeDeadLetters{
+		AddFn: func(database, retentionPolicy string, points []tsdb.Point, reason error) error {
+			mu.Lock()
+			defer mu.Unlock()
+			reasons = append(reasons, reason)
+			return nil
+		},
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.ShardWriter = sw
+	c.HintedHandoff = hh
+	c.TSDBStore = store
+	c.DeadLetters = dl
+
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reasons) != 1 || reasons[0] != rejection {
+		t.Fatalf("unexpected rejections: %v", reasons)
+	}
+}
+
+// Ensures Done is only called once no shard write uses the points, even if
+// WritePoints returned before the remote writes finished.
+func TestPointsWriter_WritePoints_Done(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelAll,
+	}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+
+	done := make(chan struct{})
+	pr.Done = func() { close(done) }
+
+	release := make(chan struct{})
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.WriteTimeout = 10 * time.Millisecond
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error { return nil },
+	}
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+			<-release
+			return nil
+		},
+	}
+
+	if err := c.WritePoints(pr); err != cluster.ErrTimeout {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("done called while remote writes are running")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("done not called")
+	}
+}
+
+// Ensures asynchronous writes queue remote replicas in hinted handoff.
+func TestPointsWriter_WritePoints_AckModeAsync(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		request  cluster.AckMode
+		database cluster.AckMode
+		async    bool
+	}{
+		{name: "default", async: false},
+		{name: "database", database: cluster.AckModeAsync, async: true},
+		{name: "request", request: cluster.AckModeAsync, async: true},
+		{name: "request overrides database", request: cluster.AckModeSync, database: cluster.AckModeAsync, async: false},
+	} {
+		pr := &cluster.WritePointsRequest{
+			Database:         "mydb",
+			RetentionPolicy:  "myrp",
+			ConsistencyLevel: cluster.ConsistencyLevelAll,
+			AckMode:          tt.request,
+		}
+		pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+
+		var mu sync.Mutex
+		var written, queued []uint64
+		sw := &fakeShardWriter{
+			ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+				mu.Lock()
+				defer mu.Unlock()
+				written = append(written, nodeID)
+				return nil
+			},
+		}
+		hh := &fakeShardWriter{
+			ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+				mu.Lock()
+				defer mu.Unlock()
+				queued = append(queued, nodeID)
+				return nil
+			},
+		}
+		store := &fakeStore{
+			WriteFn: func(shardID uint64, points []tsdb.Point) error { return nil },
+		}
+
+		ms := NewMetaStore()
+		ms.NodeIDFn = func() uint64 { return 1 }
+		c := cluster.NewPointsWriter()
+		c.MetaStore = ms
+		c.ShardWriter = sw
+		c.TSDBStore = store
+		c.HintedHandoff = hh
+		c.AckModes = map[string]cluster.AckMode{"mydb": tt.database}
+
+		if err := c.WritePoints(pr); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+
+		// The local write is always synchronous; the two remote owners are
+		// either written or queued.
+		if tt.async && (len(written) != 0 || len(queued) != 2) {
+			t.Errorf("%s: unexpected writes: written=%v queued=%v", tt.name, written, queued)
+		} else if !tt.async && (len(written) != 2 || len(queued) != 0) {
+			t.Errorf("%s: unexpected writes: written=%v queued=%v", tt.name, written, queued)
+		}
+	}
+}
+
+// Ensures the time taken by each phase of a write is recorded on the request.
+func TestPointsWriter_WritePoints_Timings(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelAll,
+		Timings:          &cluster.WriteTimings{Parse: time.Millisecond},
+	}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	}
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+	}
+
+	stats := tsdb.NewStatistics("cluster", "writeLatency", map[string]string{"phase": "replicate"})
+	n := stats.Get("count")
+
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	}
+
+	if tm := pr.Timings; tm.Parse != time.Millisecond {
+		t.Errorf("unexpected parse time: %s", tm.Parse)
+	} else if tm.WAL < 10*time.Millisecond || tm.WAL >= tm.Replicate {
+		t.Errorf("unexpected wal time: %s", tm.WAL)
+	} else if tm.Replicate < 20*time.Millisecond {
+		t.Errorf("unexpected replicate time: %s", tm.Replicate)
+	}
+
+	// The timings are observed once every shard write finished, after
+	// WritePoints returned.
+	for i := 0; stats.Get("count") != n+1 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := stats.Get("count"); got != n+1 {
+		t.Errorf("unexpected replicate latency count: %d", got-n)
+	}
+}
+
+// Ensures shard time ranges are extended before points outside of them are written.
+func TestPointsWriter_WritePoints_ShardTimeRanges(t *testing.T) {
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	rp, _ := ms.RetentionPolicy("mydb", "myrp")
+	sh := &rp.ShardGroups[0].Shards[0]
+	sh.HasTimeRange = true
+	sh.MinTime, sh.MaxTime = time.Unix(0, 0), time.Unix(600, 0)
+
+	type update struct {
+		shardID  uint64
+		min, max time.Time
+	}
+	var updates []update
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error {
+			if len(updates) == 0 && points[0].Time().After(sh.MaxTime) {
+				t.Error("points written before the time range was extended")
+			}
+			return nil
+		},
+	}
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return nil },
+	}
+	c.ShardTimeRanges = &fakeShardTimeRanges{
+		UpdateFn: func(database, policy string, shardID uint64, min, max time.Time) error {
+			updates = append(updates, update{shardID, min, max})
+			return nil
+		},
+	}
+
+	// Points within the recorded range don't update it.
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, time.Unix(60, 0), nil)
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	} else if len(updates) != 0 {
+		t.Fatalf("unexpected updates: %v", updates)
+	}
+
+	// Points after it extend it, rounded out.
+	pr.Points = nil
+	pr.AddPoint("cpu", 1.0, time.Unix(700, 0), nil)
+	pr.AddPoint("cpu", 1.0, time.Unix(1300, 0), nil)
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	} else if exp := []update{{sh.ID, time.Unix(600, 0), time.Unix(1800, 0).Add(-1)}}; !reflect.DeepEqual(updates, exp) {
+		t.Fatalf("unexpected updates: %v", updates)
+	}
+}
+
+// Ensures points written to a renamed measurement or tag are stored under the original names.
+func TestPointsWriter_WritePoints_Renamed(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelAll,
+	}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), map[string]string{"hostname": "server-a"})
+	pr.AddPoint("mem", 1.0, time.Unix(0, 0), map[string]string{"hostname": "server-a"})
+
+	var mu sync.Mutex
+	var keys []string
+	write := func(shardID, nodeID uint64, points []tsdb.Point) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range points {
+			keys = append(keys, string(p.Key()))
+		}
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.DatabaseFn = func(database string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{
+			Name:               database,
+			MeasurementRenames: map[string]string{"cpu_typo": "cpu"},
+			TagRenames: []meta.TagRenameInfo{
+				{Measurement: "cpu_typo", Key: "host", NewName: "hostname"},
+				{Measurement: "cpu_typo", Key: "host", Value: "serverA", NewName: "server-a"},
+			},
+		}, nil
+	}
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.ShardWriter = &fakeShardWriter{ShardWriteFn: write}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error { return write(shardID, 1, points) },
+	}
+
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(keys)
+	if exp := []string{
+		"cpu_typo,host=serverA", "cpu_typo,host=serverA", "cpu_typo,host=serverA",
+		"mem,hostname=server-a", "mem,hostname=server-a", "mem,hostname=server-a",
+	}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+// Ensures points for several shards owned by the same remote node are written in one request.
+func TestPointsWriter_WritePoints_CoalesceRemote(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelAll,
+	}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+	pr.AddPoint("cpu", 2.0, time.Unix(0, 0).Add(time.Hour), nil)
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	rp, _ := ms.RetentionPolicy("mydb", "myrp")
+
+	var mu sync.Mutex
+	requests := make(map[uint64][]uint64)
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []tsdb.Point) error { return nil },
+	}
+	c.ShardWriter = &fakeMultiShardWriter{
+		fakeShardWriter: fakeShardWriter{
+			ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+				t.Errorf("unexpected single shard write: shard=%d, node=%d", shardID, nodeID)
+				return nil
+			},
+		},
+		WriteShardsFn: func(ownerID uint64, shards []cluster.ShardPoints, traceID string) []error {
+			mu.Lock()
+			defer mu.Unlock()
+			errs := make([]error, len(shards))
+			for i, sh := range shards {
+				requests[ownerID] = append(requests[ownerID], sh.ShardID)
+				if ownerID == 3 && sh.ShardID == rp.ShardGroups[1].Shards[0].ID {
+					errs[i] = fmt.Errorf("failed to write")
+				}
+			}
+			return errs
+		},
+	}
+
+	c.HintedHandoff = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return nil },
+	}
+
+	// The second shard fails on node 3 so the consistency level isn't met.
+	if err := c.WritePoints(pr); err != cluster.ErrPartialWrite {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, nodeID := range []uint64{2, 3} {
+		ids := requests[nodeID]
+		sort.Sort(uint64Slice(ids))
+		if exp := []uint64{rp.ShardGroups[0].Shards[0].ID, rp.ShardGroups[1].Shards[0].ID}; !reflect.DeepEqual(ids, exp) {
+			t.Fatalf("unexpected shards for node %d: %v", nodeID, ids)
+		}
+	}
+	if len(requests) != 2 {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
+
+type uint64Slice []uint64
+
+func (a uint64Slice) Len() int           { return len(a) }
+func (a uint64Slice) Less(i, j int) bool { return a[i] < a[j] }
+func (a uint64Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
 var shardID uint64
 
+type fakeMultiShardWriter struct {
+	fakeShardWriter
+	WriteShardsFn func(ownerID uint64, shards []cluster.ShardPoints, traceID string) []error
+}
+
+func (f *fakeMultiShardWriter) WriteShards(ownerID uint64, shards []cluster.ShardPoints, traceID string) []error {
+	return f.WriteShardsFn(ownerID, shards, traceID)
+}
+
 type fakeShardWriter struct {
 	ShardWriteFn func(shardID, nodeID uint64, points []tsdb.Point) error
 }
@@ -318,6 +783,14 @@ func (f *fakeShardWriter) WriteShard(shardID, nodeID uint64, points []tsdb.Point
 	return f.ShardWriteFn(shardID, nodeID, points)
 }
 
+type fakeShardTimeRanges struct {
+	UpdateFn func(database, policy string, shardID uint64, min, max time.Time) error
+}
+
+func (f *fakeShardTimeRanges) UpdateShardTimeRange(database, policy string, shardID uint64, min, max time.Time) error {
+	return f.UpdateFn(database, policy, shardID, min, max)
+}
+
 type fakeStore struct {
 	WriteFn       func(shardID uint64, points []tsdb.Point) error
 	CreateShardfn func(database, retentionPolicy string, shardID uint64) error
@@ -331,8 +804,20 @@ func (f *fakeStore) CreateShard(database, retentionPolicy string, shardID uint64
 	return f.CreateShardfn(database, retentionPolicy, shardID)
 }
 
+type fakeDeadLetters struct {
+	AddFn func(database, retentionPolicy string, points []tsdb.Point, reason error) error
+}
+
+func (f *fakeDeadLetters) Add(database, retentionPolicy string, points []tsdb.Point, reason error) error {
+	return f.AddFn(database, retentionPolicy, points, reason)
+}
+
 func NewMetaStore() *MetaStore {
 	ms := &MetaStore{}
+	ms.DatabaseFn = func(database string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: database, DefaultRetentionPolicy: "myp"}, nil
+	}
+
 	rp := NewRetentionPolicy("myp", time.Hour, 3)
 	AttachShardGroupInfo(rp, []uint64{1, 2, 3})
 	AttachShardGroupInfo(rp, []uint64{1, 2, 3})
@@ -434,3 +919,33 @@ func AttachShardGroupInfo(rp *meta.RetentionPolicyInfo, ownerIDs []uint64) {
 func nextShardID() uint64 {
 	return atomic.AddUint64(&shardID, 1)
 }
+
+// Ensures points too far in the future are rejected before a shard group is created for them.
+func TestPointsWriter_WritePoints_FutureSkew(t *testing.T) {
+	pr := &cluster.WritePointsRequest{
+		Database:         "mydb",
+		RetentionPolicy:  "myrp",
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+	}
+	pr.AddPoint("cpu", 1.0, time.Now().Add(365*24*time.Hour), nil)
+
+	ms := NewMetaStore()
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		t.Fatal("unexpected shard group creation")
+		return nil, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.FutureSkewChecker = &cluster.FutureSkewChecker{MaxSkew: time.Hour, Policy: cluster.FutureSkewPolicyReject, Now: time.Now}
+
+	// The rejected points are counted in the write statistics.
+	stats := tsdb.NewStatistics("cluster", "write", nil)
+	rejected := stats.Get("futureSkewRejected")
+
+	if err := c.WritePoints(pr); err == nil || !influxdb.IsClientError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := stats.Get("futureSkewRejected") - rejected; n != 1 {
+		t.Fatalf("unexpected rejected points: %d", n)
+	}
+}
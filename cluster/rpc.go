@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -15,13 +17,20 @@ type MapShardRequest struct {
 	pb internal.MapShardRequest
 }
 
-func (m *MapShardRequest) ShardID() uint64  { return m.pb.GetShardID() }
-func (m *MapShardRequest) Query() string    { return m.pb.GetQuery() }
-func (m *MapShardRequest) ChunkSize() int32 { return m.pb.GetChunkSize() }
+func (m *MapShardRequest) ShardID() uint64     { return m.pb.GetShardID() }
+func (m *MapShardRequest) Query() string       { return m.pb.GetQuery() }
+func (m *MapShardRequest) ChunkSize() int32    { return m.pb.GetChunkSize() }
+func (m *MapShardRequest) ResumeAfter() uint64 { return m.pb.GetResumeAfter() }
+func (m *MapShardRequest) TraceID() string     { return m.pb.GetTraceID() }
 
 func (m *MapShardRequest) SetShardID(id uint64)         { m.pb.ShardID = &id }
 func (m *MapShardRequest) SetQuery(query string)        { m.pb.Query = &query }
 func (m *MapShardRequest) SetChunkSize(chunkSize int32) { m.pb.ChunkSize = &chunkSize }
+func (m *MapShardRequest) SetTraceID(id string)         { m.pb.TraceID = &id }
+
+// SetResumeAfter sets the sequence number of the last chunk the client
+// received. The remote node skips every chunk up to and including it.
+func (m *MapShardRequest) SetResumeAfter(seq uint64) { m.pb.ResumeAfter = &seq }
 
 // MarshalBinary encodes the object to a binary format.
 func (m *MapShardRequest) MarshalBinary() ([]byte, error) {
@@ -53,12 +62,14 @@ func (r *MapShardResponse) Message() string   { return r.pb.GetMessage() }
 func (r *MapShardResponse) TagSets() []string { return r.pb.GetTagSets() }
 func (r *MapShardResponse) Fields() []string  { return r.pb.GetFields() }
 func (r *MapShardResponse) Data() []byte      { return r.pb.GetData() }
+func (r *MapShardResponse) Seq() uint64       { return r.pb.GetSeq() }
 
 func (r *MapShardResponse) SetCode(code int)            { r.pb.Code = proto.Int32(int32(code)) }
 func (r *MapShardResponse) SetMessage(message string)   { r.pb.Message = &message }
 func (r *MapShardResponse) SetTagSets(tagsets []string) { r.pb.TagSets = tagsets }
 func (r *MapShardResponse) SetFields(fields []string)   { r.pb.Fields = fields }
 func (r *MapShardResponse) SetData(data []byte)         { r.pb.Data = data }
+func (r *MapShardResponse) SetSeq(seq uint64)           { r.pb.Seq = &seq }
 
 // MarshalBinary encodes the object to a binary format.
 func (r *MapShardResponse) MarshalBinary() ([]byte, error) {
@@ -79,12 +90,59 @@ type WritePointsRequest struct {
 	RetentionPolicy  string
 	ConsistencyLevel ConsistencyLevel
 	Points           []tsdb.Point
+
+	// TraceID identifies the request that caused the write in log lines on
+	// every node involved. Optional.
+	TraceID string
+
+	// AckMode overrides the ack mode configured for the database. Optional.
+	AckMode AckMode
+
+	// Timings records how long each phase of the write took. The caller may
+	// set the parse time; the rest is filled in as the points are written.
+	Timings *WriteTimings
+
+	// Done is called once no shard write uses the points anymore, such as
+	// to recycle them. It may be called after WritePoints returns since a
+	// write can return on the first error or a timeout while replicas are
+	// still being written. Optional.
+	Done func()
+}
+
+// WriteTimings is how long each phase of writing a batch of points took.
+// WAL and Replicate are the slowest local and remote shard writes, since
+// shards are written concurrently. They may still grow after WritePoints
+// returns, while replicas the consistency level didn't wait for are written.
+type WriteTimings struct {
+	mu        sync.Mutex
+	Parse     time.Duration
+	Validate  time.Duration
+	ShardMap  time.Duration
+	WAL       time.Duration
+	Replicate time.Duration
+}
+
+// String returns a summary of the timings for logging.
+func (t *WriteTimings) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fmt.Sprintf("parse=%s validate=%s shardmap=%s wal=%s replicate=%s",
+		t.Parse, t.Validate, t.ShardMap, t.WAL, t.Replicate)
+}
+
+// max sets the duration of a concurrent phase to d if it's the longest yet.
+func (t *WriteTimings) max(phase *time.Duration, d time.Duration) {
+	t.mu.Lock()
+	if d > *phase {
+		*phase = d
+	}
+	t.mu.Unlock()
 }
 
 // AddPoint adds a point to the WritePointRequest with field name 'value'
 func (w *WritePointsRequest) AddPoint(name string, value interface{}, timestamp time.Time, tags map[string]string) {
 	w.Points = append(w.Points, tsdb.NewPoint(
-		name, tags, map[string]interface{}{"value": value}, timestamp,
+		name, tsdb.NewTags(tags), map[string]interface{}{"value": value}, timestamp,
 	))
 }
 
@@ -101,11 +159,14 @@ type WriteShardResponse struct {
 func (w *WriteShardRequest) SetShardID(id uint64) { w.pb.ShardID = &id }
 func (w *WriteShardRequest) ShardID() uint64      { return w.pb.GetShardID() }
 
+func (w *WriteShardRequest) SetTraceID(id string) { w.pb.TraceID = &id }
+func (w *WriteShardRequest) TraceID() string      { return w.pb.GetTraceID() }
+
 func (w *WriteShardRequest) Points() []tsdb.Point { return w.unmarshalPoints() }
 
 func (w *WriteShardRequest) AddPoint(name string, value interface{}, timestamp time.Time, tags map[string]string) {
 	w.AddPoints([]tsdb.Point{tsdb.NewPoint(
-		name, tags, map[string]interface{}{"value": value}, timestamp,
+		name, tsdb.NewTags(tags), map[string]interface{}{"value": value}, timestamp,
 	)})
 }
 
@@ -113,6 +174,38 @@ func (w *WriteShardRequest) AddPoints(points []tsdb.Point) {
 	w.pb.Points = append(w.pb.Points, w.marshalPoints(points)...)
 }
 
+// ShardPoints are the points written to one shard of a write request.
+type ShardPoints struct {
+	ShardID uint64
+	Points  []tsdb.Point
+}
+
+// AddShard adds the points of a shard to the request. The first shard uses
+// the shard ID and points of the request itself and the rest are sent as
+// extra shard sections.
+func (w *WriteShardRequest) AddShard(shardID uint64, points []tsdb.Point) {
+	if w.pb.ShardID == nil {
+		w.SetShardID(shardID)
+		w.AddPoints(points)
+		return
+	}
+
+	w.pb.Shards = append(w.pb.Shards, &internal.ShardPoints{
+		ShardID: proto.Uint64(shardID),
+		Points:  w.marshalPoints(points),
+	})
+}
+
+// Shards returns the points of every shard in the request, in the order
+// they were added.
+func (w *WriteShardRequest) Shards() []ShardPoints {
+	a := []ShardPoints{{ShardID: w.ShardID(), Points: w.Points()}}
+	for _, sh := range w.pb.GetShards() {
+		a = append(a, ShardPoints{ShardID: sh.GetShardID(), Points: unmarshalPoints(sh.GetPoints())})
+	}
+	return a
+}
+
 // MarshalBinary encodes the object to a binary format.
 func (w *WriteShardRequest) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&w.pb)
@@ -147,12 +240,10 @@ func (w *WriteShardRequest) marshalPoints(points []tsdb.Point) []*internal.Point
 		}
 
 		tags := []*internal.Tag{}
-		for k, v := range p.Tags() {
-			key := k
-			value := v
+		for _, t := range p.Tags() {
 			tags = append(tags, &internal.Tag{
-				Key:   &key,
-				Value: &value,
+				Key:   proto.String(t.Key),
+				Value: proto.String(t.Value),
 			})
 		}
 		name := p.Name()
@@ -176,45 +267,82 @@ func (w *WriteShardRequest) UnmarshalBinary(buf []byte) error {
 }
 
 func (w *WriteShardRequest) unmarshalPoints() []tsdb.Point {
-	points := make([]tsdb.Point, len(w.pb.GetPoints()))
-	for i, p := range w.pb.GetPoints() {
-		pt := tsdb.NewPoint(
-			p.GetName(), map[string]string{},
-			map[string]interface{}{}, time.Unix(0, p.GetTime()))
+	return unmarshalPoints(w.pb.GetPoints())
+}
 
+// unmarshalPoints converts points from their protobuf representation.
+func unmarshalPoints(pts []*internal.Point) []tsdb.Point {
+	points := make([]tsdb.Point, len(pts))
+	for i, p := range pts {
+		fields := make(tsdb.Fields, len(p.GetFields()))
 		for _, f := range p.GetFields() {
 			n := f.GetName()
 			if f.Int32 != nil {
-				pt.AddField(n, f.GetInt32())
+				fields[n] = f.GetInt32()
 			} else if f.Int64 != nil {
-				pt.AddField(n, f.GetInt64())
+				fields[n] = f.GetInt64()
 			} else if f.Float64 != nil {
-				pt.AddField(n, f.GetFloat64())
+				fields[n] = f.GetFloat64()
 			} else if f.Bool != nil {
-				pt.AddField(n, f.GetBool())
+				fields[n] = f.GetBool()
 			} else if f.String_ != nil {
-				pt.AddField(n, f.GetString_())
+				fields[n] = f.GetString_()
 			} else {
-				pt.AddField(n, f.GetBytes())
+				fields[n] = f.GetBytes()
 			}
 		}
 
-		tags := tsdb.Tags{}
+		tags := make(tsdb.Tags, 0, len(p.GetTags()))
 		for _, t := range p.GetTags() {
-			tags[t.GetKey()] = t.GetValue()
+			tags.Set(t.GetKey(), t.GetValue())
 		}
-		pt.SetTags(tags)
-		points[i] = pt
+		points[i] = tsdb.NewPoint(p.GetName(), tags, fields, time.Unix(0, p.GetTime()))
 	}
 	return points
 }
 
 func (w *WriteShardResponse) SetCode(code int)          { w.pb.Code = proto.Int32(int32(code)) }
 func (w *WriteShardResponse) SetMessage(message string) { w.pb.Message = &message }
+func (w *WriteShardResponse) SetCodecs(mask uint64)     { w.pb.Codecs = &mask }
+func (w *WriteShardResponse) SetMultiShard(v bool)      { w.pb.MultiShard = &v }
 
 func (w *WriteShardResponse) Code() int       { return int(w.pb.GetCode()) }
 func (w *WriteShardResponse) Message() string { return w.pb.GetMessage() }
 
+// Codecs returns the capability flags of the codecs the responding node can
+// decode. Bit n is set if the codec with ID n is registered.
+func (w *WriteShardResponse) Codecs() uint64 { return w.pb.GetCodecs() }
+
+// MultiShard returns true if the responding node accepts requests with
+// points for more than one shard.
+func (w *WriteShardResponse) MultiShard() bool { return w.pb.GetMultiShard() }
+
+// ShardStatus is the result of writing the points of one shard of a request.
+type ShardStatus struct {
+	ShardID uint64
+	Code    int
+	Message string
+}
+
+// AddShardStatus adds the result of writing a shard to the response.
+func (w *WriteShardResponse) AddShardStatus(shardID uint64, code int, message string) {
+	w.pb.Shards = append(w.pb.Shards, &internal.ShardStatus{
+		ShardID: proto.Uint64(shardID),
+		Code:    proto.Int32(int32(code)),
+		Message: proto.String(message),
+	})
+}
+
+// ShardStatuses returns the results of writing each shard of a request with
+// more than one shard.
+func (w *WriteShardResponse) ShardStatuses() []ShardStatus {
+	a := make([]ShardStatus, len(w.pb.GetShards()))
+	for i, st := range w.pb.GetShards() {
+		a[i] = ShardStatus{ShardID: st.GetShardID(), Code: int(st.GetCode()), Message: st.GetMessage()}
+	}
+	return a
+}
+
 // MarshalBinary encodes the object to a binary format.
 func (w *WriteShardResponse) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&w.pb)
@@ -227,3 +355,102 @@ func (w *WriteShardResponse) UnmarshalBinary(buf []byte) error {
 	}
 	return nil
 }
+
+// DropSeriesRequest represents a request to drop series from every shard of a
+// database on a remote node.
+type DropSeriesRequest struct {
+	pb internal.DropSeriesRequest
+}
+
+func (r *DropSeriesRequest) Database() string     { return r.pb.GetDatabase() }
+func (r *DropSeriesRequest) SeriesKeys() []string { return r.pb.GetSeriesKeys() }
+
+func (r *DropSeriesRequest) SetDatabase(database string) { r.pb.Database = &database }
+func (r *DropSeriesRequest) SetSeriesKeys(keys []string) { r.pb.SeriesKeys = keys }
+
+// MarshalBinary encodes the object to a binary format.
+func (r *DropSeriesRequest) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&r.pb)
+}
+
+// UnmarshalBinary populates DropSeriesRequest from a binary format.
+func (r *DropSeriesRequest) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &r.pb); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DropSeriesResponse represents the response returned from a remote DropSeriesRequest call.
+type DropSeriesResponse struct {
+	pb internal.DropSeriesResponse
+}
+
+func (r *DropSeriesResponse) SetCode(code int)          { r.pb.Code = proto.Int32(int32(code)) }
+func (r *DropSeriesResponse) SetMessage(message string) { r.pb.Message = &message }
+
+func (r *DropSeriesResponse) Code() int       { return int(r.pb.GetCode()) }
+func (r *DropSeriesResponse) Message() string { return r.pb.GetMessage() }
+
+// MarshalBinary encodes the object to a binary format.
+func (r *DropSeriesResponse) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&r.pb)
+}
+
+// UnmarshalBinary populates DropSeriesResponse from a binary format.
+func (r *DropSeriesResponse) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &r.pb); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ShardHasDataRequest asks a remote node whether a shard has data for a query.
+type ShardHasDataRequest struct {
+	pb internal.ShardHasDataRequest
+}
+
+func (r *ShardHasDataRequest) ShardID() uint64 { return r.pb.GetShardID() }
+func (r *ShardHasDataRequest) Query() string   { return r.pb.GetQuery() }
+
+func (r *ShardHasDataRequest) SetShardID(id uint64)  { r.pb.ShardID = &id }
+func (r *ShardHasDataRequest) SetQuery(query string) { r.pb.Query = &query }
+
+// MarshalBinary encodes the object to a binary format.
+func (r *ShardHasDataRequest) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&r.pb)
+}
+
+// UnmarshalBinary populates ShardHasDataRequest from a binary format.
+func (r *ShardHasDataRequest) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &r.pb); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ShardHasDataResponse represents the response returned from a remote ShardHasDataRequest call.
+type ShardHasDataResponse struct {
+	pb internal.ShardHasDataResponse
+}
+
+func (r *ShardHasDataResponse) SetCode(code int)          { r.pb.Code = proto.Int32(int32(code)) }
+func (r *ShardHasDataResponse) SetMessage(message string) { r.pb.Message = &message }
+func (r *ShardHasDataResponse) SetHasData(v bool)         { r.pb.HasData = &v }
+
+func (r *ShardHasDataResponse) Code() int       { return int(r.pb.GetCode()) }
+func (r *ShardHasDataResponse) Message() string { return r.pb.GetMessage() }
+func (r *ShardHasDataResponse) HasData() bool   { return r.pb.GetHasData() }
+
+// MarshalBinary encodes the object to a binary format.
+func (r *ShardHasDataResponse) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&r.pb)
+}
+
+// UnmarshalBinary populates ShardHasDataResponse from a binary format.
+func (r *ShardHasDataResponse) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &r.pb); err != nil {
+		return err
+	}
+	return nil
+}
@@ -55,9 +55,9 @@ func TestWriteShardRequestBinary(t *testing.T) {
 			t.Errorf("Point #%d HashID() mismatch: got %v, exp %v", i, g.HashID(), p.HashID())
 		}
 
-		for k, v := range p.Tags() {
-			if g.Tags()[k] != v {
-				t.Errorf("Point #%d tag mismatch: got %v, exp %v", i, k, v)
+		for _, tag := range p.Tags() {
+			if g.Tags().Get(tag.Key) != tag.Value {
+				t.Errorf("Point #%d tag mismatch: got %v, exp %v", i, tag.Key, tag.Value)
 			}
 		}
 
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/influxdb/influxdb/meta"
+)
+
+// SeriesDropper drops series from every shard of a database on every node in
+// the cluster.
+type SeriesDropper struct {
+	MetaStore interface {
+		NodeID() uint64
+		Nodes() ([]meta.NodeInfo, error)
+	}
+
+	TSDBStore interface {
+		DeleteSeries(database string, keys []string) error
+	}
+
+	ShardWriter interface {
+		DropSeries(ownerID uint64, database string, keys []string) error
+	}
+}
+
+// DropSeries drops the series locally and then from each remote node. All
+// nodes are attempted even if some fail; the first error is returned.
+func (d *SeriesDropper) DropSeries(database string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	nodes, err := d.MetaStore.Nodes()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	if err := d.TSDBStore.DeleteSeries(database, keys); err != nil {
+		firstErr = err
+	}
+
+	for _, n := range nodes {
+		if n.ID == d.MetaStore.NodeID() {
+			continue
+		}
+		if err := d.ShardWriter.DropSeries(n.ID, database, keys); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("node %d: %s", n.ID, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/clock"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// SeriesCreationRateError is returned when a write creates more series than
+// its database allows per second. The points of existing series and of the
+// new series within the limit are still written.
+type SeriesCreationRateError struct {
+	Database string
+	Rate     int // new series per second
+	Series   int // new series rejected
+	Points   int // points of the rejected series
+}
+
+func (e *SeriesCreationRateError) Error() string {
+	return fmt.Sprintf("%s: %d new series (%d points) over the limit of %d per second in database %s",
+		influxdb.ErrSeriesCreationRateExceeded, e.Series, e.Points, e.Rate, e.Database)
+}
+
+// SeriesCreationLimiter limits how many series per second each database may
+// create, smoothing the growth of the index when new tag values show up
+// everywhere at once. Writes to existing series are never limited.
+//
+// Each database may create up to its rate of series in a burst, then one
+// more every 1/rate seconds.
+type SeriesCreationLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*seriesBucket
+	rejected uint64
+
+	// Default is the rate for databases without their own rate.
+	// No limit when zero.
+	Default int
+
+	// Rates holds the rate for each database that overrides the default.
+	Rates map[string]int
+
+	// TSDBStore looks up the series that exist.
+	TSDBStore interface {
+		DatabaseIndex(name string) *tsdb.DatabaseIndex
+	}
+
+	Clock clock.Clock
+}
+
+// NewSeriesCreationLimiter returns a new SeriesCreationLimiter from the
+// cluster configuration.
+func NewSeriesCreationLimiter(c Config) (*SeriesCreationLimiter, error) {
+	if c.SeriesCreationRate < 0 {
+		return nil, fmt.Errorf("invalid series creation rate: %d", c.SeriesCreationRate)
+	}
+
+	l := &SeriesCreationLimiter{
+		buckets: make(map[string]*seriesBucket),
+		Default: c.SeriesCreationRate,
+		Rates:   make(map[string]int),
+		Clock:   clock.New(),
+	}
+	for db, n := range c.SeriesCreationRates {
+		if n < 0 {
+			return nil, fmt.Errorf("database %s: invalid series creation rate: %d", db, n)
+		}
+		l.Rates[db] = n
+	}
+	return l, nil
+}
+
+// Rate returns the series creation rate for a database.
+func (l *SeriesCreationLimiter) Rate(database string) int {
+	if n, ok := l.Rates[database]; ok {
+		return n
+	}
+	return l.Default
+}
+
+// Limit returns the points of a write whose series exist or may be created.
+// A *SeriesCreationRateError is returned with them if any were left out.
+func (l *SeriesCreationLimiter) Limit(database string, points []tsdb.Point) ([]tsdb.Point, error) {
+	rate := l.Rate(database)
+	if rate <= 0 {
+		return points, nil
+	}
+
+	var index *tsdb.DatabaseIndex
+	if l.TSDBStore != nil {
+		index = l.TSDBStore.DatabaseIndex(database)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[database]
+	if b == nil {
+		b = &seriesBucket{tokens: float64(rate), last: l.Clock.Now()}
+		l.buckets[database] = b
+	}
+	b.refill(rate, l.Clock.Now())
+
+	// Whether each new series of the write may be created, so every point
+	// of a series gets the same answer.
+	var created map[string]bool
+	var kept []tsdb.Point
+	e := &SeriesCreationRateError{Database: database, Rate: rate}
+	for i, p := range points {
+		key := string(p.Key())
+		ok, seen := created[key]
+		if !seen {
+			ok = (index != nil && index.Series(key) != nil)
+			if !ok {
+				ok = b.take()
+				if created == nil {
+					created = make(map[string]bool)
+				}
+				created[key] = ok
+				if !ok {
+					e.Series++
+				}
+			}
+		}
+
+		if !ok {
+			if kept == nil {
+				kept = append(make([]tsdb.Point, 0, len(points)), points[:i]...)
+			}
+			e.Points++
+			continue
+		} else if kept != nil {
+			kept = append(kept, p)
+		}
+	}
+
+	if e.Points == 0 {
+		return points, nil
+	}
+	atomic.AddUint64(&l.rejected, uint64(e.Points))
+	return kept, e
+}
+
+// Rejected returns the number of points rejected by the limiter.
+func (l *SeriesCreationLimiter) Rejected() uint64 {
+	return atomic.LoadUint64(&l.rejected)
+}
+
+// seriesBucket is a token bucket of the series a database may create.
+type seriesBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last refill, up to rate.
+func (b *seriesBucket) refill(rate int, now time.Time) {
+	if d := now.Sub(b.last); d > 0 {
+		b.tokens += d.Seconds() * float64(rate)
+		if b.tokens > float64(rate) {
+			b.tokens = float64(rate)
+		}
+	}
+	b.last = now
+}
+
+// take uses a token if there's one left.
+func (b *seriesBucket) take() bool {
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,76 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/clock"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensures new series over the rate of their database are left out while the
+// points of existing series are kept.
+func TestSeriesCreationLimiter_Limit(t *testing.T) {
+	c := cluster.NewConfig()
+	c.SeriesCreationRate = 2
+	c.SeriesCreationRates = map[string]int{"unlimited": 0}
+	l, err := cluster.NewSeriesCreationLimiter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := tsdb.NewDatabaseIndex()
+	index.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries("cpu,host=a", map[string]string{"host": "a"}))
+	l.TSDBStore = &SeriesCreationLimiterStore{index: index}
+	m := clock.NewMock(time.Unix(0, 0))
+	l.Clock = m
+
+	newPoints := func(hosts ...string) []tsdb.Point {
+		var a []tsdb.Point
+		for _, h := range hosts {
+			a = append(a, tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": h}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0)))
+		}
+		return a
+	}
+
+	// Hosts b and c use up the burst, so d is left out, with both its points.
+	points, err := l.Limit("db0", newPoints("a", "b", "c", "d", "b", "d"))
+	if e, ok := err.(*cluster.SeriesCreationRateError); !ok || !influxdb.IsClientError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.Series != 1 || e.Points != 2 {
+		t.Fatalf("unexpected error: %#v", e)
+	} else if len(points) != 4 {
+		t.Fatalf("unexpected points: %v", points)
+	}
+
+	// Existing series are never limited.
+	if _, err := l.Limit("db0", newPoints("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another series may be created every half second.
+	m.Add(500 * time.Millisecond)
+	if points, err := l.Limit("db0", newPoints("d", "e")); err == nil || len(points) != 1 || string(points[0].Key()) != "cpu,host=d" {
+		t.Fatalf("unexpected points: %v, err: %v", points, err)
+	}
+
+	// Databases may have no limit.
+	if points, err := l.Limit("unlimited", newPoints("x", "y", "z")); err != nil || len(points) != 3 {
+		t.Fatalf("unexpected points: %v, err: %v", points, err)
+	}
+
+	if n := l.Rejected(); n != 3 {
+		t.Fatalf("unexpected rejected: %d", n)
+	}
+}
+
+// SeriesCreationLimiterStore returns the same index for every database.
+type SeriesCreationLimiterStore struct {
+	index *tsdb.DatabaseIndex
+}
+
+func (s *SeriesCreationLimiterStore) DatabaseIndex(name string) *tsdb.DatabaseIndex {
+	return s.index
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"log"
 	"net"
@@ -18,6 +19,25 @@ import (
 // MaxMessageSize defines how large a message can be before we reject it
 const MaxMessageSize = 1024 * 1024 * 1024 // 1GB
 
+// tlvHeader is the first byte of every type-length-value record. The high
+// nibble is a magic number and the low nibble is the framing version so
+// corrupt streams and incompatible peers are detected before anything is
+// allocated.
+//
+// Nodes from before the header was added send records starting with the type
+// and without a checksum. Records are read in either framing, and records are
+// only written with the header once SetMessageChecksums is set.
+const tlvHeader = 0xC0 | 1
+
+// tlvChecksums is set when records are written with the header and checksum.
+var tlvChecksums bool
+
+// SetMessageChecksums sets whether records sent to other nodes are written
+// with a header byte and checksum. Older nodes can't read them, so it should
+// only be set once every node in the cluster reads both framings. It must be
+// set before any record is written, usually at startup.
+func SetMessageChecksums(v bool) { tlvChecksums = v }
+
 // MuxHeader is the header byte used in the TCP mux.
 const MuxHeader = 2
 
@@ -30,6 +50,10 @@ type Service struct {
 
 	Listener net.Listener
 
+	// HTTPListener, if set, accepts connections of nodes using the HTTP/2
+	// transport.
+	HTTPListener net.Listener
+
 	MetaStore interface {
 		ShardOwner(shardID uint64) (string, string, *meta.ShardGroupInfo)
 	}
@@ -38,16 +62,27 @@ type Service struct {
 		CreateShard(database, policy string, shardID uint64) error
 		WriteToShard(shardID uint64, points []tsdb.Point) error
 		CreateMapper(shardID uint64, query string, chunkSize int) (tsdb.Mapper, error)
+		ShardHasData(shardID uint64, query string) (bool, error)
+		DeleteSeries(database string, keys []string) error
 	}
 
+	// MaxMessageSize is the largest request accepted from a remote node.
+	MaxMessageSize int64
+
 	Logger *log.Logger
 }
 
 // NewService returns a new instance of Service.
 func NewService(c Config) *Service {
+	maxMessageSize := c.MaxMessageSize
+	if maxMessageSize <= 0 {
+		maxMessageSize = MaxMessageSize
+	}
+
 	return &Service{
-		closing: make(chan struct{}),
-		Logger:  log.New(os.Stderr, "[tcp] ", log.LstdFlags),
+		closing:        make(chan struct{}),
+		MaxMessageSize: maxMessageSize,
+		Logger:         log.New(os.Stderr, "[tcp] ", log.LstdFlags),
 	}
 }
 
@@ -59,6 +94,11 @@ func (s *Service) Open() error {
 	s.wg.Add(1)
 	go s.serve()
 
+	if s.HTTPListener != nil {
+		s.wg.Add(1)
+		go s.serveHTTP()
+	}
+
 	return nil
 }
 
@@ -104,6 +144,9 @@ func (s *Service) Close() error {
 	if s.Listener != nil {
 		s.Listener.Close()
 	}
+	if s.HTTPListener != nil {
+		s.HTTPListener.Close()
+	}
 
 	// Shut down all handlers.
 	close(s.closing)
@@ -129,47 +172,115 @@ func (s *Service) handleConn(conn net.Conn) {
 	defer func() {
 		s.Logger.Printf("close remote write connection from %v\n", conn.RemoteAddr())
 	}()
+	s.serveMessages(conn, conn.RemoteAddr().String())
+}
+
+// serveMessages processes the requests read from conn until it's closed. The
+// responses are written back to conn.
+func (s *Service) serveMessages(conn io.ReadWriter, remoteAddr string) {
 	for {
 		// Read type-length-value.
-		typ, buf, err := ReadTLV(conn)
+		typ, buf, legacy, err := readTLV(conn, s.MaxMessageSize)
 		if err != nil {
 			if strings.HasSuffix(err.Error(), "EOF") {
 				return
 			}
-			s.Logger.Printf("unable to read type-length-value %s", err)
+			s.Logger.Printf("unable to read type-length-value from %v: %s", remoteAddr, err)
 			return
 		}
 
+		// Respond in the framing of the request so older nodes can read it.
+		var w io.Writer = conn
+		if legacy {
+			w = legacyWriter{conn}
+		}
+
+		// Unwrap compressed messages.
+		if typ == compressedMessage {
+			if typ, buf, err = decodeCompressedMessage(buf); err != nil {
+				s.Logger.Printf("unable to decode compressed message: %s", err)
+				return
+			}
+		}
+
 		// Delegate message processing by type.
 		switch typ {
 		case writeShardRequestMessage:
-			err := s.processWriteShardRequest(buf)
+			var req WriteShardRequest
+			var statuses []ShardStatus
+			err := req.UnmarshalBinary(buf)
+			if err == nil {
+				statuses, err = s.processWriteShardRequest(&req)
+			}
 			if err != nil {
-				s.Logger.Printf("process write shard error: %s", err)
+				s.Logger.Printf("process write shard error: %s%s", err, traceSuffix(req.TraceID()))
 			}
-			s.writeShardResponse(conn, err)
+			s.writeShardResponse(w, statuses, err)
 		case mapShardRequestMessage:
-			err := s.processMapShardRequest(conn, buf)
+			var req MapShardRequest
+			err := req.UnmarshalBinary(buf)
+			if err == nil {
+				err = s.processMapShardRequest(w, &req)
+			}
 			if err != nil {
-				s.Logger.Printf("process map shard error: %s", err)
-				if err := writeMapShardResponseMessage(conn, NewMapShardResponse(1, err.Error())); err != nil {
-					s.Logger.Printf("process map shard error writing response: %s", err.Error())
+				s.Logger.Printf("process map shard error: %s%s", err, traceSuffix(req.TraceID()))
+				if err := writeMapShardResponseMessage(w, NewMapShardResponse(1, err.Error())); err != nil {
+					s.Logger.Printf("process map shard error writing response: %s%s", err.Error(), traceSuffix(req.TraceID()))
 				}
 			}
+		case dropSeriesRequestMessage:
+			var req DropSeriesRequest
+			err := req.UnmarshalBinary(buf)
+			if err == nil {
+				err = s.TSDBStore.DeleteSeries(req.Database(), req.SeriesKeys())
+			}
+			if err != nil {
+				s.Logger.Printf("process drop series error: %s", err)
+			}
+			s.dropSeriesResponse(w, err)
+		case shardHasDataRequestMessage:
+			var req ShardHasDataRequest
+			var ok bool
+			err := req.UnmarshalBinary(buf)
+			if err == nil {
+				ok, err = s.TSDBStore.ShardHasData(req.ShardID(), req.Query())
+			}
+			if err != nil {
+				s.Logger.Printf("process shard has data error: %s", err)
+			}
+			s.shardHasDataResponse(w, ok, err)
 		default:
 			s.Logger.Printf("cluster service message type not found: %d", typ)
 		}
 	}
 }
 
-func (s *Service) processWriteShardRequest(buf []byte) error {
-	// Build request
-	var req WriteShardRequest
-	if err := req.UnmarshalBinary(buf); err != nil {
-		return err
+// processWriteShardRequest writes the points of every shard in the request.
+// Requests with more than one shard return the status of each shard. The
+// returned error is the first shard write that failed.
+func (s *Service) processWriteShardRequest(req *WriteShardRequest) ([]ShardStatus, error) {
+	shards := req.Shards()
+	if len(shards) == 1 {
+		return nil, s.writeShard(shards[0].ShardID, shards[0].Points, req.TraceID())
+	}
+
+	var firstErr error
+	statuses := make([]ShardStatus, len(shards))
+	for i, sh := range shards {
+		statuses[i].ShardID = sh.ShardID
+		if err := s.writeShard(sh.ShardID, sh.Points, req.TraceID()); err != nil {
+			statuses[i].Code, statuses[i].Message = writeShardCode(err), err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
 	}
+	return statuses, firstErr
+}
 
-	err := s.TSDBStore.WriteToShard(req.ShardID(), req.Points())
+// writeShard writes points to a local shard.
+func (s *Service) writeShard(shardID uint64, points []tsdb.Point, traceID string) error {
+	err := s.TSDBStore.WriteToShard(shardID, points)
 
 	// We may have received a write for a shard that we don't have locally because the
 	// sending node may have just created the shard (via the metastore) and the write
@@ -179,38 +290,46 @@ func (s *Service) processWriteShardRequest(buf []byte) error {
 	if err == tsdb.ErrShardNotFound {
 
 		// Query the metastore for the owner of this shard
-		database, retentionPolicy, sgi := s.MetaStore.ShardOwner(req.ShardID())
+		database, retentionPolicy, sgi := s.MetaStore.ShardOwner(shardID)
 		if sgi == nil {
-			// If we can't find it, then we need to drop this request
+			// If we can't find it, then we need to reject this request
 			// as it is no longer valid.  This could happen if writes were queued via
-			// hinted handoff and delivered after a shard group was deleted.
-			s.Logger.Printf("drop write request: shard=%d", req.ShardID())
-			return nil
+			// hinted handoff and delivered after a shard group was deleted by the
+			// retention policy.
+			s.Logger.Printf("drop write request: shard=%d%s", shardID, traceSuffix(traceID))
+			return &tsdb.PointsRejectedError{Err: fmt.Errorf("write shard %d: %s", shardID, ErrShardGroupDeleted)}
 		}
 
-		err = s.TSDBStore.CreateShard(database, retentionPolicy, req.ShardID())
+		err = s.TSDBStore.CreateShard(database, retentionPolicy, shardID)
 		if err != nil {
 			return err
 		}
-		return s.TSDBStore.WriteToShard(req.ShardID(), req.Points())
+		return s.TSDBStore.WriteToShard(shardID, points)
 	}
 
-	if err != nil {
-		return fmt.Errorf("write shard %d: %s", req.ShardID(), err)
+	if err, ok := err.(*tsdb.PointsRejectedError); ok {
+		return &tsdb.PointsRejectedError{Err: fmt.Errorf("write shard %d: %s", shardID, err)}
+	} else if err != nil {
+		return fmt.Errorf("write shard %d: %s", shardID, err)
 	}
 
 	return nil
 }
 
-func (s *Service) writeShardResponse(w io.Writer, e error) {
+func (s *Service) writeShardResponse(w io.Writer, statuses []ShardStatus, e error) {
 	// Build response.
 	var resp WriteShardResponse
 	if e != nil {
-		resp.SetCode(1)
+		resp.SetCode(writeShardCode(e))
 		resp.SetMessage(e.Error())
 	} else {
 		resp.SetCode(0)
 	}
+	for _, st := range statuses {
+		resp.AddShardStatus(st.ShardID, st.Code, st.Message)
+	}
+	resp.SetCodecs(codecFlags())
+	resp.SetMultiShard(true)
 
 	// Marshal response to binary.
 	buf, err := resp.MarshalBinary()
@@ -225,13 +344,54 @@ func (s *Service) writeShardResponse(w io.Writer, e error) {
 	}
 }
 
-func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
-	// Decode request
-	var req MapShardRequest
-	if err := req.UnmarshalBinary(buf); err != nil {
-		return err
+func (s *Service) dropSeriesResponse(w io.Writer, e error) {
+	// Build response.
+	var resp DropSeriesResponse
+	if e != nil {
+		resp.SetCode(1)
+		resp.SetMessage(e.Error())
+	} else {
+		resp.SetCode(0)
+	}
+
+	// Marshal response to binary.
+	buf, err := resp.MarshalBinary()
+	if err != nil {
+		s.Logger.Printf("error marshalling drop series response: %s", err)
+		return
 	}
 
+	// Write to connection.
+	if err := WriteTLV(w, dropSeriesResponseMessage, buf); err != nil {
+		s.Logger.Printf("drop series response error: %s", err)
+	}
+}
+
+func (s *Service) shardHasDataResponse(w io.Writer, ok bool, e error) {
+	// Build response.
+	var resp ShardHasDataResponse
+	if e != nil {
+		resp.SetCode(1)
+		resp.SetMessage(e.Error())
+	} else {
+		resp.SetCode(0)
+		resp.SetHasData(ok)
+	}
+
+	// Marshal response to binary.
+	buf, err := resp.MarshalBinary()
+	if err != nil {
+		s.Logger.Printf("error marshalling shard has data response: %s", err)
+		return
+	}
+
+	// Write to connection.
+	if err := WriteTLV(w, shardHasDataResponseMessage, buf); err != nil {
+		s.Logger.Printf("shard has data response error: %s", err)
+	}
+}
+
+func (s *Service) processMapShardRequest(w io.Writer, req *MapShardRequest) error {
 	m, err := s.TSDBStore.CreateMapper(req.ShardID(), req.Query(), int(req.ChunkSize()))
 	if err != nil {
 		return fmt.Errorf("create mapper: %s", err)
@@ -246,8 +406,21 @@ func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 	defer m.Close()
 
 	var metaSent bool
+	var seq uint64
 	for {
+		chunk, err := m.NextChunk()
+		if err != nil {
+			return fmt.Errorf("next chunk: %s", err)
+		}
+
+		// Skip the chunks a resuming client has already received.
+		seq++
+		if chunk != nil && seq <= req.ResumeAfter() {
+			continue
+		}
+
 		var resp MapShardResponse
+		resp.SetSeq(seq)
 
 		if !metaSent {
 			resp.SetTagSets(m.TagSets())
@@ -255,11 +428,6 @@ func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 			metaSent = true
 		}
 
-		chunk, err := m.NextChunk()
-		if err != nil {
-			return fmt.Errorf("next chunk: %s", err)
-		}
-
 		// NOTE: Even if the chunk is nil, we still need to send one
 		// empty response to let the other side know we're out of data.
 
@@ -284,6 +452,15 @@ func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 	}
 }
 
+// traceSuffix returns the text appended to log lines about a request with the
+// given trace ID.
+func traceSuffix(traceID string) string {
+	if traceID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [trace:%s]", traceID)
+}
+
 func writeMapShardResponseMessage(w io.Writer, msg *MapShardResponse) error {
 	buf, err := msg.MarshalBinary()
 	if err != nil {
@@ -292,45 +469,115 @@ func writeMapShardResponseMessage(w io.Writer, msg *MapShardResponse) error {
 	return WriteTLV(w, mapShardResponseMessage, buf)
 }
 
-// ReadTLV reads a type-length-value record from r.
+// ProtocolError is returned when a type-length-value record is malformed,
+// such as when its header is unknown or its checksum doesn't match. The
+// stream can't be resynchronized so the connection should be closed.
+type ProtocolError struct {
+	Message string
+}
+
+// Error returns a string representation of the error.
+func (e *ProtocolError) Error() string { return "protocol error: " + e.Message }
+
+// ReadTLV reads a type-length-value record from r. Records larger than
+// MaxMessageSize are rejected.
 func ReadTLV(r io.Reader) (byte, []byte, error) {
-	var typ [1]byte
-	if _, err := io.ReadFull(r, typ[:]); err != nil {
-		return 0, nil, fmt.Errorf("read message type: %s", err)
-	}
+	return ReadTLVLimit(r, MaxMessageSize)
+}
 
-	// Read the size of the message.
-	var sz int64
-	if err := binary.Read(r, binary.BigEndian, &sz); err != nil {
-		return 0, nil, fmt.Errorf("read message size: %s", err)
+// ReadTLVLimit reads a type-length-value record from r. Records with a value
+// larger than max bytes are rejected before the value is read.
+//
+// A record is the header byte, the type, the big-endian int64 size and the
+// CRC-32 (IEEE) of the type and value followed by the value itself. Records
+// from older nodes are the type, the size and the value.
+func ReadTLVLimit(r io.Reader, max int64) (byte, []byte, error) {
+	typ, buf, _, err := readTLV(r, max)
+	return typ, buf, err
+}
+
+// readTLV reads a type-length-value record in either framing from r. Returns
+// true if the record had no header and checksum.
+func readTLV(r io.Reader, max int64) (typ byte, buf []byte, legacy bool, err error) {
+	var hdr [14]byte
+	if _, err := io.ReadFull(r, hdr[:1]); err != nil {
+		return 0, nil, false, fmt.Errorf("read message header: %s", err)
 	}
 
-	if sz == 0 {
-		return 0, nil, fmt.Errorf("invalid message size: %d", sz)
+	// Records without a header start with their type.
+	var sz int64
+	if hdr[0] >= writeShardRequestMessage && hdr[0] <= shardHasDataResponseMessage {
+		typ, legacy = hdr[0], true
+		if _, err := io.ReadFull(r, hdr[1:9]); err != nil {
+			return 0, nil, false, fmt.Errorf("read message header: %s", err)
+		}
+		sz = int64(binary.BigEndian.Uint64(hdr[1:9]))
+	} else if hdr[0] == tlvHeader {
+		if _, err := io.ReadFull(r, hdr[1:]); err != nil {
+			return 0, nil, false, fmt.Errorf("read message header: %s", err)
+		}
+		typ, sz = hdr[1], int64(binary.BigEndian.Uint64(hdr[2:10]))
+	} else {
+		return 0, nil, false, &ProtocolError{Message: fmt.Sprintf("invalid message header: %#x", hdr[0])}
 	}
 
-	if sz >= MaxMessageSize {
-		return 0, nil, fmt.Errorf("max message size of %d exceeded: %d", MaxMessageSize, sz)
+	// Check the size of the message.
+	if sz <= 0 {
+		return 0, nil, false, &ProtocolError{Message: fmt.Sprintf("invalid message size: %d", sz)}
+	} else if sz > max {
+		return 0, nil, false, &ProtocolError{Message: fmt.Sprintf("max message size of %d exceeded: %d", max, sz)}
 	}
 
-	// Read the value.
-	buf := make([]byte, sz)
+	// Read the value and verify it wasn't corrupted.
+	buf = make([]byte, sz)
 	if _, err := io.ReadFull(r, buf); err != nil {
-		return 0, nil, fmt.Errorf("read message value: %s", err)
+		return 0, nil, false, fmt.Errorf("read message value: %s", err)
+	} else if !legacy && checksum(typ, buf) != binary.BigEndian.Uint32(hdr[10:14]) {
+		return 0, nil, false, &ProtocolError{Message: "message checksum mismatch"}
 	}
 
-	return typ[0], buf, nil
+	return typ, buf, legacy, nil
 }
 
-// WriteTLV writes a type-length-value record to w.
+// legacyWriter writes records without the header and checksum, such as
+// responses to older nodes.
+type legacyWriter struct {
+	io.Writer
+}
+
+// WriteTLV writes a type-length-value record to w. The record only has a
+// header and checksum if SetMessageChecksums is set and w isn't a response to
+// an older node.
 func WriteTLV(w io.Writer, typ byte, buf []byte) error {
-	if _, err := w.Write([]byte{typ}); err != nil {
-		return fmt.Errorf("write message type: %s", err)
+	if _, ok := w.(legacyWriter); ok || !tlvChecksums {
+		return writeLegacyTLV(w, typ, buf)
+	}
+
+	var hdr [14]byte
+	hdr[0] = tlvHeader
+	hdr[1] = typ
+	binary.BigEndian.PutUint64(hdr[2:10], uint64(len(buf)))
+	binary.BigEndian.PutUint32(hdr[10:14], checksum(typ, buf))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return fmt.Errorf("write message header: %s", err)
 	}
 
-	// Write the size of the message.
-	if err := binary.Write(w, binary.BigEndian, int64(len(buf))); err != nil {
-		return fmt.Errorf("write message size: %s", err)
+	// Write the value.
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write message value: %s", err)
+	}
+
+	return nil
+}
+
+// writeLegacyTLV writes a type-length-value record without the header and
+// checksum to w.
+func writeLegacyTLV(w io.Writer, typ byte, buf []byte) error {
+	var hdr [9]byte
+	hdr[0] = typ
+	binary.BigEndian.PutUint64(hdr[1:9], uint64(len(buf)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return fmt.Errorf("write message header: %s", err)
 	}
 
 	// Write the value.
@@ -340,3 +587,46 @@ func WriteTLV(w io.Writer, typ byte, buf []byte) error {
 
 	return nil
 }
+
+// checksum returns the checksum of a record's type and value.
+func checksum(typ byte, buf []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE([]byte{typ}), crc32.IEEETable, buf)
+}
+
+// writeCompressedTLV writes a type-length-value record wrapped in a compressed
+// message. The value of the compressed message is the codec ID followed by the
+// compressed type and value of the original record.
+func writeCompressedTLV(w io.Writer, c tsdb.Codec, typ byte, buf []byte) error {
+	value := append([]byte{c.ID()}, c.Encode(append([]byte{typ}, buf...))...)
+	return WriteTLV(w, compressedMessage, value)
+}
+
+// decodeCompressedMessage returns the type and value of the record wrapped in
+// a compressed message.
+func decodeCompressedMessage(buf []byte) (byte, []byte, error) {
+	if len(buf) == 0 {
+		return 0, nil, fmt.Errorf("compressed message too short")
+	}
+
+	c := tsdb.CodecByID(buf[0])
+	if c == nil {
+		return 0, nil, fmt.Errorf("unknown codec: %d", buf[0])
+	}
+
+	b, err := c.Decode(buf[1:])
+	if err != nil {
+		return 0, nil, err
+	} else if len(b) == 0 {
+		return 0, nil, fmt.Errorf("compressed message missing type")
+	}
+	return b[0], b[1:], nil
+}
+
+// codecFlags returns the capability flags of the codecs this node can decode.
+func codecFlags() uint64 {
+	var flags uint64
+	for _, c := range tsdb.Codecs() {
+		flags |= 1 << c.ID()
+	}
+	return flags
+}
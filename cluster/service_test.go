@@ -1,8 +1,11 @@
 package cluster_test
 
 import (
+	"bytes"
 	"fmt"
 	"net"
+	"reflect"
+	"testing"
 	"time"
 
 	"github.com/influxdb/influxdb/cluster"
@@ -26,9 +29,12 @@ type testService struct {
 	nodeID           uint64
 	ln               net.Listener
 	muxln            net.Listener
+	httpln           net.Listener
 	writeShardFunc   func(shardID uint64, points []tsdb.Point) error
 	createShardFunc  func(database, policy string, shardID uint64) error
 	createMapperFunc func(shardID uint64, query string, chunkSize int) (tsdb.Mapper, error)
+	shardHasDataFunc func(shardID uint64, query string) (bool, error)
+	deleteSeriesFunc func(database string, keys []string) error
 }
 
 func newTestWriteService(f func(shardID uint64, points []tsdb.Point) error) testService {
@@ -39,12 +45,14 @@ func newTestWriteService(f func(shardID uint64, points []tsdb.Point) error) test
 
 	mux := tcp.NewMux()
 	muxln := mux.Listen(cluster.MuxHeader)
+	httpln := mux.Listen(cluster.HTTPMuxHeader)
 	go mux.Serve(ln)
 
 	return testService{
 		writeShardFunc: f,
 		ln:             ln,
 		muxln:          muxln,
+		httpln:         httpln,
 	}
 }
 
@@ -73,6 +81,14 @@ func (t testService) CreateMapper(shardID uint64, query string, chunkSize int) (
 	return t.createMapperFunc(shardID, query, chunkSize)
 }
 
+func (t testService) ShardHasData(shardID uint64, query string) (bool, error) {
+	return t.shardHasDataFunc(shardID, query)
+}
+
+func (t testService) DeleteSeries(database string, keys []string) error {
+	return t.deleteSeriesFunc(database, keys)
+}
+
 func writeShardSuccess(shardID uint64, points []tsdb.Point) error {
 	responses <- &serviceResponse{
 		shardID: shardID,
@@ -101,3 +117,157 @@ func (testService) ResponseN(n int) ([]*serviceResponse, error) {
 		}
 	}
 }
+
+// Ensure a type-length-value record can be written and read back in either
+// framing.
+func TestReadTLV(t *testing.T) {
+	defer cluster.SetMessageChecksums(false)
+
+	for _, checksums := range []bool{false, true} {
+		cluster.SetMessageChecksums(checksums)
+
+		var buf bytes.Buffer
+		if err := cluster.WriteTLV(&buf, 3, []byte("foo")); err != nil {
+			t.Fatal(err)
+		} else if n := buf.Len(); (checksums && n != 17) || (!checksums && n != 12) {
+			t.Fatalf("unexpected record size with checksums=%v: %d", checksums, n)
+		}
+
+		typ, value, err := cluster.ReadTLV(&buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if typ != 3 {
+			t.Fatalf("unexpected type: %d", typ)
+		} else if !reflect.DeepEqual(value, []byte("foo")) {
+			t.Fatalf("unexpected value: %q", value)
+		}
+	}
+}
+
+// Ensure malformed records are rejected with a protocol error.
+func TestReadTLVLimit_ProtocolError(t *testing.T) {
+	cluster.SetMessageChecksums(true)
+	defer cluster.SetMessageChecksums(false)
+
+	var buf bytes.Buffer
+	if err := cluster.WriteTLV(&buf, 3, []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	record := buf.Bytes()
+
+	for i, tt := range []struct {
+		record []byte
+		max    int64
+		err    string
+	}{
+		// Oversized value.
+		{record: record, max: 5, err: "protocol error: max message size of 5 exceeded: 6"},
+
+		// Unknown header.
+		{record: append([]byte{0x7f}, record[1:]...), max: 10, err: "protocol error: invalid message header: 0x7f"},
+
+		// Corrupt value.
+		{record: append(append([]byte{}, record[:len(record)-1]...), 'z'), max: 10, err: "protocol error: message checksum mismatch"},
+
+		// Corrupt type.
+		{record: append([]byte{record[0], 4}, record[2:]...), max: 10, err: "protocol error: message checksum mismatch"},
+	} {
+		_, _, err := cluster.ReadTLVLimit(bytes.NewReader(tt.record), tt.max)
+		if _, ok := err.(*cluster.ProtocolError); !ok {
+			t.Errorf("%d. expected protocol error: %v", i, err)
+		} else if err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %s", i, err)
+		}
+	}
+}
+
+// Ensure requests from older nodes, without a header and checksum, are
+// answered the same way.
+func TestService_LegacyFraming(t *testing.T) {
+	cluster.SetMessageChecksums(true)
+	defer cluster.SetMessageChecksums(false)
+
+	ts := newTestWriteService(nil)
+	ts.deleteSeriesFunc = func(database string, keys []string) error { return nil }
+
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = ts
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var req cluster.DropSeriesRequest
+	req.SetDatabase("db0")
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Send a drop series request as the type, size and value.
+	record := []byte{cluster.MuxHeader, 5, 0, 0, 0, 0, 0, 0, 0, byte(len(buf))}
+	if _, err := conn.Write(append(record, buf...)); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	hdr := make([]byte, 1)
+	if _, err := conn.Read(hdr); err != nil {
+		t.Fatal(err)
+	} else if hdr[0] != 6 {
+		t.Fatalf("unexpected response header: %#x", hdr[0])
+	}
+}
+
+// Ensure the shard mapper asks a remote node whether a shard has data.
+func TestShardMapper_ShardHasData(t *testing.T) {
+	ts := newTestWriteService(nil)
+	var shardID uint64
+	var query string
+	ts.shardHasDataFunc = func(id uint64, q string) (bool, error) {
+		shardID, query = id, q
+		return q == "SELECT value FROM cpu", nil
+	}
+
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = ts
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	m := cluster.NewShardMapper(time.Minute)
+	m.MetaStore = &remoteMetaStore{metaStore: metaStore{host: ts.ln.Addr().String()}}
+	sh := meta.ShardInfo{ID: 3, OwnerIDs: []uint64{2}}
+
+	if ok, err := m.ShardHasData(sh, "SELECT value FROM cpu"); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected data")
+	} else if shardID != 3 || query != "SELECT value FROM cpu" {
+		t.Fatalf("unexpected request: %d %s", shardID, query)
+	}
+
+	if ok, err := m.ShardHasData(sh, "SELECT value FROM mem"); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("expected no data")
+	}
+}
+
+// remoteMetaStore is a metaStore for a node that owns no shards.
+type remoteMetaStore struct {
+	metaStore
+}
+
+func (m *remoteMetaStore) NodeID() uint64 { return 1 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/influxdb/influxdb/meta"
@@ -25,22 +26,38 @@ type ShardMapper struct {
 
 	TSDBStore interface {
 		CreateMapper(shardID uint64, query string, chunkSize int) (tsdb.Mapper, error)
+		ShardHasData(shardID uint64, query string) (bool, error)
 	}
 
+	// Transport is TransportHTTP2 to map remote shards over HTTP/2 streams
+	// instead of pooled TCP connections.
+	Transport string
+
 	timeout time.Duration
 	pool    *clientPool
+	http    *httpClient
+
+	mu        sync.Mutex
+	noHasData map[uint64]bool // nodes that don't answer ShardHasData requests
 }
 
 // NewShardMapper returns a mapper of local and remote shards.
 func NewShardMapper(timeout time.Duration) *ShardMapper {
 	return &ShardMapper{
 		pool:    newClientPool(),
+		http:    newHTTPClient(timeout),
 		timeout: timeout,
 	}
 }
 
 // CreateMapper returns a Mapper for the given shard ID.
 func (s *ShardMapper) CreateMapper(sh meta.ShardInfo, stmt string, chunkSize int) (tsdb.Mapper, error) {
+	return s.CreateMapperWithTrace(sh, stmt, chunkSize, "")
+}
+
+// CreateMapperWithTrace returns a Mapper for the given shard ID. The trace ID
+// is sent to the remote node if the shard isn't mapped locally.
+func (s *ShardMapper) CreateMapperWithTrace(sh meta.ShardInfo, stmt string, chunkSize int, traceID string) (tsdb.Mapper, error) {
 	m, err := s.TSDBStore.CreateMapper(sh.ID, stmt, chunkSize)
 	if err != nil {
 		return nil, err
@@ -48,42 +65,160 @@ func (s *ShardMapper) CreateMapper(sh meta.ShardInfo, stmt string, chunkSize int
 
 	if !sh.OwnedBy(s.MetaStore.NodeID()) || s.ForceRemoteMapping {
 		// Pick a node in a pseudo-random manner.
-		conn, err := s.dial(sh.OwnerIDs[rand.Intn(len(sh.OwnerIDs))])
+		nodeID := sh.OwnerIDs[rand.Intn(len(sh.OwnerIDs))]
+		conn, err := s.dialNode(nodeID)
 		if err != nil {
 			return nil, err
 		}
-		conn.SetDeadline(time.Now().Add(s.timeout))
 
-		m.SetRemote(NewRemoteMapper(conn.(*pool.PoolConn), sh.ID, stmt, chunkSize))
+		// Resume from the same node since other owners may not produce the
+		// same chunks, such as while a replica is still being written.
+		rm := NewRemoteMapper(conn, sh.ID, stmt, chunkSize)
+		rm.TraceID = traceID
+		rm.dial = func() (remoteShardConn, error) { return s.dialNode(nodeID) }
+		m.SetRemote(rm)
 	}
 
 	return m, nil
 }
 
+// ShardHasData returns false if the shard has none of the measurements queried
+// by stmt, so it can be skipped before paying for a mapper. Remote shards are
+// checked by one of their owners with a single request. Shards whose owner
+// can't answer, such as nodes running an older version, are assumed to have data.
+func (s *ShardMapper) ShardHasData(sh meta.ShardInfo, stmt string) (bool, error) {
+	if sh.OwnedBy(s.MetaStore.NodeID()) && !s.ForceRemoteMapping {
+		return s.TSDBStore.ShardHasData(sh.ID, stmt)
+	}
+
+	nodeID := sh.OwnerIDs[rand.Intn(len(sh.OwnerIDs))]
+	if s.hasDataUnsupported(nodeID) {
+		return true, nil
+	}
+
+	conn, err := s.conn(nodeID)
+	if err != nil {
+		return true, nil
+	}
+	defer conn.Close() // return to pool
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	var req ShardHasDataRequest
+	req.SetShardID(sh.ID)
+	req.SetQuery(stmt)
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	if err := WriteTLV(conn, shardHasDataRequestMessage, buf); err != nil {
+		conn.MarkUnusable()
+		return true, nil
+	}
+
+	_, buf, err = ReadTLV(conn)
+	if err != nil {
+		// Nodes that don't know the request never answer it.
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			s.setHasDataUnsupported(nodeID)
+		}
+		conn.MarkUnusable()
+		return true, nil
+	}
+
+	var resp ShardHasDataResponse
+	if err := resp.UnmarshalBinary(buf); err != nil {
+		return false, err
+	} else if resp.Code() != 0 {
+		return false, fmt.Errorf("error code %d: %s", resp.Code(), resp.Message())
+	}
+	return resp.HasData(), nil
+}
+
+func (s *ShardMapper) hasDataUnsupported(nodeID uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.noHasData[nodeID]
+}
+
+func (s *ShardMapper) setHasDataUnsupported(nodeID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.noHasData == nil {
+		s.noHasData = make(map[uint64]bool)
+	}
+	s.noHasData[nodeID] = true
+}
+
+// dialNode connects to an owner of a shard for a map request.
+func (s *ShardMapper) dialNode(nodeID uint64) (remoteShardConn, error) {
+	conn, err := s.conn(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	return conn, nil
+}
+
+// conn returns a connection to a node for a single request: a stream if the
+// transport is HTTP/2 or a connection from the node's pool.
+func (s *ShardMapper) conn(nodeID uint64) (clusterConn, error) {
+	if s.Transport == TransportHTTP2 {
+		st, err := s.http.open(s.MetaStore, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+
+	c, err := s.dial(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*pool.PoolConn), nil
+}
+
 func (s *ShardMapper) dial(nodeID uint64) (net.Conn, error) {
 	// If we don't have a connection pool for that addr yet, create one
 	_, ok := s.pool.getPool(nodeID)
 	if !ok {
 		factory := &connFactory{nodeID: nodeID, clientPool: s.pool, timeout: s.timeout}
 		factory.metaStore = s.MetaStore
+		factory.conns = newNodeConns()
 
 		p, err := pool.NewChannelPool(1, 3, factory.dial)
 		if err != nil {
 			return nil, err
 		}
-		s.pool.setPool(nodeID, p)
+		s.pool.setPool(nodeID, p, factory.conns)
 	}
 	return s.pool.conn(nodeID)
 }
 
+// EvictStaleNodes closes the connections to nodes that aren't in nodes anymore
+// or whose address has changed. Remote mappers reading from them fail with
+// ErrTopologyChanged, or resume if the node is still reachable.
+func (s *ShardMapper) EvictStaleNodes(nodes []meta.NodeInfo) {
+	s.pool.evictStale(nodes)
+}
+
 type remoteShardConn interface {
 	io.ReadWriter
 	Close() error
 	MarkUnusable()
 }
 
+// maxMapperResumeAttempts is the number of times a RemoteMapper tries to
+// reconnect after its connection breaks while reading chunks.
+const maxMapperResumeAttempts = 3
+
 // RemoteMapper implements the tsdb.Mapper interface. It connects to a remote node,
 // sends a query, and interprets the stream of data that comes back.
+//
+// Every chunk sent by the remote node is numbered. If the connection breaks
+// while chunks are being read, the mapper reconnects to the same node and asks
+// it to continue after the last chunk it received. Other owners of the shard
+// aren't used since their chunks may differ, such as when a replica is behind.
 type RemoteMapper struct {
 	shardID   uint64
 	stmt      string
@@ -92,8 +227,15 @@ type RemoteMapper struct {
 	tagsets []string
 	fields  []string
 
+	// TraceID is sent with the request to tag the remote node's log lines.
+	TraceID string
+
 	conn             remoteShardConn
 	bufferedResponse *MapShardResponse
+	seq              uint64 // sequence number of the last chunk returned
+
+	// dial reconnects to the node of conn. Resuming is disabled if nil.
+	dial func() (remoteShardConn, error)
 }
 
 // NewRemoteMapper returns a new remote mapper using the given connection.
@@ -113,46 +255,100 @@ func (r *RemoteMapper) Open() (err error) {
 			r.conn.Close()
 		}
 	}()
+
+	r.bufferedResponse, err = r.request(r.conn, 0)
+	if err != nil {
+		return err
+	}
+
+	// Decode the first response to get the TagSets.
+	r.tagsets = r.bufferedResponse.TagSets()
+	r.fields = r.bufferedResponse.Fields()
+
+	return nil
+}
+
+// request sends the map request over conn and returns the first response.
+// The remote node skips all chunks up to and including resumeAfter.
+func (r *RemoteMapper) request(conn remoteShardConn, resumeAfter uint64) (*MapShardResponse, error) {
 	// Build Map request.
 	var request MapShardRequest
 	request.SetShardID(r.shardID)
 	request.SetQuery(r.stmt)
 	request.SetChunkSize(int32(r.chunkSize))
+	if r.TraceID != "" {
+		request.SetTraceID(r.TraceID)
+	}
+	if resumeAfter > 0 {
+		request.SetResumeAfter(resumeAfter)
+	}
 
 	// Marshal into protocol buffers.
 	buf, err := request.MarshalBinary()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Write request.
-	if err := WriteTLV(r.conn, mapShardRequestMessage, buf); err != nil {
-		r.conn.MarkUnusable()
-		return err
+	if err := WriteTLV(conn, mapShardRequestMessage, buf); err != nil {
+		conn.MarkUnusable()
+		return nil, connError(conn, err)
 	}
 
+	return readMapShardResponse(conn)
+}
+
+// readMapShardResponse reads a single response from conn.
+func readMapShardResponse(conn remoteShardConn) (*MapShardResponse, error) {
 	// Read the response.
-	_, buf, err = ReadTLV(r.conn)
+	_, buf, err := ReadTLV(conn)
 	if err != nil {
-		r.conn.MarkUnusable()
-		return err
+		conn.MarkUnusable()
+		return nil, connError(conn, err)
+	}
+	return decodeMapShardResponse(buf)
+}
+
+// decodeMapShardResponse unmarshals a response and returns any remote error.
+func decodeMapShardResponse(buf []byte) (*MapShardResponse, error) {
+	response := &MapShardResponse{}
+	if err := response.UnmarshalBinary(buf); err != nil {
+		return nil, err
 	}
 
-	// Unmarshal response.
-	r.bufferedResponse = &MapShardResponse{}
-	if err := r.bufferedResponse.UnmarshalBinary(buf); err != nil {
-		return err
+	if response.Code() != 0 {
+		return nil, fmt.Errorf("error code %d: %s", response.Code(), response.Message())
 	}
 
-	if r.bufferedResponse.Code() != 0 {
-		return fmt.Errorf("error code %d: %s", r.bufferedResponse.Code(), r.bufferedResponse.Message())
+	return response, nil
+}
+
+// resume reconnects to the remote node and returns the first response
+// after the last chunk received. cause is the error that broke the connection.
+func (r *RemoteMapper) resume(cause error) (*MapShardResponse, error) {
+	if r.dial == nil {
+		return nil, cause
 	}
+	r.conn.Close()
 
-	// Decode the first response to get the TagSets.
-	r.tagsets = r.bufferedResponse.TagSets()
-	r.fields = r.bufferedResponse.Fields()
+	for i := 0; i < maxMapperResumeAttempts; i++ {
+		conn, err := r.dial()
+		if err != nil {
+			cause = err
+			continue
+		}
 
-	return nil
+		response, err := r.request(conn, r.seq)
+		if err != nil {
+			conn.Close()
+			cause = err
+			continue
+		}
+		r.conn = conn
+		return response, nil
+	}
+
+	return nil, fmt.Errorf("resume after chunk %d: %s", r.seq, cause)
 }
 
 func (r *RemoteMapper) SetRemote(m tsdb.Mapper) error {
@@ -174,24 +370,18 @@ func (r *RemoteMapper) NextChunk() (chunk interface{}, err error) {
 		response = r.bufferedResponse
 		r.bufferedResponse = nil
 	} else {
-		response = &MapShardResponse{}
-
-		// Read the response.
 		_, buf, err := ReadTLV(r.conn)
 		if err != nil {
+			// The connection broke, continue from another owner.
 			r.conn.MarkUnusable()
+			if response, err = r.resume(connError(r.conn, err)); err != nil {
+				return nil, err
+			}
+		} else if response, err = decodeMapShardResponse(buf); err != nil {
 			return nil, err
 		}
-
-		// Unmarshal response.
-		if err := response.UnmarshalBinary(buf); err != nil {
-			return nil, err
-		}
-
-		if response.Code() != 0 {
-			return nil, fmt.Errorf("error code %d: %s", response.Code(), response.Message())
-		}
 	}
+	r.seq = response.Seq()
 
 	if response.Data() == nil {
 		return nil, nil
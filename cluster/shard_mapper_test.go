@@ -3,9 +3,13 @@ package cluster
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
+	"reflect"
 	"testing"
 
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -18,16 +22,26 @@ type remoteShardResponder struct {
 }
 
 func newRemoteShardResponder(outputs []*tsdb.MapperOutput, tagsets []string) *remoteShardResponder {
+	return newSequencedShardResponder(outputs, tagsets, 1)
+}
+
+// newSequencedShardResponder returns a responder whose responses are numbered
+// from seq. The connection breaks once all the outputs have been read.
+func newSequencedShardResponder(outputs []*tsdb.MapperOutput, tagsets []string, seq uint64) *remoteShardResponder {
 	r := &remoteShardResponder{}
 	a := make([]byte, 0, 1024)
 	r.buffer = bytes.NewBuffer(a)
 
 	// Pump the outputs in the buffer for later reading.
-	for _, o := range outputs {
+	for i, o := range outputs {
 		resp := &MapShardResponse{}
 		resp.SetCode(0)
+		resp.SetSeq(seq + uint64(i))
 		if o != nil {
-			d, _ := json.Marshal(o)
+			d, err := json.Marshal(o)
+			if err != nil {
+				panic(err)
+			}
 			resp.SetData(d)
 			resp.SetTagSets(tagsets)
 		}
@@ -45,7 +59,7 @@ func (r remoteShardResponder) Read(p []byte) (n int, err error) {
 	return io.ReadFull(r.buffer, p)
 }
 
-func (r remoteShardResponder) Write(p []byte) (n int, err error) {
+func (r *remoteShardResponder) Write(p []byte) (n int, err error) {
 	if r.rxBytes == nil {
 		r.rxBytes = make([]byte, 0)
 	}
@@ -98,3 +112,107 @@ func TestShardWriter_RemoteMapper_Success(t *testing.T) {
 		t.Fatal("received more chunks when none expected")
 	}
 }
+
+// Ensure a RemoteMapper resumes from the last received chunk when its connection breaks.
+func TestShardWriter_RemoteMapper_Resume(t *testing.T) {
+	outputs := []*tsdb.MapperOutput{{Name: "cpu"}, {Name: "mem"}}
+
+	// The first connection breaks after the first chunk.
+	c0 := newSequencedShardResponder(outputs[:1], []string{"tagsetA"}, 1)
+	c1 := newSequencedShardResponder([]*tsdb.MapperOutput{outputs[1], nil}, []string{"tagsetA"}, 2)
+
+	r := NewRemoteMapper(c0, 1234, "SELECT * FROM CPU", 10)
+	r.dial = func() (remoteShardConn, error) { return c1, nil }
+	if err := r.Open(); err != nil {
+		t.Fatalf("failed to open remote mapper: %s", err.Error())
+	}
+
+	for _, exp := range outputs {
+		chunk, err := r.NextChunk()
+		if err != nil {
+			t.Fatalf("failed to get next chunk from mapper: %s", err.Error())
+		}
+		output := &tsdb.MapperOutput{}
+		if err := json.Unmarshal(chunk.([]byte), output); err != nil {
+			t.Fatal(err)
+		} else if output.Name != exp.Name {
+			t.Fatalf("unexpected output: exp %s, got %s", exp.Name, output.Name)
+		}
+	}
+
+	if chunk, err := r.NextChunk(); err != nil {
+		t.Fatalf("failed to get next chunk from mapper: %s", err.Error())
+	} else if chunk != nil {
+		t.Fatal("received more chunks when none expected")
+	}
+
+	// The second request should skip the chunk already received.
+	var req MapShardRequest
+	if _, buf, err := ReadTLV(bytes.NewReader(c1.rxBytes)); err != nil {
+		t.Fatal(err)
+	} else if err := req.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if req.ResumeAfter() != 1 {
+		t.Fatalf("unexpected resume sequence: %d", req.ResumeAfter())
+	}
+}
+
+// Ensure a RemoteMapper fails once it can't reconnect.
+func TestShardWriter_RemoteMapper_ResumeFailed(t *testing.T) {
+	c := newSequencedShardResponder([]*tsdb.MapperOutput{{Name: "cpu"}}, nil, 1)
+
+	var n int
+	r := NewRemoteMapper(c, 1234, "SELECT * FROM CPU", 10)
+	r.dial = func() (remoteShardConn, error) { n++; return nil, errors.New("connection refused") }
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	} else if _, err := r.NextChunk(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.NextChunk(); err == nil || err.Error() != "resume after chunk 1: connection refused" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != maxMapperResumeAttempts {
+		t.Fatalf("unexpected dial count: %d", n)
+	}
+}
+
+// Ensure histograms with an unbounded bucket can be received from a remote shard.
+func TestShardWriter_RemoteMapper_Histogram(t *testing.T) {
+	h := influxql.Histogram{{UpperBound: 0.1, Count: 2}, {UpperBound: math.Inf(1), Count: 1}}
+	c := newRemoteShardResponder([]*tsdb.MapperOutput{{
+		Name:   "cpu",
+		Values: []*tsdb.MapperValue{{Value: []interface{}{h}}},
+	}, nil}, []string{"tagsetA"})
+
+	r := NewRemoteMapper(c, 1234, "SELECT histogram_merge(latency) FROM cpu", 10)
+	if err := r.Open(); err != nil {
+		t.Fatalf("failed to open remote mapper: %s", err.Error())
+	}
+	chunk, err := r.NextChunk()
+	if err != nil {
+		t.Fatalf("failed to get next chunk from mapper: %s", err.Error())
+	}
+
+	// Decode the output as the local mapper does.
+	var output struct {
+		Values []struct {
+			Value []json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(chunk.([]byte), &output); err != nil {
+		t.Fatal(err)
+	} else if len(output.Values) != 1 || len(output.Values[0].Value) != 1 {
+		t.Fatalf("unexpected output: %s", chunk)
+	}
+
+	unmarshal, err := influxql.InitializeUnmarshaller(&influxql.Call{Name: "histogram_merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := unmarshal(output.Values[0].Value[0]); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(v, h) {
+		t.Fatalf("unexpected histogram: %v", v)
+	}
+}
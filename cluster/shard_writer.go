@@ -3,9 +3,11 @@ package cluster
 import (
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/retry"
 	"github.com/influxdb/influxdb/tsdb"
 	"gopkg.in/fatih/pool.v2"
 )
@@ -15,13 +17,58 @@ const (
 	writeShardResponseMessage
 	mapShardRequestMessage
 	mapShardResponseMessage
+	dropSeriesRequestMessage
+	dropSeriesResponseMessage
+	compressedMessage
+	shardHasDataRequestMessage
+	shardHasDataResponseMessage
 )
 
+// Write shard response codes.
+const (
+	writeShardFailed   = 1 // the write failed and may succeed if retried
+	writeShardRejected = 2 // the points were rejected and would be again
+)
+
+// writeShardCode returns the response code of a shard write error.
+func writeShardCode(err error) int {
+	if _, ok := err.(*tsdb.PointsRejectedError); ok {
+		return writeShardRejected
+	}
+	return writeShardFailed
+}
+
+// writeShardError returns the error of a shard write response code.
+func writeShardError(code int, message string) error {
+	err := fmt.Errorf("error code %d: %s", code, message)
+	if code == writeShardRejected {
+		return &tsdb.PointsRejectedError{Err: err}
+	}
+	return err
+}
+
 // ShardWriter writes a set of points to a shard.
 type ShardWriter struct {
 	pool    *clientPool
+	http    *httpClient
 	timeout time.Duration
 
+	// Transport is TransportHTTP2 to send requests over HTTP/2 streams
+	// instead of pooled TCP connections.
+	Transport string
+
+	// Codec compresses write requests to nodes that can decode it. Requests
+	// are sent uncompressed until a node responds with its capability flags.
+	Codec tsdb.Codec
+
+	mu         sync.Mutex
+	codecs     map[uint64]uint64 // capability flags by node id
+	multiShard map[uint64]bool   // nodes accepting requests with multiple shards
+
+	// RetryPolicy resends requests that fail to reach the node. Requests
+	// aren't retried when it's nil.
+	RetryPolicy *retry.Policy
+
 	MetaStore interface {
 		Node(id uint64) (ni *meta.NodeInfo, err error)
 	}
@@ -31,40 +78,144 @@ type ShardWriter struct {
 func NewShardWriter(timeout time.Duration) *ShardWriter {
 	return &ShardWriter{
 		pool:    newClientPool(),
+		http:    newHTTPClient(timeout),
 		timeout: timeout,
 	}
 }
 
+// NewShardWriterRetryPolicy returns the policy retrying writes to remote
+// nodes from the cluster configuration. Returns nil if retries are disabled.
+// Retries share a budget and stop once the shard writer timeout has elapsed.
+func NewShardWriterRetryPolicy(c Config) *retry.Policy {
+	if c.ShardWriterRetries <= 0 {
+		return nil
+	}
+	return &retry.Policy{
+		InitialInterval: 50 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Jitter:          retry.DefaultJitter,
+		MaxAttempts:     c.ShardWriterRetries + 1,
+		MaxElapsed:      time.Duration(c.ShardWriterTimeout),
+		Budget:          retry.NewBudget(c.ShardWriterRetryBudget, 10),
+	}
+}
+
+// WriteShard writes points to a shard on a remote node.
 func (w *ShardWriter) WriteShard(shardID, ownerID uint64, points []tsdb.Point) error {
-	c, err := w.dial(ownerID)
+	return w.WriteShardWithTrace(shardID, ownerID, points, "")
+}
+
+// WriteShardWithTrace writes points to a shard on a remote node. The trace ID
+// is included in the remote node's log lines about the write.
+func (w *ShardWriter) WriteShardWithTrace(shardID, ownerID uint64, points []tsdb.Point, traceID string) error {
+	// Build write request.
+	var request WriteShardRequest
+	request.SetShardID(shardID)
+	request.AddPoints(points)
+	if traceID != "" {
+		request.SetTraceID(traceID)
+	}
+
+	response, err := w.writeShardRequest(ownerID, &request)
 	if err != nil {
 		return err
 	}
 
-	conn, ok := c.(*pool.PoolConn)
-	if !ok {
-		panic("wrong connection type")
+	if response.Code() != 0 {
+		return writeShardError(response.Code(), response.Message())
+	}
+
+	return nil
+}
+
+// WriteShards writes points to several shards owned by a remote node in a
+// single request and returns the error of each shard, in order. Nodes that
+// haven't yet responded that they accept requests with multiple shards are
+// sent one request per shard.
+func (w *ShardWriter) WriteShards(ownerID uint64, shards []ShardPoints, traceID string) []error {
+	errs := make([]error, len(shards))
+	if !w.nodeMultiShard(ownerID) {
+		for i, sh := range shards {
+			errs[i] = w.WriteShardWithTrace(sh.ShardID, ownerID, sh.Points, traceID)
+		}
+		return errs
 	}
-	defer func(conn net.Conn) {
-		conn.Close() // return to pool
-	}(conn)
 
-	// Build write request.
 	var request WriteShardRequest
-	request.SetShardID(shardID)
-	request.AddPoints(points)
+	for _, sh := range shards {
+		request.AddShard(sh.ShardID, sh.Points)
+	}
+	if traceID != "" {
+		request.SetTraceID(traceID)
+	}
+
+	response, err := w.writeShardRequest(ownerID, &request)
+	if err == nil && response.Code() != 0 && len(response.ShardStatuses()) == 0 {
+		err = writeShardError(response.Code(), response.Message())
+	}
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
 
+	// Match the shard statuses to the shards of the request.
+	statuses := make(map[uint64]ShardStatus)
+	for _, st := range response.ShardStatuses() {
+		statuses[st.ShardID] = st
+	}
+	for i, sh := range shards {
+		if st, ok := statuses[sh.ShardID]; !ok {
+			errs[i] = fmt.Errorf("no status for shard %d", sh.ShardID)
+		} else if st.Code != 0 {
+			errs[i] = writeShardError(st.Code, st.Message)
+		}
+	}
+	return errs
+}
+
+// writeShardRequest sends a write request to a remote node and returns its
+// response. Requests that fail to reach the node, or whose response is lost,
+// are sent again under the retry policy. Resending is safe since writing the
+// same points twice stores them once.
+func (w *ShardWriter) writeShardRequest(ownerID uint64, request *WriteShardRequest) (*WriteShardResponse, error) {
 	// Marshal into protocol buffers.
 	buf, err := request.MarshalBinary()
 	if err != nil {
+		return nil, err
+	}
+
+	if w.RetryPolicy == nil {
+		return w.sendWriteShardRequest(ownerID, buf)
+	}
+
+	var response *WriteShardResponse
+	err = w.RetryPolicy.Do(func() (err error) {
+		response, err = w.sendWriteShardRequest(ownerID, buf)
 		return err
+	})
+	return response, err
+}
+
+// sendWriteShardRequest sends a marshaled write request to a remote node.
+func (w *ShardWriter) sendWriteShardRequest(ownerID uint64, buf []byte) (*WriteShardResponse, error) {
+	conn, err := w.conn(ownerID)
+	if err != nil {
+		return nil, err
 	}
+	defer conn.Close() // return to pool
 
-	// Write request.
+	// Write request, compressed if the node can decode it.
 	conn.SetWriteDeadline(time.Now().Add(w.timeout))
-	if err := WriteTLV(conn, writeShardRequestMessage, buf); err != nil {
+	if w.Codec != nil && w.nodeCodecs(ownerID)&(1<<w.Codec.ID()) != 0 {
+		err = writeCompressedTLV(conn, w.Codec, writeShardRequestMessage, buf)
+	} else {
+		err = WriteTLV(conn, writeShardRequestMessage, buf)
+	}
+	if err != nil {
 		conn.MarkUnusable()
-		return err
+		return nil, connError(conn, err)
 	}
 
 	// Read the response.
@@ -72,11 +223,52 @@ func (w *ShardWriter) WriteShard(shardID, ownerID uint64, points []tsdb.Point) e
 	_, buf, err = ReadTLV(conn)
 	if err != nil {
 		conn.MarkUnusable()
-		return err
+		return nil, connError(conn, err)
 	}
 
 	// Unmarshal response.
 	var response WriteShardResponse
+	if err := response.UnmarshalBinary(buf); err != nil {
+		return nil, retry.Fatal(err)
+	}
+	w.setNodeCodecs(ownerID, response.Codecs(), response.MultiShard())
+
+	return &response, nil
+}
+
+// DropSeries drops series from every shard of a database on a remote node.
+func (w *ShardWriter) DropSeries(ownerID uint64, database string, keys []string) error {
+	conn, err := w.conn(ownerID)
+	if err != nil {
+		return err
+	}
+	defer conn.Close() // return to pool
+
+	// Build and marshal the request.
+	var request DropSeriesRequest
+	request.SetDatabase(database)
+	request.SetSeriesKeys(keys)
+	buf, err := request.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	// Write request.
+	conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	if err := WriteTLV(conn, dropSeriesRequestMessage, buf); err != nil {
+		conn.MarkUnusable()
+		return connError(conn, err)
+	}
+
+	// Read the response.
+	conn.SetReadDeadline(time.Now().Add(w.timeout))
+	_, buf, err = ReadTLV(conn)
+	if err != nil {
+		conn.MarkUnusable()
+		return connError(conn, err)
+	}
+
+	var response DropSeriesResponse
 	if err := response.UnmarshalBinary(buf); err != nil {
 		return err
 	}
@@ -88,28 +280,90 @@ func (w *ShardWriter) WriteShard(shardID, ownerID uint64, points []tsdb.Point) e
 	return nil
 }
 
+// nodeCodecs returns the capability flags of the codecs a node can decode.
+func (w *ShardWriter) nodeCodecs(nodeID uint64) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.codecs[nodeID]
+}
+
+// nodeMultiShard returns true if a node accepts requests with multiple shards.
+func (w *ShardWriter) nodeMultiShard(nodeID uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.multiShard[nodeID]
+}
+
+// setNodeCodecs sets the capability flags of the codecs a node can decode and
+// whether it accepts requests with multiple shards.
+func (w *ShardWriter) setNodeCodecs(nodeID, flags uint64, multiShard bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.codecs == nil {
+		w.codecs = make(map[uint64]uint64)
+		w.multiShard = make(map[uint64]bool)
+	}
+	w.codecs[nodeID] = flags
+	w.multiShard[nodeID] = multiShard
+}
+
+// conn returns a connection to a node for a single request: a stream if the
+// transport is HTTP/2 or a connection from the node's pool.
+func (w *ShardWriter) conn(nodeID uint64) (clusterConn, error) {
+	if w.Transport == TransportHTTP2 {
+		s, err := w.http.open(w.MetaStore, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	c, err := w.dial(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := c.(*pool.PoolConn)
+	if !ok {
+		panic("wrong connection type")
+	}
+	return conn, nil
+}
+
 func (c *ShardWriter) dial(nodeID uint64) (net.Conn, error) {
 	// If we don't have a connection pool for that addr yet, create one
 	_, ok := c.pool.getPool(nodeID)
 	if !ok {
 		factory := &connFactory{nodeID: nodeID, clientPool: c.pool, timeout: c.timeout}
 		factory.metaStore = c.MetaStore
+		factory.conns = newNodeConns()
 
 		p, err := pool.NewChannelPool(1, 3, factory.dial)
 		if err != nil {
 			return nil, err
 		}
-		c.pool.setPool(nodeID, p)
+		c.pool.setPool(nodeID, p, factory.conns)
 	}
 	return c.pool.conn(nodeID)
 }
 
+// EvictStaleNodes closes the connections to nodes that aren't in nodes anymore
+// or whose address has changed. Writes in flight to them fail with
+// ErrTopologyChanged instead of waiting for the timeout.
+func (w *ShardWriter) EvictStaleNodes(nodes []meta.NodeInfo) {
+	if w.pool != nil {
+		w.pool.evictStale(nodes)
+	}
+}
+
 func (w *ShardWriter) Close() error {
 	if w.pool == nil {
 		return fmt.Errorf("client already closed")
 	}
 	w.pool.close()
 	w.pool = nil
+	if w.http != nil {
+		w.http.close()
+	}
 	return nil
 }
 
@@ -131,6 +385,9 @@ type connFactory struct {
 	metaStore interface {
 		Node(id uint64) (ni *meta.NodeInfo, err error)
 	}
+
+	// conns tracks the dialed connections so they can be closed in use.
+	conns *nodeConns
 }
 
 func (c *connFactory) dial() (net.Conn, error) {
@@ -159,5 +416,5 @@ func (c *connFactory) dial() (net.Conn, error) {
 		return nil, err
 	}
 
-	return conn, nil
+	return c.conns.add(conn, ni.Host)
 }
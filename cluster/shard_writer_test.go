@@ -1,12 +1,17 @@
 package cluster_test
 
 import (
+	"bytes"
+	"errors"
+	"log"
 	"net"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -28,7 +33,7 @@ func TestShardWriter_WriteShard_Success(t *testing.T) {
 	// Build a single point.
 	now := time.Now()
 	var points []tsdb.Point
-	points = append(points, tsdb.NewPoint("cpu", tsdb.Tags{"host": "server01"}, map[string]interface{}{"value": int64(100)}, now))
+	points = append(points, tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, now))
 
 	// Write to shard and close.
 	if err := w.WriteShard(1, 2, points); err != nil {
@@ -50,8 +55,8 @@ func TestShardWriter_WriteShard_Success(t *testing.T) {
 		t.Fatalf("unexpected name: %s", p.Name())
 	} else if p.Fields()["value"] != int64(100) {
 		t.Fatalf("unexpected 'value' field: %d", p.Fields()["value"])
-	} else if p.Tags()["host"] != "server01" {
-		t.Fatalf("unexpected 'host' tag: %s", p.Tags()["host"])
+	} else if p.Tags().Get("host") != "server01" {
+		t.Fatalf("unexpected 'host' tag: %s", p.Tags().Get("host"))
 	} else if p.Time().UnixNano() != now.UnixNano() {
 		t.Fatalf("unexpected time: %s", p.Time())
 	}
@@ -75,7 +80,7 @@ func TestShardWriter_WriteShard_Multiple(t *testing.T) {
 	// Build a single point.
 	now := time.Now()
 	var points []tsdb.Point
-	points = append(points, tsdb.NewPoint("cpu", tsdb.Tags{"host": "server01"}, map[string]interface{}{"value": int64(100)}, now))
+	points = append(points, tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, now))
 
 	// Write to shard twice and close.
 	if err := w.WriteShard(1, 2, points); err != nil {
@@ -99,8 +104,8 @@ func TestShardWriter_WriteShard_Multiple(t *testing.T) {
 		t.Fatalf("unexpected name: %s", p.Name())
 	} else if p.Fields()["value"] != int64(100) {
 		t.Fatalf("unexpected 'value' field: %d", p.Fields()["value"])
-	} else if p.Tags()["host"] != "server01" {
-		t.Fatalf("unexpected 'host' tag: %s", p.Tags()["host"])
+	} else if p.Tags().Get("host") != "server01" {
+		t.Fatalf("unexpected 'host' tag: %s", p.Tags().Get("host"))
 	} else if p.Time().UnixNano() != now.UnixNano() {
 		t.Fatalf("unexpected time: %s", p.Time())
 	}
@@ -126,7 +131,7 @@ func TestShardWriter_WriteShard_Error(t *testing.T) {
 	ownerID := uint64(2)
 	var points []tsdb.Point
 	points = append(points, tsdb.NewPoint(
-		"cpu", tsdb.Tags{"host": "server01"}, map[string]interface{}{"value": int64(100)}, now,
+		"cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, now,
 	))
 
 	if err := w.WriteShard(shardID, ownerID, points); err == nil || err.Error() != "error code 1: write shard 1: failed to write" {
@@ -134,6 +139,142 @@ func TestShardWriter_WriteShard_Error(t *testing.T) {
 	}
 }
 
+// Ensure the shard writer compresses requests once the node says it can decode them.
+func TestShardWriter_WriteShard_Compressed(t *testing.T) {
+	var values []interface{}
+	ts := newTestWriteService(func(shardID uint64, points []tsdb.Point) error {
+		values = append(values, points[0].Fields()["value"])
+		return nil
+	})
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = ts
+
+	var buf bytes.Buffer
+	s.SetLogger(log.New(&buf, "", 0))
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.MetaStore = &metaStore{host: ts.ln.Addr().String()}
+	w.Codec = tsdb.CodecByName("snappy")
+
+	// The first request is uncompressed, the second one is compressed.
+	for i := 0; i < 2; i++ {
+		points := []tsdb.Point{tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(i)}, time.Unix(0, int64(i)))}
+		if err := w.WriteShard(1, 2, points); err != nil {
+			t.Fatalf("%d. %s", i, err)
+		}
+	}
+	w.Close()
+
+	if !reflect.DeepEqual(values, []interface{}{int64(0), int64(1)}) {
+		t.Fatalf("unexpected values: %v", values)
+	} else if strings.Contains(buf.String(), "error") {
+		t.Fatalf("unexpected log output: %s", buf.String())
+	}
+}
+
+// Ensure the shard writer writes several shards in one request once the node
+// says it accepts them, and returns the error of each shard.
+func TestShardWriter_WriteShards(t *testing.T) {
+	var writes []uint64
+	ts := newTestWriteService(func(shardID uint64, points []tsdb.Point) error {
+		writes = append(writes, shardID)
+		if shardID == 3 {
+			return errors.New("failed to write")
+		}
+		return nil
+	})
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = ts
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.MetaStore = &metaStore{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	points := []tsdb.Point{tsdb.NewPoint("cpu", nil, map[string]interface{}{"value": int64(100)}, time.Unix(0, 0))}
+	shards := []cluster.ShardPoints{{ShardID: 1, Points: points}, {ShardID: 2, Points: points}, {ShardID: 3, Points: points}}
+
+	// The node's capabilities are unknown so the first write sends one request
+	// per shard and the second one sends a single request.
+	for i := 0; i < 2; i++ {
+		errs := w.WriteShards(2, shards, "")
+		if len(errs) != 3 || errs[0] != nil || errs[1] != nil {
+			t.Fatalf("%d. unexpected errors: %v", i, errs)
+		} else if errs[2] == nil || errs[2].Error() != "error code 1: write shard 3: failed to write" {
+			t.Fatalf("%d. unexpected error: %v", i, errs[2])
+		}
+	}
+
+	if !reflect.DeepEqual(writes, []uint64{1, 2, 3, 1, 2, 3}) {
+		t.Fatalf("unexpected writes: %v", writes)
+	}
+}
+
+// Ensure a write request with several shards round trips through its binary format.
+func TestWriteShardRequest_Shards(t *testing.T) {
+	var req cluster.WriteShardRequest
+	req.AddShard(1, []tsdb.Point{tsdb.NewPoint("cpu", nil, map[string]interface{}{"value": int64(1)}, time.Unix(0, 0))})
+	req.AddShard(2, []tsdb.Point{tsdb.NewPoint("mem", nil, map[string]interface{}{"value": int64(2)}, time.Unix(0, 0))})
+
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other cluster.WriteShardRequest
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	shards := other.Shards()
+	if len(shards) != 2 {
+		t.Fatalf("unexpected shard count: %d", len(shards))
+	} else if shards[0].ShardID != 1 || shards[0].Points[0].Name() != "cpu" {
+		t.Fatalf("unexpected shard: %d %v", shards[0].ShardID, shards[0].Points)
+	} else if shards[1].ShardID != 2 || shards[1].Points[0].Name() != "mem" {
+		t.Fatalf("unexpected shard: %d %v", shards[1].ShardID, shards[1].Points)
+	}
+}
+
+// Ensure the remote node logs errors with the trace ID of the write.
+func TestShardWriter_WriteShardWithTrace(t *testing.T) {
+	ts := newTestWriteService(writeShardFail)
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = ts
+
+	var buf bytes.Buffer
+	s.SetLogger(log.New(&buf, "", 0))
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.MetaStore = &metaStore{host: ts.ln.Addr().String()}
+
+	points := []tsdb.Point{tsdb.NewPoint("cpu", nil, map[string]interface{}{"value": int64(100)}, time.Now())}
+	if err := w.WriteShardWithTrace(1, 2, points, "abc123"); err == nil {
+		t.Fatal("expected error")
+	}
+	w.Close()
+
+	if exp := "process write shard error: write shard 1: failed to write [trace:abc123]"; !strings.Contains(buf.String(), exp) {
+		t.Fatalf("expected log line %q, got: %s", exp, buf.String())
+	}
+}
+
 // Ensure the shard writer returns an error when dialing times out.
 func TestShardWriter_Write_ErrDialTimeout(t *testing.T) {
 	ts := newTestWriteService(writeShardSuccess)
@@ -154,7 +295,7 @@ func TestShardWriter_Write_ErrDialTimeout(t *testing.T) {
 	ownerID := uint64(2)
 	var points []tsdb.Point
 	points = append(points, tsdb.NewPoint(
-		"cpu", tsdb.Tags{"host": "server01"}, map[string]interface{}{"value": int64(100)}, now,
+		"cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, now,
 	))
 
 	if err, exp := w.WriteShard(shardID, ownerID, points), "i/o timeout"; err == nil || !strings.Contains(err.Error(), exp) {
@@ -177,10 +318,84 @@ func TestShardWriter_Write_ErrReadTimeout(t *testing.T) {
 	ownerID := uint64(2)
 	var points []tsdb.Point
 	points = append(points, tsdb.NewPoint(
-		"cpu", tsdb.Tags{"host": "server01"}, map[string]interface{}{"value": int64(100)}, now,
+		"cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, now,
 	))
 
 	if err := w.WriteShard(shardID, ownerID, points); err == nil || !strings.Contains(err.Error(), "i/o timeout") {
 		t.Fatalf("unexpected error: %s", err)
 	}
 }
+
+// Ensure a write in flight is aborted when its node moves to another address.
+func TestShardWriter_Write_ErrTopologyChanged(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.MetaStore = &metaStore{host: ln.Addr().String()}
+	points := []tsdb.Point{tsdb.NewPoint(
+		"cpu", tsdb.NewTags(map[string]string{"host": "server01"}), map[string]interface{}{"value": int64(100)}, time.Now(),
+	)}
+
+	// The listener never responds so the write waits until it's aborted.
+	errc := make(chan error, 1)
+	go func() { errc <- w.WriteShard(1, 2, points) }()
+
+	nodes := []meta.NodeInfo{{ID: 2, Host: "127.0.0.1:1"}}
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case err := <-errc:
+			if err != cluster.ErrTopologyChanged {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		case <-timeout:
+			t.Fatal("write wasn't aborted")
+		case <-time.After(10 * time.Millisecond):
+			w.EvictStaleNodes(nodes)
+		}
+	}
+}
+
+// Ensure the shard writer can drop series on a remote node.
+func TestShardWriter_DropSeries(t *testing.T) {
+	ts := newTestWriteService(nil)
+	var database string
+	var keys []string
+	var deleteErr error
+	ts.deleteSeriesFunc = func(db string, k []string) error {
+		database, keys = db, k
+		return deleteErr
+	}
+
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = ts
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.MetaStore = &metaStore{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	if err := w.DropSeries(2, "db0", []string{"cpu,host=a", "cpu,host=b"}); err != nil {
+		t.Fatal(err)
+	} else if database != "db0" {
+		t.Fatalf("unexpected database: %s", database)
+	} else if !reflect.DeepEqual(keys, []string{"cpu,host=a", "cpu,host=b"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	// Errors on the remote node are returned.
+	deleteErr = errors.New("marker")
+	if err := w.DropSeries(2, "db0", []string{"cpu,host=a"}); err == nil || !strings.Contains(err.Error(), "marker") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// FoldedTagsField is the string field holding the tags folded out of a point,
+// in the form "key1=value1,key2=value2" of series keys.
+const FoldedTagsField = "_tags"
+
+// TagLimitPolicy controls what happens to points with more tags than their
+// database allows.
+type TagLimitPolicy string
+
+const (
+	// TagLimitPolicyReject fails the write.
+	TagLimitPolicyReject TagLimitPolicy = "reject"
+
+	// TagLimitPolicyFold keeps the most important tags and moves the others
+	// into the FoldedTagsField of the point.
+	TagLimitPolicyFold TagLimitPolicy = "fold"
+)
+
+// ParseTagLimitPolicy parses a policy name. A blank name is "reject".
+func ParseTagLimitPolicy(s string) (TagLimitPolicy, error) {
+	switch p := TagLimitPolicy(strings.ToLower(s)); p {
+	case "":
+		return TagLimitPolicyReject, nil
+	case TagLimitPolicyReject, TagLimitPolicyFold:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid tag limit policy: %q", s)
+	}
+}
+
+// TagLimiter caps the number of tags of written points, which protects the
+// index from agents attaching dozens of auto-discovered labels.
+type TagLimiter struct {
+	// Default is the limit for databases without their own limit.
+	// No limit when zero.
+	Default int
+
+	// Limits holds the limit for each database that overrides the default.
+	Limits map[string]int
+
+	// Policy is applied to points over the limit.
+	Policy TagLimitPolicy
+
+	// Priority holds the tag keys kept first when folding, most important
+	// first. Other tags are kept by key.
+	Priority []string
+}
+
+// TagLimiterStats counts what a TagLimiter did to the points of a write.
+type TagLimiterStats struct {
+	Folded   int // points whose extra tags were folded into a field
+	Rejected int // points that caused the write to fail
+}
+
+// NewTagLimiter returns a new TagLimiter from the cluster configuration.
+func NewTagLimiter(c Config) (*TagLimiter, error) {
+	p, err := ParseTagLimitPolicy(c.TagLimitPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	tl := &TagLimiter{
+		Default:  c.TagLimit,
+		Limits:   make(map[string]int),
+		Policy:   p,
+		Priority: c.TagPriority,
+	}
+	for db, n := range c.TagLimits {
+		if n < 0 {
+			return nil, fmt.Errorf("database %s: invalid tag limit: %d", db, n)
+		}
+		tl.Limits[db] = n
+	}
+	return tl, nil
+}
+
+// Limit returns the tag limit for a database.
+func (l *TagLimiter) Limit(database string) int {
+	if n, ok := l.Limits[database]; ok {
+		return n
+	}
+	return l.Default
+}
+
+// Check applies the policy to the points of a database over its limit.
+// Folded points are updated in place. Returns what was done to the points and
+// an error if the write should fail.
+func (l *TagLimiter) Check(database string, points []tsdb.Point) (TagLimiterStats, error) {
+	var stats TagLimiterStats
+	limit := l.Limit(database)
+	if limit <= 0 {
+		return stats, nil
+	}
+
+	for _, p := range points {
+		var n int
+		p.ForEachTag(func(_, _ []byte) bool { n++; return true })
+		if n <= limit {
+			continue
+		}
+
+		if l.Policy == TagLimitPolicyFold {
+			l.fold(p, limit)
+			stats.Folded++
+			continue
+		}
+		stats.Rejected++
+	}
+
+	if stats.Rejected > 0 {
+		return stats, fmt.Errorf("%s: %d points with more than %d tags in database %s",
+			influxdb.ErrTooManyTags, stats.Rejected, limit, database)
+	}
+	return stats, nil
+}
+
+// fold keeps the limit most important tags of a point and moves the others
+// into its FoldedTagsField.
+func (l *TagLimiter) fold(p tsdb.Point, limit int) {
+	tags := p.Tags()
+
+	// Order the tags by priority. The sort is stable so the tags without a
+	// priority stay sorted by key.
+	ordered := append(tsdb.Tags(nil), tags...)
+	sort.Stable(tagsByPriority{tags: ordered, priority: l.Priority})
+
+	kept := append(tsdb.Tags(nil), ordered[:limit]...)
+	folded := append(tsdb.Tags(nil), ordered[limit:]...)
+	sort.Sort(kept)
+	sort.Sort(folded)
+
+	p.SetTags(kept)
+	p.AddField(FoldedTagsField, strings.TrimPrefix(string(folded.HashKey()), ","))
+}
+
+// tagsByPriority sorts tags by the position of their key in priority. Tags
+// whose key isn't in priority sort last.
+type tagsByPriority struct {
+	tags     tsdb.Tags
+	priority []string
+}
+
+func (a tagsByPriority) Len() int           { return len(a.tags) }
+func (a tagsByPriority) Less(i, j int) bool { return a.rank(a.tags[i].Key) < a.rank(a.tags[j].Key) }
+func (a tagsByPriority) Swap(i, j int)      { a.tags[i], a.tags[j] = a.tags[j], a.tags[i] }
+
+// rank returns the position of key in priority.
+func (a tagsByPriority) rank(key string) int {
+	for i, k := range a.priority {
+		if k == key {
+			return i
+		}
+	}
+	return len(a.priority)
+}
@@ -0,0 +1,71 @@
+package cluster_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensures points over the tag limit of their database are rejected or folded.
+func TestTagLimiter_Check(t *testing.T) {
+	c := cluster.NewConfig()
+	c.TagLimit = 3
+	c.TagLimits = map[string]int{"agents": 2, "unlimited": 0}
+	c.TagPriority = []string{"region", "host"}
+	tl, err := cluster.NewTagLimiter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPoints := func() []tsdb.Point {
+		tags := tsdb.NewTags(map[string]string{"a": "1", "b": "x,y", "host": "serverA", "region": "uswest"})
+		return []tsdb.Point{
+			tsdb.NewPoint("cpu", tags[:2], tsdb.Fields{"value": 1.0}, time.Unix(0, 0)),
+			tsdb.NewPoint("cpu", tags, tsdb.Fields{"value": 1.0}, time.Unix(0, 0)),
+		}
+	}
+
+	// The default limit rejects the point with four tags.
+	if s, err := tl.Check("db0", newPoints()); err == nil || !influxdb.IsClientError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !strings.Contains(err.Error(), "1 points with more than 3 tags") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if s != (cluster.TagLimiterStats{Rejected: 1}) {
+		t.Fatalf("unexpected stats: %#v", s)
+	}
+
+	// Databases may have no limit.
+	if _, err := tl.Check("unlimited", newPoints()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Folding keeps the tags with a priority first.
+	tl.Policy = cluster.TagLimitPolicyFold
+	points := newPoints()
+	if s, err := tl.Check("agents", points); err != nil {
+		t.Fatal(err)
+	} else if s != (cluster.TagLimiterStats{Folded: 1}) {
+		t.Fatalf("unexpected stats: %#v", s)
+	}
+	if got, exp := string(points[0].Key()), "cpu,a=1,b=x\\,y"; got != exp {
+		t.Fatalf("unexpected key:\n got %s\n exp %s", got, exp)
+	}
+	if got, exp := string(points[1].Key()), "cpu,host=serverA,region=uswest"; got != exp {
+		t.Fatalf("unexpected key:\n got %s\n exp %s", got, exp)
+	} else if got, exp := points[1].Fields()[cluster.FoldedTagsField], "a=1,b=x\\,y"; got != exp {
+		t.Fatalf("unexpected folded tags:\n got %v\n exp %s", got, exp)
+	}
+}
+
+// Ensures invalid tag limit policies are rejected.
+func TestNewTagLimiter_InvalidPolicy(t *testing.T) {
+	c := cluster.NewConfig()
+	c.TagLimitPolicy = "drop"
+	if _, err := cluster.NewTagLimiter(c); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,244 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// TimestampPolicy controls what happens to points whose timestamps are likely
+// in the wrong precision, such as seconds written as nanoseconds.
+type TimestampPolicy string
+
+const (
+	// TimestampPolicyAccept writes the points unchanged and only counts them.
+	TimestampPolicyAccept TimestampPolicy = "accept"
+
+	// TimestampPolicyFix rescales the timestamps into the plausible range.
+	// Points that can't be rescaled are rejected.
+	TimestampPolicyFix TimestampPolicy = "fix"
+
+	// TimestampPolicyReject fails the write.
+	TimestampPolicyReject TimestampPolicy = "reject"
+)
+
+var (
+	// DefaultMinPlausibleTime is the earliest timestamp considered plausible.
+	DefaultMinPlausibleTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// DefaultMaxPlausibleTime is the latest timestamp considered plausible.
+	DefaultMaxPlausibleTime = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// ParseTimestampPolicy parses a policy name.
+func ParseTimestampPolicy(s string) (TimestampPolicy, error) {
+	switch p := TimestampPolicy(strings.ToLower(s)); p {
+	case TimestampPolicyAccept, TimestampPolicyFix, TimestampPolicyReject:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid timestamp policy: %q", s)
+	}
+}
+
+// TimestampChecker finds points with timestamps outside of a plausible range
+// and applies a per-database policy to them.
+type TimestampChecker struct {
+	// Default is the policy for databases without their own policy.
+	Default TimestampPolicy
+
+	// Policies holds the policy for each database that overrides the default.
+	Policies map[string]TimestampPolicy
+
+	// MinTime and MaxTime bound the plausible timestamps.
+	MinTime time.Time
+	MaxTime time.Time
+}
+
+// TimestampCheckerStats counts what a TimestampChecker did to the points of a write.
+type TimestampCheckerStats struct {
+	Flagged  int // points outside the plausible range
+	Fixed    int // points rescaled into the plausible range
+	Rejected int // points that caused the write to fail
+}
+
+// NewTimestampChecker returns a new TimestampChecker from the cluster configuration.
+func NewTimestampChecker(c Config) (*TimestampChecker, error) {
+	tc := &TimestampChecker{
+		Default:  TimestampPolicyAccept,
+		Policies: make(map[string]TimestampPolicy),
+		MinTime:  DefaultMinPlausibleTime,
+		MaxTime:  DefaultMaxPlausibleTime,
+	}
+
+	if c.TimestampPolicy != "" {
+		p, err := ParseTimestampPolicy(c.TimestampPolicy)
+		if err != nil {
+			return nil, err
+		}
+		tc.Default = p
+	}
+
+	for db, s := range c.TimestampPolicies {
+		p, err := ParseTimestampPolicy(s)
+		if err != nil {
+			return nil, fmt.Errorf("database %s: %s", db, err)
+		}
+		tc.Policies[db] = p
+	}
+
+	return tc, nil
+}
+
+// Policy returns the policy for a database.
+func (c *TimestampChecker) Policy(database string) TimestampPolicy {
+	if p, ok := c.Policies[database]; ok {
+		return p
+	}
+	return c.Default
+}
+
+// Check applies the database's policy to points with implausible timestamps.
+// Fixed points are updated in place. Returns what was done to the points and
+// an error if the write should fail.
+func (c *TimestampChecker) Check(database string, points []tsdb.Point) (TimestampCheckerStats, error) {
+	min, max := c.MinTime.UnixNano(), c.MaxTime.UnixNano()
+	policy := c.Policy(database)
+
+	var stats TimestampCheckerStats
+	for _, p := range points {
+		ts := p.UnixNano()
+		if ts >= min && ts < max {
+			continue
+		}
+		stats.Flagged++
+
+		switch policy {
+		case TimestampPolicyFix:
+			if fixed, ok := rescaleTimestamp(ts, min, max); ok {
+				p.SetTime(time.Unix(0, fixed))
+				stats.Fixed++
+				continue
+			}
+			stats.Rejected++
+		case TimestampPolicyReject:
+			stats.Rejected++
+		}
+	}
+
+	if stats.Rejected > 0 {
+		return stats, fmt.Errorf("%s: %d points outside %s to %s, check the write precision",
+			influxdb.ErrImplausibleTimestamp, stats.Rejected, c.MinTime.Format(time.RFC3339), c.MaxTime.Format(time.RFC3339))
+	}
+	return stats, nil
+}
+
+// FutureSkewPolicy controls what happens to points with timestamps too far
+// ahead of the server's clock.
+type FutureSkewPolicy string
+
+const (
+	// FutureSkewPolicyReject fails the write.
+	FutureSkewPolicyReject FutureSkewPolicy = "reject"
+
+	// FutureSkewPolicyClamp moves the timestamps back to the latest allowed time.
+	FutureSkewPolicyClamp FutureSkewPolicy = "clamp"
+)
+
+// ParseFutureSkewPolicy parses a policy name. A blank name is "reject".
+func ParseFutureSkewPolicy(s string) (FutureSkewPolicy, error) {
+	switch p := FutureSkewPolicy(strings.ToLower(s)); p {
+	case "":
+		return FutureSkewPolicyReject, nil
+	case FutureSkewPolicyReject, FutureSkewPolicyClamp:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid future skew policy: %q", s)
+	}
+}
+
+// FutureSkewChecker limits how far ahead of the server's clock point
+// timestamps may be. Agents with skewed clocks otherwise create shard groups
+// far in the future that aren't removed by the retention policy for years.
+type FutureSkewChecker struct {
+	// MaxSkew is how far after the current time a timestamp may be.
+	MaxSkew time.Duration
+
+	// Policy is applied to points after the maximum skew.
+	Policy FutureSkewPolicy
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// FutureSkewCheckerStats counts what a FutureSkewChecker did to the points of a write.
+type FutureSkewCheckerStats struct {
+	Clamped  int // points moved back to the latest allowed time
+	Rejected int // points that caused the write to fail
+}
+
+// NewFutureSkewChecker returns a new FutureSkewChecker from the cluster configuration.
+func NewFutureSkewChecker(c Config) (*FutureSkewChecker, error) {
+	p, err := ParseFutureSkewPolicy(c.FutureSkewPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return &FutureSkewChecker{
+		MaxSkew: time.Duration(c.MaxFutureSkew),
+		Policy:  p,
+		Now:     time.Now,
+	}, nil
+}
+
+// Check applies the policy to points after the maximum skew. Clamped points
+// are updated in place. Returns what was done to the points and an error if
+// the write should fail.
+func (c *FutureSkewChecker) Check(points []tsdb.Point) (FutureSkewCheckerStats, error) {
+	latest := c.Now().Add(c.MaxSkew)
+	max := latest.UnixNano()
+
+	var stats FutureSkewCheckerStats
+	for _, p := range points {
+		if p.UnixNano() <= max {
+			continue
+		}
+
+		if c.Policy == FutureSkewPolicyClamp {
+			p.SetTime(latest)
+			stats.Clamped++
+			continue
+		}
+		stats.Rejected++
+	}
+
+	if stats.Rejected > 0 {
+		return stats, fmt.Errorf("%s: %d points after %s, check the client's clock",
+			influxdb.ErrFutureTimestamp, stats.Rejected, latest.UTC().Format(time.RFC3339))
+	}
+	return stats, nil
+}
+
+// rescaleTimestamp multiplies or divides ts by powers of 1000 until it falls
+// between min and max. Returns false if no precision fits.
+func rescaleTimestamp(ts, min, max int64) (int64, bool) {
+	for _, m := range []int64{1e3, 1e6, 1e9} {
+		var v int64
+		if ts < min {
+			// Skip multipliers that would overflow.
+			if ts > math.MaxInt64/m || ts < math.MinInt64/m {
+				continue
+			}
+			v = ts * m
+		} else {
+			v = ts / m
+		}
+
+		if v >= min && v < max {
+			return v, true
+		}
+	}
+	return 0, false
+}
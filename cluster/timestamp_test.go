@@ -0,0 +1,127 @@
+package cluster_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/toml"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensures each policy is applied to points with implausible timestamps.
+func TestTimestampChecker_Check(t *testing.T) {
+	c := cluster.NewConfig()
+	c.TimestampPolicy = "reject"
+	c.TimestampPolicies = map[string]string{"fixdb": "fix", "acceptdb": "accept"}
+	tc, err := cluster.NewTimestampChecker(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC)
+	newPoints := func() []tsdb.Point {
+		return []tsdb.Point{
+			tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now),
+			// Seconds written as nanoseconds.
+			tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, time.Unix(0, now.Unix())),
+			// Milliseconds written as nanoseconds.
+			tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, time.Unix(0, now.UnixNano()/int64(time.Millisecond))),
+		}
+	}
+
+	// The default policy rejects the write.
+	if s, err := tc.Check("db0", newPoints()); err == nil || !influxdb.IsClientError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !strings.Contains(err.Error(), "2 points") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if s != (cluster.TimestampCheckerStats{Flagged: 2, Rejected: 2}) {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	// Accepted points are left as-is.
+	points := newPoints()
+	if s, err := tc.Check("acceptdb", points); err != nil {
+		t.Fatal(err)
+	} else if s != (cluster.TimestampCheckerStats{Flagged: 2}) {
+		t.Fatalf("unexpected stats: %+v", s)
+	} else if points[1].UnixNano() != now.Unix() {
+		t.Fatalf("unexpected time: %v", points[1].Time())
+	}
+
+	// Fixed points are rescaled to nanoseconds.
+	points = newPoints()
+	if s, err := tc.Check("fixdb", points); err != nil {
+		t.Fatal(err)
+	} else if s != (cluster.TimestampCheckerStats{Flagged: 2, Fixed: 2}) {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	for i, p := range points {
+		if !p.Time().Equal(now) {
+			t.Fatalf("%d. unexpected time: %v", i, p.Time())
+		}
+	}
+}
+
+// Ensures invalid policies are rejected.
+func TestNewTimestampChecker_InvalidPolicy(t *testing.T) {
+	c := cluster.NewConfig()
+	c.TimestampPolicies = map[string]string{"db0": "ignore"}
+	if _, err := cluster.NewTimestampChecker(c); err == nil || err.Error() != `database db0: invalid timestamp policy: "ignore"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensures points too far in the future are rejected or clamped.
+func TestFutureSkewChecker_Check(t *testing.T) {
+	now := time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC)
+	newPoints := func() []tsdb.Point {
+		return []tsdb.Point{
+			tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now.Add(time.Hour)),
+			tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now.Add(time.Hour+1)),
+			tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now.AddDate(10, 0, 0)),
+		}
+	}
+
+	c := cluster.NewConfig()
+	c.MaxFutureSkew = toml.Duration(time.Hour)
+	fc, err := cluster.NewFutureSkewChecker(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.Now = func() time.Time { return now }
+
+	// Points are rejected by default.
+	if s, err := fc.Check(newPoints()); err == nil || !influxdb.IsClientError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !strings.Contains(err.Error(), "2 points after 2015-07-01T01:00:00Z") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if s != (cluster.FutureSkewCheckerStats{Rejected: 2}) {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+
+	// Clamped points are moved back to the latest allowed time.
+	fc.Policy = cluster.FutureSkewPolicyClamp
+	points := newPoints()
+	if s, err := fc.Check(points); err != nil {
+		t.Fatal(err)
+	} else if s != (cluster.FutureSkewCheckerStats{Clamped: 2}) {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	for i, p := range points {
+		if !p.Time().Equal(now.Add(time.Hour)) {
+			t.Fatalf("%d. unexpected time: %v", i, p.Time())
+		}
+	}
+}
+
+// Ensures invalid future skew policies are rejected.
+func TestNewFutureSkewChecker_InvalidPolicy(t *testing.T) {
+	c := cluster.NewConfig()
+	c.FutureSkewPolicy = "ignore"
+	if _, err := cluster.NewFutureSkewChecker(c); err == nil || err.Error() != `invalid future skew policy: "ignore"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"errors"
+	"time"
+)
+
+// HTTPMuxHeader is the header byte of connections using the HTTP/2 transport
+// in the TCP mux.
+const HTTPMuxHeader = 6
+
+const (
+	// TransportTCP sends requests to other nodes over pooled TCP connections.
+	TransportTCP = "tcp"
+
+	// TransportHTTP2 sends each request to other nodes over its own HTTP/2
+	// stream. The streams to a node are multiplexed over a single connection
+	// with the flow control of HTTP/2.
+	TransportHTTP2 = "http2"
+)
+
+// clusterConn is a connection to a remote node: a pooled TCP connection or an
+// HTTP/2 stream.
+type clusterConn interface {
+	remoteShardConn
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// ErrHTTP2NotSupported is returned when the HTTP/2 transport is used by a
+// binary built without the "http2" build tag. The transport requires a newer
+// Go than the release builds use.
+var ErrHTTP2NotSupported = errors.New("cluster HTTP/2 transport not supported: rebuild with -tags http2")
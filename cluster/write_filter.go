@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// WriteFilter drops points whose measurement or series key matches a
+// deny-list. It's meant to shut off a misbehaving producer without changes on
+// the client side, so dropped points don't fail the write.
+type WriteFilter struct {
+	measurements []*writeFilterRule
+	series       []*writeFilterRule
+}
+
+// writeFilterRule is a single deny pattern and the number of points it dropped.
+type writeFilterRule struct {
+	re      *regexp.Regexp
+	dropped uint64
+}
+
+// WriteFilterStat is the number of points dropped by a deny pattern.
+type WriteFilterStat struct {
+	Type    string // "measurement" or "series"
+	Pattern string
+	Dropped uint64
+}
+
+// NewWriteFilter returns a new WriteFilter from the cluster configuration.
+func NewWriteFilter(c Config) (*WriteFilter, error) {
+	f := &WriteFilter{}
+	for _, s := range c.DenyMeasurements {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("deny measurement %q: %s", s, err)
+		}
+		f.measurements = append(f.measurements, &writeFilterRule{re: re})
+	}
+	for _, s := range c.DenySeries {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("deny series %q: %s", s, err)
+		}
+		f.series = append(f.series, &writeFilterRule{re: re})
+	}
+	return f, nil
+}
+
+// Filter returns the points that don't match any deny pattern. The original
+// slice is returned when no points are dropped.
+func (f *WriteFilter) Filter(points []tsdb.Point) []tsdb.Point {
+	var kept []tsdb.Point
+	for i, p := range points {
+		if r := f.match(p); r != nil {
+			atomic.AddUint64(&r.dropped, 1)
+			if kept == nil {
+				kept = append(make([]tsdb.Point, 0, len(points)), points[:i]...)
+			}
+			continue
+		}
+		if kept != nil {
+			kept = append(kept, p)
+		}
+	}
+
+	if kept == nil {
+		return points
+	}
+	return kept
+}
+
+// match returns the first rule matching the point, if any.
+func (f *WriteFilter) match(p tsdb.Point) *writeFilterRule {
+	for _, r := range f.measurements {
+		if r.re.MatchString(p.Name()) {
+			return r
+		}
+	}
+	if len(f.series) > 0 {
+		key := p.Key()
+		for _, r := range f.series {
+			if r.re.Match(key) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// Stats returns the number of points dropped by each deny pattern.
+func (f *WriteFilter) Stats() []WriteFilterStat {
+	var a []WriteFilterStat
+	for _, r := range f.measurements {
+		a = append(a, WriteFilterStat{Type: "measurement", Pattern: r.re.String(), Dropped: atomic.LoadUint64(&r.dropped)})
+	}
+	for _, r := range f.series {
+		a = append(a, WriteFilterStat{Type: "series", Pattern: r.re.String(), Dropped: atomic.LoadUint64(&r.dropped)})
+	}
+	return a
+}
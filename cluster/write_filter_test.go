@@ -0,0 +1,50 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensures points matching a deny pattern are dropped and counted.
+func TestWriteFilter_Filter(t *testing.T) {
+	c := cluster.NewConfig()
+	c.DenyMeasurements = []string{"^debug_"}
+	c.DenySeries = []string{`^cpu,host=bad\b`}
+	f, err := cluster.NewWriteFilter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(0, 0)
+	points := []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "bad"}), tsdb.Fields{"value": 1.0}, now),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "badger"}), tsdb.Fields{"value": 1.0}, now),
+		tsdb.NewPoint("debug_cpu", nil, tsdb.Fields{"value": 1.0}, now),
+		tsdb.NewPoint("mem", tsdb.NewTags(map[string]string{"host": "bad"}), tsdb.Fields{"value": 1.0}, now),
+	}
+
+	kept := f.Filter(points)
+	if len(kept) != 2 || kept[0].Tags().Get("host") != "badger" || kept[1].Name() != "mem" {
+		t.Fatalf("unexpected points: %v", kept)
+	}
+
+	if exp := []cluster.WriteFilterStat{
+		{Type: "measurement", Pattern: "^debug_", Dropped: 1},
+		{Type: "series", Pattern: `^cpu,host=bad\b`, Dropped: 1},
+	}; !reflect.DeepEqual(f.Stats(), exp) {
+		t.Fatalf("unexpected stats: %#v", f.Stats())
+	}
+}
+
+// Ensures an invalid pattern is rejected.
+func TestNewWriteFilter_ErrInvalidPattern(t *testing.T) {
+	c := cluster.NewConfig()
+	c.DenySeries = []string{"("}
+	if _, err := cluster.NewWriteFilter(c); err == nil {
+		t.Fatal("expected error")
+	}
+}
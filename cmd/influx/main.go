@@ -17,7 +17,9 @@ import (
 	"text/tabwriter"
 
 	"github.com/influxdb/influxdb/client"
+	csvimporter "github.com/influxdb/influxdb/importer/csv"
 	"github.com/influxdb/influxdb/importer/v8"
+	tsdbcsv "github.com/influxdb/influxdb/tsdb/csv"
 	"github.com/peterh/liner"
 )
 
@@ -54,6 +56,8 @@ type CommandLine struct {
 	PPS             int // Controls how many points per second the import will allow via throttling
 	Path            string
 	Compressed      bool
+	CSV             string // Column mapping of a CSV file to import, see csv.ParseSpec
+	Resume          string // File recording the progress of an import so it can be resumed
 }
 
 func main() {
@@ -74,6 +78,8 @@ func main() {
 	fs.IntVar(&c.PPS, "pps", defaultPPS, "How many points per second the import will allow.  By default it is zero and will not throttle importing.")
 	fs.StringVar(&c.Path, "path", "", "path to the file to import")
 	fs.BoolVar(&c.Compressed, "compressed", false, "set to true if the import file is compressed")
+	fs.StringVar(&c.CSV, "csv", "", "column mapping of a CSV file to import")
+	fs.StringVar(&c.Resume, "resume", "", "file recording the progress of the import, to resume it if it's interrupted")
 
 	// Define our own custom usage to print
 	fs.Usage = func() {
@@ -106,12 +112,25 @@ func main() {
        Path to file to import
   -compressed
        Set to true if the import file is compressed
+  -csv 'measurement=name;tags=col,...;fields=col,...;types=col:type,...;time=col;time-format=rfc3339'
+       Import the file as CSV, mapping its columns to the tags, fields and time of points.
+       Use measurement-column=col instead of measurement to read the measurement of each row.
+       The points are written to the database set with -database.
+  -resume 'file'
+       Record the progress of an import in file. Running an interrupted import again
+       with the same file skips the lines that were already written.
 
 Examples:
 
     # Use influx in a non-interactive mode to query the database "metrics" and pretty print json:
     $ influx -database 'metrics' -execute 'select * from cpu' -format 'json' -pretty
 
+    # Import a CSV file whose first row names the columns "host", "value" and "ts":
+    $ influx -import -path 'cpu.csv' -database 'metrics' -csv 'measurement=cpu;tags=host;time=ts;time-format=s'
+
+    # Import an export, resuming where a previous run was interrupted:
+    $ influx -import -path 'export.gz' -compressed -resume 'export.resume'
+
     # Connect to a specific database on startup and set database context:
     $ influx -database 'metrics' -host 'localhost' -port '8086'
 `)
@@ -169,16 +188,27 @@ Examples:
 			return
 		}
 
+		if c.CSV != "" {
+			if err := c.importCSV(u); err != nil {
+				fmt.Printf("ERROR: %s\n", err)
+				c.Line.Close()
+				os.Exit(1)
+			}
+			c.Line.Close()
+			os.Exit(0)
+		}
+
 		config := v8.NewConfig()
 		config.Username = c.Username
 		config.Password = c.Password
-		config.Precision = "ns"
+		config.Precision = "n"
 		config.WriteConsistency = "any"
 		config.Path = c.Path
 		config.Version = version
 		config.URL = u
 		config.Compressed = c.Compressed
 		config.PPS = c.PPS
+		config.ResumeFile = c.Resume
 
 		i := v8.NewImporter(config)
 		if err := i.Import(); err != nil {
@@ -224,6 +254,27 @@ Examples:
 	}
 }
 
+// importCSV imports the file at Path as CSV with the column mapping of the -csv flag.
+func (c *CommandLine) importCSV(u url.URL) error {
+	spec, err := tsdbcsv.ParseSpec(c.CSV)
+	if err != nil {
+		return err
+	}
+
+	config := csvimporter.NewConfig()
+	config.Username = c.Username
+	config.Password = c.Password
+	config.URL = u
+	config.Database = c.Database
+	config.RetentionPolicy = c.RetentionPolicy
+	config.WriteConsistency = "any"
+	config.Path = c.Path
+	config.Version = version
+	config.Compressed = c.Compressed
+	config.Spec = spec
+	return csvimporter.NewImporter(config).Import()
+}
+
 func showVersion() {
 	fmt.Println("InfluxDB shell " + version)
 }
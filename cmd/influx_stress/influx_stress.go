@@ -3,14 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
-	"math/rand"
 	"net/url"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/tsdb"
+	_ "github.com/influxdb/influxdb/tsdb/engine"
+	"github.com/influxdb/influxdb/tsdb/gen"
 )
 
 var (
@@ -21,72 +25,102 @@ var (
 	batchInterval = flag.Duration("batchinterval", 0*time.Second, "duration between batches")
 	database      = flag.String("database", "stress", "name of database")
 	address       = flag.String("addr", "localhost:8086", "IP address and port of database (e.g., localhost:8086)")
+	measurement   = flag.String("measurement", gen.DefaultMeasurement, "name of the measurement to write to")
+	tags          = flag.String("tags", "region:1,host", "tag keys and their cardinality (e.g., region:5,host:1000); keys without a cardinality have a value per series")
+	distribution  = flag.String("distribution", gen.Uniform, "distribution of points across series: uniform or zipf")
+	values        = flag.String("values", gen.Random, "pattern of field values: random, sine, counter or constant")
+	interval      = flag.Duration("interval", gen.DefaultInterval, "time between points of the same series")
+	rate          = flag.Int("rate", 0, "maximum points written per second, 0 for no limit")
+	seed          = flag.Int64("seed", 0, "seed of the random values, to reproduce a workload")
+	dataDir       = flag.String("datadir", "", "write directly into a store at this path instead of through the HTTP API")
 )
 
 func main() {
 	flag.Parse()
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
-	startTime := time.Now()
-	counter := NewConcurrencyLimiter(*concurrency)
+	// The points of each series end at the current time.
+	c := gen.NewConfig()
+	c.Measurement = *measurement
+	c.SeriesN = *seriesCount
+	c.Distribution = *distribution
+	c.Values = *values
+	c.Interval = *interval
+	c.Start = time.Now().Add(-time.Duration(*pointCount) * *interval)
+	c.Seed = *seed
 
-	u, _ := url.Parse(fmt.Sprintf("http://%s", *address))
-	c, err := client.NewClient(client.Config{URL: *u})
+	var err error
+	if c.Tags, err = gen.ParseTags(*tags); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	g, err := gen.NewGenerator(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var w writer
+	if *dataDir != "" {
+		w, err = newStoreWriter(*dataDir, *database)
+	} else {
+		w, err = newHTTPWriter(*address, *database)
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+	defer w.Close()
+
+	startTime := time.Now()
+	counter := NewConcurrencyLimiter(*concurrency)
 
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	responseTimes := make([]int, 0)
 
 	totalPoints := 0
+	for n := *pointCount * *seriesCount; totalPoints < n; {
+		size := *batchSize
+		if remaining := n - totalPoints; remaining < size {
+			size = remaining
+		}
+		batch := g.Batch(size)
+		totalPoints += size
 
-	batch := &client.BatchPoints{
-		Database:         *database,
-		WriteConsistency: "any",
-		Time:             time.Now(),
-		Precision:        "n",
-	}
-	for i := 1; i <= *pointCount; i++ {
-		for j := 1; j <= *seriesCount; j++ {
-			p := client.Point{
-				Measurement: "cpu",
-				Tags:        map[string]string{"region": "uswest", "host": fmt.Sprintf("host-%d", j)},
-				Fields:      map[string]interface{}{"value": rand.Float64()},
+		wg.Add(1)
+		counter.Increment()
+		go func(points []tsdb.Point, total int) {
+			st := time.Now()
+			if err := w.Write(points); err != nil {
+				fmt.Println("ERROR: ", err.Error())
+			} else {
+				mu.Lock()
+				responseTimes = append(responseTimes, int(time.Since(st).Nanoseconds()))
+				mu.Unlock()
 			}
-			batch.Points = append(batch.Points, p)
-			if len(batch.Points) >= *batchSize {
-				wg.Add(1)
-				counter.Increment()
-				totalPoints += len(batch.Points)
-				go func(b *client.BatchPoints, total int) {
-					st := time.Now()
-					if _, err := c.Write(*b); err != nil {
-						fmt.Println("ERROR: ", err.Error())
-					} else {
-						mu.Lock()
-						responseTimes = append(responseTimes, int(time.Since(st).Nanoseconds()))
-						mu.Unlock()
-					}
-					wg.Done()
-					counter.Decrement()
-					if total%500000 == 0 {
-						fmt.Printf("%d total points. %d in %s\n", total, *batchSize, time.Since(st))
-					}
-				}(batch, totalPoints)
-
-				batch = &client.BatchPoints{
-					Database:         *database,
-					WriteConsistency: "any",
-					Precision:        "n",
-					Time:             time.Now(),
-				}
+			wg.Done()
+			counter.Decrement()
+			if total%500000 == 0 {
+				fmt.Printf("%d total points. %d in %s\n", total, len(points), time.Since(st))
+			}
+		}(batch, totalPoints)
+
+		// Keep under the rate limit and wait between batches.
+		if *rate > 0 {
+			if d := time.Duration(totalPoints)*time.Second/time.Duration(*rate) - time.Since(startTime); d > 0 {
+				time.Sleep(d)
 			}
 		}
+		time.Sleep(*batchInterval)
 	}
 
 	wg.Wait()
+	if len(responseTimes) == 0 {
+		w.Close()
+		fmt.Println("No batches written")
+		os.Exit(1)
+	}
 	sort.Sort(sort.Reverse(sort.IntSlice(responseTimes)))
 
 	total := int64(0)
@@ -98,11 +132,81 @@ func main() {
 	fmt.Printf("Wrote %d points at average rate of %.0f\n", totalPoints, float64(totalPoints)/time.Since(startTime).Seconds())
 	fmt.Println("Average response time: ", time.Duration(mean))
 	fmt.Println("Slowest response times:")
-	for _, r := range responseTimes[:100] {
+	if len(responseTimes) > 100 {
+		responseTimes = responseTimes[:100]
+	}
+	for _, r := range responseTimes {
 		fmt.Println(time.Duration(r))
 	}
 }
 
+// writer writes batches of generated points.
+type writer interface {
+	Write(points []tsdb.Point) error
+	Close() error
+}
+
+// httpWriter writes points through the HTTP API.
+type httpWriter struct {
+	client   *client.Client
+	database string
+}
+
+func newHTTPWriter(addr, database string) (*httpWriter, error) {
+	u, err := url.Parse(fmt.Sprintf("http://%s", addr))
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.NewClient(client.Config{URL: *u})
+	if err != nil {
+		return nil, err
+	}
+	return &httpWriter{client: c, database: database}, nil
+}
+
+func (w *httpWriter) Write(points []tsdb.Point) error {
+	batch := client.BatchPoints{
+		Database:         w.database,
+		WriteConsistency: "any",
+		Precision:        "n",
+		Points:           make([]client.Point, len(points)),
+	}
+	for i, p := range points {
+		batch.Points[i] = client.Point{
+			Measurement: p.Name(),
+			Tags:        p.Tags().Map(),
+			Fields:      p.Fields(),
+			Time:        p.Time(),
+		}
+	}
+	_, err := w.client.Write(batch)
+	return err
+}
+
+func (w *httpWriter) Close() error { return nil }
+
+// storeWriter writes points directly into a single shard of a local store,
+// bypassing the network and the cluster write path.
+type storeWriter struct {
+	store *tsdb.Store
+}
+
+func newStoreWriter(path, database string) (*storeWriter, error) {
+	store := tsdb.NewStore(filepath.Join(path, "data"))
+	store.EngineOptions.Config.WALDir = filepath.Join(path, "wal")
+	if err := store.Open(); err != nil {
+		return nil, err
+	}
+	if err := store.CreateShard(database, "default", 1); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return &storeWriter{store: store}, nil
+}
+
+func (w *storeWriter) Write(points []tsdb.Point) error { return w.store.WriteToShard(1, points) }
+func (w *storeWriter) Close() error                    { return w.store.Close() }
+
 // ConcurrencyLimiter is a go routine safe struct that can be used to
 // ensure that no more than a specifid max number of goroutines are
 // executing.
@@ -0,0 +1,155 @@
+package convert
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/tsdb"
+	_ "github.com/influxdb/influxdb/tsdb/engine"
+)
+
+// Command represents the program execution for "influxd convert".
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewCommand returns a new instance of Command with default settings.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run executes the program.
+func (cmd *Command) Run(args ...string) error {
+	config, format, database, err := cmd.parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Convert(config, format, database)
+}
+
+// Convert converts every shard in the data directory to format. If database
+// is set then only shards in that database are converted. The server must not
+// be running while shards are converted.
+func (cmd *Command) Convert(config *Config, format, database string) error {
+	opt := tsdb.NewEngineOptions()
+	opt.Config = config.Data
+
+	dbs, err := ioutil.ReadDir(config.Data.Dir)
+	if err != nil {
+		return fmt.Errorf("read data dir: %s", err)
+	}
+
+	var n int
+	for _, db := range dbs {
+		if !db.IsDir() || (database != "" && db.Name() != database) {
+			continue
+		}
+
+		rps, err := ioutil.ReadDir(filepath.Join(config.Data.Dir, db.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, rp := range rps {
+			// Retention policies should be directories.
+			if !rp.IsDir() {
+				continue
+			}
+
+			shards, err := ioutil.ReadDir(filepath.Join(config.Data.Dir, db.Name(), rp.Name()))
+			if err != nil {
+				return err
+			}
+
+			for _, sh := range shards {
+				// Shard file names are numeric shard IDs.
+				if _, err := strconv.ParseUint(sh.Name(), 10, 64); err != nil {
+					continue
+				}
+
+				path := filepath.Join(config.Data.Dir, db.Name(), rp.Name(), sh.Name())
+				walPath := filepath.Join(config.Data.WALDir, db.Name(), rp.Name(), sh.Name())
+
+				// Skip shards that are already in the requested format.
+				if f, err := tsdb.EngineFormat(path); err != nil {
+					return fmt.Errorf("format: shard=%s, err=%s", path, err)
+				} else if f == format {
+					continue
+				}
+
+				fmt.Fprintf(cmd.Stdout, "converting: %s\n", path)
+				if err := tsdb.ConvertShard(path, walPath, format, opt); err != nil {
+					return fmt.Errorf("convert: shard=%s, err=%s", path, err)
+				}
+				n++
+			}
+		}
+	}
+
+	// Notify user of completion.
+	fmt.Fprintf(cmd.Stdout, "converted %d shards to %s\n", n, format)
+	return nil
+}
+
+// parseFlags parses and validates the command line arguments.
+func (cmd *Command) parseFlags(args []string) (*Config, string, string, error) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	configPath := fs.String("config", "", "")
+	format := fs.String("format", tsdb.DefaultEngine, "")
+	database := fs.String("database", "", "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = cmd.printUsage
+	if err := fs.Parse(args); err != nil {
+		return nil, "", "", err
+	}
+
+	// Parse configuration file from disk.
+	if *configPath == "" {
+		return nil, "", "", fmt.Errorf("config required")
+	}
+
+	// Parse config.
+	config := Config{
+		Data: tsdb.NewConfig(),
+	}
+	if _, err := toml.DecodeFile(*configPath, &config); err != nil {
+		return nil, "", "", err
+	}
+
+	return &config, *format, *database, nil
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	fmt.Fprintf(cmd.Stderr, `usage: influxd convert [flags]
+
+convert rewrites the shards of a stopped data node in a different engine format.
+Each shard is verified after it's converted and the original is kept with a
+".bak" extension.
+
+        -config <path>
+                          Set the path to the configuration file.
+
+        -format <engine>
+                          Set the engine format to convert to. Defaults to %q.
+
+        -database <name>
+                          Only convert shards in the given database.
+`, tsdb.DefaultEngine)
+}
+
+// Config represents a partial config for converting shards.
+type Config struct {
+	Data tsdb.Config `toml:"data"`
+}
@@ -36,6 +36,8 @@ The commands are:
 
     backup               downloads a snapshot of a data node and saves it to disk
     config               display the default configuration
+    convert              converts shards to a different engine format
+    replay               writes the WAL or hinted handoff of a stopped node to a server
     restore              uses a snapshot of a data node to rebuild a cluster
     run                  run node with existing configuration
     version              displays the InfluxDB version
@@ -13,7 +13,9 @@ import (
 	"time"
 
 	"github.com/influxdb/influxdb/cmd/influxd/backup"
+	"github.com/influxdb/influxdb/cmd/influxd/convert"
 	"github.com/influxdb/influxdb/cmd/influxd/help"
+	"github.com/influxdb/influxdb/cmd/influxd/replay"
 	"github.com/influxdb/influxdb/cmd/influxd/restore"
 	"github.com/influxdb/influxdb/cmd/influxd/run"
 )
@@ -114,6 +116,16 @@ func (m *Main) Run(args ...string) error {
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("backup: %s", err)
 		}
+	case "convert":
+		name := convert.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("convert: %s", err)
+		}
+	case "replay":
+		name := replay.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("replay: %s", err)
+		}
 	case "restore":
 		name := restore.NewCommand()
 		if err := name.Run(args...); err != nil {
@@ -0,0 +1,454 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/hh"
+	"github.com/influxdb/influxdb/snapshot"
+	"github.com/influxdb/influxdb/tsdb"
+	"github.com/influxdb/influxdb/tsdb/engine/bz1"
+	"github.com/influxdb/influxdb/tsdb/engine/wal"
+)
+
+// Sources of points to replay.
+const (
+	SourceWAL     = "wal"
+	SourceHandoff = "hh"
+)
+
+// DefaultBatchSize is the default number of points written per request.
+const DefaultBatchSize = 5000
+
+// Command represents the program execution for "influxd replay".
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewCommand returns a new instance of Command with default settings.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Options represents what to replay and where to write it.
+type Options struct {
+	// Source is either SourceWAL or SourceHandoff.
+	Source string
+
+	// Directories of the stopped node.
+	DataDir    string
+	WALDir     string
+	HandoffDir string
+
+	// Meta maps the shards of hinted handoff writes to their database and
+	// retention policy. Required to replay hinted handoff queues.
+	Meta *meta.Data
+
+	// Filters. Zero values match everything.
+	NodeID    uint64
+	Database  string
+	StartTime time.Time
+	EndTime   time.Time
+
+	// Server to write to and the number of points per write.
+	URL       url.URL
+	Username  string
+	Password  string
+	BatchSize int
+
+	// DryRun counts the points that would be replayed without writing them.
+	DryRun bool
+}
+
+// Run executes the program.
+func (cmd *Command) Run(args ...string) error {
+	opt, err := cmd.parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Replay(opt)
+}
+
+// Replay reads the points of the WAL or the hinted handoff queues of a
+// stopped data node and writes them to a server over HTTP. Points are written
+// in batches per database and retention policy. The node's files are not
+// modified.
+func (cmd *Command) Replay(opt *Options) error {
+	r := &replayer{
+		opt:     opt,
+		batches: make(map[destination][]client.Point),
+	}
+	if !opt.DryRun {
+		c, err := client.NewClient(client.Config{URL: opt.URL, Username: opt.Username, Password: opt.Password})
+		if err != nil {
+			return err
+		}
+		r.client = c
+	}
+
+	switch opt.Source {
+	case SourceWAL:
+		if err := cmd.replayWAL(r); err != nil {
+			return err
+		}
+	case SourceHandoff:
+		if err := cmd.replayHandoff(r); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown source: %s", opt.Source)
+	}
+
+	if err := r.flushAll(); err != nil {
+		return err
+	}
+
+	// Notify user of completion.
+	if opt.DryRun {
+		fmt.Fprintf(cmd.Stdout, "found %d points to replay\n", r.n)
+	} else {
+		fmt.Fprintf(cmd.Stdout, "replayed %d points\n", r.n)
+	}
+	return nil
+}
+
+// replayWAL replays the WAL of every shard. The WAL directory has the same
+// database, retention policy and shard layout as the data directory.
+func (cmd *Command) replayWAL(r *replayer) error {
+	dbs, err := ioutil.ReadDir(r.opt.WALDir)
+	if err != nil {
+		return fmt.Errorf("read wal dir: %s", err)
+	}
+
+	for _, db := range dbs {
+		if !db.IsDir() || (r.opt.Database != "" && db.Name() != r.opt.Database) {
+			continue
+		}
+
+		rps, err := ioutil.ReadDir(filepath.Join(r.opt.WALDir, db.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, rp := range rps {
+			if !rp.IsDir() {
+				continue
+			}
+
+			shards, err := ioutil.ReadDir(filepath.Join(r.opt.WALDir, db.Name(), rp.Name()))
+			if err != nil {
+				return err
+			}
+
+			for _, sh := range shards {
+				// Shard WAL directory names are numeric shard IDs.
+				if _, err := strconv.ParseUint(sh.Name(), 10, 64); err != nil || !sh.IsDir() {
+					continue
+				}
+
+				// Fields flushed before the node stopped are only in the shard.
+				var fields map[string]*tsdb.MeasurementFields
+				if r.opt.DataDir != "" {
+					path := filepath.Join(r.opt.DataDir, db.Name(), rp.Name(), sh.Name())
+					if _, err := os.Stat(path); err == nil {
+						if fields, err = bz1.ReadFields(path); err != nil {
+							return fmt.Errorf("read fields: shard=%s, err=%s", path, err)
+						}
+					}
+				}
+
+				path := filepath.Join(r.opt.WALDir, db.Name(), rp.Name(), sh.Name())
+				d := destination{db.Name(), rp.Name()}
+				skipped, err := wal.ReadPoints(path, fields, func(p tsdb.Point) error { return r.add(d, p) })
+				if err != nil {
+					return fmt.Errorf("read wal: path=%s, err=%s", path, err)
+				} else if skipped > 0 {
+					fmt.Fprintf(cmd.Stderr, "skipped %d points without field metadata in %s\n", skipped, path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// replayHandoff replays the writes queued for other nodes.
+func (cmd *Command) replayHandoff(r *replayer) error {
+	if r.opt.Meta == nil {
+		return errors.New("meta data required to replay hinted handoff")
+	}
+
+	// Map shards to the database and retention policy they belong to.
+	shards := make(map[uint64]destination)
+	for _, di := range r.opt.Meta.Databases {
+		for _, rpi := range di.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				for _, si := range sgi.Shards {
+					shards[si.ID] = destination{di.Name, rpi.Name}
+				}
+			}
+		}
+	}
+
+	return hh.ReadWrites(r.opt.HandoffDir, func(nodeID, shardID uint64, points []tsdb.Point) error {
+		if r.opt.NodeID != 0 && nodeID != r.opt.NodeID {
+			return nil
+		}
+
+		d, ok := shards[shardID]
+		if !ok {
+			fmt.Fprintf(cmd.Stderr, "skipped %d points for unknown shard %d\n", len(points), shardID)
+			return nil
+		} else if r.opt.Database != "" && d.db != r.opt.Database {
+			return nil
+		}
+
+		for _, p := range points {
+			if err := r.add(d, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// destination is a database and retention policy points are written to.
+type destination struct {
+	db, rp string
+}
+
+// replayer batches points by destination and writes full batches.
+type replayer struct {
+	opt     *Options
+	client  *client.Client
+	batches map[destination][]client.Point
+	n       int
+}
+
+// add adds a point to the batch of its destination if it is in the time range.
+func (r *replayer) add(d destination, p tsdb.Point) error {
+	if !r.opt.StartTime.IsZero() && p.Time().Before(r.opt.StartTime) {
+		return nil
+	} else if !r.opt.EndTime.IsZero() && !p.Time().Before(r.opt.EndTime) {
+		return nil
+	}
+
+	r.batches[d] = append(r.batches[d], client.Point{Raw: p.String()})
+	if len(r.batches[d]) >= r.opt.BatchSize {
+		return r.flush(d)
+	}
+	return nil
+}
+
+// flush writes the batch of a destination.
+func (r *replayer) flush(d destination) error {
+	points := r.batches[d]
+	delete(r.batches, d)
+	if len(points) == 0 {
+		return nil
+	}
+
+	if r.client != nil {
+		if _, err := r.client.Write(client.BatchPoints{
+			Database:        d.db,
+			RetentionPolicy: d.rp,
+			Points:          points,
+		}); err != nil {
+			return fmt.Errorf("write: db=%s, rp=%s, err=%s", d.db, d.rp, err)
+		}
+	}
+	r.n += len(points)
+	return nil
+}
+
+// flushAll writes the batches of all destinations.
+func (r *replayer) flushAll() error {
+	for d := range r.batches {
+		if err := r.flush(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFlags parses and validates the command line arguments.
+func (cmd *Command) parseFlags(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	configPath := fs.String("config", "", "")
+	source := fs.String("source", SourceWAL, "")
+	snapshotPath := fs.String("snapshot", "", "")
+	host := fs.String("host", "localhost:8086", "")
+	username := fs.String("username", "", "")
+	password := fs.String("password", "", "")
+	node := fs.Uint64("node", 0, "")
+	database := fs.String("database", "", "")
+	start := fs.String("start", "", "")
+	end := fs.String("end", "", "")
+	batchSize := fs.Int("batch-size", DefaultBatchSize, "")
+	dryRun := fs.Bool("dry-run", false, "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = cmd.printUsage
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Parse configuration file from disk.
+	if *configPath == "" {
+		return nil, fmt.Errorf("config required")
+	}
+
+	// Parse config.
+	config := Config{
+		Data:          tsdb.NewConfig(),
+		HintedHandoff: hh.NewConfig(),
+	}
+	if _, err := toml.DecodeFile(*configPath, &config); err != nil {
+		return nil, err
+	}
+
+	opt := &Options{
+		Source:     *source,
+		DataDir:    config.Data.Dir,
+		WALDir:     config.Data.WALDir,
+		HandoffDir: config.HintedHandoff.Dir,
+		NodeID:     *node,
+		Database:   *database,
+		URL:        url.URL{Scheme: "http", Host: *host},
+		Username:   *username,
+		Password:   *password,
+		BatchSize:  *batchSize,
+		DryRun:     *dryRun,
+	}
+
+	if opt.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	var err error
+	if *start != "" {
+		if opt.StartTime, err = time.Parse(time.RFC3339, *start); err != nil {
+			return nil, fmt.Errorf("parse start: %s", err)
+		}
+	}
+	if *end != "" {
+		if opt.EndTime, err = time.Parse(time.RFC3339, *end); err != nil {
+			return nil, fmt.Errorf("parse end: %s", err)
+		}
+	}
+
+	if opt.Source == SourceHandoff {
+		if *snapshotPath == "" {
+			return nil, fmt.Errorf("snapshot required to replay hinted handoff")
+		}
+		if opt.Meta, err = readMeta(*snapshotPath); err != nil {
+			return nil, fmt.Errorf("read snapshot: %s", err)
+		}
+	}
+
+	return opt, nil
+}
+
+// readMeta returns the meta data in a snapshot created by "influxd backup".
+func readMeta(path string) (*meta.Data, error) {
+	mr, files, err := snapshot.OpenFileMultiReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	for {
+		sf, err := mr.Next()
+		if err == io.EOF {
+			return nil, errors.New("meta data not found")
+		} else if err != nil {
+			return nil, err
+		}
+		if sf.Name != "meta" {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, mr, sf.Size); err != nil {
+			return nil, err
+		}
+
+		var data meta.Data
+		if err := data.UnmarshalBinary(buf.Bytes()); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	fmt.Fprintf(cmd.Stderr, `usage: influxd replay [flags]
+
+replay reads the WAL or the hinted handoff queues of a stopped data node and
+writes their points to a server over HTTP. Use it to recover writes when the
+node cannot replay them itself. The node's files are not modified.
+
+        -config <path>
+                          Set the path to the configuration file.
+
+        -source <wal|hh>
+                          Replay the WAL or the hinted handoff queues.
+                          Defaults to "wal".
+
+        -snapshot <path>
+                          Set the path to a snapshot created by "influxd backup".
+                          Required to find the database of hinted handoff writes.
+
+        -host <host:port>
+                          The server to write to. Defaults to localhost:8086.
+
+        -username <name>
+        -password <password>
+                          Credentials of the server to write to.
+
+        -node <id>
+                          Only replay hinted handoff writes queued for a node.
+
+        -database <name>
+                          Only replay points of a database.
+
+        -start <time>
+        -end <time>
+                          Only replay points in a time range, in RFC3339 format.
+                          The end time is exclusive.
+
+        -batch-size <n>
+                          Set the number of points written per request.
+                          Defaults to %d.
+
+        -dry-run
+                          Count the points that would be replayed without
+                          writing them.
+`, DefaultBatchSize)
+}
+
+// Config represents a partial config for replaying writes.
+type Config struct {
+	Data          tsdb.Config `toml:"data"`
+	HintedHandoff hh.Config   `toml:"hinted-handoff"`
+}
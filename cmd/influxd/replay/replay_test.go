@@ -0,0 +1,112 @@
+package replay_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cmd/influxd/replay"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/hh"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the replay command writes queued hinted handoff writes to their
+// database and retention policy.
+func TestCommand_Replay_Handoff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxd-replay-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Queue writes for two nodes, one of them to a shard that no longer exists.
+	p, err := hh.NewProcessor(dir, nil, hh.ProcessorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	mustWriteShard(t, p, 1, 2, "cpu value=1 0", "cpu value=2 10")
+	mustWriteShard(t, p, 3, 2, "mem value=3 0")
+	mustWriteShard(t, p, 1, 4, "cpu value=4 20")
+	mustWriteShard(t, p, 100, 4, "cpu value=5 0")
+
+	// Record the writes received by the server.
+	var writes []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		writes = append(writes, r.URL.Query().Get("db")+"."+r.URL.Query().Get("rp")+": "+strings.TrimSpace(string(b)))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	data := &meta.Data{
+		Databases: []meta.DatabaseInfo{
+			{Name: "db0", RetentionPolicies: []meta.RetentionPolicyInfo{
+				{Name: "rp0", ShardGroups: []meta.ShardGroupInfo{{Shards: []meta.ShardInfo{{ID: 1}}}}},
+			}},
+			{Name: "db1", RetentionPolicies: []meta.RetentionPolicyInfo{
+				{Name: "rp1", ShardGroups: []meta.ShardGroupInfo{{Shards: []meta.ShardInfo{{ID: 3}}}}},
+			}},
+		},
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := &replay.Command{Stdout: &stdout, Stderr: &stderr}
+	if err := cmd.Replay(&replay.Options{
+		Source:     replay.SourceHandoff,
+		HandoffDir: dir,
+		Meta:       data,
+		EndTime:    now.Add(20),
+		URL:        *u,
+		BatchSize:  2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(writes)
+	if exp := []string{
+		"db0.rp0: cpu value=1 0\ncpu value=2 10",
+		"db1.rp1: mem value=3 0",
+	}; !reflect.DeepEqual(writes, exp) {
+		t.Fatalf("unexpected writes: %q", writes)
+	} else if stdout.String() != "replayed 3 points\n" {
+		t.Fatalf("unexpected stdout: %s", stdout.String())
+	} else if stderr.String() != "skipped 1 points for unknown shard 100\n" {
+		t.Fatalf("unexpected stderr: %s", stderr.String())
+	}
+
+	// Filter by node without writing anything.
+	stdout.Reset()
+	if err := cmd.Replay(&replay.Options{
+		Source:     replay.SourceHandoff,
+		HandoffDir: dir,
+		Meta:       data,
+		NodeID:     4,
+		BatchSize:  2,
+		DryRun:     true,
+	}); err != nil {
+		t.Fatal(err)
+	} else if stdout.String() != "found 1 points to replay\n" {
+		t.Fatalf("unexpected stdout: %s", stdout.String())
+	}
+}
+
+// mustWriteShard queues line protocol points for a shard owned by a node.
+func mustWriteShard(t *testing.T, p *hh.Processor, shardID, nodeID uint64, lines ...string) {
+	points, err := tsdb.ParsePointsString(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := p.WriteShard(shardID, nodeID, points); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -11,12 +11,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/admin"
 	"github.com/influxdb/influxdb/services/collectd"
 	"github.com/influxdb/influxdb/services/continuous_querier"
 	"github.com/influxdb/influxdb/services/graphite"
+	"github.com/influxdb/influxdb/services/grpcd"
 	"github.com/influxdb/influxdb/services/hh"
 	"github.com/influxdb/influxdb/services/httpd"
 	"github.com/influxdb/influxdb/services/monitor"
@@ -37,11 +39,16 @@ type Config struct {
 
 	Admin     admin.Config      `toml:"admin"`
 	HTTPD     httpd.Config      `toml:"http"`
+	GRPC      grpcd.Config      `toml:"grpc"`
 	Graphites []graphite.Config `toml:"graphite"`
 	Collectd  collectd.Config   `toml:"collectd"`
 	OpenTSDB  opentsdb.Config   `toml:"opentsdb"`
 	UDPs      []udp.Config      `toml:"udp"`
 
+	// Inputs holds the "[input.<name>]" sections of registered inputs. They're
+	// decoded by the input when the server is built.
+	Inputs map[string]toml.Primitive `toml:"input"`
+
 	// Snapshot SnapshotConfig `toml:"snapshot"`
 	Monitoring      monitor.Config            `toml:"monitoring"`
 	ContinuousQuery continuous_querier.Config `toml:"continuous_queries"`
@@ -62,6 +69,7 @@ func NewConfig() *Config {
 
 	c.Admin = admin.NewConfig()
 	c.HTTPD = httpd.NewConfig()
+	c.GRPC = grpcd.NewConfig()
 	c.Collectd = collectd.NewConfig()
 	c.OpenTSDB = opentsdb.NewConfig()
 	c.Graphites = append(c.Graphites, graphite.NewConfig())
@@ -93,6 +101,8 @@ func NewDemoConfig() (*Config, error) {
 	c.Data.Dir = filepath.Join(homeDir, ".influxdb/data")
 	c.HintedHandoff.Dir = filepath.Join(homeDir, ".influxdb/hh")
 	c.Data.WALDir = filepath.Join(homeDir, ".influxdb/wal")
+	c.Data.IndexDir = filepath.Join(homeDir, ".influxdb/index")
+	c.Data.TrashDir = filepath.Join(homeDir, ".influxdb/trash")
 
 	c.Admin.Enabled = true
 	c.Monitoring.Enabled = false
@@ -110,6 +120,20 @@ func (c *Config) Validate() error {
 		return errors.New("HintedHandoff.Dir must be specified")
 	} else if c.Data.WALDir == "" {
 		return errors.New("Data.WALDir must be specified")
+	} else if c.Data.TrashRetention > 0 && c.Data.TrashDir == "" {
+		return errors.New("Data.TrashDir must be specified")
+	}
+
+	if err := c.Data.Validate(); err != nil {
+		return fmt.Errorf("invalid data config: %v", err)
+	}
+
+	if err := c.HTTPD.Validate(); err != nil {
+		return fmt.Errorf("invalid http config: %v", err)
+	}
+
+	if err := c.GRPC.Validate(); err != nil {
+		return fmt.Errorf("invalid grpc config: %v", err)
 	}
 
 	for _, g := range c.Graphites {
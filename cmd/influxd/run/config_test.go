@@ -44,6 +44,7 @@ bind-address = ":4444"
 
 [monitoring]
 enabled = true
+max-tag-values-growth = 1000
 
 [continuous_queries]
 enabled = true
@@ -74,6 +75,8 @@ enabled = true
 		t.Fatalf("unexpected udp bind address: %s", c.UDPs[0].BindAddress)
 	} else if c.Monitoring.Enabled != true {
 		t.Fatalf("unexpected monitoring enabled: %v", c.Monitoring.Enabled)
+	} else if c.Monitoring.MaxTagValuesGrowth != 1000 {
+		t.Fatalf("unexpected max tag values growth: %d", c.Monitoring.MaxTagValuesGrowth)
 	} else if c.ContinuousQuery.Enabled != true {
 		t.Fatalf("unexpected continuous query enabled: %v", c.ContinuousQuery.Enabled)
 	}
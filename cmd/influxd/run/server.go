@@ -9,17 +9,22 @@ import (
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/admin"
 	"github.com/influxdb/influxdb/services/collectd"
 	"github.com/influxdb/influxdb/services/continuous_querier"
 	"github.com/influxdb/influxdb/services/graphite"
+	"github.com/influxdb/influxdb/services/grpcd"
 	"github.com/influxdb/influxdb/services/hh"
 	"github.com/influxdb/influxdb/services/httpd"
+	"github.com/influxdb/influxdb/services/input"
+	"github.com/influxdb/influxdb/services/monitor"
 	"github.com/influxdb/influxdb/services/opentsdb"
 	"github.com/influxdb/influxdb/services/precreator"
 	"github.com/influxdb/influxdb/services/retention"
@@ -85,6 +90,20 @@ func NewServer(c *Config, version string) (*Server, error) {
 		reportingDisabled: c.ReportingDisabled,
 	}
 
+	if c.Data.BlockCompression != "" && tsdb.CodecByName(c.Data.BlockCompression) == nil {
+		return nil, fmt.Errorf("unknown block compression: %s", c.Data.BlockCompression)
+	}
+
+	if c.Cluster.ShardHasher != "" {
+		h := tsdb.HasherByName(c.Cluster.ShardHasher)
+		if h == nil {
+			return nil, fmt.Errorf("unknown shard hasher: %s", c.Cluster.ShardHasher)
+		}
+		tsdb.SetHasher(h)
+	}
+	tsdb.SetConsistentBuckets(c.Cluster.ConsistentShardHashing)
+	cluster.SetMessageChecksums(c.Cluster.MessageChecksums)
+
 	// Copy TSDB configuration.
 	s.TSDBStore.EngineOptions.MaxWALSize = c.Data.MaxWALSize
 	s.TSDBStore.EngineOptions.WALFlushInterval = time.Duration(c.Data.WALFlushInterval)
@@ -95,16 +114,41 @@ func NewServer(c *Config, version string) (*Server, error) {
 	s.ShardMapper.ForceRemoteMapping = c.Cluster.ForceRemoteShardMapping
 	s.ShardMapper.MetaStore = s.MetaStore
 	s.ShardMapper.TSDBStore = s.TSDBStore
+	switch c.Cluster.Transport {
+	case cluster.TransportHTTP2:
+		if !cluster.HTTP2Supported {
+			return nil, cluster.ErrHTTP2NotSupported
+		}
+		s.ShardMapper.Transport = c.Cluster.Transport
+	case "", cluster.TransportTCP:
+		s.ShardMapper.Transport = c.Cluster.Transport
+	default:
+		return nil, fmt.Errorf("unknown cluster transport: %s", c.Cluster.Transport)
+	}
 
 	// Initialize query executor.
 	s.QueryExecutor = tsdb.NewQueryExecutor(s.TSDBStore)
 	s.QueryExecutor.MetaStore = s.MetaStore
-	s.QueryExecutor.MetaStatementExecutor = &meta.StatementExecutor{Store: s.MetaStore}
+	s.QueryExecutor.MetaStatementExecutor = &meta.StatementExecutor{
+		Store:         s.MetaStore,
+		TrashDropped:  c.Data.TrashRetention > 0,
+		ExpireAltered: c.Retention.Enabled,
+	}
 	s.QueryExecutor.ShardMapper = s.ShardMapper
+	s.QueryExecutor.MaxRowLimit = c.Cluster.MaxRowLimit
+	s.QueryExecutor.NumericTagOrder = c.Cluster.NumericTagOrder
 
 	// Set the shard writer
 	s.ShardWriter = cluster.NewShardWriter(time.Duration(c.Cluster.ShardWriterTimeout))
 	s.ShardWriter.MetaStore = s.MetaStore
+	s.ShardWriter.RetryPolicy = cluster.NewShardWriterRetryPolicy(c.Cluster)
+	s.ShardWriter.Transport = c.Cluster.Transport
+	if c.Cluster.WireCompression != "" {
+		s.ShardWriter.Codec = tsdb.CodecByName(c.Cluster.WireCompression)
+		if s.ShardWriter.Codec == nil {
+			return nil, fmt.Errorf("unknown wire compression: %s", c.Cluster.WireCompression)
+		}
+	}
 
 	// Create the hinted handoff service
 	s.HintedHandoff = hh.NewService(c.HintedHandoff, s.ShardWriter)
@@ -116,26 +160,69 @@ func NewServer(c *Config, version string) (*Server, error) {
 	s.PointsWriter.TSDBStore = s.TSDBStore
 	s.PointsWriter.ShardWriter = s.ShardWriter
 	s.PointsWriter.HintedHandoff = s.HintedHandoff
+	s.PointsWriter.ShardTimeRanges = s.MetaStore
+	if c.Cluster.DeadLetterDir != "" {
+		s.PointsWriter.DeadLetters = cluster.NewDeadLetterQueue(c.Cluster.DeadLetterDir)
+	}
+	if c.Cluster.TimestampPolicy != "" || len(c.Cluster.TimestampPolicies) > 0 {
+		tc, err := cluster.NewTimestampChecker(c.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		s.PointsWriter.TimestampChecker = tc
+	}
+	if c.Cluster.TagLimit > 0 || len(c.Cluster.TagLimits) > 0 {
+		tl, err := cluster.NewTagLimiter(c.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		s.PointsWriter.TagLimiter = tl
+	}
+	if c.Cluster.SeriesCreationRate > 0 || len(c.Cluster.SeriesCreationRates) > 0 {
+		sl, err := cluster.NewSeriesCreationLimiter(c.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		sl.TSDBStore = s.TSDBStore
+		s.PointsWriter.SeriesCreationLimiter = sl
+	}
+	if c.Cluster.MaxFutureSkew > 0 {
+		fc, err := cluster.NewFutureSkewChecker(c.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		s.PointsWriter.FutureSkewChecker = fc
+	}
+	if len(c.Cluster.FixedSchemaDatabases) > 0 {
+		mc := cluster.NewMeasurementCreationChecker(c.Cluster)
+		mc.TSDBStore = s.TSDBStore
+		s.PointsWriter.MeasurementCreationChecker = mc
+	}
+	ackMode, ackModes, err := cluster.ParseAckModes(c.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	s.PointsWriter.AckMode, s.PointsWriter.AckModes = ackMode, ackModes
+	if len(c.Cluster.DenyMeasurements) > 0 || len(c.Cluster.DenySeries) > 0 {
+		wf, err := cluster.NewWriteFilter(c.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		s.PointsWriter.WriteFilter = wf
+	}
 
 	// Append services.
 	s.appendClusterService(c.Cluster)
 	s.appendPrecreatorService(c.Precreator)
 	s.appendSnapshotterService()
-	s.appendAdminService(c.Admin)
 	s.appendContinuousQueryService(c.ContinuousQuery)
 	s.appendHTTPDService(c.HTTPD)
-	s.appendCollectdService(c.Collectd)
-	if err := s.appendOpenTSDBService(c.OpenTSDB); err != nil {
-		return nil, err
-	}
-	for _, g := range c.UDPs {
-		s.appendUDPService(g)
-	}
+	s.appendGRPCService(c.GRPC)
+	s.appendAdminService(c.Admin)
 	s.appendRetentionPolicyService(c.Retention)
-	for _, g := range c.Graphites {
-		if err := s.appendGraphiteService(g); err != nil {
-			return nil, err
-		}
+	s.appendMonitorService(c.Monitoring)
+	if err := s.appendInputServices(c); err != nil {
+		return nil, err
 	}
 
 	return s, nil
@@ -164,6 +251,18 @@ func (s *Server) appendRetentionPolicyService(c retention.Config) {
 	srv := retention.NewService(c)
 	srv.MetaStore = s.MetaStore
 	srv.TSDBStore = s.TSDBStore
+	srv.TrashRetention = time.Duration(s.TSDBStore.EngineOptions.Config.TrashRetention)
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendMonitorService(c monitor.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := monitor.NewMonitor(c)
+	srv.MetaStore = s.MetaStore
+	srv.TSDBStore = s.TSDBStore
+	srv.PointsWriter = s.PointsWriter
 	s.Services = append(s.Services, srv)
 }
 
@@ -172,6 +271,14 @@ func (s *Server) appendAdminService(c admin.Config) {
 		return
 	}
 	srv := admin.NewService(c)
+
+	// Serve the API requests of the interface with the HTTP handler, so they
+	// require the same authentication.
+	for _, svc := range s.Services {
+		if h, ok := svc.(*httpd.Service); ok {
+			srv.Handler = h.Handler
+		}
+	}
 	s.Services = append(s.Services, srv)
 }
 
@@ -183,6 +290,11 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.Handler.MetaStore = s.MetaStore
 	srv.Handler.QueryExecutor = s.QueryExecutor
 	srv.Handler.PointsWriter = s.PointsWriter
+	srv.Handler.SeriesDropper = &cluster.SeriesDropper{
+		MetaStore:   s.MetaStore,
+		TSDBStore:   s.TSDBStore,
+		ShardWriter: s.ShardWriter,
+	}
 	srv.Handler.Version = s.version
 
 	// If a ContinuousQuerier service has been started, attach it.
@@ -195,66 +307,84 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	s.Services = append(s.Services, srv)
 }
 
-func (s *Server) appendCollectdService(c collectd.Config) {
+func (s *Server) appendGRPCService(c grpcd.Config) {
 	if !c.Enabled {
 		return
 	}
-	srv := collectd.NewService(c)
+	srv := grpcd.NewService(c)
 	srv.MetaStore = s.MetaStore
+	srv.QueryExecutor = s.QueryExecutor
 	srv.PointsWriter = s.PointsWriter
 	s.Services = append(s.Services, srv)
 }
 
-func (s *Server) appendOpenTSDBService(c opentsdb.Config) error {
+func (s *Server) appendPrecreatorService(c precreator.Config) error {
 	if !c.Enabled {
 		return nil
 	}
-	srv, err := opentsdb.NewService(c)
+	srv, err := precreator.NewService(c)
 	if err != nil {
 		return err
 	}
-	srv.PointsWriter = s.PointsWriter
+
 	srv.MetaStore = s.MetaStore
 	s.Services = append(s.Services, srv)
 	return nil
 }
 
-func (s *Server) appendGraphiteService(c graphite.Config) error {
-	if !c.Enabled {
-		return nil
+// appendInputServices appends the services receiving points over an input
+// protocol. Built-in inputs have their own config sections while registered
+// inputs are configured in "[input.<name>]" sections.
+func (s *Server) appendInputServices(c *Config) error {
+	var inputs []input.Service
+	if c.Collectd.Enabled {
+		inputs = append(inputs, collectd.NewService(c.Collectd))
 	}
-	srv, err := graphite.NewService(c)
-	if err != nil {
-		return err
+	if c.OpenTSDB.Enabled {
+		srv, err := opentsdb.NewService(c.OpenTSDB)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, srv)
 	}
-
-	srv.PointsWriter = s.PointsWriter
-	srv.MetaStore = s.MetaStore
-	s.Services = append(s.Services, srv)
-	return nil
-}
-
-func (s *Server) appendPrecreatorService(c precreator.Config) error {
-	if !c.Enabled {
-		return nil
+	for _, u := range c.UDPs {
+		if u.Enabled {
+			inputs = append(inputs, udp.NewService(u))
+		}
 	}
-	srv, err := precreator.NewService(c)
-	if err != nil {
-		return err
+	for _, g := range c.Graphites {
+		if !g.Enabled {
+			continue
+		}
+		srv, err := graphite.NewService(g)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, srv)
 	}
 
-	srv.MetaStore = s.MetaStore
-	s.Services = append(s.Services, srv)
-	return nil
-}
+	names := make([]string, 0, len(c.Inputs))
+	for name := range c.Inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prim := c.Inputs[name]
+		a, err := input.New(name, func(v interface{}) error { return toml.PrimitiveDecode(prim, v) })
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, a...)
+	}
 
-func (s *Server) appendUDPService(c udp.Config) {
-	if !c.Enabled {
-		return
+	for _, srv := range inputs {
+		srv.SetPointsWriter(s.PointsWriter)
+		if m, ok := srv.(input.MetaStoreSetter); ok {
+			m.SetMetaStore(s.MetaStore)
+		}
+		s.Services = append(s.Services, srv)
 	}
-	srv := udp.NewService(c)
-	srv.PointsWriter = s.PointsWriter
-	s.Services = append(s.Services, srv)
+	return nil
 }
 
 func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
@@ -309,6 +439,7 @@ func (s *Server) Open() error {
 		s.MetaStore.RPCListener = mux.Listen(meta.MuxRPCHeader)
 
 		s.ClusterService.Listener = mux.Listen(cluster.MuxHeader)
+		s.ClusterService.HTTPListener = mux.Listen(cluster.HTTPMuxHeader)
 		s.SnapshotterService.Listener = mux.Listen(snapshotter.MuxHeader)
 		go mux.Serve(ln)
 
@@ -320,6 +451,7 @@ func (s *Server) Open() error {
 
 		// Wait for the store to initialize.
 		<-s.MetaStore.Ready()
+		go s.monitorTopology()
 
 		// Open TSDB store.
 		if err := s.TSDBStore.Open(); err != nil {
@@ -451,6 +583,24 @@ func (s *Server) monitorErrorChan(ch <-chan error) {
 	}
 }
 
+// monitorTopology closes the connections to nodes that are removed or moved
+// in the meta store, so requests to them fail instead of timing out.
+func (s *Server) monitorTopology() {
+	for {
+		if err := s.MetaStore.WaitForDataChanged(); err != nil {
+			return
+		}
+
+		nodes, err := s.MetaStore.Nodes()
+		if err != nil {
+			log.Printf("failed to retrieve nodes: %s", err)
+			continue
+		}
+		s.ShardWriter.EvictStaleNodes(nodes)
+		s.ShardMapper.EvictStaleNodes(nodes)
+	}
+}
+
 // hostAddr returns the host and port that remote nodes will use to reach this
 // node.
 func (s *Server) hostAddr() (string, string, error) {
@@ -0,0 +1,184 @@
+// Package embedded runs the storage and query engine of InfluxDB inside a Go
+// program. A DB is a single node without HTTP, clustering or background
+// services: points are written and queries are executed by calling its
+// methods directly.
+//
+//	db, err := embedded.Open("/var/lib/myapp/influxdb")
+//	...
+//	db.Query("CREATE DATABASE mydb", "")
+//	db.WritePoints("mydb", "", points)
+//	results, err := db.Query("SELECT mean(value) FROM cpu", "mydb")
+package embedded
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
+	_ "github.com/influxdb/influxdb/tsdb/engine"
+)
+
+// DefaultChunkSize is the number of values mappers read at a time.
+const DefaultChunkSize = 10000
+
+// DB is an embedded database stored in a directory. It's safe for concurrent use.
+type DB struct {
+	path string
+
+	metaStore     *metaStore
+	store         *tsdb.Store
+	queryExecutor *tsdb.QueryExecutor
+	pointsWriter  *cluster.PointsWriter
+}
+
+// Open opens the database in a directory, creating it if it doesn't exist.
+// The meta data, the shards and their WAL are kept in the "meta", "data" and
+// "wal" files and directories under path.
+func Open(path string) (*DB, error) {
+	return OpenWithConfig(path, tsdb.NewConfig())
+}
+
+// OpenWithConfig opens the database in a directory with a storage config.
+// The data and WAL directories of the config are set under path.
+func OpenWithConfig(path string, c tsdb.Config) (*DB, error) {
+	if err := os.MkdirAll(path, 0777); err != nil {
+		return nil, err
+	}
+
+	c.Dir = filepath.Join(path, "data")
+	c.WALDir = filepath.Join(path, "wal")
+
+	ms, err := openMetaStore(filepath.Join(path, "meta"))
+	if err != nil {
+		return nil, fmt.Errorf("open meta store: %s", err)
+	}
+
+	db := &DB{
+		path:      path,
+		metaStore: ms,
+		store:     tsdb.NewStore(c.Dir),
+	}
+	db.store.EngineOptions.Config = c
+
+	db.queryExecutor = tsdb.NewQueryExecutor(db.store)
+	db.queryExecutor.MetaStore = ms
+	db.queryExecutor.MetaStatementExecutor = &meta.StatementExecutor{
+		Store:        ms,
+		TrashDropped: c.TrashRetention > 0,
+	}
+	db.queryExecutor.ShardMapper = &shardMapper{store: db.store}
+
+	db.pointsWriter = cluster.NewPointsWriter()
+	db.pointsWriter.MetaStore = ms
+	db.pointsWriter.TSDBStore = db.store
+	db.pointsWriter.ShardTimeRanges = ms
+
+	if err := db.store.Open(); err != nil {
+		return nil, fmt.Errorf("open tsdb store: %s", err)
+	}
+	if err := db.pointsWriter.Open(); err != nil {
+		db.store.Close()
+		return nil, fmt.Errorf("open points writer: %s", err)
+	}
+
+	return db, nil
+}
+
+// Close closes the database.
+func (db *DB) Close() error {
+	db.pointsWriter.Close()
+	return db.store.Close()
+}
+
+// Path returns the directory of the database.
+func (db *DB) Path() string { return db.path }
+
+// WritePoints writes points to a database. The default retention policy of
+// the database is used if retentionPolicy is blank.
+func (db *DB) WritePoints(database, retentionPolicy string, points []tsdb.Point) error {
+	return db.pointsWriter.WritePoints(&cluster.WritePointsRequest{
+		Database:         database,
+		RetentionPolicy:  retentionPolicy,
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		Points:           points,
+	})
+}
+
+// WriteString parses points in the line protocol and writes them to a
+// database. Points without a timestamp are given the current time.
+func (db *DB) WriteString(database, retentionPolicy, lines string) error {
+	points, err := tsdb.ParsePointsString(lines)
+	if err != nil {
+		return err
+	}
+	return db.WritePoints(database, retentionPolicy, points)
+}
+
+// Query executes the statements of a query against a database and returns
+// one result per statement. The database is used by statements that don't
+// name their own. The returned error is either a parse error or the error of
+// the first failed statement, which is also set on its result.
+func (db *DB) Query(command, database string) ([]*influxql.Result, error) {
+	q, err := influxql.NewParser(strings.NewReader(command)).ParseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := db.queryExecutor.ExecuteQuery(q, database, DefaultChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Results for the same statement are combined.
+	var results []*influxql.Result
+	for r := range ch {
+		if r == nil {
+			continue
+		}
+
+		l := len(results)
+		if l == 0 || results[l-1].StatementID != r.StatementID {
+			results = append(results, r)
+			continue
+		}
+
+		cr := results[l-1]
+		if cr.Err == nil && len(cr.Series) > 0 {
+			last := cr.Series[len(cr.Series)-1]
+			for len(r.Series) > 0 && last.SameSeries(r.Series[0]) {
+				last.Values = append(last.Values, r.Series[0].Values...)
+				r.Series = r.Series[1:]
+			}
+		}
+		cr.Series = append(cr.Series, r.Series...)
+		cr.Partial = cr.Partial || r.Partial
+		if cr.Err == nil {
+			cr.Err = r.Err
+		}
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+	return results, nil
+}
+
+// shardMapper creates mappers for shards in the local store.
+type shardMapper struct {
+	store *tsdb.Store
+}
+
+func (m *shardMapper) CreateMapper(sh meta.ShardInfo, stmt string, chunkSize int) (tsdb.Mapper, error) {
+	return m.store.CreateMapper(sh.ID, stmt, chunkSize)
+}
+
+func (m *shardMapper) ShardHasData(sh meta.ShardInfo, stmt string) (bool, error) {
+	return m.store.ShardHasData(sh.ID, stmt)
+}
@@ -0,0 +1,85 @@
+package embedded_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/embedded"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure points written to an embedded database can be queried, including
+// after it's reopened.
+func TestDB_WriteQuery(t *testing.T) {
+	path, err := ioutil.TempDir("", "influxdb-embedded-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := embedded.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Query("CREATE DATABASE db0", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WriteString("db0", "", "cpu,host=serverA value=1 0\ncpu,host=serverB value=3 10000000000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WritePoints("db0", "default", []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 2.0}, time.Unix(20, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `[{"series":[{"name":"cpu","columns":["time","sum"],"values":[["1970-01-01T00:00:00Z",6]]}]}]`
+	if got := mustQuery(t, db, `SELECT sum(value) FROM cpu`, "db0"); got != exp {
+		t.Fatalf("unexpected results:\n\nexp=%s\n\ngot=%s\n", exp, got)
+	}
+
+	// Writes to unknown databases and bad queries are errors.
+	if err := db.WriteString("db1", "", "cpu value=1"); err == nil || err.Error() != "database not found: db1" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := db.Query("SELECT FROM", "db0"); err == nil {
+		t.Fatal("expected parse error")
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the database and read the points back.
+	db, err = embedded.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	exp = `[{"series":[{"name":"databases","columns":["name"],"values":[["db0"]]}]}]`
+	if got := mustQuery(t, db, `SHOW DATABASES`, ""); got != exp {
+		t.Fatalf("unexpected results:\n\nexp=%s\n\ngot=%s\n", exp, got)
+	}
+
+	exp = `[{"series":[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","value"],"values":[["1970-01-01T00:00:00Z",1],["1970-01-01T00:00:20Z",2]]}]}]`
+	if got := mustQuery(t, db, `SELECT value FROM cpu WHERE host = 'serverA' GROUP BY host`, "db0"); got != exp {
+		t.Fatalf("unexpected results:\n\nexp=%s\n\ngot=%s\n", exp, got)
+	}
+}
+
+// mustQuery executes a query and returns its results as JSON.
+func mustQuery(t *testing.T, db *embedded.DB, command, database string) string {
+	results, err := db.Query(command, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,323 @@
+package embedded
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// metaStore keeps the meta data of an embedded database in a single file.
+// There is no raft log: every change is applied to a copy of the data, which
+// is written to disk before it replaces the current data. Readers can hold on
+// to the data they were given since it's never modified in place.
+type metaStore struct {
+	mu   sync.RWMutex
+	path string
+	data *meta.Data
+	id   uint64
+}
+
+// openMetaStore loads the meta data from path, creating the file and the
+// local node if it doesn't exist.
+func openMetaStore(path string) (*metaStore, error) {
+	s := &metaStore{path: path, data: &meta.Data{}}
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := s.update(func(data *meta.Data) error { return data.CreateNode(localHost) }); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if err := s.data.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+
+	ni := s.data.NodeByHost(localHost)
+	if ni == nil {
+		return nil, meta.ErrNodeNotFound
+	}
+	s.id = ni.ID
+
+	return s, nil
+}
+
+// localHost is the host of the only node of an embedded database.
+const localHost = "localhost"
+
+// read returns the current meta data.
+func (s *metaStore) read() *meta.Data {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// update applies fn to a copy of the meta data and saves it.
+func (s *metaStore) update(fn func(data *meta.Data) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	other := s.data.Clone()
+	if err := fn(other); err != nil {
+		return err
+	}
+	other.Index++
+
+	buf, err := other.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave a partial file.
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0666); err != nil {
+		return err
+	} else if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	s.data = other
+	return nil
+}
+
+func (s *metaStore) NodeID() uint64 { return s.id }
+
+func (s *metaStore) Node(id uint64) (*meta.NodeInfo, error) { return s.read().Node(id), nil }
+
+func (s *metaStore) Nodes() ([]meta.NodeInfo, error) { return s.read().Nodes, nil }
+
+// Peers returns no raft peers since an embedded database has none.
+func (s *metaStore) Peers() ([]string, error) { return nil, nil }
+
+func (s *metaStore) Database(name string) (*meta.DatabaseInfo, error) {
+	return s.read().Database(name), nil
+}
+
+func (s *metaStore) Databases() ([]meta.DatabaseInfo, error) { return s.read().Databases, nil }
+
+// CreateDatabase creates a database along with its default retention policy.
+func (s *metaStore) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	if err := s.update(func(data *meta.Data) error {
+		if err := data.CreateDatabase(name); err != nil {
+			return err
+		}
+
+		rpi := meta.NewRetentionPolicyInfo(meta.AutoCreateRetentionPolicyName)
+		rpi.ReplicaN = 1
+		rpi.Duration = meta.AutoCreateRetentionPolicyPeriod
+		if err := data.CreateRetentionPolicy(name, rpi); err != nil {
+			return err
+		}
+		return data.SetDefaultRetentionPolicy(name, rpi.Name)
+	}); err != nil {
+		return nil, err
+	}
+	return s.Database(name)
+}
+
+func (s *metaStore) DropDatabase(name string) error {
+	return s.update(func(data *meta.Data) error { return data.DropDatabase(name) })
+}
+
+func (s *metaStore) TrashDatabase(name string) error {
+	return s.update(func(data *meta.Data) error { return data.TrashDatabase(name, time.Now()) })
+}
+
+func (s *metaStore) RestoreDatabase(name string) error {
+	return s.update(func(data *meta.Data) error { return data.RestoreDatabase(name) })
+}
+
+func (s *metaStore) DroppedDatabases() ([]meta.DroppedDatabaseInfo, error) {
+	return s.read().DroppedDatabases, nil
+}
+
+func (s *metaStore) RetentionPolicy(database, name string) (*meta.RetentionPolicyInfo, error) {
+	return s.read().RetentionPolicy(database, name)
+}
+
+func (s *metaStore) DefaultRetentionPolicy(database string) (*meta.RetentionPolicyInfo, error) {
+	di := s.read().Database(database)
+	if di == nil {
+		return nil, meta.ErrDatabaseNotFound
+	}
+	return di.RetentionPolicy(di.DefaultRetentionPolicy), nil
+}
+
+func (s *metaStore) CreateRetentionPolicy(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error) {
+	if rpi.Duration < meta.MinRetentionPolicyDuration && rpi.Duration != 0 {
+		return nil, meta.ErrRetentionPolicyDurationTooLow
+	}
+	if err := s.update(func(data *meta.Data) error { return data.CreateRetentionPolicy(database, rpi) }); err != nil {
+		return nil, err
+	}
+	return s.RetentionPolicy(database, rpi.Name)
+}
+
+func (s *metaStore) UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate) error {
+	return s.update(func(data *meta.Data) error { return data.UpdateRetentionPolicy(database, name, rpu) })
+}
+
+func (s *metaStore) SetDefaultRetentionPolicy(database, name string) error {
+	return s.update(func(data *meta.Data) error { return data.SetDefaultRetentionPolicy(database, name) })
+}
+
+func (s *metaStore) DropRetentionPolicy(database, name string) error {
+	return s.update(func(data *meta.Data) error { return data.DropRetentionPolicy(database, name) })
+}
+
+func (s *metaStore) DeleteShardGroup(database, policy string, id uint64) error {
+	return s.update(func(data *meta.Data) error { return data.DeleteShardGroup(database, policy, id) })
+}
+
+func (s *metaStore) ShardGroupsByTimeRange(database, policy string, min, max time.Time) ([]meta.ShardGroupInfo, error) {
+	return s.read().ShardGroupsByTimeRange(database, policy, min, max)
+}
+
+// CreateShardGroupIfNotExists returns the shard group of a timestamp,
+// creating it if it doesn't exist.
+func (s *metaStore) CreateShardGroupIfNotExists(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+	if sgi, err := s.read().ShardGroupByTimestamp(database, policy, timestamp); err != nil {
+		return nil, err
+	} else if sgi != nil && !sgi.Deleted() {
+		return sgi, nil
+	}
+
+	if err := s.update(func(data *meta.Data) error {
+		if err := data.CreateShardGroup(database, policy, timestamp); err != meta.ErrShardGroupExists {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s.read().ShardGroupByTimestamp(database, policy, timestamp)
+}
+
+// ShardOwner returns the database, retention policy and shard group of a shard.
+func (s *metaStore) ShardOwner(shardID uint64) (string, string, *meta.ShardGroupInfo) {
+	data := s.read()
+	for _, di := range data.Databases {
+		for _, rpi := range di.RetentionPolicies {
+			for i := range rpi.ShardGroups {
+				if rpi.ShardGroups[i].Deleted() {
+					continue
+				}
+				for _, sh := range rpi.ShardGroups[i].Shards {
+					if sh.ID == shardID {
+						return di.Name, rpi.Name, &rpi.ShardGroups[i]
+					}
+				}
+			}
+		}
+	}
+	return "", "", nil
+}
+
+func (s *metaStore) UpdateShardTimeRange(database, policy string, shardID uint64, min, max time.Time) error {
+	return s.update(func(data *meta.Data) error {
+		return data.UpdateShardTimeRange(database, policy, shardID, min, max)
+	})
+}
+
+func (s *metaStore) User(name string) (*meta.UserInfo, error) { return s.read().User(name), nil }
+
+func (s *metaStore) Users() ([]meta.UserInfo, error) { return s.read().Users, nil }
+
+func (s *metaStore) UserCount() (int, error) { return len(s.read().Users), nil }
+
+func (s *metaStore) AdminUserExists() (bool, error) {
+	for _, u := range s.read().Users {
+		if u.Admin {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *metaStore) Authenticate(username, password string) (*meta.UserInfo, error) {
+	u := s.read().User(username)
+	if u == nil {
+		return nil, meta.ErrUserNotFound
+	} else if err := bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(password)); err != nil {
+		return nil, meta.ErrAuthenticate
+	}
+	return u, nil
+}
+
+func (s *metaStore) CreateUser(name, password string, admin bool) (*meta.UserInfo, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), meta.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.update(func(data *meta.Data) error { return data.CreateUser(name, string(hash), admin) }); err != nil {
+		return nil, err
+	}
+	return s.User(name)
+}
+
+func (s *metaStore) UpdateUser(name, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), meta.BcryptCost)
+	if err != nil {
+		return err
+	}
+	return s.update(func(data *meta.Data) error { return data.UpdateUser(name, string(hash)) })
+}
+
+func (s *metaStore) DropUser(name string) error {
+	return s.update(func(data *meta.Data) error { return data.DropUser(name) })
+}
+
+func (s *metaStore) SetPrivilege(username, database string, p influxql.Privilege) error {
+	return s.update(func(data *meta.Data) error { return data.SetPrivilege(username, database, p) })
+}
+
+func (s *metaStore) SetAdminPrivilege(username string, admin bool) error {
+	return s.update(func(data *meta.Data) error { return data.SetAdminPrivilege(username, admin) })
+}
+
+func (s *metaStore) UserPrivileges(username string) (map[string]influxql.Privilege, error) {
+	return s.read().UserPrivileges(username)
+}
+
+func (s *metaStore) UserPrivilege(username, database string) (*influxql.Privilege, error) {
+	return s.read().UserPrivilege(username, database)
+}
+
+func (s *metaStore) CreateContinuousQuery(database, name, query string) error {
+	return s.update(func(data *meta.Data) error { return data.CreateContinuousQuery(database, name, query) })
+}
+
+func (s *metaStore) DropContinuousQuery(database, name string) error {
+	return s.update(func(data *meta.Data) error { return data.DropContinuousQuery(database, name) })
+}
+
+func (s *metaStore) SetInterval(name string, d time.Duration) error {
+	return s.update(func(data *meta.Data) error { return data.SetInterval(name, d) })
+}
+
+func (s *metaStore) RenameMeasurement(database, name, newName string) error {
+	return s.update(func(data *meta.Data) error { return data.RenameMeasurement(database, name, newName) })
+}
+
+func (s *metaStore) RenameTagKey(database, measurement, key, newKey string) error {
+	return s.update(func(data *meta.Data) error { return data.RenameTagKey(database, measurement, key, newKey) })
+}
+
+func (s *metaStore) RenameTagValue(database, measurement, key, value, newValue string) error {
+	return s.update(func(data *meta.Data) error {
+		return data.RenameTagValue(database, measurement, key, value, newValue)
+	})
+}
+
+func (s *metaStore) CreateTagAlias(database, key, value, alias string) error {
+	return s.update(func(data *meta.Data) error { return data.CreateTagAlias(database, key, value, alias) })
+}
+
+func (s *metaStore) DropTagAlias(database, key, value, alias string) error {
+	return s.update(func(data *meta.Data) error { return data.DropTagAlias(database, key, value, alias) })
+}
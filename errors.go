@@ -14,6 +14,23 @@ var (
 
 	// ErrFieldTypeConflict is returned when a new field already exists with a different type.
 	ErrFieldTypeConflict = errors.New("field type conflict")
+
+	// ErrImplausibleTimestamp is returned when a point's timestamp is likely in the wrong precision.
+	ErrImplausibleTimestamp = errors.New("implausible timestamp")
+
+	// ErrFutureTimestamp is returned when a point's timestamp is too far ahead of the server's clock.
+	ErrFutureTimestamp = errors.New("timestamp too far in the future")
+
+	// ErrMeasurementCreationDisabled is returned when a write would create a
+	// measurement in a database that doesn't allow new measurements.
+	ErrMeasurementCreationDisabled = errors.New("measurement creation disabled")
+
+	// ErrTooManyTags is returned when a point has more tags than its database allows.
+	ErrTooManyTags = errors.New("too many tags")
+
+	// ErrSeriesCreationRateExceeded is returned when a write creates more
+	// series per second than its database allows.
+	ErrSeriesCreationRateExceeded = errors.New("series creation rate exceeded")
 )
 
 func ErrDatabaseNotFound(name string) error { return fmt.Errorf("database not found: %s", name) }
@@ -47,6 +64,26 @@ func IsClientError(err error) bool {
 		return true
 	}
 
+	if strings.Contains(err.Error(), ErrImplausibleTimestamp.Error()) {
+		return true
+	}
+
+	if strings.Contains(err.Error(), ErrFutureTimestamp.Error()) {
+		return true
+	}
+
+	if strings.Contains(err.Error(), ErrMeasurementCreationDisabled.Error()) {
+		return true
+	}
+
+	if strings.Contains(err.Error(), ErrTooManyTags.Error()) {
+		return true
+	}
+
+	if strings.Contains(err.Error(), ErrSeriesCreationRateExceeded.Error()) {
+		return true
+	}
+
 	return false
 }
 
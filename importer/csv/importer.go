@@ -0,0 +1,135 @@
+package csv
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/tsdb"
+	tsdbcsv "github.com/influxdb/influxdb/tsdb/csv"
+)
+
+const batchSize = 5000
+
+// Config is the config used to initialize a CSV importer
+type Config struct {
+	Username         string
+	Password         string
+	URL              url.URL
+	Database         string
+	RetentionPolicy  string
+	WriteConsistency string
+	Path             string
+	Version          string
+	Compressed       bool
+	Spec             tsdbcsv.Spec
+}
+
+// NewConfig returns an initialized *Config
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// Importer writes the rows of a CSV file as points, converting them with the
+// column mapping of the config.
+type Importer struct {
+	client       *client.Client
+	config       *Config
+	batch        []string
+	totalInserts int
+}
+
+// NewImporter will return an intialized Importer struct
+func NewImporter(config *Config) *Importer {
+	return &Importer{
+		config: config,
+		batch:  make([]string, 0, batchSize),
+	}
+}
+
+// Import converts the file specified in the Config and writes the points in
+// chunks specified by batchSize. It stops at the first malformed row or
+// failed write; the batches before it are already written.
+func (i *Importer) Import() error {
+	if i.config.Path == "" {
+		return fmt.Errorf("file argument required")
+	} else if i.config.Database == "" {
+		return fmt.Errorf("database argument required")
+	} else if err := i.config.Spec.Validate(); err != nil {
+		return err
+	}
+
+	// Create a client and try to connect
+	config := client.NewConfig()
+	config.URL = i.config.URL
+	config.Username = i.config.Username
+	config.Password = i.config.Password
+	config.UserAgent = fmt.Sprintf("influxDB importer/%s", i.config.Version)
+	cl, err := client.NewClient(config)
+	if err != nil {
+		return fmt.Errorf("could not create client %s", err)
+	}
+	i.client = cl
+	if _, _, e := i.client.Ping(); e != nil {
+		return fmt.Errorf("failed to connect to %s\n", i.client.Addr())
+	}
+
+	f, err := os.Open(i.config.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if i.config.Compressed {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	start := time.Now()
+	defer func() {
+		log.Printf("Processed %d inserts in %s\n", i.totalInserts, time.Since(start))
+	}()
+
+	cr := tsdbcsv.NewReader(r, i.config.Spec)
+	for cr.Next() {
+		if err := i.batchAccumulator(cr.Point()); err != nil {
+			return err
+		}
+	}
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	return i.batchWrite()
+}
+
+func (i *Importer) batchAccumulator(pt tsdb.Point) error {
+	i.batch = append(i.batch, pt.String())
+	if len(i.batch) < batchSize {
+		return nil
+	}
+	return i.batchWrite()
+}
+
+func (i *Importer) batchWrite() error {
+	if len(i.batch) == 0 {
+		return nil
+	}
+
+	if _, err := i.client.WriteLineProtocol(strings.Join(i.batch, "\n"), i.config.Database, i.config.RetentionPolicy, "n", i.config.WriteConsistency); err != nil {
+		return fmt.Errorf("error writing batch: %s", err)
+	}
+	i.totalInserts += len(i.batch)
+	i.batch = i.batch[:0]
+	return nil
+}
@@ -5,9 +5,11 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +29,11 @@ type Config struct {
 	Version          string
 	Compressed       bool
 	PPS              int
+
+	// ResumeFile records the number of lines of the file that were imported
+	// after each batch, so an interrupted import can be run again to resume
+	// after the last batch written. It's removed once the import completes.
+	ResumeFile string
 }
 
 // NewConfig returns an initialized *Config
@@ -47,6 +54,8 @@ type Importer struct {
 	throttlePointsWritten int
 	lastWrite             time.Time
 	throttle              *time.Ticker
+	line                  int // number of lines read from the file
+	resumeLine            int // number of lines imported by a previous run
 }
 
 // NewImporter will return an intialized Importer struct
@@ -79,6 +88,18 @@ func (i *Importer) Import() error {
 		return fmt.Errorf("file argument required")
 	}
 
+	// Skip the lines imported by an interrupted import.
+	if i.config.ResumeFile != "" {
+		n, err := readResumeFile(i.config.ResumeFile)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("Resuming import after line %d\n", n)
+		}
+		i.resumeLine = n
+	}
+
 	defer func() {
 		if i.totalInserts > 0 {
 			log.Printf("Processed %d commands\n", i.totalCommands)
@@ -132,17 +153,26 @@ func (i *Importer) Import() error {
 		return fmt.Errorf("reading standard input: %s", err)
 	}
 
+	// The import completed, so there's nothing left to resume.
+	if i.config.ResumeFile != "" {
+		if err := os.Remove(i.config.ResumeFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (i *Importer) processDDL(scanner *bufio.Scanner) {
 	for scanner.Scan() {
+		i.line++
 		line := scanner.Text()
 		// If we find the DML token, we are done with DDL
 		if strings.HasPrefix(line, "# DML") {
 			return
 		}
-		if strings.HasPrefix(line, "#") {
+		// Commands were executed by the import being resumed
+		if strings.HasPrefix(line, "#") || i.line <= i.resumeLine {
 			continue
 		}
 		i.queryExecutor(line)
@@ -152,6 +182,7 @@ func (i *Importer) processDDL(scanner *bufio.Scanner) {
 func (i *Importer) processDML(scanner *bufio.Scanner) {
 	start := time.Now()
 	for scanner.Scan() {
+		i.line++
 		line := scanner.Text()
 		if strings.HasPrefix(line, "# CONTEXT-DATABASE:") {
 			i.database = strings.TrimSpace(strings.Split(line, ":")[1])
@@ -159,11 +190,18 @@ func (i *Importer) processDML(scanner *bufio.Scanner) {
 		if strings.HasPrefix(line, "# CONTEXT-RETENTION-POLICY:") {
 			i.retentionPolicy = strings.TrimSpace(strings.Split(line, ":")[1])
 		}
-		if strings.HasPrefix(line, "#") {
+		// The context is still read from the lines imported by the import
+		// being resumed, but their points aren't written again
+		if strings.HasPrefix(line, "#") || i.line <= i.resumeLine {
 			continue
 		}
 		i.batchAccumulator(line, start)
 	}
+
+	// Write the last, partial batch
+	if len(i.batch) > 0 {
+		i.processBatch(start)
+	}
 }
 
 func (i *Importer) execute(command string) {
@@ -185,23 +223,33 @@ func (i *Importer) queryExecutor(command string) {
 func (i *Importer) batchAccumulator(line string, start time.Time) {
 	i.batch = append(i.batch, line)
 	if len(i.batch) == batchSize {
-		if e := i.batchWrite(); e != nil {
-			log.Println("error writing batch: ", e)
-			// Output failed lines to STDOUT so users can capture lines that failed to import
-			fmt.Println(strings.Join(i.batch, "\n"))
-			i.failedInserts += len(i.batch)
-		} else {
-			i.totalInserts += len(i.batch)
-		}
-		i.batch = i.batch[:0]
-		// Give some status feedback every 100000 lines processed
-		processed := i.totalInserts + i.failedInserts
-		if processed%100000 == 0 {
-			since := time.Since(start)
-			pps := float64(processed) / since.Seconds()
-			log.Printf("Processed %d lines.  Time elapsed: %s.  Points per second (PPS): %d", processed, since.String(), int64(pps))
+		i.processBatch(start)
+	}
+}
+
+func (i *Importer) processBatch(start time.Time) {
+	if e := i.batchWrite(); e != nil {
+		log.Println("error writing batch: ", e)
+		// Output failed lines to STDOUT so users can capture lines that failed to import
+		fmt.Println(strings.Join(i.batch, "\n"))
+		i.failedInserts += len(i.batch)
+	} else {
+		i.totalInserts += len(i.batch)
+		// Record the progress of the import
+		if i.config.ResumeFile != "" {
+			if err := writeResumeFile(i.config.ResumeFile, i.line); err != nil {
+				log.Printf("error writing resume file: %s\n", err)
+			}
 		}
 	}
+	i.batch = i.batch[:0]
+	// Give some status feedback every 100000 lines processed
+	processed := i.totalInserts + i.failedInserts
+	if processed%100000 == 0 {
+		since := time.Since(start)
+		pps := float64(processed) / since.Seconds()
+		log.Printf("Processed %d lines.  Time elapsed: %s.  Points per second (PPS): %d", processed, since.String(), int64(pps))
+	}
 }
 
 func (i *Importer) batchWrite() error {
@@ -234,3 +282,30 @@ func (i *Importer) batchWrite() error {
 	i.lastWrite = time.Now()
 	return e
 }
+
+// readResumeFile returns the number of lines imported according to the resume
+// file at path, or zero if there's no resume file.
+func readResumeFile(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid resume file %s: %q", path, b)
+	}
+	return n, nil
+}
+
+// writeResumeFile records that the first n lines were imported. The file is
+// replaced by a rename so an interruption never leaves it truncated.
+func writeResumeFile(path string, n int) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(n)+"\n"), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
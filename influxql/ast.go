@@ -82,6 +82,7 @@ func (*AlterRetentionPolicyStatement) node()  {}
 func (*CreateContinuousQueryStatement) node() {}
 func (*CreateDatabaseStatement) node()        {}
 func (*CreateRetentionPolicyStatement) node() {}
+func (*CreateTagAliasStatement) node()        {}
 func (*CreateUserStatement) node()            {}
 func (*Distinct) node()                       {}
 func (*DeleteStatement) node()                {}
@@ -90,17 +91,24 @@ func (*DropDatabaseStatement) node()          {}
 func (*DropMeasurementStatement) node()       {}
 func (*DropRetentionPolicyStatement) node()   {}
 func (*DropSeriesStatement) node()            {}
+func (*DropTagAliasStatement) node()          {}
 func (*DropUserStatement) node()              {}
 func (*GrantStatement) node()                 {}
 func (*GrantAdminStatement) node()            {}
+func (*RenameMeasurementStatement) node()     {}
+func (*RenameTagKeyStatement) node()          {}
+func (*RenameTagValueStatement) node()        {}
 func (*RevokeStatement) node()                {}
 func (*RevokeAdminStatement) node()           {}
+func (*RestoreDatabaseStatement) node()       {}
 func (*SelectStatement) node()                {}
 func (*SetPasswordUserStatement) node()       {}
+func (*SetIntervalStatement) node()           {}
 func (*ShowContinuousQueriesStatement) node() {}
 func (*ShowGrantsForUserStatement) node()     {}
 func (*ShowServersStatement) node()           {}
 func (*ShowDatabasesStatement) node()         {}
+func (*ShowDroppedDatabasesStatement) node()  {}
 func (*ShowFieldKeysStatement) node()         {}
 func (*ShowRetentionPoliciesStatement) node() {}
 func (*ShowMeasurementsStatement) node()      {}
@@ -119,6 +127,7 @@ func (Dimensions) node()       {}
 func (*DurationLiteral) node() {}
 func (*Field) node()           {}
 func (Fields) node()           {}
+func (*ListLiteral) node()     {}
 func (*Measurement) node()     {}
 func (Measurements) node()     {}
 func (*nilLiteral) node()      {}
@@ -188,6 +197,7 @@ func (*AlterRetentionPolicyStatement) stmt()  {}
 func (*CreateContinuousQueryStatement) stmt() {}
 func (*CreateDatabaseStatement) stmt()        {}
 func (*CreateRetentionPolicyStatement) stmt() {}
+func (*CreateTagAliasStatement) stmt()        {}
 func (*CreateUserStatement) stmt()            {}
 func (*DeleteStatement) stmt()                {}
 func (*DropContinuousQueryStatement) stmt()   {}
@@ -195,13 +205,19 @@ func (*DropDatabaseStatement) stmt()          {}
 func (*DropMeasurementStatement) stmt()       {}
 func (*DropRetentionPolicyStatement) stmt()   {}
 func (*DropSeriesStatement) stmt()            {}
+func (*DropTagAliasStatement) stmt()          {}
 func (*DropUserStatement) stmt()              {}
 func (*GrantStatement) stmt()                 {}
 func (*GrantAdminStatement) stmt()            {}
+func (*RenameMeasurementStatement) stmt()     {}
+func (*RenameTagKeyStatement) stmt()          {}
+func (*RenameTagValueStatement) stmt()        {}
+func (*RestoreDatabaseStatement) stmt()       {}
 func (*ShowContinuousQueriesStatement) stmt() {}
 func (*ShowGrantsForUserStatement) stmt()     {}
 func (*ShowServersStatement) stmt()           {}
 func (*ShowDatabasesStatement) stmt()         {}
+func (*ShowDroppedDatabasesStatement) stmt()  {}
 func (*ShowFieldKeysStatement) stmt()         {}
 func (*ShowMeasurementsStatement) stmt()      {}
 func (*ShowRetentionPoliciesStatement) stmt() {}
@@ -215,6 +231,7 @@ func (*RevokeStatement) stmt()                {}
 func (*RevokeAdminStatement) stmt()           {}
 func (*SelectStatement) stmt()                {}
 func (*SetPasswordUserStatement) stmt()       {}
+func (*SetIntervalStatement) stmt()           {}
 
 // Expr represents an expression that can be evaluated to a value.
 type Expr interface {
@@ -227,6 +244,7 @@ func (*BooleanLiteral) expr()  {}
 func (*Call) expr()            {}
 func (*Distinct) expr()        {}
 func (*DurationLiteral) expr() {}
+func (*ListLiteral) expr()     {}
 func (*nilLiteral) expr()      {}
 func (*NumberLiteral) expr()   {}
 func (*ParenExpr) expr()       {}
@@ -336,6 +354,25 @@ func (s *DropDatabaseStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// RestoreDatabaseStatement represents a command to restore a dropped database.
+type RestoreDatabaseStatement struct {
+	// Name of the database to be restored.
+	Name string
+}
+
+// String returns a string representation of the restore database statement.
+func (s *RestoreDatabaseStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("RESTORE DATABASE ")
+	_, _ = buf.WriteString(s.Name)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a RestoreDatabaseStatement.
+func (s *RestoreDatabaseStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // DropRetentionPolicyStatement represents a command to drop a retention policy from a database.
 type DropRetentionPolicyStatement struct {
 	// Name of the policy to drop.
@@ -513,6 +550,42 @@ func (s *SetPasswordUserStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// Names of the intervals that can be changed with a SetIntervalStatement.
+const (
+	RetentionCheckInterval     = "retention-check"
+	ContinuousQueryRunInterval = "continuous-query-run"
+)
+
+// SetIntervalStatement represents a command for changing how often a
+// background service runs on every node.
+type SetIntervalStatement struct {
+	// Name of the interval: RetentionCheckInterval or ContinuousQueryRunInterval.
+	Name string
+
+	// The new interval. Zero restores the configured interval.
+	Interval time.Duration
+}
+
+// String returns a string representation of the set interval statement.
+func (s *SetIntervalStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SET ")
+	switch s.Name {
+	case RetentionCheckInterval:
+		_, _ = buf.WriteString("RETENTION CHECK")
+	case ContinuousQueryRunInterval:
+		_, _ = buf.WriteString("CONTINUOUS QUERY RUN")
+	}
+	_, _ = buf.WriteString(" INTERVAL ")
+	_, _ = buf.WriteString(FormatDuration(s.Interval))
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a SetIntervalStatement.
+func (s *SetIntervalStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // RevokeStatement represents a command to revoke a privilege from a user.
 type RevokeStatement struct {
 	// The privilege to be revoked.
@@ -615,6 +688,9 @@ type AlterRetentionPolicyStatement struct {
 	// Replication factor for data written to this policy.
 	Replication *int
 
+	// Duration of the shard groups created for this policy.
+	ShardGroupDuration *time.Duration
+
 	// Should this policy be set as defalut for the database?
 	Default bool
 }
@@ -637,6 +713,11 @@ func (s *AlterRetentionPolicyStatement) String() string {
 		_, _ = buf.WriteString(strconv.Itoa(*s.Replication))
 	}
 
+	if s.ShardGroupDuration != nil {
+		_, _ = buf.WriteString(" SHARD DURATION ")
+		_, _ = buf.WriteString(FormatDuration(*s.ShardGroupDuration))
+	}
+
 	if s.Default {
 		_, _ = buf.WriteString(" DEFAULT")
 	}
@@ -1005,6 +1086,10 @@ func (s *SelectStatement) validate(tr targetRequirement) error {
 		return err
 	}
 
+	if err := s.validateInterpolate(); err != nil {
+		return err
+	}
+
 	if err := s.validateWildcard(); err != nil {
 		return err
 	}
@@ -1041,7 +1126,7 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 				if min, max, got := 1, 2, len(c.Args); got > max || got < min {
 					return fmt.Errorf("invalid number of arguments for %s, expected at least %d but no more than %d, got %d", c.Name, min, max, got)
 				}
-			case "percentile":
+			case "percentile", "sample", "histogram_percentile":
 				if exp, got := 2, len(c.Args); got != exp {
 					return fmt.Errorf("invalid number of arguments for %s, expected %d, got %d", c.Name, exp, got)
 				}
@@ -1212,6 +1297,32 @@ func (s *SelectStatement) validateDerivative() error {
 	return nil
 }
 
+func (s *SelectStatement) validateInterpolate() error {
+	for _, f := range s.Fields {
+		// interpolate must wrap an aggregate over the GROUP BY time windows
+		if c, ok := f.Expr.(*Call); ok && c.Name == "interpolate" {
+			if len(c.Args) != 1 {
+				continue // reported by validateAggregates
+			}
+			if _, ok := c.Args[0].(*Call); !ok {
+				return fmt.Errorf("interpolate requires an aggregate function argument")
+			}
+			if d, _ := s.GroupByInterval(); d == 0 {
+				return fmt.Errorf("interpolate requires a GROUP BY time interval")
+			}
+			continue
+		}
+
+		// interpolate fills a whole column so it can't be part of an expression
+		for _, c := range walkFunctionCalls(f.Expr) {
+			if c.Name == "interpolate" {
+				return fmt.Errorf("interpolate cannot be used in an expression")
+			}
+		}
+	}
+	return nil
+}
+
 // GroupByIterval extracts the time interval, if specified.
 func (s *SelectStatement) GroupByInterval() (time.Duration, error) {
 	// return if we've already pulled it out
@@ -1668,6 +1779,17 @@ func (s *ShowDatabasesStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// ShowDroppedDatabasesStatement represents a command for listing dropped databases.
+type ShowDroppedDatabasesStatement struct{}
+
+// String returns a string representation of the list dropped databases command.
+func (s *ShowDroppedDatabasesStatement) String() string { return "SHOW DROPPED DATABASES" }
+
+// RequiredPrivileges returns the privilege required to execute a ShowDroppedDatabasesStatement
+func (s *ShowDroppedDatabasesStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // CreateContinuousQueryStatement represents a command for creating a continuous query.
 type CreateContinuousQueryStatement struct {
 	// Name of the continuous query to be created.
@@ -1791,6 +1913,196 @@ func (s *DropMeasurementStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// RenameMeasurementStatement represents a command to rename a measurement.
+// Stored points keep their name; the new name is an alias used by writes and
+// queries on every node.
+type RenameMeasurementStatement struct {
+	// Name of the measurement to be renamed.
+	Name string
+
+	// New name of the measurement.
+	NewName string
+
+	// Database containing the measurement. Defaults to the query's database.
+	Database string
+}
+
+// String returns a string representation of the rename measurement statement.
+func (s *RenameMeasurementStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("RENAME MEASUREMENT ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(QuoteIdent(s.NewName))
+	if s.Database != "" {
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteIdent(s.Database))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RenameMeasurementStatement
+func (s *RenameMeasurementStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// RenameTagKeyStatement represents a command to rename a tag key across the
+// existing series of a measurement. Stored series keep the old key; the new
+// key is an alias used by writes and queries on every node.
+type RenameTagKeyStatement struct {
+	// Measurement containing the tag key.
+	Measurement string
+
+	// Tag key to be renamed.
+	Key string
+
+	// New name of the tag key.
+	NewKey string
+
+	// Database containing the measurement. Defaults to the query's database.
+	Database string
+}
+
+// String returns a string representation of the rename tag key statement.
+func (s *RenameTagKeyStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("RENAME TAG KEY ")
+	_, _ = buf.WriteString(QuoteIdent(s.Key))
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(QuoteIdent(s.NewKey))
+	_, _ = buf.WriteString(" FROM ")
+	_, _ = buf.WriteString(QuoteIdent(s.Measurement))
+	if s.Database != "" {
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteIdent(s.Database))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RenameTagKeyStatement
+func (s *RenameTagKeyStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// RenameTagValueStatement represents a command to rewrite a tag value across
+// the existing series of a measurement. Stored series keep the old value; the
+// new value is an alias used by writes and queries on every node.
+type RenameTagValueStatement struct {
+	// Measurement containing the tag.
+	Measurement string
+
+	// Tag key of the value.
+	Key string
+
+	// Tag value to be rewritten.
+	Value string
+
+	// New tag value.
+	NewValue string
+
+	// Database containing the measurement. Defaults to the query's database.
+	Database string
+}
+
+// String returns a string representation of the rename tag value statement.
+func (s *RenameTagValueStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("RENAME TAG VALUE ")
+	_, _ = buf.WriteString(QuoteString(s.Value))
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(QuoteString(s.NewValue))
+	_, _ = buf.WriteString(" WITH KEY = ")
+	_, _ = buf.WriteString(QuoteIdent(s.Key))
+	_, _ = buf.WriteString(" FROM ")
+	_, _ = buf.WriteString(QuoteIdent(s.Measurement))
+	if s.Database != "" {
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteIdent(s.Database))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RenameTagValueStatement
+func (s *RenameTagValueStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// CreateTagAliasStatement represents a command to add a deprecated name for a
+// tag key, or for a value of the key if Value is set. Queries using the alias
+// are rewritten to the current name, so they keep working once new points are
+// written with it.
+type CreateTagAliasStatement struct {
+	// Deprecated name of the tag key or value.
+	Alias string
+
+	// Current tag key.
+	Key string
+
+	// Current tag value. Blank for an alias of the key.
+	Value string
+
+	// Database of the alias. Defaults to the query's database.
+	Database string
+}
+
+// String returns a string representation of the create tag alias statement.
+func (s *CreateTagAliasStatement) String() string {
+	return "CREATE " + tagAliasString(s.Alias, s.Key, s.Value, s.Database)
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a CreateTagAliasStatement
+func (s *CreateTagAliasStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropTagAliasStatement represents a command to remove an alias of a tag key,
+// or of a value of the key if Value is set.
+type DropTagAliasStatement struct {
+	// Deprecated name of the tag key or value.
+	Alias string
+
+	// Current tag key.
+	Key string
+
+	// Current tag value. Blank for an alias of the key.
+	Value string
+
+	// Database of the alias. Defaults to the query's database.
+	Database string
+}
+
+// String returns a string representation of the drop tag alias statement.
+func (s *DropTagAliasStatement) String() string {
+	return "DROP " + tagAliasString(s.Alias, s.Key, s.Value, s.Database)
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a DropTagAliasStatement
+func (s *DropTagAliasStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// tagAliasString returns the tag alias of a create or drop tag alias statement.
+func tagAliasString(alias, key, value, database string) string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("TAG ALIAS ")
+	if value == "" {
+		_, _ = buf.WriteString(QuoteIdent(alias))
+		_, _ = buf.WriteString(" FOR KEY ")
+		_, _ = buf.WriteString(QuoteIdent(key))
+	} else {
+		_, _ = buf.WriteString(QuoteString(alias))
+		_, _ = buf.WriteString(" FOR VALUE ")
+		_, _ = buf.WriteString(QuoteString(value))
+		_, _ = buf.WriteString(" WITH KEY = ")
+		_, _ = buf.WriteString(QuoteIdent(key))
+	}
+	if database != "" {
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteIdent(database))
+	}
+	return buf.String()
+}
+
 // ShowRetentionPoliciesStatement represents a command for listing retention policies.
 type ShowRetentionPoliciesStatement struct {
 	// Name of the database to list policies for.
@@ -1810,8 +2122,11 @@ func (s *ShowRetentionPoliciesStatement) RequiredPrivileges() ExecutionPrivilege
 	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
 }
 
-// ShowRetentionPoliciesStatement represents a command for displaying stats for a given server.
+// ShowStatsStatement represents a command for displaying stats for a given server.
 type ShowStatsStatement struct {
+	// Module to show stats for, e.g. "httpd". Stats of all modules are shown if blank.
+	Module string
+
 	// Hostname or IP of the server for stats.
 	Host string
 }
@@ -1819,9 +2134,14 @@ type ShowStatsStatement struct {
 // String returns a string representation of a ShowStatsStatement.
 func (s *ShowStatsStatement) String() string {
 	var buf bytes.Buffer
-	_, _ = buf.WriteString("SHOW STATS ")
+	_, _ = buf.WriteString("SHOW STATS")
+	if s.Module != "" {
+		_, _ = buf.WriteString(" FOR ")
+		_, _ = buf.WriteString(QuoteString(s.Module))
+	}
 	if s.Host != "" {
-		_, _ = buf.WriteString(s.Host)
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteString(s.Host))
 	}
 	return buf.String()
 }
@@ -2283,6 +2603,36 @@ type StringLiteral struct {
 // String returns a string representation of the literal.
 func (l *StringLiteral) String() string { return QuoteString(l.Val) }
 
+// ListLiteral represents a list of string literals, used as the right-hand
+// side of an IN expression.
+type ListLiteral struct {
+	Vals []string
+}
+
+// String returns a string representation of the literal.
+func (l *ListLiteral) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("(")
+	for i, v := range l.Vals {
+		if i > 0 {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(QuoteString(v))
+	}
+	_, _ = buf.WriteString(")")
+	return buf.String()
+}
+
+// Contains returns true if s is one of the values of the list.
+func (l *ListLiteral) Contains(s string) bool {
+	for _, v := range l.Vals {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // TimeLiteral represents a point-in-time literal.
 type TimeLiteral struct {
 	Val time.Time
@@ -2381,6 +2731,8 @@ func CloneExpr(expr Expr) Expr {
 		return &Distinct{Val: expr.Val}
 	case *DurationLiteral:
 		return &DurationLiteral{Val: expr.Val}
+	case *ListLiteral:
+		return &ListLiteral{Vals: append([]string(nil), expr.Vals...)}
 	case *NumberLiteral:
 		return &NumberLiteral{Val: expr.Val}
 	case *ParenExpr:
@@ -2672,6 +3024,8 @@ func Eval(expr Expr, m map[string]interface{}) interface{} {
 		return evalBinaryExpr(expr, m)
 	case *BooleanLiteral:
 		return expr.Val
+	case *ListLiteral:
+		return expr
 	case *NumberLiteral:
 		return expr.Val
 	case *ParenExpr:
@@ -2761,6 +3115,10 @@ func evalBinaryExpr(expr *BinaryExpr, m map[string]interface{}) interface{} {
 			return lhs / rhs
 		}
 	case string:
+		if expr.Op == IN {
+			list, ok := rhs.(*ListLiteral)
+			return ok && list.Contains(lhs)
+		}
 		rhs, _ := rhs.(string)
 		switch expr.Op {
 		case EQ:
@@ -2916,7 +3274,7 @@ func reduceBinaryExprDurationLHS(op Token, lhs *DurationLiteral, rhs Expr) Expr
 
 func reduceBinaryExprNilLHS(op Token, lhs *nilLiteral, rhs Expr) Expr {
 	switch op {
-	case EQ, NEQ:
+	case EQ, NEQ, IN:
 		return &BooleanLiteral{Val: false}
 	}
 	return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
@@ -2967,6 +3325,10 @@ func reduceBinaryExprStringLHS(op Token, lhs *StringLiteral, rhs Expr) Expr {
 		case ADD:
 			return &StringLiteral{Val: lhs.Val + rhs.Val}
 		}
+	case *ListLiteral:
+		if op == IN {
+			return &BooleanLiteral{Val: rhs.Contains(lhs.Val)}
+		}
 	case *nilLiteral:
 		switch op {
 		case EQ, NEQ:
@@ -651,6 +651,9 @@ func TestEval(t *testing.T) {
 		{in: `foo = 'bar'`, out: true, data: map[string]interface{}{"foo": "bar"}},
 		{in: `foo = 'bar'`, out: nil, data: map[string]interface{}{"foo": nil}},
 		{in: `foo <> 'bar'`, out: true, data: map[string]interface{}{"foo": "xxx"}},
+		{in: `foo IN ('bar', 'baz')`, out: true, data: map[string]interface{}{"foo": "baz"}},
+		{in: `foo IN ('bar', 'baz')`, out: false, data: map[string]interface{}{"foo": "xxx"}},
+		{in: `foo IN ('bar', 'baz')`, out: nil, data: map[string]interface{}{"foo": nil}},
 	} {
 		// Evaluate expression.
 		out := influxql.Eval(MustParseExpr(tt.in), tt.data)
@@ -731,6 +734,10 @@ func TestReduce(t *testing.T) {
 		{in: `foo = 'bar'`, out: `true`, data: map[string]interface{}{"foo": "bar"}},
 		{in: `foo = 'bar'`, out: `false`, data: map[string]interface{}{"foo": nil}},
 		{in: `foo <> 'bar'`, out: `false`, data: map[string]interface{}{"foo": nil}},
+		{in: `foo IN ('bar', 'baz')`, out: `true`, data: map[string]interface{}{"foo": "bar"}},
+		{in: `foo IN ('bar', 'baz')`, out: `false`, data: map[string]interface{}{"foo": "xxx"}},
+		{in: `foo IN ('bar', 'baz')`, out: `false`, data: map[string]interface{}{"foo": nil}},
+		{in: `foo IN ('bar', 'baz')`, out: `foo IN ('bar', 'baz')`},
 	} {
 		// Fold expression.
 		expr := influxql.Reduce(MustParseExpr(tt.in), tt.data)
@@ -13,6 +13,7 @@ import (
 	"math/rand"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Iterator represents a forward-only iterator over a set of points.
@@ -21,6 +22,34 @@ type Iterator interface {
 	Next() (time int64, value interface{})
 }
 
+// TagIterator is an Iterator that also knows the tags of the series of the
+// last point returned by Next. Selector functions use it to return the tags
+// of the point they select.
+type TagIterator interface {
+	Iterator
+	Tags() map[string]string
+}
+
+// iteratorTags returns the tags of the last point returned by itr, if it knows them.
+func iteratorTags(itr Iterator) map[string]string {
+	if t, ok := itr.(TagIterator); ok {
+		return t.Tags()
+	}
+	return nil
+}
+
+// SelectorPoint is the point picked by a selector function, such as first()
+// or max(), along with its time and the tags of its series.
+type SelectorPoint struct {
+	Time  int64
+	Value interface{}
+	Tags  map[string]string
+}
+
+// SelectorFunc reduces mapper output to the point it selects, or nil if there
+// are no points.
+type SelectorFunc func([]interface{}) *SelectorPoint
+
 // MapFunc represents a function used for mapping over a sequential series of data.
 // The iterator represents a single group by interval
 type MapFunc func(Iterator) interface{}
@@ -39,8 +68,8 @@ func InitializeMapFunc(c *Call) (MapFunc, error) {
 		return MapRawQuery, nil
 	}
 
-	// Ensure that there is either a single argument or if for percentile, two
-	if c.Name == "percentile" {
+	// Ensure that there is either a single argument or if for percentile or sample, two
+	if c.Name == "percentile" || c.Name == "sample" || c.Name == "histogram_percentile" {
 		if len(c.Args) != 2 {
 			return nil, fmt.Errorf("expected two arguments for %s()", c.Name)
 		}
@@ -53,9 +82,9 @@ func InitializeMapFunc(c *Call) (MapFunc, error) {
 		return nil, fmt.Errorf("expected one argument for %s()", c.Name)
 	}
 
-	// derivative can take a nested aggregate function, everything else expects
-	// a variable reference as the first arg
-	if !strings.HasSuffix(c.Name, "derivative") {
+	// derivative and interpolate can take a nested aggregate function,
+	// everything else expects a variable reference as the first arg
+	if !strings.HasSuffix(c.Name, "derivative") && c.Name != "interpolate" {
 		// Ensure the argument is appropriate for the aggregate function.
 		switch fc := c.Args[0].(type) {
 		case *VarRef:
@@ -110,6 +139,19 @@ func InitializeMapFunc(c *Call) (MapFunc, error) {
 			return nil, fmt.Errorf("expected float argument in percentile()")
 		}
 		return MapEcho, nil
+	case "sample":
+		n, err := sampleSize(c)
+		if err != nil {
+			return nil, err
+		}
+		return MapSample(n), nil
+	case "histogram_merge":
+		return MapHistogram, nil
+	case "histogram_percentile":
+		if _, ok := c.Args[1].(*NumberLiteral); !ok {
+			return nil, fmt.Errorf("expected float argument in histogram_percentile()")
+		}
+		return MapHistogram, nil
 	case "derivative", "non_negative_derivative":
 		// If the arg is another aggregate e.g. derivative(mean(value)), then
 		// use the map func for that nested aggregate
@@ -117,6 +159,13 @@ func InitializeMapFunc(c *Call) (MapFunc, error) {
 			return InitializeMapFunc(fn)
 		}
 		return MapRawQuery, nil
+	case "interpolate":
+		// interpolate(mean(value)) fills the empty windows of the nested
+		// aggregate after it's reduced, so map with the nested aggregate.
+		if fn, ok := c.Args[0].(*Call); ok {
+			return InitializeMapFunc(fn)
+		}
+		return nil, fmt.Errorf("expected function argument to %s", c.Name)
 	default:
 		return nil, fmt.Errorf("function not found: %q", c.Name)
 	}
@@ -166,6 +215,28 @@ func InitializeReduceFunc(c *Call) (ReduceFunc, error) {
 			return nil, fmt.Errorf("expected float argument in percentile()")
 		}
 		return ReducePercentile(lit.Val), nil
+	case "sample":
+		if len(c.Args) != 2 {
+			return nil, fmt.Errorf("expected two arguments for sample()")
+		}
+
+		n, err := sampleSize(c)
+		if err != nil {
+			return nil, err
+		}
+		return ReduceSample(n), nil
+	case "histogram_merge":
+		return ReduceHistogramMerge, nil
+	case "histogram_percentile":
+		if len(c.Args) != 2 {
+			return nil, fmt.Errorf("expected float argument in histogram_percentile()")
+		}
+
+		lit, ok := c.Args[1].(*NumberLiteral)
+		if !ok {
+			return nil, fmt.Errorf("expected float argument in histogram_percentile()")
+		}
+		return ReduceHistogramPercentile(lit.Val), nil
 	case "derivative", "non_negative_derivative":
 		// If the arg is another aggregate e.g. derivative(mean(value)), then
 		// use the map func for that nested aggregate
@@ -173,11 +244,32 @@ func InitializeReduceFunc(c *Call) (ReduceFunc, error) {
 			return InitializeReduceFunc(fn)
 		}
 		return nil, fmt.Errorf("expected function argument to %s", c.Name)
+	case "interpolate":
+		if fn, ok := c.Args[0].(*Call); ok {
+			return InitializeReduceFunc(fn)
+		}
+		return nil, fmt.Errorf("expected function argument to %s", c.Name)
 	default:
 		return nil, fmt.Errorf("function not found: %q", c.Name)
 	}
 }
 
+// InitializeSelectorFunc returns the SelectorFunc of a call to a selector:
+// first(), last(), min() or max(). It returns nil for other calls.
+func InitializeSelectorFunc(c *Call) SelectorFunc {
+	switch c.Name {
+	case "first":
+		return SelectFirst
+	case "last":
+		return SelectLast
+	case "min":
+		return SelectMin
+	case "max":
+		return SelectMax
+	}
+	return nil
+}
+
 func InitializeUnmarshaller(c *Call) (UnmarshalFunc, error) {
 	// if c is nil it's a raw data query
 	if c == nil {
@@ -190,6 +282,14 @@ func InitializeUnmarshaller(c *Call) (UnmarshalFunc, error) {
 
 	// Retrieve marshal function by name
 	switch c.Name {
+	case "interpolate", "derivative", "non_negative_derivative":
+		// The mapper output is the one of the nested aggregate.
+		if len(c.Args) > 0 {
+			if fn, ok := c.Args[0].(*Call); ok {
+				return InitializeUnmarshaller(fn)
+			}
+		}
+		return nil, fmt.Errorf("expected function argument to %s", c.Name)
 	case "mean":
 		return func(b []byte) (interface{}, error) {
 			var o meanMapOutput
@@ -220,6 +320,12 @@ func InitializeUnmarshaller(c *Call) (UnmarshalFunc, error) {
 			err := json.Unmarshal(b, &o)
 			return &o, err
 		}, nil
+	case "min", "max":
+		return func(b []byte) (interface{}, error) {
+			var o minMaxMapOut
+			err := json.Unmarshal(b, &o)
+			return &o, err
+		}, nil
 	case "stddev":
 		return func(b []byte) (interface{}, error) {
 			val := make([]float64, 0)
@@ -232,6 +338,18 @@ func InitializeUnmarshaller(c *Call) (UnmarshalFunc, error) {
 			err := json.Unmarshal(b, &a)
 			return a, err
 		}, nil
+	case "sample":
+		return func(b []byte) (interface{}, error) {
+			var o sampleMapOutput
+			err := json.Unmarshal(b, &o)
+			return &o, err
+		}, nil
+	case "histogram_merge", "histogram_percentile":
+		return func(b []byte) (interface{}, error) {
+			var h Histogram
+			err := json.Unmarshal(b, &h)
+			return h, err
+		}, nil
 	default:
 		return func(b []byte) (interface{}, error) {
 			var val interface{}
@@ -683,11 +801,14 @@ func partition(data []float64) (lows []float64, pivotValue float64, highs []floa
 type minMaxMapOut struct {
 	Val  float64
 	Type NumberType
+	Time int64             `json:",omitempty"`
+	Tags map[string]string `json:",omitempty"`
 }
 
-// MapMin collects the values to pass to the reducer
-func MapMin(itr Iterator) interface{} {
-	min := &minMaxMapOut{}
+// mapMinMax returns the smallest value of an iterator, or the largest if max
+// is true, with the time and tags of its point. The earliest point wins ties.
+func mapMinMax(itr Iterator, max bool) interface{} {
+	out := &minMaxMapOut{}
 
 	pointsYielded := false
 	var val float64
@@ -698,117 +819,82 @@ func MapMin(itr Iterator) interface{} {
 			val = n
 		case int64:
 			val = float64(n)
-			min.Type = Int64Type
+			out.Type = Int64Type
 		}
 
-		// Initialize min
-		if !pointsYielded {
-			min.Val = val
+		if !pointsYielded || (max && val > out.Val) || (!max && val < out.Val) || (val == out.Val && k < out.Time) {
+			out.Val, out.Time, out.Tags = val, k, iteratorTags(itr)
 			pointsYielded = true
 		}
-		min.Val = math.Min(min.Val, val)
 	}
 	if pointsYielded {
-		return min
+		return out
 	}
 	return nil
 }
 
-// ReduceMin computes the min of value.
-func ReduceMin(values []interface{}) interface{} {
-	min := &minMaxMapOut{}
-	pointsYielded := false
-
+// selectMinMax merges the output of mapMinMax.
+func selectMinMax(values []interface{}, max bool) *SelectorPoint {
+	var out *minMaxMapOut
 	for _, value := range values {
-		if value == nil {
-			continue
-		}
-
 		v, ok := value.(*minMaxMapOut)
-		if !ok {
+		if !ok || v == nil {
 			continue
 		}
 
-		// Initialize min
-		if !pointsYielded {
-			min.Val = v.Val
-			min.Type = v.Type
-			pointsYielded = true
+		if out == nil {
+			out = &minMaxMapOut{Val: v.Val, Type: v.Type, Time: v.Time, Tags: v.Tags}
+		} else if (max && v.Val > out.Val) || (!max && v.Val < out.Val) || (v.Val == out.Val && v.Time < out.Time) {
+			out.Val, out.Time, out.Tags = v.Val, v.Time, v.Tags
 		}
-		min.Val = math.Min(min.Val, v.Val)
 	}
-	if pointsYielded {
-		switch min.Type {
-		case Float64Type:
-			return min.Val
-		case Int64Type:
-			return int64(min.Val)
-		}
+	if out == nil {
+		return nil
 	}
-	return nil
+
+	p := &SelectorPoint{Time: out.Time, Value: out.Val, Tags: out.Tags}
+	if out.Type == Int64Type {
+		p.Value = int64(out.Val)
+	}
+	return p
 }
 
-// MapMax collects the values to pass to the reducer
-func MapMax(itr Iterator) interface{} {
-	max := &minMaxMapOut{}
+// MapMin collects the values to pass to the reducer
+func MapMin(itr Iterator) interface{} {
+	return mapMinMax(itr, false)
+}
 
-	pointsYielded := false
-	var val float64
+// ReduceMin computes the min of value.
+func ReduceMin(values []interface{}) interface{} {
+	return selectorValue(SelectMin(values))
+}
 
-	for k, v := itr.Next(); k != -1; k, v = itr.Next() {
-		switch n := v.(type) {
-		case float64:
-			val = n
-		case int64:
-			val = float64(n)
-			max.Type = Int64Type
-		}
+// SelectMin returns the point with the smallest value.
+func SelectMin(values []interface{}) *SelectorPoint {
+	return selectMinMax(values, false)
+}
 
-		// Initialize max
-		if !pointsYielded {
-			max.Val = val
-			pointsYielded = true
-		}
-		max.Val = math.Max(max.Val, val)
-	}
-	if pointsYielded {
-		return max
-	}
-	return nil
+// MapMax collects the values to pass to the reducer
+func MapMax(itr Iterator) interface{} {
+	return mapMinMax(itr, true)
 }
 
 // ReduceMax computes the max of value.
 func ReduceMax(values []interface{}) interface{} {
-	max := &minMaxMapOut{}
-	pointsYielded := false
-
-	for _, value := range values {
-		if value == nil {
-			continue
-		}
+	return selectorValue(SelectMax(values))
+}
 
-		v, ok := value.(*minMaxMapOut)
-		if !ok {
-			continue
-		}
+// SelectMax returns the point with the largest value.
+func SelectMax(values []interface{}) *SelectorPoint {
+	return selectMinMax(values, true)
+}
 
-		// Initialize max
-		if !pointsYielded {
-			max.Val = v.Val
-			max.Type = v.Type
-			pointsYielded = true
-		}
-		max.Val = math.Max(max.Val, v.Val)
-	}
-	if pointsYielded {
-		switch max.Type {
-		case Float64Type:
-			return max.Val
-		case Int64Type:
-			return int64(max.Val)
-		}
+// selectorValue returns the value of a selected point, or nil if there's no point.
+func selectorValue(p *SelectorPoint) interface{} {
+	if p == nil {
+		return nil
 	}
-	return nil
+	return p.Value
 }
 
 type spreadMapOutput struct {
@@ -932,24 +1018,20 @@ func ReduceStddev(values []interface{}) interface{} {
 type firstLastMapOutput struct {
 	Time int64
 	Val  interface{}
+	Tags map[string]string `json:",omitempty"`
 }
 
-// MapFirst collects the values to pass to the reducer
-func MapFirst(itr Iterator) interface{} {
+// mapFirstLast returns the earliest point of an iterator, or the latest if
+// last is true.
+func mapFirstLast(itr Iterator, last bool) interface{} {
 	out := &firstLastMapOutput{}
 	pointsYielded := false
 
 	for k, v := itr.Next(); k != -1; k, v = itr.Next() {
-		// Initialize first
-		if !pointsYielded {
-			out.Time = k
-			out.Val = v
+		if !pointsYielded || (last && k > out.Time) || (!last && k < out.Time) {
+			out.Time, out.Val, out.Tags = k, v, iteratorTags(itr)
 			pointsYielded = true
 		}
-		if k < out.Time {
-			out.Time = k
-			out.Val = v
-		}
 	}
 	if pointsYielded {
 		return out
@@ -957,82 +1039,50 @@ func MapFirst(itr Iterator) interface{} {
 	return nil
 }
 
-// ReduceFirst computes the first of value.
-func ReduceFirst(values []interface{}) interface{} {
-	out := &firstLastMapOutput{}
-	pointsYielded := false
-
+// selectFirstLast merges the output of mapFirstLast.
+func selectFirstLast(values []interface{}, last bool) *SelectorPoint {
+	var out *SelectorPoint
 	for _, v := range values {
-		if v == nil {
+		val, ok := v.(*firstLastMapOutput)
+		if !ok || val == nil {
 			continue
 		}
-		val := v.(*firstLastMapOutput)
-		// Initialize first
-		if !pointsYielded {
-			out.Time = val.Time
-			out.Val = val.Val
-			pointsYielded = true
-		}
-		if val.Time < out.Time {
-			out.Time = val.Time
-			out.Val = val.Val
+
+		if out == nil || (last && val.Time > out.Time) || (!last && val.Time < out.Time) {
+			out = &SelectorPoint{Time: val.Time, Value: val.Val, Tags: val.Tags}
 		}
 	}
-	if pointsYielded {
-		return out.Val
-	}
-	return nil
+	return out
+}
+
+// MapFirst collects the values to pass to the reducer
+func MapFirst(itr Iterator) interface{} {
+	return mapFirstLast(itr, false)
+}
+
+// ReduceFirst computes the first of value.
+func ReduceFirst(values []interface{}) interface{} {
+	return selectorValue(SelectFirst(values))
+}
+
+// SelectFirst returns the earliest point.
+func SelectFirst(values []interface{}) *SelectorPoint {
+	return selectFirstLast(values, false)
 }
 
 // MapLast collects the values to pass to the reducer
 func MapLast(itr Iterator) interface{} {
-	out := &firstLastMapOutput{}
-	pointsYielded := false
-
-	for k, v := itr.Next(); k != -1; k, v = itr.Next() {
-		// Initialize last
-		if !pointsYielded {
-			out.Time = k
-			out.Val = v
-			pointsYielded = true
-		}
-		if k > out.Time {
-			out.Time = k
-			out.Val = v
-		}
-	}
-	if pointsYielded {
-		return out
-	}
-	return nil
+	return mapFirstLast(itr, true)
 }
 
 // ReduceLast computes the last of value.
 func ReduceLast(values []interface{}) interface{} {
-	out := &firstLastMapOutput{}
-	pointsYielded := false
-
-	for _, v := range values {
-		if v == nil {
-			continue
-		}
+	return selectorValue(SelectLast(values))
+}
 
-		val := v.(*firstLastMapOutput)
-		// Initialize last
-		if !pointsYielded {
-			out.Time = val.Time
-			out.Val = val.Val
-			pointsYielded = true
-		}
-		if val.Time > out.Time {
-			out.Time = val.Time
-			out.Val = val.Val
-		}
-	}
-	if pointsYielded {
-		return out.Val
-	}
-	return nil
+// SelectLast returns the latest point.
+func SelectLast(values []interface{}) *SelectorPoint {
+	return selectFirstLast(values, true)
 }
 
 // MapEcho emits the data points for each group by interval
@@ -1078,10 +1128,180 @@ func ReducePercentile(percentile float64) ReduceFunc {
 	}
 }
 
+// sampleSize returns the number of points requested by a sample() call.
+func sampleSize(c *Call) (int, error) {
+	lit, ok := c.Args[1].(*NumberLiteral)
+	if !ok || lit.Val < 1 || lit.Val != math.Trunc(lit.Val) {
+		return 0, fmt.Errorf("expected positive integer argument in sample()")
+	}
+	return int(lit.Val), nil
+}
+
+type sampleValue struct {
+	Time  int64
+	Value interface{}
+}
+
+type sampleValues []sampleValue
+
+func (a sampleValues) Len() int           { return len(a) }
+func (a sampleValues) Less(i, j int) bool { return a[i].Time < a[j].Time }
+func (a sampleValues) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// sampleMapOutput is a reservoir of values sampled from Count values.
+type sampleMapOutput struct {
+	Count  int64
+	Values sampleValues
+}
+
+// MapSample returns a map function that keeps a uniformly random sample of
+// n values using reservoir sampling.
+func MapSample(n int) MapFunc {
+	return func(itr Iterator) interface{} {
+		out := &sampleMapOutput{}
+		for k, v := itr.Next(); k != -1; k, v = itr.Next() {
+			out.Count++
+			if len(out.Values) < n {
+				out.Values = append(out.Values, sampleValue{k, v})
+			} else if i := rand.Int63n(out.Count); i < int64(n) {
+				out.Values[i] = sampleValue{k, v}
+			}
+		}
+
+		if out.Count == 0 {
+			return nil
+		}
+		return out
+	}
+}
+
+// ReduceSample returns a reduce function that merges reservoirs into a
+// uniformly random sample of n values, ordered by time. Each value is drawn
+// from a reservoir with a probability proportional to the number of values
+// the reservoir was sampled from. The values are returned as pairs of the
+// time of their point and the value, like the values of a row.
+func ReduceSample(n int) ReduceFunc {
+	return func(values []interface{}) interface{} {
+		var (
+			pools  []sampleValues
+			counts []int64
+			total  int64
+		)
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			o := v.(*sampleMapOutput)
+			if len(o.Values) == 0 {
+				continue
+			}
+
+			// Copy the values since they're removed from the pool as they're drawn.
+			pool := make(sampleValues, len(o.Values))
+			copy(pool, o.Values)
+			pools = append(pools, pool)
+			counts = append(counts, o.Count)
+			total += o.Count
+		}
+
+		if len(pools) == 0 {
+			return nil
+		}
+
+		var sample sampleValues
+		for len(sample) < n && total > 0 {
+			// Pick a reservoir weighted by the values it represents.
+			r := rand.Int63n(total)
+			i := 0
+			for ; r >= counts[i]; i++ {
+				r -= counts[i]
+			}
+
+			// Draw a random value from it.
+			pool := pools[i]
+			j := rand.Intn(len(pool))
+			sample = append(sample, pool[j])
+			pool[j] = pool[len(pool)-1]
+			pools[i] = pool[:len(pool)-1]
+
+			counts[i]--
+			total--
+
+			// An exhausted reservoir can't be picked again.
+			if len(pools[i]) == 0 {
+				total -= counts[i]
+				counts[i] = 0
+			}
+		}
+
+		sort.Sort(sample)
+		out := make([]interface{}, len(sample))
+		for i, v := range sample {
+			out[i] = []interface{}{time.Unix(0, v.Time).UTC(), v.Value}
+		}
+		return out
+	}
+}
+
+// MapHistogram merges the histograms stored in string fields. Values that
+// aren't valid histograms are ignored.
+func MapHistogram(itr Iterator) interface{} {
+	var out Histogram
+	for k, v := itr.Next(); k != -1; k, v = itr.Next() {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		h, err := ParseHistogram(s)
+		if err != nil {
+			continue
+		}
+		out = out.Merge(h)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return out
+}
+
+// reduceHistograms merges the histograms output by MapHistogram.
+func reduceHistograms(values []interface{}) Histogram {
+	var out Histogram
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		out = out.Merge(v.(Histogram))
+	}
+	return out
+}
+
+// ReduceHistogramMerge merges histograms and returns them in their string representation.
+func ReduceHistogramMerge(values []interface{}) interface{} {
+	h := reduceHistograms(values)
+	if h == nil {
+		return nil
+	}
+	return h.String()
+}
+
+// ReduceHistogramPercentile merges histograms and estimates the percentile of their values.
+func ReduceHistogramPercentile(percentile float64) ReduceFunc {
+	return func(values []interface{}) interface{} {
+		v, ok := reduceHistograms(values).Percentile(percentile)
+		if !ok {
+			return nil
+		}
+		return v
+	}
+}
+
 // IsNumeric returns whether a given aggregate can only be run on numeric fields.
 func IsNumeric(c *Call) bool {
 	switch c.Name {
-	case "count", "first", "last", "distinct":
+	case "count", "first", "last", "distinct", "sample", "histogram_merge", "histogram_percentile":
 		return false
 	default:
 		return true
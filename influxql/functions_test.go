@@ -1,6 +1,7 @@
 package influxql
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -30,6 +31,14 @@ func (t *testIterator) Next() (timestamp int64, value interface{}) {
 	return -1, nil
 }
 
+// testTagIterator is a testIterator of points of a single series.
+type testTagIterator struct {
+	testIterator
+	tags map[string]string
+}
+
+func (t *testTagIterator) Tags() map[string]string { return t.tags }
+
 func TestMapMeanNoValues(t *testing.T) {
 	iter := &testIterator{}
 	if got := MapMean(iter); got != nil {
@@ -532,3 +541,115 @@ func BenchmarkGetSortedRangeBySort(b *testing.B) {
 	}
 	benchGetSortedRangeResults = results
 }
+
+func TestInitializeMapFuncSample(t *testing.T) {
+	c := &Call{
+		Name: "sample",
+		Args: []Expr{&VarRef{Val: "field1"}},
+	}
+	if _, err := InitializeMapFunc(c); err == nil || err.Error() != "expected two arguments for sample()" {
+		t.Errorf("InitializeMapFunc(%v) unexpected error: %v", c, err)
+	}
+
+	c.Args = append(c.Args, &NumberLiteral{Val: 1.5})
+	if _, err := InitializeMapFunc(c); err == nil || err.Error() != "expected positive integer argument in sample()" {
+		t.Errorf("InitializeMapFunc(%v) unexpected error: %v", c, err)
+	}
+}
+
+func TestMapSample(t *testing.T) {
+	iter := &testIterator{}
+	for i := 0; i < 100; i++ {
+		iter.values = append(iter.values, point{"0", int64(i), float64(i)})
+	}
+
+	out := MapSample(10)(iter).(*sampleMapOutput)
+	if out.Count != 100 {
+		t.Errorf("Wrong count. exp %v got %v", 100, out.Count)
+	} else if len(out.Values) != 10 {
+		t.Errorf("Wrong number of values. exp %v got %v", 10, len(out.Values))
+	}
+
+	if got := MapSample(10)(&testIterator{}); got != nil {
+		t.Errorf("Wrong values. exp nil got %v", spew.Sdump(got))
+	}
+}
+
+func TestReduceSample(t *testing.T) {
+	// Fewer values than requested returns all of them in time order.
+	values := ReduceSample(10)([]interface{}{
+		&sampleMapOutput{Count: 2, Values: sampleValues{{3, 3.0}, {1, 1.0}}},
+		nil,
+		&sampleMapOutput{Count: 1, Values: sampleValues{{2, 2.0}}},
+	})
+	exp := []interface{}{
+		[]interface{}{time.Unix(0, 1).UTC(), 1.0},
+		[]interface{}{time.Unix(0, 2).UTC(), 2.0},
+		[]interface{}{time.Unix(0, 3).UTC(), 3.0},
+	}
+	if !reflect.DeepEqual(values, exp) {
+		t.Errorf("Wrong values. exp %v got %v", spew.Sdump(exp), spew.Sdump(values))
+	}
+
+	// Values are drawn from each reservoir in proportion to its count.
+	var fromLarge int
+	for i := 0; i < 1000; i++ {
+		values := ReduceSample(1)([]interface{}{
+			&sampleMapOutput{Count: 900, Values: sampleValues{{1, "large"}}},
+			&sampleMapOutput{Count: 100, Values: sampleValues{{2, "small"}}},
+		}).([]interface{})
+		if len(values) != 1 {
+			t.Fatalf("Wrong number of values. exp 1 got %d", len(values))
+		} else if values[0].([]interface{})[1] == "large" {
+			fromLarge++
+		}
+	}
+	if fromLarge < 800 || fromLarge > 980 {
+		t.Errorf("Unexpected distribution: %d of 1000 from the larger reservoir", fromLarge)
+	}
+
+	if got := ReduceSample(1)([]interface{}{nil}); got != nil {
+		t.Errorf("Wrong values. exp nil got %v", spew.Sdump(got))
+	}
+}
+
+// Ensure selectors return the time and tags of the point they select, after
+// their map output is sent between nodes.
+func TestSelectors(t *testing.T) {
+	a := map[string]string{"host": "a"}
+	b := map[string]string{"host": "b"}
+
+	for _, tt := range []struct {
+		name string
+		exp  SelectorPoint
+	}{
+		{name: "first", exp: SelectorPoint{Time: 1, Value: float64(5), Tags: a}},
+		{name: "last", exp: SelectorPoint{Time: 40, Value: float64(5), Tags: b}},
+		{name: "min", exp: SelectorPoint{Time: 20, Value: float64(1), Tags: a}},
+		{name: "max", exp: SelectorPoint{Time: 30, Value: float64(9), Tags: b}},
+	} {
+		c := &Call{Name: tt.name, Args: []Expr{&VarRef{Val: "value"}}}
+		mapFn, _ := InitializeMapFunc(c)
+		unmarshal, _ := InitializeUnmarshaller(c)
+
+		// Map two shards, the second one as if it were on another node.
+		local := mapFn(&testTagIterator{testIterator{values: []point{{time: 1, value: float64(5)}, {time: 20, value: float64(1)}}}, a})
+		remote := mapFn(&testTagIterator{testIterator{values: []point{{time: 30, value: float64(9)}, {time: 40, value: float64(5)}}}, b})
+		buf, err := json.Marshal(remote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if remote, err = unmarshal(buf); err != nil {
+			t.Fatal(err)
+		}
+
+		p := InitializeSelectorFunc(c)([]interface{}{local, nil, remote})
+		if p == nil || !reflect.DeepEqual(*p, tt.exp) {
+			t.Errorf("%s: unexpected point: %#v", tt.name, p)
+		}
+	}
+
+	if InitializeSelectorFunc(&Call{Name: "mean"}) != nil {
+		t.Fatal("expected mean not to be a selector")
+	}
+}
@@ -0,0 +1,165 @@
+package influxql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Histogram is a set of pre-aggregated bucket counts.
+//
+// Histograms are written as string fields holding a comma separated list of
+// buckets. Each bucket is the inclusive upper bound of the bucket and the
+// number of values that fell in it, separated by a colon. The last bucket
+// may be unbounded:
+//
+//	latency="0.05:120,0.1:34,0.5:7,+Inf:1"
+//
+// Counts are per bucket, not cumulative.
+type Histogram []HistogramBucket
+
+// HistogramBucket is a single bucket of a histogram.
+type HistogramBucket struct {
+	UpperBound float64
+	Count      int64
+}
+
+// histogramBucketJSON is the JSON encoding of a bucket. The bound is a string
+// since JSON numbers can't hold the "+Inf" bound of an unbounded bucket.
+type histogramBucketJSON struct {
+	UpperBound json.RawMessage
+	Count      int64
+}
+
+// MarshalJSON encodes the bucket with its bound as a string, such as "+Inf".
+func (b HistogramBucket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UpperBound string
+		Count      int64
+	}{strconv.FormatFloat(b.UpperBound, 'g', -1, 64), b.Count})
+}
+
+// UnmarshalJSON decodes a bucket whose bound is a string or a number.
+func (b *HistogramBucket) UnmarshalJSON(data []byte) error {
+	var v histogramBucketJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	s := string(v.UpperBound)
+	if len(v.UpperBound) > 0 && v.UpperBound[0] == '"' {
+		if err := json.Unmarshal(v.UpperBound, &s); err != nil {
+			return err
+		}
+	}
+	upper, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(upper) {
+		return fmt.Errorf("invalid histogram bucket bound: %s", v.UpperBound)
+	}
+
+	b.UpperBound, b.Count = upper, v.Count
+	return nil
+}
+
+func (h Histogram) Len() int           { return len(h) }
+func (h Histogram) Less(i, j int) bool { return h[i].UpperBound < h[j].UpperBound }
+func (h Histogram) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// ParseHistogram parses a histogram from its string representation.
+func ParseHistogram(s string) (Histogram, error) {
+	var h Histogram
+	for _, b := range strings.Split(s, ",") {
+		parts := strings.Split(b, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid histogram bucket: %q", b)
+		}
+
+		upper, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil || math.IsNaN(upper) {
+			return nil, fmt.Errorf("invalid histogram bucket bound: %q", parts[0])
+		}
+
+		count, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid histogram bucket count: %q", parts[1])
+		}
+
+		h = append(h, HistogramBucket{UpperBound: upper, Count: count})
+	}
+
+	// Combine any buckets with the same bound.
+	return h.Merge(nil), nil
+}
+
+// String returns the string representation of the histogram.
+func (h Histogram) String() string {
+	buckets := make([]string, len(h))
+	for i, b := range h {
+		buckets[i] = strconv.FormatFloat(b.UpperBound, 'g', -1, 64) + ":" + strconv.FormatInt(b.Count, 10)
+	}
+	return strings.Join(buckets, ",")
+}
+
+// Merge returns a new histogram with the counts of h and other added together.
+// Buckets with the same upper bound are combined.
+func (h Histogram) Merge(other Histogram) Histogram {
+	counts := make(map[float64]int64, len(h)+len(other))
+	for _, b := range h {
+		counts[b.UpperBound] += b.Count
+	}
+	for _, b := range other {
+		counts[b.UpperBound] += b.Count
+	}
+
+	merged := make(Histogram, 0, len(counts))
+	for upper, count := range counts {
+		merged = append(merged, HistogramBucket{UpperBound: upper, Count: count})
+	}
+	sort.Sort(merged)
+	return merged
+}
+
+// Count returns the total number of values in the histogram.
+func (h Histogram) Count() int64 {
+	var n int64
+	for _, b := range h {
+		n += b.Count
+	}
+	return n
+}
+
+// Percentile estimates the given percentile, between 0 and 100, of the values
+// in the histogram. Values are assumed to be spread evenly within a bucket. The
+// first bucket is assumed to start at zero, or at its bound if it is negative.
+// A percentile in the unbounded bucket returns the bound of the previous one.
+// Returns false if the histogram is empty.
+func (h Histogram) Percentile(percentile float64) (float64, bool) {
+	total := h.Count()
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := float64(total) * percentile / 100.0
+	var seen int64
+	for i, b := range h {
+		if b.Count == 0 || float64(seen+b.Count) < rank {
+			seen += b.Count
+			continue
+		}
+
+		lower := math.Min(0, b.UpperBound)
+		if i > 0 {
+			lower = h[i-1].UpperBound
+		}
+		if math.IsInf(b.UpperBound, 1) {
+			return lower, true
+		}
+		return lower + (b.UpperBound-lower)*(rank-float64(seen))/float64(b.Count), true
+	}
+
+	// The percentile is past the last bucket.
+	return h[len(h)-1].UpperBound, true
+}
@@ -0,0 +1,105 @@
+package influxql_test
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// Ensure histograms can be parsed and formatted.
+func TestParseHistogram(t *testing.T) {
+	var tests = []struct {
+		s   string
+		h   influxql.Histogram
+		str string
+		err string
+	}{
+		{
+			s:   "0.5:3,0.1:2,+Inf:1",
+			h:   influxql.Histogram{{UpperBound: 0.1, Count: 2}, {UpperBound: 0.5, Count: 3}, {UpperBound: math.Inf(1), Count: 1}},
+			str: "0.1:2,0.5:3,+Inf:1",
+		},
+		{
+			s:   "1:2, 1:3",
+			h:   influxql.Histogram{{UpperBound: 1, Count: 5}},
+			str: "1:5",
+		},
+		{s: "1", err: `invalid histogram bucket: "1"`},
+		{s: "x:1", err: `invalid histogram bucket bound: "x"`},
+		{s: "1:-1", err: `invalid histogram bucket count: "-1"`},
+	}
+
+	for i, tt := range tests {
+		h, err := influxql.ParseHistogram(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, errstring(err))
+		} else if tt.err != "" {
+			continue
+		} else if !reflect.DeepEqual(h, tt.h) {
+			t.Errorf("%d. %q: histogram mismatch:\n  exp=%v\n  got=%v", i, tt.s, tt.h, h)
+		} else if h.String() != tt.str {
+			t.Errorf("%d. %q: string mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.str, h.String())
+		}
+	}
+}
+
+// Ensure histograms with an unbounded bucket can be encoded as JSON, as remote
+// mappers send them.
+func TestHistogram_JSON(t *testing.T) {
+	h := influxql.Histogram{{UpperBound: 0.1, Count: 2}, {UpperBound: math.Inf(1), Count: 1}}
+	b, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := `[{"UpperBound":"0.1","Count":2},{"UpperBound":"+Inf","Count":1}]`; string(b) != exp {
+		t.Fatalf("unexpected JSON:\n  exp=%s\n  got=%s", exp, b)
+	}
+
+	var other influxql.Histogram
+	if err := json.Unmarshal(b, &other); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other, h) {
+		t.Fatalf("unexpected histogram: %v", other)
+	}
+
+	// Bounds encoded as numbers are still accepted.
+	if err := json.Unmarshal([]byte(`[{"UpperBound":0.5,"Count":3}]`), &other); err != nil {
+		t.Fatal(err)
+	} else if exp := (influxql.Histogram{{UpperBound: 0.5, Count: 3}}); !reflect.DeepEqual(other, exp) {
+		t.Fatalf("unexpected histogram: %v", other)
+	}
+}
+
+// Ensure percentiles are interpolated within buckets.
+func TestHistogram_Percentile(t *testing.T) {
+	h := influxql.Histogram{{UpperBound: 10, Count: 50}, {UpperBound: 20, Count: 30}, {UpperBound: 40, Count: 20}}
+
+	var tests = []struct {
+		percentile float64
+		exp        float64
+	}{
+		{0, 0},
+		{25, 5},
+		{50, 10},
+		{65, 15},
+		{90, 30},
+		{100, 40},
+	}
+	for _, tt := range tests {
+		if v, ok := h.Percentile(tt.percentile); !ok || v != tt.exp {
+			t.Errorf("percentile %v: exp %v got %v", tt.percentile, tt.exp, v)
+		}
+	}
+
+	// Values in the unbounded bucket return the largest bound.
+	h = influxql.Histogram{{UpperBound: 10, Count: 1}, {UpperBound: math.Inf(1), Count: 1}}
+	if v, _ := h.Percentile(99); v != 10 {
+		t.Errorf("unbounded percentile: exp 10 got %v", v)
+	}
+
+	if _, ok := (influxql.Histogram{}).Percentile(50); ok {
+		t.Error("expected no percentile for an empty histogram")
+	}
+}
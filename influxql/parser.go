@@ -94,9 +94,13 @@ func (p *Parser) ParseStatement() (Statement, error) {
 	case ALTER:
 		return p.parseAlterStatement()
 	case SET:
-		return p.parseSetPasswordUserStatement()
+		return p.parseSetStatement()
+	case RENAME:
+		return p.parseRenameStatement()
+	case RESTORE:
+		return p.parseRestoreStatement()
 	default:
-		return nil, newParseError(tokstr(tok, lit), []string{"SELECT", "DELETE", "SHOW", "CREATE", "DROP", "GRANT", "REVOKE", "ALTER", "SET"}, pos)
+		return nil, newParseError(tokstr(tok, lit), []string{"SELECT", "DELETE", "SHOW", "CREATE", "DROP", "GRANT", "REVOKE", "ALTER", "SET", "RENAME", "RESTORE"}, pos)
 	}
 }
 
@@ -111,6 +115,12 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseGrantsForUserStatement()
 	case DATABASES:
 		return p.parseShowDatabasesStatement()
+	case DROPPED:
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == DATABASES {
+			return p.parseShowDroppedDatabasesStatement()
+		}
+		return nil, newParseError(tokstr(tok, lit), []string{"DATABASES"}, pos)
 	case SERVERS:
 		return p.parseShowServersStatement()
 	case FIELD:
@@ -145,7 +155,7 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseShowUsersStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASES", "FIELD", "GRANTS", "MEASUREMENTS", "RETENTION", "SERIES", "SERVERS", "TAG", "USERS"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASES", "DROPPED", "FIELD", "GRANTS", "MEASUREMENTS", "RETENTION", "SERIES", "SERVERS", "TAG", "USERS"}, pos)
 }
 
 // parseCreateStatement parses a string and returns a create statement.
@@ -164,9 +174,15 @@ func (p *Parser) parseCreateStatement() (Statement, error) {
 			return nil, newParseError(tokstr(tok, lit), []string{"POLICY"}, pos)
 		}
 		return p.parseCreateRetentionPolicyStatement()
+	} else if tok == TAG {
+		alias, key, value, database, err := p.parseTagAlias()
+		if err != nil {
+			return nil, err
+		}
+		return &CreateTagAliasStatement{Alias: alias, Key: key, Value: value, Database: database}, nil
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASE", "USER", "RETENTION"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASE", "USER", "RETENTION", "TAG"}, pos)
 }
 
 // parseDropStatement parses a string and returns a drop statement.
@@ -188,6 +204,12 @@ func (p *Parser) parseDropStatement() (Statement, error) {
 		return p.parseDropRetentionPolicyStatement()
 	} else if tok == USER {
 		return p.parseDropUserStatement()
+	} else if tok == TAG {
+		alias, key, value, database, err := p.parseTagAlias()
+		if err != nil {
+			return nil, err
+		}
+		return &DropTagAliasStatement{Alias: alias, Key: key, Value: value, Database: database}, nil
 	}
 
 	return nil, newParseError(tokstr(tok, lit), []string{"SERIES", "CONTINUOUS", "MEASUREMENT"}, pos)
@@ -209,6 +231,52 @@ func (p *Parser) parseAlterStatement() (Statement, error) {
 
 // parseSetPasswordUserStatement parses a string and returns a set statement.
 // This function assumes the SET token has already been consumed.
+// parseSetStatement parses a string and returns a set statement.
+// This function assumes the SET token has already been consumed.
+func (p *Parser) parseSetStatement() (Statement, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case PASSWORD:
+		p.unscan()
+		return p.parseSetPasswordUserStatement()
+	case RETENTION:
+		if err := p.parseKeywords("CHECK", "INTERVAL"); err != nil {
+			return nil, err
+		}
+		return p.parseSetIntervalStatement(RetentionCheckInterval)
+	case CONTINUOUS:
+		if err := p.parseTokens([]Token{QUERY}); err != nil {
+			return nil, err
+		} else if err := p.parseKeywords("RUN", "INTERVAL"); err != nil {
+			return nil, err
+		}
+		return p.parseSetIntervalStatement(ContinuousQueryRunInterval)
+	}
+
+	return nil, newParseError(tokstr(tok, lit), []string{"PASSWORD", "RETENTION", "CONTINUOUS"}, pos)
+}
+
+// parseSetIntervalStatement parses the duration of a set interval statement.
+// This function assumes the interval's name has already been consumed.
+func (p *Parser) parseSetIntervalStatement(name string) (*SetIntervalStatement, error) {
+	d, err := p.parseDuration()
+	if err != nil {
+		return nil, err
+	}
+	return &SetIntervalStatement{Name: name, Interval: d}, nil
+}
+
+// parseKeywords parses a sequence of words that aren't reserved keywords and
+// are scanned as identifiers. Matching is case-insensitive.
+func (p *Parser) parseKeywords(words ...string) error {
+	for _, word := range words {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || !strings.EqualFold(lit, word) {
+			return newParseError(tokstr(tok, lit), []string{word}, pos)
+		}
+	}
+	return nil
+}
+
 func (p *Parser) parseSetPasswordUserStatement() (*SetPasswordUserStatement, error) {
 	stmt := &SetPasswordUserStatement{}
 
@@ -325,11 +393,25 @@ func (p *Parser) parseAlterRetentionPolicyStatement() (*AlterRetentionPolicyStat
 	}
 	stmt.Database = ident
 
-	// Loop through option tokens (DURATION, REPLICATION, DEFAULT, etc.).
-	maxNumOptions := 3
+	// Loop through option tokens (DURATION, REPLICATION, SHARD DURATION, DEFAULT, etc.).
+	maxNumOptions := 4
 Loop:
 	for i := 0; i < maxNumOptions; i++ {
 		tok, pos, lit := p.scanIgnoreWhitespace()
+
+		// SHARD is only recognized here so it's still a valid identifier.
+		if tok == IDENT && strings.EqualFold(lit, "SHARD") {
+			if tok, pos, lit := p.scanIgnoreWhitespace(); tok != DURATION {
+				return nil, newParseError(tokstr(tok, lit), []string{"DURATION"}, pos)
+			}
+			d, err := p.parseDuration()
+			if err != nil {
+				return nil, err
+			}
+			stmt.ShardGroupDuration = &d
+			continue
+		}
+
 		switch tok {
 		case DURATION:
 			d, err := p.parseDuration()
@@ -347,7 +429,7 @@ Loop:
 			stmt.Default = true
 		default:
 			if i < 1 {
-				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "RETENTION", "DEFAULT"}, pos)
+				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "RETENTION", "SHARD", "DEFAULT"}, pos)
 			}
 			p.unscan()
 			break Loop
@@ -510,6 +592,36 @@ func (p *Parser) parseSegmentedIdents() ([]string, error) {
 	return idents, nil
 }
 
+// parseList parses a parenthesized, comma-separated list of strings.
+func (p *Parser) parseList() (*ListLiteral, error) {
+	// Parse required ( token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != LPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	// Parse first (required) string.
+	s, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+	vals := []string{s}
+
+	// Parse remaining (optional) strings.
+	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == RPAREN {
+			return &ListLiteral{Vals: vals}, nil
+		} else if tok != COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", ")"}, pos)
+		}
+
+		if s, err = p.parseString(); err != nil {
+			return nil, err
+		}
+		vals = append(vals, s)
+	}
+}
+
 // parserString parses a string.
 func (p *Parser) parseString() (string, error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
@@ -1104,6 +1216,169 @@ func (p *Parser) parseDropMeasurementStatement() (*DropMeasurementStatement, err
 	return stmt, nil
 }
 
+// parseRenameStatement parses a string and returns a rename statement.
+// This function assumes the RENAME token has already been consumed.
+func (p *Parser) parseRenameStatement() (Statement, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case MEASUREMENT:
+		return p.parseRenameMeasurementStatement()
+	case TAG:
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == KEY {
+			return p.parseRenameTagKeyStatement()
+		} else if tok == IDENT && strings.EqualFold(lit, "VALUE") {
+			return p.parseRenameTagValueStatement()
+		}
+		return nil, newParseError(tokstr(tok, lit), []string{"KEY", "VALUE"}, pos)
+	}
+	return nil, newParseError(tokstr(tok, lit), []string{"MEASUREMENT", "TAG"}, pos)
+}
+
+// parseRenameTagKeyStatement parses a string and returns a RenameTagKeyStatement.
+// This function assumes the "RENAME TAG KEY" tokens have already been consumed.
+func (p *Parser) parseRenameTagKeyStatement() (*RenameTagKeyStatement, error) {
+	stmt := &RenameTagKeyStatement{}
+	var err error
+
+	// Parse the current and new tag keys.
+	if stmt.Key, err = p.parseIdent(); err != nil {
+		return nil, err
+	} else if err := p.parseTokens([]Token{TO}); err != nil {
+		return nil, err
+	} else if stmt.NewKey, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+
+	if stmt.Measurement, stmt.Database, err = p.parseRenameTagTarget(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseRenameTagValueStatement parses a string and returns a RenameTagValueStatement.
+// This function assumes the "RENAME TAG VALUE" tokens have already been consumed.
+func (p *Parser) parseRenameTagValueStatement() (*RenameTagValueStatement, error) {
+	stmt := &RenameTagValueStatement{}
+	var err error
+
+	// Parse the current and new tag values.
+	if stmt.Value, err = p.parseString(); err != nil {
+		return nil, err
+	} else if err := p.parseTokens([]Token{TO}); err != nil {
+		return nil, err
+	} else if stmt.NewValue, err = p.parseString(); err != nil {
+		return nil, err
+	}
+
+	// Parse the tag key.
+	if err := p.parseTokens([]Token{WITH, KEY, EQ}); err != nil {
+		return nil, err
+	} else if stmt.Key, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+
+	if stmt.Measurement, stmt.Database, err = p.parseRenameTagTarget(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseRenameTagTarget parses the measurement and optional database of a
+// rename tag statement.
+func (p *Parser) parseRenameTagTarget() (measurement, database string, err error) {
+	if err := p.parseTokens([]Token{FROM}); err != nil {
+		return "", "", err
+	} else if measurement, err = p.parseIdent(); err != nil {
+		return "", "", err
+	}
+
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
+		if database, err = p.parseIdent(); err != nil {
+			return "", "", err
+		}
+	} else {
+		p.unscan()
+	}
+	return measurement, database, nil
+}
+
+// parseTagAlias parses the tag alias of a create or drop tag alias statement:
+// an identifier FOR KEY key or a string FOR VALUE 'value' WITH KEY = key,
+// optionally followed by ON database. This function assumes the TAG token
+// has already been consumed.
+func (p *Parser) parseTagAlias() (alias, key, value, database string, err error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || !strings.EqualFold(lit, "ALIAS") {
+		return "", "", "", "", newParseError(tokstr(tok, lit), []string{"ALIAS"}, pos)
+	}
+
+	// Aliases of values are strings and aliases of keys are identifiers.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == STRING {
+		alias = lit
+		if err := p.parseTokens([]Token{FOR}); err != nil {
+			return "", "", "", "", err
+		} else if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || !strings.EqualFold(lit, "VALUE") {
+			return "", "", "", "", newParseError(tokstr(tok, lit), []string{"VALUE"}, pos)
+		} else if value, err = p.parseString(); err != nil {
+			return "", "", "", "", err
+		} else if err := p.parseTokens([]Token{WITH, KEY, EQ}); err != nil {
+			return "", "", "", "", err
+		}
+	} else {
+		p.unscan()
+		if alias, err = p.parseIdent(); err != nil {
+			return "", "", "", "", err
+		} else if err := p.parseTokens([]Token{FOR, KEY}); err != nil {
+			return "", "", "", "", err
+		}
+	}
+
+	if key, err = p.parseIdent(); err != nil {
+		return "", "", "", "", err
+	}
+
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
+		if database, err = p.parseIdent(); err != nil {
+			return "", "", "", "", err
+		}
+	} else {
+		p.unscan()
+	}
+	return alias, key, value, database, nil
+}
+
+// parseRenameMeasurementStatement parses a string and returns a RenameMeasurementStatement.
+// This function assumes the "RENAME MEASUREMENT" tokens have already been consumed.
+func (p *Parser) parseRenameMeasurementStatement() (*RenameMeasurementStatement, error) {
+	stmt := &RenameMeasurementStatement{}
+
+	// Parse the current name of the measurement.
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Parse the new name of the measurement.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+		return nil, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+	}
+	if stmt.NewName, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+
+	// Parse the optional database.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
+		if stmt.Database, err = p.parseIdent(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
+	return stmt, nil
+}
+
 // parseDropSeriesStatement parses a string and returns a DropSeriesStatement.
 // This function assumes the "DROP SERIES" tokens have already been consumed.
 func (p *Parser) parseDropSeriesStatement() (*DropSeriesStatement, error) {
@@ -1181,6 +1456,13 @@ func (p *Parser) parseShowDatabasesStatement() (*ShowDatabasesStatement, error)
 	return stmt, nil
 }
 
+// parseShowDroppedDatabasesStatement parses a string and returns a ShowDroppedDatabasesStatement.
+// This function assumes the "SHOW DROPPED DATABASES" tokens have already been consumed.
+func (p *Parser) parseShowDroppedDatabasesStatement() (*ShowDroppedDatabasesStatement, error) {
+	stmt := &ShowDroppedDatabasesStatement{}
+	return stmt, nil
+}
+
 // parseCreateContinuousQueriesStatement parses a string and returns a CreateContinuousQueryStatement.
 // This function assumes the "CREATE CONTINUOUS" tokens have already been consumed.
 func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQueryStatement, error) {
@@ -1275,6 +1557,31 @@ func (p *Parser) parseDropDatabaseStatement() (*DropDatabaseStatement, error) {
 	return stmt, nil
 }
 
+// parseRestoreStatement parses a string and returns a restore statement.
+// This function assumes the RESTORE token has already been consumed.
+func (p *Parser) parseRestoreStatement() (Statement, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok == DATABASE {
+		return p.parseRestoreDatabaseStatement()
+	}
+	return nil, newParseError(tokstr(tok, lit), []string{"DATABASE"}, pos)
+}
+
+// parseRestoreDatabaseStatement parses a string and returns a RestoreDatabaseStatement.
+// This function assumes the RESTORE DATABASE tokens have already been consumed.
+func (p *Parser) parseRestoreDatabaseStatement() (*RestoreDatabaseStatement, error) {
+	stmt := &RestoreDatabaseStatement{}
+
+	// Parse the name of the database to be restored.
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	return stmt, nil
+}
+
 // parseDropRetentionPolicyStatement parses a string and returns a DropRetentionPolicyStatement.
 // This function assumes the DROP RETENTION POLICY tokens have been consumed.
 func (p *Parser) parseDropRetentionPolicyStatement() (*DropRetentionPolicyStatement, error) {
@@ -1391,6 +1698,16 @@ func (p *Parser) parseShowStatsStatement() (*ShowStatsStatement, error) {
 	stmt := &ShowStatsStatement{}
 	var err error
 
+	// Parse optional FOR clause.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FOR {
+		if stmt.Module, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
+	// Parse optional ON clause.
 	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
 		stmt.Host, err = p.parseString()
 	} else {
@@ -1873,14 +2190,19 @@ func (p *Parser) ParseExpr() (Expr, error) {
 	for {
 		// If the next token is NOT an operator then return the expression.
 		op, _, _ := p.scanIgnoreWhitespace()
-		if !op.isOperator() {
+		if !op.isOperator() && op != IN {
 			p.unscan()
 			return root.RHS, nil
 		}
 
 		// Otherwise parse the next expression.
 		var rhs Expr
-		if IsRegexOp(op) {
+		if op == IN {
+			// RHS of an IN operator must be a list of strings.
+			if rhs, err = p.parseList(); err != nil {
+				return nil, err
+			}
+		} else if IsRegexOp(op) {
 			// RHS of a regex operator must be a regular expression.
 			p.consumeWhitespace()
 			if rhs, err = p.parseRegex(); err != nil {
@@ -191,6 +191,16 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT statement with an identifier named shard
+		{
+			s: `SELECT shard FROM cpu`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "shard"}}},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+			},
+		},
+
 		// SELECT * FROM cpu WHERE host = 'serverC' AND region =~ /.*west.*/
 		{
 			s: `SELECT * FROM cpu WHERE host = 'serverC' AND region =~ /.*west.*/`,
@@ -214,6 +224,29 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT * FROM cpu WHERE host IN ('serverA', 'serverB') AND value > 1
+		{
+			s: `SELECT * FROM cpu WHERE host IN ('serverA', 'serverB') AND value > 1`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields:     []*influxql.Field{{Expr: &influxql.Wildcard{}}},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op: influxql.AND,
+					LHS: &influxql.BinaryExpr{
+						Op:  influxql.IN,
+						LHS: &influxql.VarRef{Val: "host"},
+						RHS: &influxql.ListLiteral{Vals: []string{"serverA", "serverB"}},
+					},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.GT,
+						LHS: &influxql.VarRef{Val: "value"},
+						RHS: &influxql.NumberLiteral{Val: 1},
+					},
+				},
+			},
+		},
+
 		// select distinct statements
 		{
 			s: `select distinct(field1) from cpu`,
@@ -546,6 +579,12 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowDatabasesStatement{},
 		},
 
+		// SHOW DROPPED DATABASES
+		{
+			s:    `SHOW DROPPED DATABASES`,
+			stmt: &influxql.ShowDroppedDatabasesStatement{},
+		},
+
 		// SHOW SERIES statement
 		{
 			s:    `SHOW SERIES`,
@@ -957,6 +996,18 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SET RETENTION CHECK INTERVAL
+		{
+			s:    `SET RETENTION CHECK INTERVAL 30m`,
+			stmt: &influxql.SetIntervalStatement{Name: influxql.RetentionCheckInterval, Interval: 30 * time.Minute},
+		},
+
+		// SET CONTINUOUS QUERY RUN INTERVAL
+		{
+			s:    `set continuous query run interval 5s`,
+			stmt: &influxql.SetIntervalStatement{Name: influxql.ContinuousQueryRunInterval, Interval: 5 * time.Second},
+		},
+
 		// DROP CONTINUOUS QUERY statement
 		{
 			s:    `DROP CONTINUOUS QUERY myquery ON foo`,
@@ -969,12 +1020,58 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.DropDatabaseStatement{Name: "testdb"},
 		},
 
+		// RESTORE DATABASE statement
+		{
+			s:    `RESTORE DATABASE testdb`,
+			stmt: &influxql.RestoreDatabaseStatement{Name: "testdb"},
+		},
+
 		// DROP MEASUREMENT statement
 		{
 			s:    `DROP MEASUREMENT cpu`,
 			stmt: &influxql.DropMeasurementStatement{Name: "cpu"},
 		},
 
+		// RENAME MEASUREMENT statement
+		{
+			s:    `RENAME MEASUREMENT cpu_typo TO cpu`,
+			stmt: &influxql.RenameMeasurementStatement{Name: "cpu_typo", NewName: "cpu"},
+		},
+
+		// RENAME MEASUREMENT statement with database
+		{
+			s:    `RENAME MEASUREMENT "cpu typo" TO cpu ON mydb`,
+			stmt: &influxql.RenameMeasurementStatement{Name: "cpu typo", NewName: "cpu", Database: "mydb"},
+		},
+
+		// RENAME TAG KEY statement
+		{
+			s:    `RENAME TAG KEY host TO hostname FROM cpu ON mydb`,
+			stmt: &influxql.RenameTagKeyStatement{Key: "host", NewKey: "hostname", Measurement: "cpu", Database: "mydb"},
+		},
+
+		// RENAME TAG VALUE statement
+		{
+			s:    `RENAME TAG VALUE 'serverA' TO 'server-a' WITH KEY = host FROM cpu`,
+			stmt: &influxql.RenameTagValueStatement{Value: "serverA", NewValue: "server-a", Key: "host", Measurement: "cpu"},
+		},
+
+		// CREATE TAG ALIAS statement
+		{
+			s:    `CREATE TAG ALIAS dc FOR KEY datacenter ON mydb`,
+			stmt: &influxql.CreateTagAliasStatement{Alias: "dc", Key: "datacenter", Database: "mydb"},
+		},
+		{
+			s:    `CREATE TAG ALIAS 'usw1' FOR VALUE 'us-west-1' WITH KEY = datacenter`,
+			stmt: &influxql.CreateTagAliasStatement{Alias: "usw1", Value: "us-west-1", Key: "datacenter"},
+		},
+
+		// DROP TAG ALIAS statement
+		{
+			s:    `DROP TAG ALIAS dc FOR KEY datacenter`,
+			stmt: &influxql.DropTagAliasStatement{Alias: "dc", Key: "datacenter"},
+		},
+
 		// DROP RETENTION POLICY
 		{
 			s: `DROP RETENTION POLICY "1h.cpu" ON mydb`,
@@ -1177,6 +1274,27 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: newAlterRetentionPolicyStatement("default", "testdb", -1, 4, false),
 		},
 
+		// ALTER RETENTION POLICY with shard duration
+		{
+			s: `ALTER RETENTION POLICY policy1 ON testdb DURATION 1w SHARD DURATION 1d`,
+			stmt: &influxql.AlterRetentionPolicyStatement{
+				Name:               "policy1",
+				Database:           "testdb",
+				Duration:           durationPtr(7 * 24 * time.Hour),
+				ShardGroupDuration: durationPtr(24 * time.Hour),
+			},
+		},
+
+		// ALTER RETENTION POLICY named shard with shard duration
+		{
+			s: `ALTER RETENTION POLICY shard ON testdb shard duration 1d`,
+			stmt: &influxql.AlterRetentionPolicyStatement{
+				Name:               "shard",
+				Database:           "testdb",
+				ShardGroupDuration: durationPtr(24 * time.Hour),
+			},
+		},
+
 		// SHOW STATS
 		{
 			s: `SHOW STATS`,
@@ -1196,6 +1314,19 @@ func TestParser_ParseStatement(t *testing.T) {
 				Host: "192.167.1.44",
 			},
 		},
+		{
+			s: `SHOW STATS FOR 'httpd'`,
+			stmt: &influxql.ShowStatsStatement{
+				Module: "httpd",
+			},
+		},
+		{
+			s: `SHOW STATS FOR 'wal' ON 'servera'`,
+			stmt: &influxql.ShowStatsStatement{
+				Module: "wal",
+				Host:   "servera",
+			},
+		},
 
 		// SHOW DIAGNOSTICS
 		{
@@ -1204,10 +1335,19 @@ func TestParser_ParseStatement(t *testing.T) {
 		},
 
 		// Errors
-		{s: ``, err: `found EOF, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET at line 1, char 1`},
+		{s: ``, err: `found EOF, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET, RENAME, RESTORE at line 1, char 1`},
 		{s: `SELECT`, err: `found EOF, expected identifier, string, number, bool at line 1, char 8`},
+		{s: `RENAME`, err: `found EOF, expected MEASUREMENT, TAG at line 1, char 8`},
+		{s: `RENAME TAG host`, err: `found host, expected KEY, VALUE at line 1, char 12`},
+		{s: `RENAME TAG KEY host TO hostname`, err: `found EOF, expected FROM at line 1, char 33`},
+		{s: `RENAME TAG VALUE 'a' TO 'b' FROM cpu`, err: `found FROM, expected WITH at line 1, char 29`},
+		{s: `CREATE TAG dc`, err: `found dc, expected ALIAS at line 1, char 12`},
+		{s: `CREATE TAG ALIAS dc FOR datacenter`, err: `found datacenter, expected KEY at line 1, char 25`},
+		{s: `DROP TAG ALIAS 'a' FOR VALUE 'b' ON db`, err: `found ON, expected WITH at line 1, char 34`},
+		{s: `RENAME MEASUREMENT cpu`, err: `found EOF, expected TO at line 1, char 24`},
+		{s: `RENAME MEASUREMENT cpu TO`, err: `found EOF, expected identifier at line 1, char 27`},
 		{s: `SELECT time FROM myseries`, err: `at least 1 non-time field must be queried`},
-		{s: `blah blah`, err: `found blah, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET at line 1, char 1`},
+		{s: `blah blah`, err: `found blah, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET, RENAME, RESTORE at line 1, char 1`},
 		{s: `SELECT field1 X`, err: `found X, expected FROM at line 1, char 15`},
 		{s: `SELECT field1 FROM "series" WHERE X +;`, err: `found ;, expected identifier, string, number, bool at line 1, char 38`},
 		{s: `SELECT field1 FROM myseries GROUP`, err: `found EOF, expected BY at line 1, char 35`},
@@ -1245,7 +1385,13 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `select count() from myseries`, err: `invalid number of arguments for count, expected 1, got 0`},
 		{s: `select derivative() from myseries`, err: `invalid number of arguments for derivative, expected at least 1 but no more than 2, got 0`},
 		{s: `select derivative(mean(value), 1h, 3) from myseries`, err: `invalid number of arguments for derivative, expected at least 1 but no more than 2, got 3`},
+		{s: `select interpolate(value) from myseries where time > now() - 1h group by time(1m)`, err: `interpolate requires an aggregate function argument`},
+		{s: `select interpolate(mean(value)) from myseries`, err: `interpolate requires a GROUP BY time interval`},
+		{s: `select interpolate(mean(value)) * 2 from myseries where time > now() - 1h group by time(1m)`, err: `interpolate cannot be used in an expression`},
 		{s: `SELECT field1 from myseries WHERE host =~ 'asd' LIMIT 1`, err: `found asd, expected regex at line 1, char 42`},
+		{s: `SELECT field1 from myseries WHERE host IN 'asd'`, err: `found asd, expected ( at line 1, char 42`},
+		{s: `SELECT field1 from myseries WHERE host IN ()`, err: `found ), expected string at line 1, char 44`},
+		{s: `SELECT field1 from myseries WHERE host IN ('a' 'b')`, err: `found b, expected ,, ) at line 1, char 47`},
 		{s: `SELECT value > 2 FROM cpu`, err: `invalid operator > in SELECT clause at line 1, char 8; operator is intended for WHERE clause`},
 		{s: `SELECT value = 2 FROM cpu`, err: `invalid operator = in SELECT clause at line 1, char 8; operator is intended for WHERE clause`},
 		{s: `SELECT s =~ /foo/ FROM cpu`, err: `invalid operator =~ in SELECT clause at line 1, char 8; operator is intended for WHERE clause`},
@@ -1265,7 +1411,7 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SHOW RETENTION POLICIES`, err: `found EOF, expected ON at line 1, char 25`},
 		{s: `SHOW RETENTION POLICIES mydb`, err: `found mydb, expected ON at line 1, char 25`},
 		{s: `SHOW RETENTION POLICIES ON`, err: `found EOF, expected identifier at line 1, char 28`},
-		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, FIELD, GRANTS, MEASUREMENTS, RETENTION, SERIES, SERVERS, TAG, USERS at line 1, char 6`},
+		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, DROPPED, FIELD, GRANTS, MEASUREMENTS, RETENTION, SERIES, SERVERS, TAG, USERS at line 1, char 6`},
 		{s: `SHOW STATS ON`, err: `found EOF, expected string at line 1, char 15`},
 		{s: `SHOW GRANTS`, err: `found EOF, expected FOR at line 1, char 13`},
 		{s: `SHOW GRANTS FOR`, err: `found EOF, expected identifier at line 1, char 17`},
@@ -1277,6 +1423,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 25`},
 		{s: `DROP FOO`, err: `found FOO, expected SERIES, CONTINUOUS, MEASUREMENT at line 1, char 6`},
 		{s: `DROP DATABASE`, err: `found EOF, expected identifier at line 1, char 15`},
+		{s: `RESTORE`, err: `found EOF, expected DATABASE at line 1, char 9`},
+		{s: `RESTORE DATABASE`, err: `found EOF, expected identifier at line 1, char 18`},
+		{s: `SHOW DROPPED`, err: `found EOF, expected DATABASES at line 1, char 14`},
 		{s: `DROP RETENTION`, err: `found EOF, expected POLICY at line 1, char 16`},
 		{s: `DROP RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 23`},
 		{s: `DROP RETENTION POLICY "1h.cpu"`, err: `found EOF, expected ON at line 1, char 31`},
@@ -1367,9 +1516,13 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `ALTER RETENTION`, err: `found EOF, expected POLICY at line 1, char 17`},
 		{s: `ALTER RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 24`},
 		{s: `ALTER RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 32`}, {s: `ALTER RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 35`},
-		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, RETENTION, DEFAULT at line 1, char 42`},
-		{s: `SET`, err: `found EOF, expected PASSWORD at line 1, char 5`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, RETENTION, SHARD, DEFAULT at line 1, char 42`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb SHARD 1d`, err: `found 1d, expected DURATION at line 1, char 48`},
+		{s: `SET`, err: `found EOF, expected PASSWORD, RETENTION, CONTINUOUS at line 1, char 5`},
 		{s: `SET PASSWORD`, err: `found EOF, expected FOR at line 1, char 14`},
+		{s: `SET RETENTION INTERVAL 1m`, err: `found INTERVAL, expected CHECK at line 1, char 15`},
+		{s: `SET RETENTION CHECK INTERVAL`, err: `found EOF, expected duration at line 1, char 30`},
+		{s: `SET CONTINUOUS QUERY RUN 1s`, err: `found 1s, expected INTERVAL at line 1, char 26`},
 		{s: `SET PASSWORD something`, err: `found something, expected FOR at line 1, char 14`},
 		{s: `SET PASSWORD FOR`, err: `found EOF, expected identifier at line 1, char 18`},
 		{s: `SET PASSWORD FOR dejan`, err: `found EOF, expected = at line 1, char 24`},
@@ -1790,6 +1943,9 @@ func newAlterRetentionPolicyStatement(name string, DB string, d time.Duration, r
 	return stmt
 }
 
+// durationPtr returns a pointer to d.
+func durationPtr(d time.Duration) *time.Duration { return &d }
+
 // mustMarshalJSON encodes a value to JSON.
 func mustMarshalJSON(v interface{}) []byte {
 	b, err := json.Marshal(v)
@@ -27,10 +27,33 @@ type Row struct {
 	Name    string            `json:"name,omitempty"`
 	Tags    map[string]string `json:"tags,omitempty"`
 	Columns []string          `json:"columns,omitempty"`
+	Types   []string          `json:"types,omitempty"`
 	Values  [][]interface{}   `json:"values,omitempty"`
 	Err     error             `json:"err,omitempty"`
 }
 
+// SetTypes sets the data type of each column from the first value of the
+// column that isn't null, so a column whose first values are null still gets
+// a type. A leading "time" column is of type time even if its values were
+// converted to epochs, and columns without values are of unknown type.
+func (r *Row) SetTypes() {
+	r.Types = make([]string, len(r.Columns))
+	for i, name := range r.Columns {
+		typ := Unknown
+		if i == 0 && name == "time" {
+			typ = Time
+		} else {
+			for _, v := range r.Values {
+				if i < len(v) && v[i] != nil {
+					typ = InspectDataType(v[i])
+					break
+				}
+			}
+		}
+		r.Types[i] = typ.String()
+	}
+}
+
 // SameSeries returns true if r contains values for the same series as o.
 func (r *Row) SameSeries(o *Row) bool {
 	return r.tagsHash() == o.tagsHash() && r.Name == o.Name
@@ -83,14 +106,18 @@ type Result struct {
 	StatementID int `json:"-"`
 	Series      Rows
 	Err         error
+
+	// Partial is true if the results were truncated by a row limit.
+	Partial bool
 }
 
 // MarshalJSON encodes the result into JSON.
 func (r *Result) MarshalJSON() ([]byte, error) {
 	// Define a struct that outputs "error" as a string.
 	var o struct {
-		Series []*Row `json:"series,omitempty"`
-		Err    string `json:"error,omitempty"`
+		Series  []*Row `json:"series,omitempty"`
+		Err     string `json:"error,omitempty"`
+		Partial bool   `json:"partial,omitempty"`
 	}
 
 	// Copy fields to output struct.
@@ -98,6 +125,7 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
+	o.Partial = r.Partial
 
 	return json.Marshal(&o)
 }
@@ -105,8 +133,9 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON decodes the data into the Result struct
 func (r *Result) UnmarshalJSON(b []byte) error {
 	var o struct {
-		Series []*Row `json:"series,omitempty"`
-		Err    string `json:"error,omitempty"`
+		Series  []*Row `json:"series,omitempty"`
+		Err     string `json:"error,omitempty"`
+		Partial bool   `json:"partial,omitempty"`
 	}
 
 	err := json.Unmarshal(b, &o)
@@ -114,6 +143,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Series = o.Series
+	r.Partial = o.Partial
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
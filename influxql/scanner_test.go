@@ -120,6 +120,7 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `DELETE`, tok: influxql.DELETE},
 		{s: `DESC`, tok: influxql.DESC},
 		{s: `DROP`, tok: influxql.DROP},
+		{s: `DROPPED`, tok: influxql.DROPPED},
 		{s: `DURATION`, tok: influxql.DURATION},
 		{s: `END`, tok: influxql.END},
 		{s: `EXISTS`, tok: influxql.EXISTS},
@@ -148,6 +149,7 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `QUERIES`, tok: influxql.QUERIES},
 		{s: `QUERY`, tok: influxql.QUERY},
 		{s: `READ`, tok: influxql.READ},
+		{s: `RESTORE`, tok: influxql.RESTORE},
 		{s: `RETENTION`, tok: influxql.RETENTION},
 		{s: `REVOKE`, tok: influxql.REVOKE},
 		{s: `SELECT`, tok: influxql.SELECT},
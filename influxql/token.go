@@ -70,6 +70,7 @@ const (
 	DESC
 	DISTINCT
 	DROP
+	DROPPED
 	DURATION
 	END
 	EXISTS
@@ -101,7 +102,9 @@ const (
 	QUERIES
 	QUERY
 	READ
+	RENAME
 	REPLICATION
+	RESTORE
 	RETENTION
 	REVOKE
 	SELECT
@@ -176,6 +179,7 @@ var tokens = [...]string{
 	DELETE:       "DELETE",
 	DESC:         "DESC",
 	DROP:         "DROP",
+	DROPPED:      "DROPPED",
 	DISTINCT:     "DISTINCT",
 	DURATION:     "DURATION",
 	END:          "END",
@@ -208,7 +212,9 @@ var tokens = [...]string{
 	QUERIES:      "QUERIES",
 	QUERY:        "QUERY",
 	READ:         "READ",
+	RENAME:       "RENAME",
 	REPLICATION:  "REPLICATION",
+	RESTORE:      "RESTORE",
 	RETENTION:    "RETENTION",
 	REVOKE:       "REVOKE",
 	SELECT:       "SELECT",
@@ -259,7 +265,7 @@ func (tok Token) Precedence() int {
 		return 1
 	case AND:
 		return 2
-	case EQ, NEQ, EQREGEX, NEQREGEX, LT, LTE, GT, GTE:
+	case EQ, NEQ, EQREGEX, NEQREGEX, LT, LTE, GT, GTE, IN:
 		return 3
 	case ADD, SUB:
 		return 4
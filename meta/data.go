@@ -34,6 +34,14 @@ type Data struct {
 	MaxNodeID       uint64
 	MaxShardGroupID uint64
 	MaxShardID      uint64
+
+	// Intervals of background services that were changed at runtime, keyed
+	// by name. They override the intervals in each node's configuration.
+	Intervals map[string]time.Duration
+
+	// DroppedDatabases are databases that were dropped but are kept so they
+	// can be restored.
+	DroppedDatabases []DroppedDatabaseInfo
 }
 
 // Node returns a node by id.
@@ -120,6 +128,61 @@ func (data *Data) DropDatabase(name string) error {
 	return ErrDatabaseNotFound
 }
 
+// TrashDatabase removes a database by name but keeps it as a dropped
+// database so it can be restored. A dropped database with the same name is
+// replaced.
+func (data *Data) TrashDatabase(name string, droppedAt time.Time) error {
+	for i := range data.Databases {
+		if data.Databases[i].Name == name {
+			di := data.Databases[i]
+			data.Databases = append(data.Databases[:i], data.Databases[i+1:]...)
+
+			_ = data.PurgeDroppedDatabase(name)
+			data.DroppedDatabases = append(data.DroppedDatabases, DroppedDatabaseInfo{
+				Database:  di,
+				DroppedAt: droppedAt.UTC(),
+			})
+			return nil
+		}
+	}
+	return ErrDatabaseNotFound
+}
+
+// DroppedDatabase returns a dropped database by name.
+func (data *Data) DroppedDatabase(name string) *DroppedDatabaseInfo {
+	for i := range data.DroppedDatabases {
+		if data.DroppedDatabases[i].Database.Name == name {
+			return &data.DroppedDatabases[i]
+		}
+	}
+	return nil
+}
+
+// RestoreDatabase moves a dropped database back to the databases.
+func (data *Data) RestoreDatabase(name string) error {
+	if data.Database(name) != nil {
+		return ErrDatabaseExists
+	}
+
+	ddi := data.DroppedDatabase(name)
+	if ddi == nil {
+		return ErrDroppedDatabaseNotFound
+	}
+	data.Databases = append(data.Databases, ddi.Database)
+	return data.PurgeDroppedDatabase(name)
+}
+
+// PurgeDroppedDatabase permanently removes a dropped database by name.
+func (data *Data) PurgeDroppedDatabase(name string) error {
+	for i := range data.DroppedDatabases {
+		if data.DroppedDatabases[i].Database.Name == name {
+			data.DroppedDatabases = append(data.DroppedDatabases[:i], data.DroppedDatabases[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDroppedDatabaseNotFound
+}
+
 // RetentionPolicy returns a retention policy for a database by name.
 func (data *Data) RetentionPolicy(database, name string) (*RetentionPolicyInfo, error) {
 	di := data.Database(database)
@@ -206,6 +269,22 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 		return ErrRetentionPolicyDurationTooLow
 	}
 
+	// Enforce shard group duration of at least MinRetentionPolicyDuration
+	if rpu.ShardGroupDuration != nil && *rpu.ShardGroupDuration < MinRetentionPolicyDuration {
+		return ErrShardGroupDurationTooLow
+	}
+
+	// Ensure the shard groups will fit in the policy's duration.
+	if rpu.ShardGroupDuration != nil {
+		duration := rpi.Duration
+		if rpu.Duration != nil {
+			duration = *rpu.Duration
+		}
+		if duration != 0 && *rpu.ShardGroupDuration > duration {
+			return ErrIncompatibleDurations
+		}
+	}
+
 	// Update fields.
 	if rpu.Name != nil {
 		rpi.Name = *rpu.Name
@@ -214,6 +293,11 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 		rpi.Duration = *rpu.Duration
 		rpi.ShardGroupDuration = shardGroupDuration(rpi.Duration)
 	}
+	if rpu.ShardGroupDuration != nil {
+		// Existing shard groups keep their time ranges. Only groups created
+		// from now on use the new duration.
+		rpi.ShardGroupDuration = *rpu.ShardGroupDuration
+	}
 	if rpu.ReplicaN != nil {
 		rpi.ReplicaN = *rpu.ReplicaN
 	}
@@ -334,7 +418,7 @@ func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time)
 	sgi.Shards = make([]ShardInfo, shardN)
 	for i := range sgi.Shards {
 		data.MaxShardID++
-		sgi.Shards[i] = ShardInfo{ID: data.MaxShardID}
+		sgi.Shards[i] = ShardInfo{ID: data.MaxShardID, HasTimeRange: true}
 	}
 
 	// Assign data nodes to shards via round robin.
@@ -376,6 +460,41 @@ func (data *Data) DeleteShardGroup(database, policy string, id uint64) error {
 	return ErrShardGroupNotFound
 }
 
+// UpdateShardTimeRange extends the time range of the points written to a
+// shard to include min and max.
+func (data *Data) UpdateShardTimeRange(database, policy string, shardID uint64, min, max time.Time) error {
+	rpi, err := data.RetentionPolicy(database, policy)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		return ErrRetentionPolicyNotFound
+	}
+
+	for i := range rpi.ShardGroups {
+		for j := range rpi.ShardGroups[i].Shards {
+			sh := &rpi.ShardGroups[i].Shards[j]
+			if sh.ID != shardID {
+				continue
+			}
+
+			// Shards created before time ranges were recorded may already
+			// have points outside of the range so they're left unbounded.
+			if !sh.HasTimeRange {
+				return nil
+			}
+			if sh.MinTime.IsZero() || min.Before(sh.MinTime) {
+				sh.MinTime = min.UTC()
+			}
+			if max.After(sh.MaxTime) {
+				sh.MaxTime = max.UTC()
+			}
+			return nil
+		}
+	}
+
+	return ErrShardNotFound
+}
+
 // CreateContinuousQuery adds a named continuous query to a database.
 func (data *Data) CreateContinuousQuery(database, name, query string) error {
 	di := data.Database(database)
@@ -415,6 +534,149 @@ func (data *Data) DropContinuousQuery(database, name string) error {
 	return ErrContinuousQueryNotFound
 }
 
+// RenameMeasurement renames a measurement in a database. Points keep the
+// name they're stored under and the new name is recorded as an alias.
+func (data *Data) RenameMeasurement(database, name, newName string) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	} else if newName == "" {
+		return ErrMeasurementNameRequired
+	} else if _, ok := di.MeasurementRenames[name]; ok {
+		return ErrMeasurementNotFound
+	}
+
+	stored := di.StoredMeasurementName(name)
+	for k, v := range di.MeasurementRenames {
+		if k != stored && (k == newName || v == newName) {
+			return ErrMeasurementExists
+		}
+	}
+
+	// Renaming a measurement back to its stored name drops the alias.
+	if newName == stored {
+		delete(di.MeasurementRenames, stored)
+		return nil
+	}
+
+	if di.MeasurementRenames == nil {
+		di.MeasurementRenames = make(map[string]string)
+	}
+	di.MeasurementRenames[stored] = newName
+	return nil
+}
+
+// RenameTagKey renames a tag key across the series of a measurement. Series
+// keep the key they're stored with and the new key is recorded as an alias.
+func (data *Data) RenameTagKey(database, measurement, key, newKey string) error {
+	return data.renameTag(database, measurement, key, "", newKey)
+}
+
+// RenameTagValue rewrites a tag value across the series of a measurement.
+// Series keep the value they're stored with and the new value is recorded as
+// an alias.
+func (data *Data) RenameTagValue(database, measurement, key, value, newValue string) error {
+	if value == "" {
+		return ErrTagNameRequired
+	}
+	return data.renameTag(database, measurement, key, value, newValue)
+}
+
+// renameTag renames the key, or the value of the key if value is set, of a
+// tag in a measurement. The arguments are the current names.
+func (data *Data) renameTag(database, measurement, key, value, newName string) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	} else if newName == "" {
+		return ErrTagNameRequired
+	}
+
+	// Resolve the current names to the stored names.
+	measurement = di.StoredMeasurementName(measurement)
+	name := key
+	if value == "" {
+		key = ""
+	} else {
+		key, name = di.StoredTagKey(measurement, key), value
+	}
+
+	// A stored name hidden by an alias can't be renamed again.
+	stored := name
+	for _, r := range di.TagRenames {
+		if r.Measurement != measurement || r.key() != key {
+			continue
+		} else if r.name() == name {
+			return ErrTagNotFound
+		} else if r.NewName == name {
+			stored = r.name()
+		}
+	}
+
+	for _, r := range di.TagRenames {
+		if r.Measurement == measurement && r.key() == key && r.name() != stored && (r.name() == newName || r.NewName == newName) {
+			return ErrTagExists
+		}
+	}
+
+	// Remove the old alias and add the new one unless the tag is renamed back
+	// to its stored name.
+	for i, r := range di.TagRenames {
+		if r.Measurement == measurement && r.key() == key && r.name() == stored {
+			di.TagRenames = append(di.TagRenames[:i], di.TagRenames[i+1:]...)
+			break
+		}
+	}
+	if newName != stored {
+		r := TagRenameInfo{Measurement: measurement, Key: stored, NewName: newName}
+		if key != "" {
+			r.Key, r.Value = key, stored
+		}
+		di.TagRenames = append(di.TagRenames, r)
+	}
+	return nil
+}
+
+// CreateTagAlias adds a deprecated name that queries of a database may use
+// for a tag key, or for a value of the key if value is set. Queries using the
+// alias are rewritten to the current name.
+func (data *Data) CreateTagAlias(database, key, value, alias string) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	} else if key == "" || alias == "" {
+		return ErrTagNameRequired
+	}
+
+	for _, a := range di.TagAliases {
+		if a.Alias != alias || (a.Value == "") != (value == "") {
+			continue
+		} else if value == "" || a.Key == key {
+			return ErrTagAliasExists
+		}
+	}
+
+	di.TagAliases = append(di.TagAliases, TagAliasInfo{Key: key, Value: value, Alias: alias})
+	return nil
+}
+
+// DropTagAlias removes an alias of a tag key, or of a value of the key if
+// value is set.
+func (data *Data) DropTagAlias(database, key, value, alias string) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+
+	for i, a := range di.TagAliases {
+		if a == (TagAliasInfo{Key: key, Value: value, Alias: alias}) {
+			di.TagAliases = append(di.TagAliases[:i], di.TagAliases[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTagAliasNotFound
+}
+
 // User returns a user by username.
 func (data *Data) User(username string) *UserInfo {
 	for i := range data.Users {
@@ -519,6 +781,27 @@ func (data *Data) UserPrivilege(name, database string) (*influxql.Privilege, err
 	return influxql.NewPrivilege(influxql.NoPrivileges), nil
 }
 
+// SetInterval sets how often a background service runs on every node. A zero
+// interval restores the configured interval.
+func (data *Data) SetInterval(name string, d time.Duration) error {
+	switch name {
+	case influxql.RetentionCheckInterval, influxql.ContinuousQueryRunInterval:
+	default:
+		return ErrIntervalNotFound
+	}
+
+	if d == 0 {
+		delete(data.Intervals, name)
+		return nil
+	}
+
+	if data.Intervals == nil {
+		data.Intervals = make(map[string]time.Duration)
+	}
+	data.Intervals[name] = d
+	return nil
+}
+
 // Clone returns a copy of data with a new version.
 func (data *Data) Clone() *Data {
 	other := *data
@@ -547,6 +830,22 @@ func (data *Data) Clone() *Data {
 		}
 	}
 
+	// Copy intervals.
+	if data.Intervals != nil {
+		other.Intervals = make(map[string]time.Duration, len(data.Intervals))
+		for name, d := range data.Intervals {
+			other.Intervals[name] = d
+		}
+	}
+
+	// Deep copy dropped databases.
+	if data.DroppedDatabases != nil {
+		other.DroppedDatabases = make([]DroppedDatabaseInfo, len(data.DroppedDatabases))
+		for i := range data.DroppedDatabases {
+			other.DroppedDatabases[i] = data.DroppedDatabases[i].clone()
+		}
+	}
+
 	return &other
 }
 
@@ -577,6 +876,23 @@ func (data *Data) marshal() *internal.Data {
 		pb.Users[i] = data.Users[i].marshal()
 	}
 
+	names := make([]string, 0, len(data.Intervals))
+	for name := range data.Intervals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pb.Intervals = append(pb.Intervals, &internal.IntervalInfo{
+			Name:     proto.String(name),
+			Duration: proto.Int64(int64(data.Intervals[name])),
+		})
+	}
+
+	pb.DroppedDatabases = make([]*internal.DroppedDatabaseInfo, len(data.DroppedDatabases))
+	for i := range data.DroppedDatabases {
+		pb.DroppedDatabases[i] = data.DroppedDatabases[i].marshal()
+	}
+
 	return pb
 }
 
@@ -604,6 +920,22 @@ func (data *Data) unmarshal(pb *internal.Data) {
 	for i, x := range pb.GetUsers() {
 		data.Users[i].unmarshal(x)
 	}
+
+	data.Intervals = nil
+	if len(pb.GetIntervals()) > 0 {
+		data.Intervals = make(map[string]time.Duration, len(pb.GetIntervals()))
+		for _, x := range pb.GetIntervals() {
+			data.Intervals[x.GetName()] = time.Duration(x.GetDuration())
+		}
+	}
+
+	data.DroppedDatabases = nil
+	if len(pb.GetDroppedDatabases()) > 0 {
+		data.DroppedDatabases = make([]DroppedDatabaseInfo, len(pb.GetDroppedDatabases()))
+		for i, x := range pb.GetDroppedDatabases() {
+			data.DroppedDatabases[i].unmarshal(x)
+		}
+	}
 }
 
 // MarshalBinary encodes the metadata to a binary format.
@@ -650,6 +982,88 @@ type DatabaseInfo struct {
 	DefaultRetentionPolicy string
 	RetentionPolicies      []RetentionPolicyInfo
 	ContinuousQueries      []ContinuousQueryInfo
+
+	// New names of renamed measurements, keyed by the name their points are
+	// stored under.
+	MeasurementRenames map[string]string
+
+	// Renamed tag keys and rewritten tag values.
+	TagRenames []TagRenameInfo
+
+	// Deprecated tag keys and values that queries may still use.
+	TagAliases []TagAliasInfo
+}
+
+// TagKey returns the current name of a tag key stored in a measurement.
+func (di DatabaseInfo) TagKey(measurement, key string) string {
+	for _, r := range di.TagRenames {
+		if r.Measurement == measurement && r.Value == "" && r.Key == key {
+			return r.NewName
+		}
+	}
+	return key
+}
+
+// StoredTagKey returns the key a tag of a measurement is stored with. This
+// differs from key if the tag key was renamed.
+func (di DatabaseInfo) StoredTagKey(measurement, key string) string {
+	for _, r := range di.TagRenames {
+		if r.Measurement == measurement && r.Value == "" && r.NewName == key {
+			return r.Key
+		}
+	}
+	return key
+}
+
+// TagValue returns the current value of a tag stored in a measurement. The
+// key is the stored key.
+func (di DatabaseInfo) TagValue(measurement, key, value string) string {
+	for _, r := range di.TagRenames {
+		if r.Measurement == measurement && r.Key == key && r.Value != "" && r.Value == value {
+			return r.NewName
+		}
+	}
+	return value
+}
+
+// StoredTagValue returns the value a tag of a measurement is stored with. The
+// key is the stored key. This differs from value if the value was rewritten.
+func (di DatabaseInfo) StoredTagValue(measurement, key, value string) string {
+	for _, r := range di.TagRenames {
+		if r.Measurement == measurement && r.Key == key && r.Value != "" && r.NewName == value {
+			return r.Value
+		}
+	}
+	return value
+}
+
+// HasTagRenames returns true if a tag key or value of a stored measurement was renamed.
+func (di DatabaseInfo) HasTagRenames(measurement string) bool {
+	for _, r := range di.TagRenames {
+		if r.Measurement == measurement {
+			return true
+		}
+	}
+	return false
+}
+
+// MeasurementName returns the current name of the measurement stored as name.
+func (di DatabaseInfo) MeasurementName(name string) string {
+	if newName, ok := di.MeasurementRenames[name]; ok {
+		return newName
+	}
+	return name
+}
+
+// StoredMeasurementName returns the name the points of a measurement are
+// stored under. This differs from name if the measurement was renamed.
+func (di DatabaseInfo) StoredMeasurementName(name string) string {
+	for stored, newName := range di.MeasurementRenames {
+		if newName == name {
+			return stored
+		}
+	}
+	return name
 }
 
 // RetentionPolicy returns a retention policy by name.
@@ -681,6 +1095,26 @@ func (di DatabaseInfo) clone() DatabaseInfo {
 		}
 	}
 
+	// Copy measurement renames.
+	if di.MeasurementRenames != nil {
+		other.MeasurementRenames = make(map[string]string, len(di.MeasurementRenames))
+		for k, v := range di.MeasurementRenames {
+			other.MeasurementRenames[k] = v
+		}
+	}
+
+	// Copy tag renames.
+	if di.TagRenames != nil {
+		other.TagRenames = make([]TagRenameInfo, len(di.TagRenames))
+		copy(other.TagRenames, di.TagRenames)
+	}
+
+	// Copy tag aliases.
+	if di.TagAliases != nil {
+		other.TagAliases = make([]TagAliasInfo, len(di.TagAliases))
+		copy(other.TagAliases, di.TagAliases)
+	}
+
 	return other
 }
 
@@ -699,6 +1133,26 @@ func (di DatabaseInfo) marshal() *internal.DatabaseInfo {
 	for i := range di.ContinuousQueries {
 		pb.ContinuousQueries[i] = di.ContinuousQueries[i].marshal()
 	}
+
+	names := make([]string, 0, len(di.MeasurementRenames))
+	for name := range di.MeasurementRenames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pb.MeasurementRenames = append(pb.MeasurementRenames, &internal.MeasurementRename{
+			Name:    proto.String(name),
+			NewName: proto.String(di.MeasurementRenames[name]),
+		})
+	}
+
+	for i := range di.TagRenames {
+		pb.TagRenames = append(pb.TagRenames, di.TagRenames[i].marshal())
+	}
+
+	for i := range di.TagAliases {
+		pb.TagAliases = append(pb.TagAliases, di.TagAliases[i].marshal())
+	}
 	return pb
 }
 
@@ -720,6 +1174,127 @@ func (di *DatabaseInfo) unmarshal(pb *internal.DatabaseInfo) {
 			di.ContinuousQueries[i].unmarshal(x)
 		}
 	}
+
+	if len(pb.GetMeasurementRenames()) > 0 {
+		di.MeasurementRenames = make(map[string]string, len(pb.GetMeasurementRenames()))
+		for _, x := range pb.GetMeasurementRenames() {
+			di.MeasurementRenames[x.GetName()] = x.GetNewName()
+		}
+	}
+
+	if len(pb.GetTagRenames()) > 0 {
+		di.TagRenames = make([]TagRenameInfo, len(pb.GetTagRenames()))
+		for i, x := range pb.GetTagRenames() {
+			di.TagRenames[i].unmarshal(x)
+		}
+	}
+
+	if len(pb.GetTagAliases()) > 0 {
+		di.TagAliases = make([]TagAliasInfo, len(pb.GetTagAliases()))
+		for i, x := range pb.GetTagAliases() {
+			di.TagAliases[i].unmarshal(x)
+		}
+	}
+}
+
+// TagRenameInfo represents a renamed tag key, or a rewritten tag value if
+// Value is set. Measurement, Key and Value are the stored names.
+type TagRenameInfo struct {
+	Measurement string
+	Key         string
+	Value       string
+	NewName     string
+}
+
+// key returns the stored key of a rewritten value, or blank for a renamed key.
+func (r TagRenameInfo) key() string {
+	if r.Value == "" {
+		return ""
+	}
+	return r.Key
+}
+
+// name returns the stored name that was renamed.
+func (r TagRenameInfo) name() string {
+	if r.Value == "" {
+		return r.Key
+	}
+	return r.Value
+}
+
+// marshal serializes to a protobuf representation.
+func (r TagRenameInfo) marshal() *internal.TagRenameInfo {
+	pb := &internal.TagRenameInfo{
+		Measurement: proto.String(r.Measurement),
+		Key:         proto.String(r.Key),
+		NewName:     proto.String(r.NewName),
+	}
+	if r.Value != "" {
+		pb.Value = proto.String(r.Value)
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (r *TagRenameInfo) unmarshal(pb *internal.TagRenameInfo) {
+	r.Measurement = pb.GetMeasurement()
+	r.Key = pb.GetKey()
+	r.Value = pb.GetValue()
+	r.NewName = pb.GetNewName()
+}
+
+// TagAliasInfo represents a deprecated tag key, or a deprecated value of Key
+// if Value is set. Key and Value are the current names.
+type TagAliasInfo struct {
+	Key   string
+	Value string
+	Alias string
+}
+
+// marshal serializes to a protobuf representation.
+func (a TagAliasInfo) marshal() *internal.TagAliasInfo {
+	pb := &internal.TagAliasInfo{
+		Key:   proto.String(a.Key),
+		Alias: proto.String(a.Alias),
+	}
+	if a.Value != "" {
+		pb.Value = proto.String(a.Value)
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (a *TagAliasInfo) unmarshal(pb *internal.TagAliasInfo) {
+	a.Key = pb.GetKey()
+	a.Value = pb.GetValue()
+	a.Alias = pb.GetAlias()
+}
+
+// DroppedDatabaseInfo represents a dropped database kept so it can be restored.
+type DroppedDatabaseInfo struct {
+	Database  DatabaseInfo
+	DroppedAt time.Time
+}
+
+// clone returns a deep copy of ddi.
+func (ddi DroppedDatabaseInfo) clone() DroppedDatabaseInfo {
+	other := ddi
+	other.Database = ddi.Database.clone()
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ddi DroppedDatabaseInfo) marshal() *internal.DroppedDatabaseInfo {
+	return &internal.DroppedDatabaseInfo{
+		Database:  ddi.Database.marshal(),
+		DroppedAt: proto.Int64(ddi.DroppedAt.UnixNano()),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ddi *DroppedDatabaseInfo) unmarshal(pb *internal.DroppedDatabaseInfo) {
+	ddi.Database.unmarshal(pb.GetDatabase())
+	ddi.DroppedAt = time.Unix(0, pb.GetDroppedAt()).UTC()
 }
 
 // RetentionPolicyInfo represents metadata about a retention policy.
@@ -919,6 +1494,24 @@ func (sgi *ShardGroupInfo) unmarshal(pb *internal.ShardGroupInfo) {
 type ShardInfo struct {
 	ID       uint64
 	OwnerIDs []uint64
+
+	// MinTime and MaxTime bound the points written to the shard if
+	// HasTimeRange is set. They're zero until points are written. Shards
+	// created before ranges were recorded have no time range.
+	HasTimeRange bool
+	MinTime      time.Time
+	MaxTime      time.Time
+}
+
+// Overlaps returns true if points between min and max may be in the shard.
+// Shards without a time range always overlap.
+func (si ShardInfo) Overlaps(min, max time.Time) bool {
+	if !si.HasTimeRange {
+		return true
+	} else if si.MinTime.IsZero() && si.MaxTime.IsZero() {
+		return false
+	}
+	return !si.MaxTime.Before(min) && !si.MinTime.After(max)
 }
 
 // OwnedBy returns whether the shard's owner IDs includes nodeID.
@@ -952,6 +1545,14 @@ func (si ShardInfo) marshal() *internal.ShardInfo {
 	pb.OwnerIDs = make([]uint64, len(si.OwnerIDs))
 	copy(pb.OwnerIDs, si.OwnerIDs)
 
+	if si.HasTimeRange {
+		pb.HasTimeRange = proto.Bool(true)
+		if !si.MinTime.IsZero() || !si.MaxTime.IsZero() {
+			pb.MinTime = proto.Int64(si.MinTime.UnixNano())
+			pb.MaxTime = proto.Int64(si.MaxTime.UnixNano())
+		}
+	}
+
 	return pb
 }
 
@@ -960,6 +1561,11 @@ func (si *ShardInfo) unmarshal(pb *internal.ShardInfo) {
 	si.ID = pb.GetID()
 	si.OwnerIDs = make([]uint64, len(pb.GetOwnerIDs()))
 	copy(si.OwnerIDs, pb.GetOwnerIDs())
+	si.HasTimeRange = pb.GetHasTimeRange()
+	if pb.MinTime != nil || pb.MaxTime != nil {
+		si.MinTime = time.Unix(0, pb.GetMinTime()).UTC()
+		si.MaxTime = time.Unix(0, pb.GetMaxTime()).UTC()
+	}
 }
 
 // ContinuousQueryInfo represents metadata about a continuous query.
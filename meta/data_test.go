@@ -90,6 +90,44 @@ func TestData_DropDatabase(t *testing.T) {
 	}
 }
 
+// Ensure a database can be trashed and restored.
+func TestData_TrashDatabase(t *testing.T) {
+	var data meta.Data
+	for i := 0; i < 2; i++ {
+		if err := data.CreateDatabase(fmt.Sprintf("db%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	droppedAt := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.TrashDatabase("db1", droppedAt); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases, []meta.DatabaseInfo{{Name: "db0"}}) {
+		t.Fatalf("unexpected databases: %#v", data.Databases)
+	} else if ddi := data.DroppedDatabase("db1"); ddi == nil || !ddi.DroppedAt.Equal(droppedAt) {
+		t.Fatalf("unexpected dropped database: %#v", ddi)
+	}
+
+	// A database with the same name can't be restored over an existing one.
+	if err := data.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RestoreDatabase("db1"); err != meta.ErrDatabaseExists {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.DropDatabase("db1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RestoreDatabase("db1"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases, []meta.DatabaseInfo{{Name: "db0"}, {Name: "db1"}}) {
+		t.Fatalf("unexpected databases: %#v", data.Databases)
+	} else if len(data.DroppedDatabases) != 0 {
+		t.Fatalf("unexpected dropped databases: %#v", data.DroppedDatabases)
+	} else if err := data.RestoreDatabase("db2"); err != meta.ErrDroppedDatabaseNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // Ensure a retention policy can be created.
 func TestData_CreateRetentionPolicy(t *testing.T) {
 	data := meta.Data{Nodes: []meta.NodeInfo{{ID: 1}, {ID: 2}}}
@@ -185,6 +223,43 @@ func TestData_UpdateRetentionPolicy(t *testing.T) {
 	}
 }
 
+// Ensure a retention policy's shard group duration can be updated.
+func TestData_UpdateRetentionPolicy_ShardGroupDuration(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateNode("node0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: 7 * 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rpu meta.RetentionPolicyUpdate
+	rpu.SetShardGroupDuration(2 * time.Hour)
+	if err := data.UpdateRetentionPolicy("db0", "rp0", &rpu); err != nil {
+		t.Fatal(err)
+	} else if rpi, _ := data.RetentionPolicy("db0", "rp0"); rpi.ShardGroupDuration != 2*time.Hour {
+		t.Fatalf("unexpected shard group duration: %s", rpi.ShardGroupDuration)
+	}
+
+	// Ensure new shard groups use the new duration.
+	if err := data.CreateShardGroup("db0", "rp0", time.Date(2000, time.January, 1, 3, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	} else if sgi := data.Databases[0].RetentionPolicies[0].ShardGroups[0]; sgi.EndTime.Sub(sgi.StartTime) != 2*time.Hour {
+		t.Fatalf("unexpected shard group range: %s - %s", sgi.StartTime, sgi.EndTime)
+	}
+
+	// Ensure the shard group duration is validated.
+	rpu.SetShardGroupDuration(time.Minute)
+	if err := data.UpdateRetentionPolicy("db0", "rp0", &rpu); err != meta.ErrShardGroupDurationTooLow {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rpu.SetShardGroupDuration(8 * 24 * time.Hour)
+	if err := data.UpdateRetentionPolicy("db0", "rp0", &rpu); err != meta.ErrIncompatibleDurations {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure a retention policy can be removed.
 func TestData_DropRetentionPolicy(t *testing.T) {
 	var data meta.Data
@@ -299,7 +374,7 @@ func TestData_CreateShardGroup(t *testing.T) {
 		StartTime: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
 		EndTime:   time.Date(2000, time.January, 1, 1, 0, 0, 0, time.UTC),
 		Shards: []meta.ShardInfo{
-			{ID: 1, OwnerIDs: []uint64{1, 2}},
+			{ID: 1, OwnerIDs: []uint64{1, 2}, HasTimeRange: true},
 		},
 	}) {
 		t.Fatalf("unexpected shard group: %#v", sgi)
@@ -428,6 +503,64 @@ func TestData_DeleteShardGroup(t *testing.T) {
 	}
 }
 
+// Ensure the time range of points written to a shard can be extended.
+func TestData_UpdateShardTimeRange(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateNode("node0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateShardGroup("db0", "rp0", time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new shard has no points so it doesn't overlap any time range.
+	sh := &data.Databases[0].RetentionPolicies[0].ShardGroups[0].Shards[0]
+	if sh.Overlaps(time.Unix(0, 0), time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("unexpected overlap of empty shard")
+	}
+
+	t0 := time.Date(2000, time.January, 1, 0, 10, 0, 0, time.UTC)
+	t1 := time.Date(2000, time.January, 1, 0, 20, 0, 0, time.UTC)
+	if err := data.UpdateShardTimeRange("db0", "rp0", 1, t0, t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.UpdateShardTimeRange("db0", "rp0", 1, t1, t1); err != nil {
+		t.Fatal(err)
+	} else if !sh.MinTime.Equal(t0) || !sh.MaxTime.Equal(t1) {
+		t.Fatalf("unexpected time range: %s - %s", sh.MinTime, sh.MaxTime)
+	}
+
+	if sh.Overlaps(t1.Add(time.Second), t1.Add(time.Minute)) {
+		t.Fatal("unexpected overlap after max time")
+	} else if !sh.Overlaps(t1, t1.Add(time.Minute)) {
+		t.Fatal("expected overlap at max time")
+	}
+
+	// The range survives encoding.
+	var other meta.Data
+	if buf, err := data.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	} else if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if osh := other.Databases[0].RetentionPolicies[0].ShardGroups[0].Shards[0]; !osh.HasTimeRange || !osh.MinTime.Equal(t0) || !osh.MaxTime.Equal(t1) {
+		t.Fatalf("unexpected decoded shard: %#v", osh)
+	}
+
+	// Shards without a time range stay unbounded.
+	sh.HasTimeRange, sh.MinTime, sh.MaxTime = false, time.Time{}, time.Time{}
+	if err := data.UpdateShardTimeRange("db0", "rp0", 1, t0, t1); err != nil {
+		t.Fatal(err)
+	} else if !sh.MinTime.IsZero() || !sh.Overlaps(t1.Add(time.Hour), t1.Add(time.Hour)) {
+		t.Fatalf("unexpected time range: %s - %s", sh.MinTime, sh.MaxTime)
+	}
+
+	if err := data.UpdateShardTimeRange("db0", "rp0", 2, t0, t1); err != meta.ErrShardNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // Ensure a continuous query can be created.
 func TestData_CreateContinuousQuery(t *testing.T) {
 	var data meta.Data
@@ -462,6 +595,148 @@ func TestData_DropContinuousQuery(t *testing.T) {
 	}
 }
 
+// Ensure a measurement can be renamed and renamed back.
+func TestData_RenameMeasurement(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RenameMeasurement("db0", "cpu_typo", "cpu_load"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RenameMeasurement("db0", "cpu_load", "cpu"); err != nil {
+		t.Fatal(err)
+	}
+
+	di := data.Database("db0")
+	if name := di.MeasurementName("cpu_typo"); name != "cpu" {
+		t.Fatalf("unexpected name: %s", name)
+	} else if name := di.StoredMeasurementName("cpu"); name != "cpu_typo" {
+		t.Fatalf("unexpected stored name: %s", name)
+	} else if name := di.StoredMeasurementName("mem"); name != "mem" {
+		t.Fatalf("unexpected stored name: %s", name)
+	}
+
+	// Renaming back to the stored name removes the alias.
+	if err := data.RenameMeasurement("db0", "cpu", "cpu_typo"); err != nil {
+		t.Fatal(err)
+	} else if len(data.Database("db0").MeasurementRenames) != 0 {
+		t.Fatalf("unexpected renames: %v", data.Database("db0").MeasurementRenames)
+	}
+}
+
+// Ensure renaming a measurement returns an error if the new name is in use.
+func TestData_RenameMeasurement_ErrMeasurementExists(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RenameMeasurement("db0", "cpu_typo", "cpu"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RenameMeasurement("db0", "mem_typo", "cpu"); err != meta.ErrMeasurementExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RenameMeasurement("db0", "mem_typo", "cpu_typo"); err != meta.ErrMeasurementExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure renaming a measurement returns an error if the database doesn't exist.
+func TestData_RenameMeasurement_ErrDatabaseNotFound(t *testing.T) {
+	var data meta.Data
+	if err := data.RenameMeasurement("db0", "cpu_typo", "cpu"); err != meta.ErrDatabaseNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure tag keys and values can be renamed.
+func TestData_RenameTag(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RenameTagKey("db0", "cpu", "host", "hostname"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RenameTagValue("db0", "cpu", "hostname", "serverA", "server-a"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RenameTagValue("db0", "cpu", "hostname", "server-a", "server-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	di := data.Database("db0")
+	if key := di.TagKey("cpu", "host"); key != "hostname" {
+		t.Fatalf("unexpected key: %s", key)
+	} else if key := di.StoredTagKey("cpu", "hostname"); key != "host" {
+		t.Fatalf("unexpected stored key: %s", key)
+	} else if value := di.TagValue("cpu", "host", "serverA"); value != "server-1" {
+		t.Fatalf("unexpected value: %s", value)
+	} else if value := di.StoredTagValue("cpu", "host", "server-1"); value != "serverA" {
+		t.Fatalf("unexpected stored value: %s", value)
+	} else if di.HasTagRenames("mem") {
+		t.Fatal("unexpected tag renames for mem")
+	}
+
+	// Renaming back to the stored name removes the alias.
+	if err := data.RenameTagKey("db0", "cpu", "hostname", "host"); err != nil {
+		t.Fatal(err)
+	} else if key := data.Database("db0").TagKey("cpu", "host"); key != "host" {
+		t.Fatalf("unexpected key: %s", key)
+	}
+}
+
+// Ensure renaming a tag returns an error if the new name is in use or the
+// stored name was already renamed.
+func TestData_RenameTag_Err(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RenameTagKey("db0", "cpu", "host", "hostname"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RenameTagKey("db0", "cpu", "server", "hostname"); err != meta.ErrTagExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RenameTagKey("db0", "cpu", "host", "server"); err != meta.ErrTagNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RenameTagKey("db0", "cpu", "hostname", ""); err != meta.ErrTagNameRequired {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure tag aliases can be created and dropped.
+func TestData_TagAlias(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.CreateTagAlias("db0", "datacenter", "", "dc"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateTagAlias("db0", "datacenter", "us-west-1", "usw1"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateTagAlias("db0", "region", "", "dc"); err != meta.ErrTagAliasExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateTagAlias("db0", "datacenter", "", ""); err != meta.ErrTagNameRequired {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if a := data.Database("db0").TagAliases; !reflect.DeepEqual(a, []meta.TagAliasInfo{
+		{Key: "datacenter", Alias: "dc"},
+		{Key: "datacenter", Value: "us-west-1", Alias: "usw1"},
+	}) {
+		t.Fatalf("unexpected aliases: %#v", a)
+	}
+
+	if err := data.DropTagAlias("db0", "datacenter", "", "usw1"); err != meta.ErrTagAliasNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.DropTagAlias("db0", "datacenter", "us-west-1", "usw1"); err != nil {
+		t.Fatal(err)
+	} else if a := data.Database("db0").TagAliases; len(a) != 1 {
+		t.Fatalf("unexpected aliases: %#v", a)
+	}
+}
+
 // Ensure a user can be created.
 func TestData_CreateUser(t *testing.T) {
 	var data meta.Data
@@ -544,6 +819,30 @@ func TestData_UpdateUser_ErrUserNotFound(t *testing.T) {
 	}
 }
 
+// Ensure an interval can be set and cleared.
+func TestData_SetInterval(t *testing.T) {
+	var data meta.Data
+	if err := data.SetInterval(influxql.ContinuousQueryRunInterval, 10*time.Second); err != nil {
+		t.Fatal(err)
+	} else if d := data.Intervals[influxql.ContinuousQueryRunInterval]; d != 10*time.Second {
+		t.Fatalf("unexpected interval: %s", d)
+	}
+
+	if err := data.SetInterval(influxql.ContinuousQueryRunInterval, 0); err != nil {
+		t.Fatal(err)
+	} else if _, ok := data.Intervals[influxql.ContinuousQueryRunInterval]; ok {
+		t.Fatal("expected interval to be cleared")
+	}
+}
+
+// Ensure an unknown interval can't be set.
+func TestData_SetInterval_ErrIntervalNotFound(t *testing.T) {
+	var data meta.Data
+	if err := data.SetInterval("no_such_interval", time.Second); err != meta.ErrIntervalNotFound {
+		t.Fatal(err)
+	}
+}
+
 // Ensure the data can be deeply copied.
 func TestData_Clone(t *testing.T) {
 	data := meta.Data{
@@ -581,6 +880,11 @@ func TestData_Clone(t *testing.T) {
 				ContinuousQueries: []meta.ContinuousQueryInfo{
 					{Query: "SELECT count() FROM foo"},
 				},
+				MeasurementRenames: map[string]string{"cpu_typo": "cpu"},
+				TagRenames: []meta.TagRenameInfo{
+					{Measurement: "cpu", Key: "host", NewName: "hostname"},
+					{Measurement: "cpu", Key: "region", Value: "us_west", NewName: "us-west"},
+				},
 			},
 		},
 		Users: []meta.UserInfo{
@@ -658,6 +962,15 @@ func TestData_MarshalBinary(t *testing.T) {
 				Privileges: map[string]influxql.Privilege{"db0": influxql.AllPrivileges},
 			},
 		},
+		Intervals: map[string]time.Duration{
+			influxql.RetentionCheckInterval: 5 * time.Minute,
+		},
+		DroppedDatabases: []meta.DroppedDatabaseInfo{
+			{
+				Database:  meta.DatabaseInfo{Name: "db1", DefaultRetentionPolicy: "default"},
+				DroppedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
 	}
 
 	// Marshal the data struture.
@@ -680,5 +993,9 @@ func TestData_MarshalBinary(t *testing.T) {
 		t.Fatalf("unexpected databases: %#v", other.Databases)
 	} else if !reflect.DeepEqual(data.Users, other.Users) {
 		t.Fatalf("unexpected users: %#v", other.Users)
+	} else if !reflect.DeepEqual(data.Intervals, other.Intervals) {
+		t.Fatalf("unexpected intervals: %#v", other.Intervals)
+	} else if !reflect.DeepEqual(data.DroppedDatabases, other.DroppedDatabases) {
+		t.Fatalf("unexpected dropped databases: %#v", other.DroppedDatabases)
 	}
 }
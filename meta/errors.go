@@ -37,6 +37,10 @@ var (
 
 	// ErrDatabaseNameRequired is returned when creating a database without a name.
 	ErrDatabaseNameRequired = errors.New("database name required")
+
+	// ErrDroppedDatabaseNotFound is returned when restoring or purging a
+	// dropped database that isn't kept.
+	ErrDroppedDatabaseNotFound = errors.New("dropped database not found")
 )
 
 var (
@@ -56,19 +60,61 @@ var (
 	// ErrRetentionPolicyDurationTooLow is returned when updating a retention
 	// policy that has a duration lower than the allowed minimum.
 	ErrRetentionPolicyDurationTooLow = errors.New(fmt.Sprintf("retention policy duration must be at least %s",
-		RetentionPolicyMinDuration))
+		MinRetentionPolicyDuration))
+
+	// ErrShardGroupDurationTooLow is returned when updating a retention
+	// policy with a shard group duration lower than the allowed minimum.
+	ErrShardGroupDurationTooLow = errors.New(fmt.Sprintf("shard group duration must be at least %s",
+		MinRetentionPolicyDuration))
+
+	// ErrIncompatibleDurations is returned when a retention policy's shard group
+	// duration is longer than the policy's duration.
+	ErrIncompatibleDurations = errors.New("retention policy duration must be greater than the shard group duration")
 
 	// ErrReplicationFactorTooLow is returned when the replication factor is not in an
 	// acceptable range.
 	ErrReplicationFactorTooLow = errors.New("replication factor must be greater than 0")
 )
 
+var (
+	// ErrMeasurementExists is returned when renaming a measurement to a name
+	// that's already in use.
+	ErrMeasurementExists = errors.New("measurement already exists")
+
+	// ErrMeasurementNotFound is returned when renaming a measurement that was
+	// already renamed.
+	ErrMeasurementNotFound = errors.New("measurement not found")
+
+	// ErrMeasurementNameRequired is returned when renaming a measurement to a blank name.
+	ErrMeasurementNameRequired = errors.New("measurement name required")
+
+	// ErrTagExists is returned when renaming a tag key or value to one that's
+	// already in use.
+	ErrTagExists = errors.New("tag already exists")
+
+	// ErrTagNotFound is returned when renaming a tag key or value that was
+	// already renamed.
+	ErrTagNotFound = errors.New("tag not found")
+
+	// ErrTagNameRequired is returned when renaming a tag key or value to a blank name.
+	ErrTagNameRequired = errors.New("tag name required")
+
+	// ErrTagAliasExists is returned when creating a tag alias that's already in use.
+	ErrTagAliasExists = errors.New("tag alias already exists")
+
+	// ErrTagAliasNotFound is returned when dropping a tag alias that doesn't exist.
+	ErrTagAliasNotFound = errors.New("tag alias not found")
+)
+
 var (
 	// ErrShardGroupExists is returned when creating an already existing shard group.
 	ErrShardGroupExists = errors.New("shard group already exists")
 
 	// ErrShardGroupNotFound is returned when mutating a shard group that doesn't exist.
 	ErrShardGroupNotFound = errors.New("shard group not found")
+
+	// ErrShardNotFound is returned when mutating a shard that doesn't exist.
+	ErrShardNotFound = errors.New("shard not found")
 )
 
 var (
@@ -90,10 +136,19 @@ var (
 	ErrUsernameRequired = errors.New("username required")
 )
 
+var (
+	// ErrIntervalNotFound is returned when setting an interval that doesn't exist.
+	ErrIntervalNotFound = errors.New("interval not found")
+)
+
 var errs = [...]error{
 	ErrStoreOpen, ErrStoreClosed,
 	ErrNodeExists, ErrNodeNotFound,
-	ErrDatabaseExists, ErrDatabaseNotFound, ErrDatabaseNameRequired,
+	ErrDatabaseExists, ErrDatabaseNotFound, ErrDatabaseNameRequired, ErrDroppedDatabaseNotFound,
+	ErrIntervalNotFound,
+	ErrMeasurementExists, ErrMeasurementNotFound, ErrMeasurementNameRequired,
+	ErrTagExists, ErrTagNotFound, ErrTagNameRequired,
+	ErrTagAliasExists, ErrTagAliasNotFound,
 }
 
 // errLookup stores a mapping of error strings to well defined error types.
@@ -12,12 +12,17 @@ It has these top-level messages:
 	Data
 	NodeInfo
 	DatabaseInfo
+	MeasurementRename
+	TagRenameInfo
+	TagAliasInfo
 	RetentionPolicyInfo
 	ShardGroupInfo
 	ShardInfo
 	ContinuousQueryInfo
 	UserInfo
 	UserPrivilege
+	IntervalInfo
+	DroppedDatabaseInfo
 	Command
 	CreateNodeCommand
 	DeleteNodeCommand
@@ -38,6 +43,15 @@ It has these top-level messages:
 	SetDataCommand
 	SetAdminPrivilegeCommand
 	UpdateNodeCommand
+	SetIntervalCommand
+	RenameMeasurementCommand
+	RenameTagCommand
+	UpdateShardTimeRangeCommand
+	TrashDatabaseCommand
+	RestoreDatabaseCommand
+	PurgeDroppedDatabaseCommand
+	CreateTagAliasCommand
+	DropTagAliasCommand
 	Response
 	ResponseHeader
 	ErrorResponse
@@ -113,6 +127,15 @@ const (
 	Command_SetDataCommand                   Command_Type = 17
 	Command_SetAdminPrivilegeCommand         Command_Type = 18
 	Command_UpdateNodeCommand                Command_Type = 19
+	Command_SetIntervalCommand               Command_Type = 20
+	Command_RenameMeasurementCommand         Command_Type = 21
+	Command_RenameTagCommand                 Command_Type = 22
+	Command_UpdateShardTimeRangeCommand      Command_Type = 23
+	Command_TrashDatabaseCommand             Command_Type = 24
+	Command_RestoreDatabaseCommand           Command_Type = 25
+	Command_PurgeDroppedDatabaseCommand      Command_Type = 26
+	Command_CreateTagAliasCommand            Command_Type = 27
+	Command_DropTagAliasCommand              Command_Type = 28
 )
 
 var Command_Type_name = map[int32]string{
@@ -135,6 +158,15 @@ var Command_Type_name = map[int32]string{
 	17: "SetDataCommand",
 	18: "SetAdminPrivilegeCommand",
 	19: "UpdateNodeCommand",
+	20: "SetIntervalCommand",
+	21: "RenameMeasurementCommand",
+	22: "RenameTagCommand",
+	23: "UpdateShardTimeRangeCommand",
+	24: "TrashDatabaseCommand",
+	25: "RestoreDatabaseCommand",
+	26: "PurgeDroppedDatabaseCommand",
+	27: "CreateTagAliasCommand",
+	28: "DropTagAliasCommand",
 }
 var Command_Type_value = map[string]int32{
 	"CreateNodeCommand":                1,
@@ -156,6 +188,15 @@ var Command_Type_value = map[string]int32{
 	"SetDataCommand":                   17,
 	"SetAdminPrivilegeCommand":         18,
 	"UpdateNodeCommand":                19,
+	"SetIntervalCommand":               20,
+	"RenameMeasurementCommand":         21,
+	"RenameTagCommand":                 22,
+	"UpdateShardTimeRangeCommand":      23,
+	"TrashDatabaseCommand":             24,
+	"RestoreDatabaseCommand":           25,
+	"PurgeDroppedDatabaseCommand":      26,
+	"CreateTagAliasCommand":            27,
+	"DropTagAliasCommand":              28,
 }
 
 func (x Command_Type) Enum() *Command_Type {
@@ -176,16 +217,18 @@ func (x *Command_Type) UnmarshalJSON(data []byte) error {
 }
 
 type Data struct {
-	Term             *uint64         `protobuf:"varint,1,req" json:"Term,omitempty"`
-	Index            *uint64         `protobuf:"varint,2,req" json:"Index,omitempty"`
-	ClusterID        *uint64         `protobuf:"varint,3,req" json:"ClusterID,omitempty"`
-	Nodes            []*NodeInfo     `protobuf:"bytes,4,rep" json:"Nodes,omitempty"`
-	Databases        []*DatabaseInfo `protobuf:"bytes,5,rep" json:"Databases,omitempty"`
-	Users            []*UserInfo     `protobuf:"bytes,6,rep" json:"Users,omitempty"`
-	MaxNodeID        *uint64         `protobuf:"varint,7,req" json:"MaxNodeID,omitempty"`
-	MaxShardGroupID  *uint64         `protobuf:"varint,8,req" json:"MaxShardGroupID,omitempty"`
-	MaxShardID       *uint64         `protobuf:"varint,9,req" json:"MaxShardID,omitempty"`
-	XXX_unrecognized []byte          `json:"-"`
+	Term             *uint64                `protobuf:"varint,1,req" json:"Term,omitempty"`
+	Index            *uint64                `protobuf:"varint,2,req" json:"Index,omitempty"`
+	ClusterID        *uint64                `protobuf:"varint,3,req" json:"ClusterID,omitempty"`
+	Nodes            []*NodeInfo            `protobuf:"bytes,4,rep" json:"Nodes,omitempty"`
+	Databases        []*DatabaseInfo        `protobuf:"bytes,5,rep" json:"Databases,omitempty"`
+	Users            []*UserInfo            `protobuf:"bytes,6,rep" json:"Users,omitempty"`
+	MaxNodeID        *uint64                `protobuf:"varint,7,req" json:"MaxNodeID,omitempty"`
+	MaxShardGroupID  *uint64                `protobuf:"varint,8,req" json:"MaxShardGroupID,omitempty"`
+	MaxShardID       *uint64                `protobuf:"varint,9,req" json:"MaxShardID,omitempty"`
+	Intervals        []*IntervalInfo        `protobuf:"bytes,10,rep" json:"Intervals,omitempty"`
+	DroppedDatabases []*DroppedDatabaseInfo `protobuf:"bytes,11,rep" json:"DroppedDatabases,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
 }
 
 func (m *Data) Reset()         { *m = Data{} }
@@ -255,6 +298,20 @@ func (m *Data) GetMaxShardID() uint64 {
 	return 0
 }
 
+func (m *Data) GetIntervals() []*IntervalInfo {
+	if m != nil {
+		return m.Intervals
+	}
+	return nil
+}
+
+func (m *Data) GetDroppedDatabases() []*DroppedDatabaseInfo {
+	if m != nil {
+		return m.DroppedDatabases
+	}
+	return nil
+}
+
 type NodeInfo struct {
 	ID               *uint64 `protobuf:"varint,1,req" json:"ID,omitempty"`
 	Host             *string `protobuf:"bytes,2,req" json:"Host,omitempty"`
@@ -284,6 +341,9 @@ type DatabaseInfo struct {
 	DefaultRetentionPolicy *string                `protobuf:"bytes,2,req" json:"DefaultRetentionPolicy,omitempty"`
 	RetentionPolicies      []*RetentionPolicyInfo `protobuf:"bytes,3,rep" json:"RetentionPolicies,omitempty"`
 	ContinuousQueries      []*ContinuousQueryInfo `protobuf:"bytes,4,rep" json:"ContinuousQueries,omitempty"`
+	MeasurementRenames     []*MeasurementRename   `protobuf:"bytes,5,rep" json:"MeasurementRenames,omitempty"`
+	TagRenames             []*TagRenameInfo       `protobuf:"bytes,6,rep" json:"TagRenames,omitempty"`
+	TagAliases             []*TagAliasInfo        `protobuf:"bytes,7,rep" json:"TagAliases,omitempty"`
 	XXX_unrecognized       []byte                 `json:"-"`
 }
 
@@ -319,6 +379,123 @@ func (m *DatabaseInfo) GetContinuousQueries() []*ContinuousQueryInfo {
 	return nil
 }
 
+func (m *DatabaseInfo) GetMeasurementRenames() []*MeasurementRename {
+	if m != nil {
+		return m.MeasurementRenames
+	}
+	return nil
+}
+
+func (m *DatabaseInfo) GetTagRenames() []*TagRenameInfo {
+	if m != nil {
+		return m.TagRenames
+	}
+	return nil
+}
+
+func (m *DatabaseInfo) GetTagAliases() []*TagAliasInfo {
+	if m != nil {
+		return m.TagAliases
+	}
+	return nil
+}
+
+type MeasurementRename struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	NewName          *string `protobuf:"bytes,2,req" json:"NewName,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *MeasurementRename) Reset()         { *m = MeasurementRename{} }
+func (m *MeasurementRename) String() string { return proto.CompactTextString(m) }
+func (*MeasurementRename) ProtoMessage()    {}
+
+func (m *MeasurementRename) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *MeasurementRename) GetNewName() string {
+	if m != nil && m.NewName != nil {
+		return *m.NewName
+	}
+	return ""
+}
+
+type TagRenameInfo struct {
+	Measurement      *string `protobuf:"bytes,1,req" json:"Measurement,omitempty"`
+	Key              *string `protobuf:"bytes,2,req" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,3,opt" json:"Value,omitempty"`
+	NewName          *string `protobuf:"bytes,4,req" json:"NewName,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TagRenameInfo) Reset()         { *m = TagRenameInfo{} }
+func (m *TagRenameInfo) String() string { return proto.CompactTextString(m) }
+func (*TagRenameInfo) ProtoMessage()    {}
+
+func (m *TagRenameInfo) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
+func (m *TagRenameInfo) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *TagRenameInfo) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func (m *TagRenameInfo) GetNewName() string {
+	if m != nil && m.NewName != nil {
+		return *m.NewName
+	}
+	return ""
+}
+
+type TagAliasInfo struct {
+	Key              *string `protobuf:"bytes,1,req" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,2,opt" json:"Value,omitempty"`
+	Alias            *string `protobuf:"bytes,3,req" json:"Alias,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TagAliasInfo) Reset()         { *m = TagAliasInfo{} }
+func (m *TagAliasInfo) String() string { return proto.CompactTextString(m) }
+func (*TagAliasInfo) ProtoMessage()    {}
+
+func (m *TagAliasInfo) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *TagAliasInfo) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func (m *TagAliasInfo) GetAlias() string {
+	if m != nil && m.Alias != nil {
+		return *m.Alias
+	}
+	return ""
+}
+
 type RetentionPolicyInfo struct {
 	Name               *string           `protobuf:"bytes,1,req" json:"Name,omitempty"`
 	Duration           *int64            `protobuf:"varint,2,req" json:"Duration,omitempty"`
@@ -418,6 +595,9 @@ func (m *ShardGroupInfo) GetShards() []*ShardInfo {
 type ShardInfo struct {
 	ID               *uint64  `protobuf:"varint,1,req" json:"ID,omitempty"`
 	OwnerIDs         []uint64 `protobuf:"varint,2,rep" json:"OwnerIDs,omitempty"`
+	HasTimeRange     *bool    `protobuf:"varint,3,opt" json:"HasTimeRange,omitempty"`
+	MinTime          *int64   `protobuf:"varint,4,opt" json:"MinTime,omitempty"`
+	MaxTime          *int64   `protobuf:"varint,5,opt" json:"MaxTime,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -439,6 +619,27 @@ func (m *ShardInfo) GetOwnerIDs() []uint64 {
 	return nil
 }
 
+func (m *ShardInfo) GetHasTimeRange() bool {
+	if m != nil && m.HasTimeRange != nil {
+		return *m.HasTimeRange
+	}
+	return false
+}
+
+func (m *ShardInfo) GetMinTime() int64 {
+	if m != nil && m.MinTime != nil {
+		return *m.MinTime
+	}
+	return 0
+}
+
+func (m *ShardInfo) GetMaxTime() int64 {
+	if m != nil && m.MaxTime != nil {
+		return *m.MaxTime
+	}
+	return 0
+}
+
 type ContinuousQueryInfo struct {
 	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
 	Query            *string `protobuf:"bytes,2,req" json:"Query,omitempty"`
@@ -527,6 +728,54 @@ func (m *UserPrivilege) GetPrivilege() int32 {
 	return 0
 }
 
+type IntervalInfo struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Duration         *int64  `protobuf:"varint,2,req" json:"Duration,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *IntervalInfo) Reset()         { *m = IntervalInfo{} }
+func (m *IntervalInfo) String() string { return proto.CompactTextString(m) }
+func (*IntervalInfo) ProtoMessage()    {}
+
+func (m *IntervalInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *IntervalInfo) GetDuration() int64 {
+	if m != nil && m.Duration != nil {
+		return *m.Duration
+	}
+	return 0
+}
+
+type DroppedDatabaseInfo struct {
+	Database         *DatabaseInfo `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	DroppedAt        *int64        `protobuf:"varint,2,req" json:"DroppedAt,omitempty"`
+	XXX_unrecognized []byte        `json:"-"`
+}
+
+func (m *DroppedDatabaseInfo) Reset()         { *m = DroppedDatabaseInfo{} }
+func (m *DroppedDatabaseInfo) String() string { return proto.CompactTextString(m) }
+func (*DroppedDatabaseInfo) ProtoMessage()    {}
+
+func (m *DroppedDatabaseInfo) GetDatabase() *DatabaseInfo {
+	if m != nil {
+		return m.Database
+	}
+	return nil
+}
+
+func (m *DroppedDatabaseInfo) GetDroppedAt() int64 {
+	if m != nil && m.DroppedAt != nil {
+		return *m.DroppedAt
+	}
+	return 0
+}
+
 type Command struct {
 	Type             *Command_Type             `protobuf:"varint,1,req,name=type,enum=internal.Command_Type" json:"type,omitempty"`
 	XXX_extensions   map[int32]proto.Extension `json:"-"`
@@ -759,12 +1008,13 @@ var E_SetDefaultRetentionPolicyCommand_Command = &proto.ExtensionDesc{
 }
 
 type UpdateRetentionPolicyCommand struct {
-	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
-	NewName          *string `protobuf:"bytes,3,opt" json:"NewName,omitempty"`
-	Duration         *int64  `protobuf:"varint,4,opt" json:"Duration,omitempty"`
-	ReplicaN         *uint32 `protobuf:"varint,5,opt" json:"ReplicaN,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Database           *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name               *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	NewName            *string `protobuf:"bytes,3,opt" json:"NewName,omitempty"`
+	Duration           *int64  `protobuf:"varint,4,opt" json:"Duration,omitempty"`
+	ReplicaN           *uint32 `protobuf:"varint,5,opt" json:"ReplicaN,omitempty"`
+	ShardGroupDuration *int64  `protobuf:"varint,6,opt" json:"ShardGroupDuration,omitempty"`
+	XXX_unrecognized   []byte  `json:"-"`
 }
 
 func (m *UpdateRetentionPolicyCommand) Reset()         { *m = UpdateRetentionPolicyCommand{} }
@@ -806,6 +1056,13 @@ func (m *UpdateRetentionPolicyCommand) GetReplicaN() uint32 {
 	return 0
 }
 
+func (m *UpdateRetentionPolicyCommand) GetShardGroupDuration() int64 {
+	if m != nil && m.ShardGroupDuration != nil {
+		return *m.ShardGroupDuration
+	}
+	return 0
+}
+
 var E_UpdateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*UpdateRetentionPolicyCommand)(nil),
@@ -1190,6 +1447,366 @@ var E_UpdateNodeCommand_Command = &proto.ExtensionDesc{
 	Tag:           "bytes,119,opt,name=command",
 }
 
+type SetIntervalCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Duration         *int64  `protobuf:"varint,2,req" json:"Duration,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetIntervalCommand) Reset()         { *m = SetIntervalCommand{} }
+func (m *SetIntervalCommand) String() string { return proto.CompactTextString(m) }
+func (*SetIntervalCommand) ProtoMessage()    {}
+
+func (m *SetIntervalCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *SetIntervalCommand) GetDuration() int64 {
+	if m != nil && m.Duration != nil {
+		return *m.Duration
+	}
+	return 0
+}
+
+var E_SetIntervalCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetIntervalCommand)(nil),
+	Field:         120,
+	Name:          "internal.SetIntervalCommand.command",
+	Tag:           "bytes,120,opt,name=command",
+}
+
+type RenameMeasurementCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	NewName          *string `protobuf:"bytes,3,req" json:"NewName,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RenameMeasurementCommand) Reset()         { *m = RenameMeasurementCommand{} }
+func (m *RenameMeasurementCommand) String() string { return proto.CompactTextString(m) }
+func (*RenameMeasurementCommand) ProtoMessage()    {}
+
+func (m *RenameMeasurementCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *RenameMeasurementCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *RenameMeasurementCommand) GetNewName() string {
+	if m != nil && m.NewName != nil {
+		return *m.NewName
+	}
+	return ""
+}
+
+var E_RenameMeasurementCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RenameMeasurementCommand)(nil),
+	Field:         121,
+	Name:          "internal.RenameMeasurementCommand.command",
+	Tag:           "bytes,121,opt,name=command",
+}
+
+type RenameTagCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Measurement      *string `protobuf:"bytes,2,req" json:"Measurement,omitempty"`
+	Key              *string `protobuf:"bytes,3,req" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,4,opt" json:"Value,omitempty"`
+	NewName          *string `protobuf:"bytes,5,req" json:"NewName,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RenameTagCommand) Reset()         { *m = RenameTagCommand{} }
+func (m *RenameTagCommand) String() string { return proto.CompactTextString(m) }
+func (*RenameTagCommand) ProtoMessage()    {}
+
+func (m *RenameTagCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *RenameTagCommand) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
+func (m *RenameTagCommand) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *RenameTagCommand) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func (m *RenameTagCommand) GetNewName() string {
+	if m != nil && m.NewName != nil {
+		return *m.NewName
+	}
+	return ""
+}
+
+var E_RenameTagCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RenameTagCommand)(nil),
+	Field:         122,
+	Name:          "internal.RenameTagCommand.command",
+	Tag:           "bytes,122,opt,name=command",
+}
+
+type UpdateShardTimeRangeCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Policy           *string `protobuf:"bytes,2,req" json:"Policy,omitempty"`
+	ShardID          *uint64 `protobuf:"varint,3,req" json:"ShardID,omitempty"`
+	MinTime          *int64  `protobuf:"varint,4,req" json:"MinTime,omitempty"`
+	MaxTime          *int64  `protobuf:"varint,5,req" json:"MaxTime,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UpdateShardTimeRangeCommand) Reset()         { *m = UpdateShardTimeRangeCommand{} }
+func (m *UpdateShardTimeRangeCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateShardTimeRangeCommand) ProtoMessage()    {}
+
+func (m *UpdateShardTimeRangeCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *UpdateShardTimeRangeCommand) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *UpdateShardTimeRangeCommand) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *UpdateShardTimeRangeCommand) GetMinTime() int64 {
+	if m != nil && m.MinTime != nil {
+		return *m.MinTime
+	}
+	return 0
+}
+
+func (m *UpdateShardTimeRangeCommand) GetMaxTime() int64 {
+	if m != nil && m.MaxTime != nil {
+		return *m.MaxTime
+	}
+	return 0
+}
+
+var E_UpdateShardTimeRangeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateShardTimeRangeCommand)(nil),
+	Field:         123,
+	Name:          "internal.UpdateShardTimeRangeCommand.command",
+	Tag:           "bytes,123,opt,name=command",
+}
+
+type TrashDatabaseCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	DroppedAt        *int64  `protobuf:"varint,2,req" json:"DroppedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TrashDatabaseCommand) Reset()         { *m = TrashDatabaseCommand{} }
+func (m *TrashDatabaseCommand) String() string { return proto.CompactTextString(m) }
+func (*TrashDatabaseCommand) ProtoMessage()    {}
+
+func (m *TrashDatabaseCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *TrashDatabaseCommand) GetDroppedAt() int64 {
+	if m != nil && m.DroppedAt != nil {
+		return *m.DroppedAt
+	}
+	return 0
+}
+
+var E_TrashDatabaseCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*TrashDatabaseCommand)(nil),
+	Field:         124,
+	Name:          "internal.TrashDatabaseCommand.command",
+	Tag:           "bytes,124,opt,name=command",
+}
+
+type RestoreDatabaseCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RestoreDatabaseCommand) Reset()         { *m = RestoreDatabaseCommand{} }
+func (m *RestoreDatabaseCommand) String() string { return proto.CompactTextString(m) }
+func (*RestoreDatabaseCommand) ProtoMessage()    {}
+
+func (m *RestoreDatabaseCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_RestoreDatabaseCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RestoreDatabaseCommand)(nil),
+	Field:         125,
+	Name:          "internal.RestoreDatabaseCommand.command",
+	Tag:           "bytes,125,opt,name=command",
+}
+
+type PurgeDroppedDatabaseCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *PurgeDroppedDatabaseCommand) Reset()         { *m = PurgeDroppedDatabaseCommand{} }
+func (m *PurgeDroppedDatabaseCommand) String() string { return proto.CompactTextString(m) }
+func (*PurgeDroppedDatabaseCommand) ProtoMessage()    {}
+
+func (m *PurgeDroppedDatabaseCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_PurgeDroppedDatabaseCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*PurgeDroppedDatabaseCommand)(nil),
+	Field:         126,
+	Name:          "internal.PurgeDroppedDatabaseCommand.command",
+	Tag:           "bytes,126,opt,name=command",
+}
+
+type CreateTagAliasCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Key              *string `protobuf:"bytes,2,req" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,3,opt" json:"Value,omitempty"`
+	Alias            *string `protobuf:"bytes,4,req" json:"Alias,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CreateTagAliasCommand) Reset()         { *m = CreateTagAliasCommand{} }
+func (m *CreateTagAliasCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateTagAliasCommand) ProtoMessage()    {}
+
+func (m *CreateTagAliasCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateTagAliasCommand) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *CreateTagAliasCommand) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func (m *CreateTagAliasCommand) GetAlias() string {
+	if m != nil && m.Alias != nil {
+		return *m.Alias
+	}
+	return ""
+}
+
+var E_CreateTagAliasCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateTagAliasCommand)(nil),
+	Field:         127,
+	Name:          "internal.CreateTagAliasCommand.command",
+	Tag:           "bytes,127,opt,name=command",
+}
+
+type DropTagAliasCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Key              *string `protobuf:"bytes,2,req" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,3,opt" json:"Value,omitempty"`
+	Alias            *string `protobuf:"bytes,4,req" json:"Alias,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropTagAliasCommand) Reset()         { *m = DropTagAliasCommand{} }
+func (m *DropTagAliasCommand) String() string { return proto.CompactTextString(m) }
+func (*DropTagAliasCommand) ProtoMessage()    {}
+
+func (m *DropTagAliasCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropTagAliasCommand) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *DropTagAliasCommand) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+func (m *DropTagAliasCommand) GetAlias() string {
+	if m != nil && m.Alias != nil {
+		return *m.Alias
+	}
+	return ""
+}
+
+var E_DropTagAliasCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropTagAliasCommand)(nil),
+	Field:         128,
+	Name:          "internal.DropTagAliasCommand.command",
+	Tag:           "bytes,128,opt,name=command",
+}
+
 type Response struct {
 	OK               *bool   `protobuf:"varint,1,req" json:"OK,omitempty"`
 	Error            *string `protobuf:"bytes,2,opt" json:"Error,omitempty"`
@@ -1418,4 +2035,13 @@ func init() {
 	proto.RegisterExtension(E_SetDataCommand_Command)
 	proto.RegisterExtension(E_SetAdminPrivilegeCommand_Command)
 	proto.RegisterExtension(E_UpdateNodeCommand_Command)
+	proto.RegisterExtension(E_SetIntervalCommand_Command)
+	proto.RegisterExtension(E_RenameMeasurementCommand_Command)
+	proto.RegisterExtension(E_RenameTagCommand_Command)
+	proto.RegisterExtension(E_UpdateShardTimeRangeCommand_Command)
+	proto.RegisterExtension(E_TrashDatabaseCommand_Command)
+	proto.RegisterExtension(E_RestoreDatabaseCommand_Command)
+	proto.RegisterExtension(E_PurgeDroppedDatabaseCommand_Command)
+	proto.RegisterExtension(E_CreateTagAliasCommand_Command)
+	proto.RegisterExtension(E_DropTagAliasCommand_Command)
 }
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/influxdb/influxdb/influxql"
 )
@@ -16,12 +17,16 @@ type StatementExecutor struct {
 		Databases() ([]DatabaseInfo, error)
 		CreateDatabase(name string) (*DatabaseInfo, error)
 		DropDatabase(name string) error
+		TrashDatabase(name string) error
+		RestoreDatabase(name string) error
+		DroppedDatabases() ([]DroppedDatabaseInfo, error)
 
 		DefaultRetentionPolicy(database string) (*RetentionPolicyInfo, error)
 		CreateRetentionPolicy(database string, rpi *RetentionPolicyInfo) (*RetentionPolicyInfo, error)
 		UpdateRetentionPolicy(database, name string, rpu *RetentionPolicyUpdate) error
 		SetDefaultRetentionPolicy(database, name string) error
 		DropRetentionPolicy(database, name string) error
+		DeleteShardGroup(database, policy string, id uint64) error
 
 		Users() ([]UserInfo, error)
 		CreateUser(name, password string, admin bool) (*UserInfo, error)
@@ -34,7 +39,22 @@ type StatementExecutor struct {
 
 		CreateContinuousQuery(database, name, query string) error
 		DropContinuousQuery(database, name string) error
+
+		SetInterval(name string, d time.Duration) error
+		RenameMeasurement(database, name, newName string) error
+		RenameTagKey(database, measurement, key, newKey string) error
+		RenameTagValue(database, measurement, key, value, newValue string) error
+		CreateTagAlias(database, key, value, alias string) error
+		DropTagAlias(database, key, value, alias string) error
 	}
+
+	// If set, dropped databases are kept in the trash so they can be restored.
+	TrashDropped bool
+
+	// If set, altering a policy's duration deletes the shard groups that have
+	// expired under the new duration rather than waiting for the next
+	// retention check.
+	ExpireAltered bool
 }
 
 // ExecuteStatement executes stmt against the meta store as user.
@@ -46,6 +66,10 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.
 		return e.executeDropDatabaseStatement(stmt)
 	case *influxql.ShowDatabasesStatement:
 		return e.executeShowDatabasesStatement(stmt)
+	case *influxql.RestoreDatabaseStatement:
+		return e.executeRestoreDatabaseStatement(stmt)
+	case *influxql.ShowDroppedDatabasesStatement:
+		return e.executeShowDroppedDatabasesStatement(stmt)
 	case *influxql.ShowGrantsForUserStatement:
 		return e.executeShowGrantsForUserStatement(stmt)
 	case *influxql.ShowServersStatement:
@@ -82,6 +106,18 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.
 		return e.executeShowContinuousQueriesStatement(stmt)
 	case *influxql.ShowStatsStatement:
 		return e.executeShowStatsStatement(stmt)
+	case *influxql.SetIntervalStatement:
+		return e.executeSetIntervalStatement(stmt)
+	case *influxql.RenameMeasurementStatement:
+		return e.executeRenameMeasurementStatement(stmt)
+	case *influxql.RenameTagKeyStatement:
+		return e.executeRenameTagKeyStatement(stmt)
+	case *influxql.RenameTagValueStatement:
+		return e.executeRenameTagValueStatement(stmt)
+	case *influxql.CreateTagAliasStatement:
+		return e.executeCreateTagAliasStatement(stmt)
+	case *influxql.DropTagAliasStatement:
+		return e.executeDropTagAliasStatement(stmt)
 	default:
 		panic(fmt.Sprintf("unsupported statement type: %T", stmt))
 	}
@@ -93,9 +129,16 @@ func (e *StatementExecutor) executeCreateDatabaseStatement(q *influxql.CreateDat
 }
 
 func (e *StatementExecutor) executeDropDatabaseStatement(q *influxql.DropDatabaseStatement) *influxql.Result {
+	if e.TrashDropped {
+		return &influxql.Result{Err: e.Store.TrashDatabase(q.Name)}
+	}
 	return &influxql.Result{Err: e.Store.DropDatabase(q.Name)}
 }
 
+func (e *StatementExecutor) executeRestoreDatabaseStatement(q *influxql.RestoreDatabaseStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.RestoreDatabase(q.Name)}
+}
+
 func (e *StatementExecutor) executeShowDatabasesStatement(q *influxql.ShowDatabasesStatement) *influxql.Result {
 	dis, err := e.Store.Databases()
 	if err != nil {
@@ -109,6 +152,19 @@ func (e *StatementExecutor) executeShowDatabasesStatement(q *influxql.ShowDataba
 	return &influxql.Result{Series: []*influxql.Row{row}}
 }
 
+func (e *StatementExecutor) executeShowDroppedDatabasesStatement(q *influxql.ShowDroppedDatabasesStatement) *influxql.Result {
+	ddis, err := e.Store.DroppedDatabases()
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	row := &influxql.Row{Name: "databases", Columns: []string{"name", "dropped_at"}}
+	for _, ddi := range ddis {
+		row.Values = append(row.Values, []interface{}{ddi.Database.Name, ddi.DroppedAt.Format(time.RFC3339)})
+	}
+	return &influxql.Result{Series: []*influxql.Row{row}}
+}
+
 func (e *StatementExecutor) executeShowGrantsForUserStatement(q *influxql.ShowGrantsForUserStatement) *influxql.Result {
 	priv, err := e.Store.UserPrivileges(q.Name)
 	if err != nil {
@@ -215,8 +271,9 @@ func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql
 
 func (e *StatementExecutor) executeAlterRetentionPolicyStatement(stmt *influxql.AlterRetentionPolicyStatement) *influxql.Result {
 	rpu := &RetentionPolicyUpdate{
-		Duration: stmt.Duration,
-		ReplicaN: stmt.Replication,
+		Duration:           stmt.Duration,
+		ReplicaN:           stmt.Replication,
+		ShardGroupDuration: stmt.ShardGroupDuration,
 	}
 
 	// Update the retention policy.
@@ -225,6 +282,13 @@ func (e *StatementExecutor) executeAlterRetentionPolicyStatement(stmt *influxql.
 		return &influxql.Result{Err: err}
 	}
 
+	// Re-evaluate the expiry of existing shard groups.
+	if e.ExpireAltered && stmt.Duration != nil {
+		if err := e.expireShardGroups(stmt.Database, stmt.Name); err != nil {
+			return &influxql.Result{Err: err}
+		}
+	}
+
 	// If requested, set as default retention policy.
 	if stmt.Default {
 		err = e.Store.SetDefaultRetentionPolicy(stmt.Database, stmt.Name)
@@ -233,6 +297,27 @@ func (e *StatementExecutor) executeAlterRetentionPolicyStatement(stmt *influxql.
 	return &influxql.Result{Err: err}
 }
 
+// expireShardGroups deletes the shard groups of a policy that have expired.
+func (e *StatementExecutor) expireShardGroups(database, policy string) error {
+	di, err := e.Store.Database(database)
+	if err != nil {
+		return err
+	} else if di == nil {
+		return ErrDatabaseNotFound
+	}
+	rpi := di.RetentionPolicy(policy)
+	if rpi == nil {
+		return ErrRetentionPolicyNotFound
+	}
+
+	for _, g := range rpi.ExpiredShardGroups(time.Now().UTC()) {
+		if err := e.Store.DeleteShardGroup(database, policy, g.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *StatementExecutor) executeDropRetentionPolicyStatement(q *influxql.DropRetentionPolicyStatement) *influxql.Result {
 	return &influxql.Result{Err: e.Store.DropRetentionPolicy(q.Database, q.Name)}
 }
@@ -264,6 +349,30 @@ func (e *StatementExecutor) executeDropContinuousQueryStatement(q *influxql.Drop
 	}
 }
 
+func (e *StatementExecutor) executeSetIntervalStatement(q *influxql.SetIntervalStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.SetInterval(q.Name, q.Interval)}
+}
+
+func (e *StatementExecutor) executeRenameMeasurementStatement(q *influxql.RenameMeasurementStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.RenameMeasurement(q.Database, q.Name, q.NewName)}
+}
+
+func (e *StatementExecutor) executeRenameTagKeyStatement(q *influxql.RenameTagKeyStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.RenameTagKey(q.Database, q.Measurement, q.Key, q.NewKey)}
+}
+
+func (e *StatementExecutor) executeRenameTagValueStatement(q *influxql.RenameTagValueStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.RenameTagValue(q.Database, q.Measurement, q.Key, q.Value, q.NewValue)}
+}
+
+func (e *StatementExecutor) executeCreateTagAliasStatement(q *influxql.CreateTagAliasStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.CreateTagAlias(q.Database, q.Key, q.Value, q.Alias)}
+}
+
+func (e *StatementExecutor) executeDropTagAliasStatement(q *influxql.DropTagAliasStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.DropTagAlias(q.Database, q.Key, q.Value, q.Alias)}
+}
+
 func (e *StatementExecutor) executeShowContinuousQueriesStatement(stmt *influxql.ShowContinuousQueriesStatement) *influxql.Result {
 	dis, err := e.Store.Databases()
 	if err != nil {
@@ -45,6 +45,65 @@ func TestStatementExecutor_ExecuteStatement_DropDatabase(t *testing.T) {
 	}
 }
 
+// Ensure a DROP DATABASE statement moves the database to the trash when enabled.
+func TestStatementExecutor_ExecuteStatement_DropDatabase_Trash(t *testing.T) {
+	e := NewStatementExecutor()
+	e.TrashDropped = true
+	e.Store.TrashDatabaseFn = func(name string) error {
+		if name != "foo" {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		return nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`DROP DATABASE foo`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a RESTORE DATABASE statement can be executed.
+func TestStatementExecutor_ExecuteStatement_RestoreDatabase(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.RestoreDatabaseFn = func(name string) error {
+		if name != "foo" {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		return nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`RESTORE DATABASE foo`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a SHOW DROPPED DATABASES statement can be executed.
+func TestStatementExecutor_ExecuteStatement_ShowDroppedDatabases(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DroppedDatabasesFn = func() ([]meta.DroppedDatabaseInfo, error) {
+		return []meta.DroppedDatabaseInfo{
+			{Database: meta.DatabaseInfo{Name: "foo"}, DroppedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}, nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`SHOW DROPPED DATABASES`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
+		{
+			Name:    "databases",
+			Columns: []string{"name", "dropped_at"},
+			Values: [][]interface{}{
+				{"foo", "2000-01-01T00:00:00Z"},
+			},
+		},
+	}) {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(res.Series))
+	}
+}
+
 // Ensure a SHOW DATABASES statement can be executed.
 func TestStatementExecutor_ExecuteStatement_ShowDatabases(t *testing.T) {
 	e := NewStatementExecutor()
@@ -465,6 +524,8 @@ func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy(t *testing.T) {
 			t.Fatalf("unexpected duration: %v", *rpu.Duration)
 		} else if rpu.ReplicaN != nil && *rpu.ReplicaN != 2 {
 			t.Fatalf("unexpected replication factor: %v", *rpu.ReplicaN)
+		} else if rpu.ShardGroupDuration != nil && *rpu.ShardGroupDuration != 24*time.Hour {
+			t.Fatalf("unexpected shard group duration: %v", *rpu.ShardGroupDuration)
 		}
 		return nil
 	}
@@ -477,7 +538,7 @@ func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy(t *testing.T) {
 		return nil
 	}
 
-	stmt := influxql.MustParseStatement(`ALTER RETENTION POLICY rp0 ON foo DURATION 7d REPLICATION 2 DEFAULT`)
+	stmt := influxql.MustParseStatement(`ALTER RETENTION POLICY rp0 ON foo DURATION 7d REPLICATION 2 SHARD DURATION 1d DEFAULT`)
 	if res := e.ExecuteStatement(stmt); res.Err != nil {
 		t.Fatalf("unexpected error: %s", res.Err)
 	}
@@ -506,6 +567,53 @@ func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy_Err(t *testing.
 	}
 }
 
+// Ensure a ALTER RETENTION POLICY statement deletes the shard groups that
+// expire under the new duration.
+func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy_Expire(t *testing.T) {
+	e := NewStatementExecutor()
+	e.ExpireAltered = true
+	e.Store.UpdateRetentionPolicyFn = func(database, name string, rpu *meta.RetentionPolicyUpdate) error {
+		return nil
+	}
+	now := time.Now().UTC()
+	e.Store.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{
+			Name: name,
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:     "rp0",
+				Duration: 24 * time.Hour,
+				ShardGroups: []meta.ShardGroupInfo{
+					{ID: 1, StartTime: now.Add(-4 * 24 * time.Hour), EndTime: now.Add(-3 * 24 * time.Hour)},
+					{ID: 2, StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour)},
+				},
+			}},
+		}, nil
+	}
+	var deleted []uint64
+	e.Store.DeleteShardGroupFn = func(database, policy string, id uint64) error {
+		if database != "foo" || policy != "rp0" {
+			t.Fatalf("unexpected policy: %s.%s", database, policy)
+		}
+		deleted = append(deleted, id)
+		return nil
+	}
+
+	// Only changing the duration re-evaluates the expiry.
+	stmt := influxql.MustParseStatement(`ALTER RETENTION POLICY rp0 ON foo REPLICATION 2`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatalf("unexpected error: %s", res.Err)
+	} else if len(deleted) != 0 {
+		t.Fatalf("unexpected deleted shard groups: %v", deleted)
+	}
+
+	stmt = influxql.MustParseStatement(`ALTER RETENTION POLICY rp0 ON foo DURATION 1d`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatalf("unexpected error: %s", res.Err)
+	} else if !reflect.DeepEqual(deleted, []uint64{1}) {
+		t.Fatalf("unexpected deleted shard groups: %v", deleted)
+	}
+}
+
 // Ensure a ALTER RETENTION POLICY statement returns errors from the store.
 func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy_ErrSetDefault(t *testing.T) {
 	e := NewStatementExecutor()
@@ -743,6 +851,100 @@ func TestStatementExecutor_ExecuteStatement_ShowContinuousQueries_Err(t *testing
 	}
 }
 
+// Ensure a SET RETENTION CHECK INTERVAL statement can be executed.
+func TestStatementExecutor_ExecuteStatement_SetInterval(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.SetIntervalFn = func(name string, d time.Duration) error {
+		if name != influxql.RetentionCheckInterval {
+			t.Fatalf("unexpected name: %s", name)
+		} else if d != 5*time.Minute {
+			t.Fatalf("unexpected interval: %s", d)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`SET RETENTION CHECK INTERVAL 5m`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a RENAME MEASUREMENT statement can be executed.
+func TestStatementExecutor_ExecuteStatement_RenameMeasurement(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.RenameMeasurementFn = func(database, name, newName string) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if name != "cpu_typo" {
+			t.Fatalf("unexpected name: %s", name)
+		} else if newName != "cpu" {
+			t.Fatalf("unexpected new name: %s", newName)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`RENAME MEASUREMENT cpu_typo TO cpu ON db0`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a RENAME TAG VALUE statement can be executed.
+func TestStatementExecutor_ExecuteStatement_RenameTagValue(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.RenameTagValueFn = func(database, measurement, key, value, newValue string) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if measurement != "cpu" {
+			t.Fatalf("unexpected measurement: %s", measurement)
+		} else if key != "region" {
+			t.Fatalf("unexpected key: %s", key)
+		} else if value != "us_west" || newValue != "us-west" {
+			t.Fatalf("unexpected values: %s, %s", value, newValue)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`RENAME TAG VALUE 'us_west' TO 'us-west' WITH KEY = region FROM cpu ON db0`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+}
+
+// Ensure CREATE and DROP TAG ALIAS statements can be executed.
+func TestStatementExecutor_ExecuteStatement_TagAlias(t *testing.T) {
+	e := NewStatementExecutor()
+	var created, dropped []string
+	e.Store.CreateTagAliasFn = func(database, key, value, alias string) error {
+		created = append(created, database+" "+key+" "+value+" "+alias)
+		return nil
+	}
+	e.Store.DropTagAliasFn = func(database, key, value, alias string) error {
+		dropped = append(dropped, database+" "+key+" "+value+" "+alias)
+		return nil
+	}
+
+	for _, s := range []string{
+		`CREATE TAG ALIAS dc FOR KEY datacenter ON db0`,
+		`CREATE TAG ALIAS 'usw' FOR VALUE 'us-west' WITH KEY = datacenter ON db0`,
+		`DROP TAG ALIAS 'usw' FOR VALUE 'us-west' WITH KEY = datacenter ON db0`,
+	} {
+		if res := e.ExecuteStatement(influxql.MustParseStatement(s)); res.Err != nil {
+			t.Fatal(res.Err)
+		}
+	}
+
+	if !reflect.DeepEqual(created, []string{"db0 datacenter  dc", "db0 datacenter us-west usw"}) {
+		t.Fatalf("unexpected created aliases: %q", created)
+	} else if !reflect.DeepEqual(dropped, []string{"db0 datacenter us-west usw"}) {
+		t.Fatalf("unexpected dropped aliases: %q", dropped)
+	}
+}
+
 // Ensure that executing an unsupported statement will panic.
 func TestStatementExecutor_ExecuteStatement_Unsupported(t *testing.T) {
 	var panicked bool
@@ -786,11 +988,15 @@ type StatementExecutorStore struct {
 	DatabasesFn                 func() ([]meta.DatabaseInfo, error)
 	CreateDatabaseFn            func(name string) (*meta.DatabaseInfo, error)
 	DropDatabaseFn              func(name string) error
+	TrashDatabaseFn             func(name string) error
+	RestoreDatabaseFn           func(name string) error
+	DroppedDatabasesFn          func() ([]meta.DroppedDatabaseInfo, error)
 	DefaultRetentionPolicyFn    func(database string) (*meta.RetentionPolicyInfo, error)
 	CreateRetentionPolicyFn     func(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error)
 	UpdateRetentionPolicyFn     func(database, name string, rpu *meta.RetentionPolicyUpdate) error
 	SetDefaultRetentionPolicyFn func(database, name string) error
 	DropRetentionPolicyFn       func(database, name string) error
+	DeleteShardGroupFn          func(database, policy string, id uint64) error
 	UsersFn                     func() ([]meta.UserInfo, error)
 	CreateUserFn                func(name, password string, admin bool) (*meta.UserInfo, error)
 	UpdateUserFn                func(name, password string) error
@@ -802,6 +1008,12 @@ type StatementExecutorStore struct {
 	ContinuousQueriesFn         func() ([]meta.ContinuousQueryInfo, error)
 	CreateContinuousQueryFn     func(database, name, query string) error
 	DropContinuousQueryFn       func(database, name string) error
+	SetIntervalFn               func(name string, d time.Duration) error
+	RenameMeasurementFn         func(database, name, newName string) error
+	RenameTagKeyFn              func(database, measurement, key, newKey string) error
+	RenameTagValueFn            func(database, measurement, key, value, newValue string) error
+	CreateTagAliasFn            func(database, key, value, alias string) error
+	DropTagAliasFn              func(database, key, value, alias string) error
 }
 
 func (s *StatementExecutorStore) Nodes() ([]meta.NodeInfo, error) {
@@ -828,6 +1040,18 @@ func (s *StatementExecutorStore) DropDatabase(name string) error {
 	return s.DropDatabaseFn(name)
 }
 
+func (s *StatementExecutorStore) TrashDatabase(name string) error {
+	return s.TrashDatabaseFn(name)
+}
+
+func (s *StatementExecutorStore) RestoreDatabase(name string) error {
+	return s.RestoreDatabaseFn(name)
+}
+
+func (s *StatementExecutorStore) DroppedDatabases() ([]meta.DroppedDatabaseInfo, error) {
+	return s.DroppedDatabasesFn()
+}
+
 func (s *StatementExecutorStore) DefaultRetentionPolicy(database string) (*meta.RetentionPolicyInfo, error) {
 	return s.DefaultRetentionPolicyFn(database)
 }
@@ -848,6 +1072,10 @@ func (s *StatementExecutorStore) DropRetentionPolicy(database, name string) erro
 	return s.DropRetentionPolicyFn(database, name)
 }
 
+func (s *StatementExecutorStore) DeleteShardGroup(database, policy string, id uint64) error {
+	return s.DeleteShardGroupFn(database, policy, id)
+}
+
 func (s *StatementExecutorStore) Users() ([]meta.UserInfo, error) {
 	return s.UsersFn()
 }
@@ -891,3 +1119,27 @@ func (s *StatementExecutorStore) CreateContinuousQuery(database, name, query str
 func (s *StatementExecutorStore) DropContinuousQuery(database, name string) error {
 	return s.DropContinuousQueryFn(database, name)
 }
+
+func (s *StatementExecutorStore) SetInterval(name string, d time.Duration) error {
+	return s.SetIntervalFn(name, d)
+}
+
+func (s *StatementExecutorStore) RenameMeasurement(database, name, newName string) error {
+	return s.RenameMeasurementFn(database, name, newName)
+}
+
+func (s *StatementExecutorStore) RenameTagKey(database, measurement, key, newKey string) error {
+	return s.RenameTagKeyFn(database, measurement, key, newKey)
+}
+
+func (s *StatementExecutorStore) RenameTagValue(database, measurement, key, value, newValue string) error {
+	return s.RenameTagValueFn(database, measurement, key, value, newValue)
+}
+
+func (s *StatementExecutorStore) CreateTagAlias(database, key, value, alias string) error {
+	return s.CreateTagAliasFn(database, key, value, alias)
+}
+
+func (s *StatementExecutorStore) DropTagAlias(database, key, value, alias string) error {
+	return s.DropTagAliasFn(database, key, value, alias)
+}
@@ -46,7 +46,10 @@ const ExecMagic = "EXEC"
 const (
 	AutoCreateRetentionPolicyName   = "default"
 	AutoCreateRetentionPolicyPeriod = 0
-	RetentionPolicyMinDuration      = time.Hour
+
+	// RetentionPolicyMinDuration is the minimum duration for a policy.
+	// It is kept for compatibility, use MinRetentionPolicyDuration.
+	RetentionPolicyMinDuration = MinRetentionPolicyDuration
 
 	// MaxAutoCreatedRetentionPolicyReplicaN is the maximum replication factor that will
 	// be set for auto-created retention policies.
@@ -917,6 +920,44 @@ func (s *Store) DropDatabase(name string) error {
 	)
 }
 
+// TrashDatabase removes a database from the metastore by name but keeps it
+// as a dropped database that can be restored.
+func (s *Store) TrashDatabase(name string) error {
+	return s.exec(internal.Command_TrashDatabaseCommand, internal.E_TrashDatabaseCommand_Command,
+		&internal.TrashDatabaseCommand{
+			Name:      proto.String(name),
+			DroppedAt: proto.Int64(time.Now().UnixNano()),
+		},
+	)
+}
+
+// RestoreDatabase moves a dropped database back into the metastore.
+func (s *Store) RestoreDatabase(name string) error {
+	return s.exec(internal.Command_RestoreDatabaseCommand, internal.E_RestoreDatabaseCommand_Command,
+		&internal.RestoreDatabaseCommand{
+			Name: proto.String(name),
+		},
+	)
+}
+
+// PurgeDroppedDatabase permanently removes a dropped database by name.
+func (s *Store) PurgeDroppedDatabase(name string) error {
+	return s.exec(internal.Command_PurgeDroppedDatabaseCommand, internal.E_PurgeDroppedDatabaseCommand_Command,
+		&internal.PurgeDroppedDatabaseCommand{
+			Name: proto.String(name),
+		},
+	)
+}
+
+// DroppedDatabases returns the databases that were dropped but not purged.
+func (s *Store) DroppedDatabases() (ddis []DroppedDatabaseInfo, err error) {
+	err = s.read(func(data *Data) error {
+		ddis = data.DroppedDatabases
+		return nil
+	})
+	return
+}
+
 // RetentionPolicy returns a retention policy for a database by name.
 func (s *Store) RetentionPolicy(database, name string) (rpi *RetentionPolicyInfo, err error) {
 	err = s.read(func(data *Data) error {
@@ -965,7 +1006,7 @@ func (s *Store) RetentionPolicies(database string) (a []RetentionPolicyInfo, err
 
 // CreateRetentionPolicy creates a new retention policy for a database.
 func (s *Store) CreateRetentionPolicy(database string, rpi *RetentionPolicyInfo) (*RetentionPolicyInfo, error) {
-	if rpi.Duration < RetentionPolicyMinDuration && rpi.Duration != 0 {
+	if rpi.Duration < MinRetentionPolicyDuration && rpi.Duration != 0 {
 		return nil, ErrRetentionPolicyDurationTooLow
 	}
 	if err := s.exec(internal.Command_CreateRetentionPolicyCommand, internal.E_CreateRetentionPolicyCommand_Command,
@@ -1026,13 +1067,20 @@ func (s *Store) UpdateRetentionPolicy(database, name string, rpu *RetentionPolic
 		replicaN = &value
 	}
 
+	var shardGroupDuration *int64
+	if rpu.ShardGroupDuration != nil {
+		value := int64(*rpu.ShardGroupDuration)
+		shardGroupDuration = &value
+	}
+
 	return s.exec(internal.Command_UpdateRetentionPolicyCommand, internal.E_UpdateRetentionPolicyCommand_Command,
 		&internal.UpdateRetentionPolicyCommand{
-			Database: proto.String(database),
-			Name:     proto.String(name),
-			NewName:  newName,
-			Duration: duration,
-			ReplicaN: replicaN,
+			Database:           proto.String(database),
+			Name:               proto.String(name),
+			NewName:            newName,
+			Duration:           duration,
+			ReplicaN:           replicaN,
+			ShardGroupDuration: shardGroupDuration,
 		},
 	)
 }
@@ -1362,6 +1410,105 @@ func (s *Store) SetAdminPrivilege(username string, admin bool) error {
 	)
 }
 
+// RenameMeasurement renames a measurement in a database on every node.
+func (s *Store) RenameMeasurement(database, name, newName string) error {
+	return s.exec(internal.Command_RenameMeasurementCommand, internal.E_RenameMeasurementCommand_Command,
+		&internal.RenameMeasurementCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+			NewName:  proto.String(newName),
+		},
+	)
+}
+
+// UpdateShardTimeRange extends the recorded time range of the points written
+// to a shard to include min and max.
+func (s *Store) UpdateShardTimeRange(database, policy string, shardID uint64, min, max time.Time) error {
+	return s.exec(internal.Command_UpdateShardTimeRangeCommand, internal.E_UpdateShardTimeRangeCommand_Command,
+		&internal.UpdateShardTimeRangeCommand{
+			Database: proto.String(database),
+			Policy:   proto.String(policy),
+			ShardID:  proto.Uint64(shardID),
+			MinTime:  proto.Int64(min.UnixNano()),
+			MaxTime:  proto.Int64(max.UnixNano()),
+		},
+	)
+}
+
+// RenameTagKey renames a tag key of a measurement on every node.
+func (s *Store) RenameTagKey(database, measurement, key, newKey string) error {
+	return s.exec(internal.Command_RenameTagCommand, internal.E_RenameTagCommand_Command,
+		&internal.RenameTagCommand{
+			Database:    proto.String(database),
+			Measurement: proto.String(measurement),
+			Key:         proto.String(key),
+			NewName:     proto.String(newKey),
+		},
+	)
+}
+
+// RenameTagValue rewrites a tag value of a measurement on every node.
+func (s *Store) RenameTagValue(database, measurement, key, value, newValue string) error {
+	return s.exec(internal.Command_RenameTagCommand, internal.E_RenameTagCommand_Command,
+		&internal.RenameTagCommand{
+			Database:    proto.String(database),
+			Measurement: proto.String(measurement),
+			Key:         proto.String(key),
+			Value:       proto.String(value),
+			NewName:     proto.String(newValue),
+		},
+	)
+}
+
+// CreateTagAlias adds an alias of a tag key, or of a value of the key if
+// value is set, to a database on every node.
+func (s *Store) CreateTagAlias(database, key, value, alias string) error {
+	cmd := &internal.CreateTagAliasCommand{
+		Database: proto.String(database),
+		Key:      proto.String(key),
+		Alias:    proto.String(alias),
+	}
+	if value != "" {
+		cmd.Value = proto.String(value)
+	}
+	return s.exec(internal.Command_CreateTagAliasCommand, internal.E_CreateTagAliasCommand_Command, cmd)
+}
+
+// DropTagAlias removes an alias of a tag key, or of a value of the key if
+// value is set, from a database on every node.
+func (s *Store) DropTagAlias(database, key, value, alias string) error {
+	cmd := &internal.DropTagAliasCommand{
+		Database: proto.String(database),
+		Key:      proto.String(key),
+		Alias:    proto.String(alias),
+	}
+	if value != "" {
+		cmd.Value = proto.String(value)
+	}
+	return s.exec(internal.Command_DropTagAliasCommand, internal.E_DropTagAliasCommand_Command, cmd)
+}
+
+// SetInterval sets how often a background service runs on every node. A zero
+// interval restores the configured interval.
+func (s *Store) SetInterval(name string, d time.Duration) error {
+	return s.exec(internal.Command_SetIntervalCommand, internal.E_SetIntervalCommand_Command,
+		&internal.SetIntervalCommand{
+			Name:     proto.String(name),
+			Duration: proto.Int64(int64(d)),
+		},
+	)
+}
+
+// Interval returns the interval of a background service set at runtime.
+// Returns zero if it wasn't set and the configured interval should be used.
+func (s *Store) Interval(name string) (d time.Duration, err error) {
+	err = s.read(func(data *Data) error {
+		d = data.Intervals[name]
+		return nil
+	})
+	return
+}
+
 // UserPrivileges returns a list of all databases.
 func (s *Store) UserPrivileges(username string) (p map[string]influxql.Privilege, err error) {
 	err = s.read(func(data *Data) error {
@@ -1651,6 +1798,24 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 			return fsm.applySetDataCommand(&cmd)
 		case internal.Command_UpdateNodeCommand:
 			return fsm.applyUpdateNodeCommand(&cmd)
+		case internal.Command_SetIntervalCommand:
+			return fsm.applySetIntervalCommand(&cmd)
+		case internal.Command_RenameMeasurementCommand:
+			return fsm.applyRenameMeasurementCommand(&cmd)
+		case internal.Command_RenameTagCommand:
+			return fsm.applyRenameTagCommand(&cmd)
+		case internal.Command_UpdateShardTimeRangeCommand:
+			return fsm.applyUpdateShardTimeRangeCommand(&cmd)
+		case internal.Command_TrashDatabaseCommand:
+			return fsm.applyTrashDatabaseCommand(&cmd)
+		case internal.Command_RestoreDatabaseCommand:
+			return fsm.applyRestoreDatabaseCommand(&cmd)
+		case internal.Command_PurgeDroppedDatabaseCommand:
+			return fsm.applyPurgeDroppedDatabaseCommand(&cmd)
+		case internal.Command_CreateTagAliasCommand:
+			return fsm.applyCreateTagAliasCommand(&cmd)
+		case internal.Command_DropTagAliasCommand:
+			return fsm.applyDropTagAliasCommand(&cmd)
 		default:
 			panic(fmt.Errorf("cannot apply command: %x", l.Data))
 		}
@@ -1743,6 +1908,48 @@ func (fsm *storeFSM) applyDropDatabaseCommand(cmd *internal.Command) interface{}
 	return nil
 }
 
+func (fsm *storeFSM) applyTrashDatabaseCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_TrashDatabaseCommand_Command)
+	v := ext.(*internal.TrashDatabaseCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.TrashDatabase(v.GetName(), time.Unix(0, v.GetDroppedAt())); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyRestoreDatabaseCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RestoreDatabaseCommand_Command)
+	v := ext.(*internal.RestoreDatabaseCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.RestoreDatabase(v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyPurgeDroppedDatabaseCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_PurgeDroppedDatabaseCommand_Command)
+	v := ext.(*internal.PurgeDroppedDatabaseCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.PurgeDroppedDatabase(v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
 func (fsm *storeFSM) applyCreateRetentionPolicyCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateRetentionPolicyCommand_Command)
 	v := ext.(*internal.CreateRetentionPolicyCommand)
@@ -1806,6 +2013,10 @@ func (fsm *storeFSM) applyUpdateRetentionPolicyCommand(cmd *internal.Command) in
 		value := int(v.GetReplicaN())
 		rpu.ReplicaN = &value
 	}
+	if v.ShardGroupDuration != nil {
+		value := time.Duration(v.GetShardGroupDuration())
+		rpu.ShardGroupDuration = &value
+	}
 
 	// Copy data and update.
 	other := fsm.data.Clone()
@@ -1941,6 +2152,91 @@ func (fsm *storeFSM) applySetAdminPrivilegeCommand(cmd *internal.Command) interf
 	return nil
 }
 
+func (fsm *storeFSM) applySetIntervalCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetIntervalCommand_Command)
+	v := ext.(*internal.SetIntervalCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetInterval(v.GetName(), time.Duration(v.GetDuration())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyRenameMeasurementCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RenameMeasurementCommand_Command)
+	v := ext.(*internal.RenameMeasurementCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.RenameMeasurement(v.GetDatabase(), v.GetName(), v.GetNewName()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyRenameTagCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RenameTagCommand_Command)
+	v := ext.(*internal.RenameTagCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	var err error
+	if v.Value == nil {
+		err = other.RenameTagKey(v.GetDatabase(), v.GetMeasurement(), v.GetKey(), v.GetNewName())
+	} else {
+		err = other.RenameTagValue(v.GetDatabase(), v.GetMeasurement(), v.GetKey(), v.GetValue(), v.GetNewName())
+	}
+	if err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateTagAliasCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateTagAliasCommand_Command)
+	v := ext.(*internal.CreateTagAliasCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateTagAlias(v.GetDatabase(), v.GetKey(), v.GetValue(), v.GetAlias()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyDropTagAliasCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropTagAliasCommand_Command)
+	v := ext.(*internal.DropTagAliasCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropTagAlias(v.GetDatabase(), v.GetKey(), v.GetValue(), v.GetAlias()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyUpdateShardTimeRangeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateShardTimeRangeCommand_Command)
+	v := ext.(*internal.UpdateShardTimeRangeCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateShardTimeRange(v.GetDatabase(), v.GetPolicy(), v.GetShardID(),
+		time.Unix(0, v.GetMinTime()), time.Unix(0, v.GetMaxTime())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
 func (fsm *storeFSM) applySetDataCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_SetDataCommand_Command)
 	v := ext.(*internal.SetDataCommand)
@@ -2062,14 +2358,16 @@ func (l *raftLayer) Close() error { return l.ln.Close() }
 
 // RetentionPolicyUpdate represents retention policy fields to be updated.
 type RetentionPolicyUpdate struct {
-	Name     *string
-	Duration *time.Duration
-	ReplicaN *int
+	Name               *string
+	Duration           *time.Duration
+	ReplicaN           *int
+	ShardGroupDuration *time.Duration
 }
 
-func (rpu *RetentionPolicyUpdate) SetName(v string)            { rpu.Name = &v }
-func (rpu *RetentionPolicyUpdate) SetDuration(v time.Duration) { rpu.Duration = &v }
-func (rpu *RetentionPolicyUpdate) SetReplicaN(v int)           { rpu.ReplicaN = &v }
+func (rpu *RetentionPolicyUpdate) SetName(v string)                      { rpu.Name = &v }
+func (rpu *RetentionPolicyUpdate) SetDuration(v time.Duration)           { rpu.Duration = &v }
+func (rpu *RetentionPolicyUpdate) SetReplicaN(v int)                     { rpu.ReplicaN = &v }
+func (rpu *RetentionPolicyUpdate) SetShardGroupDuration(v time.Duration) { rpu.ShardGroupDuration = &v }
 
 // assert will panic with a given formatted message if the given condition is false.
 func assert(condition bool, msg string, v ...interface{}) {
@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tcp"
 	"github.com/influxdb/influxdb/toml"
@@ -624,6 +625,41 @@ func TestStore_DropUser(t *testing.T) {
 }
 
 // Ensure the store can update a user.
+// Ensure the store can set an interval and restore the configured one.
+func TestStore_SetInterval(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	if err := s.SetInterval(influxql.RetentionCheckInterval, 5*time.Minute); err != nil {
+		t.Fatal(err)
+	} else if d, err := s.Interval(influxql.RetentionCheckInterval); err != nil {
+		t.Fatal(err)
+	} else if d != 5*time.Minute {
+		t.Fatalf("unexpected interval: %s", d)
+	}
+
+	// A zero interval restores the configured interval.
+	if err := s.SetInterval(influxql.RetentionCheckInterval, 0); err != nil {
+		t.Fatal(err)
+	} else if d, err := s.Interval(influxql.RetentionCheckInterval); err != nil {
+		t.Fatal(err)
+	} else if d != 0 {
+		t.Fatalf("unexpected interval: %s", d)
+	}
+}
+
+// Ensure the store returns an error when setting an unknown interval.
+func TestStore_SetInterval_ErrIntervalNotFound(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	if err := s.SetInterval("no_such_interval", time.Minute); err != meta.ErrIntervalNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 func TestStore_UpdateUser(t *testing.T) {
 	t.Parallel()
 	s := MustOpenStore()
@@ -0,0 +1,222 @@
+// Package retry retries operations with jittered exponential backoff.
+//
+// A Policy decides how long to wait between attempts and which errors are
+// worth retrying. A Budget, shared by the callers of a subsystem, limits
+// retries to a fraction of the requests so a failing node isn't sent a
+// multiple of its normal load.
+package retry
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInitialInterval is the default wait before the first retry.
+	DefaultInitialInterval = 100 * time.Millisecond
+
+	// DefaultMaxInterval is the default longest wait between attempts.
+	DefaultMaxInterval = 10 * time.Second
+
+	// DefaultMultiplier is the default growth of the wait after each attempt.
+	DefaultMultiplier = 2.0
+
+	// DefaultJitter is the default fraction of each wait that's randomized.
+	DefaultJitter = 0.2
+)
+
+// ErrBudgetExhausted is returned, wrapped in an Error, when an operation
+// could have been retried but its budget had no retries left.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Policy is how an operation is retried. The zero value uses the defaults and
+// retries every error that isn't fatal until the operation succeeds.
+type Policy struct {
+	// InitialInterval is the wait before the first retry. It's multiplied by
+	// Multiplier after each retry, up to MaxInterval.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+
+	// Jitter is the fraction of each wait that's randomized, between 0 and 1,
+	// so callers failing at the same time don't retry at the same time.
+	Jitter float64
+
+	// MaxAttempts is the number of times the operation is tried, including
+	// the first. No limit when zero.
+	MaxAttempts int
+
+	// MaxElapsed is how long an operation may be retried for. No limit when zero.
+	MaxElapsed time.Duration
+
+	// Retryable classifies errors. Errors are retried if it returns true.
+	// Every error that isn't fatal is retried when it's nil.
+	Retryable func(err error) bool
+
+	// Budget limits how many operations may be retried. Optional.
+	Budget *Budget
+
+	// Sleep waits between attempts. Returns false if the retries should stop.
+	// Defaults to time.Sleep. Tests and callers that need to stop on close
+	// can replace it.
+	Sleep func(d time.Duration) bool
+}
+
+// Backoff returns the wait before retrying after the nth failed attempt,
+// starting at 1, including jitter.
+func (p *Policy) Backoff(n int) time.Duration {
+	initial, max, mult := p.InitialInterval, p.MaxInterval, p.Multiplier
+	if initial <= 0 {
+		initial = DefaultInitialInterval
+	}
+	if max <= 0 {
+		max = DefaultMaxInterval
+	}
+	if mult < 1 {
+		mult = DefaultMultiplier
+	}
+
+	d := float64(initial) * math.Pow(mult, float64(n-1))
+	if d > float64(max) {
+		d = float64(max)
+	}
+
+	// Spread the wait evenly over [d*(1-jitter), d*(1+jitter)).
+	jitter := p.Jitter
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		d *= 1 - jitter + 2*jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// IsRetryable returns true if err should be retried under the policy.
+func (p *Policy) IsRetryable(err error) bool {
+	if err == nil || IsFatal(err) {
+		return false
+	} else if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+// Do calls fn until it succeeds, returns an error that isn't retryable or the
+// policy's limits are reached. The error of the last attempt is returned.
+func (p *Policy) Do(fn func() error) error {
+	if p.Budget != nil {
+		p.Budget.Deposit()
+	}
+
+	start := time.Now()
+	for n := 1; ; n++ {
+		err := fn()
+		if !p.IsRetryable(err) {
+			return unwrapFatal(err)
+		}
+
+		// Stop if the limits of the policy are reached.
+		if p.MaxAttempts > 0 && n >= p.MaxAttempts {
+			return err
+		}
+		d := p.Backoff(n)
+		if p.MaxElapsed > 0 && time.Since(start)+d > p.MaxElapsed {
+			return err
+		}
+		if p.Budget != nil && !p.Budget.Withdraw() {
+			return &Error{Err: err, Reason: ErrBudgetExhausted}
+		}
+
+		if !p.sleep(d) {
+			return err
+		}
+	}
+}
+
+func (p *Policy) sleep(d time.Duration) bool {
+	if p.Sleep != nil {
+		return p.Sleep(d)
+	}
+	time.Sleep(d)
+	return true
+}
+
+// Error is the error of an operation that wasn't retried for a reason other
+// than its own error.
+type Error struct {
+	Err    error
+	Reason error
+}
+
+func (e *Error) Error() string { return e.Err.Error() + " (" + e.Reason.Error() + ")" }
+
+// fatalError marks an error that must not be retried.
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+
+// Fatal marks an error so it's never retried. Do returns the original error.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// IsFatal returns true if err was marked with Fatal.
+func IsFatal(err error) bool {
+	_, ok := err.(*fatalError)
+	return ok
+}
+
+// unwrapFatal returns the error marked with Fatal, or err if it isn't marked.
+func unwrapFatal(err error) error {
+	if e, ok := err.(*fatalError); ok {
+		return e.err
+	}
+	return err
+}
+
+// Budget limits retries to a ratio of the operations. Each operation
+// deposits the ratio in tokens and each retry withdraws a whole token, up to
+// a maximum balance so a long healthy period doesn't allow a retry storm.
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+// NewBudget returns a budget allowing retries for a ratio of the operations,
+// with at most max retries saved up. The budget starts full.
+func NewBudget(ratio float64, max int) *Budget {
+	return &Budget{ratio: ratio, max: float64(max), tokens: float64(max)}
+}
+
+// Deposit records an operation.
+func (b *Budget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens += b.ratio; b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// Withdraw returns true if a retry is allowed, spending a token.
+func (b *Budget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
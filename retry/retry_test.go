@@ -0,0 +1,135 @@
+package retry_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/retry"
+)
+
+// Ensure the backoff grows exponentially, is capped and stays within the jitter.
+func TestPolicy_Backoff(t *testing.T) {
+	p := &retry.Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second}
+	for i, tt := range []struct {
+		n   int
+		exp time.Duration
+	}{
+		{n: 1, exp: 100 * time.Millisecond},
+		{n: 2, exp: 200 * time.Millisecond},
+		{n: 4, exp: 800 * time.Millisecond},
+		{n: 5, exp: time.Second},
+		{n: 50, exp: time.Second},
+	} {
+		if d := p.Backoff(tt.n); d != tt.exp {
+			t.Errorf("%d. backoff mismatch: got %s, exp %s", i, d, tt.exp)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Backoff(2); d < 100*time.Millisecond || d >= 300*time.Millisecond {
+			t.Fatalf("backoff out of jitter range: %s", d)
+		}
+	}
+}
+
+// Ensure an operation is retried until it succeeds.
+func TestPolicy_Do(t *testing.T) {
+	var slept []time.Duration
+	p := &retry.Policy{
+		InitialInterval: time.Millisecond,
+		Sleep:           func(d time.Duration) bool { slept = append(slept, d); return true },
+	}
+
+	var n int
+	if err := p.Do(func() error {
+		if n++; n < 3 {
+			return errors.New("fail")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatalf("unexpected attempts: %d", n)
+	} else if len(slept) != 2 || slept[0] != time.Millisecond || slept[1] != 2*time.Millisecond {
+		t.Fatalf("unexpected sleeps: %v", slept)
+	}
+}
+
+// Ensure the limits of a policy stop the retries.
+func TestPolicy_Do_Limits(t *testing.T) {
+	errFail := errors.New("fail")
+	noSleep := func(time.Duration) bool { return true }
+
+	// Limited number of attempts.
+	var n int
+	p := &retry.Policy{MaxAttempts: 3, Sleep: noSleep}
+	if err := p.Do(func() error { n++; return errFail }); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 3 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+
+	// Errors that aren't retryable.
+	n = 0
+	p = &retry.Policy{Retryable: func(err error) bool { return false }, Sleep: noSleep}
+	if err := p.Do(func() error { n++; return errFail }); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 1 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+
+	// Fatal errors are returned unwrapped.
+	n = 0
+	p = &retry.Policy{Sleep: noSleep}
+	if err := p.Do(func() error { n++; return retry.Fatal(errFail) }); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 1 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+
+	// Sleep stops the retries.
+	n = 0
+	p = &retry.Policy{Sleep: func(time.Duration) bool { return false }}
+	if err := p.Do(func() error { n++; return errFail }); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 1 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+}
+
+// Ensure a budget limits the retries to a ratio of the operations.
+func TestPolicy_Do_Budget(t *testing.T) {
+	errFail := errors.New("fail")
+	p := &retry.Policy{
+		MaxAttempts: 2,
+		Budget:      retry.NewBudget(0.5, 1),
+		Sleep:       func(time.Duration) bool { return true },
+	}
+
+	// The budget starts full so the first operation is retried.
+	var n int
+	if err := p.Do(func() error { n++; return errFail }); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 2 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+
+	// The second operation only deposited half a retry.
+	n = 0
+	err := p.Do(func() error { n++; return errFail })
+	if e, ok := err.(*retry.Error); !ok || e.Reason != retry.ErrBudgetExhausted || e.Err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 1 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+
+	// The third one completes a token.
+	n = 0
+	if err := p.Do(func() error { n++; return errFail }); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 2 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+}
@@ -22,6 +22,12 @@ type Service struct {
 	cert     string
 	err      chan error
 
+	// Handler serves the API requests of the interface, such as queries and
+	// writes, from the admin port. It is expected to enforce the same
+	// authentication as the API. If nil, the interface must be pointed at
+	// the API port instead.
+	Handler http.Handler
+
 	logger *log.Logger
 }
 
@@ -103,8 +109,18 @@ func (s *Service) serve() {
 		panic(err)
 	}
 
-	// Run file system handler on listener.
-	err = http.Serve(s.listener, http.FileServer(statikFS))
+	// Serve the API paths of the interface through the handler so they're
+	// authenticated like the API, and everything else from the file system.
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(statikFS))
+	if s.Handler != nil {
+		for _, path := range []string{"/query", "/write", "/ping"} {
+			mux.Handle(path, s.Handler)
+		}
+	}
+
+	// Run handler on listener.
+	err = http.Serve(s.listener, mux)
 	if err != nil && !strings.Contains(err.Error(), "closed") {
 		s.err <- fmt.Errorf("listener error: addr=%s, err=%s", s.Addr(), err)
 	}
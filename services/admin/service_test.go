@@ -31,3 +31,34 @@ func TestService_Index(t *testing.T) {
 		t.Fatalf("unable to read body: %s", err)
 	}
 }
+
+// Ensure the API paths are served by the handler and other paths by the file system.
+func TestService_Handler(t *testing.T) {
+	s := admin.NewService(admin.Config{BindAddress: "127.0.0.1:0"})
+	s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{path: "/query?q=SHOW+DATABASES", status: http.StatusUnauthorized},
+		{path: "/write?db=foo", status: http.StatusUnauthorized},
+		{path: "/ping", status: http.StatusUnauthorized},
+		{path: "/", status: http.StatusOK},
+	} {
+		resp, err := http.Get("http://" + s.Addr().String() + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: unexpected status: %d", tt.path, resp.StatusCode)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/input"
 	"github.com/influxdb/influxdb/tsdb"
 	"github.com/kimor79/gollectd"
 )
@@ -42,6 +43,7 @@ type Service struct {
 	batcher *tsdb.PointBatcher
 	typesdb gollectd.Types
 	addr    net.Addr
+	stats   *tsdb.Statistics
 }
 
 // NewService returns a new instance of the collectd service.
@@ -50,6 +52,7 @@ func NewService(c Config) *Service {
 		Config: &c,
 		Logger: log.New(os.Stderr, "[collectd] ", log.LstdFlags),
 		err:    make(chan error),
+		stats:  tsdb.NewStatistics("collectd", "collectd", map[string]string{"bind": c.BindAddress}),
 	}
 
 	return s
@@ -135,10 +138,20 @@ func (s *Service) Close() error {
 	s.stop = nil
 	s.ln = nil
 	s.batcher = nil
+	tsdb.UnregisterStatistics(s.stats)
 	s.Logger.Println("collectd UDP closed")
 	return nil
 }
 
+// SetPointsWriter sets the writer received points are written to.
+func (s *Service) SetPointsWriter(w input.PointsWriter) { s.PointsWriter = w }
+
+// SetMetaStore sets the meta store used to create the target database.
+func (s *Service) SetMetaStore(m input.MetaStore) { s.MetaStore = m }
+
+// Statistics returns the counters kept by the service.
+func (s *Service) Statistics() *tsdb.Statistics { return s.stats }
+
 // SetLogger sets the internal logger to the logger passed in.
 func (s *Service) SetLogger(l *log.Logger) {
 	s.Logger = l
@@ -195,10 +208,12 @@ func (s *Service) handleMessage(buffer []byte) {
 	packets, err := gollectd.Packets(buffer, s.typesdb)
 	if err != nil {
 		s.Logger.Printf("Collectd parse error: %s", err)
+		s.stats.Add("parseFail", 1)
 		return
 	}
 	for _, packet := range *packets {
 		points := Unmarshal(&packet)
+		s.stats.Add("pointsReceived", int64(len(points)))
 		for _, p := range points {
 			s.batcher.In() <- p
 		}
@@ -221,8 +236,11 @@ func (s *Service) writePoints() {
 			}
 			if err := s.PointsWriter.WritePoints(req); err != nil {
 				s.Logger.Printf("failed to write batch: %s", err)
+				s.stats.Add("batchesTxFail", 1)
 				continue
 			}
+			s.stats.Add("batchesTx", 1)
+			s.stats.Add("pointsTx", int64(len(batch)))
 		}
 	}
 }
@@ -263,7 +281,12 @@ func Unmarshal(packet *gollectd.Packet) []tsdb.Point {
 		if packet.TypeInstance != "" {
 			tags["type_instance"] = packet.TypeInstance
 		}
-		p := tsdb.NewPoint(name, tags, fields, timestamp)
+		p, err := tsdb.NewPointChecked(name, tsdb.NewTags(tags), fields, timestamp)
+		if err != nil {
+			// Drop NaN and +/-Inf values, which collectd sends for unknown
+			// gauges, since they are not supported values.
+			continue
+		}
 
 		points = append(points, p)
 	}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"log"
+	"math"
 	"net"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/toml"
 	"github.com/influxdb/influxdb/tsdb"
+	"github.com/kimor79/gollectd"
 )
 
 // Test that the service checks / creates the target database on startup.
@@ -178,6 +180,26 @@ Loop:
 	}
 }
 
+// Test that NaN and infinite values are dropped instead of being encoded.
+func TestUnmarshal_NonFinite(t *testing.T) {
+	points := Unmarshal(&gollectd.Packet{
+		Hostname: "server01",
+		Plugin:   "cpu",
+		Time:     1414080767,
+		Values: []gollectd.Value{
+			{Name: "idle", Value: math.NaN()},
+			{Name: "user", Value: math.Inf(1)},
+			{Name: "system", Value: 10},
+		},
+	})
+
+	if len(points) != 1 {
+		t.Fatalf("exp 1 point, got %d", len(points))
+	} else if exp, got := "cpu_system,host=server01 value=10.0 1414080767000000000", points[0].String(); got != exp {
+		t.Fatalf("\n\texp = %s\n\tgot = %s\n", exp, got)
+	}
+}
+
 type testService struct {
 	*Service
 	MetaStore    testMetaStore
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/influxdb/clock"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
@@ -36,6 +37,7 @@ type metaStore interface {
 	IsLeader() bool
 	Databases() ([]meta.DatabaseInfo, error)
 	Database(name string) (*meta.DatabaseInfo, error)
+	Interval(name string) (time.Duration, error)
 }
 
 // pointsWriter is an internal interface to make testing easier.
@@ -50,6 +52,8 @@ type Service struct {
 	PointsWriter  pointsWriter
 	Config        *Config
 	RunInterval   time.Duration
+	// Clock schedules the queries and sets the time ranges they compute.
+	Clock clock.Clock
 	// RunCh can be used by clients to signal service to run CQs.
 	RunCh          chan struct{}
 	Logger         *log.Logger
@@ -65,6 +69,7 @@ func NewService(c Config) *Service {
 	s := &Service{
 		Config:         &c,
 		RunInterval:    time.Second,
+		Clock:          clock.New(),
 		RunCh:          make(chan struct{}),
 		loggingEnabled: c.LogEnabled,
 		Logger:         log.New(os.Stderr, "[continuous_querier] ", log.LstdFlags),
@@ -163,7 +168,7 @@ func (s *Service) backgroundLoop() {
 				s.Logger.Print("running continuous queries by request")
 				s.runContinuousQueries()
 			}
-		case <-time.After(s.RunInterval):
+		case <-s.Clock.After(s.runInterval()):
 			if s.MetaStore.IsLeader() {
 				s.runContinuousQueries()
 			}
@@ -171,6 +176,16 @@ func (s *Service) backgroundLoop() {
 	}
 }
 
+// runInterval returns the interval set with SET CONTINUOUS QUERY RUN INTERVAL,
+// or RunInterval if it wasn't set.
+func (s *Service) runInterval() time.Duration {
+	d, err := s.MetaStore.Interval(influxql.ContinuousQueryRunInterval)
+	if err != nil || d <= 0 {
+		return s.RunInterval
+	}
+	return d
+}
+
 // runContinuousQueries gets CQs from the meta store and runs them.
 func (s *Service) runContinuousQueries() {
 	// Get list of all databases.
@@ -210,8 +225,9 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	}
 
 	// See if this query needs to be run.
+	now := s.Clock.Now()
 	computeNoMoreThan := time.Duration(s.Config.ComputeNoMoreThan)
-	run, err := cq.shouldRunContinuousQuery(s.Config.ComputeRunsPerInterval, computeNoMoreThan)
+	run, err := cq.shouldRunContinuousQuery(now, s.Config.ComputeRunsPerInterval, computeNoMoreThan)
 	if err != nil {
 		return err
 	} else if !run {
@@ -219,7 +235,6 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	}
 
 	// We're about to run the query so store the time.
-	now := time.Now()
 	cq.LastRun = now
 	s.lastRuns[cqi.Name] = now
 
@@ -374,10 +389,19 @@ func (s *Service) convertRowToPoints(measurementName string, row *influxql.Row)
 	for _, v := range row.Values {
 		vals := make(map[string]interface{})
 		for fieldName, fieldIndex := range fieldIndexes {
-			vals[fieldName] = v[fieldIndex]
+			// Skip empty values, such as those of fill(null).
+			if v[fieldIndex] != nil {
+				vals[fieldName] = v[fieldIndex]
+			}
 		}
 
-		p := tsdb.NewPoint(measurementName, row.Tags, vals, v[timeIndex].(time.Time))
+		p, err := tsdb.NewPointChecked(measurementName, tsdb.NewTags(row.Tags), vals, v[timeIndex].(time.Time))
+		if err == tsdb.ErrPointMissingFields {
+			continue
+		} else if err != nil {
+			log.Printf("dropping point of %s at %s: %s", measurementName, v[timeIndex], err)
+			continue
+		}
 
 		points = append(points, p)
 	}
@@ -422,7 +446,7 @@ func NewContinuousQuery(database string, cqi *meta.ContinuousQueryInfo) (*Contin
 // shouldRunContinuousQuery returns true if the CQ should be schedule to run. It will use the
 // lastRunTime of the CQ and the rules for when to run set through the config to determine
 // if this CQ should be run
-func (cq *ContinuousQuery) shouldRunContinuousQuery(runsPerInterval int, noMoreThan time.Duration) (bool, error) {
+func (cq *ContinuousQuery) shouldRunContinuousQuery(now time.Time, runsPerInterval int, noMoreThan time.Duration) (bool, error) {
 	// if it's not aggregated we don't run it
 	if cq.q.IsRawQuery {
 		return false, errors.New("continuous queries must be aggregate queries")
@@ -443,7 +467,7 @@ func (cq *ContinuousQuery) shouldRunContinuousQuery(runsPerInterval int, noMoreT
 	}
 
 	// if we've passed the amount of time since the last run, do it up
-	if cq.LastRun.Add(computeEvery).UnixNano() <= time.Now().UnixNano() {
+	if cq.LastRun.Add(computeEvery).UnixNano() <= now.UnixNano() {
 		return true, nil
 	}
 
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb/clock"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
@@ -61,6 +63,44 @@ func TestExecuteContinuousQuery_HappyPath(t *testing.T) {
 	}
 }
 
+// Test ExecuteContinuousQuery computes the time range and schedule from the clock.
+func TestExecuteContinuousQuery_Clock(t *testing.T) {
+	s := NewTestService(t)
+	c := clock.NewMock(time.Date(2015, 1, 1, 0, 0, 10, 500000000, time.UTC))
+	s.Clock = c
+	dbis, _ := s.MetaStore.Databases()
+	dbi := dbis[0]
+	cqi := dbi.ContinuousQueries[0]
+
+	var queries []string
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+		queries = append(queries, query.String())
+		return nil, nil
+	}
+
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi); err != nil {
+		t.Fatal(err)
+	} else if len(queries) == 0 || !strings.Contains(queries[0], "time >= '2015-01-01T00:00:10Z'") {
+		t.Fatalf("unexpected queries: %v", queries)
+	}
+
+	// The query isn't run again until the clock moves.
+	n := len(queries)
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi); err != nil {
+		t.Fatal(err)
+	} else if len(queries) != n {
+		t.Fatalf("unexpected queries: %v", queries[n:])
+	}
+
+	c.Add(2 * time.Minute)
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi); err != nil {
+		t.Fatal(err)
+	} else if len(queries) == n || !strings.Contains(queries[n], "time >= '2015-01-01T00:02:10Z'") {
+		t.Fatalf("unexpected queries: %v", queries[n:])
+	}
+}
+
 // Test the service happy path.
 func TestService_HappyPath(t *testing.T) {
 	s := NewTestService(t)
@@ -261,6 +301,7 @@ type MetaStore struct {
 	mu            sync.RWMutex
 	Leader        bool
 	DatabaseInfos []meta.DatabaseInfo
+	RunInterval   time.Duration
 	Err           error
 	t             *testing.T
 }
@@ -287,6 +328,16 @@ func (ms *MetaStore) Databases() ([]meta.DatabaseInfo, error) {
 	return ms.DatabaseInfos, ms.Err
 }
 
+// Interval returns the run interval set at runtime, if any.
+func (ms *MetaStore) Interval(name string) (time.Duration, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if name != influxql.ContinuousQueryRunInterval {
+		return 0, nil
+	}
+	return ms.RunInterval, nil
+}
+
 // Database returns a single database by name.
 func (ms *MetaStore) Database(name string) (*meta.DatabaseInfo, error) {
 	ms.mu.RLock()
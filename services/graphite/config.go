@@ -45,6 +45,7 @@ type Config struct {
 	Templates        []string      `toml:"templates"`
 	Tags             []string      `toml:"tags"`
 	Separator        string        `toml:"separator"`
+	FieldTemplates   bool          `toml:"field-templates"`
 }
 
 // NewConfig returns a new Config with defaults.
@@ -83,10 +84,10 @@ func (c *Config) WithDefaults() *Config {
 }
 
 func (c *Config) DefaultTags() tsdb.Tags {
-	tags := tsdb.Tags{}
+	var tags tsdb.Tags
 	for _, t := range c.Tags {
 		parts := strings.Split(t, "=")
-		tags[parts[0]] = parts[1]
+		tags.Set(parts[0], parts[1])
 	}
 	return tags
 }
@@ -32,6 +32,10 @@ type Options struct {
 	Separator   string
 	Templates   []string
 	DefaultTags tsdb.Tags
+
+	// FieldTemplates makes the "field" part of templates name the field of
+	// the value. Otherwise "field" is a tag like any other part.
+	FieldTemplates bool
 }
 
 // NewParserWithOptions returns a graphite parser using the given options
@@ -56,12 +60,12 @@ func NewParserWithOptions(options Options) (*Parser, error) {
 		}
 
 		// Parse out the default tags specific to this template
-		tags := tsdb.Tags{}
+		var tags tsdb.Tags
 		if strings.Contains(parts[len(parts)-1], "=") {
 			tagStrs := strings.Split(parts[len(parts)-1], ",")
 			for _, kv := range tagStrs {
 				parts := strings.Split(kv, "=")
-				tags[parts[0]] = parts[1]
+				tags.Set(parts[0], parts[1])
 			}
 		}
 
@@ -69,6 +73,7 @@ func NewParserWithOptions(options Options) (*Parser, error) {
 		if err != nil {
 			return nil, err
 		}
+		tmpl.field = options.FieldTemplates
 		matcher.Add(filter, tmpl)
 	}
 	return &Parser{matcher: matcher, tags: options.DefaultTags}, nil
@@ -94,7 +99,7 @@ func (p *Parser) Parse(line string) (tsdb.Point, error) {
 
 	// decode the name and tags
 	matcher := p.matcher.Match(fields[0])
-	measurement, tags := matcher.Apply(fields[0])
+	measurement, tags, field := matcher.Apply(fields[0])
 
 	// Could not extract measurement, use the raw value
 	if measurement == "" {
@@ -107,7 +112,11 @@ func (p *Parser) Parse(line string) (tsdb.Point, error) {
 		return nil, fmt.Errorf(`field "%s" value: %s`, fields[0], err)
 	}
 
-	fieldValues := map[string]interface{}{"value": v}
+	// Use the default field name unless the template extracted one.
+	if field == "" {
+		field = "value"
+	}
+	fieldValues := map[string]interface{}{field: v}
 
 	// If no 3rd field, use now as timestamp
 	timestamp := time.Now().UTC()
@@ -128,14 +137,13 @@ func (p *Parser) Parse(line string) (tsdb.Point, error) {
 	}
 
 	// Set the default tags on the point if they are not already set
-	for k, v := range p.tags {
-		if _, ok := tags[k]; !ok {
-			tags[k] = v
+	for _, t := range p.tags {
+		if _, ok := tags[t.Key]; !ok {
+			tags[t.Key] = t.Value
 		}
 	}
-	point := tsdb.NewPoint(measurement, tags, fieldValues, timestamp)
-
-	return point, nil
+	// Graphite reports missing data as NaN, so those values are kept.
+	return tsdb.NewPointWithNonFinite(measurement, tsdb.NewTags(tags), fieldValues, timestamp, tsdb.NonFiniteKeep)
 }
 
 // template represents a pattern and tags to map a graphite metric string to a influxdb Point
@@ -144,6 +152,7 @@ type template struct {
 	defaultTags       tsdb.Tags
 	greedyMeasurement bool
 	separator         string
+	field             bool // "field" names the field instead of a tag
 }
 
 func NewTemplate(pattern string, defaultTags tsdb.Tags, separator string) (*template, error) {
@@ -168,17 +177,18 @@ func NewTemplate(pattern string, defaultTags tsdb.Tags, separator string) (*temp
 }
 
 // Apply extracts the template fields form the given line and returns the measurement
-// name and tags
-func (t *template) Apply(line string) (string, map[string]string) {
+// name, tags and field name. The field name is empty if the template has no field.
+func (t *template) Apply(line string) (string, map[string]string, string) {
 	fields := strings.Split(line, ".")
 	var (
 		measurement []string
+		field       []string
 		tags        = make(map[string]string)
 	)
 
 	// Set any default tags
-	for k, v := range t.defaultTags {
-		tags[k] = v
+	for _, tag := range t.defaultTags {
+		tags[tag.Key] = tag.Value
 	}
 
 	for i, tag := range t.tags {
@@ -191,12 +201,14 @@ func (t *template) Apply(line string) (string, map[string]string) {
 		} else if tag == "measurement*" {
 			measurement = append(measurement, fields[i:]...)
 			break
+		} else if tag == "field" && t.field {
+			field = append(field, fields[i])
 		} else if tag != "" {
 			tags[tag] = fields[i]
 		}
 	}
 
-	return strings.Join(measurement, t.separator), tags
+	return strings.Join(measurement, t.separator), tags, strings.Join(field, t.separator)
 }
 
 // matcher determines which template should be applied to a given metric
@@ -105,7 +105,7 @@ func TestTemplateApply(t *testing.T) {
 			continue
 		}
 
-		measurement, tags := tmpl.Apply(test.input)
+		measurement, tags, _ := tmpl.Apply(test.input)
 		if measurement != test.measurement {
 			t.Fatalf("name parse failer.  expected %v, got %v", test.measurement, measurement)
 		}
@@ -230,7 +230,7 @@ func TestParseNaN(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("servers.localhost.cpu_load",
-		tsdb.Tags{},
+		tsdb.Tags(nil),
 		tsdb.Fields{"value": math.NaN()},
 		time.Unix(1435077219, 0))
 
@@ -250,7 +250,7 @@ func TestFilterMatchDefault(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("miss.servers.localhost.cpu_load",
-		tsdb.Tags{},
+		tsdb.Tags(nil),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -271,7 +271,7 @@ func TestFilterMatchMultipleMeasurement(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu.cpu_load.10",
-		tsdb.Tags{"host": "localhost"},
+		tsdb.NewTags(map[string]string{"host": "localhost"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -295,7 +295,7 @@ func TestFilterMatchMultipleMeasurementSeparator(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu_cpu_load_10",
-		tsdb.Tags{"host": "localhost"},
+		tsdb.NewTags(map[string]string{"host": "localhost"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -316,7 +316,7 @@ func TestFilterMatchSingle(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost"},
+		tsdb.NewTags(map[string]string{"host": "localhost"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -337,7 +337,7 @@ func TestParseNoMatch(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("servers.localhost.memory.VmallocChunk",
-		tsdb.Tags{},
+		tsdb.Tags(nil),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -358,7 +358,7 @@ func TestFilterMatchWildcard(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost"},
+		tsdb.NewTags(map[string]string{"host": "localhost"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -381,7 +381,7 @@ func TestFilterMatchExactBeforeWildcard(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost"},
+		tsdb.NewTags(map[string]string{"host": "localhost"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -409,7 +409,7 @@ func TestFilterMatchMostLongestFilter(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost", "resource": "cpu"},
+		tsdb.NewTags(map[string]string{"host": "localhost", "resource": "cpu"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -436,7 +436,7 @@ func TestFilterMatchMultipleWildcards(t *testing.T) {
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "server01"},
+		tsdb.NewTags(map[string]string{"host": "server01"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -451,17 +451,17 @@ func TestFilterMatchMultipleWildcards(t *testing.T) {
 }
 
 func TestParseDefaultTags(t *testing.T) {
-	p, err := graphite.NewParser([]string{"servers.localhost .host.measurement*"}, tsdb.Tags{
+	p, err := graphite.NewParser([]string{"servers.localhost .host.measurement*"}, tsdb.NewTags(map[string]string{
 		"region": "us-east",
 		"zone":   "1c",
 		"host":   "should not set",
-	})
+	}))
 	if err != nil {
 		t.Fatalf("unexpected error creating parser, got %v", err)
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost", "region": "us-east", "zone": "1c"},
+		tsdb.NewTags(map[string]string{"host": "localhost", "region": "us-east", "zone": "1c"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -476,16 +476,16 @@ func TestParseDefaultTags(t *testing.T) {
 }
 
 func TestParseDefaultTemplateTags(t *testing.T) {
-	p, err := graphite.NewParser([]string{"servers.localhost .host.measurement* zone=1c"}, tsdb.Tags{
+	p, err := graphite.NewParser([]string{"servers.localhost .host.measurement* zone=1c"}, tsdb.NewTags(map[string]string{
 		"region": "us-east",
 		"host":   "should not set",
-	})
+	}))
 	if err != nil {
 		t.Fatalf("unexpected error creating parser, got %v", err)
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost", "region": "us-east", "zone": "1c"},
+		tsdb.NewTags(map[string]string{"host": "localhost", "region": "us-east", "zone": "1c"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -500,16 +500,16 @@ func TestParseDefaultTemplateTags(t *testing.T) {
 }
 
 func TestParseDefaultTemplateTagsOverridGlobal(t *testing.T) {
-	p, err := graphite.NewParser([]string{"servers.localhost .host.measurement* zone=1c,region=us-east"}, tsdb.Tags{
+	p, err := graphite.NewParser([]string{"servers.localhost .host.measurement* zone=1c,region=us-east"}, tsdb.NewTags(map[string]string{
 		"region": "shot not be set",
 		"host":   "should not set",
-	})
+	}))
 	if err != nil {
 		t.Fatalf("unexpected error creating parser, got %v", err)
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost", "region": "us-east", "zone": "1c"},
+		tsdb.NewTags(map[string]string{"host": "localhost", "region": "us-east", "zone": "1c"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -524,16 +524,16 @@ func TestParseDefaultTemplateTagsOverridGlobal(t *testing.T) {
 }
 
 func TestParseTemplateWhitespace(t *testing.T) {
-	p, err := graphite.NewParser([]string{"servers.localhost        .host.measurement*           zone=1c"}, tsdb.Tags{
+	p, err := graphite.NewParser([]string{"servers.localhost        .host.measurement*           zone=1c"}, tsdb.NewTags(map[string]string{
 		"region": "us-east",
 		"host":   "should not set",
-	})
+	}))
 	if err != nil {
 		t.Fatalf("unexpected error creating parser, got %v", err)
 	}
 
 	exp := tsdb.NewPoint("cpu_load",
-		tsdb.Tags{"host": "localhost", "region": "us-east", "zone": "1c"},
+		tsdb.NewTags(map[string]string{"host": "localhost", "region": "us-east", "zone": "1c"}),
 		tsdb.Fields{"value": float64(11)},
 		time.Unix(1435077219, 0))
 
@@ -546,3 +546,52 @@ func TestParseTemplateWhitespace(t *testing.T) {
 		t.Errorf("parse mismatch: got %v, exp %v", pt.String(), exp.String())
 	}
 }
+
+func TestParseTemplateField(t *testing.T) {
+	p, err := graphite.NewParserWithOptions(graphite.Options{
+		Templates:      []string{".host.measurement.field"},
+		Separator:      graphite.DefaultSeparator,
+		FieldTemplates: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating parser, got %v", err)
+	}
+
+	point, err := p.Parse("servers.localhost.loadavg.10 11 1435077219")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	if exp := "loadavg"; point.Name() != exp {
+		t.Errorf("parser.Parse() measurement mismatch: got %v, exp %v", point.Name(), exp)
+	}
+	if exp := "localhost"; point.Tags().Get("host") != exp {
+		t.Errorf("parser.Parse() tag mismatch: got %v, exp %v", point.Tags().Get("host"), exp)
+	}
+	if f, ok := point.Fields()["10"].(float64); !ok || f != 11 {
+		t.Errorf("parser.Parse() field mismatch: got %v", point.Fields())
+	}
+}
+
+// Ensure templates with a "field" tag keep it a tag unless field templates are
+// enabled.
+func TestParseTemplateFieldTag(t *testing.T) {
+	p, err := graphite.NewParser([]string{".host.measurement.field"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating parser, got %v", err)
+	}
+
+	exp := tsdb.NewPoint("loadavg",
+		tsdb.NewTags(map[string]string{"host": "localhost", "field": "10"}),
+		tsdb.Fields{"value": float64(11)},
+		time.Unix(1435077219, 0))
+
+	pt, err := p.Parse("servers.localhost.loadavg.10 11 1435077219")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	if exp.String() != pt.String() {
+		t.Errorf("parse mismatch: got %v, exp %v", pt.String(), exp.String())
+	}
+}
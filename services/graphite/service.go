@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"log"
-	"math"
 	"net"
 	"os"
 	"strings"
@@ -13,6 +12,7 @@ import (
 
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/input"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -31,6 +31,7 @@ type Service struct {
 
 	batcher *tsdb.PointBatcher
 	parser  *Parser
+	stats   *tsdb.Statistics
 
 	logger *log.Logger
 
@@ -60,6 +61,7 @@ func NewService(c Config) (*Service, error) {
 		protocol:     d.Protocol,
 		batchSize:    d.BatchSize,
 		batchTimeout: time.Duration(d.BatchTimeout),
+		stats:        tsdb.NewStatistics("graphite", "graphite", map[string]string{"bind": d.BindAddress}),
 		logger:       log.New(os.Stderr, "[graphite] ", log.LstdFlags),
 		done:         make(chan struct{}),
 	}
@@ -71,9 +73,10 @@ func NewService(c Config) (*Service, error) {
 	s.consistencyLevel = consistencyLevel
 
 	parser, err := NewParserWithOptions(Options{
-		Templates:   d.Templates,
-		DefaultTags: d.DefaultTags(),
-		Separator:   d.Separator})
+		Templates:      d.Templates,
+		DefaultTags:    d.DefaultTags(),
+		Separator:      d.Separator,
+		FieldTemplates: d.FieldTemplates})
 
 	if err != nil {
 		return nil, err
@@ -130,10 +133,20 @@ func (s *Service) Close() error {
 	close(s.done)
 	s.wg.Wait()
 	s.done = nil
+	tsdb.UnregisterStatistics(s.stats)
 
 	return nil
 }
 
+// SetPointsWriter sets the writer parsed points are written to.
+func (s *Service) SetPointsWriter(w input.PointsWriter) { s.PointsWriter = w }
+
+// SetMetaStore sets the meta store used to create the target database.
+func (s *Service) SetMetaStore(m input.MetaStore) { s.MetaStore = m }
+
+// Statistics returns the counters kept by the service.
+func (s *Service) Statistics() *tsdb.Statistics { return s.stats }
+
 // SetLogger sets the internal logger to the logger passed in.
 func (s *Service) SetLogger(l *log.Logger) {
 	s.logger = l
@@ -231,18 +244,18 @@ func (s *Service) handleLine(line string) {
 	point, err := s.parser.Parse(line)
 	if err != nil {
 		s.logger.Printf("unable to parse line: %s", err)
+		s.stats.Add("parseFail", 1)
 		return
 	}
 
-	f, ok := point.Fields()["value"].(float64)
-	if ok {
-		// Drop NaN and +/-Inf data points since they are not supported values
-		if math.IsNaN(f) || math.IsInf(f, 0) {
-			s.logger.Printf("dropping unsupported value: '%v'", line)
-			return
-		}
+	// Drop NaN and +/-Inf data points since they are not supported values.
+	// Templates may name the field, so it isn't always "value".
+	if _, err := point.Fields().Finite(tsdb.NonFiniteReject); err != nil {
+		s.logger.Printf("dropping unsupported value: '%v'", line)
+		return
 	}
 
+	s.stats.Add("pointsReceived", 1)
 	s.batcher.In() <- point
 }
 
@@ -256,10 +269,14 @@ func (s *Service) processBatches(batcher *tsdb.PointBatcher) {
 				Database:         s.database,
 				RetentionPolicy:  "",
 				ConsistencyLevel: s.consistencyLevel,
-				Points:           batch,
+				Points:           tsdb.MergePoints(batch),
 			}); err != nil {
 				s.logger.Printf("failed to write point batch to database %q: %s", s.database, err)
+				s.stats.Add("batchesTxFail", 1)
+				continue
 			}
+			s.stats.Add("batchesTx", 1)
+			s.stats.Add("pointsTx", int64(len(batch)))
 		case <-s.done:
 			return
 		}
@@ -45,7 +45,7 @@ func Test_ServerGraphiteTCP(t *testing.T) {
 			} else if req.Points[0].String() !=
 				tsdb.NewPoint(
 					"cpu",
-					map[string]string{},
+					tsdb.NewTags(map[string]string{}),
 					map[string]interface{}{"value": 23.456},
 					time.Unix(now.Unix(), 0)).String() {
 			}
@@ -114,7 +114,7 @@ func Test_ServerGraphiteUDP(t *testing.T) {
 			} else if req.Points[0].String() !=
 				tsdb.NewPoint(
 					"cpu",
-					map[string]string{},
+					tsdb.NewTags(map[string]string{}),
 					map[string]interface{}{"value": 23.456},
 					time.Unix(now.Unix(), 0)).String() {
 				t.Fatalf("unexpected points: %#v", req.Points[0].String())
@@ -0,0 +1,45 @@
+package grpcd
+
+import "errors"
+
+const (
+	// DefaultBindAddress is the default address the service listens on.
+	DefaultBindAddress = ":8090"
+
+	// DefaultCertificate is the default certificate and key file.
+	DefaultCertificate = "/etc/ssl/influxdb.pem"
+
+	// DefaultChunkSize is the number of rows of a query sent in each
+	// response when the request doesn't set it.
+	DefaultChunkSize = 10000
+)
+
+// Config represents the configuration of the gRPC service.
+type Config struct {
+	Enabled     bool   `toml:"enabled"`
+	BindAddress string `toml:"bind-address"`
+	AuthEnabled bool   `toml:"auth-enabled"`
+
+	// TLSEnabled serves the service over TLS with the certificate and key
+	// in the Certificate file.
+	TLSEnabled  bool   `toml:"tls-enabled"`
+	Certificate string `toml:"certificate"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		BindAddress: DefaultBindAddress,
+		Certificate: DefaultCertificate,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.Enabled && c.BindAddress == "" {
+		return errors.New("bind-address is required")
+	} else if c.TLSEnabled && c.Certificate == "" {
+		return errors.New("certificate is required when TLS is enabled")
+	}
+	return nil
+}
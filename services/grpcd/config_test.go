@@ -0,0 +1,42 @@
+package grpcd_test
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/services/grpcd"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c grpcd.Config
+	if _, err := toml.Decode(`
+enabled = true
+bind-address = ":9090"
+auth-enabled = true
+tls-enabled = true
+certificate = "/dev/null"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled != true {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.BindAddress != ":9090" {
+		t.Fatalf("unexpected bind address: %s", c.BindAddress)
+	} else if c.AuthEnabled != true {
+		t.Fatalf("unexpected auth enabled: %v", c.AuthEnabled)
+	} else if c.TLSEnabled != true {
+		t.Fatalf("unexpected tls enabled: %v", c.TLSEnabled)
+	} else if c.Certificate != "/dev/null" {
+		t.Fatalf("unexpected certificate: %s", c.Certificate)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Certificate = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for TLS without a certificate")
+	}
+}
@@ -0,0 +1,330 @@
+// Code generated by protoc-gen-gogo.
+// source: internal/grpcd.proto
+// DO NOT EDIT!
+
+// +build grpc
+
+/*
+Package internal is a generated protocol buffer package.
+
+It is generated from these files:
+	internal/grpcd.proto
+
+It has these top-level messages:
+	WritePointsRequest
+	WritePointsResponse
+	QueryRequest
+	QueryResponse
+*/
+package internal
+
+import proto "github.com/gogo/protobuf/proto"
+import math "math"
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = math.Inf
+
+type WritePointsRequest struct {
+	Database         *string  `protobuf:"bytes,1,opt" json:"Database,omitempty"`
+	RetentionPolicy  *string  `protobuf:"bytes,2,opt" json:"RetentionPolicy,omitempty"`
+	Consistency      *string  `protobuf:"bytes,3,opt" json:"Consistency,omitempty"`
+	Points           [][]byte `protobuf:"bytes,4,rep" json:"Points,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *WritePointsRequest) Reset()         { *m = WritePointsRequest{} }
+func (m *WritePointsRequest) String() string { return proto.CompactTextString(m) }
+func (*WritePointsRequest) ProtoMessage()    {}
+
+func (m *WritePointsRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *WritePointsRequest) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *WritePointsRequest) GetConsistency() string {
+	if m != nil && m.Consistency != nil {
+		return *m.Consistency
+	}
+	return ""
+}
+
+func (m *WritePointsRequest) GetPoints() [][]byte {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+type WritePointsResponse struct {
+	PointsWritten    *uint64 `protobuf:"varint,1,req" json:"PointsWritten,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *WritePointsResponse) Reset()         { *m = WritePointsResponse{} }
+func (m *WritePointsResponse) String() string { return proto.CompactTextString(m) }
+func (*WritePointsResponse) ProtoMessage()    {}
+
+func (m *WritePointsResponse) GetPointsWritten() uint64 {
+	if m != nil && m.PointsWritten != nil {
+		return *m.PointsWritten
+	}
+	return 0
+}
+
+type QueryRequest struct {
+	Query            *string `protobuf:"bytes,1,req" json:"Query,omitempty"`
+	Database         *string `protobuf:"bytes,2,opt" json:"Database,omitempty"`
+	ChunkSize        *int64  `protobuf:"varint,3,opt" json:"ChunkSize,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *QueryRequest) Reset()         { *m = QueryRequest{} }
+func (m *QueryRequest) String() string { return proto.CompactTextString(m) }
+func (*QueryRequest) ProtoMessage()    {}
+
+func (m *QueryRequest) GetQuery() string {
+	if m != nil && m.Query != nil {
+		return *m.Query
+	}
+	return ""
+}
+
+func (m *QueryRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *QueryRequest) GetChunkSize() int64 {
+	if m != nil && m.ChunkSize != nil {
+		return *m.ChunkSize
+	}
+	return 0
+}
+
+type QueryResponse struct {
+	StatementID      *int64   `protobuf:"varint,1,req" json:"StatementID,omitempty"`
+	Points           [][]byte `protobuf:"bytes,2,rep" json:"Points,omitempty"`
+	Error            *string  `protobuf:"bytes,3,opt" json:"Error,omitempty"`
+	Partial          *bool    `protobuf:"varint,4,opt" json:"Partial,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *QueryResponse) Reset()         { *m = QueryResponse{} }
+func (m *QueryResponse) String() string { return proto.CompactTextString(m) }
+func (*QueryResponse) ProtoMessage()    {}
+
+func (m *QueryResponse) GetStatementID() int64 {
+	if m != nil && m.StatementID != nil {
+		return *m.StatementID
+	}
+	return 0
+}
+
+func (m *QueryResponse) GetPoints() [][]byte {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+func (m *QueryResponse) GetError() string {
+	if m != nil && m.Error != nil {
+		return *m.Error
+	}
+	return ""
+}
+
+func (m *QueryResponse) GetPartial() bool {
+	if m != nil && m.Partial != nil {
+		return *m.Partial
+	}
+	return false
+}
+
+func init() {
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// Client API for InfluxDB service
+
+type InfluxDBClient interface {
+	// WritePoints writes the points of every request of the stream. The
+	// response is sent once the stream is closed by the client.
+	WritePoints(ctx context.Context, opts ...grpc.CallOption) (InfluxDB_WritePointsClient, error)
+	// Query streams the rows of the results of a query as points.
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (InfluxDB_QueryClient, error)
+}
+
+type influxDBClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewInfluxDBClient(cc *grpc.ClientConn) InfluxDBClient {
+	return &influxDBClient{cc}
+}
+
+func (c *influxDBClient) WritePoints(ctx context.Context, opts ...grpc.CallOption) (InfluxDB_WritePointsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_InfluxDB_serviceDesc.Streams[0], c.cc, "/internal.InfluxDB/WritePoints", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &influxDBWritePointsClient{stream}
+	return x, nil
+}
+
+type InfluxDB_WritePointsClient interface {
+	Send(*WritePointsRequest) error
+	CloseAndRecv() (*WritePointsResponse, error)
+	grpc.ClientStream
+}
+
+type influxDBWritePointsClient struct {
+	grpc.ClientStream
+}
+
+func (x *influxDBWritePointsClient) Send(m *WritePointsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *influxDBWritePointsClient) CloseAndRecv() (*WritePointsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(WritePointsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *influxDBClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (InfluxDB_QueryClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_InfluxDB_serviceDesc.Streams[1], c.cc, "/internal.InfluxDB/Query", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &influxDBQueryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type InfluxDB_QueryClient interface {
+	Recv() (*QueryResponse, error)
+	grpc.ClientStream
+}
+
+type influxDBQueryClient struct {
+	grpc.ClientStream
+}
+
+func (x *influxDBQueryClient) Recv() (*QueryResponse, error) {
+	m := new(QueryResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for InfluxDB service
+
+type InfluxDBServer interface {
+	// WritePoints writes the points of every request of the stream. The
+	// response is sent once the stream is closed by the client.
+	WritePoints(InfluxDB_WritePointsServer) error
+	// Query streams the rows of the results of a query as points.
+	Query(*QueryRequest, InfluxDB_QueryServer) error
+}
+
+func RegisterInfluxDBServer(s *grpc.Server, srv InfluxDBServer) {
+	s.RegisterService(&_InfluxDB_serviceDesc, srv)
+}
+
+func _InfluxDB_WritePoints_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(InfluxDBServer).WritePoints(&influxDBWritePointsServer{stream})
+}
+
+type InfluxDB_WritePointsServer interface {
+	SendAndClose(*WritePointsResponse) error
+	Recv() (*WritePointsRequest, error)
+	grpc.ServerStream
+}
+
+type influxDBWritePointsServer struct {
+	grpc.ServerStream
+}
+
+func (x *influxDBWritePointsServer) SendAndClose(m *WritePointsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *influxDBWritePointsServer) Recv() (*WritePointsRequest, error) {
+	m := new(WritePointsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _InfluxDB_Query_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InfluxDBServer).Query(m, &influxDBQueryServer{stream})
+}
+
+type InfluxDB_QueryServer interface {
+	Send(*QueryResponse) error
+	grpc.ServerStream
+}
+
+type influxDBQueryServer struct {
+	grpc.ServerStream
+}
+
+func (x *influxDBQueryServer) Send(m *QueryResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _InfluxDB_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "internal.InfluxDB",
+	HandlerType: (*InfluxDBServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WritePoints",
+			Handler:       _InfluxDB_WritePoints_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Query",
+			Handler:       _InfluxDB_Query_Handler,
+			ServerStreams: true,
+		},
+	},
+}
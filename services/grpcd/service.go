@@ -0,0 +1,341 @@
+// +build grpc
+
+package grpcd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/grpcd/internal"
+	"github.com/influxdb/influxdb/tsdb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service serves writes and queries over gRPC. Points are sent in the
+// binary encoding of tsdb.Point rather than in the line protocol.
+type Service struct {
+	ln     net.Listener
+	server *grpc.Server
+
+	addr string
+	auth bool
+	tls  bool
+	cert string
+
+	MetaStore interface {
+		Database(name string) (*meta.DatabaseInfo, error)
+		Authenticate(username, password string) (*meta.UserInfo, error)
+		Users() ([]meta.UserInfo, error)
+	}
+
+	QueryExecutor interface {
+		Authorize(u *meta.UserInfo, q *influxql.Query, db string) error
+		ExecuteQuery(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error)
+	}
+
+	PointsWriter interface {
+		WritePoints(p *cluster.WritePointsRequest) error
+	}
+
+	Logger *log.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		addr:   c.BindAddress,
+		auth:   c.AuthEnabled,
+		tls:    c.TLSEnabled,
+		cert:   c.Certificate,
+		Logger: log.New(os.Stderr, "[grpcd] ", log.LstdFlags),
+	}
+}
+
+// Open starts the service.
+func (s *Service) Open() error {
+	s.Logger.Println("Starting gRPC service")
+	s.Logger.Println("Authentication enabled:", s.auth)
+
+	var opts []grpc.ServerOption
+	if s.tls {
+		creds, err := credentials.NewServerTLSFromFile(s.cert, s.cert)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+	s.Logger.Println("Listening on gRPC:", ln.Addr().String())
+
+	s.server = grpc.NewServer(opts...)
+	internal.RegisterInfluxDBServer(s.server, &server{s})
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !strings.Contains(err.Error(), "closed") {
+			s.Logger.Printf("listener failed: addr=%s, err=%s", ln.Addr(), err)
+		}
+	}()
+	return nil
+}
+
+// Close stops the server and closes the listener.
+func (s *Service) Close() error {
+	if s.server != nil {
+		s.server.Stop()
+	}
+	return nil
+}
+
+// SetLogger sets the internal logger to the logger passed in.
+func (s *Service) SetLogger(l *log.Logger) {
+	s.Logger = l
+}
+
+// Addr returns the listener's address. Returns nil if listener is closed.
+func (s *Service) Addr() net.Addr {
+	if s.ln != nil {
+		return s.ln.Addr()
+	}
+	return nil
+}
+
+// authenticate returns the user whose credentials are in the "username" and
+// "password" metadata of a call. As with HTTP, authentication isn't required
+// until a user has been created.
+func (s *Service) authenticate(ctx context.Context) (*meta.UserInfo, error) {
+	if !s.auth {
+		return nil, nil
+	}
+
+	uis, err := s.MetaStore.Users()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if len(uis) == 0 {
+		return nil, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	username, password := firstValue(md["username"]), firstValue(md["password"])
+	if username == "" {
+		return nil, status.Error(codes.Unauthenticated, "username required")
+	}
+
+	user, err := s.MetaStore.Authenticate(username, password)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return user, nil
+}
+
+// writePoints writes the points of a request.
+func (s *Service) writePoints(req *internal.WritePointsRequest, user *meta.UserInfo) error {
+	database := req.GetDatabase()
+	if database == "" {
+		return status.Error(codes.InvalidArgument, "database is required")
+	}
+
+	if di, err := s.MetaStore.Database(database); err != nil {
+		return status.Errorf(codes.Internal, "metastore database error: %s", err)
+	} else if di == nil {
+		return status.Errorf(codes.NotFound, "database not found: %q", database)
+	}
+
+	if s.auth && user == nil {
+		return status.Errorf(codes.PermissionDenied, "user is required to write to database %q", database)
+	} else if s.auth && !user.Authorize(influxql.WritePrivilege, database) {
+		return status.Errorf(codes.PermissionDenied, "%q user is not authorized to write to database %q", user.Name, database)
+	}
+
+	consistency := cluster.ConsistencyLevelOne
+	if level := req.GetConsistency(); level != "" {
+		var err error
+		if consistency, err = cluster.ParseConsistencyLevel(level); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	points := make([]tsdb.Point, 0, len(req.Points))
+	for i, b := range req.Points {
+		p, err := tsdb.NewPointFromBytes(b)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "point %d: %s", i, err)
+		}
+		points = append(points, p)
+	}
+
+	if err := s.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+		Database:         database,
+		RetentionPolicy:  req.GetRetentionPolicy(),
+		ConsistencyLevel: consistency,
+		Points:           points,
+	}); influxdb.IsClientError(err) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// server implements the gRPC service on top of Service.
+type server struct {
+	*Service
+}
+
+// WritePoints writes the points of every request of the stream. The stream
+// fails at the first request that can't be written; the points of the
+// requests before it have been written.
+func (s *server) WritePoints(stream internal.InfluxDB_WritePointsServer) error {
+	user, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	var n uint64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&internal.WritePointsResponse{PointsWritten: proto.Uint64(n)})
+		} else if err != nil {
+			return err
+		}
+
+		if err := s.writePoints(req, user); err != nil {
+			return err
+		}
+		n += uint64(len(req.Points))
+	}
+}
+
+// Query executes a query and streams the rows of its results as points.
+func (s *server) Query(req *internal.QueryRequest, stream internal.InfluxDB_QueryServer) error {
+	user, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	query, err := influxql.NewParser(strings.NewReader(req.GetQuery())).ParseQuery()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "error parsing query: %s", err)
+	}
+
+	db := req.GetDatabase()
+	if s.auth {
+		if err := s.QueryExecutor.Authorize(user, query, db); err != nil {
+			return status.Errorf(codes.PermissionDenied, "error authorizing query: %s", err)
+		}
+	}
+
+	chunkSize := int(req.GetChunkSize())
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	results, err := s.QueryExecutor.ExecuteQuery(query, db, chunkSize)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for r := range results {
+		if r == nil {
+			continue
+		}
+
+		resp := &internal.QueryResponse{StatementID: proto.Int64(int64(r.StatementID))}
+		if r.Partial {
+			resp.Partial = proto.Bool(true)
+		}
+		if r.Err != nil {
+			resp.Error = proto.String(r.Err.Error())
+		}
+		for _, row := range r.Series {
+			if row.Err != nil && resp.Error == nil {
+				resp.Error = proto.String(row.Err.Error())
+			}
+			buf, err := rowPoints(row)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			resp.Points = append(resp.Points, buf...)
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowPoints returns the binary encoding of a point for each value of a row.
+// The time of a point is in the "time" column, and the other columns are
+// its fields. Null columns are left out, as are values with no fields.
+func rowPoints(row *influxql.Row) ([][]byte, error) {
+	var a [][]byte
+	for _, values := range row.Values {
+		t := time.Unix(0, 0)
+		fields := make(tsdb.Fields)
+		for i, v := range values {
+			if i >= len(row.Columns) || v == nil {
+				continue
+			}
+			name := row.Columns[i]
+			if name == "time" {
+				if ts, ok := v.(time.Time); ok {
+					t = ts
+					continue
+				}
+			}
+			fields[name] = fieldValue(v)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		b, err := tsdb.NewPoint(row.Name, tsdb.NewTags(row.Tags), fields, t).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, b)
+	}
+	return a, nil
+}
+
+// fieldValue returns v as a type that can be a field value.
+func fieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64, int64, bool, string:
+		return v
+	case int:
+		return int64(v)
+	case time.Time:
+		return v.UnixNano()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func firstValue(a []string) string {
+	if len(a) == 0 {
+		return ""
+	}
+	return a[0]
+}
@@ -0,0 +1,61 @@
+// +build !grpc
+
+package grpcd
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+)
+
+// ErrNotSupported is returned when the service is opened by a binary built
+// without the "grpc" build tag. gRPC requires a newer Go than the release
+// builds use.
+var ErrNotSupported = errors.New("gRPC service not supported: rebuild with -tags grpc")
+
+// Service is the gRPC service of binaries built without gRPC support. It
+// fails to open.
+type Service struct {
+	MetaStore interface {
+		Database(name string) (*meta.DatabaseInfo, error)
+		Authenticate(username, password string) (*meta.UserInfo, error)
+		Users() ([]meta.UserInfo, error)
+	}
+
+	QueryExecutor interface {
+		Authorize(u *meta.UserInfo, q *influxql.Query, db string) error
+		ExecuteQuery(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error)
+	}
+
+	PointsWriter interface {
+		WritePoints(p *cluster.WritePointsRequest) error
+	}
+
+	Logger *log.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		Logger: log.New(os.Stderr, "[grpcd] ", log.LstdFlags),
+	}
+}
+
+// Open returns ErrNotSupported.
+func (s *Service) Open() error { return ErrNotSupported }
+
+// Close does nothing.
+func (s *Service) Close() error { return nil }
+
+// SetLogger sets the internal logger to the logger passed in.
+func (s *Service) SetLogger(l *log.Logger) {
+	s.Logger = l
+}
+
+// Addr returns nil since the service never listens.
+func (s *Service) Addr() net.Addr { return nil }
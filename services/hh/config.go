@@ -22,6 +22,10 @@ const (
 	// DefaultRetryInterval is the default amout of time the system waits before
 	// attempting to flush hinted handoff queues.
 	DefaultRetryInterval = time.Second
+
+	// DefaultRetryMaxInterval is the default longest time the system waits
+	// before retrying the queue of a node that keeps failing.
+	DefaultRetryMaxInterval = time.Minute
 )
 
 type Config struct {
@@ -31,6 +35,10 @@ type Config struct {
 	MaxAge         toml.Duration `toml:"max-age"`
 	RetryRateLimit int64         `toml:"retry-rate-limit"`
 	RetryInterval  toml.Duration `toml:"retry-interval"`
+
+	// RetryMaxInterval is the longest backoff between retries to a node. The
+	// backoff starts at RetryInterval and doubles after each failure.
+	RetryMaxInterval toml.Duration `toml:"retry-max-interval"`
 }
 
 func NewConfig() Config {
@@ -40,5 +48,7 @@ func NewConfig() Config {
 		MaxAge:         toml.Duration(DefaultMaxAge),
 		RetryRateLimit: DefaultRetryRateLimit,
 		RetryInterval:  toml.Duration(DefaultRetryInterval),
+
+		RetryMaxInterval: toml.Duration(DefaultRetryMaxInterval),
 	}
 }
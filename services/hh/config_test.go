@@ -17,6 +17,7 @@ retry-interval = "10m"
 max-size=2048
 max-age="20m"
 retry-rate-limit=1000
+retry-max-interval = "5m"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -42,4 +43,8 @@ retry-rate-limit=1000
 		t.Fatalf("unexpected retry rate limit: got %v, exp %v", c.RetryRateLimit, exp)
 	}
 
+	if exp := 5 * time.Minute; c.RetryMaxInterval.String() != exp.String() {
+		t.Fatalf("unexpected retry max interval: got %v, exp %v", c.RetryMaxInterval, exp)
+	}
+
 }
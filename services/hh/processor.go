@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/influxdb/retry"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -21,23 +22,37 @@ type Processor struct {
 	maxSize        int64
 	maxAge         time.Duration
 	retryRateLimit int64
+	retryPolicy    retry.Policy
 
-	queues map[uint64]*queue
-	writer shardWriter
-	Logger *log.Logger
+	queues  map[uint64]*queue
+	retries map[uint64]*nodeRetry
+	writer  shardWriter
+	Logger  *log.Logger
 }
 
 type ProcessorOptions struct {
 	MaxSize        int64
 	RetryRateLimit int64
+
+	// The queue of a node is retried after RetryInterval once its writes
+	// fail, backing off up to RetryMaxInterval while they keep failing.
+	RetryInterval    time.Duration
+	RetryMaxInterval time.Duration
+}
+
+// nodeRetry is when the queue of a node is processed next after failures.
+type nodeRetry struct {
+	failures int
+	next     time.Time
 }
 
 func NewProcessor(dir string, writer shardWriter, options ProcessorOptions) (*Processor, error) {
 	p := &Processor{
-		dir:    dir,
-		queues: map[uint64]*queue{},
-		writer: writer,
-		Logger: log.New(os.Stderr, "[handoff] ", log.LstdFlags),
+		dir:     dir,
+		queues:  map[uint64]*queue{},
+		retries: map[uint64]*nodeRetry{},
+		writer:  writer,
+		Logger:  log.New(os.Stderr, "[handoff] ", log.LstdFlags),
 	}
 	p.setOptions(options)
 
@@ -62,6 +77,19 @@ func (p *Processor) setOptions(options ProcessorOptions) {
 	if options.RetryRateLimit != 0 {
 		p.retryRateLimit = options.RetryRateLimit
 	}
+
+	p.retryPolicy = retry.Policy{
+		InitialInterval: DefaultRetryInterval,
+		MaxInterval:     DefaultRetryMaxInterval,
+		Jitter:          retry.DefaultJitter,
+		Retryable:       tsdb.IsRetryable,
+	}
+	if options.RetryInterval != 0 {
+		p.retryPolicy.InitialInterval = options.RetryInterval
+	}
+	if options.RetryMaxInterval != 0 {
+		p.retryPolicy.MaxInterval = options.RetryMaxInterval
+	}
 }
 
 func (p *Processor) loadQueues() error {
@@ -100,6 +128,7 @@ func (p *Processor) addQueue(nodeID uint64) (*queue, error) {
 		return nil, err
 	}
 	p.queues[nodeID] = queue
+	p.retries[nodeID] = &nodeRetry{}
 	return queue, nil
 }
 
@@ -122,7 +151,12 @@ func (p *Processor) Process() error {
 
 	res := make(chan error, len(p.queues))
 	for nodeID, q := range p.queues {
-		go func(nodeID uint64, q *queue) {
+		go func(nodeID uint64, q *queue, r *nodeRetry) {
+			// Wait for the backoff of a node that failed.
+			if time.Now().Before(r.next) {
+				res <- nil
+				return
+			}
 
 			// Log how many writes we successfully sent at the end
 			var sent int
@@ -143,7 +177,7 @@ func (p *Processor) Process() error {
 				}
 
 				// unmarshal the byte slice back to shard ID and points
-				shardID, points, err := p.unmarshalWrite(buf)
+				shardID, points, err := unmarshalWrite(buf)
 				if err != nil {
 					p.Logger.Printf("unmarshal write failed: %v", err)
 					if err := q.Advance(); err != nil {
@@ -153,11 +187,15 @@ func (p *Processor) Process() error {
 				}
 
 				// Try to send the write to the node
-				if err := p.writer.WriteShard(shardID, nodeID, points); err != nil && tsdb.IsRetryable(err) {
-					p.Logger.Printf("remote write failed: %v", err)
+				if err := p.writer.WriteShard(shardID, nodeID, points); p.retryPolicy.IsRetryable(err) {
+					r.failures++
+					d := p.retryPolicy.Backoff(r.failures)
+					r.next = time.Now().Add(d)
+					p.Logger.Printf("remote write failed, retrying node %d in %s: %v", nodeID, d, err)
 					res <- nil
 					break
 				}
+				r.failures, r.next = 0, time.Time{}
 
 				// If we get here, the write succeeded so advance the queue to the next item
 				if err := q.Advance(); err != nil {
@@ -174,7 +212,7 @@ func (p *Processor) Process() error {
 				time.Sleep(limiter.Delay())
 
 			}
-		}(nodeID, q)
+		}(nodeID, q, p.retries[nodeID])
 	}
 
 	for range p.queues {
@@ -190,21 +228,52 @@ func (p *Processor) marshalWrite(shardID uint64, points []tsdb.Point) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, shardID)
 	for _, p := range points {
-		b = append(b, []byte(p.String())...)
+		b = p.AppendString(b, tsdb.Nanosecond)
 		b = append(b, '\n')
 	}
 	return b
 }
 
-func (p *Processor) unmarshalWrite(b []byte) (uint64, []tsdb.Point, error) {
+func unmarshalWrite(b []byte) (uint64, []tsdb.Point, error) {
 	if len(b) < 8 {
 		return 0, nil, fmt.Errorf("too short: len = %d", len(b))
 	}
 	ownerID := binary.BigEndian.Uint64(b[:8])
-	points, err := tsdb.ParsePoints(b[8:])
+	// The points were accepted before they were queued, so their NaN and
+	// infinite values are kept.
+	points, err := tsdb.ParsePointsWithOptions(b[8:], tsdb.ParseOptions{DefaultTime: time.Now().UTC(), NonFinite: tsdb.NonFiniteKeep})
 	return ownerID, points, err
 }
 
+// ReadWrites reads the writes pending in the hinted handoff queues of a
+// stopped node without modifying them. It calls fn with each write in the
+// order it was queued for its node. Writes that cannot be unmarshaled are
+// skipped.
+func ReadWrites(dir string, fn func(nodeID, shardID uint64, points []tsdb.Point) error) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		nodeID, err := strconv.ParseUint(file.Name(), 10, 64)
+		if err != nil || !file.IsDir() {
+			continue
+		}
+
+		if err := readQueue(filepath.Join(dir, file.Name()), func(b []byte) error {
+			shardID, points, err := unmarshalWrite(b)
+			if err != nil {
+				return nil
+			}
+			return fn(nodeID, shardID, points)
+		}); err != nil {
+			return fmt.Errorf("node %d: %s", nodeID, err)
+		}
+	}
+	return nil
+}
+
 func (p *Processor) PurgeOlderThan(when time.Duration) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
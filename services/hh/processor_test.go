@@ -1,7 +1,10 @@
 package hh
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -24,7 +27,7 @@ func TestProcessorProcess(t *testing.T) {
 
 	// expected data to be queue and sent to the shardWriter
 	var expShardID, expNodeID, count = uint64(100), uint64(200), 0
-	pt := tsdb.NewPoint("cpu", tsdb.Tags{"foo": "bar"}, tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+	pt := tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"foo": "bar"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
 
 	sh := &fakeShardWriter{
 		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
@@ -78,3 +81,105 @@ func TestProcessorProcess(t *testing.T) {
 	}
 
 }
+
+func TestReadWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return nil },
+	}
+
+	p, err := NewProcessor(dir, sh, ProcessorOptions{MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("ReadWrites() failed to create processor: %v", err)
+	}
+
+	pt := tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"foo": "bar"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+
+	// Writes already sent to their node must not be read back.
+	if err := p.WriteShard(100, 200, []tsdb.Point{pt}); err != nil {
+		t.Fatalf("ReadWrites() failed to write points: %v", err)
+	} else if err := p.Process(); err != nil {
+		t.Fatalf("ReadWrites() failed to process writes: %v", err)
+	}
+	if err := p.WriteShard(101, 200, []tsdb.Point{pt}); err != nil {
+		t.Fatalf("ReadWrites() failed to write points: %v", err)
+	}
+	if err := p.WriteShard(102, 300, []tsdb.Point{pt, pt}); err != nil {
+		t.Fatalf("ReadWrites() failed to write points: %v", err)
+	}
+
+	var got []string
+	if err := ReadWrites(dir, func(nodeID, shardID uint64, points []tsdb.Point) error {
+		got = append(got, fmt.Sprintf("%d/%d/%d", nodeID, shardID, len(points)))
+		return nil
+	}); err != nil {
+		t.Fatalf("ReadWrites() failed: %v", err)
+	}
+
+	if exp := []string{"200/101/1", "300/102/2"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("ReadWrites() mismatch: got %v, exp %v", got, exp)
+	}
+}
+
+// Ensure a node whose writes fail isn't retried until its backoff has passed.
+func TestProcessorProcess_Backoff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var count int
+	var fail = true
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+			count++
+			if fail {
+				return fmt.Errorf("node down")
+			}
+			return nil
+		},
+	}
+
+	p, err := NewProcessor(dir, sh, ProcessorOptions{MaxSize: 1024, RetryInterval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Process() failed to create processor: %v", err)
+	}
+
+	pt := tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"foo": "bar"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+	if err := p.WriteShard(100, 200, []tsdb.Point{pt}); err != nil {
+		t.Fatalf("Process() failed to write points: %v", err)
+	}
+
+	// The first attempt fails and the node backs off.
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	} else if err := p.Process(); err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if exp := 1; count != exp {
+		t.Fatalf("Process() write count mismatch: got %v, exp %v", count, exp)
+	}
+
+	// Once the backoff has passed the write is sent again.
+	fail = false
+	time.Sleep(150 * time.Millisecond)
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if exp := 2; count != exp {
+		t.Fatalf("Process() write count mismatch: got %v, exp %v", count, exp)
+	}
+
+	// The queue is empty now.
+	if err := p.Process(); err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	} else if exp := 2; count != exp {
+		t.Fatalf("Process() write count mismatch: got %v, exp %v", count, exp)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -655,6 +656,57 @@ func (l *segment) readBytes(b []byte) error {
 	return nil
 }
 
+// readQueue calls fn with each block of the queue in dir that has not been
+// advanced past, without opening the queue or modifying its segments.
+func readQueue(dir string, fn func(b []byte) error) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	// Segment file names are numeric and are read in numeric order.
+	var ids []uint64
+	for _, file := range files {
+		if id, err := strconv.ParseUint(file.Name(), 10, 64); err == nil && !file.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(uint64Slice(ids))
+
+	for _, id := range ids {
+		path := filepath.Join(dir, strconv.FormatUint(id, 10))
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		} else if len(buf) < footerSize {
+			continue
+		}
+
+		// The footer holds the position of the current block.
+		end := int64(len(buf) - footerSize)
+		for pos := int64(btou64(buf[end:])); pos < end; {
+			if pos+8 > end {
+				return fmt.Errorf("segment %s: truncated block size at %d", path, pos)
+			}
+			sz := int64(btou64(buf[pos : pos+8]))
+			if pos+8+sz > end {
+				return fmt.Errorf("segment %s: truncated block at %d", path, pos)
+			}
+			if err := fn(buf[pos+8 : pos+8+sz]); err != nil {
+				return err
+			}
+			pos += 8 + sz
+		}
+	}
+	return nil
+}
+
+type uint64Slice []uint64
+
+func (a uint64Slice) Len() int           { return len(a) }
+func (a uint64Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a uint64Slice) Less(i, j int) bool { return a[i] < a[j] }
+
 func u64tob(v uint64) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, v)
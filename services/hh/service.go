@@ -41,8 +41,10 @@ func NewService(c Config, w shardWriter) *Service {
 		Logger: log.New(os.Stderr, "[handoff] ", log.LstdFlags),
 	}
 	processor, err := NewProcessor(c.Dir, w, ProcessorOptions{
-		MaxSize:        c.MaxSize,
-		RetryRateLimit: c.RetryRateLimit,
+		MaxSize:          c.MaxSize,
+		RetryRateLimit:   c.RetryRateLimit,
+		RetryInterval:    time.Duration(c.RetryInterval),
+		RetryMaxInterval: time.Duration(c.RetryMaxInterval),
 	})
 	if err != nil {
 		s.Logger.Fatalf("Failed to start hinted handoff processor: %v", err)
@@ -0,0 +1,170 @@
+package httpd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// DefaultCompressionMinSize is the default size in bytes a response must
+	// reach before it is compressed.
+	DefaultCompressionMinSize = 1024
+)
+
+// compressionWriters are the response encodings that can be configured, by
+// their Content-Encoding name.
+var compressionWriters = map[string]func(w io.Writer) compressWriter{
+	"gzip":   func(w io.Writer) compressWriter { return gzip.NewWriter(w) },
+	"snappy": func(w io.Writer) compressWriter { return snappy.NewBufferedWriter(w) },
+}
+
+// ValidateCompressionEncodings returns an error if an encoding isn't supported.
+func ValidateCompressionEncodings(encodings []string) error {
+	for _, enc := range encodings {
+		if _, ok := compressionWriters[enc]; !ok {
+			return fmt.Errorf("unknown compression encoding: %s", enc)
+		}
+	}
+	return nil
+}
+
+// compressWriter is a compressor that can flush buffered data.
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressResponseWriter buffers a response until it reaches the minimum size
+// and then compresses it. Responses ending or flushed before they reach the
+// minimum size are sent uncompressed.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	cw      compressWriter
+}
+
+// WriteHeader holds the status until the encoding is decided.
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.cw != nil {
+		return w.cw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes any buffered response and closes the compressor.
+func (w *compressResponseWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= w.minSize && len(w.buf) > 0); err != nil {
+			return err
+		}
+	}
+	if w.cw != nil {
+		return w.cw.Close()
+	}
+	return nil
+}
+
+// decide writes the header and the buffered response, compressed if requested.
+func (w *compressResponseWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+		w.cw = compressionWriters[w.encoding](w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// acceptedEncoding returns the first of the encodings accepted by a request,
+// or a blank string if the request doesn't accept any.
+func acceptedEncoding(r *http.Request, encodings []string) string {
+	accepted := make(map[string]bool)
+	for _, s := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(s, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+
+		// Encodings with a zero quality value are refused.
+		ok := true
+		for _, p := range parts[1:] {
+			if q := strings.Replace(p, " ", "", -1); strings.HasPrefix(q, "q=") {
+				ok = strings.Trim(q[2:], "0.") != ""
+			}
+		}
+		accepted[name] = ok
+	}
+
+	for _, enc := range encodings {
+		if accepted[enc] {
+			return enc
+		}
+	}
+	return ""
+}
+
+// compressFilter compresses responses with the first configured encoding the
+// client accepts once they reach the minimum size.
+func compressFilter(inner http.Handler, h *Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := acceptedEncoding(r, h.CompressionEncodings)
+		if enc == "" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: enc, minSize: h.CompressionMinSize}
+		defer cw.Close()
+		inner.ServeHTTP(cw, r)
+	})
+}
@@ -1,5 +1,11 @@
 package httpd
 
+import (
+	"fmt"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
 type Config struct {
 	Enabled          bool   `toml:"enabled"`
 	BindAddress      string `toml:"bind-address"`
@@ -9,6 +15,42 @@ type Config struct {
 	PprofEnabled     bool   `toml:"pprof-enabled"`
 	HttpsEnabled     bool   `toml:"https-enabled"`
 	HttpsCertificate string `toml:"https-certificate"`
+
+	// CompressionEncodings are the encodings responses can be compressed
+	// with, in order of preference: "gzip" and "snappy". Responses smaller
+	// than CompressionMinSize bytes are sent uncompressed. No encodings
+	// disables compression.
+	CompressionEncodings []string `toml:"compression-encodings"`
+	CompressionMinSize   int      `toml:"compression-min-size"`
+
+	// Validation limits what written points may contain.
+	Validation tsdb.ValidationConfig `toml:"validation"`
+
+	// PointPooling recycles the points of line protocol writes once every
+	// shard write using them has finished.
+	PointPooling bool `toml:"point-pooling"`
+
+	// SeriesKeyInternSize is the number of series whose keys are shared by
+	// the points of line protocol writes instead of each point holding its
+	// own. Zero disables interning.
+	SeriesKeyInternSize int `toml:"series-key-intern-size"`
+
+	// DuplicateFields is what happens to lines setting a field more than
+	// once: "reject", "first" to keep the first value or "last" to keep the
+	// last one. Lines are rejected when it's empty.
+	DuplicateFields string `toml:"duplicate-fields"`
+
+	// NonFiniteFields is what happens to NaN and infinite float fields:
+	// "reject" the line, "drop" the field, "clamp" infinite values to the
+	// largest float or "keep" them. Lines are rejected when it's empty.
+	NonFiniteFields string `toml:"non-finite-fields"`
+
+	// CheckKeys rejects lines whose measurement, tag keys, tag values or
+	// field keys aren't valid UTF-8 or are over MaxKeySize and
+	// MaxTagValueSize bytes. Zero sizes are tsdb.DefaultMaxKeySize.
+	CheckKeys       bool `toml:"check-keys"`
+	MaxKeySize      int  `toml:"max-key-size"`
+	MaxTagValueSize int  `toml:"max-tag-value-size"`
 }
 
 func NewConfig() Config {
@@ -18,5 +60,27 @@ func NewConfig() Config {
 		LogEnabled:       true,
 		HttpsEnabled:     false,
 		HttpsCertificate: "/etc/ssl/influxdb.pem",
+
+		CompressionEncodings: []string{"gzip"},
+		CompressionMinSize:   DefaultCompressionMinSize,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if err := ValidateCompressionEncodings(c.CompressionEncodings); err != nil {
+		return err
+	}
+	if _, err := tsdb.ParseDuplicateFieldPolicy(c.DuplicateFields); err != nil {
+		return fmt.Errorf("duplicate-fields: %s", err)
+	}
+	if _, err := tsdb.ParseNonFinitePolicy(c.NonFiniteFields); err != nil {
+		return fmt.Errorf("non-finite-fields: %s", err)
+	}
+	if c.MaxKeySize < 0 {
+		return fmt.Errorf("max-key-size: must not be negative: %d", c.MaxKeySize)
+	} else if c.MaxTagValueSize < 0 {
+		return fmt.Errorf("max-tag-value-size: must not be negative: %d", c.MaxTagValueSize)
 	}
+	return c.Validation.Validate()
 }
@@ -1,6 +1,7 @@
 package httpd_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -19,6 +20,12 @@ write-tracing = true
 pprof-enabled = true
 https-enabled = true
 https-certificate = "/dev/null"
+compression-encodings = ["snappy", "gzip"]
+compression-min-size = 100
+
+[validation]
+max-tags-per-point = 10
+reject-nan-inf = true
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -40,6 +47,24 @@ https-certificate = "/dev/null"
 		t.Fatalf("unexpected https enabled: %v", c.HttpsEnabled)
 	} else if c.HttpsCertificate != "/dev/null" {
 		t.Fatalf("unexpected https certificate: %v", c.HttpsCertificate)
+	} else if !reflect.DeepEqual(c.CompressionEncodings, []string{"snappy", "gzip"}) {
+		t.Fatalf("unexpected compression encodings: %v", c.CompressionEncodings)
+	} else if c.CompressionMinSize != 100 {
+		t.Fatalf("unexpected compression min size: %v", c.CompressionMinSize)
+	} else if c.Validation.MaxTagsPerPoint != 10 || !c.Validation.RejectNaNInf {
+		t.Fatalf("unexpected validation: %#v", c.Validation)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.CompressionEncodings = []string{"deflate"}
+	if err := c.Validate(); err == nil || err.Error() != "unknown compression encoding: deflate" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.CompressionEncodings, c.NonFiniteFields = nil, "zero"
+	if err := c.Validate(); err == nil || err.Error() != `non-finite-fields: invalid non-finite field policy: "zero"` {
+		t.Fatalf("unexpected error: %v", err)
 	}
 }
 
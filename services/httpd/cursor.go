@@ -0,0 +1,160 @@
+package httpd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// ErrInvalidCursor is returned when a cursor token can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// queryCursor is the position in a paged query after the last returned value.
+// It holds everything needed to resume the query so pages can be requested
+// from any node, even after a restart.
+type queryCursor struct {
+	Query    string `json:"q"`
+	Database string `json:"db,omitempty"`
+	PageSize int    `json:"n"`
+	Series   string `json:"s"`
+	Time     int64  `json:"t"`
+
+	// Index is the number of values of the series at Time that were
+	// returned, so values with the same time aren't skipped or repeated.
+	Index int `json:"i,omitempty"`
+}
+
+// encodeCursor returns an opaque token for c.
+func encodeCursor(c *queryCursor) string {
+	buf, _ := json.Marshal(c)
+	return base64.URLEncoding.EncodeToString(buf)
+}
+
+// decodeCursor decodes a token returned by encodeCursor.
+func decodeCursor(s string) (*queryCursor, error) {
+	buf, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c queryCursor
+	if err := json.Unmarshal(buf, &c); err != nil || c.Query == "" || c.PageSize <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// queryPager limits the results of a SELECT statement to a page of values.
+// Series are returned in the same order every time the query runs so the
+// page is resumed after the series, time and index among values with that
+// time of the last value of the previous page.
+//
+// Each page runs the query again and skips the values of the previous pages,
+// so later pages cost more than earlier ones, and points written between
+// pages can move values from one page to another.
+type queryPager struct {
+	query    string
+	database string
+	size     int
+
+	after   *queryCursor // position of the previous page, nil for the first page
+	found   bool         // true once the series of the previous page was reached
+	skipped int          // values at the time of the previous page skipped so far
+
+	n          int    // number of values in the page
+	lastSeries string // series of the last value in the page
+	lastTime   int64  // time of the last value in the page
+	lastIndex  int    // values of the series at lastTime, including previous pages
+
+	// Set once the page is full and more values exist.
+	next *queryCursor
+}
+
+// filter removes the values of r that aren't part of the page.
+func (p *queryPager) filter(r *influxql.Result) {
+	var rows []*influxql.Row
+	for _, row := range r.Series {
+		if p.next != nil {
+			break
+		}
+
+		// Skip series returned by previous pages.
+		id := seriesID(row)
+		if p.after != nil && !p.found {
+			if id != p.after.Series {
+				continue
+			}
+			p.found = true
+		}
+
+		var values [][]interface{}
+		for _, v := range row.Values {
+			t := valueTime(v)
+			if p.after != nil && id == p.after.Series {
+				if t < p.after.Time {
+					continue
+				} else if t == p.after.Time && p.skipped < p.after.Index {
+					p.skipped++
+					continue
+				}
+			}
+
+			// There are more values than fit in the page so return a cursor.
+			if p.n == p.size {
+				p.next = &queryCursor{
+					Query:    p.query,
+					Database: p.database,
+					PageSize: p.size,
+					Series:   p.lastSeries,
+					Time:     p.lastTime,
+					Index:    p.lastIndex,
+				}
+				break
+			}
+
+			values = append(values, v)
+			p.n++
+			if id == p.lastSeries && t == p.lastTime {
+				p.lastIndex++
+			} else {
+				p.lastSeries, p.lastTime, p.lastIndex = id, t, 1
+			}
+		}
+
+		if len(values) > 0 {
+			row.Values = values
+			rows = append(rows, row)
+		}
+	}
+	r.Series = rows
+}
+
+// seriesID returns a string identifying the series of a row.
+func seriesID(row *influxql.Row) string {
+	keys := make([]string, 0, len(row.Tags))
+	for k := range row.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	a := []string{row.Name}
+	for _, k := range keys {
+		a = append(a, k+"="+row.Tags[k])
+	}
+	return strings.Join(a, ",")
+}
+
+// valueTime returns the time of a value in nanoseconds.
+func valueTime(v []interface{}) int64 {
+	if len(v) > 0 {
+		if t, ok := v[0].(time.Time); ok {
+			return t.UnixNano()
+		}
+	}
+	return 0
+}
@@ -1,7 +1,6 @@
 package httpd
 
 import (
-	"bytes"
 	"compress/gzip"
 	"encoding/json"
 	"errors"
@@ -11,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -19,11 +19,13 @@ import (
 	"github.com/bmizerany/pat"
 	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/clock"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/continuous_querier"
 	"github.com/influxdb/influxdb/tsdb"
+	"github.com/influxdb/influxdb/tsdb/csv"
 	"github.com/influxdb/influxdb/uuid"
 )
 
@@ -34,7 +36,6 @@ const (
 )
 
 // TODO: Standard response headers (see: HeaderHandler)
-// TODO: Compression (see: CompressionHeaderHandler)
 
 // TODO: Check HTTP response codes: 400, 401, 403, 409.
 
@@ -61,18 +62,52 @@ type Handler struct {
 
 	QueryExecutor interface {
 		Authorize(u *meta.UserInfo, q *influxql.Query, db string) error
-		ExecuteQuery(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error)
+		ExecuteQueryWithTrace(q *influxql.Query, db string, chunkSize int, traceID string) (<-chan *influxql.Result, error)
 	}
 
 	PointsWriter interface {
 		WritePoints(p *cluster.WritePointsRequest) error
 	}
 
+	SeriesDropper interface {
+		DropSeries(database string, keys []string) error
+	}
+
 	ContinuousQuerier continuous_querier.ContinuousQuerier
 
+	// Validator rejects written points that break its rules. Optional.
+	Validator *tsdb.Validator
+
+	// PointPooling parses line protocol writes into pooled points, which
+	// are returned to the pool once they're written.
+	PointPooling bool
+
+	// KeyInterner shares the series keys of line protocol writes. Optional.
+	KeyInterner *tsdb.KeyInterner
+
+	// DuplicateFields is what happens to lines setting a field more than once.
+	DuplicateFields tsdb.DuplicateFieldPolicy
+
+	// NonFiniteFields is what happens to NaN and infinite float fields.
+	NonFiniteFields tsdb.NonFinitePolicy
+
+	// Clock is the time of written points without a timestamp.
+	Clock clock.Clock
+
+	// KeyLimits rejects line protocol writes with keys or tag values that
+	// aren't valid UTF-8 or are too large. Optional.
+	KeyLimits *tsdb.KeyLimits
+
+	// Encodings responses can be compressed with, in order of preference,
+	// and the size in bytes a response must reach to be compressed.
+	CompressionEncodings []string
+	CompressionMinSize   int
+
 	Logger         *log.Logger
 	loggingEnabled bool // Log every HTTP access.
 	WriteTrace     bool // Detailed logging of write path
+
+	stats *tsdb.Statistics
 }
 
 // NewHandler returns a new instance of handler with routes.
@@ -83,6 +118,9 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool) *Handler
 		Logger:                log.New(os.Stderr, "[http] ", log.LstdFlags),
 		loggingEnabled:        loggingEnabled,
 		WriteTrace:            writeTrace,
+		CompressionEncodings:  []string{"gzip"},
+		Clock:                 clock.New(),
+		stats:                 tsdb.NewStatistics("httpd", "httpd", nil),
 	}
 
 	h.SetRoutes([]route{
@@ -102,6 +140,22 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool) *Handler
 			"write", // Data-ingest route.
 			"POST", "/write", true, true, h.serveWrite,
 		},
+		route{
+			"write-multi", // Satisfy CORS checks.
+			"OPTIONS", "/write/multi", true, true, h.serveOptions,
+		},
+		route{
+			"write-multi", // Data-ingest route for batches with several destinations.
+			"POST", "/write/multi", true, true, h.serveWriteMulti,
+		},
+		route{
+			"write-csv", // Satisfy CORS checks.
+			"OPTIONS", "/write/csv", true, true, h.serveOptions,
+		},
+		route{
+			"write-csv", // Data-ingest route for CSV files.
+			"POST", "/write/csv", true, true, h.serveWriteCSV,
+		},
 		route{ // Ping
 			"ping",
 			"GET", "/ping", true, true, h.servePing,
@@ -110,6 +164,14 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool) *Handler
 			"ping-head",
 			"HEAD", "/ping", true, true, h.servePing,
 		},
+		route{ // List series matching a filter
+			"series",
+			"GET", "/series", true, true, h.serveSeries,
+		},
+		route{ // Drop series matching a filter
+			"series-delete",
+			"DELETE", "/series", true, true, h.serveDeleteSeries,
+		},
 		route{ // Tell data node to run CQs that should be run
 			"process_continuous_queries",
 			"POST", "/data/process_continuous_queries", false, false, h.serveProcessContinuousQueries,
@@ -133,7 +195,7 @@ func (h *Handler) SetRoutes(routes []route) {
 		}
 
 		if r.gzipped {
-			handler = gzipFilter(handler)
+			handler = compressFilter(handler, h)
 		}
 		handler = versionHeader(handler, h)
 		handler = cors(handler)
@@ -192,10 +254,28 @@ func (h *Handler) serveProcessContinuousQueries(w http.ResponseWriter, r *http.R
 
 // serveQuery parses an incoming query and, if valid, executes the query.
 func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	h.stats.Add("queryReq", 1)
+
 	q := r.URL.Query()
 	pretty := q.Get("pretty") == "true"
 
 	qp := strings.TrimSpace(q.Get("q"))
+	db := q.Get("db")
+
+	// A cursor resumes the query of a previous page.
+	var cursor *queryCursor
+	if s := q.Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			httpError(w, err.Error(), pretty, http.StatusBadRequest)
+			return
+		} else if qp != "" && qp != c.Query {
+			httpError(w, "cursor is for a different query", pretty, http.StatusBadRequest)
+			return
+		}
+		cursor, qp, db = c, c.Query, c.Database
+	}
+
 	if qp == "" {
 		httpError(w, `missing required parameter "q"`, pretty, http.StatusBadRequest)
 		return
@@ -203,8 +283,10 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 
 	epoch := strings.TrimSpace(q.Get("epoch"))
 
+	// With types=true the rows have the data type of each column.
+	types := q.Get("types") == "true"
+
 	p := influxql.NewParser(strings.NewReader(qp))
-	db := q.Get("db")
 
 	// Parse query from query string.
 	query, err := p.ParseQuery()
@@ -232,8 +314,34 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 		}
 	}
 
+	// Page the results if a page size was requested or a cursor was passed in.
+	var pager *queryPager
+	if s := q.Get("page_size"); s != "" || cursor != nil {
+		pager = &queryPager{query: qp, database: db, after: cursor}
+		if cursor != nil {
+			pager.size = cursor.PageSize
+			pager.lastSeries, pager.lastTime, pager.lastIndex = cursor.Series, cursor.Time, cursor.Index
+		}
+		if s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				httpError(w, "invalid page_size: "+s, pretty, http.StatusBadRequest)
+				return
+			}
+			pager.size = n
+		}
+
+		if len(query.Statements) != 1 {
+			httpError(w, "paged queries must have exactly one statement", pretty, http.StatusBadRequest)
+			return
+		} else if _, ok := query.Statements[0].(*influxql.SelectStatement); !ok {
+			httpError(w, "only SELECT statements can be paged", pretty, http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Parse chunk size. Use default if not provided or unparsable.
-	chunked := (q.Get("chunked") == "true")
+	chunked := (q.Get("chunked") == "true") && pager == nil
 	chunkSize := DefaultChunkSize
 	if chunked {
 		if n, err := strconv.ParseInt(q.Get("chunk_size"), 10, 64); err == nil {
@@ -243,7 +351,7 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 
 	// Execute query.
 	w.Header().Add("content-type", "application/json")
-	results, err := h.QueryExecutor.ExecuteQuery(query, db, chunkSize)
+	results, err := h.QueryExecutor.ExecuteQueryWithTrace(query, db, chunkSize, r.Header.Get("Request-Id"))
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -263,6 +371,13 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 			continue
 		}
 
+		// Drop values outside of the requested page.
+		if pager != nil && r.Err == nil {
+			if pager.filter(r); len(r.Series) == 0 {
+				continue
+			}
+		}
+
 		// if requested, convert result timestamps to epoch
 		if epoch != "" {
 			convertToEpoch(r, epoch)
@@ -270,6 +385,9 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 
 		// Write out result immediately if chunked.
 		if chunked {
+			if types {
+				setColumnTypes(r)
+			}
 			w.Write(MarshalJSON(Response{
 				Results: []*influxql.Result{r},
 			}, pretty))
@@ -302,6 +420,7 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 			// Append remaining rows as new rows.
 			r.Series = r.Series[rowsMerged:]
 			cr.Series = append(cr.Series, r.Series...)
+			cr.Partial = cr.Partial || r.Partial
 		} else {
 			resp.Results = append(resp.Results, r)
 		}
@@ -309,19 +428,56 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 
 	// If it's not chunked we buffered everything in memory, so write it out
 	if !chunked {
+		// Types are set once the rows are merged so a column that's null in
+		// the first chunk gets the type of its values in the later ones.
+		if types {
+			for _, r := range resp.Results {
+				setColumnTypes(r)
+			}
+		}
+		if pager != nil {
+			if len(resp.Results) == 0 {
+				resp.Results = append(resp.Results, &influxql.Result{})
+			}
+			if pager.next != nil {
+				resp.Cursor = encodeCursor(pager.next)
+			}
+		}
 		w.Write(MarshalJSON(resp, pretty))
 	}
 }
 
 func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	h.stats.Add("writeReq", 1)
+
+	b, err := h.readWriteBody(r)
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
+	// The format parameter selects a format other than the line protocol.
+	switch r.FormValue("format") {
+	case "", "line", "json-lines":
+	default:
+		h.writeError(w, influxql.Result{Err: fmt.Errorf("unknown format: %q", r.FormValue("format"))}, http.StatusBadRequest)
+		return
+	}
+
+	if r.Header.Get("Content-Type") == "application/json" {
+		h.serveWriteJSON(w, r, b, user)
+		return
+	}
+	h.serveWriteLine(w, r, b, user)
+}
 
-	// Handle gzip decoding of the body
+// readWriteBody reads the body of a write request, decoding it if it is gzipped.
+func (h *Handler) readWriteBody(r *http.Request) ([]byte, error) {
 	body := r.Body
 	if r.Header.Get("Content-encoding") == "gzip" {
 		b, err := gzip.NewReader(r.Body)
 		if err != nil {
-			h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
-			return
+			return nil, err
 		}
 		body = b
 	}
@@ -332,82 +488,226 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user *meta.
 		if h.WriteTrace {
 			h.Logger.Print("write handler unable to read bytes from request body")
 		}
-		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
-		return
+		return nil, err
 	}
 	if h.WriteTrace {
 		h.Logger.Printf("write body received by handler: %s", string(b))
 	}
+	h.stats.Add("writeReqBytes", int64(len(b)))
+	return b, nil
+}
 
-	if r.Header.Get("Content-Type") == "application/json" {
-		h.serveWriteJSON(w, r, b, user)
+// serveWriteMulti receives JSON batches for any number of databases and
+// retention policies, as sent by relays and proxies. Batches are grouped by
+// destination and each destination is written with a single request, so a
+// destination either has all of its points accepted or none of them. The
+// response reports the outcome of each destination in the order they first
+// appear in the request.
+func (h *Handler) serveWriteMulti(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	h.stats.Add("writeReq", 1)
+	h.stats.Add("writeMultiReq", 1)
+
+	b, err := h.readWriteBody(r)
+	if err != nil {
+		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	}
-	h.serveWriteLine(w, r, b, user)
-}
 
-// serveWriteJSON receives incoming series data in JSON and writes it to the database.
-func (h *Handler) serveWriteJSON(w http.ResponseWriter, r *http.Request, body []byte, user *meta.UserInfo) {
-	var bp client.BatchPoints
-	var dec *json.Decoder
-
-	dec = json.NewDecoder(bytes.NewReader(body))
+	start := time.Now()
+	var mbp client.MultiBatchPoints
+	if err := json.Unmarshal(b, &mbp); err != nil {
+		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+	parse := time.Since(start)
 
-	if err := dec.Decode(&bp); err != nil {
-		if err.Error() == "EOF" {
-			w.WriteHeader(http.StatusOK)
+	consistency := cluster.ConsistencyLevelOne
+	if s := r.URL.Query().Get("consistency"); s != "" {
+		if consistency, err = cluster.ParseConsistencyLevel(s); err != nil {
+			resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 			return
 		}
+	}
+
+	ack, err := cluster.ParseAckMode(r.URL.Query().Get("ack"))
+	if err != nil {
+		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
+	// Group the points by destination. A destination that fails validation
+	// is rejected as a whole, including points from its other batches.
+	type destination struct{ db, rp string }
+	var dests []destination
+	seen := make(map[destination]bool)
+	points := make(map[destination][]tsdb.Point)
+	errs := make(map[destination]error)
+	for _, bp := range mbp.Batches {
+		d := destination{bp.Database, bp.RetentionPolicy}
+		if !seen[d] {
+			seen[d] = true
+			dests = append(dests, d)
+			errs[d] = h.authorizeWrite(bp.Database, user)
+		}
+		if errs[d] != nil {
+			continue
+		}
+
+		start := time.Now()
+		pts, err := NormalizeBatchPoints(bp)
+		parse += time.Since(start)
+		if err != nil {
+			errs[d] = err
+			continue
+		}
+		points[d] = append(points[d], pts...)
+	}
+
+	var resp client.MultiWriteResponse
+	for _, d := range dests {
+		result := client.WriteResult{Database: d.db, RetentionPolicy: d.rp}
+		if err := errs[d]; err != nil {
+			result.Err = err.Error()
+			resp.Results = append(resp.Results, result)
+			continue
+		}
+
+		req := &cluster.WritePointsRequest{
+			Database:         d.db,
+			RetentionPolicy:  d.rp,
+			ConsistencyLevel: consistency,
+			AckMode:          ack,
+			Points:           points[d],
+			TraceID:          r.Header.Get("Request-Id"),
+			Timings:          &cluster.WriteTimings{Parse: parse},
+		}
+		if err := h.PointsWriter.WritePoints(req); err != nil {
+			result.Err = err.Error()
+		} else {
+			result.Points = len(req.Points)
+			h.stats.Add("pointsWritten", int64(len(req.Points)))
+		}
+		h.traceWriteTimings(req)
+		resp.Results = append(resp.Results, result)
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(MarshalJSON(resp, false))
+}
+
+// authorizeWrite returns an error if user cannot write to database or if the
+// database does not exist.
+func (h *Handler) authorizeWrite(database string, user *meta.UserInfo) error {
+	if database == "" {
+		return fmt.Errorf("database is required")
+	}
+
+	if di, err := h.MetaStore.Database(database); err != nil {
+		return fmt.Errorf("metastore database error: %s", err)
+	} else if di == nil {
+		return fmt.Errorf("database not found: %q", database)
+	}
+
+	if h.requireAuthentication && user == nil {
+		return fmt.Errorf("user is required to write to database %q", database)
+	}
+
+	if h.requireAuthentication && !user.Authorize(influxql.WritePrivilege, database) {
+		return fmt.Errorf("%q user is not authorized to write to database %q", user.Name, database)
+	}
+	return nil
+}
+
+// serveWriteJSON receives incoming series data in JSON and writes it to the database.
+// The database and retention policy default to the "db" and "rp" parameters
+// for bodies that don't name them, such as the series format of 0.8.
+func (h *Handler) serveWriteJSON(w http.ResponseWriter, r *http.Request, body []byte, user *meta.UserInfo) {
+	start := time.Now()
+	batch, err := tsdb.ParsePointsJSON(body, tsdb.ParseOptions{
+		DefaultTime: h.Clock.Now().UTC(),
+		Precision:   tsdb.Precision(r.FormValue("precision")),
+		Validator:   h.Validator,
+	})
+	if err == io.EOF {
+		w.WriteHeader(http.StatusOK)
+		return
+	} else if err != nil {
 		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	}
+	parse := time.Since(start)
 
-	if bp.Database == "" {
+	database, rp := batch.Database, batch.RetentionPolicy
+	if database == "" {
+		database = r.FormValue("db")
+	}
+	if rp == "" {
+		rp = r.FormValue("rp")
+	}
+	points := batch.Points
+
+	if database == "" {
 		resultError(w, influxql.Result{Err: fmt.Errorf("database is required")}, http.StatusBadRequest)
 		return
 	}
 
-	if di, err := h.MetaStore.Database(bp.Database); err != nil {
+	if di, err := h.MetaStore.Database(database); err != nil {
 		resultError(w, influxql.Result{Err: fmt.Errorf("metastore database error: %s", err)}, http.StatusInternalServerError)
 		return
 	} else if di == nil {
-		resultError(w, influxql.Result{Err: fmt.Errorf("database not found: %q", bp.Database)}, http.StatusNotFound)
+		resultError(w, influxql.Result{Err: fmt.Errorf("database not found: %q", database)}, http.StatusNotFound)
 		return
 	}
 
 	if h.requireAuthentication && user == nil {
-		resultError(w, influxql.Result{Err: fmt.Errorf("user is required to write to database %q", bp.Database)}, http.StatusUnauthorized)
+		resultError(w, influxql.Result{Err: fmt.Errorf("user is required to write to database %q", database)}, http.StatusUnauthorized)
 		return
 	}
 
-	if h.requireAuthentication && !user.Authorize(influxql.WritePrivilege, bp.Database) {
-		resultError(w, influxql.Result{Err: fmt.Errorf("%q user is not authorized to write to database %q", user.Name, bp.Database)}, http.StatusUnauthorized)
+	if h.requireAuthentication && !user.Authorize(influxql.WritePrivilege, database) {
+		resultError(w, influxql.Result{Err: fmt.Errorf("%q user is not authorized to write to database %q", user.Name, database)}, http.StatusUnauthorized)
 		return
 	}
 
-	points, err := NormalizeBatchPoints(bp)
+	ack, err := cluster.ParseAckMode(r.URL.Query().Get("ack"))
 	if err != nil {
 		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	}
 
 	// Convert the json batch struct to a points writer struct
-	if err := h.PointsWriter.WritePoints(&cluster.WritePointsRequest{
-		Database:         bp.Database,
-		RetentionPolicy:  bp.RetentionPolicy,
+	req := &cluster.WritePointsRequest{
+		Database:         database,
+		RetentionPolicy:  rp,
 		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		AckMode:          ack,
 		Points:           points,
-	}); influxdb.IsClientError(err) {
+		TraceID:          r.Header.Get("Request-Id"),
+		Timings:          &cluster.WriteTimings{Parse: parse},
+	}
+	err = h.PointsWriter.WritePoints(req)
+	h.traceWriteTimings(req)
+	if influxdb.IsClientError(err) {
 		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	} else if err != nil {
 		resultError(w, influxql.Result{Err: err}, http.StatusInternalServerError)
 		return
 	}
+	h.stats.Add("pointsWritten", int64(len(points)))
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// traceWriteTimings logs how long each phase of a write took if write tracing
+// is enabled.
+func (h *Handler) traceWriteTimings(req *cluster.WritePointsRequest) {
+	if h.WriteTrace && req.Timings != nil {
+		h.Logger.Printf("write timings for %d points to %s: %s", len(req.Points), req.Database, req.Timings)
+	}
+}
+
 func (h *Handler) writeError(w http.ResponseWriter, result influxql.Result, statusCode int) {
 	w.WriteHeader(statusCode)
 	w.Write([]byte(result.Err.Error()))
@@ -418,7 +718,8 @@ func (h *Handler) writeError(w http.ResponseWriter, result influxql.Result, stat
 func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []byte, user *meta.UserInfo) {
 	// Some clients may not set the content-type header appropriately and send JSON with a non-json
 	// content-type.  If the body looks JSON, try to handle it as as JSON instead
-	if len(body) > 0 {
+	jsonLines := r.FormValue("format") == "json-lines"
+	if len(body) > 0 && !jsonLines {
 		var i int
 		for {
 			// JSON requests must start w/ an opening bracket
@@ -435,13 +736,34 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 		}
 	}
 
-	precision := r.FormValue("precision")
-	if precision == "" {
-		precision = "n"
+	precision, err := tsdb.ParsePrecision(r.FormValue("precision"))
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
 	}
 
-	points, err := tsdb.ParsePointsWithPrecision(body, time.Now().UTC(), precision)
-	if err != nil {
+	parsePoints := tsdb.ParsePointsWithOptions
+	if jsonLines {
+		parsePoints = tsdb.ParsePointsJSONLines
+	}
+
+	// With partial=true the points of well formed lines are written even if
+	// other lines are rejected.
+	start := time.Now()
+	points, err := parsePoints(body, tsdb.ParseOptions{
+		DefaultTime:     h.Clock.Now().UTC(),
+		Precision:       precision,
+		PartialOK:       r.FormValue("partial") == "true",
+		Validator:       h.Validator,
+		Pooled:          h.PointPooling,
+		Interner:        h.KeyInterner,
+		DuplicateFields: h.DuplicateFields,
+		KeyLimits:       h.KeyLimits,
+		NonFinite:       h.NonFiniteFields,
+	})
+	parse := time.Since(start)
+	partialErr, _ := err.(*tsdb.PartialParseError)
+	if err != nil && partialErr == nil {
 		if err.Error() == "EOF" {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -450,6 +772,16 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 		return
 	}
 
+	// Points written with a ttl expire before the end of the retention policy.
+	if s := r.FormValue("ttl"); s != "" {
+		ttl, err := influxql.ParseDuration(s)
+		if err != nil || ttl <= 0 {
+			h.writeError(w, influxql.Result{Err: fmt.Errorf("invalid ttl: %q", s)}, http.StatusBadRequest)
+			return
+		}
+		tsdb.SetTTL(points, ttl)
+	}
+
 	database := r.FormValue("db")
 	if database == "" {
 		h.writeError(w, influxql.Result{Err: fmt.Errorf("database is required")}, http.StatusBadRequest)
@@ -487,23 +819,328 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 		consistency = cluster.ConsistencyLevelQuorum
 	}
 
+	// Determine whether to wait for remote replicas.
+	ack, err := cluster.ParseAckMode(r.Form.Get("ack"))
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
 	// Write points.
-	if err := h.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+	req := &cluster.WritePointsRequest{
 		Database:         database,
 		RetentionPolicy:  r.FormValue("rp"),
 		ConsistencyLevel: consistency,
+		AckMode:          ack,
 		Points:           points,
-	}); influxdb.IsClientError(err) {
+		TraceID:          r.Header.Get("Request-Id"),
+		Timings:          &cluster.WriteTimings{Parse: parse},
+	}
+	if h.PointPooling && !jsonLines {
+		// Shard writes may still use the points after WritePoints returns.
+		req.Done = func() {
+			for _, p := range points {
+				tsdb.PutPoint(p)
+			}
+		}
+	}
+	err = h.PointsWriter.WritePoints(req)
+	h.traceWriteTimings(req)
+	if influxdb.IsClientError(err) {
 		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	} else if err != nil {
 		h.writeError(w, influxql.Result{Err: err}, http.StatusInternalServerError)
 		return
 	}
+	h.stats.Add("pointsWritten", int64(len(points)))
+
+	// The rejected lines are reported once the rest of the batch is written.
+	if partialErr != nil {
+		h.stats.Add("pointsRejected", int64(len(partialErr.Errors)))
+		h.writeError(w, influxql.Result{Err: partialErr}, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// csvBatchSize is the number of rows of a CSV file written at a time.
+const csvBatchSize = 5000
+
+// serveWriteCSV writes the rows of a CSV file as points. The "measurement",
+// "measurement-column", "tags", "fields", "types", "time" and "time-format"
+// parameters map the columns to the points, as described by csv.Spec. The body is converted while it's read
+// and written in batches, so the batches before a malformed row are written
+// even though the request fails.
+func (h *Handler) serveWriteCSV(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	h.stats.Add("writeReq", 1)
+	h.stats.Add("writeCSVReq", 1)
+
+	types, err := csv.ParseTypes(r.FormValue("types"))
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
+	spec := csv.Spec{
+		Measurement:       r.FormValue("measurement"),
+		MeasurementColumn: r.FormValue("measurement-column"),
+		Tags:              csv.SplitColumns(r.FormValue("tags")),
+		Fields:            csv.SplitColumns(r.FormValue("fields")),
+		Time:              r.FormValue("time"),
+		TimeFormat:        r.FormValue("time-format"),
+		Types:             types,
+	}
+	if err := spec.Validate(); err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
+	database := r.FormValue("db")
+	if database == "" {
+		h.writeError(w, influxql.Result{Err: fmt.Errorf("database is required")}, http.StatusBadRequest)
+		return
+	}
+
+	if di, err := h.MetaStore.Database(database); err != nil {
+		h.writeError(w, influxql.Result{Err: fmt.Errorf("metastore database error: %s", err)}, http.StatusInternalServerError)
+		return
+	} else if di == nil {
+		h.writeError(w, influxql.Result{Err: fmt.Errorf("database not found: %q", database)}, http.StatusNotFound)
+		return
+	}
+
+	if h.requireAuthentication && user == nil {
+		h.writeError(w, influxql.Result{Err: fmt.Errorf("user is required to write to database %q", database)}, http.StatusUnauthorized)
+		return
+	}
+
+	if h.requireAuthentication && !user.Authorize(influxql.WritePrivilege, database) {
+		h.writeError(w, influxql.Result{Err: fmt.Errorf("%q user is not authorized to write to database %q", user.Name, database)}, http.StatusUnauthorized)
+		return
+	}
 
+	consistency := cluster.ConsistencyLevelOne
+	if s := r.FormValue("consistency"); s != "" {
+		var err error
+		if consistency, err = cluster.ParseConsistencyLevel(s); err != nil {
+			h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	body := r.Body
+	if r.Header.Get("Content-encoding") == "gzip" {
+		b, err := gzip.NewReader(r.Body)
+		if err != nil {
+			h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+			return
+		}
+		body = b
+	}
+	defer body.Close()
+
+	write := func(points []tsdb.Point) error {
+		req := &cluster.WritePointsRequest{
+			Database:         database,
+			RetentionPolicy:  r.FormValue("rp"),
+			ConsistencyLevel: consistency,
+			Points:           points,
+			TraceID:          r.Header.Get("Request-Id"),
+		}
+		if err := h.PointsWriter.WritePoints(req); err != nil {
+			return err
+		}
+		h.stats.Add("pointsWritten", int64(len(points)))
+		return nil
+	}
+
+	cr := csv.NewReader(body, spec)
+	points := make([]tsdb.Point, 0, csvBatchSize)
+	for cr.Next() {
+		if err := h.Validator.Validate(cr.Point()); err != nil {
+			h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+			return
+		}
+		if points = append(points, cr.Point()); len(points) < csvBatchSize {
+			continue
+		}
+		if err := write(points); err != nil {
+			h.writeCSVError(w, err)
+			return
+		}
+		points = points[:0]
+	}
+	if err := cr.Err(); err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
+	if len(points) > 0 {
+		if err := write(points); err != nil {
+			h.writeCSVError(w, err)
+			return
+		}
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// writeCSVError responds with an error returned by the points writer.
+func (h *Handler) writeCSVError(w http.ResponseWriter, err error) {
+	if influxdb.IsClientError(err) {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+	h.writeError(w, influxql.Result{Err: err}, http.StatusInternalServerError)
+}
+
+// serveSeries returns the keys of the series matching the "from" and "where"
+// parameters. Only series indexed on this node are listed.
+func (h *Handler) serveSeries(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	q := r.URL.Query()
+	pretty := q.Get("pretty") == "true"
+
+	query, err := seriesQuery("SHOW SERIES", q)
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	keys, code, err := h.matchSeries(r, query, user)
+	if err != nil {
+		httpError(w, err.Error(), pretty, code)
+		return
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.Write(MarshalJSON(SeriesResponse{Series: keys, Count: len(keys)}, pretty))
+}
+
+// serveDeleteSeries drops the series matching the "from" and "where"
+// parameters, or the series keys passed as "key" parameters, from every node.
+// With "dry_run=true" only the number of matching series is returned.
+func (h *Handler) serveDeleteSeries(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	q := r.URL.Query()
+	pretty := q.Get("pretty") == "true"
+	dryRun := q.Get("dry_run") == "true"
+
+	if h.SeriesDropper == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	if q.Get("db") == "" {
+		httpError(w, `missing required parameter "db"`, pretty, http.StatusBadRequest)
+		return
+	}
+
+	keys := q["key"]
+	if len(keys) > 0 && (q.Get("from") != "" || q.Get("where") != "") {
+		httpError(w, `"key" can't be combined with "from" or "where"`, pretty, http.StatusBadRequest)
+		return
+	}
+
+	// Deleting requires the same privileges as a DROP SERIES statement.
+	if h.requireAuthentication {
+		query := &influxql.Query{Statements: influxql.Statements{&influxql.DropSeriesStatement{}}}
+		if err := h.QueryExecutor.Authorize(user, query, q.Get("db")); err != nil {
+			httpError(w, "error authorizing query: "+err.Error(), pretty, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Resolve the filter to series keys.
+	if len(keys) == 0 {
+		query, err := seriesQuery("SHOW SERIES", q)
+		if err != nil {
+			httpError(w, err.Error(), pretty, http.StatusBadRequest)
+			return
+		}
+
+		var code int
+		if keys, code, err = h.matchSeries(r, query, user); err != nil {
+			httpError(w, err.Error(), pretty, code)
+			return
+		}
+	}
+
+	if !dryRun {
+		if err := h.SeriesDropper.DropSeries(q.Get("db"), keys); err != nil {
+			httpError(w, err.Error(), pretty, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.Write(MarshalJSON(SeriesResponse{Count: len(keys), DryRun: dryRun}, pretty))
+}
+
+// matchSeries executes a SHOW SERIES query and returns the keys of the series
+// it matched. On failure the HTTP status code for the error is also returned.
+func (h *Handler) matchSeries(r *http.Request, query *influxql.Query, user *meta.UserInfo) ([]string, int, error) {
+	db := r.URL.Query().Get("db")
+	if db == "" {
+		return nil, http.StatusBadRequest, errors.New(`missing required parameter "db"`)
+	}
+
+	if h.requireAuthentication {
+		if err := h.QueryExecutor.Authorize(user, query, db); err != nil {
+			return nil, http.StatusUnauthorized, fmt.Errorf("error authorizing query: %s", err)
+		}
+	}
+
+	results, err := h.QueryExecutor.ExecuteQueryWithTrace(query, db, DefaultChunkSize, r.Header.Get("Request-Id"))
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	keys := []string{}
+	for result := range results {
+		if result == nil {
+			continue
+		} else if result.Err != nil {
+			return nil, http.StatusBadRequest, result.Err
+		}
+
+		for _, row := range result.Series {
+			for _, v := range row.Values {
+				if key, ok := v[0].(string); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys, http.StatusOK, nil
+}
+
+// seriesQuery builds a single statement from a statement prefix and the
+// "from" and "where" parameters.
+func seriesQuery(prefix string, q url.Values) (*influxql.Query, error) {
+	s := prefix
+	if from := q.Get("from"); from != "" {
+		s += " FROM " + from
+	}
+	if where := q.Get("where"); where != "" {
+		s += " WHERE " + where
+	}
+
+	query, err := influxql.ParseQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing series filter: %s", err)
+	} else if len(query.Statements) != 1 {
+		return nil, errors.New("series filter must be a single statement")
+	}
+	return query, nil
+}
+
+// SeriesResponse is the response of the series listing and delete endpoints.
+type SeriesResponse struct {
+	Series []string `json:"series,omitempty"`
+	Count  int      `json:"count"`
+	DryRun bool     `json:"dryRun,omitempty"`
+}
+
 // serveOptions returns an empty response to comply with OPTIONS pre-flight requests
 func (h *Handler) serveOptions(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
@@ -514,6 +1151,13 @@ func (h *Handler) servePing(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// setColumnTypes sets the column types of the rows of a result.
+func setColumnTypes(r *influxql.Result) {
+	for _, row := range r.Series {
+		row.SetTypes()
+	}
+}
+
 // convertToEpoch converts result timestamps from time.Time to the specified epoch.
 func convertToEpoch(r *influxql.Result, epoch string) {
 	divisor := int64(1)
@@ -652,34 +1296,6 @@ func authenticate(inner func(http.ResponseWriter, *http.Request, *meta.UserInfo)
 	})
 }
 
-type gzipResponseWriter struct {
-	io.Writer
-	http.ResponseWriter
-}
-
-func (w gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
-}
-
-func (w gzipResponseWriter) Flush() {
-	w.Writer.(*gzip.Writer).Flush()
-}
-
-// determines if the client can accept compressed responses, and encodes accordingly
-func gzipFilter(inner http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			inner.ServeHTTP(w, r)
-			return
-		}
-		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		gzw := gzipResponseWriter{Writer: gz, ResponseWriter: w}
-		inner.ServeHTTP(gzw, r)
-	})
-}
-
 // versionHeader takes a HTTP handler and returns a HTTP handler
 // and adds the X-INFLUXBD-VERSION header to outgoing responses.
 func versionHeader(inner http.Handler, h *Handler) http.Handler {
@@ -759,6 +1375,9 @@ func recovery(inner http.Handler, name string, weblog *log.Logger) http.Handler
 type Response struct {
 	Results []*influxql.Result
 	Err     error
+
+	// Token to request the next page of a paged query. Blank on the last page.
+	Cursor string
 }
 
 // MarshalJSON encodes a Response struct into JSON.
@@ -767,6 +1386,7 @@ func (r Response) MarshalJSON() ([]byte, error) {
 	var o struct {
 		Results []*influxql.Result `json:"results,omitempty"`
 		Err     string             `json:"error,omitempty"`
+		Cursor  string             `json:"cursor,omitempty"`
 	}
 
 	// Copy fields to output struct.
@@ -774,6 +1394,7 @@ func (r Response) MarshalJSON() ([]byte, error) {
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
+	o.Cursor = r.Cursor
 
 	return json.Marshal(&o)
 }
@@ -783,6 +1404,7 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 	var o struct {
 		Results []*influxql.Result `json:"results,omitempty"`
 		Err     string             `json:"error,omitempty"`
+		Cursor  string             `json:"cursor,omitempty"`
 	}
 
 	err := json.Unmarshal(b, &o)
@@ -790,6 +1412,7 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Results = o.Results
+	r.Cursor = o.Cursor
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -838,15 +1461,12 @@ func NormalizeBatchPoints(bp client.BatchPoints) ([]tsdb.Point, error) {
 			}
 		}
 
-		if p.Measurement == "" {
-			return points, fmt.Errorf("missing measurement")
-		}
-
-		if len(p.Fields) == 0 {
-			return points, fmt.Errorf("missing fields")
-		}
 		// Need to convert from a client.Point to a influxdb.Point
-		points = append(points, tsdb.NewPoint(p.Measurement, p.Tags, p.Fields, p.Time))
+		pt, err := tsdb.NewPointChecked(p.Measurement, tsdb.NewTags(p.Tags), p.Fields, p.Time)
+		if err != nil {
+			return points, err
+		}
+		points = append(points, pt)
 	}
 
 	return points, nil
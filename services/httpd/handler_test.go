@@ -1,18 +1,24 @@
 package httpd_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/httpd"
@@ -155,6 +161,69 @@ func TestHandler_Query(t *testing.T) {
 	}
 }
 
+// Ensure the handler compresses query responses the client accepts once they
+// reach the minimum size.
+func TestHandler_Query_Compression(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+		return NewResultChan(&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series0"}}}), nil
+	}
+	body := `{"results":[{"series":[{"name":"series0"}]}]}`
+
+	// Small responses are sent uncompressed.
+	h.CompressionMinSize = 1024
+	req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if enc := w.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("unexpected encoding: %s", enc)
+	} else if w.Body.String() != body {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	// Larger responses are gzipped.
+	h.CompressionMinSize = 10
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("unexpected encoding: %s", enc)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	} else if b, err := ioutil.ReadAll(gz); err != nil {
+		t.Fatal(err)
+	} else if string(b) != body {
+		t.Fatalf("unexpected body: %s", b)
+	}
+
+	// The first configured encoding the client accepts is used.
+	h.CompressionEncodings = []string{"snappy", "gzip"}
+	req.Header.Set("Accept-Encoding", "gzip, snappy")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if enc := w.Header().Get("Content-Encoding"); enc != "snappy" {
+		t.Fatalf("unexpected encoding: %s", enc)
+	} else if b, err := ioutil.ReadAll(snappy.NewReader(bytes.NewReader(w.Body.Bytes()))); err != nil {
+		t.Fatal(err)
+	} else if string(b) != body {
+		t.Fatalf("unexpected body: %s", b)
+	}
+
+	// Refused encodings aren't used.
+	req.Header.Set("Accept-Encoding", "gzip, snappy;q=0")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("unexpected encoding: %s", enc)
+	}
+}
+
 // Ensure the handler merges results from the same statement.
 func TestHandler_Query_MergeResults(t *testing.T) {
 	h := NewHandler(false)
@@ -174,6 +243,118 @@ func TestHandler_Query_MergeResults(t *testing.T) {
 	}
 }
 
+// Ensure the handler returns the type of each column with types=true, including
+// columns that are null in the first chunk.
+func TestHandler_Query_Types(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+		return NewResultChan(
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "cpu", Columns: []string{"time", "value", "host", "ok"}, Values: [][]interface{}{{time.Unix(1, 0), nil, "a", nil}}}}},
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "cpu", Columns: []string{"time", "value", "host", "ok"}, Values: [][]interface{}{{time.Unix(2, 0), 1.5, "b", nil}}}}},
+		), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu&epoch=s&types=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"cpu","columns":["time","value","host","ok"],"types":["time","float","string","unknown"],"values":[[1,null,"a",null],[2,1.5,"b",null]]}]}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler can page through the results of a query with cursors.
+func TestHandler_Query_Paged(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+		if db != "foo" {
+			t.Fatalf("unexpected db: %s", db)
+		}
+		return NewResultChan(
+			&influxql.Result{Series: influxql.Rows{
+				{Name: "cpu", Tags: map[string]string{"host": "a"}, Values: [][]interface{}{{time.Unix(0, 1)}, {time.Unix(0, 2)}, {time.Unix(0, 3)}}},
+				{Name: "cpu", Tags: map[string]string{"host": "b"}, Values: [][]interface{}{{time.Unix(0, 4)}, {time.Unix(0, 5)}}},
+			}},
+		), nil
+	}
+
+	var cursor string
+	for i, exp := range []string{
+		`{"results":[{"series":[{"name":"cpu","tags":{"host":"a"},"values":[[1],[2]]}]}],"cursor":`,
+		`{"results":[{"series":[{"name":"cpu","tags":{"host":"a"},"values":[[3]]},{"name":"cpu","tags":{"host":"b"},"values":[[4]]}]}],"cursor":`,
+		`{"results":[{"series":[{"name":"cpu","tags":{"host":"b"},"values":[[5]]}]}]}`,
+	} {
+		path := "/query?db=foo&q=SELECT+*+FROM+cpu&epoch=ns&page_size=2"
+		if cursor != "" {
+			path = "/query?epoch=ns&cursor=" + cursor
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%d. unexpected status: %d", i, w.Code)
+		} else if !strings.HasPrefix(w.Body.String(), exp) {
+			t.Fatalf("%d. unexpected body: %s", i, w.Body.String())
+		}
+
+		var resp httpd.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		cursor = resp.Cursor
+	}
+}
+
+// Ensure values with the same time aren't skipped or repeated across pages.
+func TestHandler_Query_Paged_DuplicateTimes(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+		return NewResultChan(
+			&influxql.Result{Series: influxql.Rows{
+				{Name: "cpu", Values: [][]interface{}{{time.Unix(0, 1), 1}, {time.Unix(0, 2), 2}, {time.Unix(0, 2), 3}, {time.Unix(0, 2), 4}, {time.Unix(0, 3), 5}}},
+			}},
+		), nil
+	}
+
+	var cursor string
+	for i, exp := range []string{
+		`{"results":[{"series":[{"name":"cpu","values":[[1,1],[2,2]]}]}],"cursor":`,
+		`{"results":[{"series":[{"name":"cpu","values":[[2,3],[2,4]]}]}],"cursor":`,
+		`{"results":[{"series":[{"name":"cpu","values":[[3,5]]}]}]}`,
+	} {
+		path := "/query?db=foo&q=SELECT+*+FROM+cpu&epoch=ns&page_size=2"
+		if cursor != "" {
+			path = "/query?epoch=ns&cursor=" + cursor
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%d. unexpected status: %d", i, w.Code)
+		} else if !strings.HasPrefix(w.Body.String(), exp) {
+			t.Fatalf("%d. unexpected body: %s", i, w.Body.String())
+		}
+
+		var resp httpd.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		cursor = resp.Cursor
+	}
+}
+
+// Ensure the handler returns a status 400 if a paged query isn't a single SELECT.
+func TestHandler_Query_Paged_ErrNotSelect(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SHOW+MEASUREMENTS&page_size=2", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"error":"only SELECT statements can be paged"}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
 // Ensure the handler can parse chunked and chunk size query parameters.
 func TestHandler_Query_Chunked(t *testing.T) {
 	h := NewHandler(false)
@@ -264,6 +445,318 @@ func TestHandler_Query_ErrResult(t *testing.T) {
 	}
 }
 
+// Ensure the handler lists the series matching a filter.
+func TestHandler_Series(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+		if q.String() != `SHOW SERIES FROM cpu WHERE host = 'a'` {
+			t.Fatalf("unexpected query: %s", q.String())
+		} else if db != `foo` {
+			t.Fatalf("unexpected db: %s", db)
+		}
+		return NewResultChan(&influxql.Result{Series: influxql.Rows{{
+			Name:    "cpu",
+			Columns: []string{"_key", "host"},
+			Values:  [][]interface{}{{"cpu,host=a", "a"}},
+		}}}), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/series?db=foo&from=cpu&where=host%3D%27a%27", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"series":["cpu,host=a"],"count":1}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler rejects series filters that add statements.
+func TestHandler_Series_ErrMultipleStatements(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/series?db=foo&from=cpu%3B+DROP+DATABASE+foo", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure a dry run of a series delete returns the count without dropping anything.
+func TestHandler_DeleteSeries_DryRun(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+		return NewResultChan(&influxql.Result{Series: influxql.Rows{{
+			Name:    "cpu",
+			Columns: []string{"_key", "host"},
+			Values:  [][]interface{}{{"cpu,host=a", "a"}, {"cpu,host=b", "b"}},
+		}}}), nil
+	}
+	h.SeriesDropper.DropSeriesFn = func(database string, keys []string) error {
+		t.Fatal("unexpected drop")
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/series?db=foo&from=cpu&dry_run=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"count":2,"dryRun":true}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler drops series by key.
+func TestHandler_DeleteSeries_Keys(t *testing.T) {
+	h := NewHandler(false)
+	var dropped []string
+	h.SeriesDropper.DropSeriesFn = func(database string, keys []string) error {
+		if database != "foo" {
+			t.Fatalf("unexpected database: %s", database)
+		}
+		dropped = keys
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/series?db=foo&key=cpu%2Chost%3Da&key=cpu%2Chost%3Db", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"count":2}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	} else if !reflect.DeepEqual(dropped, []string{"cpu,host=a", "cpu,host=b"}) {
+		t.Fatalf("unexpected keys: %v", dropped)
+	}
+}
+
+// Ensure a multi-destination write routes each destination separately and
+// reports errors per destination.
+func TestHandler_WriteMulti(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		if name == "missing" {
+			return nil, nil
+		}
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	written := make(map[string]int)
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		if p.Database == "fail" {
+			return errors.New("marker")
+		}
+		written[p.Database+"."+p.RetentionPolicy] += len(p.Points)
+		return nil
+	}
+
+	body := `{"batches": [
+		{"database": "db0", "points": [{"measurement": "cpu", "fields": {"value": 1}}]},
+		{"database": "db1", "retentionPolicy": "rp0", "points": [{"measurement": "cpu", "fields": {"value": 1}}]},
+		{"database": "missing", "points": [{"measurement": "cpu", "fields": {"value": 1}}]},
+		{"database": "db0", "points": [{"measurement": "mem", "fields": {"value": 1}}]},
+		{"database": "fail", "points": [{"measurement": "cpu", "fields": {"value": 1}}]},
+		{"database": "db2", "points": [{"measurement": "cpu", "fields": {"value": 1}}]},
+		{"database": "db2", "points": [{"measurement": "cpu"}]}
+	]}`
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/write/multi", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	var resp client.MultiWriteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(resp.Results, []client.WriteResult{
+		{Database: "db0", Points: 2},
+		{Database: "db1", RetentionPolicy: "rp0", Points: 1},
+		{Database: "missing", Err: `database not found: "missing"`},
+		{Database: "fail", Err: "marker"},
+		{Database: "db2", Err: "missing fields"},
+	}) {
+		t.Fatalf("unexpected results: %s", w.Body.String())
+	} else if err := resp.Error(); err == nil || err.Error() != `missing: database not found: "missing"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The destination with an invalid batch must not have written anything.
+	if !reflect.DeepEqual(written, map[string]int{"db0.": 2, "db1.rp0": 1}) {
+		t.Fatalf("unexpected writes: %v", written)
+	}
+}
+
+// Ensure the well formed lines of a batch are written with partial=true.
+func TestHandler_Write_Partial(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var written int
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		written += len(p.Points)
+		return nil
+	}
+
+	body := "cpu value=1\ncpu value=\nmem value=2"
+
+	// Nothing is written without partial.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if written != 0 {
+		t.Fatalf("unexpected points written: %d", written)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&partial=true", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if written != 2 {
+		t.Fatalf("unexpected points written: %d", written)
+	} else if !strings.Contains(w.Body.String(), "1 lines rejected: line 2") {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler writes points in the JSON lines format.
+func TestHandler_Write_JSONLines(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var points []tsdb.Point
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		points = append(points, p.Points...)
+		return nil
+	}
+
+	body := `{"measurement":"cpu","tags":{"host":"a"},"fields":{"value":1},"time":10}`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&format=json-lines&precision=s", strings.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if len(points) != 1 || points[0].String() != "cpu,host=a value=1.0 10000000000" {
+		t.Fatalf("unexpected points: %v", points)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&format=xml", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure series written in the JSON format of 0.8 are written to the database
+// of the request.
+func TestHandler_Write_JSONSeries(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var req *cluster.WritePointsRequest
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		req = p
+		return nil
+	}
+
+	body := `[{"name":"cpu","columns":["time","value"],"points":[[10,1]]}]`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/write?db=foo&precision=s", strings.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if req.Database != "foo" {
+		t.Fatalf("unexpected database: %s", req.Database)
+	} else if len(req.Points) != 1 || req.Points[0].String() != "cpu value=1.0 10000000000" {
+		t.Fatalf("unexpected points: %v", req.Points)
+	}
+}
+
+// Ensure points written with a ttl get an expiry time.
+func TestHandler_Write_TTL(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var points []tsdb.Point
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		points = append(points, p.Points...)
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&precision=s&ttl=1h", strings.NewReader("cpu value=1 10")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if len(points) != 1 {
+		t.Fatalf("unexpected points: %v", points)
+	} else if v := points[0].Fields()[tsdb.ExpiresField]; v != int64(3610*time.Second) {
+		t.Fatalf("unexpected expiry: %v", v)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&ttl=-1h", strings.NewReader("cpu value=1 10")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler rejects writes with an unknown precision.
+func TestHandler_Write_InvalidPrecision(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&precision=ns", strings.NewReader("cpu value=1 1")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if !strings.Contains(w.Body.String(), `unknown precision "ns"`) {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler writes the rows of a CSV file as points.
+func TestHandler_WriteCSV(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var points []tsdb.Point
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		points = append(points, p.Points...)
+		return nil
+	}
+
+	body := "host,value,ts\nserverA,1,10\nserverB,2.5,20\n"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write/csv?db=foo&measurement=cpu&tags=host&time=ts&time-format=s", strings.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if len(points) != 2 {
+		t.Fatalf("unexpected points written: %d", len(points))
+	} else if s := points[1].String(); s != "cpu,host=serverB value=2.5 20000000000" {
+		t.Fatalf("unexpected point: %s", s)
+	}
+
+	// A malformed row fails the request.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write/csv?db=foo&measurement=cpu&time=ts&time-format=s", strings.NewReader("value,ts\n1,x\n")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if !strings.Contains(w.Body.String(), "row 1: invalid timestamp") {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	// The measurement is required.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write/csv?db=foo", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
 func TestMarshalJSON_NoPretty(t *testing.T) {
 	if b := httpd.MarshalJSON(struct {
 		Name string `json:"name"`
@@ -306,7 +799,7 @@ func TestNormalizeBatchPoints(t *testing.T) {
 				},
 			},
 			p: []tsdb.Point{
-				tsdb.NewPoint("cpu", map[string]string{"region": "useast"}, map[string]interface{}{"value": 1.0}, now),
+				tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"region": "useast"}), map[string]interface{}{"value": 1.0}, now),
 			},
 		},
 		{
@@ -318,7 +811,7 @@ func TestNormalizeBatchPoints(t *testing.T) {
 				},
 			},
 			p: []tsdb.Point{
-				tsdb.NewPoint("cpu", map[string]string{"region": "useast"}, map[string]interface{}{"value": 1.0}, now),
+				tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"region": "useast"}), map[string]interface{}{"value": 1.0}, now),
 			},
 		},
 		{
@@ -331,8 +824,8 @@ func TestNormalizeBatchPoints(t *testing.T) {
 				},
 			},
 			p: []tsdb.Point{
-				tsdb.NewPoint("cpu", map[string]string{"day": "monday", "region": "useast"}, map[string]interface{}{"value": 1.0}, now),
-				tsdb.NewPoint("memory", map[string]string{"day": "monday"}, map[string]interface{}{"value": 2.0}, now),
+				tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"day": "monday", "region": "useast"}), map[string]interface{}{"value": 1.0}, now),
+				tsdb.NewPoint("memory", tsdb.NewTags(map[string]string{"day": "monday"}), map[string]interface{}{"value": 2.0}, now),
 			},
 		},
 	}
@@ -361,6 +854,8 @@ type Handler struct {
 	MetaStore     HandlerMetaStore
 	QueryExecutor HandlerQueryExecutor
 	TSDBStore     HandlerTSDBStore
+	SeriesDropper HandlerSeriesDropper
+	PointsWriter  HandlerPointsWriter
 }
 
 // NewHandler returns a new instance of Handler.
@@ -370,6 +865,8 @@ func NewHandler(requireAuthentication bool) *Handler {
 	}
 	h.Handler.MetaStore = &h.MetaStore
 	h.Handler.QueryExecutor = &h.QueryExecutor
+	h.Handler.SeriesDropper = &h.SeriesDropper
+	h.Handler.PointsWriter = &h.PointsWriter
 	h.Handler.Version = "0.0.0"
 	return h
 }
@@ -403,7 +900,7 @@ func (e *HandlerQueryExecutor) Authorize(u *meta.UserInfo, q *influxql.Query, db
 	return e.AuthorizeFn(u, q, db)
 }
 
-func (e *HandlerQueryExecutor) ExecuteQuery(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+func (e *HandlerQueryExecutor) ExecuteQueryWithTrace(q *influxql.Query, db string, chunkSize int, traceID string) (<-chan *influxql.Result, error) {
 	return e.ExecuteQueryFn(q, db, chunkSize)
 }
 
@@ -416,6 +913,24 @@ func (h *HandlerTSDBStore) CreateMapper(shardID uint64, query string, chunkSize
 	return h.CreateMapperFn(shardID, query, chunkSize)
 }
 
+// HandlerSeriesDropper is a mock implementation of Handler.SeriesDropper.
+type HandlerSeriesDropper struct {
+	DropSeriesFn func(database string, keys []string) error
+}
+
+func (d *HandlerSeriesDropper) DropSeries(database string, keys []string) error {
+	return d.DropSeriesFn(database, keys)
+}
+
+// HandlerPointsWriter is a mock implementation of Handler.PointsWriter.
+type HandlerPointsWriter struct {
+	WritePointsFn func(p *cluster.WritePointsRequest) error
+}
+
+func (w *HandlerPointsWriter) WritePoints(p *cluster.WritePointsRequest) error {
+	return w.WritePointsFn(p)
+}
+
 // MustNewRequest returns a new HTTP request. Panic on error.
 func MustNewRequest(method, urlStr string, body io.Reader) *http.Request {
 	r, err := http.NewRequest(method, urlStr, body)
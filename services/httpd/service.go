@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/influxdb/influxdb/tsdb"
 )
 
 // Service manages the listener and handler for an HTTP endpoint.
@@ -38,6 +40,20 @@ func NewService(c Config) *Service {
 		Logger: log.New(os.Stderr, "[httpd] ", log.LstdFlags),
 	}
 	s.Handler.Logger = s.Logger
+	s.Handler.CompressionEncodings = c.CompressionEncodings
+	s.Handler.CompressionMinSize = c.CompressionMinSize
+	s.Handler.PointPooling = c.PointPooling
+	if c.SeriesKeyInternSize > 0 {
+		s.Handler.KeyInterner = tsdb.NewKeyInterner(c.SeriesKeyInternSize)
+	}
+	if c.CheckKeys {
+		s.Handler.KeyLimits = &tsdb.KeyLimits{MaxKeySize: c.MaxKeySize, MaxTagValueSize: c.MaxTagValueSize}
+	}
+
+	// The config is validated before the service is created.
+	s.Handler.Validator, _ = tsdb.NewValidator(c.Validation)
+	s.Handler.DuplicateFields, _ = tsdb.ParseDuplicateFieldPolicy(c.DuplicateFields)
+	s.Handler.NonFiniteFields, _ = tsdb.ParseNonFinitePolicy(c.NonFiniteFields)
 	return s
 }
 
@@ -0,0 +1,95 @@
+// Package input defines the interface implemented by services that receive
+// points over an input protocol, such as graphite or collectd, so the server
+// can manage them uniformly. Services for custom protocols are added by
+// registering a constructor under the name of their config section.
+package input
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Service is an input service managed by the server.
+type Service interface {
+	Open() error
+	Close() error
+
+	// SetPointsWriter sets the writer received points are written to. It's
+	// called before the service is opened.
+	SetPointsWriter(w PointsWriter)
+
+	// Statistics returns the counters kept by the service.
+	Statistics() *tsdb.Statistics
+}
+
+// MetaStoreSetter is implemented by input services which need the meta store,
+// usually to create their target database when opened.
+type MetaStoreSetter interface {
+	SetMetaStore(m MetaStore)
+}
+
+// PointsWriter writes points received by an input service.
+type PointsWriter interface {
+	WritePoints(p *cluster.WritePointsRequest) error
+}
+
+// MetaStore is the part of the meta store used by input services.
+type MetaStore interface {
+	WaitForLeader(d time.Duration) error
+	CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error)
+}
+
+// NewFunc returns the services configured by an input's config section. The
+// section is decoded into a service specific config by calling decode.
+// Disabled services should not be returned.
+type NewFunc func(decode func(v interface{}) error) ([]Service, error)
+
+// inputs is the registry of input constructors by name.
+var inputs = struct {
+	mu sync.RWMutex
+	m  map[string]NewFunc
+}{m: make(map[string]NewFunc)}
+
+// Register registers the constructor for an input. Inputs are usually
+// registered from an init function and configured in an "[input.<name>]"
+// section of the config file.
+func Register(name string, fn NewFunc) {
+	inputs.mu.Lock()
+	defer inputs.mu.Unlock()
+
+	if _, ok := inputs.m[name]; ok {
+		panic("input already registered: " + name)
+	}
+	inputs.m[name] = fn
+}
+
+// Names returns the names of registered inputs in sorted order.
+func Names() []string {
+	inputs.mu.RLock()
+	defer inputs.mu.RUnlock()
+
+	a := make([]string, 0, len(inputs.m))
+	for name := range inputs.m {
+		a = append(a, name)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// New returns the services of a registered input.
+func New(name string, decode func(v interface{}) error) ([]Service, error) {
+	inputs.mu.RLock()
+	fn := inputs.m[name]
+	inputs.mu.RUnlock()
+
+	if fn == nil {
+		return nil, fmt.Errorf("unknown input: %s", name)
+	}
+	return fn(decode)
+}
@@ -0,0 +1,59 @@
+package input_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/influxdb/influxdb/services/input"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure a registered input is built from its decoded config section.
+func TestNew(t *testing.T) {
+	input.Register("test", func(decode func(v interface{}) error) ([]input.Service, error) {
+		var c struct{ Count int }
+		if err := decode(&c); err != nil {
+			return nil, err
+		}
+		a := make([]input.Service, c.Count)
+		for i := range a {
+			a[i] = &Service{}
+		}
+		return a, nil
+	})
+
+	if names := input.Names(); !reflect.DeepEqual(names, []string{"test"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	a, err := input.New("test", func(v interface{}) error {
+		v.(*struct{ Count int }).Count = 2
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(a) != 2 {
+		t.Fatalf("unexpected service count: %d", len(a))
+	}
+
+	// Decoding errors are returned.
+	if _, err := input.New("test", func(v interface{}) error { return errors.New("marker") }); err == nil || err.Error() != "marker" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure an error is returned for an input that isn't registered.
+func TestNew_Unknown(t *testing.T) {
+	if _, err := input.New("no_such_input", nil); err == nil || err.Error() != "unknown input: no_such_input" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Service is a mock input service.
+type Service struct{}
+
+func (s *Service) Open() error                          { return nil }
+func (s *Service) Close() error                         { return nil }
+func (s *Service) SetPointsWriter(w input.PointsWriter) {}
+func (s *Service) Statistics() *tsdb.Statistics         { return nil }
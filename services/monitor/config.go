@@ -9,17 +9,49 @@ import (
 const (
 	// DefaultStatisticsWriteInterval is the interval of time between internal stats are written
 	DefaultStatisticsWriteInterval = 1 * time.Minute
+
+	// DefaultDatabase is the database monitoring points are written to.
+	DefaultDatabase = "_internal"
+
+	// DefaultCardinalityCheckInterval is the interval between tag cardinality checks.
+	DefaultCardinalityCheckInterval = 10 * time.Minute
+
+	// DefaultIntegritySampleSize is the number of series of each shard checked
+	// by an integrity check.
+	DefaultIntegritySampleSize = 100
 )
 
 // Config represents a configuration for the monitor.
 type Config struct {
 	Enabled       bool          `toml:"enabled"`
 	WriteInterval toml.Duration `toml:"write-interval"`
+
+	// Database is where monitoring points, such as cardinality alerts, are
+	// written. It's created when it's first written to.
+	Database string `toml:"database"`
+
+	// The number of values of each tag key is checked every
+	// CardinalityCheckInterval. An alert is raised when a tag key has more
+	// than MaxTagValues values or gained more than MaxTagValuesGrowth values
+	// since the previous check. Zero disables either threshold.
+	CardinalityCheckInterval toml.Duration `toml:"cardinality-check-interval"`
+	MaxTagValues             int           `toml:"max-tag-values"`
+	MaxTagValuesGrowth       int           `toml:"max-tag-values-growth"`
+
+	// A sample of IntegritySampleSize series of every local shard is checked
+	// for corruption every IntegrityCheckInterval. The report of each shard
+	// is logged and written to the monitoring database. Zero disables the
+	// checks.
+	IntegrityCheckInterval toml.Duration `toml:"integrity-check-interval"`
+	IntegritySampleSize    int           `toml:"integrity-sample-size"`
 }
 
 func NewConfig() Config {
 	return Config{
-		Enabled:       false,
-		WriteInterval: toml.Duration(DefaultStatisticsWriteInterval),
+		Enabled:                  false,
+		WriteInterval:            toml.Duration(DefaultStatisticsWriteInterval),
+		Database:                 DefaultDatabase,
+		CardinalityCheckInterval: toml.Duration(DefaultCardinalityCheckInterval),
+		IntegritySampleSize:      DefaultIntegritySampleSize,
 	}
 }
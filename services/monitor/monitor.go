@@ -1,12 +1,262 @@
 package monitor
 
-// Monitor represents a TSDB monitoring service.
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Monitor represents a TSDB monitoring service. It checks the number of
+// values of every tag key and raises an alert, logged and written to the
+// monitoring database, when a tag key exceeds the configured thresholds. It
+// also checks the local shards for corruption and reports the results the
+// same way.
 type Monitor struct {
-	Store interface{}
+	MetaStore interface {
+		Databases() ([]meta.DatabaseInfo, error)
+		CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error)
+	}
+	TSDBStore interface {
+		DatabaseIndex(name string) *tsdb.DatabaseIndex
+		CheckIntegrity(n int) []*tsdb.IntegrityReport
+	}
+	PointsWriter interface {
+		WritePoints(p *cluster.WritePointsRequest) error
+	}
+
+	config Config
+	wg     sync.WaitGroup
+	done   chan struct{}
+
+	// cardinality is the number of values of each tag key at the last check.
+	cardinality map[tagKey]int
+
+	Logger *log.Logger
+}
+
+type tagKey struct {
+	database    string
+	measurement string
+	key         string
+}
+
+// NewMonitor returns a new instance of Monitor.
+func NewMonitor(c Config) *Monitor {
+	return &Monitor{
+		config:      c,
+		done:        make(chan struct{}),
+		cardinality: make(map[tagKey]int),
+		Logger:      log.New(os.Stderr, "[monitor] ", log.LstdFlags),
+	}
+}
+
+// Open starts the cardinality checks if a threshold is set and the integrity
+// checks if their interval is set.
+func (m *Monitor) Open() error {
+	if m.config.MaxTagValues > 0 || m.config.MaxTagValuesGrowth > 0 {
+		if m.config.CardinalityCheckInterval <= 0 {
+			return fmt.Errorf("cardinality check interval must be positive")
+		}
+
+		m.Logger.Printf("Starting tag cardinality checks every %s", time.Duration(m.config.CardinalityCheckInterval))
+		m.wg.Add(1)
+		go m.checkCardinality()
+	}
+
+	if m.config.IntegrityCheckInterval > 0 {
+		m.Logger.Printf("Starting integrity checks every %s", time.Duration(m.config.IntegrityCheckInterval))
+		m.wg.Add(1)
+		go m.checkIntegrity()
+	}
+	return nil
 }
 
-func (m *Monitor) Open() error  { return nil }
-func (m *Monitor) Close() error { return nil }
+// Close stops the monitor.
+func (m *Monitor) Close() error {
+	close(m.done)
+	m.wg.Wait()
+	return nil
+}
+
+func (m *Monitor) checkCardinality() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(m.config.CardinalityCheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			if err := m.CheckCardinality(); err != nil {
+				m.Logger.Printf("tag cardinality check failed: %s", err)
+			}
+		}
+	}
+}
+
+// CheckCardinality counts the values of every tag key of the local databases
+// and raises an alert for the tag keys over a threshold. Growth is measured
+// from the previous check so it isn't checked on the first one.
+func (m *Monitor) CheckCardinality() error {
+	dbs, err := m.MetaStore.Databases()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	seen := make(map[tagKey]bool)
+	var points []tsdb.Point
+	for _, di := range dbs {
+		index := m.TSDBStore.DatabaseIndex(di.Name)
+		if index == nil {
+			continue
+		}
+
+		for _, name := range index.Names() {
+			mm := index.Measurement(name)
+			if mm == nil {
+				continue
+			}
+
+			// Keep the last count of evicted measurements for their next check.
+			counts := mm.TagKeyCardinality()
+			if counts == nil {
+				for k := range m.cardinality {
+					if k.database == di.Name && k.measurement == name {
+						seen[k] = true
+					}
+				}
+				continue
+			}
+
+			keys := make([]string, 0, len(counts))
+			for k := range counts {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				k := tagKey{database: di.Name, measurement: name, key: key}
+				n := counts[key]
+				last, ok := m.cardinality[k]
+				m.cardinality[k], seen[k] = n, true
+
+				growth := 0
+				if ok {
+					growth = n - last
+				}
+				if !m.overThreshold(n, growth) {
+					continue
+				}
+
+				m.Logger.Printf("tag key %q of measurement %q in database %q has %d values, %d more than at the last check",
+					key, name, di.Name, n, growth)
+				points = append(points, tsdb.NewPoint("tag_cardinality_alert",
+					tsdb.NewTags(map[string]string{"database": di.Name, "measurement": name, "tagKey": key}),
+					tsdb.Fields{"values": int64(n), "growth": int64(growth)},
+					now))
+			}
+		}
+	}
+
+	// Forget the tag keys that were dropped.
+	for k := range m.cardinality {
+		if !seen[k] {
+			delete(m.cardinality, k)
+		}
+	}
+
+	if len(points) == 0 || m.PointsWriter == nil {
+		return nil
+	}
+	if _, err := m.MetaStore.CreateDatabaseIfNotExists(m.config.Database); err != nil {
+		return err
+	}
+	return m.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+		Database:         m.config.Database,
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		Points:           points,
+	})
+}
+
+func (m *Monitor) checkIntegrity() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(m.config.IntegrityCheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			if err := m.CheckIntegrity(); err != nil {
+				m.Logger.Printf("integrity check failed: %s", err)
+			}
+		}
+	}
+}
+
+// CheckIntegrity checks a sample of the series of every local shard for
+// corruption. The problems found are logged and the report of every shard
+// is written to the monitoring database.
+func (m *Monitor) CheckIntegrity() error {
+	reports := m.TSDBStore.CheckIntegrity(m.config.IntegritySampleSize)
+
+	points := make([]tsdb.Point, 0, len(reports))
+	for _, r := range reports {
+		for _, e := range r.Errors {
+			m.Logger.Printf("shard %d of %s.%s: %s", r.ShardID, r.Database, r.RetentionPolicy, e)
+		}
+
+		points = append(points, tsdb.NewPoint("shard_integrity",
+			tsdb.NewTags(map[string]string{
+				"database":        r.Database,
+				"retentionPolicy": r.RetentionPolicy,
+				"shardID":         strconv.FormatUint(r.ShardID, 10),
+			}),
+			tsdb.Fields{
+				"ok":            r.OK(),
+				"errors":        int64(len(r.Errors)),
+				"seriesChecked": int64(r.SeriesChecked),
+				"missingSeries": int64(r.MissingSeries),
+				"blocksChecked": int64(r.BlocksChecked),
+				"corruptBlocks": int64(r.CorruptBlocks),
+				"walSegments":   int64(r.WALSegments),
+				"walGaps":       int64(r.WALGaps),
+			},
+			r.Time))
+	}
+
+	if len(points) == 0 || m.PointsWriter == nil {
+		return nil
+	}
+	if _, err := m.MetaStore.CreateDatabaseIfNotExists(m.config.Database); err != nil {
+		return err
+	}
+	return m.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+		Database:         m.config.Database,
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		Points:           points,
+	})
+}
+
+// overThreshold returns true if a tag key with n values that gained growth
+// values since the last check should raise an alert.
+func (m *Monitor) overThreshold(n, growth int) bool {
+	if m.config.MaxTagValues > 0 && n > m.config.MaxTagValues {
+		return true
+	}
+	return m.config.MaxTagValuesGrowth > 0 && growth > m.config.MaxTagValuesGrowth
+}
 
 // StartSelfMonitoring starts a goroutine which monitors the InfluxDB server
 // itself and stores the results in the specified database at a given interval.
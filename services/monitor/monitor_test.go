@@ -0,0 +1,142 @@
+package monitor_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/monitor"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure alerts are raised for tag keys with too many values or growing too fast.
+func TestMonitor_CheckCardinality(t *testing.T) {
+	index := tsdb.NewDatabaseIndex()
+	addSeries(index, "cpu", "host", 0, 5)
+	addSeries(index, "mem", "host", 0, 1)
+
+	c := monitor.NewConfig()
+	c.MaxTagValues = 4
+	c.MaxTagValuesGrowth = 2
+	m := monitor.NewMonitor(c)
+	m.Logger = log.New(ioutil.Discard, "", 0)
+
+	var created string
+	m.MetaStore = &MetaStore{
+		DatabasesFn: func() ([]meta.DatabaseInfo, error) {
+			return []meta.DatabaseInfo{{Name: "db0"}, {Name: "remote"}}, nil
+		},
+		CreateDatabaseIfNotExistsFn: func(name string) (*meta.DatabaseInfo, error) {
+			created = name
+			return &meta.DatabaseInfo{Name: name}, nil
+		},
+	}
+	m.TSDBStore = &TSDBStore{index: index}
+
+	var points []tsdb.Point
+	m.PointsWriter = &PointsWriter{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+		if p.Database != "_internal" {
+			t.Fatalf("unexpected database: %s", p.Database)
+		}
+		points = p.Points
+		return nil
+	}}
+
+	// Only cpu has too many values. Growth isn't known on the first check.
+	if err := m.CheckCardinality(); err != nil {
+		t.Fatal(err)
+	} else if created != "_internal" {
+		t.Fatalf("monitoring database not created: %q", created)
+	} else if len(points) != 1 {
+		t.Fatalf("unexpected number of alerts: %d", len(points))
+	} else if exp := "tag_cardinality_alert,database=db0,measurement=cpu,tagKey=host growth=0i,values=5i"; points[0].String()[:len(exp)] != exp {
+		t.Fatalf("unexpected alert: %s", points[0])
+	}
+
+	// mem gained 3 values since the last check.
+	points = nil
+	addSeries(index, "mem", "host", 1, 4)
+	if err := m.CheckCardinality(); err != nil {
+		t.Fatal(err)
+	} else if len(points) != 2 {
+		t.Fatalf("unexpected number of alerts: %d", len(points))
+	} else if exp := "tag_cardinality_alert,database=db0,measurement=mem,tagKey=host growth=3i,values=4i"; points[1].String()[:len(exp)] != exp {
+		t.Fatalf("unexpected alert: %s", points[1])
+	}
+}
+
+// Ensure the integrity report of every shard is written and problems are logged.
+func TestMonitor_CheckIntegrity(t *testing.T) {
+	m := monitor.NewMonitor(monitor.NewConfig())
+	m.Logger = log.New(ioutil.Discard, "", 0)
+	m.MetaStore = &MetaStore{
+		CreateDatabaseIfNotExistsFn: func(name string) (*meta.DatabaseInfo, error) {
+			return &meta.DatabaseInfo{Name: name}, nil
+		},
+	}
+
+	corrupt := &tsdb.IntegrityReport{ShardID: 2, Database: "db0", RetentionPolicy: "default", BlocksChecked: 4, CorruptBlocks: 1}
+	corrupt.Errorf("series cpu: block 1: decode: corrupt input")
+	m.TSDBStore = &TSDBStore{reports: []*tsdb.IntegrityReport{
+		{ShardID: 1, Database: "db0", RetentionPolicy: "default", SeriesChecked: 10},
+		corrupt,
+	}}
+
+	var points []tsdb.Point
+	m.PointsWriter = &PointsWriter{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+		points = p.Points
+		return nil
+	}}
+
+	if err := m.CheckIntegrity(); err != nil {
+		t.Fatal(err)
+	} else if len(points) != 2 {
+		t.Fatalf("unexpected number of reports: %d", len(points))
+	} else if exp := "shard_integrity,database=db0,retentionPolicy=default,shardID=2 blocksChecked=4i,corruptBlocks=1i,errors=1i,missingSeries=0i,ok=false,"; points[1].String()[:len(exp)] != exp {
+		t.Fatalf("unexpected report: %s", points[1])
+	}
+}
+
+// addSeries adds series to the index with the values from min to max of a tag.
+func addSeries(index *tsdb.DatabaseIndex, name, key string, min, max int) {
+	for i := min; i < max; i++ {
+		tags := map[string]string{key: fmt.Sprintf("value%d", i)}
+		index.CreateSeriesIndexIfNotExists(name, tsdb.NewSeries(string(tsdb.MakeKey([]byte(name), tsdb.NewTags(tags))), tags))
+	}
+}
+
+// MetaStore is a mock implementation of Monitor.MetaStore.
+type MetaStore struct {
+	DatabasesFn                 func() ([]meta.DatabaseInfo, error)
+	CreateDatabaseIfNotExistsFn func(name string) (*meta.DatabaseInfo, error)
+}
+
+func (s *MetaStore) Databases() ([]meta.DatabaseInfo, error) { return s.DatabasesFn() }
+func (s *MetaStore) CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error) {
+	return s.CreateDatabaseIfNotExistsFn(name)
+}
+
+// TSDBStore is a mock implementation of Monitor.TSDBStore with one local database.
+type TSDBStore struct {
+	index   *tsdb.DatabaseIndex
+	reports []*tsdb.IntegrityReport
+}
+
+func (s *TSDBStore) DatabaseIndex(name string) *tsdb.DatabaseIndex {
+	if name == "db0" {
+		return s.index
+	}
+	return nil
+}
+
+func (s *TSDBStore) CheckIntegrity(n int) []*tsdb.IntegrityReport { return s.reports }
+
+// PointsWriter is a mock implementation of Monitor.PointsWriter.
+type PointsWriter struct {
+	WritePointsFn func(p *cluster.WritePointsRequest) error
+}
+
+func (w *PointsWriter) WritePoints(p *cluster.WritePointsRequest) error { return w.WritePointsFn(p) }
@@ -1,5 +1,11 @@
 package opentsdb
 
+import (
+	"time"
+
+	"github.com/influxdb/influxdb/toml"
+)
+
 const (
 	// DefaultBindAddress is the default address that the service binds to.
 	DefaultBindAddress = ":4242"
@@ -12,6 +18,12 @@ const (
 
 	// DefaultConsistencyLevel is the default write consistency level.
 	DefaultConsistencyLevel = "one"
+
+	// DefaultBatchSize is the default telnet batch size.
+	DefaultBatchSize = 1000
+
+	// DefaultBatchTimeout is the default telnet batch timeout.
+	DefaultBatchTimeout = time.Second
 )
 
 type Config struct {
@@ -22,6 +34,11 @@ type Config struct {
 	ConsistencyLevel string `toml:"consistency-level"`
 	TLSEnabled       bool   `toml:"tls-enabled"`
 	Certificate      string `toml:"certificate"`
+
+	// BatchSize and BatchTimeout control how points received over telnet
+	// are batched before they're written.
+	BatchSize    int           `toml:"batch-size"`
+	BatchTimeout toml.Duration `toml:"batch-timeout"`
 }
 
 func NewConfig() Config {
@@ -32,5 +49,7 @@ func NewConfig() Config {
 		ConsistencyLevel: DefaultConsistencyLevel,
 		TLSEnabled:       false,
 		Certificate:      "/etc/ssl/influxdb.pem",
+		BatchSize:        DefaultBatchSize,
+		BatchTimeout:     toml.Duration(DefaultBatchTimeout),
 	}
 }
@@ -2,6 +2,7 @@ package opentsdb_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdb/influxdb/services/opentsdb"
@@ -17,6 +18,8 @@ database = "xxx"
 consistency-level ="all"
 tls-enabled = true
 certificate = "/etc/ssl/cert.pem"
+batch-size = 100
+batch-timeout = "10ms"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -34,5 +37,9 @@ certificate = "/etc/ssl/cert.pem"
 		t.Fatalf("unexpected tls-enabled: %v", c.TLSEnabled)
 	} else if c.Certificate != "/etc/ssl/cert.pem" {
 		t.Fatalf("unexpected certificate: %s", c.Certificate)
+	} else if c.BatchSize != 100 {
+		t.Fatalf("unexpected batch size: %d", c.BatchSize)
+	} else if time.Duration(c.BatchTimeout) != (10 * time.Millisecond) {
+		t.Fatalf("unexpected batch timeout: %v", c.BatchTimeout)
 	}
 }
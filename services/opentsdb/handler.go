@@ -26,6 +26,8 @@ type Handler struct {
 	}
 
 	Logger *log.Logger
+
+	stats *tsdb.Statistics
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -109,24 +111,34 @@ func (h *Handler) servePut(w http.ResponseWriter, r *http.Request) {
 			ts = time.Unix(p.Time/1000, (p.Time%1000)*1000)
 		}
 
-		points = append(points, tsdb.NewPoint(p.Metric, p.Tags, map[string]interface{}{"value": p.Value}, ts))
+		pt, err := tsdb.NewPointChecked(p.Metric, tsdb.NewTags(p.Tags), map[string]interface{}{"value": p.Value}, ts)
+		if err != nil {
+			http.Error(w, "invalid point: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		points = append(points, pt)
 	}
 
-	// Write points.
+	// Write points. Data points for the same series and time are combined.
+	h.stats.Add("pointsReceived", int64(len(points)))
 	if err := h.PointsWriter.WritePoints(&cluster.WritePointsRequest{
 		Database:         h.Database,
 		RetentionPolicy:  h.RetentionPolicy,
 		ConsistencyLevel: h.ConsistencyLevel,
-		Points:           points,
+		Points:           tsdb.MergePoints(points),
 	}); influxdb.IsClientError(err) {
+		h.stats.Add("batchesTxFail", 1)
 		h.Logger.Println("write series error: ", err)
 		http.Error(w, "write series error: "+err.Error(), http.StatusBadRequest)
 		return
 	} else if err != nil {
+		h.stats.Add("batchesTxFail", 1)
 		h.Logger.Println("write series error: ", err)
 		http.Error(w, "write series error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.stats.Add("batchesTx", 1)
+	h.stats.Add("pointsTx", int64(len(points)))
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -17,6 +17,7 @@ import (
 
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/input"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -27,10 +28,15 @@ type Service struct {
 	ln     net.Listener  // main listener
 	httpln *chanListener // http channel-based listener
 
-	wg   sync.WaitGroup
-	err  chan error
-	tls  bool
-	cert string
+	wg    sync.WaitGroup
+	done  chan struct{}
+	err   chan error
+	tls   bool
+	cert  string
+	stats *tsdb.Statistics
+
+	// batcher batches the points received over telnet.
+	batcher *tsdb.PointBatcher
 
 	BindAddress      string
 	Database         string
@@ -58,12 +64,15 @@ func NewService(c Config) (*Service, error) {
 	s := &Service{
 		tls:              c.TLSEnabled,
 		cert:             c.Certificate,
+		done:             make(chan struct{}),
 		err:              make(chan error),
+		batcher:          tsdb.NewPointBatcher(c.BatchSize, time.Duration(c.BatchTimeout)),
 		BindAddress:      c.BindAddress,
 		Database:         c.Database,
 		RetentionPolicy:  c.RetentionPolicy,
 		ConsistencyLevel: consistencyLevel,
 		Logger:           log.New(os.Stderr, "[opentsdb] ", log.LstdFlags),
+		stats:            tsdb.NewStatistics("opentsdb", "opentsdb", map[string]string{"bind": c.BindAddress}),
 	}
 	return s, nil
 }
@@ -109,6 +118,11 @@ func (s *Service) Open() error {
 	}
 	s.httpln = newChanListener(s.ln.Addr())
 
+	// Start processing batches of telnet points.
+	s.batcher.Start()
+	s.wg.Add(1)
+	go s.processBatches()
+
 	// Begin listening for connections.
 	s.wg.Add(2)
 	go s.serveHTTP()
@@ -119,6 +133,9 @@ func (s *Service) Open() error {
 
 // Close closes the underlying listener.
 func (s *Service) Close() error {
+	tsdb.UnregisterStatistics(s.stats)
+	s.batcher.Stop()
+	close(s.done)
 	if s.ln != nil {
 		return s.ln.Close()
 	}
@@ -127,6 +144,15 @@ func (s *Service) Close() error {
 	return nil
 }
 
+// SetPointsWriter sets the writer received points are written to.
+func (s *Service) SetPointsWriter(w input.PointsWriter) { s.PointsWriter = w }
+
+// SetMetaStore sets the meta store used to create the target database.
+func (s *Service) SetMetaStore(m input.MetaStore) { s.MetaStore = m }
+
+// Statistics returns the counters kept by the service.
+func (s *Service) Statistics() *tsdb.Statistics { return s.stats }
+
 // SetLogger sets the internal logger to the logger passed in.
 func (s *Service) SetLogger(l *log.Logger) { s.Logger = l }
 
@@ -255,16 +281,42 @@ func (s *Service) handleTelnetConn(conn net.Conn) {
 			continue
 		}
 
-		p := tsdb.NewPoint(measurement, tags, fields, t)
-		if err := s.PointsWriter.WritePoints(&cluster.WritePointsRequest{
-			Database:         s.Database,
-			RetentionPolicy:  s.RetentionPolicy,
-			ConsistencyLevel: s.ConsistencyLevel,
-			Points:           []tsdb.Point{p},
-		}); err != nil {
-			s.Logger.Println("TSDB cannot write data: ", err)
+		p, err := tsdb.NewPointChecked(measurement, tsdb.NewTags(tags), fields, t)
+		if err != nil {
+			s.Logger.Println("TSDBServer: invalid point: ", err)
 			continue
 		}
+		s.stats.Add("pointsReceived", 1)
+		select {
+		case s.batcher.In() <- p:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// processBatches writes the batches of telnet points until the service is
+// closed.
+func (s *Service) processBatches() {
+	defer s.wg.Done()
+	for {
+		select {
+		case batch := <-s.batcher.Out():
+			if err := s.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+				Database:         s.Database,
+				RetentionPolicy:  s.RetentionPolicy,
+				ConsistencyLevel: s.ConsistencyLevel,
+				Points:           tsdb.MergePoints(batch),
+			}); err != nil {
+				s.Logger.Println("TSDB cannot write data: ", err)
+				s.stats.Add("batchesTxFail", 1)
+				continue
+			}
+			s.stats.Add("batchesTx", 1)
+			s.stats.Add("pointsTx", int64(len(batch)))
+		case <-s.done:
+			return
+		}
 	}
 }
 
@@ -276,6 +328,7 @@ func (s *Service) serveHTTP() {
 		ConsistencyLevel: s.ConsistencyLevel,
 		PointsWriter:     s.PointsWriter,
 		Logger:           s.Logger,
+		stats:            s.stats,
 	}}
 	srv.Serve(s.httpln)
 }
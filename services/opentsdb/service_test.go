@@ -40,7 +40,7 @@ func TestService_Telnet(t *testing.T) {
 		} else if !reflect.DeepEqual(req.Points, []tsdb.Point{
 			tsdb.NewPoint(
 				"sys.cpu.user",
-				map[string]string{"host": "webserver01", "cpu": "0"},
+				tsdb.NewTags(map[string]string{"host": "webserver01", "cpu": "0"}),
 				map[string]interface{}{"value": 42.5},
 				time.Unix(1356998400, 0),
 			),
@@ -94,7 +94,7 @@ func TestService_HTTP(t *testing.T) {
 		} else if !reflect.DeepEqual(req.Points, []tsdb.Point{
 			tsdb.NewPoint(
 				"sys.cpu.nice",
-				map[string]string{"dc": "lga", "host": "web01"},
+				tsdb.NewTags(map[string]string{"dc": "lga", "host": "web01"}),
 				map[string]interface{}{"value": 18.0},
 				time.Unix(1346846400, 0),
 			),
@@ -5,6 +5,8 @@ import (
 	"os"
 	"sync"
 	"time"
+
+	"github.com/influxdb/influxdb/clock"
 )
 
 type Service struct {
@@ -13,6 +15,9 @@ type Service struct {
 
 	Logger *log.Logger
 
+	// Clock decides when to check and the time shard groups are created after.
+	Clock clock.Clock
+
 	done chan struct{}
 	wg   sync.WaitGroup
 
@@ -28,6 +33,7 @@ func NewService(c Config) (*Service, error) {
 		checkInterval: time.Duration(c.CheckInterval),
 		advancePeriod: time.Duration(c.AdvancePeriod),
 		Logger:        log.New(os.Stderr, "[shard-precreation] ", log.LstdFlags),
+		Clock:         clock.New(),
 	}
 
 	return &s, nil
@@ -73,14 +79,14 @@ func (s *Service) runPrecreation() {
 
 	for {
 		select {
-		case <-time.After(s.checkInterval):
+		case <-s.Clock.After(s.checkInterval):
 			// Only run this on the leader, but always allow the loop to check
 			// as the leader can change.
 			if !s.MetaStore.IsLeader() {
 				continue
 			}
 
-			if err := s.precreate(time.Now().UTC()); err != nil {
+			if err := s.precreate(s.Clock.Now().UTC()); err != nil {
 				s.Logger.Printf("failed to precreate shards: %s", err.Error())
 			}
 		case <-s.done:
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/influxdb/clock"
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 )
 
@@ -15,12 +17,25 @@ type Service struct {
 		IsLeader() bool
 		VisitRetentionPolicies(f func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo))
 		DeleteShardGroup(database, policy string, id uint64) error
+		Interval(name string) (time.Duration, error)
+		Databases() ([]meta.DatabaseInfo, error)
+		DroppedDatabases() ([]meta.DroppedDatabaseInfo, error)
+		PurgeDroppedDatabase(name string) error
 	}
 	TSDBStore interface {
 		ShardIDs() []uint64
 		DeleteShard(shardID uint64) error
+		TrashedDatabases() ([]string, error)
+		PurgeTrash(name string) error
 	}
 
+	// TrashRetention is how long dropped databases are kept before they're
+	// purged. Zero disables purging.
+	TrashRetention time.Duration
+
+	// Clock decides when checks run and which shard groups have expired.
+	Clock clock.Clock
+
 	enabled       bool
 	checkInterval time.Duration
 	wg            sync.WaitGroup
@@ -33,6 +48,7 @@ type Service struct {
 func NewService(c Config) *Service {
 	return &Service{
 		checkInterval: time.Duration(c.CheckInterval),
+		Clock:         clock.New(),
 		done:          make(chan struct{}),
 		logger:        log.New(os.Stderr, "[retention] ", log.LstdFlags),
 	}
@@ -44,6 +60,10 @@ func (s *Service) Open() error {
 	s.wg.Add(2)
 	go s.deleteShardGroups()
 	go s.deleteShards()
+	if s.TrashRetention > 0 {
+		s.wg.Add(1)
+		go s.purgeDroppedDatabases()
+	}
 	return nil
 }
 
@@ -60,17 +80,26 @@ func (s *Service) SetLogger(l *log.Logger) {
 	s.logger = l
 }
 
+// interval returns the check interval set with SET RETENTION CHECK INTERVAL,
+// or the configured interval if it wasn't set. It's read before every check so
+// changes are picked up without a restart.
+func (s *Service) interval() time.Duration {
+	d, err := s.MetaStore.Interval(influxql.RetentionCheckInterval)
+	if err != nil || d <= 0 {
+		return s.checkInterval
+	}
+	return d
+}
+
 func (s *Service) deleteShardGroups() {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(s.checkInterval)
-	defer ticker.Stop()
 	for {
 		select {
 		case <-s.done:
 			return
 
-		case <-ticker.C:
+		case <-s.Clock.After(s.interval()):
 			// Only run this on the leader, but always allow the loop to check
 			// as the leader can change.
 			if !s.MetaStore.IsLeader() {
@@ -79,7 +108,7 @@ func (s *Service) deleteShardGroups() {
 			s.logger.Println("retention policy enforcement check commencing")
 
 			s.MetaStore.VisitRetentionPolicies(func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo) {
-				for _, g := range r.ExpiredShardGroups(time.Now().UTC()) {
+				for _, g := range r.ExpiredShardGroups(s.Clock.Now().UTC()) {
 					if err := s.MetaStore.DeleteShardGroup(d.Name, r.Name, g.ID); err != nil {
 						s.logger.Printf("failed to delete shard group %d from database %s, retention policy %s: %s",
 							g.ID, d.Name, r.Name, err.Error())
@@ -96,14 +125,12 @@ func (s *Service) deleteShardGroups() {
 func (s *Service) deleteShards() {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(s.checkInterval)
-	defer ticker.Stop()
 	for {
 		select {
 		case <-s.done:
 			return
 
-		case <-ticker.C:
+		case <-s.Clock.After(s.interval()):
 			s.logger.Println("retention policy shard deletion check commencing")
 
 			deletedShardIDs := make(map[uint64]struct{}, 0)
@@ -127,3 +154,84 @@ func (s *Service) deleteShards() {
 		}
 	}
 }
+
+// purgeDroppedDatabases removes dropped databases older than the trash
+// retention from the meta store, on the leader, and removes the trash of
+// databases that are no longer in the meta store from the local store.
+func (s *Service) purgeDroppedDatabases() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.done:
+			return
+
+		case <-s.Clock.After(s.interval()):
+			if s.MetaStore.IsLeader() {
+				s.purgeExpiredDroppedDatabases()
+			}
+			s.purgeTrash()
+		}
+	}
+}
+
+func (s *Service) purgeExpiredDroppedDatabases() {
+	ddis, err := s.MetaStore.DroppedDatabases()
+	if err != nil {
+		s.logger.Printf("failed to list dropped databases: %s", err.Error())
+		return
+	}
+
+	expiry := s.Clock.Now().UTC().Add(-s.TrashRetention)
+	for _, ddi := range ddis {
+		if !ddi.DroppedAt.Before(expiry) {
+			continue
+		}
+		if err := s.MetaStore.PurgeDroppedDatabase(ddi.Database.Name); err != nil {
+			s.logger.Printf("failed to purge dropped database %s: %s", ddi.Database.Name, err.Error())
+			continue
+		}
+		s.logger.Printf("purged dropped database %s", ddi.Database.Name)
+	}
+}
+
+func (s *Service) purgeTrash() {
+	names, err := s.TSDBStore.TrashedDatabases()
+	if err != nil {
+		s.logger.Printf("failed to list trashed databases: %s", err.Error())
+		return
+	} else if len(names) == 0 {
+		return
+	}
+
+	// Databases are trashed locally before they're dropped from the meta
+	// store, so keep the trash of databases that still exist.
+	keep := make(map[string]struct{})
+	dis, err := s.MetaStore.Databases()
+	if err != nil {
+		s.logger.Printf("failed to list databases: %s", err.Error())
+		return
+	}
+	for _, di := range dis {
+		keep[di.Name] = struct{}{}
+	}
+	ddis, err := s.MetaStore.DroppedDatabases()
+	if err != nil {
+		s.logger.Printf("failed to list dropped databases: %s", err.Error())
+		return
+	}
+	for _, ddi := range ddis {
+		keep[ddi.Database.Name] = struct{}{}
+	}
+
+	for _, name := range names {
+		if _, ok := keep[name]; ok {
+			continue
+		}
+		if err := s.TSDBStore.PurgeTrash(name); err != nil {
+			s.logger.Printf("failed to purge trash of database %s: %s", name, err.Error())
+			continue
+		}
+		s.logger.Printf("purged trash of database %s", name)
+	}
+}
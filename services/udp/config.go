@@ -9,4 +9,8 @@ type Config struct {
 	Database     string        `toml:"database"`
 	BatchSize    int           `toml:"batch-size"`
 	BatchTimeout toml.Duration `toml:"batch-timeout"`
+
+	// PointPooling recycles the points of a batch once every shard write
+	// using them has finished.
+	PointPooling bool `toml:"point-pooling"`
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/services/input"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -29,6 +30,7 @@ type Service struct {
 
 	batcher *tsdb.PointBatcher
 	config  Config
+	stats   *tsdb.Statistics
 
 	PointsWriter interface {
 		WritePoints(p *cluster.WritePointsRequest) error
@@ -43,6 +45,7 @@ func NewService(c Config) *Service {
 		done:    make(chan struct{}),
 		batcher: tsdb.NewPointBatcher(c.BatchSize, time.Duration(c.BatchTimeout)),
 		Logger:  log.New(os.Stderr, "[udp] ", log.LstdFlags),
+		stats:   tsdb.NewStatistics("udp", "udp", map[string]string{"bind": c.BindAddress}),
 	}
 }
 
@@ -81,15 +84,27 @@ func (s *Service) writePoints() {
 	for {
 		select {
 		case batch := <-s.batcher.Out():
-			err := s.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+			req := &cluster.WritePointsRequest{
 				Database:         s.config.Database,
 				RetentionPolicy:  "",
 				ConsistencyLevel: cluster.ConsistencyLevelOne,
 				Points:           batch,
-			})
+			}
+			if s.config.PointPooling {
+				req.Done = func() {
+					for _, p := range batch {
+						tsdb.PutPoint(p)
+					}
+				}
+			}
+			err := s.PointsWriter.WritePoints(req)
 			if err != nil {
 				s.Logger.Printf("Failed to write points batch to database %s: %s", s.config.Database, err)
+				s.stats.Add("batchesTxFail", 1)
+				continue
 			}
+			s.stats.Add("batchesTx", 1)
+			s.stats.Add("pointsTx", int64(len(batch)))
 
 		case <-s.done:
 			return
@@ -118,11 +133,18 @@ func (s *Service) serve() {
 			continue
 		}
 
-		points, err := tsdb.ParsePoints(buf[:n])
+		var points []tsdb.Point
+		if s.config.PointPooling {
+			points, err = tsdb.ParsePointsInto(buf[:n], nil)
+		} else {
+			points, err = tsdb.ParsePoints(buf[:n])
+		}
 		if err != nil {
 			s.Logger.Printf("Failed to parse points: %s", err)
+			s.stats.Add("parseFail", 1)
 			continue
 		}
+		s.stats.Add("pointsReceived", int64(len(points)))
 
 		for _, point := range points {
 			s.batcher.In() <- point
@@ -143,12 +165,19 @@ func (s *Service) Close() error {
 	// Release all remaining resources.
 	s.done = nil
 	s.conn = nil
+	tsdb.UnregisterStatistics(s.stats)
 
 	s.Logger.Print("Service closed")
 
 	return nil
 }
 
+// SetPointsWriter sets the writer received points are written to.
+func (s *Service) SetPointsWriter(w input.PointsWriter) { s.PointsWriter = w }
+
+// Statistics returns the counters kept by the service.
+func (s *Service) Statistics() *tsdb.Statistics { return s.stats }
+
 // SetLogger sets the internal logger to the logger passed in.
 func (s *Service) SetLogger(l *log.Logger) {
 	s.Logger = l
@@ -0,0 +1,126 @@
+// Command benchcheck compares "go test -bench" output read from stdin against
+// a stored baseline and exits with a non-zero status if any benchmark got
+// slower than the allowed threshold.
+//
+//	go test -run=XXX -bench=ParsePoints ./tsdb | benchcheck -baseline tsdb/testdata/points.bench
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	var (
+		baseline  = flag.String("baseline", "", "Path to the baseline benchmark output.")
+		threshold = flag.Float64("threshold", 10, "Allowed slowdown, in percent, before failing.")
+	)
+	flag.Parse()
+
+	if *baseline == "" {
+		fmt.Fprintln(os.Stderr, "baseline required")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*baseline)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	before, err := parseBenchmarks(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "baseline: %s\n", err)
+		os.Exit(2)
+	}
+
+	after, err := parseBenchmarks(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stdin: %s\n", err)
+		os.Exit(2)
+	} else if len(after) == 0 {
+		fmt.Fprintln(os.Stderr, "no benchmark results on stdin")
+		os.Exit(2)
+	}
+
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failed bool
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(w, "%-40s %12s %12s %8s\n", "benchmark", "old ns/op", "new ns/op", "delta")
+	for _, name := range names {
+		old, ok := before[name]
+		if !ok {
+			fmt.Fprintf(w, "%-40s %12s %12.1f %8s\n", name, "-", after[name], "new")
+			continue
+		}
+
+		delta := (after[name] - old) / old * 100
+		status := ""
+		if delta > *threshold {
+			status = "  REGRESSION"
+			failed = true
+		}
+		fmt.Fprintf(w, "%-40s %12.1f %12.1f %+7.1f%%%s\n", name, old, after[name], delta, status)
+	}
+	w.Flush()
+
+	if failed {
+		fmt.Fprintf(os.Stderr, "benchmarks regressed by more than %.1f%%\n", *threshold)
+		os.Exit(1)
+	}
+}
+
+// parseBenchmarks returns the ns/op of each benchmark in "go test -bench"
+// output. The GOMAXPROCS suffix is stripped from the names so results from
+// different machines can be compared. Benchmarks run more than once are averaged.
+func parseBenchmarks(r io.Reader) (map[string]float64, error) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+
+		for i := 2; i+1 < len(fields); i += 2 {
+			if fields[i+1] != "ns/op" {
+				continue
+			}
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid ns/op: %s", fields[0], fields[i])
+			}
+
+			name := fields[0]
+			if i := strings.LastIndex(name, "-"); i > 0 {
+				if _, err := strconv.Atoi(name[i+1:]); err == nil {
+					name = name[:i]
+				}
+			}
+			sums[name] += v
+			counts[name]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]float64, len(sums))
+	for name, sum := range sums {
+		m[name] = sum / float64(counts[name])
+	}
+	return m, nil
+}
@@ -0,0 +1,112 @@
+package tsdb
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// BatchEncoder builds a batch of points in the line protocol. Points are
+// appended to a buffer that's reused once the encoder is reset, so services
+// creating many points don't allocate a point, key and fields for each one.
+type BatchEncoder struct {
+	buf  []byte
+	n    int
+	keys []string
+}
+
+// NewBatchEncoder returns a new instance of BatchEncoder.
+func NewBatchEncoder() *BatchEncoder {
+	return &BatchEncoder{}
+}
+
+// AddPoint appends a point to the batch. Tags are written sorted by key,
+// and fields sorted by name with nil values left out. A zero time is left
+// out so the point gets the time it's written at. NaN and infinite floats
+// are rejected. The batch is unchanged when an error is returned.
+func (e *BatchEncoder) AddPoint(name string, tags Tags, fields Fields, t time.Time) error {
+	if name == "" {
+		return ErrPointMissingMeasurement
+	}
+
+	e.keys = e.keys[:0]
+	for k, v := range fields {
+		if f, ok := v.(float64); ok {
+			if _, _, err := NonFiniteReject.finite(k, f); err != nil {
+				return err
+			}
+		}
+		if v != nil {
+			e.keys = append(e.keys, k)
+		}
+	}
+	if len(e.keys) == 0 {
+		return ErrPointMissingFields
+	}
+	sort.Strings(e.keys)
+
+	b := e.buf
+	if e.n > 0 {
+		b = append(b, '\n')
+	}
+	b = appendEscaped(b, name, measurementEscapeChars)
+	b = tags.appendHashKey(b)
+
+	for i, k := range e.keys {
+		if i == 0 {
+			b = append(b, ' ')
+		} else {
+			b = append(b, ',')
+		}
+		b = appendEscaped(b, k, escapeChars)
+		b = append(b, '=')
+		b = appendFieldValue(b, fields[k])
+	}
+
+	if !t.IsZero() {
+		ns := t.UnixNano()
+		if err := checkTime(ns); err != nil {
+			return err
+		}
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, ns, 10)
+	}
+
+	e.buf = b
+	e.n++
+	return nil
+}
+
+// Len returns the number of points in the batch.
+func (e *BatchEncoder) Len() int { return e.n }
+
+// Bytes returns the batch in the line protocol, one point per line. It's
+// only valid until the encoder is reset.
+func (e *BatchEncoder) Bytes() []byte { return e.buf }
+
+// Points returns the points of the batch. They don't share memory with the
+// encoder.
+func (e *BatchEncoder) Points() ([]Point, error) {
+	if e.n == 0 {
+		return nil, nil
+	}
+	return ParsePoints(append([]byte(nil), e.buf...))
+}
+
+// Reset empties the batch, keeping its buffer for the next one.
+func (e *BatchEncoder) Reset() {
+	e.buf = e.buf[:0]
+	e.n = 0
+}
+
+// appendEscaped appends s to b with a backslash before every character in
+// table.
+func appendEscaped(b []byte, s string, table *[256]bool) []byte {
+	for i := 0; i < len(s); i++ {
+		if table[s[i]] {
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
@@ -0,0 +1,55 @@
+package tsdb_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the batch encoder escapes and sorts keys and reuses its buffer.
+func TestBatchEncoder_AddPoint(t *testing.T) {
+	e := tsdb.NewBatchEncoder()
+	if err := e.AddPoint("cpu load", tsdb.Tags{{Key: "region", Value: "us,west"}, {Key: "host", Value: "a b"}}, tsdb.Fields{
+		"value": 1.5,
+		"note":  `say "hi"`,
+		"count": int64(3),
+		"nil":   nil,
+	}, time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	} else if err := e.AddPoint("mem", nil, tsdb.Fields{"free": 2.0}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Points without fields or with NaN fields are rejected and leave the batch
+	// as it was.
+	if err := e.AddPoint("disk", nil, tsdb.Fields{"nil": nil}, time.Time{}); err != tsdb.ErrPointMissingFields {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := e.AddPoint("disk", nil, tsdb.Fields{"used": math.NaN()}, time.Time{}); err == nil || err.Error() != `field "used": NaN is not a finite number` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := `cpu\ load,host=a\ b,region=us\,west count=3i,note="say \"hi\"",value=1.5 1000000000` + "\n" + `mem free=2.0`
+	if got := string(e.Bytes()); got != exp {
+		t.Fatalf("unexpected batch:\n got: %s\nwant: %s", got, exp)
+	} else if e.Len() != 2 {
+		t.Fatalf("unexpected len: %d", e.Len())
+	}
+
+	pts, err := e.Points()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pts) != 2 {
+		t.Fatalf("unexpected points: %v", pts)
+	} else if tags := pts[0].Tags(); tags.Get("host") != "a b" || tags.Get("region") != "us,west" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+
+	e.Reset()
+	if err := e.AddPoint("mem", nil, tsdb.Fields{"free": int64(1)}, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if got := string(e.Bytes()); got != "mem free=1i" {
+		t.Fatalf("unexpected batch after reset: %s", got)
+	}
+}
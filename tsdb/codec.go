@@ -0,0 +1,86 @@
+package tsdb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// SnappyCodecID is the ID of the snappy codec.
+	SnappyCodecID = 1
+
+	// ZstdCodecID is the ID of the zstd codec. It's only registered when
+	// built with the "zstd" build tag.
+	ZstdCodecID = 2
+
+	// DefaultCodec is the name of the codec used to compress blocks by default.
+	DefaultCodec = "snappy"
+)
+
+// Codec compresses blocks of data on disk and on the wire. A codec's ID is
+// stored with the data it compresses so IDs must never be reused.
+type Codec interface {
+	ID() byte
+	Name() string
+	Encode(src []byte) []byte
+	Decode(src []byte) ([]byte, error)
+}
+
+// codecs is a lookup of registered codecs by ID.
+var codecs = make(map[byte]Codec)
+
+func init() {
+	RegisterCodec(snappyCodec{})
+}
+
+// RegisterCodec registers a compression codec. Codecs must be registered
+// before any data is read or written, usually from an init function.
+func RegisterCodec(c Codec) {
+	if c.ID() == 0 || c.ID() >= 64 {
+		panic(fmt.Sprintf("invalid codec id: %d", c.ID()))
+	} else if _, ok := codecs[c.ID()]; ok {
+		panic("codec already registered: " + c.Name())
+	} else if CodecByName(c.Name()) != nil {
+		panic("codec already registered: " + c.Name())
+	}
+	codecs[c.ID()] = c
+}
+
+// CodecByID returns a registered codec by ID. Returns nil if not registered.
+func CodecByID(id byte) Codec { return codecs[id] }
+
+// CodecByName returns a registered codec by name. Returns nil if not registered.
+func CodecByName(name string) Codec {
+	for _, c := range codecs {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Codecs returns the registered codecs sorted by ID.
+func Codecs() []Codec {
+	a := make([]Codec, 0, len(codecs))
+	for _, c := range codecs {
+		a = append(a, c)
+	}
+	sort.Sort(codecsByID(a))
+	return a
+}
+
+type codecsByID []Codec
+
+func (a codecsByID) Len() int           { return len(a) }
+func (a codecsByID) Less(i, j int) bool { return a[i].ID() < a[j].ID() }
+func (a codecsByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// snappyCodec compresses data with snappy.
+type snappyCodec struct{}
+
+func (snappyCodec) ID() byte                          { return SnappyCodecID }
+func (snappyCodec) Name() string                      { return "snappy" }
+func (snappyCodec) Encode(src []byte) []byte          { return snappy.Encode(nil, src) }
+func (snappyCodec) Decode(src []byte) ([]byte, error) { return snappy.Decode(nil, src) }
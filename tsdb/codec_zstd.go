@@ -0,0 +1,31 @@
+// +build zstd
+
+package tsdb
+
+import (
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		panic(err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		panic(err)
+	}
+	RegisterCodec(&zstdCodec{enc: enc, dec: dec})
+}
+
+// zstdCodec compresses data with zstd. It has better ratios than snappy at
+// the cost of more CPU. The encoder and decoder are safe for concurrent use.
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func (c *zstdCodec) ID() byte                          { return ZstdCodecID }
+func (c *zstdCodec) Name() string                      { return "zstd" }
+func (c *zstdCodec) Encode(src []byte) []byte          { return c.enc.EncodeAll(src, nil) }
+func (c *zstdCodec) Decode(src []byte) ([]byte, error) { return c.dec.DecodeAll(src, nil) }
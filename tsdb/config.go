@@ -1,6 +1,9 @@
 package tsdb
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/influxdb/influxdb/toml"
@@ -50,7 +53,9 @@ type Config struct {
 	WALFlushInterval       toml.Duration `toml:"wal-flush-interval"`
 	WALPartitionFlushDelay toml.Duration `toml:"wal-partition-flush-delay"`
 
-	// WAL configuration options for bz1 (introduced in 0.9.3)
+	// WAL configuration options for bz1 (introduced in 0.9.3). WALDir may be
+	// on a different volume than Dir, such as a fast disk for the WAL and bulk
+	// storage for the compacted shards, but neither may contain the other.
 	WALDir                    string        `toml:"wal-dir"`
 	WALEnableLogging          bool          `toml:"wal-enable-logging"`
 	WALReadySeriesSize        int           `toml:"wal-ready-series-size"`
@@ -58,6 +63,39 @@ type Config struct {
 	WALMaxSeriesSize          int           `toml:"wal-max-series-size"`
 	WALFlushColdInterval      toml.Duration `toml:"wal-flush-cold-interval"`
 	WALPartitionSizeThreshold uint64        `toml:"wal-partition-size-threshold"`
+
+	// MaxIndexMemory is the number of bytes of tag index each database keeps
+	// in memory. Cold measurements are evicted to IndexDir once it's exceeded.
+	// Zero keeps the whole index in memory.
+	MaxIndexMemory int    `toml:"max-index-memory"`
+	IndexDir       string `toml:"index-dir"`
+
+	// BlockCompression is the name of the codec that compresses new blocks
+	// of bz1 shards, e.g. "snappy" or "zstd" if built with the zstd tag.
+	BlockCompression string `toml:"block-compression"`
+
+	// MaxOpenFiles is the number of file handles open shards may hold. Once
+	// it's exceeded, the least recently used idle shards are closed and then
+	// reopened when they're next written or queried. Zero keeps every shard open.
+	MaxOpenFiles int `toml:"max-open-files"`
+
+	// TrashRetention is how long dropped databases are kept in TrashDir so
+	// they can be restored with RESTORE DATABASE. Zero removes them when
+	// they're dropped. TrashDir must be on the same file system as Dir and
+	// WALDir.
+	TrashRetention toml.Duration `toml:"trash-retention"`
+	TrashDir       string        `toml:"trash-dir"`
+
+	// WarmUpSeries is the number of most queried series per database whose
+	// tag index and first block are loaded into memory after the shards are
+	// opened, so the first queries after a restart don't wait on disk.
+	// Zero disables the warm-up.
+	WarmUpSeries int `toml:"warm-up-series"`
+
+	// LastValueCache keeps the last value written to each field of each
+	// series in memory so queries selecting only last() of a field, without
+	// a time range, are answered without reading the shards.
+	LastValueCache bool `toml:"last-value-cache"`
 }
 
 func NewConfig() Config {
@@ -72,5 +110,41 @@ func NewConfig() Config {
 		WALMaxSeriesSize:          DefaultMaxSeriesSize,
 		WALFlushColdInterval:      toml.Duration(DefaultFlushColdInterval),
 		WALPartitionSizeThreshold: DefaultPartitionSizeThreshold,
+
+		BlockCompression: DefaultCodec,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.Dir == "" || c.WALDir == "" {
+		return nil
+	}
+
+	dir, err := filepath.Abs(c.Dir)
+	if err != nil {
+		return fmt.Errorf("dir: %s", err)
+	}
+	walDir, err := filepath.Abs(c.WALDir)
+	if err != nil {
+		return fmt.Errorf("wal-dir: %s", err)
+	}
+
+	if dir == walDir {
+		return fmt.Errorf("wal-dir must not be the same as dir: %s", dir)
+	} else if isSubdir(walDir, dir) {
+		return fmt.Errorf("wal-dir must not be inside dir: %s", walDir)
+	} else if isSubdir(dir, walDir) {
+		return fmt.Errorf("dir must not be inside wal-dir: %s", dir)
+	}
+	return nil
+}
+
+// isSubdir returns true if path is below dir. Both must be absolute.
+func isSubdir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." {
+		return false
 	}
+	return !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
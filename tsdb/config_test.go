@@ -0,0 +1,31 @@
+package tsdb_test
+
+import (
+	"testing"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the WAL and data directories can't overlap.
+func TestConfig_Validate_Dirs(t *testing.T) {
+	for i, tt := range []struct {
+		dir, walDir string
+		valid       bool
+	}{
+		{dir: "/ssd/wal", walDir: "/ssd/wal", valid: false},
+		{dir: "/data", walDir: "/data/wal", valid: false},
+		{dir: "/wal/data", walDir: "/wal", valid: false},
+		{dir: "/data/", walDir: "/data", valid: false},
+		{dir: "/hdd/influxdb/data", walDir: "/ssd/influxdb/wal", valid: true},
+		{dir: "/var/opt/influxdb/data", walDir: "/var/opt/influxdb/data-wal", valid: true},
+		{dir: "", walDir: "/wal", valid: true},
+	} {
+		c := tsdb.NewConfig()
+		c.Dir, c.WALDir = tt.dir, tt.walDir
+		if err := c.Validate(); tt.valid && err != nil {
+			t.Errorf("%d. unexpected error: %s", i, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%d. expected error for dir %q and wal-dir %q", i, tt.dir, tt.walDir)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package tsdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+)
+
+// convertBatchSize is the number of points copied to the new engine at a time.
+const convertBatchSize = 10000
+
+// indexWriter is implemented by engines that can write points directly to their
+// index, bypassing the WAL.
+type indexWriter interface {
+	WriteIndex(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*MeasurementFields, seriesToCreate []*SeriesCreate) error
+}
+
+// ConvertShard converts the shard stored at path to the given engine format.
+//
+// The shard is copied series by series into a new file which is verified
+// against the original before being swapped into place. The original file is
+// kept next to the shard with a ".bak" extension. The shard must not be open
+// while it's being converted.
+func ConvertShard(path, walPath, format string, options EngineOptions) error {
+	if newEngineFuncs[format] == nil {
+		return fmt.Errorf("invalid engine format: %q", format)
+	}
+
+	// Open the existing shard.
+	src, index, measurementFields, err := openConvertEngine(path, walPath, options)
+	if err != nil {
+		return fmt.Errorf("open: %s", err)
+	}
+	defer func() {
+		if src != nil {
+			src.Close()
+		}
+	}()
+
+	// Create the new shard next to the existing one.
+	dstPath, dstWALPath := path+".convert", walPath+".convert"
+	if err := os.RemoveAll(dstPath); err != nil {
+		return err
+	} else if err := os.RemoveAll(dstWALPath); err != nil {
+		return err
+	}
+	defer os.RemoveAll(dstWALPath)
+
+	opt := options
+	opt.EngineVersion = format
+	dst := newEngineFuncs[format](dstPath, dstWALPath, opt)
+	if err := dst.Open(); err != nil {
+		return fmt.Errorf("open %s: %s", format, err)
+	}
+	if err := dst.LoadMetadataIndex(NewDatabaseIndex(), make(map[string]*MeasurementFields)); err != nil {
+		dst.Close()
+		return fmt.Errorf("load %s metadata: %s", format, err)
+	}
+
+	// Copy all the series.
+	if err := copySeries(src, dst, index, measurementFields); err != nil {
+		dst.Close()
+		os.RemoveAll(dstPath)
+		return fmt.Errorf("copy: %s", err)
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	// Reopen the new shard and verify it against the original.
+	dst, _, _, err = openConvertEngine(dstPath, dstWALPath, opt)
+	if err != nil {
+		return fmt.Errorf("reopen %s: %s", format, err)
+	}
+	err = verifySeries(src, dst, index)
+	dst.Close()
+	if err != nil {
+		os.RemoveAll(dstPath)
+		return fmt.Errorf("verify: %s", err)
+	}
+
+	// Swap the new shard into place.
+	err = src.Close()
+	src = nil
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		return err
+	}
+	if _, err := os.Stat(walPath); err == nil {
+		if err := os.Rename(walPath, walPath+".bak"); err != nil {
+			return restoreConverted(path, "", err)
+		}
+	} else {
+		walPath = ""
+	}
+	if err := os.Rename(dstPath, path); err != nil {
+		return restoreConverted(path, walPath, err)
+	}
+	return nil
+}
+
+// restoreConverted moves the original shard, and its WAL if walPath is set,
+// back from their ".bak" files after a failed swap and returns err.
+func restoreConverted(path, walPath string, err error) error {
+	if rerr := os.Rename(path+".bak", path); rerr != nil {
+		return fmt.Errorf("%s; restore %s: %s", err, path, rerr)
+	}
+	if walPath != "" {
+		if rerr := os.Rename(walPath+".bak", walPath); rerr != nil {
+			return fmt.Errorf("%s; restore %s: %s", err, walPath, rerr)
+		}
+	}
+	return err
+}
+
+// openConvertEngine opens the engine at path and loads its metadata.
+func openConvertEngine(path, walPath string, options EngineOptions) (Engine, *DatabaseIndex, map[string]*MeasurementFields, error) {
+	e, err := NewEngine(path, walPath, options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := e.Open(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	index := NewDatabaseIndex()
+	measurementFields := make(map[string]*MeasurementFields)
+
+	index.mu.Lock()
+	err = e.LoadMetadataIndex(index, measurementFields)
+	index.mu.Unlock()
+	if err != nil {
+		e.Close()
+		return nil, nil, nil, err
+	}
+
+	return e, index, measurementFields, nil
+}
+
+// copySeries copies the metadata and points for every series in index from src to dst.
+func copySeries(src, dst Engine, index *DatabaseIndex, measurementFields map[string]*MeasurementFields) error {
+	// Gather all the series so they're created along with the first batch of points.
+	var seriesToCreate []*SeriesCreate
+	index.mu.RLock()
+	for _, m := range index.Measurements() {
+		for _, key := range m.SeriesKeys() {
+			seriesToCreate = append(seriesToCreate, &SeriesCreate{Measurement: m.Name, Series: index.series[key]})
+		}
+	}
+	index.mu.RUnlock()
+
+	tx, err := src.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Write the metadata first.
+	if w, ok := dst.(indexWriter); ok {
+		err = w.WriteIndex(nil, measurementFields, seriesToCreate)
+	} else {
+		err = dst.WritePoints(nil, measurementFields, seriesToCreate)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, sc := range seriesToCreate {
+		var batch [][]byte
+		c := tx.Cursor(sc.Series.Key)
+		for k, v := c.Seek(u64tob(0)); k != nil; k, v = c.Next() {
+			batch = append(batch, append(append(make([]byte, 0, len(k)+len(v)), k...), v...))
+
+			if len(batch) >= convertBatchSize {
+				if err := writeConvertBatch(dst, sc, batch); err != nil {
+					return err
+				}
+				batch = nil
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := writeConvertBatch(dst, sc, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeConvertBatch writes a batch of points for a series to an engine. Each
+// point is an 8 byte timestamp followed by the encoded fields.
+func writeConvertBatch(e Engine, sc *SeriesCreate, batch [][]byte) error {
+	if w, ok := e.(indexWriter); ok {
+		return w.WriteIndex(map[string][][]byte{sc.Series.Key: batch}, nil, nil)
+	}
+
+	// Otherwise build points and write them through the engine.
+	points := make([]Point, 0, len(batch))
+	for _, v := range batch {
+		p := NewPoint(sc.Measurement, NewTags(sc.Series.Tags), nil, time.Unix(0, int64(btou64(v[0:8]))))
+		p.SetData(v[8:])
+		points = append(points, p)
+	}
+	return e.WritePoints(points, nil, nil)
+}
+
+// verifySeries ensures every series in index has the same points in both engines.
+func verifySeries(src, dst Engine, index *DatabaseIndex) error {
+	srcTx, err := src.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer srcTx.Rollback()
+
+	dstTx, err := dst.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer dstTx.Rollback()
+
+	index.mu.RLock()
+	keys := make([]string, 0, len(index.series))
+	for key := range index.series {
+		keys = append(keys, key)
+	}
+	index.mu.RUnlock()
+
+	for _, key := range keys {
+		sc, dc := srcTx.Cursor(key), dstTx.Cursor(key)
+		sk, sv := sc.Seek(u64tob(0))
+		dk, dv := dc.Seek(u64tob(0))
+		for {
+			if !bytes.Equal(sk, dk) || !bytes.Equal(sv, dv) {
+				return fmt.Errorf("series %q differs at %x", key, sk)
+			} else if sk == nil {
+				break
+			}
+			sk, sv = sc.Next()
+			dk, dv = dc.Next()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package tsdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+	"github.com/influxdb/influxdb/tsdb/engine/b1"
+	"github.com/influxdb/influxdb/tsdb/engine/bz1"
+)
+
+// Ensure a shard can be converted to a different engine format.
+func TestConvertShard(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "convert_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "shard")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	opts := tsdb.NewEngineOptions()
+	opts.EngineVersion = b1.Format
+	opts.Config.WALDir = tmpWal
+
+	// Write points to a b1 shard.
+	sh := tsdb.NewShard(1, tsdb.NewDatabaseIndex(), tmpShard, tmpWal, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	var points []tsdb.Point
+	for i := 0; i < 10; i++ {
+		points = append(points,
+			tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": float64(i)}, time.Unix(int64(i), 0)),
+			tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverB"}), tsdb.Fields{"value": float64(i)}, time.Unix(int64(i), 0)),
+		)
+	}
+	if err := sh.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+	sh.Close()
+
+	// Convert the shard and verify the new format.
+	if err := tsdb.ConvertShard(tmpShard, tmpWal, bz1.Format, opts); err != nil {
+		t.Fatal(err)
+	}
+	if format, err := tsdb.EngineFormat(tmpShard); err != nil {
+		t.Fatal(err)
+	} else if format != bz1.Format {
+		t.Fatalf("unexpected format: %s", format)
+	}
+	if _, err := os.Stat(tmpShard + ".bak"); err != nil {
+		t.Fatalf("expected backup of original shard: %s", err)
+	}
+
+	// Reopen the shard and ensure the index and points were copied.
+	index := tsdb.NewDatabaseIndex()
+	sh = tsdb.NewShard(1, index, tmpShard, tmpWal, tsdb.NewEngineOptions())
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	sh.Close()
+
+	if n := index.SeriesN(); n != 2 {
+		t.Fatalf("unexpected series count: %d", n)
+	}
+
+	e, err := tsdb.NewEngine(tmpShard, tmpWal, tsdb.NewEngineOptions())
+	if err != nil {
+		t.Fatal(err)
+	} else if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	tx, err := e.Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var n int
+	c := tx.Cursor("cpu,host=serverB")
+	for k, _ := c.Seek([]byte{0, 0, 0, 0, 0, 0, 0, 0}); k != nil; k, _ = c.Next() {
+		n++
+	}
+	if n != 10 {
+		t.Fatalf("unexpected point count: %d", n)
+	}
+}
+
+// Ensure the original shard is put back if it can't be swapped for the new one.
+func TestConvertShard_SwapFailed(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "convert_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "shard")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	opts := tsdb.NewEngineOptions()
+	opts.EngineVersion = bz1.Format
+	opts.Config.WALDir = tmpWal
+
+	sh := tsdb.NewShard(1, tsdb.NewDatabaseIndex(), tmpShard, tmpWal, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.WritePoints([]tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sh.Close()
+
+	// A non-empty directory in the way stops the WAL being backed up.
+	if err := os.MkdirAll(filepath.Join(tmpWal+".bak", "blocker"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tsdb.ConvertShard(tmpShard, tmpWal, b1.Format, opts); err == nil {
+		t.Fatal("expected error")
+	}
+	if format, err := tsdb.EngineFormat(tmpShard); err != nil {
+		t.Fatal(err)
+	} else if format != bz1.Format {
+		t.Fatalf("unexpected format: %s", format)
+	}
+	if _, err := os.Stat(tmpShard + ".bak"); !os.IsNotExist(err) {
+		t.Fatalf("unexpected backup of original shard: %v", err)
+	}
+	if _, err := os.Stat(tmpWal); err != nil {
+		t.Fatalf("expected original wal: %s", err)
+	}
+}
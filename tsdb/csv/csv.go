@@ -0,0 +1,463 @@
+// Package csv converts CSV files to points.
+//
+// The first row of a file names its columns. A Spec says which columns are
+// the measurement, tags, fields and the timestamp, and a Reader converts the
+// rows that follow to points one at a time, so files of any size can be
+// streamed. ReadPoints converts a whole file at once.
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// DefaultTimeFormat is the format of the time column when a spec doesn't set one.
+const DefaultTimeFormat = "rfc3339"
+
+// ErrMeasurementRequired is returned when a spec doesn't name a measurement
+// or a measurement column.
+var ErrMeasurementRequired = errors.New("measurement required")
+
+// The types a field column can be given in Spec.Types.
+const (
+	Float   = "float"
+	Integer = "integer"
+	Boolean = "boolean"
+	String  = "string"
+)
+
+// Spec maps the columns of a CSV file to the parts of a point.
+type Spec struct {
+	// Measurement is the name of every point, unless MeasurementColumn names
+	// the column holding the name of each point.
+	Measurement       string
+	MeasurementColumn string
+
+	// Tags are the columns stored as tags. Fields are the columns stored as
+	// fields. Every column that isn't a tag or the time is a field when
+	// Fields is empty.
+	Tags   []string
+	Fields []string
+
+	// Time is the column holding the timestamp. Points are written at the
+	// current time when it's empty.
+	Time string
+
+	// TimeFormat is "rfc3339", a precision of unix timestamps (n, u, ms, s,
+	// m, h or w) or a Go time layout. Defaults to rfc3339.
+	TimeFormat string
+
+	// Types sets the type of field columns to float, integer, boolean or
+	// string. The values of other field columns are booleans or floats if
+	// they can be parsed as one, and strings otherwise.
+	Types map[string]string
+}
+
+// ParseSpec parses a spec of semicolon separated settings, such as:
+//
+//	measurement=cpu;tags=host,region;fields=value,status;types=status:string;time=ts;time-format=s
+//
+// The measurement can be read from a column with measurement-column=name.
+func ParseSpec(s string) (Spec, error) {
+	var spec Spec
+	for _, kv := range strings.Split(s, ";") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+
+		i := strings.Index(kv, "=")
+		if i == -1 {
+			return Spec{}, fmt.Errorf("invalid setting %q: expected key=value", kv)
+		}
+		key, value := strings.TrimSpace(kv[:i]), strings.TrimSpace(kv[i+1:])
+
+		switch key {
+		case "measurement":
+			spec.Measurement = value
+		case "measurement-column":
+			spec.MeasurementColumn = value
+		case "tags":
+			spec.Tags = SplitColumns(value)
+		case "fields":
+			spec.Fields = SplitColumns(value)
+		case "time":
+			spec.Time = value
+		case "time-format":
+			spec.TimeFormat = value
+		case "types":
+			types, err := ParseTypes(value)
+			if err != nil {
+				return Spec{}, err
+			}
+			spec.Types = types
+		default:
+			return Spec{}, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return spec, spec.Validate()
+}
+
+// SplitColumns splits a comma separated list of column names.
+func SplitColumns(s string) []string {
+	var a []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			a = append(a, name)
+		}
+	}
+	return a
+}
+
+// ParseTypes parses a comma separated list of column types, such as
+// "value:integer,status:string".
+func ParseTypes(s string) (map[string]string, error) {
+	types := make(map[string]string)
+	for _, ct := range SplitColumns(s) {
+		i := strings.Index(ct, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("invalid type %q: expected column:type", ct)
+		}
+		types[strings.TrimSpace(ct[:i])] = strings.TrimSpace(ct[i+1:])
+	}
+	return types, nil
+}
+
+// Validate returns an error if the spec can't be used to convert rows.
+func (s *Spec) Validate() error {
+	if s.Measurement == "" && s.MeasurementColumn == "" {
+		return ErrMeasurementRequired
+	} else if s.Measurement != "" && s.MeasurementColumn != "" {
+		return errors.New("measurement and measurement column are both set")
+	}
+	if c := s.MeasurementColumn; c != "" {
+		if c == s.Time || containsString(s.Tags, c) || containsString(s.Fields, c) {
+			return fmt.Errorf("column %q is the measurement and another part of points", c)
+		}
+	}
+	for column, typ := range s.Types {
+		switch typ {
+		case Float, Integer, Boolean, String:
+		default:
+			return fmt.Errorf("unknown type %q of column %q: must be one of float, integer, boolean or string", typ, column)
+		}
+	}
+	for _, tag := range s.Tags {
+		if tag == s.Time {
+			return fmt.Errorf("column %q is both a tag and the time", tag)
+		}
+		for _, field := range s.Fields {
+			if tag == field {
+				return fmt.Errorf("column %q is both a tag and a field", tag)
+			}
+		}
+	}
+	return nil
+}
+
+// Reader reads points from a CSV file. It's used like a tsdb.PointScanner:
+//
+//	r := csv.NewReader(f, spec)
+//	for r.Next() {
+//		pt := r.Point()
+//		...
+//	}
+//	if err := r.Err(); err != nil {
+//		...
+//	}
+type Reader struct {
+	r    *csv.Reader
+	spec Spec
+	row  int
+	pt   tsdb.Point
+	err  error
+
+	// The indexes of the columns, read from the header row.
+	header      []string
+	measurement int
+	tags        []int
+	fields      []int
+	time        int
+
+	// DefaultTime is the time of points when the spec has no time column.
+	// Defaults to the current time and must be set before the first call to Next.
+	DefaultTime time.Time
+}
+
+// NewReader returns a reader converting the rows of r to points with spec.
+func NewReader(r io.Reader, spec Spec) *Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return &Reader{
+		r:           cr,
+		spec:        spec,
+		measurement: -1,
+		time:        -1,
+		DefaultTime: time.Now().UTC(),
+	}
+}
+
+// Next converts the next row, which is then returned by Point. It returns
+// false at the end of the file or on the first error, which is returned by Err.
+func (r *Reader) Next() bool {
+	r.pt = nil
+	if r.err != nil {
+		return false
+	}
+
+	if r.header == nil {
+		if r.err = r.readHeader(); r.err != nil {
+			return false
+		}
+	}
+
+	for {
+		record, err := r.r.Read()
+		if err != nil {
+			r.err = err
+			return false
+		}
+		r.row++
+
+		// Skip blank rows.
+		if len(record) == 1 && record[0] == "" {
+			continue
+		}
+
+		pt, err := r.convert(record)
+		if err != nil {
+			r.err = fmt.Errorf("row %d: %s", r.row, err)
+			return false
+		}
+		r.pt = pt
+		return true
+	}
+}
+
+// Point returns the point converted by the last call to Next.
+func (r *Reader) Point() tsdb.Point { return r.pt }
+
+// Err returns the error that stopped the reader, if it isn't the end of the file.
+func (r *Reader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+// readHeader reads the names of the columns and finds the columns of the spec.
+func (r *Reader) readHeader() error {
+	header, err := r.r.Read()
+	if err == io.EOF {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("header: %s", err)
+	}
+	r.header = header
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		index[header[i]] = i
+	}
+	lookup := func(name string) (int, error) {
+		i, ok := index[name]
+		if !ok {
+			return 0, fmt.Errorf("column not found: %q", name)
+		}
+		return i, nil
+	}
+
+	for _, name := range r.spec.Tags {
+		i, err := lookup(name)
+		if err != nil {
+			return err
+		}
+		r.tags = append(r.tags, i)
+	}
+	if r.spec.Time != "" {
+		if r.time, err = lookup(r.spec.Time); err != nil {
+			return err
+		}
+	}
+	if r.spec.MeasurementColumn != "" {
+		if r.measurement, err = lookup(r.spec.MeasurementColumn); err != nil {
+			return err
+		}
+	}
+
+	if len(r.spec.Fields) > 0 {
+		for _, name := range r.spec.Fields {
+			i, err := lookup(name)
+			if err != nil {
+				return err
+			}
+			r.fields = append(r.fields, i)
+		}
+		return nil
+	}
+
+	// Every remaining column is a field.
+	for i := range header {
+		if i != r.time && i != r.measurement && !containsInt(r.tags, i) {
+			r.fields = append(r.fields, i)
+		}
+	}
+	return nil
+}
+
+// convert returns the point of a row. Empty tag and field values are skipped.
+func (r *Reader) convert(record []string) (tsdb.Point, error) {
+	var tags tsdb.Tags
+	for _, i := range r.tags {
+		if v := column(record, i); v != "" {
+			tags.Set(r.header[i], v)
+		}
+	}
+
+	fields := make(tsdb.Fields, len(r.fields))
+	for _, i := range r.fields {
+		v := column(record, i)
+		if v == "" {
+			continue
+		}
+
+		name := r.header[i]
+		typ, ok := r.spec.Types[name]
+		if !ok {
+			fields[name] = parseValue(v)
+			continue
+		}
+		value, err := parseTypedValue(v, typ)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %s", name, err)
+		}
+		fields[name] = value
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("no field values")
+	}
+
+	name := r.spec.Measurement
+	if r.measurement >= 0 {
+		if name = column(record, r.measurement); name == "" {
+			return nil, errors.New("missing measurement")
+		}
+	}
+
+	t := r.DefaultTime
+	if r.time >= 0 {
+		v := column(record, r.time)
+		if v == "" {
+			return nil, errors.New("missing time")
+		}
+
+		var err error
+		if t, err = ParseTime(v, r.spec.TimeFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	return tsdb.NewPoint(name, tags, fields, t), nil
+}
+
+// ReadPoints returns the points of all the rows of a CSV file.
+func ReadPoints(r io.Reader, spec Spec) ([]tsdb.Point, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var points []tsdb.Point
+	cr := NewReader(r, spec)
+	for cr.Next() {
+		points = append(points, cr.Point())
+	}
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// ParseTime parses a timestamp in one of the formats of Spec.TimeFormat.
+func ParseTime(s, format string) (time.Time, error) {
+	if format == "" || format == DefaultTimeFormat {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	precision, err := tsdb.ParsePrecision(format)
+	if err != nil {
+		return time.Parse(format, s)
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %q", s)
+	}
+	return time.Unix(0, n*int64(precision.Duration())).UTC(), nil
+}
+
+// parseValue returns a field value as a bool or a float if it can be parsed as
+// one, or as a string otherwise. Numbers are always floats so a column doesn't
+// change type when some of its values have a decimal point.
+func parseValue(s string) interface{} {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v
+	} else if v, err := strconv.ParseBool(s); err == nil {
+		return v
+	}
+	return s
+}
+
+// parseTypedValue returns a field value of a column given a type in Spec.Types.
+func parseTypedValue(s, typ string) (interface{}, error) {
+	switch typ {
+	case Float:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v, nil
+		}
+	case Integer:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v, nil
+		}
+	case Boolean:
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v, nil
+		}
+	case String:
+		return s, nil
+	}
+	return nil, fmt.Errorf("invalid %s: %q", typ, s)
+}
+
+// column returns the value of the ith column of a row, or an empty string if
+// the row is too short.
+func column(record []string, i int) string {
+	if i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func containsInt(a []int, v int) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(a []string, v string) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
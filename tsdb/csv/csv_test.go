@@ -0,0 +1,161 @@
+package csv_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb/csv"
+)
+
+// Ensure a spec can be parsed from its string form.
+func TestParseSpec(t *testing.T) {
+	spec, err := csv.ParseSpec("measurement=cpu; tags=host,region; fields=value; time=ts; time-format=ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := csv.Spec{
+		Measurement: "cpu",
+		Tags:        []string{"host", "region"},
+		Fields:      []string{"value"},
+		Time:        "ts",
+		TimeFormat:  "ms",
+	}
+	if !reflect.DeepEqual(spec, exp) {
+		t.Fatalf("unexpected spec: %#v", spec)
+	}
+
+	spec, err = csv.ParseSpec("measurement-column=name; types=value:integer,ok:boolean")
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := (csv.Spec{MeasurementColumn: "name", Types: map[string]string{"value": "integer", "ok": "boolean"}}); !reflect.DeepEqual(spec, exp) {
+		t.Fatalf("unexpected spec: %#v", spec)
+	}
+
+	for _, s := range []string{
+		"tags=host",
+		"measurement=cpu;foo=bar",
+		"measurement",
+		"measurement=cpu;tags=host;fields=host",
+		"measurement=cpu;measurement-column=name",
+		"measurement-column=host;tags=host",
+		"measurement=cpu;types=value",
+		"measurement=cpu;types=value:int",
+	} {
+		if _, err := csv.ParseSpec(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+// Ensure the reader converts rows to points.
+func TestReader(t *testing.T) {
+	data := "host,region,value,status,ts\n" +
+		"serverA,west,1,ok,2015-09-01T00:00:00Z\n" +
+		"serverB,,2.5,true,2015-09-01T00:00:01Z\n"
+	r := csv.NewReader(strings.NewReader(data), csv.Spec{Measurement: "cpu", Tags: []string{"host", "region"}, Time: "ts"})
+
+	var a []string
+	for r.Next() {
+		a = append(a, r.Point().String())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		`cpu,host=serverA,region=west status="ok",value=1.0 1441065600000000000`,
+		`cpu,host=serverB status=true,value=2.5 1441065601000000000`,
+	}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected points:\n%s", strings.Join(a, "\n"))
+	}
+}
+
+// Ensure the measurement can be read from a column and fields can be given a
+// type.
+func TestReadPoints_Types(t *testing.T) {
+	data := "name,host,value,code,ts\n" +
+		"cpu,serverA,1,200,10\n" +
+		"mem,serverB,2.5,404,20\n"
+	points, err := csv.ReadPoints(strings.NewReader(data), csv.Spec{
+		MeasurementColumn: "name",
+		Tags:              []string{"host"},
+		Time:              "ts",
+		TimeFormat:        "s",
+		Types:             map[string]string{"code": csv.String, "value": csv.Float},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a []string
+	for _, p := range points {
+		a = append(a, p.String())
+	}
+	exp := []string{
+		`cpu,host=serverA code="200",value=1.0 10000000000`,
+		`mem,host=serverB code="404",value=2.5 20000000000`,
+	}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected points:\n%s", strings.Join(a, "\n"))
+	}
+}
+
+// Ensure only the fields of the spec are read and the default time is used
+// without a time column.
+func TestReader_Fields(t *testing.T) {
+	r := csv.NewReader(strings.NewReader("a,b,c\n1,2,3\n"), csv.Spec{Measurement: "m", Fields: []string{"b"}})
+	r.DefaultTime = time.Unix(0, 100)
+
+	if !r.Next() {
+		t.Fatalf("unexpected error: %v", r.Err())
+	} else if s := r.Point().String(); s != "m b=2.0 100" {
+		t.Fatalf("unexpected point: %s", s)
+	} else if r.Next() {
+		t.Fatal("expected end of file")
+	}
+}
+
+// Ensure the reader stops at the first malformed row.
+func TestReader_Error(t *testing.T) {
+	for i, tt := range []struct {
+		data string
+		spec csv.Spec
+		err  string
+	}{
+		{data: "a\n1\n", spec: csv.Spec{Measurement: "m", Tags: []string{"b"}}, err: `column not found: "b"`},
+		{data: "a,ts\n1,10\n2,x\n", spec: csv.Spec{Measurement: "m", Time: "ts", TimeFormat: "s"}, err: `row 2: invalid timestamp: "x"`},
+		{data: "a,ts\n,10\n", spec: csv.Spec{Measurement: "m", Time: "ts", TimeFormat: "s"}, err: "row 1: no field values"},
+		{data: "a,ts\n1,\n", spec: csv.Spec{Measurement: "m", Time: "ts"}, err: "row 1: missing time"},
+		{data: "a,b\n1,x\n", spec: csv.Spec{Measurement: "m", Types: map[string]string{"b": csv.Integer}}, err: `row 1: column "b": invalid integer: "x"`},
+		{data: "name,a\n,1\n", spec: csv.Spec{MeasurementColumn: "name"}, err: "row 1: missing measurement"},
+	} {
+		r := csv.NewReader(strings.NewReader(tt.data), tt.spec)
+		for r.Next() {
+		}
+		if err := r.Err(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
+
+// Ensure timestamps are parsed in each format.
+func TestParseTime(t *testing.T) {
+	for _, tt := range []struct {
+		s, format string
+		exp       time.Time
+	}{
+		{s: "2015-09-01T00:00:00.5Z", format: "", exp: time.Unix(1441065600, 5e8)},
+		{s: "1441065600", format: "s", exp: time.Unix(1441065600, 0)},
+		{s: "1441065600500", format: "ms", exp: time.Unix(1441065600, 5e8)},
+		{s: "2015-09-01 00:00", format: "2006-01-02 15:04", exp: time.Unix(1441065600, 0)},
+	} {
+		if v, err := csv.ParseTime(tt.s, tt.format); err != nil {
+			t.Errorf("%s: %s", tt.s, err)
+		} else if !v.Equal(tt.exp) {
+			t.Errorf("%s: unexpected time: %s", tt.s, v)
+		}
+	}
+}
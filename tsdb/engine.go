@@ -57,6 +57,22 @@ func NewEngine(path string, walPath string, options EngineOptions) (Engine, erro
 		return newEngineFuncs[options.EngineVersion](path, walPath, options), nil
 	}
 
+	format, err := EngineFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup engine by format.
+	fn := newEngineFuncs[format]
+	if fn == nil {
+		return nil, fmt.Errorf("invalid engine format: %q", format)
+	}
+
+	return fn(path, walPath, options), nil
+}
+
+// EngineFormat returns the format of the engine stored at path.
+func EngineFormat(path string) (string, error) {
 	// Only bolt-based backends are currently supported so open it and check the format.
 	var format string
 	if err := func() error {
@@ -84,16 +100,10 @@ func NewEngine(path string, walPath string, options EngineOptions) (Engine, erro
 			return nil
 		})
 	}(); err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// Lookup engine by format.
-	fn := newEngineFuncs[format]
-	if fn == nil {
-		return nil, fmt.Errorf("invalid engine format: %q", format)
-	}
-
-	return fn(path, walPath, options), nil
+	return format, nil
 }
 
 // EngineOptions represents the options used to initialize the engine.
@@ -127,6 +137,12 @@ type Tx interface {
 	Rollback() error
 }
 
+// RangeTx is implemented by transactions that can limit a cursor to a time range.
+// Data entirely outside of the range can then be skipped without being read.
+type RangeTx interface {
+	CursorRange(series string, tmin, tmax int64) Cursor
+}
+
 // Cursor represents an iterator over a series.
 type Cursor interface {
 	Seek(seek []byte) (key, value []byte)
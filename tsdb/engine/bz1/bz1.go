@@ -36,6 +36,9 @@ func init() {
 const (
 	// DefaultBlockSize is the default size of uncompressed points blocks.
 	DefaultBlockSize = 64 * 1024 // 64KB
+
+	// blockHeaderSize is the size of the header of blocks that store their codec.
+	blockHeaderSize = 13
 )
 
 // Ensure Engine implements the interface.
@@ -47,11 +50,22 @@ type Engine struct {
 	path string
 	db   *bolt.DB
 
+	// Serializes index writes with the creation of read snapshots so a
+	// transaction never misses points that are moving from the WAL to the index.
+	snapshotMu sync.RWMutex
+
 	// Write-ahead log storage.
 	WAL WAL
 
 	// Size of uncompressed points to write to a block.
 	BlockSize int
+
+	// Codec compresses new blocks. Files created before blocks stored their
+	// codec always use snappy.
+	Codec tsdb.Codec
+
+	// Set if blocks store their codec and uncompressed size in their header.
+	codecHeaders bool
 }
 
 // WAL represents a write ahead log that can be queried
@@ -60,6 +74,7 @@ type WAL interface {
 	LoadMetadataIndex(index *tsdb.DatabaseIndex, measurementFields map[string]*tsdb.MeasurementFields) error
 	DeleteSeries(keys []string) error
 	Cursor(key string) tsdb.Cursor
+	Snapshot() *wal.Snapshot
 	Open() error
 	Close() error
 }
@@ -81,8 +96,12 @@ func NewEngine(path string, walPath string, opt tsdb.EngineOptions) tsdb.Engine
 		path: path,
 
 		BlockSize: DefaultBlockSize,
+		Codec:     tsdb.CodecByName(opt.Config.BlockCompression),
 		WAL:       w,
 	}
+	if e.Codec == nil {
+		e.Codec = tsdb.CodecByName(tsdb.DefaultCodec)
+	}
 
 	w.Index = e
 
@@ -109,13 +128,17 @@ func (e *Engine) Open() error {
 		if err := e.db.Update(func(tx *bolt.Tx) error {
 			_, _ = tx.CreateBucketIfNotExists([]byte("points"))
 
-			// Set file format, if not set yet.
+			// Set file format, if not set yet. New files store the codec
+			// of each block in its header.
 			b, _ := tx.CreateBucketIfNotExists([]byte("meta"))
 			if v := b.Get([]byte("format")); v == nil {
 				if err := b.Put([]byte("format"), []byte(Format)); err != nil {
 					return fmt.Errorf("set format: %s", err)
+				} else if err := b.Put([]byte("block-codecs"), []byte{1}); err != nil {
+					return fmt.Errorf("set block codecs: %s", err)
 				}
 			}
+			e.codecHeaders = b.Get([]byte("block-codecs")) != nil
 
 			return nil
 		}); err != nil {
@@ -131,6 +154,10 @@ func (e *Engine) Open() error {
 	return nil
 }
 
+// FileN returns the number of file handles held while the engine is open: the
+// index file and the current segment file of each WAL partition.
+func (e *Engine) FileN() int { return 1 + wal.PartitionCount }
+
 // Close closes the engine.
 func (e *Engine) Close() error {
 	e.mu.Lock()
@@ -157,7 +184,7 @@ func (e *Engine) SetLogOutput(w io.Writer) {}
 func (e *Engine) LoadMetadataIndex(index *tsdb.DatabaseIndex, measurementFields map[string]*tsdb.MeasurementFields) error {
 	if err := e.db.View(func(tx *bolt.Tx) error {
 		// Load measurement metadata
-		fields, err := e.readFields(tx)
+		fields, err := readFields(tx)
 		if err != nil {
 			return err
 		}
@@ -215,6 +242,9 @@ func (e *Engine) WritePoints(points []tsdb.Point, measurementFieldsToSave map[st
 
 // WriteIndex writes marshaled points to the engine's underlying index.
 func (e *Engine) WriteIndex(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
+	e.snapshotMu.Lock()
+	defer e.snapshotMu.Unlock()
+
 	return e.db.Update(func(tx *bolt.Tx) error {
 		// Write series & field metadata.
 		if err := e.writeNewSeries(tx, seriesToCreate); err != nil {
@@ -239,7 +269,7 @@ func (e *Engine) writeNewFields(tx *bolt.Tx, measurementFieldsToSave map[string]
 	}
 
 	// read in all the previously saved fields
-	fields, err := e.readFields(tx)
+	fields, err := readFields(tx)
 	if err != nil {
 		return err
 	}
@@ -262,7 +292,7 @@ func (e *Engine) writeFields(tx *bolt.Tx, fields map[string]*tsdb.MeasurementFie
 	return tx.Bucket([]byte("meta")).Put([]byte("fields"), snappy.Encode(nil, data))
 }
 
-func (e *Engine) readFields(tx *bolt.Tx) (map[string]*tsdb.MeasurementFields, error) {
+func readFields(tx *bolt.Tx) (map[string]*tsdb.MeasurementFields, error) {
 	fields := make(map[string]*tsdb.MeasurementFields)
 
 	b := tx.Bucket([]byte("meta")).Get([]byte("fields"))
@@ -282,6 +312,30 @@ func (e *Engine) readFields(tx *bolt.Tx) (map[string]*tsdb.MeasurementFields, er
 	return fields, nil
 }
 
+// ReadFields returns the measurement fields stored in the shard at path. The
+// shard is opened read-only so it can be read while its node is stopped
+// without replaying its WAL.
+func ReadFields(path string) (map[string]*tsdb.MeasurementFields, error) {
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var fields map[string]*tsdb.MeasurementFields
+	if err := db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("meta")) == nil {
+			fields = make(map[string]*tsdb.MeasurementFields)
+			return nil
+		}
+		fields, err = readFields(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
 func (e *Engine) writeNewSeries(tx *bolt.Tx, seriesToCreate []*tsdb.SeriesCreate) error {
 	if len(seriesToCreate) == 0 {
 		return nil
@@ -368,9 +422,9 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 		return nil
 	} else {
 		// Determine uncompressed block size.
-		sz, err := snappy.DecodedLen(v[8:])
+		sz, err := decodedBlockSize(v, e.codecHeaders)
 		if err != nil {
-			return fmt.Errorf("snappy decoded len: %s", err)
+			return fmt.Errorf("decoded block size: %s", err)
 		}
 
 		// Append new blocks if our time range is past the last on-disk time
@@ -407,7 +461,7 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 		}
 
 		// Decode block.
-		buf, err := snappy.Decode(nil, v[8:])
+		buf, err := decodeBlock(v, e.codecHeaders)
 		if err != nil {
 			return fmt.Errorf("decode block: %s", err)
 		}
@@ -457,13 +511,8 @@ func (e *Engine) writeBlocks(bkt *bolt.Bucket, a [][]byte) error {
 		// If the block is larger than the target block size or this is the
 		// last point then flush the block to the bucket.
 		if len(block) >= e.BlockSize || i == len(a)-1 {
-			// Encode block in the following format:
-			//   tmax int64
-			//   data []byte (snappy compressed)
-			value := append(u64tob(uint64(tmax)), snappy.Encode(nil, block)...)
-
 			// Write block to the bucket.
-			if err := bkt.Put(u64tob(uint64(tmin)), value); err != nil {
+			if err := bkt.Put(u64tob(uint64(tmin)), e.encodeBlock(tmax, block)); err != nil {
 				return fmt.Errorf("put: ts=%d-%d, err=%s", tmin, tmax, err)
 			}
 
@@ -476,6 +525,51 @@ func (e *Engine) writeBlocks(bkt *bolt.Bucket, a [][]byte) error {
 	return nil
 }
 
+// encodeBlock compresses a block of entries in the following format:
+//   tmax  int64
+//   codec byte   (only if the file stores block codecs)
+//   size  uint32 (uncompressed size, only if the file stores block codecs)
+//   data  []byte
+func (e *Engine) encodeBlock(tmax int64, block []byte) []byte {
+	if !e.codecHeaders {
+		return append(u64tob(uint64(tmax)), snappy.Encode(nil, block)...)
+	}
+
+	var hdr [blockHeaderSize]byte
+	binary.BigEndian.PutUint64(hdr[0:8], uint64(tmax))
+	hdr[8] = e.Codec.ID()
+	binary.BigEndian.PutUint32(hdr[9:13], uint32(len(block)))
+	return append(hdr[:], e.Codec.Encode(block)...)
+}
+
+// decodeBlock returns the uncompressed entries of a block.
+func decodeBlock(v []byte, codecHeaders bool) ([]byte, error) {
+	if !codecHeaders {
+		return snappy.Decode(nil, v[8:])
+	}
+
+	if len(v) < blockHeaderSize {
+		return nil, fmt.Errorf("block too short: %d", len(v))
+	}
+	c := tsdb.CodecByID(v[8])
+	if c == nil {
+		return nil, fmt.Errorf("unknown block codec: %d", v[8])
+	}
+	return c.Decode(v[blockHeaderSize:])
+}
+
+// decodedBlockSize returns the uncompressed size of a block.
+func decodedBlockSize(v []byte, codecHeaders bool) (int, error) {
+	if !codecHeaders {
+		return snappy.DecodedLen(v[8:])
+	}
+
+	if len(v) < blockHeaderSize {
+		return 0, fmt.Errorf("block too short: %d", len(v))
+	}
+	return int(binary.BigEndian.Uint32(v[9:13])), nil
+}
+
 // DeleteSeries deletes the series from the engine.
 func (e *Engine) DeleteSeries(keys []string) error {
 	// remove it from the WAL first
@@ -507,7 +601,7 @@ func (e *Engine) DeleteMeasurement(name string, seriesKeys []string) error {
 	}
 
 	return e.db.Update(func(tx *bolt.Tx) error {
-		fields, err := e.readFields(tx)
+		fields, err := readFields(tx)
 		if err != nil {
 			return err
 		}
@@ -543,13 +637,115 @@ func (e *Engine) SeriesCount() (n int, err error) {
 	return
 }
 
-// Begin starts a new transaction on the engine.
+// Begin starts a new transaction on the engine. Read-only transactions hold a
+// WAL snapshot taken together with the index transaction. Bolt keeps the pages
+// the transaction reads until it's closed and the snapshot keeps the points
+// flushed from the WAL since, so no point is missed or partially seen while
+// writes and compactions continue. The transaction must be rolled back to
+// release both.
 func (e *Engine) Begin(writable bool) (tsdb.Tx, error) {
-	tx, err := e.db.Begin(writable)
+	if writable {
+		tx, err := e.db.Begin(true)
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{Tx: tx, engine: e, wal: e.WAL}, nil
+	}
+
+	e.snapshotMu.RLock()
+	defer e.snapshotMu.RUnlock()
+
+	tx, err := e.db.Begin(false)
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{Tx: tx, engine: e, wal: e.WAL}, nil
+	return &Tx{Tx: tx, engine: e, wal: e.WAL, snapshot: e.WAL.Snapshot()}, nil
+}
+
+// CheckIntegrity decodes every block of the sampled series and checks their
+// entries are within the block's time range, and that series with blocks have
+// metadata. Series still in the WAL have no blocks yet. It also checks the
+// segment files of the WAL are contiguous.
+func (e *Engine) CheckIntegrity(keys []string, r *tsdb.IntegrityReport) error {
+	if err := e.db.View(func(tx *bolt.Tx) error {
+		series, err := e.readSeries(tx)
+		if err != nil {
+			r.Errorf("series metadata: %s", err)
+		}
+
+		points := tx.Bucket([]byte("points"))
+		for _, key := range keys {
+			bkt := points.Bucket([]byte(key))
+			if bkt == nil {
+				continue
+			}
+			if _, ok := series[key]; !ok && err == nil {
+				r.MissingSeries++
+				r.Errorf("series %s: blocks without metadata", key)
+			}
+
+			c := bkt.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				r.BlocksChecked++
+				if err := checkBlock(k, v, e.codecHeaders); err != nil {
+					r.CorruptBlocks++
+					r.Errorf("series %s: block %d: %s", key, int64(btou64(k)), err)
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if w, ok := e.WAL.(interface {
+		CheckSegments() (n int, gaps []string, err error)
+	}); ok {
+		n, gaps, err := w.CheckSegments()
+		if err != nil {
+			return err
+		}
+		r.WALSegments += n
+		r.WALGaps += len(gaps)
+		for _, gap := range gaps {
+			r.Errorf("wal: %s", gap)
+		}
+	}
+	return nil
+}
+
+// checkBlock returns an error if a block can't be decoded or holds entries
+// out of order or outside of the time range of its key and header.
+func checkBlock(k, v []byte, codecHeaders bool) error {
+	if len(v) < 8 {
+		return fmt.Errorf("block too short: %d", len(v))
+	}
+	buf, err := decodeBlock(v, codecHeaders)
+	if err != nil {
+		return fmt.Errorf("decode: %s", err)
+	}
+	if codecHeaders {
+		if sz, _ := decodedBlockSize(v, codecHeaders); sz != len(buf) {
+			return fmt.Errorf("decoded %d bytes, header says %d", len(buf), sz)
+		}
+	}
+
+	tmin, tmax := int64(btou64(k)), int64(btou64(v[0:8]))
+	last := int64(math.MinInt64)
+	for len(buf) > 0 {
+		if len(buf) < entryHeaderSize || len(buf) < entryHeaderSize+entryDataSize(buf) {
+			return fmt.Errorf("truncated entry")
+		}
+		ts := int64(btou64(buf[0:8]))
+		if ts < tmin || ts > tmax {
+			return fmt.Errorf("entry at %d outside of block range %d - %d", ts, tmin, tmax)
+		} else if ts <= last {
+			return fmt.Errorf("entry at %d out of order", ts)
+		}
+		last = ts
+		buf = buf[entryHeaderSize+entryDataSize(buf):]
+	}
+	return nil
 }
 
 // Stats returns internal statistics for the engine.
@@ -581,13 +777,35 @@ type Stats struct {
 // Tx represents a transaction.
 type Tx struct {
 	*bolt.Tx
-	engine *Engine
-	wal    WAL
+	engine   *Engine
+	wal      WAL
+	snapshot *wal.Snapshot
+}
+
+// Rollback closes the transaction and releases its WAL snapshot.
+func (tx *Tx) Rollback() error {
+	if tx.snapshot != nil {
+		tx.snapshot.Release()
+		tx.snapshot = nil
+	}
+	return tx.Tx.Rollback()
 }
 
 // Cursor returns an iterator for a key.
 func (tx *Tx) Cursor(key string) tsdb.Cursor {
-	walCursor := tx.wal.Cursor(key)
+	return tx.CursorRange(key, math.MinInt64, math.MaxInt64)
+}
+
+// CursorRange returns an iterator for a key that only reads the blocks that
+// overlap the time range from tmin to tmax. Blocks store their min and max
+// timestamps uncompressed so the ones outside the range are never decoded.
+func (tx *Tx) CursorRange(key string, tmin, tmax int64) tsdb.Cursor {
+	var walCursor tsdb.Cursor
+	if tx.snapshot != nil {
+		walCursor = tx.snapshot.Cursor(key)
+	} else {
+		walCursor = tx.wal.Cursor(key)
+	}
 
 	// Retrieve points bucket. Ignore if there is no bucket.
 	b := tx.Bucket([]byte("points")).Bucket([]byte(key))
@@ -595,9 +813,20 @@ func (tx *Tx) Cursor(key string) tsdb.Cursor {
 		return walCursor
 	}
 
+	// Ignore the index if the series' time range doesn't overlap the requested range.
+	bc := b.Cursor()
+	if k, _ := bc.First(); k == nil || int64(btou64(k)) > tmax {
+		return walCursor
+	}
+	if _, v := bc.Last(); int64(btou64(v[0:8])) < tmin {
+		return walCursor
+	}
+
 	c := &Cursor{
-		cursor: b.Cursor(),
-		buf:    make([]byte, DefaultBlockSize),
+		cursor:       bc,
+		buf:          make([]byte, DefaultBlockSize),
+		tmax:         tmax,
+		codecHeaders: tx.engine.codecHeaders,
 	}
 
 	return tsdb.MultiCursor(walCursor, c)
@@ -608,13 +837,30 @@ type Cursor struct {
 	cursor *bolt.Cursor
 	buf    []byte // uncompressed buffer
 	off    int    // buffer offset
+	tmax   int64  // blocks starting after this time are not read
+
+	codecHeaders bool // blocks store their codec in their header
 }
 
 // Seek moves the cursor to a position and returns the closest key/value pair.
 func (c *Cursor) Seek(seek []byte) (key, value []byte) {
-	// Move cursor to appropriate block and set to buffer.
-	_, v := c.cursor.Seek(seek)
-	c.setBuf(v)
+	// Move cursor to the block that contains the seek position. Blocks are keyed
+	// by their min time so check the previous block's max time as well.
+	k, v := c.cursor.Seek(seek)
+	if k == nil {
+		if k, v = c.cursor.Last(); k != nil && bytes.Compare(v[0:8], seek) == -1 {
+			k, v = nil, nil
+		}
+	} else if bytes.Compare(k, seek) == 1 {
+		if pk, pv := c.cursor.Prev(); pk == nil {
+			k, v = c.cursor.First()
+		} else if bytes.Compare(pv[0:8], seek) != -1 {
+			k, v = pk, pv
+		} else {
+			k, v = c.cursor.Next()
+		}
+	}
+	c.setBlock(k, v)
 
 	// Read current block up to seek position.
 	c.seekBuf(seek)
@@ -651,13 +897,21 @@ func (c *Cursor) Next() (key, value []byte) {
 
 	// If no items left then read first item from next block.
 	if c.off >= len(c.buf) {
-		_, v := c.cursor.Next()
-		c.setBuf(v)
+		c.setBlock(c.cursor.Next())
 	}
 
 	return c.read()
 }
 
+// setBlock sets the buffer to the block with min time k. Blocks that start
+// after the cursor's max time are skipped without being decoded.
+func (c *Cursor) setBlock(k, v []byte) {
+	if k != nil && int64(btou64(k)) > c.tmax {
+		v = nil
+	}
+	c.setBuf(v)
+}
+
 // setBuf saves a compressed block to the buffer.
 func (c *Cursor) setBuf(block []byte) {
 	// Clear if the block is empty.
@@ -667,8 +921,7 @@ func (c *Cursor) setBuf(block []byte) {
 	}
 
 	// Otherwise decode block into buffer.
-	// Skip over the first 8 bytes since they are the max timestamp.
-	buf, err := snappy.Decode(nil, block[8:])
+	buf, err := decodeBlock(block, c.codecHeaders)
 	if err != nil {
 		c.buf = c.buf[0:0]
 		log.Printf("block decode error: %s", err)
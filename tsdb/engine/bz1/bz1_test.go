@@ -15,6 +15,8 @@ import (
 	"testing/quick"
 	"time"
 
+	"github.com/boltdb/bolt"
+	"github.com/golang/snappy"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/tsdb"
 	"github.com/influxdb/influxdb/tsdb/engine/bz1"
@@ -28,8 +30,8 @@ func TestEngine_LoadMetadataIndex_Series(t *testing.T) {
 
 	// Setup mock that writes the index
 	seriesToCreate := []*tsdb.SeriesCreate{
-		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), map[string]string{"host": "server0"})), map[string]string{"host": "server0"})},
-		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), map[string]string{"host": "server1"})), map[string]string{"host": "server1"})},
+		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tsdb.NewTags(map[string]string{"host": "server0"}))), map[string]string{"host": "server0"})},
+		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tsdb.NewTags(map[string]string{"host": "server1"}))), map[string]string{"host": "server1"})},
 		{Series: tsdb.NewSeries("series with spaces", nil)},
 	}
 	e.PointsWriter.WritePointsFn = func(a []tsdb.Point) error { return e.WriteIndex(nil, nil, seriesToCreate) }
@@ -95,6 +97,33 @@ func TestEngine_LoadMetadataIndex_Fields(t *testing.T) {
 	}
 }
 
+// Ensure the field metadata of a closed shard can be read.
+func TestReadFields(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	fields := map[string]*tsdb.MeasurementFields{
+		"cpu": &tsdb.MeasurementFields{
+			Fields: map[string]*tsdb.Field{
+				"value": &tsdb.Field{ID: 0, Name: "value"},
+			},
+		},
+	}
+	if err := e.WriteIndex(nil, fields, nil); err != nil {
+		t.Fatal(err)
+	}
+	e.Engine.Close()
+
+	mfs, err := bz1.ReadFields(e.Path())
+	if err != nil {
+		t.Fatal(err)
+	} else if mf := mfs["cpu"]; mf == nil {
+		t.Fatal("measurement fields not found")
+	} else if !reflect.DeepEqual(mf.Fields, map[string]*tsdb.Field{"value": &tsdb.Field{ID: 0, Name: "value"}}) {
+		t.Fatalf("unexpected fields: %#v", mf.Fields)
+	}
+}
+
 // Ensure the engine can write points to storage.
 func TestEngine_WritePoints_PointsWriter(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -102,11 +131,11 @@ func TestEngine_WritePoints_PointsWriter(t *testing.T) {
 
 	// Points to be inserted.
 	points := []tsdb.Point{
-		tsdb.NewPoint("cpu", tsdb.Tags{}, tsdb.Fields{}, time.Unix(0, 1)),
-		tsdb.NewPoint("cpu", tsdb.Tags{}, tsdb.Fields{}, time.Unix(0, 0)),
-		tsdb.NewPoint("cpu", tsdb.Tags{}, tsdb.Fields{}, time.Unix(1, 0)),
+		tsdb.NewPoint("cpu", tsdb.Tags(nil), tsdb.Fields{}, time.Unix(0, 1)),
+		tsdb.NewPoint("cpu", tsdb.Tags(nil), tsdb.Fields{}, time.Unix(0, 0)),
+		tsdb.NewPoint("cpu", tsdb.Tags(nil), tsdb.Fields{}, time.Unix(1, 0)),
 
-		tsdb.NewPoint("cpu", tsdb.Tags{"host": "serverA"}, tsdb.Fields{}, time.Unix(0, 0)),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{}, time.Unix(0, 0)),
 	}
 
 	// Mock points writer to ensure points are passed through.
@@ -182,6 +211,111 @@ func TestEngine_WriteIndex_Append(t *testing.T) {
 	}
 }
 
+// Ensure new blocks store their codec and uncompressed size in their header.
+func TestEngine_WriteIndex_BlockCodec(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{append(u64tob(1), 0x10), append(u64tob(2), 0x20)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+
+	_, v := tx.(*bz1.Tx).Bucket([]byte("points")).Bucket([]byte("cpu")).Cursor().First()
+	if v[8] != tsdb.SnappyCodecID {
+		t.Fatalf("unexpected codec: %d", v[8])
+	} else if sz := binary.BigEndian.Uint32(v[9:13]); sz != 26 {
+		t.Fatalf("unexpected size: %d", sz)
+	}
+
+	c := tx.Cursor("cpu")
+	if k, v := c.Seek(u64tob(0)); btou64(k) != 1 || !bytes.Equal(v, []byte{0x10}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	}
+}
+
+// Ensure the engine still reads and writes snappy blocks without a codec
+// header in files created before blocks stored their codec.
+func TestEngine_WriteIndex_LegacyBlocks(t *testing.T) {
+	e := NewEngine(tsdb.NewEngineOptions())
+	defer e.Close()
+
+	// Create a data file without block codecs.
+	db, err := bolt.Open(e.Path(), 0666, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b, _ := tx.CreateBucketIfNotExists([]byte("meta"))
+		return b.Put([]byte("format"), []byte(bz1.Format))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for _, ts := range []uint64{2, 1} {
+		if err := e.WriteIndex(map[string][][]byte{"cpu": [][]byte{append(u64tob(ts), byte(ts))}}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+
+	_, v := tx.(*bz1.Tx).Bucket([]byte("points")).Bucket([]byte("cpu")).Cursor().First()
+	if buf, err := snappy.Decode(nil, v[8:]); err != nil {
+		t.Fatal(err)
+	} else if len(buf) != 13 {
+		t.Fatalf("unexpected block size: %d", len(buf))
+	}
+
+	c := tx.Cursor("cpu")
+	if k, v := c.Seek(u64tob(0)); btou64(k) != 1 || !bytes.Equal(v, []byte{1}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, v = c.Next(); btou64(k) != 2 || !bytes.Equal(v, []byte{2}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	}
+}
+
+// Ensure the integrity check finds corrupt blocks and series without metadata.
+func TestEngine_CheckIntegrity(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu":  [][]byte{append(u64tob(1), 0x10), append(u64tob(2), 0x20)},
+		"mem":  [][]byte{append(u64tob(1), 0x30)},
+		"disk": [][]byte{append(u64tob(1), 0x40)},
+	}, nil, []*tsdb.SeriesCreate{
+		{Series: tsdb.NewSeries("cpu", nil)},
+		{Series: tsdb.NewSeries("mem", nil)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the block of mem.
+	tx := e.MustBegin(true)
+	if err := tx.(*bz1.Tx).Bucket([]byte("points")).Bucket([]byte("mem")).Put(u64tob(1), []byte("garbage garbage garbage")); err != nil {
+		t.Fatal(err)
+	} else if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var r tsdb.IntegrityReport
+	if err := e.CheckIntegrity([]string{"cpu", "disk", "mem", "net"}, &r); err != nil {
+		t.Fatal(err)
+	} else if r.BlocksChecked != 3 || r.CorruptBlocks != 1 || r.MissingSeries != 1 || len(r.Errors) != 2 {
+		t.Fatalf("unexpected report: %#v", r)
+	}
+}
+
 // Ensure the engine can rewrite blocks that contain the new point range.
 func TestEngine_WriteIndex_Insert(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -240,6 +374,52 @@ func TestEngine_WriteIndex_Insert(t *testing.T) {
 	}
 }
 
+// Ensure the cursor uses the block time ranges to seek and skip blocks.
+func TestEngine_CursorRange(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	// Write two points per block.
+	e.BlockSize = 26
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{
+			append(u64tob(1), 0x10),
+			append(u64tob(2), 0x20),
+			append(u64tob(3), 0x30),
+			append(u64tob(4), 0x40),
+		},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+
+	// Seek into the middle of the first block.
+	c := tx.Cursor("cpu")
+	if k, v := c.Seek(u64tob(2)); btou64(k) != 2 || !reflect.DeepEqual(v, []byte{0x20}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, _ = c.Next(); btou64(k) != 3 {
+		t.Fatalf("unexpected key: %x", k)
+	}
+
+	// Ensure blocks after the range aren't read.
+	c = tx.(*bz1.Tx).CursorRange("cpu", 0, 2)
+	if k, _ := c.Seek(u64tob(0)); btou64(k) != 1 {
+		t.Fatalf("unexpected key: %x", k)
+	} else if k, _ = c.Next(); btou64(k) != 2 {
+		t.Fatalf("unexpected key: %x", k)
+	} else if k, _ = c.Next(); k != nil {
+		t.Fatalf("unexpected key: %x", k)
+	}
+
+	// Ensure series outside the range are ignored.
+	c = tx.(*bz1.Tx).CursorRange("cpu", 10, 20)
+	if k, _ := c.Seek(u64tob(0)); k != nil {
+		t.Fatalf("unexpected key: %x", k)
+	}
+}
+
 // Ensure the engine ignores writes without keys.
 func TestEngine_WriteIndex_NoKeys(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -479,6 +659,8 @@ func (w *EnginePointsWriter) Close() error { return nil }
 
 func (w *EnginePointsWriter) Cursor(key string) tsdb.Cursor { return &Cursor{} }
 
+func (w *EnginePointsWriter) Snapshot() *wal.Snapshot { return nil }
+
 // Cursor represents a mock that implements tsdb.Curosr.
 type Cursor struct {
 }
@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -57,6 +58,12 @@ const (
 	// CompactionExtension is the file extension we expect for compaction files
 	CompactionExtension = "CPT"
 
+	// SnapshotExtension is the file extension for the cache snapshots written on a clean shutdown
+	SnapshotExtension = "SNP"
+
+	// CleanShutdownFileName is the name of the marker written once all snapshots are on disk
+	CleanShutdownFileName = "CLEAN"
+
 	// MetaFlushInterval is the period after which any compressed meta data in the .meta file will get
 	// flushed to the index
 	MetaFlushInterval = 10 * time.Minute
@@ -116,6 +123,11 @@ type Log struct {
 	mu         sync.RWMutex
 	partitions map[uint8]*Partition
 
+	// snapshots are the open snapshots, which hold on to the points flushed
+	// to the index after they were taken until they're released.
+	snapshotsMu sync.Mutex
+	snapshots   map[*Snapshot]struct{}
+
 	// metaFile is the file that compressed metadata like series and fields are written to
 	metaFile *os.File
 
@@ -152,6 +164,8 @@ type Log struct {
 
 	// EnableLogging specifies if detailed logs should be output
 	EnableLogging bool
+
+	stats *tsdb.Statistics
 }
 
 // IndexWriter is an interface for the indexed database the WAL flushes data to
@@ -191,6 +205,7 @@ func (l *Log) Open() error {
 	if err := os.MkdirAll(l.path, 0777); err != nil {
 		return err
 	}
+	l.stats = tsdb.NewStatistics("wal", "wal", map[string]string{"path": l.path})
 
 	// open the metafile for writing
 	if err := l.nextMetaFile(); err != nil {
@@ -207,8 +222,14 @@ func (l *Log) Open() error {
 		p.log = l
 		l.partitions[uint8(i)] = p
 	}
-	if err := l.openPartitionFiles(); err != nil {
+
+	// Skip replaying the segment files if the cache was snapshotted on a clean shutdown.
+	if ok, err := l.loadSnapshots(); err != nil {
 		return err
+	} else if !ok {
+		if err := l.openPartitionFiles(); err != nil {
+			return err
+		}
 	}
 
 	l.flushCheckTimer = time.NewTimer(l.flushCheckInterval)
@@ -229,6 +250,32 @@ func (l *Log) Cursor(key string) tsdb.Cursor {
 	return l.partition([]byte(key)).cursor(key)
 }
 
+// Snapshot returns a view of the WAL for a reader of the index as it was when
+// the snapshot was taken. Points flushed to the index afterwards are still seen
+// by the snapshot's cursors until it's released. Nothing is copied when the
+// snapshot is taken; each cursor reads the cache of its series when created.
+func (l *Log) Snapshot() *Snapshot {
+	s := &Snapshot{log: l}
+
+	l.snapshotsMu.Lock()
+	defer l.snapshotsMu.Unlock()
+	if l.snapshots == nil {
+		l.snapshots = make(map[*Snapshot]struct{})
+	}
+	l.snapshots[s] = struct{}{}
+	return s
+}
+
+// retainFlushed adds points flushed to the index to every open snapshot. The
+// map is shared by the snapshots and must not be modified afterwards.
+func (l *Log) retainFlushed(pointsByKey map[string][][]byte) {
+	l.snapshotsMu.Lock()
+	defer l.snapshotsMu.Unlock()
+	for s := range l.snapshots {
+		s.flushed = append(s.flushed, pointsByKey)
+	}
+}
+
 func (l *Log) WritePoints(points []tsdb.Point, fields map[string]*tsdb.MeasurementFields, series []*tsdb.SeriesCreate) error {
 	// persist the series and fields if there are any
 	if err := l.writeSeriesAndFields(fields, series); err != nil {
@@ -248,6 +295,8 @@ func (l *Log) WritePoints(points []tsdb.Point, fields map[string]*tsdb.Measureme
 		}
 	}
 
+	l.stats.Add("writeReq", 1)
+	l.stats.Add("pointsWritten", int64(len(points)))
 	return nil
 }
 
@@ -319,6 +368,90 @@ func (l *Log) LoadMetadataIndex(index *tsdb.DatabaseIndex, measurementFields map
 	return nil
 }
 
+// ReadPoints reads the points in the segment files of the WAL at path without
+// opening it or modifying any of its files, so it can be used on the WAL of a
+// stopped node. Field values are decoded with the fields in the WAL's
+// metadata files and those passed in, which should hold the fields already
+// flushed to the shard. Points of measurements without known fields are
+// skipped and their count is returned.
+func ReadPoints(path string, fields map[string]*tsdb.MeasurementFields, fn func(tsdb.Point) error) (int, error) {
+	l := NewLog(path)
+	l.logger = log.New(l.LogOutput, "[wal] ", log.LstdFlags)
+
+	codecs := make(map[string]*tsdb.FieldCodec)
+	for name, mf := range fields {
+		codecs[name] = tsdb.NewFieldCodec(mf.Fields)
+	}
+
+	metaFiles, err := l.metadataFiles()
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range metaFiles {
+		a, err := l.readMetadataFile(name)
+		if err != nil {
+			return 0, err
+		}
+		for _, sf := range a {
+			for k, mf := range sf.Fields {
+				codecs[k] = tsdb.NewFieldCodec(mf.Fields)
+			}
+		}
+	}
+
+	// Segment file names sort by partition and then in the order they were written.
+	names, err := filepath.Glob(filepath.Join(path, fmt.Sprintf("*.*.%s", FileExtension)))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(names)
+
+	var skipped int
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return skipped, err
+		}
+
+		sf := newSegment(f, l.logger)
+		sf.readOnly = true
+		for {
+			blockName, entries, err := sf.readCompressedBlock()
+			if blockName != "" {
+				continue // skip name blocks
+			} else if err != nil {
+				f.Close()
+				return skipped, err
+			} else if entries == nil {
+				break
+			}
+
+			for _, e := range entries {
+				measurement, tags := tsdb.ParseKey(string(e.key))
+				codec := codecs[measurement]
+				if codec == nil {
+					skipped++
+					continue
+				}
+				values, err := codec.DecodeFieldsWithNames(e.data)
+				if err != nil {
+					skipped++
+					continue
+				}
+				if err := fn(tsdb.NewPoint(measurement, tags, values, time.Unix(0, e.timestamp))); err != nil {
+					f.Close()
+					return skipped, err
+				}
+			}
+		}
+
+		if err := f.Close(); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
 // DeleteSeries will flush the metadata that is in the WAL to the index and remove
 // all series specified from the cache and the segment files in each partition. This
 // will block all writes while a compaction is done against all partitions. This function
@@ -549,15 +682,125 @@ func (l *Log) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	// Snapshot the cache so the next open doesn't have to replay the segments.
+	if err := l.writeSnapshots(); err != nil {
+		l.logger.Println("error writing snapshot, wal will be replayed on open:", err)
+	}
+
 	// clear the cache
 	if err := l.close(); err != nil {
 		return err
 	}
 
 	l.partitions = nil
+	if l.stats != nil {
+		tsdb.UnregisterStatistics(l.stats)
+	}
 	return nil
 }
 
+// writeSnapshots writes the cache of every partition to disk and then writes
+// the clean shutdown marker. The marker records the segment files each snapshot
+// covers so it can be ignored if they change before the next open.
+func (l *Log) writeSnapshots() error {
+	if l.partitions == nil {
+		return nil
+	}
+
+	var marker cleanShutdown
+	for _, p := range l.partitions {
+		segments, err := p.writeSnapshotFile()
+		if err != nil {
+			return err
+		}
+		marker.Partitions = append(marker.Partitions, partitionSnapshot{ID: p.id, Segments: segments})
+	}
+
+	b, err := json.Marshal(&marker)
+	if err != nil {
+		return err
+	}
+
+	// Write the marker to a temporary file first so a partial marker is never read.
+	path := filepath.Join(l.path, CleanShutdownFileName)
+	if err := ioutil.WriteFile(path+".tmp", b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// loadSnapshots loads the partition caches from the snapshots written on the
+// last clean shutdown. Returns false if the segment files need to be replayed
+// instead. The marker and snapshots are always removed so a crash after this
+// point is recovered from the segment files.
+func (l *Log) loadSnapshots() (bool, error) {
+	defer l.removeSnapshotFiles()
+
+	path := filepath.Join(l.path, CleanShutdownFileName)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+
+	var marker cleanShutdown
+	if err := json.Unmarshal(b, &marker); err != nil {
+		l.logger.Println("invalid clean shutdown marker, replaying wal:", err)
+		return false, nil
+	} else if len(marker.Partitions) != len(l.partitions) {
+		return false, nil
+	}
+
+	// Read all the snapshots before touching the caches so a bad one can
+	// still fall back to replaying the segment files.
+	entries := make(map[*Partition][]*entry)
+	for _, ps := range marker.Partitions {
+		p := l.partitions[ps.ID]
+		if p == nil {
+			return false, nil
+		} else if ok, err := p.segmentsMatch(ps.Segments); err != nil {
+			return false, err
+		} else if !ok {
+			l.logger.Printf("segment files of partition %d changed since shutdown, replaying wal", p.id)
+			return false, nil
+		}
+
+		a, err := p.readSnapshotFile()
+		if err != nil {
+			l.logger.Printf("error reading snapshot of partition %d, replaying wal: %s", p.id, err)
+			return false, nil
+		}
+		entries[p] = a
+	}
+
+	for p, a := range entries {
+		for _, e := range a {
+			p.addToCache(e.key, e.data, e.timestamp)
+		}
+		if err := p.openLastSegmentFile(); err != nil {
+			return false, err
+		}
+	}
+
+	if l.EnableLogging {
+		l.logger.Println("WAL loaded from clean shutdown snapshot")
+	}
+	return true, nil
+}
+
+// removeSnapshotFiles removes the snapshot files of all partitions.
+func (l *Log) removeSnapshotFiles() {
+	for _, p := range l.partitions {
+		if err := os.Remove(p.snapshotFileName()); err != nil && !os.IsNotExist(err) {
+			l.logger.Println("error removing snapshot file:", err)
+		}
+	}
+}
+
 // close all the open Log partitions and file handles
 func (l *Log) close() error {
 	for _, p := range l.partitions {
@@ -860,6 +1103,11 @@ func (p *Partition) compactionFileName() string {
 	return filepath.Join(p.path, fmt.Sprintf("%02d.%06d.%s", p.id, 1, CompactionExtension))
 }
 
+// snapshotFileName is the name of the file the cache is written to on a clean shutdown
+func (p *Partition) snapshotFileName() string {
+	return filepath.Join(p.path, fmt.Sprintf("%02d.%s", p.id, SnapshotExtension))
+}
+
 // fileIDFromName will return the segment ID from the file name
 func (p *Partition) fileIDFromName(name string) (uint32, error) {
 	parts := strings.Split(filepath.Base(name), ".")
@@ -1019,6 +1267,8 @@ func (p *Partition) flushAndCompact(flush flushType) error {
 	}
 
 	startTime := time.Now()
+	p.log.stats.Add("flush", 1)
+	p.log.stats.Add("flushBytes", int64(c.flushSize))
 	if p.log.EnableLogging {
 		ftype := "idle"
 		if flush == thresholdFlush {
@@ -1035,6 +1285,9 @@ func (p *Partition) flushAndCompact(flush flushType) error {
 		panic(fmt.Sprintf("error writing the wal to the index: %s", err.Error()))
 	}
 
+	// snapshots taken before the index write still need the flushed points
+	p.log.retainFlushed(c.seriesToFlush)
+
 	// clear the flush cache and reset the memory thresholds
 	p.mu.Lock()
 	p.flushCache = nil
@@ -1348,6 +1601,160 @@ func (p *Partition) cursor(key string) *cursor {
 	return &cursor{cache: a}
 }
 
+// writeSnapshotFile writes the cache to the snapshot file as compressed blocks,
+// one per series. Returns the segment files the snapshot replaces.
+func (p *Partition) writeSnapshotFile() ([]segmentInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The flush cache isn't in the snapshot so don't write one mid compaction.
+	if p.compactionRunning {
+		return nil, ErrCompactionRunning
+	}
+
+	if p.currentSegmentFile != nil {
+		if err := p.currentSegmentFile.Sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	segments, err := p.segmentInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(p.snapshotFileName(), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	for key, entry := range p.cache {
+		block := make([]byte, 0, entry.size+len(entry.points)*(8+len(key)))
+		for _, v := range entry.points {
+			timestamp, data := UnmarshalEntry(v)
+			block = append(block, marshalWALEntry([]byte(key), timestamp, data)...)
+		}
+
+		b := snappy.Encode(nil, block)
+		if _, err := f.Write(u64tob(uint64(len(b)))); err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// readSnapshotFile reads the entries from the snapshot file.
+func (p *Partition) readSnapshotFile() (entries []*entry, err error) {
+	f, err := os.Open(p.snapshotFileName())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// The size is checked since the segment reader stops at a corrupt block.
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	sf := newSegment(f, p.log.logger)
+	var n int64
+	for {
+		_, a, err := sf.readCompressedBlock()
+		if err != nil {
+			return nil, err
+		} else if a == nil {
+			break
+		}
+		n += sf.size
+		entries = append(entries, a...)
+	}
+
+	if n != fi.Size() {
+		return nil, fmt.Errorf("snapshot file truncated or corrupt: %s", f.Name())
+	}
+	return entries, nil
+}
+
+// segmentInfos returns the name and size of each segment file.
+func (p *Partition) segmentInfos() ([]segmentInfo, error) {
+	names, err := p.segmentFileNames()
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]segmentInfo, 0, len(names))
+	for _, n := range names {
+		fi, err := os.Stat(n)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segmentInfo{Name: filepath.Base(n), Size: fi.Size()})
+	}
+	return segments, nil
+}
+
+// segmentsMatch returns true if the segment files are the same as the given ones.
+func (p *Partition) segmentsMatch(a []segmentInfo) (bool, error) {
+	segments, err := p.segmentInfos()
+	if err != nil {
+		return false, err
+	}
+	if len(segments) != len(a) {
+		return false, nil
+	}
+	for i := range segments {
+		if segments[i] != a[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// openLastSegmentFile opens the highest segment file for appending, as it
+// would be after reading all the segment files.
+func (p *Partition) openLastSegmentFile() error {
+	names, err := p.segmentFileNames()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range names {
+		id, err := p.fileIDFromName(n)
+		if err != nil {
+			return err
+		} else if id <= p.currentSegmentID {
+			continue
+		}
+
+		f, err := os.OpenFile(n, os.O_RDWR, 0666)
+		if err != nil {
+			return err
+		}
+		size, err := f.Seek(0, os.SEEK_END)
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		if p.currentSegmentFile != nil {
+			p.currentSegmentFile.Close()
+		}
+		p.currentSegmentID = id
+		p.currentSegmentFile = f
+		p.currentSegmentSize = size
+	}
+	return nil
+}
+
 // idFromFileName parses the segment file ID from its name
 func (p *Partition) idFromFileName(name string) (uint32, error) {
 	parts := strings.Split(filepath.Base(name), ".")
@@ -1360,6 +1767,73 @@ func (p *Partition) idFromFileName(name string) (uint32, error) {
 	return uint32(id), err
 }
 
+// CheckSegments returns the number of segment files of the WAL and a
+// description of each range of segments missing between them. Partitions
+// that are being compacted are skipped since their files are changing.
+func (l *Log) CheckSegments() (int, []string, error) {
+	l.mu.RLock()
+	partitions := make([]*Partition, 0, len(l.partitions))
+	for _, p := range l.partitions {
+		partitions = append(partitions, p)
+	}
+	l.mu.RUnlock()
+
+	var n int
+	var gaps []string
+	for _, p := range partitions {
+		ids, err := p.segmentIDs()
+		if err != nil {
+			return 0, nil, err
+		} else if ids == nil {
+			continue
+		}
+		n += len(ids)
+
+		// The first segment may be the output of a compaction, which is
+		// renamed to segment 1 and followed by the segments written since.
+		if len(ids) > 1 && ids[0] == 1 {
+			ids = ids[1:]
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] != ids[i-1]+1 {
+				gaps = append(gaps, fmt.Sprintf("partition %d: segments %d to %d missing", p.id, ids[i-1]+1, ids[i]-1))
+			}
+		}
+	}
+	return n, gaps, nil
+}
+
+// segmentIDs returns the sorted IDs of the partition's segment files, or nil
+// if a compaction is running.
+func (p *Partition) segmentIDs() ([]uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.compactionRunning {
+		return nil, nil
+	}
+
+	names, err := p.segmentFileNames()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint32, 0, len(names))
+	for _, n := range names {
+		id, err := p.idFromFileName(n)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	sort.Sort(uint32Slice(ids))
+	return ids, nil
+}
+
+type uint32Slice []uint32
+
+func (a uint32Slice) Len() int           { return len(a) }
+func (a uint32Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a uint32Slice) Less(i, j int) bool { return a[i] < a[j] }
+
 // segmentFileNames returns all the segment files names for the partition
 func (p *Partition) segmentFileNames() ([]string, error) {
 	path := filepath.Join(p.path, fmt.Sprintf("%02d.*.%s", p.id, FileExtension))
@@ -1414,6 +1888,10 @@ type segment struct {
 	length []byte
 	size   int64
 	logger *log.Logger
+
+	// readOnly stops reading at partial or corrupt blocks instead of
+	// truncating the file.
+	readOnly bool
 }
 
 func newSegment(f *os.File, l *log.Logger) *segment {
@@ -1471,6 +1949,9 @@ func (s *segment) readCompressedBlock() (name string, entries []*entry, err erro
 	// overwrite with zeroes so we can start over on this wal file
 	if n != int(dataLength) {
 		s.logger.Println("partial compressed block in file:", s.f.Name())
+		if s.readOnly {
+			return "", nil, nil
+		}
 
 		// seek back to before this block and its size so we can overwrite the corrupt data
 		s.f.Seek(-int64(len(s.length)+n), 1)
@@ -1490,6 +1971,9 @@ func (s *segment) readCompressedBlock() (name string, entries []*entry, err erro
 	buf, err := snappy.Decode(nil, s.block[:dataLength])
 	if err != nil {
 		s.logger.Println("corrupt compressed block in file:", err.Error(), s.f.Name())
+		if s.readOnly {
+			return "", nil, nil
+		}
 
 		// go back to the start of this block and zero out the rest of the file
 		s.f.Seek(-int64(len(s.length)+n), 1)
@@ -1517,6 +2001,23 @@ func (s *segment) readCompressedBlock() (name string, entries []*entry, err erro
 	return
 }
 
+// cleanShutdown is the content of the clean shutdown marker.
+type cleanShutdown struct {
+	Partitions []partitionSnapshot `json:"partitions"`
+}
+
+// partitionSnapshot lists the segment files covered by a partition's snapshot.
+type partitionSnapshot struct {
+	ID       uint8         `json:"id"`
+	Segments []segmentInfo `json:"segments"`
+}
+
+// segmentInfo identifies a segment file on disk.
+type segmentInfo struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
 // entry is used as a temporary object when reading data from segment files
 type entry struct {
 	key       []byte
@@ -1553,6 +2054,46 @@ func (c *cursor) Next() (key, value []byte) {
 
 }
 
+// Snapshot is a view of the WAL for a reader of the index. It holds on to the
+// points flushed to the index after it was taken so they're not missed by a
+// reader that can't see them in the index.
+type Snapshot struct {
+	log     *Log
+	flushed []map[string][][]byte // points flushed since the snapshot, oldest first
+}
+
+// Cursor returns a cursor over the cached points for key and the points flushed
+// since the snapshot was taken.
+func (s *Snapshot) Cursor(key string) tsdb.Cursor {
+	s.log.mu.RLock()
+	c := s.log.partition([]byte(key)).cursor(key)
+	s.log.mu.RUnlock()
+
+	s.log.snapshotsMu.Lock()
+	flushed := s.flushed
+	s.log.snapshotsMu.Unlock()
+
+	var a [][]byte
+	for _, m := range flushed {
+		a = append(a, m[key]...)
+	}
+	if len(a) == 0 {
+		return c
+	}
+
+	// newer points in the cache replace flushed points with the same time
+	a = append(a, c.cache...)
+	return &cursor{cache: tsdb.DedupeEntries(a)}
+}
+
+// Release drops the snapshot and the flushed points it was holding on to.
+func (s *Snapshot) Release() {
+	s.log.snapshotsMu.Lock()
+	defer s.log.snapshotsMu.Unlock()
+	delete(s.log.snapshots, s)
+	s.flushed = nil
+}
+
 // seriesAndFields is a data struct to serialize new series and fields
 // to get created into WAL segment files
 type seriesAndFields struct {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -498,8 +499,8 @@ func TestWAL_SeriesAndFieldsGetPersisted(t *testing.T) {
 	p3 := parsePoint("cpu,host=B value=1.0 1", codec)
 
 	seriesToCreate := []*tsdb.SeriesCreate{
-		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), map[string]string{"host": "A"})), map[string]string{"host": "A"})},
-		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), map[string]string{"host": "B"})), map[string]string{"host": "B"})},
+		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tsdb.NewTags(map[string]string{"host": "A"}))), map[string]string{"host": "A"})},
+		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tsdb.NewTags(map[string]string{"host": "B"}))), map[string]string{"host": "B"})},
 	}
 
 	measaurementsToCreate := map[string]*tsdb.MeasurementFields{
@@ -611,8 +612,8 @@ func TestWAL_DeleteSeries(t *testing.T) {
 	}}
 
 	seriesToCreate := []*tsdb.SeriesCreate{
-		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), map[string]string{"host": "A"})), map[string]string{"host": "A"})},
-		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), map[string]string{"host": "B"})), map[string]string{"host": "B"})},
+		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tsdb.NewTags(map[string]string{"host": "A"}))), map[string]string{"host": "A"})},
+		{Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tsdb.NewTags(map[string]string{"host": "B"}))), map[string]string{"host": "B"})},
 	}
 
 	// test that we can write to two different series
@@ -870,6 +871,74 @@ func TestWAL_PointsSorted(t *testing.T) {
 	}
 }
 
+// Ensure a snapshot sees the points flushed to the index after it was taken
+// until it's released.
+func TestWAL_Snapshot(t *testing.T) {
+	log := openTestWAL()
+	log.partitionCount = 1
+	defer log.Close()
+	defer os.RemoveAll(log.path)
+
+	log.Index = &testIndexWriter{fn: func(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
+		return nil
+	}}
+
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't open wal: %s", err.Error())
+	}
+
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {
+			ID:   uint8(1),
+			Name: "value",
+			Type: influxql.Float,
+		},
+	})
+
+	p1 := parsePoint("cpu,host=A value=1.1 1", codec)
+	p2 := parsePoint("cpu,host=A value=4.4 4", codec)
+	if err := log.WritePoints([]tsdb.Point{p1, p2}, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	s := log.Snapshot()
+	if err := log.Flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err.Error())
+	}
+	if k, _ := log.Cursor("cpu,host=A").Next(); k != nil {
+		t.Fatal("expected flushed points to be out of the cache")
+	}
+
+	// points written after the flush are read along with the flushed ones
+	p3 := parsePoint("cpu,host=A value=2.2 2", codec)
+	if err := log.WritePoints([]tsdb.Point{p3}, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	c := s.Cursor("cpu,host=A")
+	for _, p := range []tsdb.Point{p1, p3, p2} {
+		if _, v := c.Next(); !bytes.Equal(v, p.Data()) {
+			t.Fatalf("expected %s", p.String())
+		}
+	}
+	if k, _ := c.Next(); k != nil {
+		t.Fatal("expected end of cursor")
+	}
+
+	if k, _ := s.Cursor("cpu,host=B").Next(); k != nil {
+		t.Fatal("expected empty cursor for unknown series")
+	}
+
+	// a released snapshot no longer holds on to the flushed points
+	s.Release()
+	if err := log.Flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err.Error())
+	}
+	if len(s.flushed) != 0 || len(log.snapshots) != 0 {
+		t.Fatal("expected released snapshot to be dropped")
+	}
+}
+
 // test that partitions get compacted and flushed when number of series hits compaction threshold
 // test that partitions get compacted and flushed when a single series hits the compaction threshold
 // test that writes slow down when the partition size threshold is hit
@@ -962,6 +1031,81 @@ func TestWAL_PointsSorted(t *testing.T) {
 // 	time.Sleep(time.Minute)
 // }
 
+// Ensure a clean shutdown snapshots the cache and the next open loads it
+// instead of replaying the segment files.
+func TestWAL_CleanShutdownSnapshot(t *testing.T) {
+	log := openTestWAL()
+	defer log.Close()
+	defer os.RemoveAll(log.path)
+
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't open wal: %s", err.Error())
+	}
+
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {
+			ID:   uint8(1),
+			Name: "value",
+			Type: influxql.Float,
+		},
+	})
+
+	p1 := parsePoint("cpu,host=A value=1.1 1", codec)
+	p2 := parsePoint("cpu,host=A value=2.2 2", codec)
+	p3 := parsePoint("cpu,host=B value=3.3 1", codec)
+	if err := log.WritePoints([]tsdb.Point{p1, p2, p3}, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	verify := func(points ...tsdb.Point) {
+		c := log.Cursor("cpu,host=A")
+		for i, p := range points {
+			var v []byte
+			if i == 0 {
+				_, v = c.Seek(inttob(1))
+			} else {
+				_, v = c.Next()
+			}
+			if !bytes.Equal(v, p.Data()) {
+				t.Fatalf("unexpected value for point %d: %v", i, v)
+			}
+		}
+		if k, _ := c.Next(); k != nil {
+			t.Fatalf("expected end of cursor but got: %v", k)
+		}
+	}
+
+	log.Close()
+	if _, err := os.Stat(filepath.Join(log.path, CleanShutdownFileName)); err != nil {
+		t.Fatalf("expected clean shutdown marker: %s", err)
+	}
+
+	// Reopen from the snapshot and ensure the marker is consumed.
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't reopen wal: %s", err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(log.path, CleanShutdownFileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected clean shutdown marker to be removed: %v", err)
+	}
+	verify(p1, p2)
+
+	// Writes should keep appending to the last segment file.
+	p4 := parsePoint("cpu,host=A value=4.4 3", codec)
+	if err := log.WritePoints([]tsdb.Point{p4}, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	log.Close()
+
+	// Invalidate the marker so the segment files are replayed instead.
+	if err := ioutil.WriteFile(filepath.Join(log.path, CleanShutdownFileName), []byte(`{"partitions":[]}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't reopen wal: %s", err.Error())
+	}
+	verify(p1, p2, p4)
+}
+
 type testIndexWriter struct {
 	fn func(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error
 }
@@ -1002,3 +1146,58 @@ func inttob(v int) []byte {
 	binary.BigEndian.PutUint64(b, uint64(v))
 	return b
 }
+
+// Ensure points can be read back from the files of a WAL without opening it.
+func TestWAL_ReadPoints(t *testing.T) {
+	log := openTestWAL()
+	defer log.Close()
+	defer os.RemoveAll(log.path)
+
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't open wal: %s", err.Error())
+	}
+
+	cpu := &tsdb.MeasurementFields{Fields: map[string]*tsdb.Field{
+		"value": {ID: uint8(1), Name: "value", Type: influxql.Float},
+	}}
+	mem := &tsdb.MeasurementFields{Fields: map[string]*tsdb.Field{
+		"free": {ID: uint8(1), Name: "free", Type: influxql.Float},
+	}}
+	cpu.Codec, mem.Codec = tsdb.NewFieldCodec(cpu.Fields), tsdb.NewFieldCodec(mem.Fields)
+
+	// Only the fields of cpu are written to the WAL's metadata.
+	points := []tsdb.Point{
+		parsePoint("cpu,host=A value=23.2 1", cpu.Codec),
+		parsePoint("cpu,host=B value=25.3 4", cpu.Codec),
+		parsePoint("mem,host=A free=1.0 2", mem.Codec),
+	}
+	if err := log.WritePoints(points, map[string]*tsdb.MeasurementFields{"cpu": cpu}, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	read := func(fields map[string]*tsdb.MeasurementFields) ([]string, int) {
+		var a []string
+		skipped, err := ReadPoints(log.path, fields, func(p tsdb.Point) error {
+			a = append(a, p.String())
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to read points: %s", err.Error())
+		}
+		sort.Strings(a)
+		return a, skipped
+	}
+
+	if a, skipped := read(nil); !reflect.DeepEqual(a, []string{"cpu,host=A value=23.2 1", "cpu,host=B value=25.3 4"}) {
+		t.Fatalf("unexpected points: %v", a)
+	} else if skipped != 1 {
+		t.Fatalf("unexpected skipped count: %d", skipped)
+	}
+
+	// Fields flushed to the shard are passed in.
+	if a, skipped := read(map[string]*tsdb.MeasurementFields{"mem": mem}); len(a) != 3 || a[2] != "mem,host=A free=1.0 2" {
+		t.Fatalf("unexpected points: %v", a)
+	} else if skipped != 0 {
+		t.Fatalf("unexpected skipped count: %d", skipped)
+	}
+}
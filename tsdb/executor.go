@@ -22,6 +22,18 @@ const (
 )
 
 // Mapper is the interface all Mapper types must implement.
+//
+// NextChunk returns *MapperOutput chunks, or nil once the mapper is drained,
+// in an order the Executor relies on to reduce the outputs of several mappers
+// without buffering them:
+//
+//   - Chunks are grouped by tagset. All the chunks of a tagset are returned
+//     before any chunk of the next one, in ascending tagset key order.
+//   - Within a tagset, values are in ascending time order, within and across
+//     chunks. Aggregate chunks hold a single interval each.
+//   - Chunks are never empty, and the last chunk of a tagset has TagSetEnd set.
+//
+// Chunks breaking this contract fail the query.
 type Mapper interface {
 	Open() error
 	SetRemote(m Mapper) error
@@ -37,6 +49,11 @@ type StatefulMapper struct {
 	Mapper
 	bufferedChunk *MapperOutput // Last read chunk.
 	drained       bool
+
+	// The tagset and time of the last chunk read, to check their order.
+	tagSet      string
+	tagSetEnded bool
+	lastTime    int64
 }
 
 // NextChunk wraps a RawMapper and some state.
@@ -45,15 +62,48 @@ func (sm *StatefulMapper) NextChunk() (*MapperOutput, error) {
 	if err != nil {
 		return nil, err
 	}
-	chunk, ok := c.(*MapperOutput)
-	if !ok {
-		if chunk == interface{}(nil) {
-			return nil, nil
-		}
+	chunk, _ := c.(*MapperOutput)
+	if err := sm.checkOrder(chunk); err != nil {
+		return nil, err
 	}
 	return chunk, nil
 }
 
+// checkOrder returns an error if a chunk breaks the order of the Mapper
+// contract, and records the chunk's tagset and time otherwise.
+func (sm *StatefulMapper) checkOrder(chunk *MapperOutput) error {
+	if chunk == nil {
+		if sm.tagSet != "" && !sm.tagSetEnded {
+			return fmt.Errorf("mapper drained before the end of tagset %s", sm.tagSet)
+		}
+		return nil
+	} else if len(chunk.Values) == 0 {
+		return fmt.Errorf("mapper returned an empty chunk for tagset %s", chunk.key())
+	}
+
+	key := chunk.key()
+	switch {
+	case sm.tagSet == "":
+	case key < sm.tagSet, key == sm.tagSet && sm.tagSetEnded:
+		return fmt.Errorf("mapper returned tagset %s out of order", key)
+	case key > sm.tagSet && !sm.tagSetEnded:
+		return fmt.Errorf("mapper returned tagset %s before the end of tagset %s", key, sm.tagSet)
+	case key == sm.tagSet && chunk.Values[0].Time < sm.lastTime:
+		return fmt.Errorf("mapper returned values of tagset %s out of time order", key)
+	}
+
+	for i := 1; i < len(chunk.Values); i++ {
+		if chunk.Values[i].Time < chunk.Values[i-1].Time {
+			return fmt.Errorf("mapper returned values of tagset %s out of time order", key)
+		}
+	}
+
+	sm.tagSet = key
+	sm.tagSetEnded = chunk.TagSetEnd
+	sm.lastTime = chunk.Values[len(chunk.Values)-1].Time
+	return nil
+}
+
 type Executor struct {
 	stmt           *influxql.SelectStatement
 	mappers        []*StatefulMapper
@@ -65,7 +115,7 @@ type Executor struct {
 func NewExecutor(stmt *influxql.SelectStatement, mappers []Mapper, chunkSize int) *Executor {
 	a := []*StatefulMapper{}
 	for _, m := range mappers {
-		a = append(a, &StatefulMapper{m, nil, false})
+		a = append(a, &StatefulMapper{Mapper: m})
 	}
 	return &Executor{
 		stmt:           stmt,
@@ -348,6 +398,16 @@ func (e *Executor) executeAggregate(out chan *influxql.Row) {
 		reduceFuncs[i] = reduceFunc
 	}
 
+	// A query selecting a single point, such as SELECT max(value) FROM cpu,
+	// returns the time of the selected point instead of the start of the
+	// window when there's no GROUP BY time.
+	var selector influxql.SelectorFunc
+	if d, _ := e.stmt.GroupByInterval(); d == 0 && len(aggregates) == 1 && len(e.stmt.Fields) == 1 {
+		if _, ok := e.stmt.Fields[0].Expr.(*influxql.Call); ok {
+			selector = influxql.InitializeSelectorFunc(aggregates[0])
+		}
+	}
+
 	// Put together the rows to return, starting with columns.
 	columnNames := make([]string, len(e.stmt.Fields)+1)
 	columnNames[0] = "time"
@@ -372,54 +432,56 @@ func (e *Executor) executeAggregate(out chan *influxql.Row) {
 		}
 	}
 
-	// Prime each mapper's chunk buffer.
+	// Keep looping until all mappers drained.
 	var err error
-	for _, m := range e.mappers {
-		m.bufferedChunk, err = m.NextChunk()
-		if err != nil {
-			out <- &influxql.Row{Err: err}
-			return
+	for {
+		// Buffer the next chunk of each mapper, or mark it drained.
+		for _, m := range e.mappers {
+			if m.drained || m.bufferedChunk != nil {
+				continue
+			}
+			m.bufferedChunk, err = m.NextChunk()
+			if err != nil {
+				out <- &influxql.Row{Err: err}
+				return
+			}
+			if m.bufferedChunk == nil {
+				m.drained = true
+			}
 		}
-		if m.bufferedChunk == nil {
-			m.drained = true
+		if e.mappersDrained() {
+			break
 		}
-	}
 
-	// Keep looping until all mappers drained.
-	for !e.mappersDrained() {
 		// Send out data for the next alphabetically-lowest tagset. All Mappers send out in this order
 		// so collect data for this tagset, ignoring all others.
 		tagset := e.nextMapperTagSet()
 		chunks := []*MapperOutput{}
 
-		// Pull as much as possible from each mapper. Stop when a mapper offers
-		// data for a new tagset, or empties completely.
+		// Pull the chunks of the tagset from each mapper, up to the chunk that
+		// ends it, without reading ahead into the next tagset.
 		for _, m := range e.mappers {
-			if m.drained {
+			if m.bufferedChunk == nil || m.bufferedChunk.key() != tagset {
 				continue
 			}
 
 			for {
-				if m.bufferedChunk == nil {
-					m.bufferedChunk, err = m.NextChunk()
-					if err != nil {
-						out <- &influxql.Row{Err: err}
-						return
-					}
-					if m.bufferedChunk == nil {
-						m.drained = true
-						break
-					}
+				chunks = append(chunks, m.bufferedChunk)
+				end := m.bufferedChunk.TagSetEnd
+				m.bufferedChunk = nil
+				if end {
+					break
 				}
 
-				// Got a chunk. Can we use it?
-				if m.bufferedChunk.key() != tagset {
-					// No, so just leave it in the buffer.
+				m.bufferedChunk, err = m.NextChunk()
+				if err != nil {
+					out <- &influxql.Row{Err: err}
+					return
+				}
+				if m.bufferedChunk == nil {
+					m.drained = true
 					break
 				}
-				// We can, take it.
-				chunks = append(chunks, m.bufferedChunk)
-				m.bufferedChunk = nil
 			}
 		}
 
@@ -440,11 +502,19 @@ func (e *Executor) executeAggregate(out chan *influxql.Row) {
 
 			startTime := chunk.Values[0].Time
 			_, ok := buckets[startTime]
-			values := chunk.Values[0].Value.([]interface{})
+			mv := chunk.Values[0]
 			if !ok {
-				buckets[startTime] = make([][]interface{}, len(values))
+				buckets[startTime] = make([][]interface{}, mv.Len())
 			}
-			for i, v := range values {
+
+			// Only read the outputs of the aggregates that are reduced, so
+			// the others of remote chunks are never decoded.
+			for i := 0; i < mv.Len() && i < len(reduceFuncs); i++ {
+				v, err := mv.Column(i)
+				if err != nil {
+					out <- &influxql.Row{Err: err}
+					return
+				}
 				buckets[startTime][i] = append(buckets[startTime][i], v)
 			}
 		}
@@ -464,6 +534,14 @@ func (e *Executor) executeAggregate(out chan *influxql.Row) {
 			values[i] = make([]interface{}, 0, len(columnNames))
 			values[i] = append(values[i], time.Unix(0, t).UTC()) // Time value is always first.
 
+			if selector != nil {
+				if p := selector(buckets[t][0]); p != nil {
+					values[i][0] = time.Unix(0, p.Time).UTC()
+					values[i] = append(values[i], p.Value)
+					continue
+				}
+			}
+
 			for j, f := range reduceFuncs {
 				reducedVal := f(buckets[t][j])
 				values[i] = append(values[i], reducedVal)
@@ -473,6 +551,9 @@ func (e *Executor) executeAggregate(out chan *influxql.Row) {
 		// Perform any mathematics.
 		values = processForMath(e.stmt.Fields, values)
 
+		// Interpolate the empty windows between values
+		values = e.processInterpolate(values)
+
 		// Handle any fill options
 		values = e.processFill(values)
 
@@ -538,6 +619,20 @@ func (e *Executor) processFill(results [][]interface{}) [][]interface{} {
 	return results
 }
 
+// processInterpolate fills the empty windows of the interpolate() columns.
+func (e *Executor) processInterpolate(results [][]interface{}) [][]interface{} {
+	var columns []int
+	for i, f := range e.stmt.Fields {
+		if c, ok := f.Expr.(*influxql.Call); ok && c.Name == "interpolate" {
+			columns = append(columns, i+1) // the first column is time
+		}
+	}
+	if len(columns) == 0 {
+		return results
+	}
+	return ProcessAggregateInterpolate(results, columns)
+}
+
 // processDerivative returns the derivatives of the results
 func (e *Executor) processDerivative(results [][]interface{}) [][]interface{} {
 	// Return early if we're not supposed to process the derivatives
@@ -733,7 +828,9 @@ func (r *limitedRowWriter) processValues(values []*MapperValue) *influxql.Row {
 				vals[1] = val
 			}
 		} else {
-			fields := v.Value.(map[string]interface{})
+			// Fields missing from the point, including all of them when the
+			// mapper sent a null value, are returned as nulls.
+			fields, _ := v.Value.(map[string]interface{})
 
 			// time is always the first value
 			vals[0] = time.Unix(0, v.Time).UTC()
@@ -937,6 +1034,52 @@ func ProcessAggregateDerivative(results [][]interface{}, isNonNegative bool, int
 	return derivatives
 }
 
+// ProcessAggregateInterpolate fills the nil values of the given columns of an
+// aggregate result set by linear interpolation between the closest values
+// before and after them. Nil values at the start or end of a column, or next to
+// a value that isn't numeric, are left for the fill option.
+func ProcessAggregateInterpolate(results [][]interface{}, columns []int) [][]interface{} {
+	for _, j := range columns {
+		prev := -1
+		for i := range results {
+			if results[i][j] == nil {
+				continue
+			}
+
+			// Fill the gap since the previous value, if there is one.
+			if prev >= 0 && i-prev > 1 {
+				interpolate(results, j, prev, i)
+			}
+			prev = i
+		}
+	}
+	return results
+}
+
+// interpolate fills column j of the rows between from and to.
+func interpolate(results [][]interface{}, j, from, to int) {
+	if !isNumericValue(results[from][j]) || !isNumericValue(results[to][j]) {
+		return
+	}
+
+	t0, t1 := results[from][0].(time.Time), results[to][0].(time.Time)
+	v0, v1 := int64toFloat64(results[from][j]), int64toFloat64(results[to][j])
+	elapsed := float64(t1.Sub(t0))
+	for i := from + 1; i < to; i++ {
+		t := results[i][0].(time.Time)
+		results[i][j] = v0 + (v1-v0)*float64(t.Sub(t0))/elapsed
+	}
+}
+
+// isNumericValue returns true if v is an aggregate value that can be interpolated.
+func isNumericValue(v interface{}) bool {
+	switch v.(type) {
+	case float64, int64:
+		return true
+	}
+	return false
+}
+
 // derivativeInterval returns the time interval for the one (and only) derivative func
 func derivativeInterval(stmt *influxql.SelectStatement) (time.Duration, error) {
 	if len(stmt.FunctionCalls()[0].Args) == 2 {
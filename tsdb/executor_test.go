@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -58,20 +59,20 @@ func TestWritePointsAndExecuteTwoShards(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	if err := store.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"value": 100},
 		pt1time,
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	pt2time := time.Unix(2, 0).UTC()
 	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"value": 200},
 		pt2time,
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -132,6 +133,32 @@ func TestWritePointsAndExecuteTwoShards(t *testing.T) {
 			stmt:     `SELECT sum(value) FROM cpu`,
 			expected: `[{"name":"cpu","columns":["time","sum"],"values":[["1970-01-01T00:00:00Z",300]]}]`,
 		},
+		{
+			stmt:     `SELECT sample(value, 5) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","sample"],"values":[["1970-01-01T00:00:00Z",[["1970-01-01T00:00:01Z",100],["1970-01-01T00:00:02Z",200]]]]}]`,
+		},
+
+		// Selectors return the time of the selected point without GROUP BY time.
+		{
+			stmt:     `SELECT max(value) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","max"],"values":[["1970-01-01T00:00:02Z",200]]}]`,
+		},
+		{
+			stmt:     `SELECT min(value) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","min"],"values":[["1970-01-01T00:00:01Z",100]]}]`,
+		},
+		{
+			stmt:     `SELECT first(value) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","first"],"values":[["1970-01-01T00:00:01Z",100]]}]`,
+		},
+		{
+			stmt:     `SELECT last(value) FROM cpu GROUP BY host`,
+			expected: `[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","last"],"values":[["1970-01-01T00:00:01Z",100]]},{"name":"cpu","tags":{"host":"serverB"},"columns":["time","last"],"values":[["1970-01-01T00:00:02Z",200]]}]`,
+		},
+		{
+			stmt:     `SELECT min(value), max(value) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","min","max"],"values":[["1970-01-01T00:00:00Z",100,200]]}]`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -150,6 +177,72 @@ func TestWritePointsAndExecuteTwoShards(t *testing.T) {
 	}
 }
 
+// Ensure histograms written as string fields can be merged across shards.
+func TestWritePointsAndExecuteHistogram(t *testing.T) {
+	store, query_executor := testStoreAndQueryExecutor()
+	defer os.RemoveAll(store.Path())
+	query_executor.MetaStore = &testQEMetastore{
+		sgFunc: func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+			return []meta.ShardGroupInfo{
+				{
+					ID:        sgID,
+					StartTime: time.Now().Add(-time.Hour),
+					EndTime:   time.Now().Add(time.Hour),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID0), OwnerIDs: []uint64{nID}}},
+				},
+				{
+					ID:        sgID,
+					StartTime: time.Now().Add(-2 * time.Hour),
+					EndTime:   time.Now().Add(-time.Hour),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID1), OwnerIDs: []uint64{nID}}},
+				},
+			}, nil
+		},
+	}
+
+	if err := store.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
+		"http",
+		tsdb.NewTags(map[string]string{"host": "serverA"}),
+		map[string]interface{}{"latency": "10:50,20:10"},
+		time.Unix(1, 0).UTC(),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
+		"http",
+		tsdb.NewTags(map[string]string{"host": "serverB"}),
+		map[string]interface{}{"latency": "20:20,40:20"},
+		time.Unix(2, 0).UTC(),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT histogram_merge(latency) FROM http`,
+			expected: `[{"name":"http","columns":["time","histogram_merge"],"values":[["1970-01-01T00:00:00Z","10:50,20:30,40:20"]]}]`,
+		},
+		{
+			stmt:     `SELECT histogram_percentile(latency, 90) FROM http`,
+			expected: `[{"name":"http","columns":["time","histogram_percentile"],"values":[["1970-01-01T00:00:00Z",30]]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		executor, err := query_executor.Plan(mustParseSelectStatement(tt.stmt), 0)
+		if err != nil {
+			t.Fatalf("failed to plan query: %s", err.Error())
+		}
+		got := executeAndGetResults(executor)
+		if got != tt.expected {
+			t.Fatalf("Test %s\nexp: %s\ngot: %s\n", tt.stmt, tt.expected, got)
+		}
+	}
+}
+
 // Test that executor correctly orders data across shards.
 func TestWritePointsAndExecuteTwoShardsAlign(t *testing.T) {
 	// Create the mock planner and its metastore
@@ -187,27 +280,27 @@ func TestWritePointsAndExecuteTwoShardsAlign(t *testing.T) {
 	// Write interleaving, by time, chunks to the shards.
 	if err := store.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA"},
+		tsdb.NewTags(map[string]string{"host": "serverA"}),
 		map[string]interface{}{"value": 100},
 		time.Unix(1, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB"},
+		tsdb.NewTags(map[string]string{"host": "serverB"}),
 		map[string]interface{}{"value": 200},
 		time.Unix(2, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA"},
+		tsdb.NewTags(map[string]string{"host": "serverA"}),
 		map[string]interface{}{"value": 300},
 		time.Unix(3, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -267,20 +360,20 @@ func TestWritePointsAndExecuteTwoShardsQueryRewrite(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	if err := store0.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA"},
+		tsdb.NewTags(map[string]string{"host": "serverA"}),
 		map[string]interface{}{"value1": 100},
 		pt1time,
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	pt2time := time.Unix(2, 0).UTC()
 	if err := store1.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB"},
+		tsdb.NewTags(map[string]string{"host": "serverB"}),
 		map[string]interface{}{"value2": 200},
 		pt2time,
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	var tests = []struct {
 		skip      bool   // Skip test
@@ -359,45 +452,45 @@ func TestWritePointsAndExecuteTwoShardsTagSetOrdering(t *testing.T) {
 	// Write tagsets "y" and "z" to first shard.
 	if err := store.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "y"},
+		tsdb.NewTags(map[string]string{"host": "y"}),
 		map[string]interface{}{"value": 100},
 		time.Unix(1, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	if err := store.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "z"},
+		tsdb.NewTags(map[string]string{"host": "z"}),
 		map[string]interface{}{"value": 200},
 		time.Unix(1, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	// Write tagsets "x", y" and "z" to second shard.
 	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "x"},
+		tsdb.NewTags(map[string]string{"host": "x"}),
 		map[string]interface{}{"value": 300},
 		time.Unix(2, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "y"},
+		tsdb.NewTags(map[string]string{"host": "y"}),
 		map[string]interface{}{"value": 400},
 		time.Unix(3, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	if err := store.WriteToShard(sID1, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "z"},
+		tsdb.NewTags(map[string]string{"host": "z"}),
 		map[string]interface{}{"value": 500},
 		time.Unix(3, 0).UTC(),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -434,6 +527,82 @@ func TestWritePointsAndExecuteTwoShardsTagSetOrdering(t *testing.T) {
 
 // TestProccessAggregateDerivative tests the RawQueryDerivativeProcessor transformation function on the engine.
 // The is called for a query with a GROUP BY.
+// Ensure the executor fails queries with mapper chunks breaking the order of
+// the Mapper contract.
+func TestExecutor_MapperOrder(t *testing.T) {
+	cpu := func(host string, end bool, times ...int64) *tsdb.MapperOutput {
+		mo := &tsdb.MapperOutput{Name: "cpu", Tags: map[string]string{"host": host}, Fields: []string{"value"}, TagSetEnd: end}
+		for _, ts := range times {
+			mo.Values = append(mo.Values, &tsdb.MapperValue{Time: ts, Value: 1.0})
+		}
+		return mo
+	}
+
+	for i, tt := range []struct {
+		chunks []*tsdb.MapperOutput
+		err    string
+	}{
+		{
+			chunks: []*tsdb.MapperOutput{cpu("a", false, 1, 2), cpu("a", true, 3), cpu("b", true, 1)},
+		},
+		{
+			chunks: []*tsdb.MapperOutput{cpu("a", false, 2, 1)},
+			err:    "mapper returned values of tagset cpu|host|a out of time order",
+		},
+		{
+			chunks: []*tsdb.MapperOutput{cpu("a", false, 2), cpu("a", true, 1)},
+			err:    "mapper returned values of tagset cpu|host|a out of time order",
+		},
+		{
+			chunks: []*tsdb.MapperOutput{cpu("b", true, 1), cpu("a", true, 1)},
+			err:    "mapper returned tagset cpu|host|a out of order",
+		},
+		{
+			chunks: []*tsdb.MapperOutput{cpu("a", false, 1), cpu("b", true, 1)},
+			err:    "mapper returned tagset cpu|host|b before the end of tagset cpu|host|a",
+		},
+		{
+			chunks: []*tsdb.MapperOutput{cpu("a", false, 1)},
+			err:    "mapper drained before the end of tagset cpu|host|a",
+		},
+	} {
+		stmt := mustParseSelectStatement(`SELECT value FROM cpu GROUP BY host`)
+		e := tsdb.NewExecutor(stmt, []tsdb.Mapper{&chunkMapper{chunks: tt.chunks}}, 100)
+
+		// Execution stops at the first error.
+		var err string
+		for row := range e.Execute() {
+			if row.Err != nil {
+				err = row.Err.Error()
+				break
+			}
+		}
+		if err != tt.err {
+			t.Errorf("%d. unexpected error: %q, exp %q", i, err, tt.err)
+		}
+	}
+}
+
+// chunkMapper is a mapper returning a fixed list of chunks.
+type chunkMapper struct {
+	chunks []*tsdb.MapperOutput
+}
+
+func (m *chunkMapper) Open() error                 { return nil }
+func (m *chunkMapper) SetRemote(tsdb.Mapper) error { return nil }
+func (m *chunkMapper) TagSets() []string           { return nil }
+func (m *chunkMapper) Fields() []string            { return []string{"value"} }
+func (m *chunkMapper) Close()                      {}
+
+func (m *chunkMapper) NextChunk() (interface{}, error) {
+	if len(m.chunks) == 0 {
+		return nil, nil
+	}
+	chunk := m.chunks[0]
+	m.chunks = m.chunks[1:]
+	return chunk, nil
+}
+
 func TestProcessAggregateDerivative(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -654,6 +823,74 @@ func TestProcessAggregateDerivative(t *testing.T) {
 	}
 }
 
+func TestProcessAggregateInterpolate(t *testing.T) {
+	ts := func(m int) time.Time { return time.Unix(0, 0).Add(time.Duration(m) * time.Minute) }
+
+	tests := []struct {
+		name string
+		in   [][]interface{}
+		exp  [][]interface{}
+	}{
+		{
+			name: "empty input",
+			in:   [][]interface{}{},
+			exp:  [][]interface{}{},
+		},
+		{
+			name: "gaps between values",
+			in: [][]interface{}{
+				{ts(0), 1.0},
+				{ts(1), nil},
+				{ts(2), nil},
+				{ts(3), int64(4)},
+				{ts(4), nil},
+				{ts(5), 2.0},
+			},
+			exp: [][]interface{}{
+				{ts(0), 1.0},
+				{ts(1), 2.0},
+				{ts(2), 3.0},
+				{ts(3), int64(4)},
+				{ts(4), 3.0},
+				{ts(5), 2.0},
+			},
+		},
+		{
+			name: "leading and trailing gaps left empty",
+			in: [][]interface{}{
+				{ts(0), nil},
+				{ts(1), 1.0},
+				{ts(2), nil},
+			},
+			exp: [][]interface{}{
+				{ts(0), nil},
+				{ts(1), 1.0},
+				{ts(2), nil},
+			},
+		},
+		{
+			name: "non-numeric values left empty",
+			in: [][]interface{}{
+				{ts(0), "a"},
+				{ts(1), nil},
+				{ts(2), "b"},
+			},
+			exp: [][]interface{}{
+				{ts(0), "a"},
+				{ts(1), nil},
+				{ts(2), "b"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := tsdb.ProcessAggregateInterpolate(test.in, []int{1})
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Fatalf("ProcessAggregateInterpolate - %s results mismatch:\ngot %v\nexp %v", test.name, got, test.exp)
+		}
+	}
+}
+
 // TestProcessRawQueryDerivative tests the RawQueryDerivativeProcessor transformation function on the engine.
 // The is called for a queries that do not have a group by.
 func TestProcessRawQueryDerivative(t *testing.T) {
@@ -0,0 +1,154 @@
+package tsdb
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// FieldIterator reads the fields of a point one at a time from their text
+// encoding, so they can be consumed without the allocations of building a
+// Fields map:
+//
+//	it := p.FieldIterator()
+//	for it.Next() {
+//		name, typ, value := it.Name(), it.Type(), it.Value()
+//		...
+//	}
+//
+// The slices returned by the iterator refer to the point and must not be
+// modified or kept after the point is changed.
+type FieldIterator struct {
+	buf   []byte
+	i     int
+	name  []byte
+	value []byte
+	typ   influxql.DataType
+}
+
+// FieldIterator returns an iterator over the fields of the point, in the order
+// they're encoded.
+func (p *point) FieldIterator() FieldIterator {
+	return FieldIterator{buf: p.encodedFields()}
+}
+
+// FieldTypes returns the type of each field of the point: influxql.Float,
+// Integer, Boolean or String. The types are read from the encoding of the
+// values, which aren't decoded, so a write can be checked against the types
+// of its measurement without the cost of Fields.
+func (p *point) FieldTypes() map[string]influxql.DataType {
+	types := make(map[string]influxql.DataType)
+	it := p.FieldIterator()
+	for it.Next() {
+		types[string(it.Name())] = it.Type()
+	}
+	return types
+}
+
+// Next moves to the next field. It returns false when there are no more fields.
+func (it *FieldIterator) Next() bool {
+	for it.i < len(it.buf) {
+		i, name := scanTo(it.buf, it.i, '=')
+		if len(name) == 0 {
+			it.i = i + 1
+			continue
+		}
+		if bytes.IndexByte(name, '\\') != -1 {
+			name = unescape(name)
+		}
+
+		i, value := scanFieldValue(it.buf, i+1)
+		it.i = i + 1
+		it.name, it.value, it.typ = name, value, fieldType(value)
+		return true
+	}
+	it.name, it.value, it.typ = nil, nil, influxql.Unknown
+	return false
+}
+
+// Name returns the unescaped name of the current field.
+func (it *FieldIterator) Name() []byte { return it.name }
+
+// Type returns the type of the current field. It's influxql.Unknown if the
+// field has no value.
+func (it *FieldIterator) Type() influxql.DataType { return it.typ }
+
+// Value returns the encoded value of the current field, such as 1.5, 10i, true
+// or a quoted and escaped string.
+func (it *FieldIterator) Value() []byte { return it.value }
+
+// FloatValue returns the value of the current float field.
+func (it *FieldIterator) FloatValue() (float64, error) {
+	return strconv.ParseFloat(string(it.value), 64)
+}
+
+// IntegerValue returns the value of the current integer field.
+func (it *FieldIterator) IntegerValue() (int64, error) {
+	if len(it.value) == 0 {
+		return 0, fmt.Errorf("unable to parse integer value ''")
+	}
+	return strconv.ParseInt(string(it.value[:len(it.value)-1]), 10, 64)
+}
+
+// BooleanValue returns the value of the current boolean field.
+func (it *FieldIterator) BooleanValue() (bool, error) {
+	return strconv.ParseBool(string(it.value))
+}
+
+// StringValue returns the unquoted and unescaped value of the current string field.
+func (it *FieldIterator) StringValue() string {
+	if len(it.value) < 2 {
+		return ""
+	}
+	return unescapeStringField(string(it.value[1 : len(it.value)-1]))
+}
+
+// Interface returns the value of the current field as the type stored in a
+// Fields map, or nil if the field has no value.
+func (it *FieldIterator) Interface() (interface{}, error) {
+	switch it.typ {
+	case influxql.Float:
+		v, err := it.FloatValue()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse number value '%v': %v", string(it.value), err)
+		}
+		return v, nil
+	case influxql.Integer:
+		v, err := it.IntegerValue()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse number value '%v': %v", string(it.value), err)
+		}
+		return v, nil
+	case influxql.Boolean:
+		v, err := it.BooleanValue()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse bool value '%v': %v", string(it.value), err)
+		}
+		return v, nil
+	case influxql.String:
+		return it.StringValue(), nil
+	}
+	return nil, nil
+}
+
+// fieldType returns the type of an encoded field value.
+func fieldType(value []byte) influxql.DataType {
+	if len(value) == 0 {
+		return influxql.Unknown
+	}
+
+	switch c := value[0]; {
+	case c == '"':
+		return influxql.String
+	case (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' ||
+		c == 'N' || c == 'n' || // NaN
+		c == 'I' || c == 'i': // Inf
+		if value[len(value)-1] == 'i' {
+			return influxql.Integer
+		}
+		return influxql.Float
+	}
+	return influxql.Boolean
+}
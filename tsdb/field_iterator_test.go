@@ -0,0 +1,79 @@
+package tsdb_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the iterator returns the name, type and encoded value of each field.
+func TestFieldIterator(t *testing.T) {
+	pts, err := tsdb.ParsePoints([]byte(`cpu a=1,b=-2i,c=true,d="x\"y, z",e\ f=1.5e3`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type field struct {
+		name  string
+		typ   influxql.DataType
+		value string
+		v     interface{}
+	}
+	var a []field
+	it := pts[0].FieldIterator()
+	for it.Next() {
+		v, err := it.Interface()
+		if err != nil {
+			t.Fatal(err)
+		}
+		a = append(a, field{string(it.Name()), it.Type(), string(it.Value()), v})
+	}
+
+	exp := []field{
+		{"a", influxql.Float, "1", 1.0},
+		{"b", influxql.Integer, "-2i", int64(-2)},
+		{"c", influxql.Boolean, "true", true},
+		{"d", influxql.String, `"x\"y, z"`, `x"y, z`},
+		{"e f", influxql.Float, "1.5e3", 1500.0},
+	}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected fields:\n got: %#v\nwant: %#v", a, exp)
+	}
+}
+
+// Ensure the iterator reads fields added to a point.
+func TestFieldIterator_AddField(t *testing.T) {
+	p := tsdb.NewPoint("cpu", nil, tsdb.Fields{"a": int64(1)}, time.Unix(0, 0))
+	p.AddField("b", "x")
+
+	var names []string
+	it := p.FieldIterator()
+	for it.Next() {
+		names = append(names, string(it.Name()))
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+}
+
+// Ensure the types of the fields of a point are read from their encoding.
+func TestPoint_FieldTypes(t *testing.T) {
+	pts, err := tsdb.ParsePoints([]byte(`cpu a=1,b=-2i,c=true,d="x=1i, z",e\ f=1.5e3`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]influxql.DataType{
+		"a":   influxql.Float,
+		"b":   influxql.Integer,
+		"c":   influxql.Boolean,
+		"d":   influxql.String,
+		"e f": influxql.Float,
+	}
+	if types := pts[0].FieldTypes(); !reflect.DeepEqual(types, exp) {
+		t.Fatalf("unexpected types: %v", types)
+	}
+}
@@ -0,0 +1,181 @@
+package tsdb
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fileBudget limits the number of file handles held by the open shards of a
+// store. Once over the limit, the least recently used idle shards are closed.
+// They're reopened the next time they're needed.
+type fileBudget struct {
+	mu     sync.Mutex
+	max    int
+	n      int
+	shards map[*Shard]int // open shards and the file handles they hold
+	stats  *Statistics
+}
+
+// newFileBudget returns a budget of max file handles. Returns nil if max is
+// zero, which leaves shards open.
+func newFileBudget(max int) *fileBudget {
+	if max <= 0 {
+		return nil
+	}
+	return &fileBudget{
+		max:    max,
+		shards: make(map[*Shard]int),
+		stats:  NewStatistics("tsdb", "files", nil),
+	}
+}
+
+// add accounts for the file handles of an open shard and closes idle shards
+// if the budget is exceeded.
+func (b *fileBudget) add(sh *Shard) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.shards[sh]; ok {
+		return
+	}
+	n := sh.fileN()
+	b.shards[sh] = n
+	b.n += n
+	b.stats.Add("openFiles", int64(n))
+
+	if b.n > b.max {
+		b.evict(sh)
+	}
+}
+
+// remove stops accounting for a shard, such as when it's closed.
+func (b *fileBudget) remove(sh *Shard) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeShard(sh)
+}
+
+// evict closes the least recently used idle shards, other than keep, until
+// the budget is no longer exceeded. Shards in use are skipped so the budget
+// may stay exceeded until they're released. The caller must hold the lock.
+func (b *fileBudget) evict(keep *Shard) {
+	shards := make([]*Shard, 0, len(b.shards))
+	for sh := range b.shards {
+		if sh != keep {
+			shards = append(shards, sh)
+		}
+	}
+	sort.Sort(shardsByLastUsed(shards))
+
+	for _, sh := range shards {
+		if b.n <= b.max {
+			break
+		}
+
+		ok, err := sh.closeIdle()
+		if err != nil {
+			log.New(sh.LogOutput, "[shard] ", log.LstdFlags).Printf("close idle shard %d: %s", sh.id, err)
+		}
+		if ok {
+			b.removeShard(sh)
+			b.stats.Add("idleClosed", 1)
+		}
+	}
+}
+
+// removeShard removes a shard from the budget. The caller must hold the lock.
+func (b *fileBudget) removeShard(sh *Shard) {
+	n, ok := b.shards[sh]
+	if !ok {
+		return
+	}
+	delete(b.shards, sh)
+	b.n -= n
+	b.stats.Add("openFiles", -int64(n))
+}
+
+// acquire reopens the shard if it was closed to stay within the file budget
+// and keeps it open until release is called.
+func (s *Shard) acquire() error {
+	s.openMu.Lock()
+	reopen := s.idle
+	if reopen {
+		if err := s.Open(); err != nil {
+			s.openMu.Unlock()
+			return fmt.Errorf("reopen shard %d: %s", s.id, err)
+		}
+		s.idle = false
+	}
+	s.refs++
+	s.openMu.Unlock()
+
+	atomic.StoreInt64(&s.lastUsed, time.Now().UnixNano())
+	if reopen {
+		s.budget.stats.Add("reopened", 1)
+		s.budget.add(s)
+	}
+	return nil
+}
+
+// release allows the shard to be closed once it's idle.
+func (s *Shard) release() {
+	s.openMu.Lock()
+	s.refs--
+	s.openMu.Unlock()
+}
+
+// closeIdle closes the shard's engine if the shard isn't in use. Returns true
+// if the engine was closed.
+func (s *Shard) closeIdle() (bool, error) {
+	s.openMu.Lock()
+	defer s.openMu.Unlock()
+
+	if s.refs > 0 || s.idle {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.engine == nil {
+		return false, nil
+	}
+
+	err := s.engine.Close()
+	s.engine = nil
+	s.idle = true
+	return true, err
+}
+
+// fileN returns the number of file handles held by the shard while it's open.
+func (s *Shard) fileN() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if fc, ok := s.engine.(interface {
+		FileN() int
+	}); ok {
+		return fc.FileN()
+	}
+	return 1
+}
+
+// shardsByLastUsed sorts shards from least to most recently used.
+type shardsByLastUsed []*Shard
+
+func (a shardsByLastUsed) Len() int { return len(a) }
+func (a shardsByLastUsed) Less(i, j int) bool {
+	return atomic.LoadInt64(&a[i].lastUsed) < atomic.LoadInt64(&a[j].lastUsed)
+}
+func (a shardsByLastUsed) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
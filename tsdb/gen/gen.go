@@ -0,0 +1,226 @@
+// Package gen generates synthetic points for capacity testing and for
+// benchmarking queries. Workloads are described by a Config: the number of
+// series, the cardinality of each tag, how points are distributed across the
+// series and the pattern their values follow.
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+const (
+	// DefaultMeasurement is the measurement points are written to by default.
+	DefaultMeasurement = "cpu"
+
+	// DefaultField is the name of the field holding the generated values.
+	DefaultField = "value"
+
+	// DefaultInterval is the time between points of the same series.
+	DefaultInterval = 10 * time.Second
+
+	// zipfS is the skew of the zipf distribution. Higher is more skewed.
+	zipfS = 1.1
+)
+
+// Distributions of points across series.
+const (
+	// Uniform writes the next point of each series in turn.
+	Uniform = "uniform"
+
+	// Zipf writes most points to a few series, like a workload where a
+	// handful of hosts or containers are far busier than the rest.
+	Zipf = "zipf"
+)
+
+// Value patterns.
+const (
+	Random   = "random"   // uniformly random floats in [0, 100)
+	Sine     = "sine"     // a sine wave over an hour, phase shifted per series
+	Counter  = "counter"  // an integer incremented with each point of a series
+	Constant = "constant" // always 1
+)
+
+// Tag describes a tag key and the number of distinct values it has.
+type Tag struct {
+	Key         string
+	Cardinality int
+}
+
+// ParseTags parses tags in the form "key:cardinality,key:cardinality". A tag
+// without a cardinality has a distinct value per series.
+func ParseTags(s string) ([]Tag, error) {
+	var tags []Tag
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		var tag Tag
+		if i := strings.Index(spec, ":"); i == -1 {
+			tag.Key = spec
+		} else {
+			n, err := strconv.Atoi(spec[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid tag cardinality: %s", spec)
+			}
+			tag.Key, tag.Cardinality = spec[:i], n
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Config describes a generated workload.
+type Config struct {
+	Measurement string
+	Field       string
+
+	// SeriesN is the number of series points are generated for. The tags of
+	// each series are different if the product of the tag cardinalities is
+	// at least SeriesN.
+	SeriesN int
+	Tags    []Tag
+
+	Distribution string
+	Values       string
+
+	// Start is the timestamp of the first point of each series and Interval
+	// is the time between points of the same series.
+	Start    time.Time
+	Interval time.Duration
+
+	// Seed seeds the random source so workloads can be reproduced.
+	Seed int64
+}
+
+// NewConfig returns a Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Measurement:  DefaultMeasurement,
+		Field:        DefaultField,
+		SeriesN:      1,
+		Distribution: Uniform,
+		Values:       Random,
+		Interval:     DefaultInterval,
+	}
+}
+
+// Generator generates the points of a workload.
+type Generator struct {
+	c    Config
+	rand *rand.Rand
+	zipf *rand.Zipf
+
+	tags  []tsdb.Tags // tags by series
+	n     []int64     // points generated by series
+	next  int         // next series of a uniform distribution
+	total int64
+}
+
+// NewGenerator returns a new generator for a workload.
+func NewGenerator(c Config) (*Generator, error) {
+	if c.Measurement == "" {
+		return nil, errors.New("measurement required")
+	} else if c.Field == "" {
+		return nil, errors.New("field required")
+	} else if c.SeriesN <= 0 {
+		return nil, errors.New("series count must be positive")
+	} else if c.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	switch c.Values {
+	case Random, Sine, Counter, Constant:
+	default:
+		return nil, fmt.Errorf("unknown value pattern: %s", c.Values)
+	}
+
+	g := &Generator{
+		c:    c,
+		rand: rand.New(rand.NewSource(c.Seed)),
+		tags: make([]tsdb.Tags, c.SeriesN),
+		n:    make([]int64, c.SeriesN),
+	}
+
+	switch c.Distribution {
+	case Uniform:
+	case Zipf:
+		g.zipf = rand.NewZipf(g.rand, zipfS, 1, uint64(c.SeriesN-1))
+	default:
+		return nil, fmt.Errorf("unknown distribution: %s", c.Distribution)
+	}
+
+	// Assign tag values by treating the series index as a mixed radix number
+	// with a digit per tag, so no two series share all of their tag values
+	// until the cardinalities are exhausted.
+	for i := range g.tags {
+		tags := make(map[string]string, len(c.Tags))
+		x := i
+		for _, t := range c.Tags {
+			card := t.Cardinality
+			if card <= 0 {
+				card = c.SeriesN
+			}
+			tags[t.Key] = t.Key + "-" + strconv.Itoa(x%card)
+			x /= card
+		}
+		g.tags[i] = tsdb.NewTags(tags)
+	}
+
+	return g, nil
+}
+
+// N returns the number of points generated so far.
+func (g *Generator) N() int64 { return g.total }
+
+// Next returns the next point of the workload.
+func (g *Generator) Next() tsdb.Point {
+	// Choose the series of the point.
+	var i int
+	if g.zipf != nil {
+		i = int(g.zipf.Uint64())
+	} else {
+		i = g.next
+		g.next = (g.next + 1) % g.c.SeriesN
+	}
+
+	n := g.n[i]
+	g.n[i]++
+	g.total++
+
+	t := g.c.Start.Add(time.Duration(n) * g.c.Interval)
+	return tsdb.NewPoint(g.c.Measurement, g.tags[i], map[string]interface{}{g.c.Field: g.value(i, n, t)}, t)
+}
+
+// Batch returns the next n points of the workload.
+func (g *Generator) Batch(n int) []tsdb.Point {
+	a := make([]tsdb.Point, n)
+	for i := range a {
+		a[i] = g.Next()
+	}
+	return a
+}
+
+// value returns the value of the nth point of series i at time t.
+func (g *Generator) value(i int, n int64, t time.Time) interface{} {
+	switch g.c.Values {
+	case Sine:
+		phase := 2 * math.Pi * float64(i) / float64(g.c.SeriesN)
+		return 50 + 50*math.Sin(2*math.Pi*float64(t.UnixNano()%int64(time.Hour))/float64(time.Hour)+phase)
+	case Counter:
+		return n
+	case Constant:
+		return float64(1)
+	default:
+		return g.rand.Float64() * 100
+	}
+}
@@ -0,0 +1,103 @@
+package gen_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb/gen"
+)
+
+// Ensure tag specs can be parsed.
+func TestParseTags(t *testing.T) {
+	tags, err := gen.ParseTags("region:5, host")
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(tags, []gen.Tag{{Key: "region", Cardinality: 5}, {Key: "host"}}) {
+		t.Fatalf("unexpected tags: %#v", tags)
+	}
+
+	if _, err := gen.ParseTags("host:x"); err == nil || err.Error() != "invalid tag cardinality: host:x" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a uniform workload writes a point to each series in turn and tags
+// follow their cardinality.
+func TestGenerator_Uniform(t *testing.T) {
+	c := gen.NewConfig()
+	c.SeriesN = 4
+	c.Tags = []gen.Tag{{Key: "region", Cardinality: 2}, {Key: "host", Cardinality: 2}}
+	c.Values = gen.Counter
+	c.Start = time.Unix(0, 0)
+	c.Interval = time.Second
+
+	g, err := gen.NewGenerator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make(map[string]int)
+	regions := make(map[string]struct{})
+	for _, p := range g.Batch(8) {
+		keys[string(p.Key())]++
+		regions[p.Tags().Get("region")] = struct{}{}
+	}
+	if len(keys) != 4 {
+		t.Fatalf("unexpected series count: %d", len(keys))
+	} else if len(regions) != 2 {
+		t.Fatalf("unexpected region count: %d", len(regions))
+	}
+	for k, n := range keys {
+		if n != 2 {
+			t.Fatalf("unexpected point count for %s: %d", k, n)
+		}
+	}
+
+	// The third point of the first series follows the other two.
+	p := g.Next()
+	if p.Time() != time.Unix(2, 0) {
+		t.Fatalf("unexpected time: %s", p.Time())
+	} else if v := p.Fields()["value"]; v != int64(2) {
+		t.Fatalf("unexpected value: %v", v)
+	} else if g.N() != 9 {
+		t.Fatalf("unexpected point count: %d", g.N())
+	}
+}
+
+// Ensure workloads with the same seed are the same.
+func TestGenerator_Seed(t *testing.T) {
+	c := gen.NewConfig()
+	c.SeriesN = 100
+	c.Tags = []gen.Tag{{Key: "host"}}
+	c.Distribution = gen.Zipf
+	c.Seed = 42
+
+	g0, err := gen.NewGenerator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g1, _ := gen.NewGenerator(c)
+
+	for i := 0; i < 100; i++ {
+		p0, p1 := g0.Next(), g1.Next()
+		if p0.String() != p1.String() {
+			t.Fatalf("%d. points differ: %s != %s", i, p0, p1)
+		}
+	}
+}
+
+// Ensure invalid workloads are rejected.
+func TestNewGenerator_Invalid(t *testing.T) {
+	c := gen.NewConfig()
+	c.Distribution = "pareto"
+	if _, err := gen.NewGenerator(c); err == nil || err.Error() != "unknown distribution: pareto" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c = gen.NewConfig()
+	c.Values = "square"
+	if _, err := gen.NewGenerator(c); err == nil || err.Error() != "unknown value pattern: square" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package tsdb
+
+// DefaultHasher is the name of the hasher of series keys used by default.
+const DefaultHasher = "fnv64a"
+
+// Hasher hashes series keys into the HashID of points. The hash routes points
+// to shards, so every node of a cluster must use the same hasher and it can't
+// be changed without moving series between the shards of current shard groups.
+type Hasher interface {
+	Name() string
+	Sum64(key []byte) uint64
+}
+
+// hashers is a lookup of registered hashers by name.
+var hashers = make(map[string]Hasher)
+
+func init() {
+	RegisterHasher(fnv64aHasher{})
+}
+
+// RegisterHasher registers a hasher of series keys, usually from an init function.
+func RegisterHasher(h Hasher) {
+	if _, ok := hashers[h.Name()]; ok {
+		panic("hasher already registered: " + h.Name())
+	}
+	hashers[h.Name()] = h
+}
+
+// HasherByName returns a registered hasher by name. Returns nil if not registered.
+func HasherByName(name string) Hasher { return hashers[name] }
+
+var (
+	// keyHasher hashes the keys of points. Nil is the inlined FNV-1a.
+	keyHasher Hasher
+
+	// consistentBuckets maps hashes to buckets with a consistent hash.
+	consistentBuckets bool
+)
+
+// SetHasher sets the hasher of the HashID of points. A nil hasher restores
+// the default. It must be set before any point is hashed, usually at startup.
+func SetHasher(h Hasher) {
+	if _, ok := h.(fnv64aHasher); ok {
+		h = nil
+	}
+	keyHasher = h
+}
+
+// SetConsistentBuckets sets whether BucketID uses a consistent hash instead
+// of the HashID modulo the number of buckets. With a consistent hash only
+// 1/n of the keys move to another bucket when the number of buckets grows to
+// n. It must be set before any point is hashed, usually at startup.
+func SetConsistentBuckets(v bool) { consistentBuckets = v }
+
+// BucketID returns the bucket of n a hash falls in, the same way
+// Point.BucketID does. n less than 1 is treated as 1.
+func BucketID(hash uint64, n int) int {
+	if n <= 1 {
+		return 0
+	} else if consistentBuckets {
+		return jumpHash(hash, n)
+	}
+	return int(hash % uint64(n))
+}
+
+// jumpHash is the jump consistent hash of Lamping and Veach.
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// fnv64a computes the FNV-1a hash of key inline since hash/fnv allocates a
+// hasher on every call.
+func fnv64a(key []byte) uint64 {
+	h := uint64(offset64)
+	for _, c := range key {
+		h ^= uint64(c)
+		h *= prime64
+	}
+	return h
+}
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// fnv64aHasher is the default hasher, the FNV-1a hash of keys.
+type fnv64aHasher struct{}
+
+func (fnv64aHasher) Name() string            { return DefaultHasher }
+func (fnv64aHasher) Sum64(key []byte) uint64 { return fnv64a(key) }
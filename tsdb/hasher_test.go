@@ -0,0 +1,66 @@
+package tsdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure HashID uses the hasher that's set.
+func TestSetHasher(t *testing.T) {
+	defer tsdb.SetHasher(nil)
+
+	pt := tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+	def := pt.HashID()
+
+	tsdb.SetHasher(lenHasher{})
+	if got, exp := pt.HashID(), uint64(len("cpu,host=serverA")); got != exp {
+		t.Fatalf("unexpected hash: got %d, exp %d", got, exp)
+	}
+
+	tsdb.SetHasher(tsdb.HasherByName(tsdb.DefaultHasher))
+	if got := pt.HashID(); got != def {
+		t.Fatalf("unexpected hash: got %d, exp %d", got, def)
+	}
+}
+
+// Ensure consistent buckets only move keys to the new bucket when a bucket is added.
+func TestBucketID_Consistent(t *testing.T) {
+	tsdb.SetConsistentBuckets(true)
+	defer tsdb.SetConsistentBuckets(false)
+
+	var moved int
+	for i := uint64(0); i < 10000; i++ {
+		h := i * 0x9E3779B97F4A7C15
+		a, b := tsdb.BucketID(h, 10), tsdb.BucketID(h, 11)
+		if a < 0 || a >= 10 {
+			t.Fatalf("bucket out of range: %d", a)
+		} else if a != b {
+			if b != 10 {
+				t.Fatalf("key moved from bucket %d to %d", a, b)
+			}
+			moved++
+		}
+	}
+
+	// About 1/11 of the keys move.
+	if moved < 700 || moved > 1100 {
+		t.Fatalf("unexpected number of moved keys: %d", moved)
+	}
+}
+
+// Ensure buckets are the hash modulo the number of buckets by default.
+func TestBucketID(t *testing.T) {
+	if got := tsdb.BucketID(23, 10); got != 3 {
+		t.Fatalf("unexpected bucket: %d", got)
+	} else if got := tsdb.BucketID(23, 0); got != 0 {
+		t.Fatalf("unexpected bucket: %d", got)
+	}
+}
+
+// lenHasher hashes keys to their length.
+type lenHasher struct{}
+
+func (lenHasher) Name() string            { return "len" }
+func (lenHasher) Sum64(key []byte) uint64 { return uint64(len(key)) }
@@ -0,0 +1,19 @@
+// +build xxhash
+
+package tsdb
+
+import (
+	"github.com/cespare/xxhash"
+)
+
+func init() {
+	RegisterHasher(xxhashHasher{})
+}
+
+// xxhashHasher hashes keys with xxHash, which is faster than FNV-1a on long
+// keys and distributes them better. It's only registered when built with the
+// "xxhash" build tag.
+type xxhashHasher struct{}
+
+func (xxhashHasher) Name() string            { return "xxhash" }
+func (xxhashHasher) Sum64(key []byte) uint64 { return xxhash.Sum64(key) }
@@ -0,0 +1,254 @@
+package tsdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// seriesIndexOverhead is the estimated number of bytes used by a series
+	// in the tag index, not counting its tags.
+	seriesIndexOverhead = 64
+
+	// tagIndexOverhead is the estimated number of bytes used by each tag of
+	// a series in the tag index, not counting the key and value.
+	tagIndexOverhead = 48
+)
+
+// SetMaxIndexMemory limits the estimated memory used by the tag indexes of the
+// measurements in the database. Once over the limit, the tags and tag index of
+// the least recently used measurements are written to files in path and
+// dropped from memory. They're loaded back the next time they're needed.
+//
+// Series keys and IDs are always kept in memory so writes never need to load
+// an evicted index. A limit of zero disables eviction.
+func (d *DatabaseIndex) SetMaxIndexMemory(n int64, path string) {
+	d.evictMu.Lock()
+	defer d.evictMu.Unlock()
+	atomic.StoreInt64(&d.maxIndexMemory, n)
+	d.evictPath = path
+}
+
+// IndexSize returns the estimated number of bytes used by the tag indexes in memory.
+func (d *DatabaseIndex) IndexSize() int64 { return atomic.LoadInt64(&d.indexSize) }
+
+// EvictIndexes evicts the least recently used tag indexes until the index is
+// back under its memory limit.
+func (d *DatabaseIndex) EvictIndexes() error {
+	if !d.overIndexMemory() {
+		return nil
+	}
+
+	d.evictMu.Lock()
+	defer d.evictMu.Unlock()
+
+	d.mu.RLock()
+	measurements := d.Measurements()
+	d.mu.RUnlock()
+	sort.Sort(measurementsByLastUsed(measurements))
+
+	for _, m := range measurements {
+		if !d.overIndexMemory() {
+			break
+		}
+		if err := m.evict(); err != nil {
+			return fmt.Errorf("evict %s: %s", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// overIndexMemory returns true if the tag indexes use more than the memory limit.
+func (d *DatabaseIndex) overIndexMemory() bool {
+	max := atomic.LoadInt64(&d.maxIndexMemory)
+	return max > 0 && d.IndexSize() > max
+}
+
+// addIndexSize adds n bytes to the size of the measurement's tag index.
+// The caller must hold the write lock.
+func (m *Measurement) addIndexSize(n int64) {
+	m.indexSize += n
+	if m.index != nil {
+		atomic.AddInt64(&m.index.indexSize, n)
+	}
+}
+
+// touch marks the measurement as recently used.
+func (m *Measurement) touch() { atomic.StoreInt64(&m.lastUsed, time.Now().UnixNano()) }
+
+// rlockLoaded takes the read lock, loading the tag index first if it was
+// evicted. The lock isn't held if the index can't be loaded.
+func (m *Measurement) rlockLoaded() error {
+	m.touch()
+	m.mu.RLock()
+	for m.evicted {
+		m.mu.RUnlock()
+		if err := m.ensureLoaded(); err != nil {
+			return err
+		}
+		m.mu.RLock()
+	}
+	return nil
+}
+
+// ensureLoaded loads the tag index if it was evicted.
+func (m *Measurement) ensureLoaded() error {
+	m.touch()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.evicted {
+		return m.load()
+	}
+	return nil
+}
+
+// evict writes the tags of every series in the measurement to disk and drops
+// them and the tag index from memory.
+func (m *Measurement) evict() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.evicted || len(m.seriesIDs) == 0 || m.index == nil {
+		return nil
+	}
+
+	if m.evictPath == "" {
+		id := atomic.AddUint64(&m.index.lastEvictID, 1)
+		m.evictPath = filepath.Join(m.index.evictPath, strconv.FormatUint(id, 10)+".idx")
+	}
+	if err := os.MkdirAll(filepath.Dir(m.evictPath), 0700); err != nil {
+		return err
+	}
+
+	// Write the series to a temporary file and move it into place once complete.
+	f, err := os.Create(m.evictPath + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	for _, id := range m.seriesIDs {
+		buf, err := m.seriesByID[id].MarshalBinary()
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		var hdr [12]byte
+		binary.BigEndian.PutUint64(hdr[0:8], id)
+		binary.BigEndian.PutUint32(hdr[8:12], uint32(len(buf)))
+		if _, err := w.Write(hdr[:]); err != nil {
+			f.Close()
+			return err
+		} else if _, err := w.Write(buf); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	} else if err := os.Rename(f.Name(), m.evictPath); err != nil {
+		return err
+	}
+
+	// Drop the tags and tag index from memory.
+	for _, s := range m.seriesByID {
+		s.Tags = nil
+	}
+	m.seriesByTagKeyValue = nil
+	m.addIndexSize(-m.indexSize)
+	m.evicted = true
+
+	return nil
+}
+
+// load reads the evicted tags back from disk and rebuilds the tag index. The
+// caller must hold the write lock. The index stays evicted if the file can't
+// be read.
+func (m *Measurement) load() error {
+	f, err := os.Open(m.evictPath)
+	if err != nil {
+		return fmt.Errorf("load evicted index for %s: %s", m.Name, err)
+	}
+	defer f.Close()
+
+	// Read every series before changing the index so it's left evicted if
+	// the file is corrupt.
+	tags := make(map[uint64]map[string]string, len(m.seriesByID))
+	r := bufio.NewReader(f)
+	for {
+		var hdr [12]byte
+		if _, err := io.ReadFull(r, hdr[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("load evicted index for %s: %s", m.Name, err)
+		}
+
+		buf := make([]byte, binary.BigEndian.Uint32(hdr[8:12]))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return fmt.Errorf("load evicted index for %s: %s", m.Name, err)
+		}
+
+		var tmp Series
+		if err := tmp.UnmarshalBinary(buf); err != nil {
+			return fmt.Errorf("load evicted index for %s: %s", m.Name, err)
+		}
+		tags[binary.BigEndian.Uint64(hdr[0:8])] = tmp.Tags
+	}
+
+	// Series dropped since the index was evicted are skipped. Series added
+	// since then kept their tags in memory.
+	m.seriesByTagKeyValue = make(map[string]map[string]SeriesIDs)
+	for _, id := range m.seriesIDs {
+		s := m.seriesByID[id]
+		if t, ok := tags[id]; ok {
+			s.Tags = t
+		}
+		m.indexSeriesTags(s)
+	}
+
+	m.evicted = false
+	return nil
+}
+
+// dropIndex removes the evicted tag index from disk and the measurement's
+// size from the database index.
+func (m *Measurement) dropIndex() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addIndexSize(-m.indexSize)
+	if m.evictPath != "" {
+		os.Remove(m.evictPath)
+	}
+}
+
+// seriesIndexSize returns the estimated number of bytes used by a series in the tag index.
+func seriesIndexSize(s *Series) int64 {
+	n := int64(seriesIndexOverhead)
+	for k, v := range s.Tags {
+		n += int64(len(k) + len(v) + tagIndexOverhead)
+	}
+	return n
+}
+
+// measurementsByLastUsed sorts measurements from least to most recently used.
+type measurementsByLastUsed Measurements
+
+func (a measurementsByLastUsed) Len() int { return len(a) }
+func (a measurementsByLastUsed) Less(i, j int) bool {
+	return atomic.LoadInt64(&a[i].lastUsed) < atomic.LoadInt64(&a[j].lastUsed)
+}
+func (a measurementsByLastUsed) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
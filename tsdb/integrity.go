@@ -0,0 +1,150 @@
+package tsdb
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// IntegrityReport is the result of checking a sample of a shard for silent
+// corruption, so it's found before a query reads the corrupt data.
+type IntegrityReport struct {
+	ShardID         uint64
+	Database        string
+	RetentionPolicy string
+	Time            time.Time
+
+	// SeriesChecked is the number of series of the index that were sampled.
+	// MissingSeries is the number of them the engine has no data or
+	// metadata for.
+	SeriesChecked int
+	MissingSeries int
+
+	// BlocksChecked is the number of blocks of the sampled series that were
+	// decoded and CorruptBlocks the number of them that failed to decode or
+	// hold entries outside of their time range.
+	BlocksChecked int
+	CorruptBlocks int
+
+	// WALSegments is the number of segment files of the WAL and WALGaps the
+	// number of segments missing between them.
+	WALSegments int
+	WALGaps     int
+
+	// Errors describes each problem found.
+	Errors []string
+}
+
+// OK returns true if no problem was found.
+func (r *IntegrityReport) OK() bool { return len(r.Errors) == 0 }
+
+// Errorf records a problem.
+func (r *IntegrityReport) Errorf(format string, a ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, a...))
+}
+
+// IntegrityChecker is implemented by engines that can check their files. The
+// keys are a sample of the shard's series that are in the index.
+type IntegrityChecker interface {
+	CheckIntegrity(keys []string, r *IntegrityReport) error
+}
+
+// CheckIntegrity checks a random sample of up to n series of the shard. The
+// first point of each series is decoded with the fields of its measurement,
+// and engines implementing IntegrityChecker check their own files.
+func (s *Shard) CheckIntegrity(n int) (*IntegrityReport, error) {
+	r := &IntegrityReport{
+		ShardID:  s.id,
+		Database: s.database,
+		Time:     time.Now().UTC(),
+	}
+
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.release()
+
+	series := sampleSeries(s.series(), n)
+	r.SeriesChecked = len(series)
+
+	tx, err := s.engine.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	keys := make([]string, 0, len(series))
+	for _, ss := range series {
+		keys = append(keys, ss.Key)
+
+		c := tx.Cursor(ss.Key)
+		if c == nil {
+			r.MissingSeries++
+			r.Errorf("series %s: no data", ss.Key)
+			continue
+		}
+		k, v := c.Seek(u64tob(0))
+		if k == nil {
+			r.MissingSeries++
+			r.Errorf("series %s: no data", ss.Key)
+			continue
+		}
+		if _, err := s.FieldCodec(ss.measurement.Name).DecodeFieldsWithNames(v); err != nil {
+			r.Errorf("series %s: decode: %s", ss.Key, err)
+		}
+	}
+
+	if ic, ok := s.engine.(IntegrityChecker); ok {
+		if err := ic.CheckIntegrity(keys, r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// sampleSeries returns up to n randomly chosen series, sorted by key.
+func sampleSeries(a []*Series, n int) []*Series {
+	if n <= 0 || len(a) <= n {
+		return a
+	}
+
+	sample := make([]*Series, n)
+	for i, j := range rand.Perm(len(a))[:n] {
+		sample[i] = a[j]
+	}
+	sort.Sort(seriesByKey(sample))
+	return sample
+}
+
+// CheckIntegrity checks a sample of up to n series of every shard. Shards
+// that can't be checked are reported with the error.
+func (s *Store) CheckIntegrity(n int) []*IntegrityReport {
+	s.mu.RLock()
+	shards := make([]*Shard, 0, len(s.shards))
+	for _, sh := range s.shards {
+		shards = append(shards, sh)
+	}
+	s.mu.RUnlock()
+	sort.Sort(shardsByID(shards))
+
+	reports := make([]*IntegrityReport, 0, len(shards))
+	for _, sh := range shards {
+		r, err := sh.CheckIntegrity(n)
+		if err != nil {
+			r = &IntegrityReport{ShardID: sh.id, Database: sh.database, Time: time.Now().UTC()}
+			r.Errorf("check: %s", err)
+		}
+		// Shards are stored in <dir>/<database>/<retention policy>/<id>.
+		r.RetentionPolicy = filepath.Base(filepath.Dir(sh.path))
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+type shardsByID []*Shard
+
+func (a shardsByID) Len() int           { return len(a) }
+func (a shardsByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a shardsByID) Less(i, j int) bool { return a[i].id < a[j].id }
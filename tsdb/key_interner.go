@@ -0,0 +1,57 @@
+package tsdb
+
+import "sync"
+
+// KeyInterner returns shared copies of series keys so the points of a series
+// written over and over don't each hold their own key, or the buffer they
+// were parsed from. It keeps the first max keys it sees and never evicts
+// them, so a key is either always or never interned. Keys seen once it's full
+// are returned as is.
+type KeyInterner struct {
+	mu   sync.RWMutex
+	max  int
+	keys map[string][]byte
+}
+
+// NewKeyInterner returns a new instance of KeyInterner holding at most max keys.
+func NewKeyInterner(max int) *KeyInterner {
+	return &KeyInterner{
+		max:  max,
+		keys: make(map[string][]byte),
+	}
+}
+
+// Intern returns the shared copy of key, adding it if there's room. The
+// returned slice must not be modified. A nil interner returns key.
+func (i *KeyInterner) Intern(key []byte) []byte {
+	if i == nil || i.max <= 0 {
+		return key
+	}
+
+	i.mu.RLock()
+	k, ok := i.keys[string(key)]
+	i.mu.RUnlock()
+	if ok {
+		return k
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if k, ok := i.keys[string(key)]; ok {
+		return k
+	} else if len(i.keys) >= i.max {
+		return key
+	}
+
+	k = make([]byte, len(key))
+	copy(k, key)
+	i.keys[string(k)] = k
+	return k
+}
+
+// Len returns the number of interned keys.
+func (i *KeyInterner) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.keys)
+}
@@ -0,0 +1,49 @@
+package tsdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure points of the same series share their key once it's interned.
+func TestParsePointsWithOptions_Interner(t *testing.T) {
+	interner := tsdb.NewKeyInterner(2)
+	buf := []byte("cpu,host=a value=1 1\ncpu,host=b value=2 1\ncpu,host=c value=3 1\ncpu,host=a value=4 2")
+
+	points, err := tsdb.ParsePointsWithOptions(buf, tsdb.ParseOptions{DefaultTime: time.Unix(0, 0), Interner: interner})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(points) != 4 {
+		t.Fatalf("unexpected point count: %d", len(points))
+	}
+
+	if a, b := points[0].Key(), points[3].Key(); &a[0] != &b[0] {
+		t.Fatalf("key not shared: %s", a)
+	}
+	if n := interner.Len(); n != 2 {
+		t.Fatalf("unexpected interned key count: %d", n)
+	}
+
+	// The interner is full so new series keep their own key.
+	if k := interner.Intern([]byte("cpu,host=c")); string(k) != "cpu,host=c" {
+		t.Fatalf("unexpected key: %s", k)
+	} else if n := interner.Len(); n != 2 {
+		t.Fatalf("unexpected interned key count: %d", n)
+	}
+
+	// Keys are copied so the parsed buffer can be reused.
+	copy(buf, "mem")
+	if k := points[3].Key(); string(k) != "cpu,host=a" {
+		t.Fatalf("unexpected key: %s", k)
+	}
+}
+
+// Ensure a nil interner returns keys as is.
+func TestKeyInterner_Nil(t *testing.T) {
+	var interner *tsdb.KeyInterner
+	if k := interner.Intern([]byte("cpu")); string(k) != "cpu" {
+		t.Fatalf("unexpected key: %s", k)
+	}
+}
@@ -0,0 +1,89 @@
+package tsdb
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxKeySize is the default size limit, in bytes, of the keys and tag
+// values checked by KeyLimits.
+const DefaultMaxKeySize = 64 * 1024
+
+// KeyLimits makes the parser check that the measurement, tag keys, tag values
+// and field keys of each line are valid UTF-8 and no larger than the limits,
+// in bytes as they're written. Zero limits are DefaultMaxKeySize. A nil
+// KeyLimits checks nothing.
+type KeyLimits struct {
+	MaxKeySize      int // Measurements, tag keys and field keys.
+	MaxTagValueSize int
+}
+
+// keyError is the error of a key or tag value that isn't valid UTF-8 or is
+// over its size limit.
+type keyError struct {
+	part   string // "measurement", "tag key", "tag value" or "field key".
+	offset int    // The byte in the line where the part starts, or the invalid UTF-8 is.
+	reason string
+}
+
+func (e *keyError) Error() string {
+	return fmt.Sprintf("%s %s at byte %d", e.part, e.reason, e.offset)
+}
+
+// check returns an error if the part of the line starting at pos is invalid.
+func (l *KeyLimits) check(part string, b []byte, pos int) *keyError {
+	if l == nil {
+		return nil
+	}
+
+	max := l.MaxKeySize
+	if part == "tag value" {
+		max = l.MaxTagValueSize
+	}
+	if max <= 0 {
+		max = DefaultMaxKeySize
+	}
+	if len(b) > max {
+		return &keyError{part: part, offset: pos, reason: fmt.Sprintf("is %d bytes, limit is %d", len(b), max)}
+	}
+
+	for i := 0; i < len(b); {
+		r, n := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && n <= 1 {
+			return &keyError{part: part, offset: pos + i, reason: "is not valid UTF-8"}
+		}
+		i += n
+	}
+	return nil
+}
+
+// checkKey checks the measurement and tags of the series key in buf[start:end].
+func (l *KeyLimits) checkKey(buf []byte, start, end int) *keyError {
+	if l == nil {
+		return nil
+	}
+
+	// Measurements end at the first unescaped comma, and tag keys at the
+	// first unescaped equals sign.
+	part, partStart := "measurement", start
+	for i := start; i <= end; i++ {
+		if i < end-1 && buf[i] == '\\' {
+			i++
+			continue
+		}
+		if i < end && buf[i] != ',' && (buf[i] != '=' || part != "tag key") {
+			continue
+		}
+
+		if e := l.check(part, buf[partStart:i], partStart); e != nil {
+			return e
+		}
+		if i < end && buf[i] == '=' {
+			part = "tag value"
+		} else {
+			part = "tag key"
+		}
+		partStart = i + 1
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package tsdb
+
+import (
+	"sync"
+	"time"
+)
+
+// LastValueCache holds the last value written to each field of each series,
+// by shard, so queries for the current value of a field are answered from
+// memory. Values written to a measurement are always recorded but the values
+// already in a shard are only read the first time the measurement is queried.
+type LastValueCache struct {
+	mu   sync.RWMutex
+	sets map[lastValueKey]*lastValueSet
+}
+
+type lastValueKey struct {
+	database    string
+	shardID     uint64
+	measurement string
+}
+
+// lastValueSet holds the last values of the series of a measurement in a shard.
+type lastValueSet struct {
+	mu sync.RWMutex
+
+	// warm is set once the values in the shard have been read.
+	warm bool
+
+	// series key to field name to last value
+	series map[string]map[string]lastValue
+}
+
+// lastValue is the last value of a field of a series.
+type lastValue struct {
+	time  int64
+	value interface{}
+
+	// expires is the ExpiresField of the point holding the value, zero if
+	// it was written without a ttl.
+	expires int64
+}
+
+// NewLastValueCache returns a new, empty instance of LastValueCache.
+func NewLastValueCache() *LastValueCache {
+	return &LastValueCache{sets: make(map[lastValueKey]*lastValueSet)}
+}
+
+// set returns the values of a measurement, creating the set if needed.
+func (c *LastValueCache) set(database string, shardID uint64, measurement string) *lastValueSet {
+	k := lastValueKey{database: database, shardID: shardID, measurement: measurement}
+
+	c.mu.RLock()
+	set := c.sets[k]
+	c.mu.RUnlock()
+	if set != nil {
+		return set
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if set = c.sets[k]; set == nil {
+		set = &lastValueSet{series: make(map[string]map[string]lastValue)}
+		c.sets[k] = set
+	}
+	return set
+}
+
+// addPoints records points written to a shard. The fields are decoded from the
+// encoded points so they have the types queries return.
+func (c *LastValueCache) addPoints(sh *Shard, points []Point) {
+	for _, p := range points {
+		fields, err := sh.FieldCodec(p.Name()).DecodeFieldsWithNames(p.Data())
+		if err != nil {
+			continue
+		}
+		c.set(sh.database, sh.id, p.Name()).add(string(p.Key()), p.UnixNano(), fields, true)
+	}
+}
+
+// DeleteDatabase drops the values of all measurements of a database, so they're
+// read from the shards again the next time they're queried.
+func (c *LastValueCache) DeleteDatabase(database string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.sets {
+		if k.database == database {
+			delete(c.sets, k)
+		}
+	}
+}
+
+// DeleteShard drops the values of all measurements of a shard.
+func (c *LastValueCache) DeleteShard(shardID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.sets {
+		if k.shardID == shardID {
+			delete(c.sets, k)
+		}
+	}
+}
+
+// add records the fields of a point of a series written at time t. Values
+// written later are kept. Values written at the same time are replaced if
+// overwrite is set, since the newer point overwrote them in the shard.
+// Fields the newer point doesn't have are forgotten and read from the shard
+// again since their last value is now an earlier one.
+func (s *lastValueSet) add(key string, t int64, fields map[string]interface{}, overwrite bool) {
+	var expires int64
+	if v, ok := fields[ExpiresField].(int64); ok {
+		expires = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := s.series[key]
+	if values == nil {
+		values = make(map[string]lastValue, len(fields))
+		s.series[key] = values
+	}
+	for name, v := range fields {
+		if name == ExpiresField {
+			continue
+		}
+		if prev, ok := values[name]; ok && (prev.time > t || (prev.time == t && !overwrite)) {
+			continue
+		}
+		values[name] = lastValue{time: t, value: v, expires: expires}
+	}
+
+	if overwrite {
+		for name, v := range values {
+			if _, ok := fields[name]; !ok && v.time == t {
+				delete(values, name)
+				s.warm = false
+			}
+		}
+	}
+}
+
+// lastValueOf returns the last value of a field among a list of series in a
+// list of shards. It returns false if none of the series have a value or if
+// several values were written at the last time, since which one a query
+// selects is undefined.
+func lastValueOf(sets []*lastValueSet, keys []string, field string) (lastValue, bool) {
+	var last lastValue
+	var found, tied bool
+	for _, s := range sets {
+		s.mu.RLock()
+		for _, key := range keys {
+			v, ok := s.series[key][field]
+			if !ok {
+				continue
+			}
+			if found && v.time == last.time {
+				tied = true
+			} else if !found || v.time > last.time {
+				last, found, tied = v, true, false
+			}
+		}
+		s.mu.RUnlock()
+	}
+	return last, found && !tied
+}
+
+// isWarm returns true if the values in the shard have been read.
+func (s *lastValueSet) isWarm() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.warm
+}
+
+// warmUp reads the values of the measurement from the shard. Values recorded
+// while the shard is read are kept if they're later.
+func (s *lastValueSet) warmUp(sh *Shard, measurement string) error {
+	if err := sh.iterateSeries(sh.measurementSeries(measurement), func(key string, t time.Time, fields map[string]interface{}) error {
+		s.add(key, t.UnixNano(), fields, false)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.warm = true
+	s.mu.Unlock()
+	return nil
+}
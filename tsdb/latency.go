@@ -0,0 +1,53 @@
+package tsdb
+
+import "time"
+
+// latencyBuckets are the upper bounds of the latency histogram buckets and
+// the counters that hold them. Durations over the last bound are counted in
+// "le_inf".
+var latencyBuckets = []struct {
+	bound time.Duration
+	key   string
+}{
+	{100 * time.Microsecond, "le_100us"},
+	{time.Millisecond, "le_1ms"},
+	{10 * time.Millisecond, "le_10ms"},
+	{100 * time.Millisecond, "le_100ms"},
+	{time.Second, "le_1s"},
+	{10 * time.Second, "le_10s"},
+}
+
+// LatencyHistogram counts durations in fixed buckets, along with their count
+// and sum in nanoseconds, as registered statistics. Counts are per bucket,
+// not cumulative.
+type LatencyHistogram struct {
+	stats *Statistics
+}
+
+// NewLatencyHistogram returns a histogram registered as statistics of a
+// module with the given name and tags.
+func NewLatencyHistogram(module, name string, tags map[string]string) *LatencyHistogram {
+	return &LatencyHistogram{stats: NewStatistics(module, name, tags)}
+}
+
+// Observe adds a duration to the histogram. Nil histograms ignore durations.
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	if h == nil {
+		return
+	}
+
+	key := "le_inf"
+	for _, b := range latencyBuckets {
+		if d <= b.bound {
+			key = b.key
+			break
+		}
+	}
+
+	h.stats.Add(key, 1)
+	h.stats.Add("count", 1)
+	h.stats.Add("sumNs", int64(d))
+}
+
+// Statistics returns the statistics the histogram is kept in.
+func (h *LatencyHistogram) Statistics() *Statistics { return h.stats }
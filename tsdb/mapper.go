@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/influxdb/influxdb/influxql"
 )
@@ -20,6 +21,45 @@ type MapperValue struct {
 	Time  int64             `json:"time,omitempty"`  // Ignored for aggregate output.
 	Value interface{}       `json:"value,omitempty"` // For aggregate, contains interval time multiple values.
 	Tags  map[string]string `json:"tags,omitempty"`  // Meta tags for results
+
+	// The aggregate outputs of remote mappers are kept encoded until they're
+	// read, so outputs the query doesn't need are never decoded.
+	raw           []json.RawMessage
+	unmarshallers []influxql.UnmarshalFunc
+}
+
+// Len returns the number of outputs of an aggregate value.
+func (mv *MapperValue) Len() int {
+	values, _ := mv.Value.([]interface{})
+	return len(values)
+}
+
+// Column returns the i-th output of an aggregate value, decoding it first if
+// it came from a remote mapper.
+func (mv *MapperValue) Column(i int) (interface{}, error) {
+	values, _ := mv.Value.([]interface{})
+	if i >= len(values) {
+		return nil, nil
+	} else if mv.raw == nil || mv.raw[i] == nil {
+		return values[i], nil
+	}
+
+	b := mv.raw[i]
+	mv.raw[i] = nil
+	if string(b) == "null" {
+		return nil, nil
+	}
+
+	var err error
+	if i < len(mv.unmarshallers) {
+		values[i], err = mv.unmarshallers[i](b)
+	} else {
+		err = json.Unmarshal(b, &values[i])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values[i], nil
 }
 
 type MapperValues []*MapperValue
@@ -31,12 +71,16 @@ func (a MapperValues) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 type MapperOutput struct {
 	Name      string            `json:"name,omitempty"`
 	Tags      map[string]string `json:"tags,omitempty"`
-	Fields    []string          `json:"fields,omitempty"` // Field names of returned data.
-	Values    []*MapperValue    `json:"values,omitempty"` // For aggregates contains a single value at [0]
+	Fields    []string          `json:"fields,omitempty"`    // Field names of returned data.
+	Values    []*MapperValue    `json:"values,omitempty"`    // For aggregates contains a single value at [0]
+	TagSetEnd bool              `json:"tagSetEnd,omitempty"` // Set on the last chunk of a tagset.
 	cursorKey string            // Tagset-based key for the source cursor. Cached for performance reasons.
 }
 
 func (mo *MapperOutput) key() string {
+	if mo.cursorKey == "" {
+		mo.cursorKey = formMeasurementTagSetKey(mo.Name, mo.Tags)
+	}
 	return mo.cursorKey
 }
 
@@ -56,6 +100,7 @@ type LocalMapper struct {
 	selectTags      []string        // tag keys that occur in the select clause
 	cursors         []*tagSetCursor // Cursors per tag sets.
 	currCursorIndex int             // Current tagset cursor being drained.
+	nextValue       *MapperValue    // Value read ahead of the last raw chunk.
 
 	// The following attributes are only used when mappers are for aggregate queries.
 
@@ -65,6 +110,9 @@ type LocalMapper struct {
 	currInterval    int                // Current interval for which data is being fetched.
 	mapFuncs        []influxql.MapFunc // The mapping functions.
 	fieldNames      []string           // the field name being read for mapping.
+
+	// Unmarshallers of the outputs of the map functions of a remote mapper.
+	unmarshallers []influxql.UnmarshalFunc
 }
 
 // NewLocalMapper returns a mapper for the given shard, which will return data for the SELECT statement.
@@ -90,9 +138,13 @@ func (lm *LocalMapper) Open() error {
 
 	var err error
 
-	// Get a read-only transaction.
+	// Keep the shard open and get a read-only transaction.
+	if err := lm.shard.acquire(); err != nil {
+		return err
+	}
 	tx, err := lm.shard.engine.Begin(false)
 	if err != nil {
+		lm.shard.release()
 		return err
 	}
 	lm.tx = tx
@@ -216,13 +268,25 @@ func (lm *LocalMapper) Open() error {
 		for _, t := range tagSets {
 			cursors := []*seriesCursor{}
 
+			if lm.shard.queryStats != nil {
+				lm.shard.queryStats.Add(lm.shard.database, t.SeriesKeys)
+			}
+
 			for i, key := range t.SeriesKeys {
-				c := lm.tx.Cursor(key)
+				var c Cursor
+				if tx, ok := lm.tx.(RangeTx); ok {
+					c = tx.CursorRange(key, lm.queryTMin, lm.queryTMax)
+				} else {
+					c = lm.tx.Cursor(key)
+				}
 				if c == nil {
 					// No data exists for this key.
 					continue
 				}
-				seriesTags := lm.shard.index.TagsForSeries(key)
+				seriesTags, err := lm.shard.index.TagsForSeries(key)
+				if err != nil {
+					return err
+				}
 				cm := newSeriesCursor(c, t.Filters[i], seriesTags)
 				cursors = append(cursors, cm)
 			}
@@ -275,8 +339,8 @@ func (lm *LocalMapper) NextChunk() (interface{}, error) {
 			return nil, nil
 		}
 
-		mo := &MapperOutput{}
-		if err := json.Unmarshal(b.([]byte), mo); err != nil {
+		mo, err := lm.unmarshalRemoteOutput(b.([]byte))
+		if err != nil {
 			return nil, err
 		} else if len(mo.Values) == 0 {
 			// Mapper on other node sent 0 values so it's done.
@@ -293,6 +357,86 @@ func (lm *LocalMapper) NextChunk() (interface{}, error) {
 	return lm.nextChunkAgg()
 }
 
+// remoteMapperOutput is the output of a remote mapper with its values still
+// encoded.
+type remoteMapperOutput struct {
+	Name   string            `json:"name,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Fields []string          `json:"fields,omitempty"`
+	Values []struct {
+		Time  int64             `json:"time,omitempty"`
+		Value json.RawMessage   `json:"value,omitempty"`
+		Tags  map[string]string `json:"tags,omitempty"`
+	} `json:"values,omitempty"`
+	TagSetEnd bool `json:"tagSetEnd,omitempty"`
+}
+
+// unmarshalRemoteOutput decodes a chunk of a remote mapper. The outputs of
+// aggregates are only split apart; each is decoded by the unmarshaller of its
+// map function when read with Column, to the type its reduce function merges.
+func (lm *LocalMapper) unmarshalRemoteOutput(b []byte) (*MapperOutput, error) {
+	var ro remoteMapperOutput
+	if err := json.Unmarshal(b, &ro); err != nil {
+		return nil, err
+	}
+
+	unmarshallers, err := lm.remoteUnmarshallers()
+	if err != nil {
+		return nil, err
+	}
+
+	mo := &MapperOutput{
+		Name:      ro.Name,
+		Tags:      ro.Tags,
+		Fields:    ro.Fields,
+		Values:    make([]*MapperValue, len(ro.Values)),
+		TagSetEnd: ro.TagSetEnd,
+	}
+	for i, v := range ro.Values {
+		mv := &MapperValue{Time: v.Time, Tags: v.Tags}
+		mo.Values[i] = mv
+		if len(v.Value) == 0 {
+			continue
+		}
+
+		if unmarshallers != nil && v.Value[0] == '[' {
+			if err := json.Unmarshal(v.Value, &mv.raw); err != nil {
+				return nil, err
+			}
+			mv.Value = make([]interface{}, len(mv.raw))
+			mv.unmarshallers = unmarshallers
+			continue
+		}
+		if err := json.Unmarshal(v.Value, &mv.Value); err != nil {
+			return nil, err
+		}
+	}
+	return mo, nil
+}
+
+// remoteUnmarshallers returns the unmarshallers of the outputs of the map
+// functions of the statement. Returns nil if the statement doesn't aggregate.
+func (lm *LocalMapper) remoteUnmarshallers() ([]influxql.UnmarshalFunc, error) {
+	s, ok := lm.stmt.(*influxql.SelectStatement)
+	if !ok || (s.IsRawQuery && !s.HasDistinct()) || s.IsSimpleDerivative() {
+		return nil, nil
+	} else if lm.unmarshallers != nil {
+		return lm.unmarshallers, nil
+	}
+
+	calls := s.FunctionCalls()
+	unmarshallers := make([]influxql.UnmarshalFunc, len(calls))
+	for i, c := range calls {
+		fn, err := influxql.InitializeUnmarshaller(c)
+		if err != nil {
+			return nil, err
+		}
+		unmarshallers[i] = fn
+	}
+	lm.unmarshallers = unmarshallers
+	return unmarshallers, nil
+}
+
 // nextChunkRaw returns the next chunk of data. Data comes in the same order as the
 // tags return by TagSets. A chunk never contains data for more than 1 tagset.
 // If there is no more data for any tagset, nil will be returned.
@@ -305,12 +449,18 @@ func (lm *LocalMapper) nextChunkRaw() (interface{}, error) {
 		}
 		cursor := lm.cursors[lm.currCursorIndex]
 
-		k, v, t := cursor.Next(lm.queryTMin, lm.queryTMax, lm.selectFields, lm.whereFields)
-		if v == nil {
+		// Use the value read ahead of the last chunk, if there is one.
+		value := lm.nextValue
+		lm.nextValue = nil
+		if value == nil {
+			value = lm.nextRawValue(cursor)
+		}
+		if value == nil {
 			// Tagset cursor is empty, move to next one.
 			lm.currCursorIndex++
 			if output != nil {
 				// There is data, so return it and continue when next called.
+				output.TagSetEnd = true
 				return output, nil
 			} else {
 				// Just go straight to the next cursor.
@@ -326,14 +476,28 @@ func (lm *LocalMapper) nextChunkRaw() (interface{}, error) {
 				cursorKey: cursor.key(),
 			}
 		}
-		value := &MapperValue{Time: k, Value: v, Tags: t}
 		output.Values = append(output.Values, value)
 		if len(output.Values) == lm.chunkSize {
+			// Read ahead so the last chunk of the tagset can be marked.
+			if lm.nextValue = lm.nextRawValue(cursor); lm.nextValue == nil {
+				lm.currCursorIndex++
+				output.TagSetEnd = true
+			}
 			return output, nil
 		}
 	}
 }
 
+// nextRawValue returns the next value of a tagset cursor, or nil if the
+// cursor is empty.
+func (lm *LocalMapper) nextRawValue(cursor *tagSetCursor) *MapperValue {
+	k, v, t := cursor.Next(lm.queryTMin, lm.queryTMax, lm.selectFields, lm.whereFields)
+	if v == nil {
+		return nil
+	}
+	return &MapperValue{Time: k, Value: v, Tags: t}
+}
+
 // nextChunkAgg returns the next chunk of data, which is the next interval of data
 // for the current tagset. Tagsets are always processed in the same order as that
 // returned by AvailTagsSets(). When there is no more data for any tagset nil
@@ -398,9 +562,8 @@ func (lm *LocalMapper) nextChunkAgg() (interface{}, error) {
 				heap.Push(tsc.pointHeap, p)
 			}
 			// Wrap the tagset cursor so it implements the mapping functions interface.
-			f := func() (time int64, value interface{}) {
-				k, v, _ := tsc.Next(qmin, tmax, []string{lm.fieldNames[i]}, lm.whereFields)
-				return k, v
+			f := func() (time int64, value interface{}, tags map[string]string) {
+				return tsc.Next(qmin, tmax, []string{lm.fieldNames[i]}, lm.whereFields)
 			}
 
 			tagSetCursor := &aggTagSetCursor{
@@ -412,6 +575,13 @@ func (lm *LocalMapper) nextChunkAgg() (interface{}, error) {
 			values := output.Values[0].Value.([]interface{})
 			output.Values[0].Value = append(values, lm.mapFuncs[i](tagSetCursor))
 		}
+
+		// Mark the last interval of the tagset and move to the next tagset.
+		if start, _ := lm.interval(lm.currInterval); start < 0 {
+			output.TagSetEnd = true
+			lm.currInterval = 0
+			lm.currCursorIndex++
+		}
 		return output, nil
 	}
 }
@@ -419,18 +589,23 @@ func (lm *LocalMapper) nextChunkAgg() (interface{}, error) {
 // nextInterval returns the next interval for which to return data. If start is less than 0
 // there are no more intervals.
 func (lm *LocalMapper) nextInterval() (start, end int64) {
-	t := lm.queryTMinWindow + int64(lm.currInterval+lm.selectStmt.Offset)*lm.intervalSize
+	start, end = lm.interval(lm.currInterval)
 
 	// Onto next interval.
 	lm.currInterval++
-	if t > lm.queryTMax || lm.currInterval > lm.numIntervals {
-		start, end = -1, 1
-	} else {
-		start, end = t, t+lm.intervalSize
-	}
 	return
 }
 
+// interval returns the interval at an index. If start is less than 0 there is no such
+// interval.
+func (lm *LocalMapper) interval(i int) (start, end int64) {
+	t := lm.queryTMinWindow + int64(i+lm.selectStmt.Offset)*lm.intervalSize
+	if t > lm.queryTMax || i+1 > lm.numIntervals {
+		return -1, 1
+	}
+	return t, t + lm.intervalSize
+}
+
 // initializeMapFunctions initialize the mapping functions for the mapper. This only applies
 // to aggregate queries.
 func (lm *LocalMapper) initializeMapFunctions() error {
@@ -515,16 +690,21 @@ func (lm *LocalMapper) expandWildcards(stmt *influxql.SelectStatement) (*influxq
 			}
 			// Get the fields for this measurement.
 			for _, name := range mm.FieldNames() {
-				if _, ok := fieldSet[name]; ok {
+				if _, ok := fieldSet[name]; ok || name == ExpiresField {
 					continue
 				}
 				fieldSet[name] = struct{}{}
 				fields = append(fields, &influxql.Field{Expr: &influxql.VarRef{Val: name}})
 			}
 
+			tagKeys, err := mm.TagKeys()
+			if err != nil {
+				return nil, err
+			}
+
 			// Add tags to fields if a field wildcard was provided and a dimension wildcard was not.
 			if hasFieldWildcard && !hasDimensionWildcard {
-				for _, t := range mm.TagKeys() {
+				for _, t := range tagKeys {
 					if _, ok := fieldSet[t]; ok {
 						continue
 					}
@@ -535,7 +715,7 @@ func (lm *LocalMapper) expandWildcards(stmt *influxql.SelectStatement) (*influxq
 
 			// Get the dimensions for this measurement.
 			if hasDimensionWildcard {
-				for _, t := range mm.TagKeys() {
+				for _, t := range tagKeys {
 					if _, ok := dimensionSet[t]; ok {
 						continue
 					}
@@ -621,21 +801,33 @@ func (lm *LocalMapper) Close() {
 	}
 	if lm != nil && lm.tx != nil {
 		_ = lm.tx.Rollback()
+		lm.tx = nil
+		lm.shard.release()
 	}
 }
 
 // aggTagSetCursor wraps a standard tagSetCursor, such that the values it emits are aggregated
 // by intervals.
 type aggTagSetCursor struct {
-	nextFunc func() (time int64, value interface{})
+	nextFunc func() (time int64, value interface{}, tags map[string]string)
+	tags     map[string]string
 }
 
 // Next returns the next value for the aggTagSetCursor. It implements the interface expected
-// by the mapping functions.
+// by the mapping functions. Nulls are skipped so aggregates only ever see values
+// that exist, whichever shard serves them.
 func (a *aggTagSetCursor) Next() (time int64, value interface{}) {
-	return a.nextFunc()
+	for {
+		time, value, a.tags = a.nextFunc()
+		if time == -1 || value != nil {
+			return time, value
+		}
+	}
 }
 
+// Tags returns the tags of the series of the last value returned by Next.
+func (a *aggTagSetCursor) Tags() map[string]string { return a.tags }
+
 type pointHeapItem struct {
 	timestamp int64
 	value     []byte
@@ -692,6 +884,10 @@ type tagSetCursor struct {
 	// Memomize the cursor's tagset-based key. Profiling shows that calculating this
 	// is significant CPU cost, and it only needs to be done once.
 	memokey string
+
+	// Points with an ExpiresField before now are skipped if expires is set.
+	expires bool
+	now     int64
 }
 
 // tagSetCursors represents a sortable slice of tagSetCursors.
@@ -720,6 +916,12 @@ func newTagSetCursor(m string, t map[string]string, c []*seriesCursor, d *FieldC
 		pointHeap:   newPointHeap(),
 	}
 
+	// Only measurements written with a ttl need their points checked.
+	if _, err := d.FieldIDByName(ExpiresField); err == nil {
+		tsc.expires = true
+		tsc.now = time.Now().UnixNano()
+	}
+
 	return tsc
 }
 
@@ -758,6 +960,11 @@ func (tsc *tagSetCursor) Next(tmin, tmax int64, selectFields, whereFields []stri
 			heap.Push(tsc.pointHeap, nextPoint)
 		}
 
+		// Skip points past their ttl.
+		if tsc.expires && tsc.expired(p.value) {
+			continue
+		}
+
 		// Decode the raw point.
 		value := tsc.decodeRawPoint(p, selectFields, whereFields)
 
@@ -770,17 +977,44 @@ func (tsc *tagSetCursor) Next(tmin, tmax int64, selectFields, whereFields []stri
 	}
 }
 
+// expired returns true if the raw point data has an expiry time that has passed.
+func (tsc *tagSetCursor) expired(value []byte) bool {
+	v, err := tsc.decoder.DecodeByName(ExpiresField, value)
+	if err != nil {
+		return false
+	}
+	t, ok := v.(int64)
+	return ok && t <= tsc.now
+}
+
 // decodeRawPoint decodes raw point data into field names & values and does WHERE filtering.
+// Only the fields referenced by the SELECT and WHERE clauses are decoded.
 func (tsc *tagSetCursor) decodeRawPoint(p *pointHeapItem, selectFields, whereFields []string) interface{} {
 	if len(selectFields) > 1 {
-		if fieldsWithNames, err := tsc.decoder.DecodeFieldsWithNames(p.value); err == nil {
-			// if there's a where clause, make sure we don't need to filter this value
-			if p.cursor.filter != nil && !matchesWhere(p.cursor.filter, fieldsWithNames) {
+		// Points with none of the selected fields don't produce a value, the
+		// same as when a single field is selected. Missing fields of other
+		// points are nulls.
+		fieldsWithNames, err := tsc.decoder.DecodeFieldsByNames(selectFields, p.value)
+		if err != nil || len(fieldsWithNames) == 0 {
+			return nil
+		}
+
+		// if there's a where clause, make sure we don't need to filter this value
+		if p.cursor.filter != nil {
+			for _, name := range whereFields {
+				if _, ok := fieldsWithNames[name]; ok {
+					continue
+				}
+				if v, err := tsc.decoder.DecodeByName(name, p.value); err == nil {
+					fieldsWithNames[name] = v
+				}
+			}
+			if !matchesWhere(p.cursor.filter, fieldsWithNames) {
 				return nil
 			}
-
-			return fieldsWithNames
 		}
+
+		return fieldsWithNames
 	}
 
 	// With only 1 field SELECTed, decoding all fields may be avoidable, which is faster.
@@ -797,8 +1031,8 @@ func (tsc *tagSetCursor) decodeRawPoint(p *pointHeapItem, selectFields, whereFie
 			if !matchesWhere(p.cursor.filter, map[string]interface{}{selectFields[0]: value}) {
 				value = nil
 			}
-		} else { // Decode everything
-			fieldsWithNames, err := tsc.decoder.DecodeFieldsWithNames(p.value)
+		} else { // Decode the fields in the WHERE clause
+			fieldsWithNames, err := tsc.decoder.DecodeFieldsByNames(whereFields, p.value)
 			if err != nil || !matchesWhere(p.cursor.filter, fieldsWithNames) {
 				value = nil
 			}
@@ -871,13 +1105,17 @@ func createTagSetsAndFields(m *Measurement, stmt *influxql.SelectStatement) (*ta
 			sfs.add(n)
 			continue
 		}
-		if m.HasTagKey(n) {
+		if ok, err := m.HasTagKey(n); err != nil {
+			return nil, err
+		} else if ok {
 			sts.add(n)
 		}
 	}
 
 	for _, n := range stmt.NamesInDimension() {
-		if m.HasTagKey(n) {
+		if ok, err := m.HasTagKey(n); err != nil {
+			return nil, err
+		} else if ok {
 			tagKeys = append(tagKeys, n)
 		}
 	}
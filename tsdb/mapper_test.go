@@ -24,20 +24,20 @@ func TestShardMapper_RawMapperTagSetsFields(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	pt1 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"idle": 60},
 		pt1time,
 	)
 	pt2time := time.Unix(2, 0).UTC()
 	pt2 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"load": 60},
 		pt2time,
 	)
 	err := shard.WritePoints([]tsdb.Point{pt1, pt2})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -114,20 +114,20 @@ func TestShardMapper_WriteAndSingleMapperRawQuerySingleValue(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	pt1 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"load": 42},
 		pt1time,
 	)
 	pt2time := time.Unix(2, 0).UTC()
 	pt2 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"load": 60},
 		pt2time,
 	)
 	err := shard.WritePoints([]tsdb.Point{pt1, pt2})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -137,7 +137,7 @@ func TestShardMapper_WriteAndSingleMapperRawQuerySingleValue(t *testing.T) {
 	}{
 		{
 			stmt:     `SELECT load FROM cpu`,
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`, `null`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`, `null`},
 		},
 		{
 			stmt:      `SELECT load FROM cpu # chunkSize 1`,
@@ -147,31 +147,31 @@ func TestShardMapper_WriteAndSingleMapperRawQuerySingleValue(t *testing.T) {
 		{
 			stmt:      `SELECT load FROM cpu # chunkSize 2`,
 			chunkSize: 2,
-			expected:  []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected:  []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:      `SELECT load FROM cpu # chunkSize 3`,
 			chunkSize: 3,
-			expected:  []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected:  []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt: `SELECT load FROM cpu GROUP BY host`,
 			expected: []string{
-				`{"name":"cpu","tags":{"host":"serverA"},"fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`,
-				`{"name":"cpu","tags":{"host":"serverB"},"fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`,
+				`{"name":"cpu","tags":{"host":"serverA"},"fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}],"tagSetEnd":true}`,
+				`{"name":"cpu","tags":{"host":"serverB"},"fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`,
 			},
 		},
 		{
 			stmt:     `SELECT load FROM cpu GROUP BY region`,
-			expected: []string{`{"name":"cpu","tags":{"region":"us-east"},"fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","tags":{"region":"us-east"},"fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT load FROM cpu WHERE host='serverA'`,
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT load FROM cpu WHERE host='serverB'`,
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT load FROM cpu WHERE host='serverC'`,
@@ -179,19 +179,19 @@ func TestShardMapper_WriteAndSingleMapperRawQuerySingleValue(t *testing.T) {
 		},
 		{
 			stmt:     `SELECT load FROM cpu WHERE load = 60`,
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT load FROM cpu WHERE load != 60`,
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     fmt.Sprintf(`SELECT load FROM cpu WHERE time = '%s'`, pt1time.Format(influxql.DateTimeFormat)),
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     fmt.Sprintf(`SELECT load FROM cpu WHERE time > '%s'`, pt1time.Format(influxql.DateTimeFormat)),
-			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     fmt.Sprintf(`SELECT load FROM cpu WHERE time > '%s'`, pt2time.Format(influxql.DateTimeFormat)),
@@ -221,20 +221,20 @@ func TestShardMapper_WriteAndSingleMapperRawQueryMultiValue(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	pt1 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"foo": 42, "bar": 43},
 		pt1time,
 	)
 	pt2time := time.Unix(2, 0).UTC()
 	pt2 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"foo": 60, "bar": 61},
 		pt2time,
 	)
 	err := shard.WritePoints([]tsdb.Point{pt1, pt2})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -244,11 +244,11 @@ func TestShardMapper_WriteAndSingleMapperRawQueryMultiValue(t *testing.T) {
 	}{
 		{
 			stmt:     `SELECT foo FROM cpu`,
-			expected: []string{`{"name":"cpu","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT foo,bar FROM cpu`,
-			expected: []string{`{"name":"cpu","fields":["bar","foo"],"values":[{"time":1000000000,"value":{"bar":43,"foo":42},"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":{"bar":61,"foo":60},"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu","fields":["bar","foo"],"values":[{"time":1000000000,"value":{"bar":43,"foo":42},"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":{"bar":61,"foo":60},"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 	}
 
@@ -266,6 +266,54 @@ func TestShardMapper_WriteAndSingleMapperRawQueryMultiValue(t *testing.T) {
 	}
 }
 
+// Ensure points missing some of the selected fields return nulls and points
+// missing all of them aren't returned.
+func TestShardMapper_WriteAndSingleMapperRawQueryMissingFields(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+
+	err := shard.WritePoints([]tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"foo": 42}, time.Unix(1, 0).UTC()),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"bar": 43}, time.Unix(2, 0).UTC()),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"baz": 44}, time.Unix(3, 0).UTC()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT foo FROM cpu`,
+			expected: `{"name":"cpu","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA"}}],"tagSetEnd":true}`,
+		},
+		{
+			stmt:     `SELECT foo,bar FROM cpu`,
+			expected: `{"name":"cpu","fields":["bar","foo"],"values":[{"time":1000000000,"value":{"foo":42},"tags":{"host":"serverA"}},{"time":2000000000,"value":{"bar":43},"tags":{"host":"serverA"}}],"tagSetEnd":true}`,
+		},
+		{
+			stmt:     `SELECT count(foo) FROM cpu`,
+			expected: `{"name":"cpu","fields":["foo"],"values":[{"value":[1]}],"tagSetEnd":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		var got string
+		if stmt.IsRawQuery {
+			got = nextRawChunkAsJson(t, openRawMapperOrFail(t, shard, stmt, 0))
+		} else {
+			got = aggIntervalAsJson(t, openLocalMapperOrFail(t, shard, stmt))
+		}
+		if got != tt.expected {
+			t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, tt.expected)
+		}
+	}
+}
+
 func TestShardMapper_WriteAndSingleMapperRawQueryMultiSource(t *testing.T) {
 	tmpDir, _ := ioutil.TempDir("", "shard_test")
 	defer os.RemoveAll(tmpDir)
@@ -274,20 +322,20 @@ func TestShardMapper_WriteAndSingleMapperRawQueryMultiSource(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	pt1 := tsdb.NewPoint(
 		"cpu0",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"foo": 42},
 		pt1time,
 	)
 	pt2time := time.Unix(2, 0).UTC()
 	pt2 := tsdb.NewPoint(
 		"cpu1",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"bar": 60},
 		pt2time,
 	)
 	err := shard.WritePoints([]tsdb.Point{pt1, pt2})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -297,15 +345,15 @@ func TestShardMapper_WriteAndSingleMapperRawQueryMultiSource(t *testing.T) {
 	}{
 		{
 			stmt:     `SELECT foo FROM cpu0,cpu1`,
-			expected: []string{`{"name":"cpu0","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu0","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT foo FROM cpu0,cpu1 WHERE foo=42`,
-			expected: []string{`{"name":"cpu0","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu0","fields":["foo"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT bar FROM cpu0,cpu1`,
-			expected: []string{`{"name":"cpu1","fields":["bar"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`},
+			expected: []string{`{"name":"cpu1","fields":["bar"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}],"tagSetEnd":true}`},
 		},
 		{
 			stmt:     `SELECT bar FROM cpu0,cpu1 WHERE foo=42`,
@@ -331,6 +379,33 @@ func TestShardMapper_WriteAndSingleMapperRawQueryMultiSource(t *testing.T) {
 	}
 }
 
+// Ensure points past their ttl are skipped and the expiry isn't a wildcard field.
+func TestShardMapper_RawQueryTTL(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+
+	// The first point expired long ago, the second expires in an hour.
+	pt1 := tsdb.NewPoint("cpu", nil, map[string]interface{}{"load": 42}, time.Unix(1, 0).UTC())
+	tsdb.SetTTL([]tsdb.Point{pt1}, time.Second)
+	pt2 := tsdb.NewPoint("cpu", nil, map[string]interface{}{"load": 60}, time.Now().UTC())
+	tsdb.SetTTL([]tsdb.Point{pt2}, time.Hour)
+	pt3 := tsdb.NewPoint("cpu", nil, map[string]interface{}{"load": 70}, time.Unix(3, 0).UTC())
+	if err := shard.WritePoints([]tsdb.Point{pt1, pt2, pt3}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range []string{`SELECT load FROM cpu`, `SELECT * FROM cpu`} {
+		mapper := openRawMapperOrFail(t, shard, mustParseSelectStatement(stmt), 0)
+		got := nextRawChunkAsJson(t, mapper)
+		exp := fmt.Sprintf(`{"name":"cpu","fields":["load"],"values":[{"time":3000000000,"value":70},{"time":%d,"value":60}],"tagSetEnd":true}`, pt2.UnixNano())
+		if got != exp {
+			t.Errorf("%s: unexpected chunk:\n got %s\nexp %s", stmt, got, exp)
+		}
+		mapper.Close()
+	}
+}
+
 func TestShardMapper_WriteAndSingleMapperAggregateQuery(t *testing.T) {
 	tmpDir, _ := ioutil.TempDir("", "shard_test")
 	defer os.RemoveAll(tmpDir)
@@ -339,20 +414,20 @@ func TestShardMapper_WriteAndSingleMapperAggregateQuery(t *testing.T) {
 	pt1time := time.Unix(10, 0).UTC()
 	pt1 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"value": 1},
 		pt1time,
 	)
 	pt2time := time.Unix(20, 0).UTC()
 	pt2 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"value": 60},
 		pt2time,
 	)
 	err := shard.WritePoints([]tsdb.Point{pt1, pt2})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -361,54 +436,54 @@ func TestShardMapper_WriteAndSingleMapperAggregateQuery(t *testing.T) {
 	}{
 		{
 			stmt:     `SELECT sum(value) FROM cpu`,
-			expected: []string{`{"name":"cpu","fields":["value"],"values":[{"value":[61]}]}`, `null`},
+			expected: []string{`{"name":"cpu","fields":["value"],"values":[{"value":[61]}],"tagSetEnd":true}`, `null`},
 		},
 		{
 			stmt:     `SELECT sum(value),mean(value) FROM cpu`,
-			expected: []string{`{"name":"cpu","fields":["value"],"values":[{"value":[61,{"Count":2,"Mean":30.5,"ResultType":1}]}]}`, `null`},
+			expected: []string{`{"name":"cpu","fields":["value"],"values":[{"value":[61,{"Count":2,"Mean":30.5,"ResultType":1}]}],"tagSetEnd":true}`, `null`},
 		},
 		{
 			stmt: `SELECT sum(value) FROM cpu GROUP BY host`,
 			expected: []string{
-				`{"name":"cpu","tags":{"host":"serverA"},"fields":["value"],"values":[{"value":[1]}]}`,
-				`{"name":"cpu","tags":{"host":"serverB"},"fields":["value"],"values":[{"value":[60]}]}`,
+				`{"name":"cpu","tags":{"host":"serverA"},"fields":["value"],"values":[{"value":[1]}],"tagSetEnd":true}`,
+				`{"name":"cpu","tags":{"host":"serverB"},"fields":["value"],"values":[{"value":[60]}],"tagSetEnd":true}`,
 				`null`},
 		},
 		{
 			stmt: `SELECT sum(value) FROM cpu GROUP BY region`,
 			expected: []string{
-				`{"name":"cpu","tags":{"region":"us-east"},"fields":["value"],"values":[{"value":[61]}]}`,
+				`{"name":"cpu","tags":{"region":"us-east"},"fields":["value"],"values":[{"value":[61]}],"tagSetEnd":true}`,
 				`null`},
 		},
 		{
 			stmt: `SELECT sum(value) FROM cpu GROUP BY region,host`,
 			expected: []string{
-				`{"name":"cpu","tags":{"host":"serverA","region":"us-east"},"fields":["value"],"values":[{"value":[1]}]}`,
-				`{"name":"cpu","tags":{"host":"serverB","region":"us-east"},"fields":["value"],"values":[{"value":[60]}]}`,
+				`{"name":"cpu","tags":{"host":"serverA","region":"us-east"},"fields":["value"],"values":[{"value":[1]}],"tagSetEnd":true}`,
+				`{"name":"cpu","tags":{"host":"serverB","region":"us-east"},"fields":["value"],"values":[{"value":[60]}],"tagSetEnd":true}`,
 				`null`},
 		},
 		{
 			stmt: `SELECT sum(value) FROM cpu WHERE host='serverB'`,
 			expected: []string{
-				`{"name":"cpu","fields":["value"],"values":[{"value":[60]}]}`,
+				`{"name":"cpu","fields":["value"],"values":[{"value":[60]}],"tagSetEnd":true}`,
 				`null`},
 		},
 		{
 			stmt: fmt.Sprintf(`SELECT sum(value) FROM cpu WHERE time = '%s'`, pt1time.Format(influxql.DateTimeFormat)),
 			expected: []string{
-				`{"name":"cpu","fields":["value"],"values":[{"time":10000000000,"value":[1]}]}`,
+				`{"name":"cpu","fields":["value"],"values":[{"time":10000000000,"value":[1]}],"tagSetEnd":true}`,
 				`null`},
 		},
 		{
 			stmt: fmt.Sprintf(`SELECT sum(value) FROM cpu WHERE time > '%s'`, pt1time.Format(influxql.DateTimeFormat)),
 			expected: []string{
-				`{"name":"cpu","fields":["value"],"values":[{"time":10000000001,"value":[60]}]}`,
+				`{"name":"cpu","fields":["value"],"values":[{"time":10000000001,"value":[60]}],"tagSetEnd":true}`,
 				`null`},
 		},
 		{
 			stmt: fmt.Sprintf(`SELECT sum(value) FROM cpu WHERE time > '%s'`, pt2time.Format(influxql.DateTimeFormat)),
 			expected: []string{
-				`{"name":"cpu","fields":["value"],"values":[{"time":20000000001,"value":[null]}]}`,
+				`{"name":"cpu","fields":["value"],"values":[{"time":20000000001,"value":[null]}],"tagSetEnd":true}`,
 				`null`},
 		},
 	}
@@ -435,20 +510,20 @@ func TestShardMapper_LocalMapperTagSetsFields(t *testing.T) {
 	pt1time := time.Unix(1, 0).UTC()
 	pt1 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverA", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		map[string]interface{}{"value": 42},
 		pt1time,
 	)
 	pt2time := time.Unix(2, 0).UTC()
 	pt2 := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "serverB", "region": "us-east"},
+		tsdb.NewTags(map[string]string{"host": "serverB", "region": "us-east"}),
 		map[string]interface{}{"value": 60},
 		pt2time,
 	)
 	err := shard.WritePoints([]tsdb.Point{pt1, pt2})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	var tests = []struct {
@@ -504,6 +579,60 @@ func TestShardMapper_LocalMapperTagSetsFields(t *testing.T) {
 	}
 }
 
+// Ensure the aggregate outputs of remote chunks are only decoded when read.
+func TestLocalMapper_RemoteAggregateColumns(t *testing.T) {
+	stmt := mustParseSelectStatement(`SELECT mean(value), max(value) FROM cpu`)
+	mapper := tsdb.NewLocalMapper(nil, stmt, 0)
+	mapper.SetRemote(&remoteChunkMapper{chunks: []string{
+		`{"name":"cpu","values":[{"value":[{"Count":2,"Mean":1.5,"ResultType":0},{"Val":"bad"}]}]}`,
+	}})
+
+	chunk, err := mapper.NextChunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mv := chunk.(*tsdb.MapperOutput).Values[0]
+	if n := mv.Len(); n != 2 {
+		t.Fatalf("unexpected column count: %d", n)
+	}
+
+	// The mean is decoded to the type its reduce function merges.
+	if v, err := mv.Column(0); err != nil {
+		t.Fatal(err)
+	} else if b, _ := json.Marshal(v); string(b) != `{"Count":2,"Mean":1.5,"ResultType":0}` {
+		t.Fatalf("unexpected mean: %s", b)
+	}
+
+	// The broken max only fails once it's read.
+	if _, err := mv.Column(1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if chunk, err := mapper.NextChunk(); err != nil || chunk != nil {
+		t.Fatalf("unexpected chunk: %v, %v", chunk, err)
+	}
+}
+
+// remoteChunkMapper is a remote mapper returning a fixed list of encoded chunks.
+type remoteChunkMapper struct {
+	chunks []string
+}
+
+func (m *remoteChunkMapper) Open() error                 { return nil }
+func (m *remoteChunkMapper) SetRemote(tsdb.Mapper) error { return nil }
+func (m *remoteChunkMapper) TagSets() []string           { return nil }
+func (m *remoteChunkMapper) Fields() []string            { return nil }
+func (m *remoteChunkMapper) Close()                      {}
+
+func (m *remoteChunkMapper) NextChunk() (interface{}, error) {
+	if len(m.chunks) == 0 {
+		return nil, nil
+	}
+	chunk := m.chunks[0]
+	m.chunks = m.chunks[1:]
+	return []byte(chunk), nil
+}
+
 func mustCreateShard(dir string) *tsdb.Shard {
 	tmpShard := path.Join(dir, "shard")
 	tmpWal := path.Join(dir, "wal")
@@ -29,6 +29,13 @@ type DatabaseIndex struct {
 	series       map[string]*Series      // map series key to the Series object
 	names        []string                // sorted list of the measurement names
 	lastID       uint64                  // last used series ID. They're in memory only for this shard
+
+	// tag index eviction, see SetMaxIndexMemory
+	evictMu        sync.RWMutex // held for reading while new series are saved
+	maxIndexMemory int64        // bytes of tag index kept in memory, zero is unlimited
+	evictPath      string       // directory holding evicted tag indexes
+	indexSize      int64        // estimated bytes used by loaded tag indexes, updated atomically
+	lastEvictID    uint64       // last ID used to name an evicted tag index file
 }
 
 func NewDatabaseIndex() *DatabaseIndex {
@@ -113,14 +120,19 @@ func (s *DatabaseIndex) CreateMeasurementIndexIfNotExists(name string) *Measurem
 }
 
 // TagsForSeries returns the tag map for the passed in series
-func (s *DatabaseIndex) TagsForSeries(key string) map[string]string {
+func (s *DatabaseIndex) TagsForSeries(key string) (map[string]string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	ss := s.series[key]
 	if ss == nil {
-		return nil
+		return nil, nil
+	}
+
+	if err := ss.measurement.rlockLoaded(); err != nil {
+		return nil, err
 	}
-	return ss.Tags
+	defer ss.measurement.mu.RUnlock()
+	return ss.Tags, nil
 }
 
 // measurementsByExpr takes and expression containing only tags and returns
@@ -154,7 +166,24 @@ func (db *DatabaseIndex) measurementsByExpr(expr influxql.Expr) (Measurements, e
 				tf.Value = s.Val
 			}
 
-			return db.measurementsByTagFilters([]*TagFilter{tf}), nil
+			return db.measurementsByTagFilters([]*TagFilter{tf})
+		case influxql.IN:
+			tag, ok := e.LHS.(*influxql.VarRef)
+			if !ok {
+				return nil, fmt.Errorf("left side of '%s' must be a tag name", e.Op.String())
+			}
+
+			list, ok := e.RHS.(*influxql.ListLiteral)
+			if !ok {
+				return nil, fmt.Errorf("right side of '%s' must be a list of tag values", e.Op.String())
+			}
+
+			// Measurements match if any of the values match.
+			filters := make([]*TagFilter, len(list.Vals))
+			for i, v := range list.Vals {
+				filters[i] = &TagFilter{Op: influxql.EQ, Key: tag.Val, Value: v}
+			}
+			return db.measurementsByTagFilters(filters)
 		case influxql.OR, influxql.AND:
 			lhsIDs, err := db.measurementsByExpr(e.LHS)
 			if err != nil {
@@ -181,14 +210,14 @@ func (db *DatabaseIndex) measurementsByExpr(expr influxql.Expr) (Measurements, e
 }
 
 // measurementsByTagFilters returns the measurements matching the filters on tag values.
-func (db *DatabaseIndex) measurementsByTagFilters(filters []*TagFilter) Measurements {
+func (db *DatabaseIndex) measurementsByTagFilters(filters []*TagFilter) (Measurements, error) {
 	// If no filters, then return all measurements.
 	if len(filters) == 0 {
 		measurements := make(Measurements, 0, len(db.measurements))
 		for _, m := range db.measurements {
 			measurements = append(measurements, m)
 		}
-		return measurements
+		return measurements, nil
 	}
 
 	// Build a list of measurements matching the filters.
@@ -197,6 +226,10 @@ func (db *DatabaseIndex) measurementsByTagFilters(filters []*TagFilter) Measurem
 
 	// Iterate through all measurements in the database.
 	for _, m := range db.measurements {
+		if err := m.rlockLoaded(); err != nil {
+			return nil, err
+		}
+
 		// Iterate filters seeing if the measurement has a matching tag.
 		for _, f := range filters {
 			tagVals, ok := m.seriesByTagKeyValue[f.Key]
@@ -236,9 +269,11 @@ func (db *DatabaseIndex) measurementsByTagFilters(filters []*TagFilter) Measurem
 				break
 			}
 		}
+
+		m.mu.RUnlock()
 	}
 
-	return measurements
+	return measurements, nil
 }
 
 // measurementsByRegex returns the measurements that match the regex.
@@ -275,6 +310,7 @@ func (db *DatabaseIndex) DropMeasurement(name string) {
 	for _, s := range m.seriesByID {
 		delete(db.series, s.Key)
 	}
+	m.dropIndex()
 
 	var names []string
 	for _, n := range db.names {
@@ -313,6 +349,12 @@ type Measurement struct {
 	measurement         *Measurement
 	seriesByTagKeyValue map[string]map[string]SeriesIDs // map from tag key to value to sorted set of series ids
 	seriesIDs           SeriesIDs                       // sorted list of series IDs in this measurement
+
+	// eviction state of the tag index
+	evicted   bool   // tags and tag index are on disk at evictPath
+	evictPath string // file holding the evicted tag index
+	indexSize int64  // estimated bytes used by the tag index while loaded
+	lastUsed  int64  // time of the last access in nanoseconds, updated atomically
 }
 
 // NewMeasurement allocates and initializes a new Measurement.
@@ -325,6 +367,7 @@ func NewMeasurement(name string, idx *DatabaseIndex) *Measurement {
 		seriesByID:          make(map[uint64]*Series),
 		seriesByTagKeyValue: make(map[string]map[string]SeriesIDs),
 		seriesIDs:           make(SeriesIDs, 0),
+		lastUsed:            time.Now().UnixNano(),
 	}
 }
 
@@ -368,11 +411,13 @@ func (m *Measurement) ValidateGroupBy(stmt *influxql.SelectStatement) error {
 }
 
 // HasTagKey returns true if at least one series in this measurement has written a value for the passed in tag key
-func (m *Measurement) HasTagKey(k string) bool {
-	m.mu.RLock()
+func (m *Measurement) HasTagKey(k string) (bool, error) {
+	if err := m.rlockLoaded(); err != nil {
+		return false, err
+	}
 	defer m.mu.RUnlock()
 	_, hasTag := m.seriesByTagKeyValue[k]
-	return hasTag
+	return hasTag, nil
 }
 
 // HasSeries returns true if there is at least 1 series under this measurement
@@ -384,6 +429,7 @@ func (m *Measurement) HasSeries() bool {
 
 // AddSeries will add a series to the measurementIndex. Returns false if already present
 func (m *Measurement) AddSeries(s *Series) bool {
+	m.touch()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -399,6 +445,18 @@ func (m *Measurement) AddSeries(s *Series) bool {
 		sort.Sort(m.seriesIDs)
 	}
 
+	// An evicted index keeps the series' tags in memory until it's loaded.
+	if !m.evicted {
+		m.indexSeriesTags(s)
+	}
+
+	return true
+}
+
+// indexSeriesTags adds the series to the tag index. The caller must hold the write lock.
+func (m *Measurement) indexSeriesTags(s *Series) {
+	m.addIndexSize(seriesIndexSize(s))
+
 	// add this series id to the tag index on the measurement
 	for k, v := range s.Tags {
 		valueMap := m.seriesByTagKeyValue[k]
@@ -416,19 +474,22 @@ func (m *Measurement) AddSeries(s *Series) bool {
 		}
 		valueMap[v] = ids
 	}
-
-	return true
 }
 
 // DropSeries will remove a series from the measurementIndex.
 func (m *Measurement) DropSeries(seriesID uint64) {
+	m.touch()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.seriesByID[seriesID]; !ok {
+	s, ok := m.seriesByID[seriesID]
+	if !ok {
 		return
 	}
 	delete(m.seriesByID, seriesID)
+	if !m.evicted {
+		m.addIndexSize(-seriesIndexSize(s))
+	}
 
 	var ids []uint64
 	for _, id := range m.seriesIDs {
@@ -505,7 +566,9 @@ func (m *Measurement) filters(stmt *influxql.SelectStatement) (map[uint64]influx
 func (m *Measurement) TagSets(stmt *influxql.SelectStatement, dimensions []string) ([]*influxql.TagSet, error) {
 	m.index.mu.RLock()
 	defer m.index.mu.RUnlock()
-	m.mu.RLock()
+	if err := m.rlockLoaded(); err != nil {
+		return nil, err
+	}
 	defer m.mu.RUnlock()
 
 	// get the unique set of series ids and the filters that should be applied to each
@@ -675,6 +738,26 @@ func (m *Measurement) idsForExpr(n *influxql.BinaryExpr) (SeriesIDs, influxql.Ex
 		return ids, &influxql.BooleanLiteral{Val: true}, nil
 	}
 
+	// if we're looking for series with any of a list of tag values
+	if list, ok := value.(*influxql.ListLiteral); ok && n.Op == influxql.IN {
+		// Collect the series of every value and sort them once instead of
+		// doing a union per value.
+		var ids SeriesIDs
+		for _, v := range list.Vals {
+			ids = append(ids, tagVals[v]...)
+		}
+		sort.Sort(ids)
+
+		// Remove series IDs found under more than one value.
+		var uniq SeriesIDs
+		for i, id := range ids {
+			if i == 0 || id != ids[i-1] {
+				uniq = append(uniq, id)
+			}
+		}
+		return uniq, &influxql.BooleanLiteral{Val: true}, nil
+	}
+
 	// if we're looking for series with a tag value that matches a regex
 	if re, ok := value.(*influxql.RegexLiteral); ok {
 		var ids SeriesIDs
@@ -707,7 +790,7 @@ func (m *Measurement) walkWhereForSeriesIds(expr influxql.Expr) (SeriesIDs, map[
 	switch n := expr.(type) {
 	case *influxql.BinaryExpr:
 		switch n.Op {
-		case influxql.EQ, influxql.NEQ, influxql.LT, influxql.LTE, influxql.GT, influxql.GTE, influxql.EQREGEX, influxql.NEQREGEX:
+		case influxql.EQ, influxql.NEQ, influxql.LT, influxql.LTE, influxql.GT, influxql.GTE, influxql.EQREGEX, influxql.NEQREGEX, influxql.IN:
 			// Get the series IDs and filter expression for the tag or field comparison.
 			ids, expr, err := m.idsForExpr(n)
 			if err != nil {
@@ -879,32 +962,38 @@ func (m *Measurement) uniqueTagValues(expr influxql.Expr) map[string][]string {
 	influxql.WalkFunc(expr, func(n influxql.Node) {
 		switch n := n.(type) {
 		case *influxql.BinaryExpr:
-			// Ignore operators that are not equality.
-			if n.Op != influxql.EQ {
+			// Ignore operators that are not equality or list membership.
+			if n.Op != influxql.EQ && n.Op != influxql.IN {
 				return
 			}
 
-			// Extract ref and string literal.
-			var key, value string
+			// Extract ref and string or list literal.
+			var key string
+			var values []string
 			switch lhs := n.LHS.(type) {
 			case *influxql.VarRef:
-				if rhs, ok := n.RHS.(*influxql.StringLiteral); ok {
-					key, value = lhs.Val, rhs.Val
+				switch rhs := n.RHS.(type) {
+				case *influxql.StringLiteral:
+					key, values = lhs.Val, []string{rhs.Val}
+				case *influxql.ListLiteral:
+					key, values = lhs.Val, rhs.Vals
 				}
 			case *influxql.StringLiteral:
 				if rhs, ok := n.RHS.(*influxql.VarRef); ok {
-					key, value = rhs.Val, lhs.Val
+					key, values = rhs.Val, []string{lhs.Val}
 				}
 			}
 			if key == "" {
 				return
 			}
 
-			// Add value to set.
+			// Add values to set.
 			if tags[key] == nil {
 				tags[key] = make(map[string]struct{})
 			}
-			tags[key][value] = struct{}{}
+			for _, v := range values {
+				tags[key][v] = struct{}{}
+			}
 		}
 	})
 
@@ -1204,9 +1293,17 @@ func timeBetweenInclusive(t, min, max time.Time) bool {
 }
 
 // TagKeys returns a list of the measurement's tag names.
-func (m *Measurement) TagKeys() []string {
-	m.mu.RLock()
+func (m *Measurement) TagKeys() ([]string, error) {
+	if err := m.rlockLoaded(); err != nil {
+		return nil, err
+	}
 	defer m.mu.RUnlock()
+	return m.tagKeys(), nil
+}
+
+// tagKeys returns a sorted list of the measurement's tag names. The caller
+// must hold the lock with the tag index loaded.
+func (m *Measurement) tagKeys() []string {
 	keys := make([]string, 0, len(m.seriesByTagKeyValue))
 	for k := range m.seriesByTagKeyValue {
 		keys = append(keys, k)
@@ -1215,6 +1312,23 @@ func (m *Measurement) TagKeys() []string {
 	return keys
 }
 
+// TagKeyCardinality returns the number of values of each of the measurement's
+// tag keys. Returns nil if the tag index is evicted so cold measurements aren't
+// loaded just to be counted.
+func (m *Measurement) TagKeyCardinality() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.evicted {
+		return nil
+	}
+
+	n := make(map[string]int, len(m.seriesByTagKeyValue))
+	for k, v := range m.seriesByTagKeyValue {
+		n[k] = len(v)
+	}
+	return n
+}
+
 // SetFieldName adds the field name to the measurement.
 func (m *Measurement) SetFieldName(name string) {
 	m.mu.Lock()
@@ -0,0 +1,222 @@
+package tsdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// The types of field values in the binary encoding of a point.
+const (
+	binaryFieldFloat  = 1
+	binaryFieldInt    = 2
+	binaryFieldBool   = 3
+	binaryFieldString = 4
+)
+
+// ErrShortPoint is returned when unmarshaling a point from a truncated buffer.
+var ErrShortPoint = errors.New("point too short")
+
+// MarshalBinary encodes the point's key, timestamp and fields in a compact
+// binary format that's decoded by UnmarshalBinary without going through the
+// line protocol. The format is:
+//
+//	uvarint key length, key
+//	varint timestamp in nanoseconds
+//	uvarint number of fields, then for each field sorted by name:
+//		uvarint name length, name, type byte, value
+//
+// Floats are 8 bytes, integers are varints, booleans are 1 byte and strings
+// are a uvarint length followed by the bytes.
+func (p *point) MarshalBinary() ([]byte, error) {
+	fields, err := p.FieldsChecked()
+	if err != nil {
+		return nil, err
+	}
+	key := p.Key()
+
+	b := make([]byte, 0, len(key)+len(p.encodedFields())+2*binary.MaxVarintLen64)
+	b = appendUvarint(b, uint64(len(key)))
+	b = append(b, key...)
+	b = appendVarint(b, p.UnixNano())
+
+	b = appendUvarint(b, uint64(len(fields)))
+	for _, name := range fields.sortedNames() {
+		b = appendUvarint(b, uint64(len(name)))
+		b = append(b, name...)
+
+		switch v := fields[name].(type) {
+		case float64:
+			b = append(b, binaryFieldFloat)
+			b = append(b, u64tob(math.Float64bits(v))...)
+		case int64:
+			b = append(b, binaryFieldInt)
+			b = appendVarint(b, v)
+		case int:
+			b = append(b, binaryFieldInt)
+			b = appendVarint(b, int64(v))
+		case int32:
+			b = append(b, binaryFieldInt)
+			b = appendVarint(b, int64(v))
+		case uint64:
+			b = append(b, binaryFieldInt)
+			b = appendVarint(b, int64(v))
+		case bool:
+			b = append(b, binaryFieldBool)
+			if v {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		case string:
+			b = append(b, binaryFieldString)
+			b = appendUvarint(b, uint64(len(v)))
+			b = append(b, v...)
+		case []byte:
+			b = append(b, binaryFieldString)
+			b = appendUvarint(b, uint64(len(v)))
+			b = append(b, v...)
+		default:
+			return nil, fmt.Errorf("field %q: unsupported type %T", name, v)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a point encoded by MarshalBinary.
+func (p *point) UnmarshalBinary(b []byte) error {
+	r := binaryReader{buf: b}
+	key := r.readBytes()
+	ts := r.varint()
+
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = ErrShortPoint
+	}
+
+	fields := make(Fields, n)
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		name := string(r.readBytes())
+		switch typ := r.readByte(); typ {
+		case binaryFieldFloat:
+			if v := r.next(8); v != nil {
+				fields[name] = math.Float64frombits(btou64(v))
+			}
+		case binaryFieldInt:
+			fields[name] = r.varint()
+		case binaryFieldBool:
+			fields[name] = r.readByte() == 1
+		case binaryFieldString:
+			fields[name] = string(r.readBytes())
+		default:
+			if r.err == nil {
+				r.err = fmt.Errorf("field %q: unknown type %d", name, typ)
+			}
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	*p = point{
+		key:          append([]byte(nil), key...),
+		time:         time.Unix(0, ts),
+		fields:       fields.MarshalBinary(),
+		cachedFields: fields,
+	}
+	return nil
+}
+
+// NewPointFromBytes returns a point decoded from the binary format written
+// by Point.MarshalBinary.
+func NewPointFromBytes(b []byte) (Point, error) {
+	p := &point{}
+	if err := p.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// sortedNames returns the names of the fields in order.
+func (p Fields) sortedNames() []string {
+	a := make([]string, 0, len(p))
+	for k := range p {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// binaryReader reads the binary encoding of a point. The first error is
+// kept and every read after it returns the zero value.
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+func (r *binaryReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	} else if n > len(r.buf) {
+		r.err = ErrShortPoint
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *binaryReader) readByte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = ErrShortPoint
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = ErrShortPoint
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *binaryReader) readBytes() []byte {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = ErrShortPoint
+		return nil
+	}
+	return r.next(int(n))
+}
@@ -0,0 +1,62 @@
+package tsdb_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure a point is the same after a round trip through the binary format.
+func TestPoint_MarshalBinary(t *testing.T) {
+	pts := []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "server a", "region": "us,west"}), tsdb.Fields{
+			"float":  1.5,
+			"int":    int64(-42),
+			"bool":   true,
+			"string": "a \"quoted\"\nstring",
+		}, time.Unix(0, 1000000001)),
+		tsdb.NewPoint("mem", nil, tsdb.Fields{"value": 0.0}, time.Unix(0, -5)),
+	}
+	parsed, err := tsdb.ParsePointsString(`disk,path=/ free=100i,used=2.5 1445000000000000000`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pts = append(pts, parsed...)
+
+	for _, pt := range pts {
+		b, err := pt.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: marshal: %s", pt, err)
+		}
+
+		got, err := tsdb.NewPointFromBytes(b)
+		if err != nil {
+			t.Fatalf("%s: unmarshal: %s", pt, err)
+		}
+		if string(got.Key()) != string(pt.Key()) {
+			t.Fatalf("key mismatch: got %s, exp %s", got.Key(), pt.Key())
+		} else if got.UnixNano() != pt.UnixNano() {
+			t.Fatalf("time mismatch: got %d, exp %d", got.UnixNano(), pt.UnixNano())
+		} else if !reflect.DeepEqual(got.Fields(), pt.Fields()) {
+			t.Fatalf("fields mismatch: got %v, exp %v", got.Fields(), pt.Fields())
+		} else if got.String() != pt.String() {
+			t.Fatalf("string mismatch: got %s, exp %s", got, pt)
+		}
+	}
+}
+
+// Ensure truncated buffers are rejected.
+func TestNewPointFromBytes_Short(t *testing.T) {
+	b, err := tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": "foo"}, time.Unix(0, 1)).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(b); i++ {
+		if _, err := tsdb.NewPointFromBytes(b[:i]); err != tsdb.ErrShortPoint {
+			t.Fatalf("%d bytes: unexpected error: %v", i, err)
+		}
+	}
+}
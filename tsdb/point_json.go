@@ -0,0 +1,420 @@
+package tsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ParsePointsJSONLines returns the points of a batch in the JSON lines format,
+// for producers that can't easily escape the line protocol. Each line is an
+// object such as:
+//
+//	{"measurement":"cpu","tags":{"host":"a"},"fields":{"value":1.5},"time":1441065600}
+//
+// Types are mapped strictly: tag values must be strings, field values must be
+// numbers, which are stored as floats like in the line protocol, strings or
+// booleans, and unknown keys are rejected. The time is an epoch in the precision
+// of the options or an RFC3339 string, and defaults to the default time.
+// Blank lines are skipped and errors are returned as a LineError, or in a
+// PartialParseError with PartialOK.
+func ParsePointsJSONLines(buf []byte, opt ParseOptions) ([]Point, error) {
+	precision, err := ParsePrecision(string(opt.Precision))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		points  []Point
+		partial *PartialParseError
+	)
+	for i, line := range bytes.Split(buf, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		err := opt.Validator.ValidateLine(line)
+		var pt Point
+		if err == nil {
+			pt, err = parseJSONLine(line, opt.DefaultTime, precision)
+		}
+		if err == nil {
+			err = opt.Validator.Validate(pt)
+		}
+		if err != nil {
+			if !opt.PartialOK {
+				return nil, LineError{Line: i + 1, Err: err}
+			}
+			if partial == nil {
+				partial = &PartialParseError{}
+			}
+			partial.Errors = append(partial.Errors, LineError{Line: i + 1, Err: err})
+			continue
+		}
+		points = append(points, pt)
+	}
+
+	if partial != nil {
+		return points, partial
+	}
+	return points, nil
+}
+
+// parseJSONLine returns the point of a line in the JSON lines format.
+func parseJSONLine(line []byte, defaultTime time.Time, precision Precision) (Point, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(line, &obj); err != nil {
+		return nil, err
+	}
+
+	var (
+		name   string
+		tags   map[string]string
+		fields Fields
+		t      time.Time
+		hasT   bool
+		err    error
+	)
+	for key, raw := range obj {
+		switch key {
+		case "measurement":
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return nil, errors.New("measurement must be a string")
+			}
+		case "tags":
+			if err := json.Unmarshal(raw, &tags); err != nil {
+				return nil, errors.New("tag values must be strings")
+			}
+		case "fields":
+			if fields, err = parseJSONFields(raw); err != nil {
+				return nil, err
+			}
+		case "time":
+			if t, err = parseJSONTime(raw, precision); err != nil {
+				return nil, err
+			}
+			hasT = true
+		default:
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	for k, v := range tags {
+		if k == "" {
+			return nil, errors.New("missing tag key")
+		} else if v == "" {
+			return nil, fmt.Errorf("missing tag value: %q", k)
+		}
+	}
+
+	p, err := NewPointChecked(name, NewTags(tags), fields, t)
+	if err != nil {
+		return nil, err
+	}
+	pt := p.(*point)
+	if !hasT {
+		pt.SetTime(defaultTime)
+		pt.SetPrecision(precision)
+	}
+	return pt, nil
+}
+
+// parseJSONFields returns the fields of a JSON object, whose values must be
+// numbers, strings or booleans.
+func parseJSONFields(raw json.RawMessage) (Fields, error) {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, errors.New("fields must be an object")
+	}
+
+	fields := make(Fields, len(m))
+	for k, v := range m {
+		if k == "" {
+			return nil, errors.New("missing field key")
+		}
+
+		switch v := v.(type) {
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid number for field %q: %s", k, v)
+			}
+			fields[k] = f
+		case string, bool:
+			fields[k] = v
+		case nil:
+			return nil, fmt.Errorf("missing value for field %q", k)
+		default:
+			return nil, fmt.Errorf("unsupported value for field %q: %T", k, v)
+		}
+	}
+	return fields, nil
+}
+
+// parseJSONTime returns a time that's either an epoch in precision or an
+// RFC3339 string.
+func parseJSONTime(raw json.RawMessage, precision Precision) (time.Time, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return time.Time{}, err
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	}
+
+	ts, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s", raw)
+	}
+	ns, ok := mulInt64(ts, int64(precision.Duration()))
+	if !ok {
+		return time.Time{}, &TimestampOverflowError{Timestamp: ts, Precision: precision}
+	} else if err := checkTime(ns); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, ns), nil
+}
+
+// JSONBatch is a batch of points written in one of the JSON write formats.
+// The database and retention policy are empty if the body doesn't name them.
+type JSONBatch struct {
+	Database        string
+	RetentionPolicy string
+	Points          []Point
+}
+
+// ParsePointsJSON returns the points of a JSON write body in either the batch
+// format or the series format of 0.8:
+//
+//	{"database":"db","tags":{"host":"a"},"points":[{"measurement":"cpu","fields":{"value":1.5},"time":1441065600}]}
+//	[{"name":"cpu","columns":["time","value"],"points":[[1441065600000,1.5]]}]
+//
+// In the batch format the tags and time of the batch apply to the points that
+// don't set them. Epoch times are in the precision of the point, the batch or
+// the options, seconds by default, and RFC3339 times are rounded to it.
+//
+// The series format, which may also be a single series object, has no tags.
+// The "time" column is an epoch in the precision of the options, milliseconds
+// by default, "sequence_number" is ignored and the other columns are fields.
+// Null values are skipped.
+//
+// Numbers are stored as floats, points without a time get the default time
+// and io.EOF is returned for an empty body.
+func ParsePointsJSON(buf []byte, opt ParseOptions) (*JSONBatch, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return nil, io.EOF
+	}
+	if opt.Precision != "" {
+		if _, err := ParsePrecision(string(opt.Precision)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tell the formats apart by the keys of the top level object.
+	if buf[0] == '[' {
+		var series []jsonSeries
+		if err := decodeJSON(buf, &series); err != nil {
+			return nil, err
+		}
+		return parseJSONSeries(series, opt)
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &keys); err != nil {
+		return nil, err
+	}
+	if _, ok := keys["columns"]; ok {
+		var series jsonSeries
+		if err := decodeJSON(buf, &series); err != nil {
+			return nil, err
+		}
+		return parseJSONSeries([]jsonSeries{series}, opt)
+	}
+
+	var bp jsonBatch
+	if err := json.Unmarshal(buf, &bp); err != nil {
+		return nil, err
+	}
+	return parseJSONBatch(&bp, opt)
+}
+
+// jsonBatch is a batch of points in the JSON batch format.
+type jsonBatch struct {
+	Database        string            `json:"database"`
+	RetentionPolicy string            `json:"retentionPolicy"`
+	Tags            map[string]string `json:"tags"`
+	Time            json.RawMessage   `json:"time"`
+	Precision       string            `json:"precision"`
+	Points          []jsonPoint       `json:"points"`
+}
+
+// jsonPoint is a point of a jsonBatch.
+type jsonPoint struct {
+	Measurement string            `json:"measurement"`
+	Tags        map[string]string `json:"tags"`
+	Fields      json.RawMessage   `json:"fields"`
+	Time        json.RawMessage   `json:"time"`
+	Precision   string            `json:"precision"`
+}
+
+// jsonSeries is a series in the JSON series format of 0.8.
+type jsonSeries struct {
+	Name    string          `json:"name"`
+	Columns []string        `json:"columns"`
+	Points  [][]interface{} `json:"points"`
+}
+
+func parseJSONBatch(bp *jsonBatch, opt ParseOptions) (*JSONBatch, error) {
+	batch := &JSONBatch{Database: bp.Database, RetentionPolicy: bp.RetentionPolicy}
+
+	defaultTime := opt.DefaultTime
+	if len(bp.Time) > 0 {
+		t, err := parseBatchTime(bp.Time, bp.Precision, opt.Precision)
+		if err != nil {
+			return nil, err
+		}
+		defaultTime = t
+	}
+
+	for i, jp := range bp.Points {
+		// The tags of the batch are merged into the point.
+		tags := jp.Tags
+		if len(bp.Tags) > 0 {
+			tags = make(map[string]string, len(jp.Tags)+len(bp.Tags))
+			for k, v := range bp.Tags {
+				tags[k] = v
+			}
+			for k, v := range jp.Tags {
+				if v != "" {
+					tags[k] = v
+				}
+			}
+		}
+
+		var fields Fields
+		if len(jp.Fields) > 0 {
+			var err error
+			if fields, err = parseJSONFields(jp.Fields); err != nil {
+				return nil, fmt.Errorf("points[%d]: %s", i, err)
+			}
+		}
+
+		t := defaultTime
+		if len(jp.Time) > 0 {
+			precision := jp.Precision
+			if precision == "" {
+				precision = bp.Precision
+			}
+			var err error
+			if t, err = parseBatchTime(jp.Time, precision, opt.Precision); err != nil {
+				return nil, fmt.Errorf("points[%d]: %s", i, err)
+			}
+		}
+
+		pt, err := NewPointChecked(jp.Measurement, NewTags(tags), fields, t)
+		if err == nil {
+			err = opt.Validator.Validate(pt)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("points[%d]: %s", i, err)
+		}
+		batch.Points = append(batch.Points, pt)
+	}
+	return batch, nil
+}
+
+// parseBatchTime returns the time of a batch or a point in the JSON batch
+// format, using the first precision that's set.
+func parseBatchTime(raw json.RawMessage, precision string, def Precision) (time.Time, error) {
+	p := Precision(precision)
+	if p == "" {
+		p = def
+	}
+	if p == "" {
+		p = Second
+	} else if _, err := ParsePrecision(string(p)); err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := parseJSONTime(raw, p)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if raw[0] == '"' {
+		t = t.Round(p.Duration())
+	}
+	return t, nil
+}
+
+func parseJSONSeries(series []jsonSeries, opt ParseOptions) (*JSONBatch, error) {
+	precision := opt.Precision
+	if precision == "" {
+		precision = Millisecond
+	}
+
+	batch := &JSONBatch{}
+	for _, s := range series {
+		for i, values := range s.Points {
+			if len(values) != len(s.Columns) {
+				return nil, fmt.Errorf("%s points[%d]: %d values for %d columns", s.Name, i, len(values), len(s.Columns))
+			}
+
+			t := opt.DefaultTime
+			fields := make(Fields, len(values))
+			for j, v := range values {
+				if v == nil {
+					continue
+				}
+
+				switch col := s.Columns[j]; col {
+				case "time":
+					n, ok := v.(json.Number)
+					if !ok {
+						return nil, fmt.Errorf("%s points[%d]: invalid time: %v", s.Name, i, v)
+					}
+					var err error
+					if t, err = parseJSONTime(json.RawMessage(n), precision); err != nil {
+						return nil, fmt.Errorf("%s points[%d]: %s", s.Name, i, err)
+					}
+				case "sequence_number":
+				default:
+					switch v := v.(type) {
+					case json.Number:
+						f, err := v.Float64()
+						if err != nil {
+							return nil, fmt.Errorf("%s points[%d]: invalid number for field %q: %s", s.Name, i, col, v)
+						}
+						fields[col] = f
+					case string, bool:
+						fields[col] = v
+					default:
+						return nil, fmt.Errorf("%s points[%d]: unsupported value for field %q: %T", s.Name, i, col, v)
+					}
+				}
+			}
+
+			pt, err := NewPointChecked(s.Name, nil, fields, t)
+			if err == nil {
+				err = opt.Validator.Validate(pt)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s points[%d]: %s", s.Name, i, err)
+			}
+			batch.Points = append(batch.Points, pt)
+		}
+	}
+	return batch, nil
+}
+
+// decodeJSON decodes buf into v, keeping numbers as json.Number.
+func decodeJSON(buf []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,141 @@
+package tsdb_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure points can be parsed from JSON lines.
+func TestParsePointsJSONLines(t *testing.T) {
+	buf := `{"measurement":"cpu","tags":{"host":"a"},"fields":{"value":1,"ok":true,"status":"up"},"time":10}
+
+{"measurement":"mem","fields":{"value":2.5},"time":"2015-09-01T00:00:00Z"}
+{"measurement":"disk","fields":{"value":3}}`
+
+	points, err := tsdb.ParsePointsJSONLines([]byte(buf), tsdb.ParseOptions{DefaultTime: time.Unix(100, 500), Precision: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a []string
+	for _, p := range points {
+		a = append(a, p.String())
+	}
+	exp := []string{
+		`cpu,host=a ok=true,status="up",value=1.0 10000000000`,
+		`mem value=2.5 1441065600000000000`,
+		`disk value=3.0 100000000000`,
+	}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected points:\n%s", strings.Join(a, "\n"))
+	}
+}
+
+// Ensure values that don't map to a field or tag type are rejected.
+func TestParsePointsJSONLines_Strict(t *testing.T) {
+	for i, tt := range []struct {
+		line string
+		err  string
+	}{
+		{line: `{"measurement":"cpu"}`, err: "line 1: missing fields"},
+		{line: `{"fields":{"value":1}}`, err: "line 1: missing measurement"},
+		{line: `{"measurement":"cpu","fields":{"value":[1]}}`, err: `line 1: unsupported value for field "value": []interface {}`},
+		{line: `{"measurement":"cpu","fields":{"value":null}}`, err: `line 1: missing value for field "value"`},
+		{line: `{"measurement":"cpu","tags":{"host":1},"fields":{"value":1}}`, err: "line 1: tag values must be strings"},
+		{line: `{"measurement":"cpu","fields":{"value":1},"timestamp":1}`, err: `line 1: unknown key "timestamp"`},
+		{line: `{"measurement":"cpu","fields":{"value":1},"time":1.5}`, err: "line 1: invalid time: 1.5"},
+		{line: `cpu value=1`, err: "line 1: invalid character 'c' looking for beginning of value"},
+	} {
+		if _, err := tsdb.ParsePointsJSONLines([]byte(tt.line), tsdb.ParseOptions{}); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
+
+// Ensure the well formed lines are returned with PartialOK.
+func TestParsePointsJSONLines_PartialOK(t *testing.T) {
+	buf := `{"measurement":"cpu","fields":{"value":1}}
+{"measurement":"cpu"}
+{"measurement":"mem","fields":{"value":2}}`
+
+	points, err := tsdb.ParsePointsJSONLines([]byte(buf), tsdb.ParseOptions{PartialOK: true})
+	if perr, ok := err.(*tsdb.PartialParseError); !ok || len(perr.Errors) != 1 || perr.Errors[0].Line != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(points) != 2 {
+		t.Fatalf("unexpected point count: %d", len(points))
+	}
+}
+
+// Ensure points can be parsed from both JSON write formats.
+func TestParsePointsJSON(t *testing.T) {
+	for i, tt := range []struct {
+		body string
+		db   string
+		exp  []string
+	}{
+		// Batch format.
+		{
+			body: `{"database":"db0","tags":{"host":"a","region":"us"},"time":10,"points":[
+				{"measurement":"cpu","tags":{"host":"b"},"fields":{"value":1}},
+				{"measurement":"mem","fields":{"value":2.5,"ok":true},"time":20,"precision":"ms"},
+				{"measurement":"disk","fields":{"status":"up"},"time":"2015-09-01T00:00:00.4Z"}]}`,
+			db: "db0",
+			exp: []string{
+				`cpu,host=b,region=us value=1.0 10000000000`,
+				`mem,host=a,region=us ok=true,value=2.5 20000000`,
+				`disk,host=a,region=us status="up" 1441065600000000000`,
+			},
+		},
+
+		// Series format of 0.8.
+		{
+			body: `[{"name":"cpu","columns":["time","sequence_number","value","status"],"points":[[1000,1,1.5,"up"],[2000,2,2,null]]}]`,
+			exp: []string{
+				`cpu status="up",value=1.5 1000000000`,
+				`cpu value=2.0 2000000000`,
+			},
+		},
+		{
+			body: `{"name":"cpu","columns":["value"],"points":[[3]]}`,
+			exp:  []string{`cpu value=3.0 100000000000`},
+		},
+	} {
+		batch, err := tsdb.ParsePointsJSON([]byte(tt.body), tsdb.ParseOptions{DefaultTime: time.Unix(100, 0)})
+		if err != nil {
+			t.Errorf("%d. unexpected error: %s", i, err)
+			continue
+		}
+
+		var a []string
+		for _, p := range batch.Points {
+			a = append(a, p.String())
+		}
+		if batch.Database != tt.db {
+			t.Errorf("%d. unexpected database: %s", i, batch.Database)
+		} else if !reflect.DeepEqual(a, tt.exp) {
+			t.Errorf("%d. unexpected points:\n%s", i, strings.Join(a, "\n"))
+		}
+	}
+}
+
+// Ensure malformed JSON writes are rejected.
+func TestParsePointsJSON_Err(t *testing.T) {
+	for i, tt := range []struct {
+		body string
+		err  string
+	}{
+		{body: ``, err: "EOF"},
+		{body: `{"points":[{"measurement":"cpu"}]}`, err: "points[0]: missing fields"},
+		{body: `{"points":[{"measurement":"cpu","fields":{"value":1},"precision":"y","time":1}]}`, err: `points[0]: unknown precision "y": must be one of n, u, ms, s, m, h or w`},
+		{body: `[{"name":"cpu","columns":["value"],"points":[[1,2]]}]`, err: "cpu points[0]: 2 values for 1 columns"},
+		{body: `[{"name":"cpu","columns":["value"],"points":[[[1]]]}]`, err: `cpu points[0]: unsupported value for field "value": []interface {}`},
+	} {
+		if _, err := tsdb.ParsePointsJSON([]byte(tt.body), tsdb.ParseOptions{}); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
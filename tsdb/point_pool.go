@@ -0,0 +1,59 @@
+package tsdb
+
+import (
+	"sync"
+	"time"
+)
+
+// pointPool recycles the points of the write path.
+var pointPool = sync.Pool{
+	New: func() interface{} { return &point{} },
+}
+
+// GetPoint returns an empty point from the pool shared with ParsePointsInto.
+// It should be returned with PutPoint once it's no longer used.
+func GetPoint() Point {
+	return pointPool.Get().(*point)
+}
+
+// PutPoint resets p and returns it to the pool. p must not be used afterwards.
+// Points of other implementations of Point are ignored.
+func PutPoint(p Point) {
+	pt, ok := p.(*point)
+	if !ok || pt == nil {
+		return
+	}
+	*pt = point{}
+	pointPool.Put(pt)
+}
+
+// ParsePointsInto parses buf like ParsePoints but into the structs of the
+// points in pts, and then into points from GetPoint, instead of allocating new
+// points. The points are returned in pts, which may be the points of a
+// previous batch once it has been written.
+func ParsePointsInto(buf []byte, pts []Point) ([]Point, error) {
+	return ParsePointsWithOptions(buf, ParseOptions{
+		DefaultTime: time.Now().UTC(),
+		Precision:   Nanosecond,
+		Pooled:      true,
+		Reuse:       pts,
+	})
+}
+
+// reusePoints returns a function allocating the points of a pooled parse. The
+// points in reuse are reset and returned first. The parsed points are appended
+// to reuse[:0], which is safe since a slot is always taken before it's
+// overwritten.
+func reusePoints(reuse []Point) func() *point {
+	return func() *point {
+		for len(reuse) > 0 {
+			pt, ok := reuse[0].(*point)
+			reuse = reuse[1:]
+			if ok && pt != nil {
+				*pt = point{}
+				return pt
+			}
+		}
+		return pointPool.Get().(*point)
+	}
+}
@@ -0,0 +1,58 @@
+package tsdb_test
+
+import (
+	"testing"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure ParsePointsInto reuses the points of a previous batch.
+func TestParsePointsInto(t *testing.T) {
+	pts, err := tsdb.ParsePointsInto([]byte("cpu,host=a value=1 1\n# comment\ncpu,host=b value=2 2"), nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pts) != 2 {
+		t.Fatalf("unexpected point count: %d", len(pts))
+	}
+	first, second := pts[0], pts[1]
+
+	pts, err = tsdb.ParsePointsInto([]byte("mem,host=c free=3 3\nmem,host=d free=4 4\nmem,host=e free=5 5"), pts)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pts) != 3 {
+		t.Fatalf("unexpected point count: %d", len(pts))
+	} else if pts[0] != first || pts[1] != second {
+		t.Fatal("expected the points to be reused")
+	}
+
+	for i, exp := range []string{"mem,host=c free=3 3", "mem,host=d free=4 4", "mem,host=e free=5 5"} {
+		if got := pts[i].String(); got != exp {
+			t.Errorf("%d. point mismatch.\ngot %s\nexp %s", i, got, exp)
+		}
+	}
+}
+
+// Ensure a point put back in the pool is returned empty.
+func TestPutPoint(t *testing.T) {
+	pts, err := tsdb.ParsePointsInto([]byte("cpu,host=a value=1 1"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsdb.PutPoint(pts[0])
+
+	if p := tsdb.GetPoint(); p.Name() != "" || len(p.Key()) != 0 {
+		t.Fatalf("expected an empty point, got %s", p.Key())
+	}
+}
+
+func BenchmarkParsePointsInto(b *testing.B) {
+	b.SetBytes(int64(len(batch5000)))
+	b.ReportAllocs()
+	var pts []tsdb.Point
+	for i := 0; i < b.N; i++ {
+		var err error
+		if pts, err = tsdb.ParsePointsInto(batch5000, pts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package tsdb
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// PointScanner reads points in the line protocol from a reader one at a time,
+// so only the current line is held in memory. It's the streaming version of
+// ParsePointsWithPrecision:
+//
+//	s := tsdb.NewPointScanner(r)
+//	for s.Next() {
+//		pt := s.Point()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type PointScanner struct {
+	r      *bufio.Reader
+	pt     Point
+	err    error
+	offset int // The offset in the input of the next line.
+
+	// DefaultTime is the time of points without a timestamp. Precision is the
+	// precision of the timestamps. They default to the current time and
+	// nanoseconds and must be set before the first call to Next.
+	DefaultTime time.Time
+	Precision   Precision
+
+	// DuplicateFields is what happens to lines setting a field more than
+	// once. They're rejected by default.
+	DuplicateFields DuplicateFieldPolicy
+
+	// KeyLimits, if set, rejects lines whose keys or tag values aren't valid
+	// UTF-8 or are over its limits.
+	KeyLimits *KeyLimits
+
+	// NonFinite is what happens to lines with NaN or infinite float fields.
+	// They're rejected by default.
+	NonFinite NonFinitePolicy
+}
+
+// NewPointScanner returns a scanner reading points from r.
+func NewPointScanner(r io.Reader) *PointScanner {
+	return &PointScanner{
+		r:           bufio.NewReader(r),
+		DefaultTime: time.Now().UTC(),
+		Precision:   Nanosecond,
+	}
+}
+
+// Next parses the next point, which is then returned by Point. It returns false
+// at the end of the input or on the first error, which is returned by Err.
+func (s *PointScanner) Next() bool {
+	s.pt = nil
+	if s.err != nil {
+		return false
+	}
+
+	for {
+		lineStart := s.offset
+		line, err := s.readLine()
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		pt := &point{}
+		if ok, err := parseLine(pt, line, lineStart, s.DefaultTime, s.Precision, s.DuplicateFields, s.NonFinite, s.KeyLimits); err != nil {
+			s.err = err
+			return false
+		} else if ok {
+			s.pt = pt
+			return true
+		}
+	}
+}
+
+// Point returns the point parsed by the last call to Next.
+func (s *PointScanner) Point() Point { return s.pt }
+
+// Err returns the error that stopped the scanner, if it isn't the end of the input.
+func (s *PointScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// readLine returns the next line without its newline. A newline inside
+// a quoted string field doesn't end the line. The returned slice is owned by
+// the caller since the parsed point refers to it. Returns io.EOF once the
+// input is consumed.
+func (s *PointScanner) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		buf, err := s.r.ReadSlice('\n')
+		line = append(line, buf...)
+		s.offset += len(buf)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		} else if err == io.EOF {
+			if len(line) == 0 {
+				return nil, io.EOF
+			}
+			return line, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		// The line is complete if its newline isn't quoted.
+		if i, _ := scanLine(line, 0); i == len(line)-1 {
+			return line[:i], nil
+		}
+	}
+}
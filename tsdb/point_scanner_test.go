@@ -0,0 +1,83 @@
+package tsdb_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the scanner returns the same points as ParsePoints.
+func TestPointScanner(t *testing.T) {
+	buf := `# comment
+cpu,host=serverA value=1 1000000000
+  
+cpu,host=serverB value=2,str="foo
+bar" 2000000000
+   mem value=3 3000000000`
+
+	exp, err := tsdb.ParsePointsString(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Use a small buffer so lines span several reads.
+	s := tsdb.NewPointScanner(bufio.NewReaderSize(strings.NewReader(buf), 16))
+	var got []tsdb.Point
+	for s.Next() {
+		got = append(got, s.Point())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(exp) {
+		t.Fatalf("point count mismatch: got %d, exp %d", len(got), len(exp))
+	}
+	for i := range exp {
+		if got[i].String() != exp[i].String() {
+			t.Errorf("%d. point mismatch:\n got %s\n exp %s", i, got[i], exp[i])
+		}
+	}
+}
+
+// Ensure the scanner applies the precision and default time.
+func TestPointScanner_Precision(t *testing.T) {
+	s := tsdb.NewPointScanner(strings.NewReader("cpu value=1 10\ncpu value=2\n"))
+	s.Precision = "s"
+	s.DefaultTime = time.Unix(100, 0)
+
+	if !s.Next() {
+		t.Fatalf("expected point: %v", s.Err())
+	} else if exp := time.Unix(10, 0); !s.Point().Time().Equal(exp) {
+		t.Fatalf("time mismatch: got %v, exp %v", s.Point().Time(), exp)
+	}
+	if !s.Next() {
+		t.Fatalf("expected point: %v", s.Err())
+	} else if exp := time.Unix(100, 0); !s.Point().Time().Equal(exp) {
+		t.Fatalf("time mismatch: got %v, exp %v", s.Point().Time(), exp)
+	}
+	if s.Next() {
+		t.Fatalf("unexpected point: %s", s.Point())
+	} else if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the scanner stops at the first invalid line.
+func TestPointScanner_Error(t *testing.T) {
+	s := tsdb.NewPointScanner(strings.NewReader("cpu value=1\ncpu\ncpu value=3\n"))
+	if !s.Next() {
+		t.Fatalf("expected point: %v", s.Err())
+	}
+	if s.Next() {
+		t.Fatalf("unexpected point: %s", s.Point())
+	} else if err := s.Err(); err == nil || !strings.Contains(err.Error(), "unable to parse 'cpu'") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Next() {
+		t.Fatal("expected scanner to stay stopped")
+	}
+}
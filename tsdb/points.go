@@ -2,14 +2,16 @@ package tsdb
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"hash/fnv"
 	"math"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/influxdb/influxdb/influxql"
 )
 
 // Point defines the values that will be written to the database
@@ -18,23 +20,33 @@ type Point interface {
 	SetName(string)
 
 	Tags() Tags
+	ForEachTag(fn func(key, value []byte) bool)
 	AddTag(key, value string)
+	AddTags(tags Tags)
 	SetTags(tags Tags)
 
 	Fields() Fields
+	FieldsChecked() (Fields, error)
+	FieldIterator() FieldIterator
+	FieldTypes() map[string]influxql.DataType
 	AddField(name string, value interface{})
+	AddFields(fields Fields)
 
 	Time() time.Time
 	SetTime(t time.Time)
 	UnixNano() int64
 
 	HashID() uint64
+	BucketID(n int) int
 	Key() []byte
 
 	Data() []byte
 	SetData(buf []byte)
 
 	String() string
+	PrecisionString(precision Precision) string
+	AppendString(dst []byte, precision Precision) []byte
+	MarshalBinary() ([]byte, error)
 }
 
 // Points represents a sortable list of points by timestamp.
@@ -67,6 +79,13 @@ type point struct {
 
 	// cached version of parsed name from key
 	cachedName string
+
+	// tags added since the key was encoded. The key is re-encoded when it's
+	// next read so adding several tags only encodes it once.
+	pendingTags Tags
+
+	// fieldsDirty is set when fields were added since they were encoded.
+	fieldsDirty bool
 }
 
 const (
@@ -89,30 +108,28 @@ var (
 	// Compile the regex that detects unquoted double quote sequences
 	quoteReplacer = regexp.MustCompile(`([^\\])"`)
 
-	escapeCodes = map[byte][]byte{
-		',': []byte(`\,`),
-		'"': []byte(`\"`),
-		' ': []byte(`\ `),
-		'=': []byte(`\=`),
-	}
+	// escapeChars, measurementEscapeChars and tagEscapeChars are lookup
+	// tables of the characters escaped with a backslash in field keys,
+	// measurement names and tags.
+	escapeChars            = newEscapeTable(",\" =")
+	measurementEscapeChars = newEscapeTable(", ")
+	tagEscapeChars         = newEscapeTable(", =")
 
-	escapeCodesStr = map[string]string{}
-
-	measurementEscapeCodes = map[byte][]byte{
-		',': []byte(`\,`),
-		' ': []byte(`\ `),
+	// stringFieldEscapeCodes are the escapes of quoted string field values.
+	// Other characters, including commas and spaces, are literal within the
+	// quotes, and a backslash before any other character is kept as is.
+	stringFieldEscapeCodes = map[byte][]byte{
+		'"':  []byte(`\"`),
+		'\\': []byte(`\\`),
+		'\n': []byte(`\n`),
 	}
 
-	tagEscapeCodes = map[byte][]byte{
-		',': []byte(`\,`),
-		' ': []byte(`\ `),
-		'=': []byte(`\=`),
-	}
+	stringFieldUnescapeCodes = map[byte]byte{}
 )
 
 func init() {
-	for k, v := range escapeCodes {
-		escapeCodesStr[string(k)] = string(v)
+	for k, v := range stringFieldEscapeCodes {
+		stringFieldUnescapeCodes[v[1]] = k
 	}
 }
 
@@ -123,90 +140,403 @@ func ParsePointsString(buf string) ([]Point, error) {
 // ParsePoints returns a slice of Points from a text representation of a point
 // with each point separated by newlines.
 func ParsePoints(buf []byte) ([]Point, error) {
-	return ParsePointsWithPrecision(buf, time.Now().UTC(), "n")
+	return ParsePointsWithPrecision(buf, time.Now().UTC(), Nanosecond)
+}
+
+func ParsePointsWithPrecision(buf []byte, defaultTime time.Time, precision Precision) ([]Point, error) {
+	return ParsePointsWithOptions(buf, ParseOptions{DefaultTime: defaultTime, Precision: precision})
+}
+
+// ParseOptions control how ParsePointsWithOptions parses a batch.
+type ParseOptions struct {
+	// DefaultTime is the time of points without a timestamp. Precision is the
+	// precision of the timestamps, nanoseconds if it's empty.
+	DefaultTime time.Time
+	Precision   Precision
+
+	// PartialOK skips malformed lines instead of rejecting the whole batch.
+	// The points of the other lines are returned along with a
+	// *PartialParseError describing the skipped lines.
+	PartialOK bool
+
+	// Validator rejects the lines and points that break its rules with a
+	// *ValidationError. Optional.
+	Validator *Validator
+
+	// Pooled parses into the structs of the points in Reuse, and then into
+	// points from GetPoint, instead of allocating the points. They should be
+	// returned with PutPoint once they're written.
+	Pooled bool
+	Reuse  []Point
+
+	// Interner, if set, replaces the keys of the points with shared copies
+	// for the series it holds.
+	Interner *KeyInterner
+
+	// DuplicateFields is what happens to lines setting a field more than
+	// once. They're rejected by default.
+	DuplicateFields DuplicateFieldPolicy
+
+	// KeyLimits, if set, rejects lines whose keys or tag values aren't valid
+	// UTF-8 or are over its limits.
+	KeyLimits *KeyLimits
+
+	// NonFinite is what happens to lines with NaN or infinite float fields.
+	// They're rejected by default.
+	NonFinite NonFinitePolicy
+}
+
+// DuplicateFieldPolicy controls what happens to lines of line protocol that
+// set a field more than once, such as "cpu value=1,value=2".
+type DuplicateFieldPolicy string
+
+const (
+	// DuplicateFieldReject rejects the line with a "duplicate field" error.
+	DuplicateFieldReject DuplicateFieldPolicy = "reject"
+
+	// DuplicateFieldFirstWins keeps the first value of the field.
+	DuplicateFieldFirstWins DuplicateFieldPolicy = "first"
+
+	// DuplicateFieldLastWins keeps the last value of the field.
+	DuplicateFieldLastWins DuplicateFieldPolicy = "last"
+)
+
+// ParseDuplicateFieldPolicy parses a policy name. A blank name is "reject".
+func ParseDuplicateFieldPolicy(s string) (DuplicateFieldPolicy, error) {
+	switch p := DuplicateFieldPolicy(strings.ToLower(s)); p {
+	case "":
+		return DuplicateFieldReject, nil
+	case DuplicateFieldReject, DuplicateFieldFirstWins, DuplicateFieldLastWins:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid duplicate field policy: %q", s)
+	}
+}
+
+// NonFinitePolicy controls what happens to float fields that are NaN or
+// infinite, such as "cpu value=NaN" or "cpu value=+Inf".
+type NonFinitePolicy string
+
+const (
+	// NonFiniteReject rejects the point with an error naming the field.
+	NonFiniteReject NonFinitePolicy = "reject"
+
+	// NonFiniteDrop removes the field. A point left without fields is
+	// rejected.
+	NonFiniteDrop NonFinitePolicy = "drop"
+
+	// NonFiniteClamp replaces infinite values with the largest float of the
+	// same sign. NaN has no closest value so it's dropped.
+	NonFiniteClamp NonFinitePolicy = "clamp"
+
+	// NonFiniteKeep writes the values as they are, as NaN, +Inf or -Inf.
+	NonFiniteKeep NonFinitePolicy = "keep"
+
+	// nonFiniteRejectRule rejects the point with the *ValidationError of the
+	// reject-nan-inf rule of a Validator.
+	nonFiniteRejectRule NonFinitePolicy = "reject-nan-inf"
+)
+
+// ParseNonFinitePolicy parses a policy name. A blank name is "reject".
+func ParseNonFinitePolicy(s string) (NonFinitePolicy, error) {
+	switch p := NonFinitePolicy(strings.ToLower(s)); p {
+	case "":
+		return NonFiniteReject, nil
+	case NonFiniteReject, NonFiniteDrop, NonFiniteClamp, NonFiniteKeep:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid non-finite field policy: %q", s)
+	}
+}
+
+// finite returns v with the policy applied if it's NaN or infinite. It returns
+// false if the field should be dropped.
+func (p NonFinitePolicy) finite(name string, v float64) (float64, bool, error) {
+	if !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v, true, nil
+	}
+
+	switch p {
+	case NonFiniteKeep:
+		return v, true, nil
+	case nonFiniteRejectRule:
+		return 0, false, &ValidationError{Rule: "reject-nan-inf", Reason: fmt.Sprintf("field %q is %v", name, v)}
+	case NonFiniteDrop:
+		return 0, false, nil
+	case NonFiniteClamp:
+		if math.IsNaN(v) {
+			return 0, false, nil
+		}
+		return math.Copysign(math.MaxFloat64, v), true, nil
+	default:
+		return 0, false, &FieldError{Field: name, Reason: fmt.Sprintf("%v is not a finite number", v)}
+	}
 }
 
-func ParsePointsWithPrecision(buf []byte, defaultTime time.Time, precision string) ([]Point, error) {
-	points := []Point{}
+// ParsePointsWithOptions returns a slice of Points from a text representation
+// of points separated by newlines.
+func ParsePointsWithOptions(buf []byte, opt ParseOptions) ([]Point, error) {
+	precision, err := ParsePrecision(string(opt.Precision))
+	if err != nil {
+		return nil, err
+	}
+
+	var points []Point
+	var alloc func() *point
+	if opt.Pooled {
+		points, alloc = opt.Reuse[:0], reusePoints(opt.Reuse)
+	} else {
+		// Allocate the points of the batch in one block instead of one at a
+		// time. There can't be more points than lines.
+		n := bytes.Count(buf, []byte{'\n'}) + 1
+		points = make([]Point, 0, n)
+		block := make([]point, n)
+		alloc = func() *point { return &block[len(points)] }
+	}
+
+	nonFinite := opt.Validator.nonFinite(opt.NonFinite)
+
 	var (
-		pos   int
-		block []byte
+		pos     int
+		line    []byte
+		lineNum int
+		partial *PartialParseError
+		pt      *point
 	)
 	for {
-		pos, block = scanLine(buf, pos)
+		lineStart := pos
+		pos, line = scanLine(buf, pos)
 		pos += 1
+		lineNum++
 
-		if len(block) == 0 {
+		if len(line) == 0 {
 			break
 		}
 
-		// lines which start with '#' are comments
-		start := skipWhitespace(block, 0)
-
-		// If line is all whitespace, just skip it
-		if start >= len(block) {
-			continue
+		if pt == nil {
+			pt = alloc()
 		}
-
-		if block[start] == '#' {
-			continue
+		ok, err := false, opt.Validator.ValidateLine(line)
+		if err == nil {
+			ok, err = parseLine(pt, line, lineStart, opt.DefaultTime, precision, opt.DuplicateFields, nonFinite, opt.KeyLimits)
+		}
+		if err == nil && ok {
+			err = opt.Validator.Validate(pt)
 		}
 
-		// strip the newline if one is present
-		if block[len(block)-1] == '\n' {
-			block = block[:len(block)-1]
+		if e, ok := err.(*ParseError); ok {
+			e.Line = lineNum
 		}
 
-		pt, err := parsePoint(block[start:len(block)], defaultTime, precision)
 		if err != nil {
-			return nil, fmt.Errorf("unable to parse '%s': %v", string(block[start:len(block)]), err)
+			if !opt.PartialOK {
+				return nil, err
+			}
+			if partial == nil {
+				partial = &PartialParseError{}
+			}
+			partial.Errors = append(partial.Errors, LineError{Line: lineNum, Err: err})
+
+			// The point may be partially filled in so reset it for the next line.
+			*pt = point{}
+		} else if ok {
+			pt.key = opt.Interner.Intern(pt.key)
+			points = append(points, pt)
+			pt = nil
 		}
-		points = append(points, pt)
 
 		if pos >= len(buf) {
 			break
 		}
 
 	}
+
+	if pt != nil && opt.Pooled {
+		PutPoint(pt)
+	}
+
+	if partial != nil {
+		return points, partial
+	}
 	return points, nil
+}
+
+// LineError is the error of a line that couldn't be parsed.
+type LineError struct {
+	Line int // The number of the line in the batch, starting at 1.
+	Err  error
+}
+
+func (e LineError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Err) }
+
+// PartialParseError is returned with the points that were parsed when
+// some of the lines of a batch couldn't be.
+type PartialParseError struct {
+	Errors []LineError
+}
+
+func (e *PartialParseError) Error() string {
+	a := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		a[i] = err.Error()
+	}
+	return fmt.Sprintf("partial parse: %d lines rejected: %s", len(e.Errors), strings.Join(a, "; "))
+}
+
+// ParseError is the error of a line that isn't valid line protocol. Its
+// message is the same as the one of the flat errors it replaces.
+type ParseError struct {
+	Line     int    // The number of the line in the batch, starting at 1, if known.
+	Column   int    // The byte in the line where parsing failed, starting at 1.
+	Offset   int    // The byte in the batch where parsing failed, starting at 0.
+	Kind     string // What's wrong, such as "missing tag value" or "bad timestamp".
+	Expected string // What the parser expected at the column, such as "tag value", if known.
+	Snippet  string // The part of the line around the column.
+
+	text string // The line without leading whitespace or newline.
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unable to parse '%s': %s", e.text, e.Kind)
+}
+
+// snippetLen is the number of bytes kept on each side of the column in the
+// Snippet of a ParseError.
+const snippetLen = 20
+
+// syntaxError is the error of a part of a line that isn't what the parser
+// expected.
+type syntaxError struct {
+	kind     string
+	expected string
+}
+
+func (e *syntaxError) Error() string { return e.kind }
+
+// errExpected returns the error kind of a failure where expected was wanted.
+func errExpected(kind, expected string) error {
+	return &syntaxError{kind: kind, expected: expected}
+}
+
+// newParseError returns the error of parsing line at the byte col, which
+// starts at 0 like the offsets in line. lineStart is the offset of line in
+// the batch.
+func newParseError(line []byte, lineStart, start, col int, err error) *ParseError {
+	if col > len(line) {
+		col = len(line)
+	}
+
+	lo, hi := col-snippetLen, col+snippetLen
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > len(line) {
+		hi = len(line)
+	}
+	if lo > hi {
+		lo = hi
+	}
+
+	var expected string
+	if e, ok := err.(*syntaxError); ok {
+		expected = e.expected
+	}
+
+	return &ParseError{
+		Column:   col + 1,
+		Offset:   lineStart + col,
+		Kind:     err.Error(),
+		Expected: expected,
+		Snippet:  string(line[lo:hi]),
+		text:     string(line[start:]),
+	}
+}
+
+// parseLine parses a line returned by scanLine into pt. It returns false for
+// blank lines and comments. Errors of lines that aren't valid line protocol
+// are *ParseError, with the offset of block in the batch added to their offset.
+func parseLine(pt *point, block []byte, blockStart int, defaultTime time.Time, precision Precision, dups DuplicateFieldPolicy, nonFinite NonFinitePolicy, limits *KeyLimits) (bool, error) {
+	// lines which start with '#' are comments
+	start := skipWhitespace(block, 0)
+
+	// If line is all whitespace, just skip it
+	if start >= len(block) {
+		return false, nil
+	}
+
+	if block[start] == '#' {
+		return false, nil
+	}
 
+	// strip the newline if one is present
+	if block[len(block)-1] == '\n' {
+		block = block[:len(block)-1]
+	}
+
+	pos, err := parsePoint(pt, block[start:len(block)], defaultTime, precision, dups, nonFinite, limits)
+	if e, ok := err.(*TimestampOverflowError); ok {
+		e.Line = string(block[start:len(block)])
+		return false, e
+	} else if e, ok := err.(*ValidationError); ok {
+		return false, e
+	} else if e, ok := err.(*keyError); ok {
+		e.offset += start
+	}
+	if err != nil {
+		return false, newParseError(block, blockStart, start, start+pos, err)
+	}
+	return true, nil
 }
 
-func parsePoint(buf []byte, defaultTime time.Time, precision string) (Point, error) {
+// parsePoint parses buf into pt. The point keeps slices of buf rather than
+// copies, and its tags and fields are only decoded when they're read. On
+// error it also returns the position in buf where parsing failed.
+func parsePoint(pt *point, buf []byte, defaultTime time.Time, precision Precision, dups DuplicateFieldPolicy, nonFinite NonFinitePolicy, limits *KeyLimits) (int, error) {
 	// scan the first block which is measurement[,tag1=value1,tag2=value=2...]
-	pos, key, err := scanKey(buf, 0)
+	pos, key, err := scanKey(buf, 0, limits)
 	if err != nil {
-		return nil, err
+		return pos, err
 	}
 
 	// measurement name is required
 	if len(key) == 0 {
-		return nil, fmt.Errorf("missing measurement")
+		return 0, errExpected("missing measurement", "measurement")
 	}
 
 	// scan the second block is which is field1=value1[,field2=value2,...]
-	pos, fields, err := scanFields(buf, pos)
+	pos, fields, err := scanFields(buf, pos, limits)
+	fieldsPos := pos - len(fields)
+	if e, ok := err.(*duplicateFieldError); ok {
+		switch dups {
+		case DuplicateFieldFirstWins, DuplicateFieldLastWins:
+			fields, err = dedupeFields(fields, dups == DuplicateFieldFirstWins), nil
+		default:
+			return e.pos, err
+		}
+	}
 	if err != nil {
-		return nil, err
+		return pos, err
+	}
+	if fields, err = applyNonFinite(fields, nonFinite); err != nil {
+		return fieldsPos, err
 	}
 
 	// at least one field is required
 	if len(fields) == 0 {
-		return nil, fmt.Errorf("missing fields")
+		return pos, errExpected("missing fields", "fields")
 	}
 
 	// scan the last block which is an optional integer timestamp
 	pos, ts, err := scanTime(buf, pos)
 
 	if err != nil {
-		return nil, err
+		return pos, err
 	}
+	tsPos := pos - len(ts)
 
-	pt := &point{
-		key:    key,
-		fields: fields,
-		ts:     ts,
-	}
+	pt.key = key
+	pt.fields = fields
+	pt.ts = ts
 
 	if len(ts) == 0 {
 		pt.time = defaultTime
@@ -214,17 +544,74 @@ func parsePoint(buf []byte, defaultTime time.Time, precision string) (Point, err
 	} else {
 		ts, err := strconv.ParseInt(string(ts), 10, 64)
 		if err != nil {
-			return nil, err
+			return tsPos, errExpected(err.Error(), "integer timestamp")
 		}
-		pt.time = time.Unix(0, ts*pt.GetPrecisionMultiplier(precision))
+		ns, ok := mulInt64(ts, pt.GetPrecisionMultiplier(precision))
+		if !ok {
+			return tsPos, &TimestampOverflowError{Timestamp: ts, Precision: precision}
+		}
+		if err := checkTime(ns); err != nil {
+			return tsPos, err
+		}
+		pt.time = time.Unix(0, ns)
+	}
+	return len(buf), nil
+}
+
+const (
+	// MinNanoTime is the smallest timestamp, in nanoseconds since the epoch,
+	// a point can have. Timestamps before the epoch are parsed but rejected
+	// since the storage engines order time keys as unsigned integers, so
+	// negative ones would sort after every other time.
+	MinNanoTime = int64(0)
+
+	// MaxNanoTime is the largest timestamp, in nanoseconds since the epoch,
+	// a point can have. math.MaxInt64 is kept as the open upper bound of
+	// time ranges.
+	MaxNanoTime = int64(math.MaxInt64) - 1
+)
+
+// ErrTimeOutOfRange is returned for a timestamp outside of MinNanoTime and MaxNanoTime.
+var ErrTimeOutOfRange = fmt.Errorf("time outside range %d - %d", MinNanoTime, MaxNanoTime)
+
+// checkTime returns ErrTimeOutOfRange if ns can't be the timestamp of a point.
+func checkTime(ns int64) error {
+	if ns < MinNanoTime || ns > MaxNanoTime {
+		return ErrTimeOutOfRange
+	}
+	return nil
+}
+
+// TimestampOverflowError is returned when a timestamp converted from its
+// precision to nanoseconds doesn't fit in an int64.
+type TimestampOverflowError struct {
+	Timestamp int64
+	Precision Precision
+	Line      string // The line that failed to parse, if known.
+}
+
+func (e *TimestampOverflowError) Error() string {
+	msg := fmt.Sprintf("timestamp %d with precision %q overflows int64 nanoseconds", e.Timestamp, e.Precision)
+	if e.Line != "" {
+		return fmt.Sprintf("unable to parse '%s': %s", e.Line, msg)
 	}
-	return pt, nil
+	return msg
+}
+
+// mulInt64 returns a*b and whether it was computed without overflowing.
+// b must be positive.
+func mulInt64(a, b int64) (int64, bool) {
+	if a > math.MaxInt64/b || a < math.MinInt64/b {
+		return 0, false
+	}
+	return a * b, true
 }
 
 // scanKey scans buf starting at i for the measurement and tag portion of the point.
 // It returns the ending position and the byte slice of key within buf.  If there
-// are tags, they will be sorted if they are not already.
-func scanKey(buf []byte, i int) (int, []byte, error) {
+// are tags, they will be sorted if they are not already. The measurement and tags
+// are checked against limits, if set.
+func scanKey(buf []byte, i int, limits *KeyLimits) (int, []byte, error) {
 	start := skipWhitespace(buf, i)
 
 	i = start
@@ -251,7 +638,7 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 		// reached the end of buf?
 		if i >= len(buf) {
 			if equals == 0 && commas > 0 {
-				return i, buf[start:i], fmt.Errorf("missing tag value")
+				return i, buf[start:i], errExpected("missing tag value", "tag value")
 			}
 
 			break
@@ -261,17 +648,17 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 		// It does not need to be escaped if part of the measurement.
 		if buf[i] == '=' && commas > 0 {
 			if i-1 < 0 || i-2 < 0 {
-				return i, buf[start:i], fmt.Errorf("missing tag name")
+				return i, buf[start:i], errExpected("missing tag name", "tag key")
 			}
 
 			// Check for "cpu,=value" but allow "cpu,a\,=value"
 			if buf[i-1] == ',' && buf[i-2] != '\\' {
-				return i, buf[start:i], fmt.Errorf("missing tag name")
+				return i, buf[start:i], errExpected("missing tag name", "tag key")
 			}
 
 			// Check for "cpu,\ =value"
 			if buf[i-1] == ' ' && buf[i-2] != '\\' {
-				return i, buf[start:i], fmt.Errorf("missing tag name")
+				return i, buf[start:i], errExpected("missing tag name", "tag key")
 			}
 
 			i += 1
@@ -279,7 +666,7 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 
 			// Check for "cpu,a=1,b= value=1"
 			if i < len(buf) && buf[i] == ' ' {
-				return i, buf[start:i], fmt.Errorf("missing tag value")
+				return i, buf[start:i], errExpected("missing tag value", "tag value")
 			}
 			continue
 		}
@@ -293,7 +680,7 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 		// At a tag separator (comma), track it's location
 		if buf[i] == ',' {
 			if equals == 0 && commas > 0 {
-				return i, buf[start:i], fmt.Errorf("missing tag value")
+				return i, buf[start:i], errExpected("missing tag value", "tag value")
 			}
 			i += 1
 
@@ -308,7 +695,7 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 
 			// Check for "cpu, value=1"
 			if i < len(buf) && buf[i] == ' ' {
-				return i, buf[start:i], fmt.Errorf("missing tag key")
+				return i, buf[start:i], errExpected("missing tag key", "tag key")
 			}
 			continue
 		}
@@ -317,10 +704,10 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 		if buf[i] == ' ' {
 			// check for "cpu,tag value=1"
 			if equals == 0 && commas > 0 {
-				return i, buf[start:i], fmt.Errorf("missing tag value")
+				return i, buf[start:i], errExpected("missing tag value", "tag value")
 			}
 			if equals > 0 && commas-1 != equals-1 {
-				return i, buf[start:i], fmt.Errorf("missing tag value")
+				return i, buf[start:i], errExpected("missing tag value", "tag value")
 			}
 
 			// grow our indices slice if we have too many tags
@@ -340,13 +727,17 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 	// check that all field sections had key and values (e.g. prevent "a=1,b"
 	// We're using commas -1 because there should always be a comma after measurement
 	if equals > 0 && commas-1 != equals-1 {
-		return i, buf[start:i], fmt.Errorf("invalid tag format")
+		return i, buf[start:i], errExpected("invalid tag format", "tag key=value")
 	}
 
 	// This check makes sure we actually received fields from the user. #3379
 	// This will catch invalid syntax such as: `cpu,host=serverA,region=us-west`
 	if i >= len(buf) {
-		return i, buf[start:i], fmt.Errorf("missing fields")
+		return i, buf[start:i], errExpected("missing fields", "fields")
+	}
+
+	if e := limits.checkKey(buf, start, i); e != nil {
+		return e.offset, buf[start:i], e
 	}
 
 	// Now we know where the key region is within buf, and the locations of tags, we
@@ -360,7 +751,7 @@ func scanKey(buf []byte, i int) (int, []byte, error) {
 
 		// If the tags are equal, then there are duplicate tags, and we should abort
 		if bytes.Equal(left, right) {
-			return i, buf[start:i], fmt.Errorf("duplicate tags")
+			return i, buf[start:i], errExpected("duplicate tags", "unique tag key")
 		}
 
 		// If left is greater than right, the tags are not sorted.  We must continue
@@ -414,17 +805,15 @@ func less(buf []byte, indices []int, i, j int) bool {
 }
 
 func isFieldEscapeChar(b byte) bool {
-	for c := range escapeCodes {
-		if c == b {
-			return true
-		}
-	}
-	return false
+	return escapeChars[b]
 }
 
 // scanFields scans buf, starting at i for the fields section of a point.  It returns
-// the ending position and the byte slice of the fields within buf
-func scanFields(buf []byte, i int) (int, []byte, error) {
+// the ending position and the byte slice of the fields within buf. A
+// *duplicateFieldError is returned with the scanned fields if they're
+// otherwise valid but a field is set more than once. Field keys are checked
+// against limits, if set.
+func scanFields(buf []byte, i int, limits *KeyLimits) (int, []byte, error) {
 	start := skipWhitespace(buf, i)
 	i = start
 	quoted := false
@@ -435,6 +824,13 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 	// tracks how many commas we've seen
 	commas := 0
 
+	// the start of the current field, the names of the fields seen so far
+	// and the first one seen twice
+	fieldStart := start
+	var a [8][]byte
+	names := a[:0]
+	var dup *duplicateFieldError
+
 	for {
 		// reached the end of buf?
 		if i >= len(buf) {
@@ -444,8 +840,9 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 		// escaped characters?
 		if buf[i] == '\\' && i+1 < len(buf) {
 
-			// Is this an escape char within a string field? Only " and \ are allowed.
-			if quoted && (buf[i+1] == '"' || buf[i+1] == '\\') {
+			// Any char is escaped within a string field, so an escaped quote
+			// doesn't end it.
+			if quoted {
 				i += 2
 				continue
 				// Non-string field escaped chars
@@ -468,25 +865,39 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 
 			// check for "... =123" but allow "a\ =123"
 			if buf[i-1] == ' ' && buf[i-2] != '\\' {
-				return i, buf[start:i], fmt.Errorf("missing field name")
+				return i, buf[start:i], errExpected("missing field name", "field key")
 			}
 
 			// check for "...a=123,=456" but allow "a=123,a\,=456"
 			if buf[i-1] == ',' && buf[i-2] != '\\' {
-				return i, buf[start:i], fmt.Errorf("missing field name")
+				return i, buf[start:i], errExpected("missing field name", "field key")
 			}
 
 			// check for "... value="
 			if i+1 >= len(buf) {
-				return i, buf[start:i], fmt.Errorf("missing field value")
+				return i, buf[start:i], errExpected("missing field value", "field value")
 			}
 
 			// check for "... value=,value2=..."
 			if buf[i+1] == ',' || buf[i+1] == ' ' {
-				return i, buf[start:i], fmt.Errorf("missing field value")
+				return i, buf[start:i], errExpected("missing field value", "field value")
+			}
+
+			name := buf[fieldStart:i]
+			if e := limits.check("field key", name, fieldStart); e != nil {
+				return e.offset, buf[start:i], e
+			}
+			if dup == nil {
+				for _, n := range names {
+					if bytes.Equal(n, name) {
+						dup = &duplicateFieldError{name: string(unescape(name)), pos: fieldStart}
+						break
+					}
+				}
 			}
+			names = append(names, name)
 
-			if isNumeric(buf[i+1]) || buf[i+1] == '-' || buf[i+1] == 'N' || buf[i+1] == 'n' {
+			if isNumeric(buf[i+1]) || buf[i+1] == '-' || buf[i+1] == '+' || buf[i+1] == 'N' || buf[i+1] == 'n' || buf[i+1] == 'I' || buf[i+1] == 'i' {
 				var err error
 				i, err = scanNumber(buf, i+1)
 				if err != nil {
@@ -507,6 +918,7 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 
 		if buf[i] == ',' && !quoted {
 			commas += 1
+			fieldStart = i + 1
 		}
 
 		// reached end of block?
@@ -517,23 +929,137 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 	}
 
 	if quoted {
-		return i, buf[start:i], fmt.Errorf("unbalanced quotes")
+		return i, buf[start:i], errExpected("unbalanced quotes", "closing quote")
 	}
 
 	// check that all field sections had key and values (e.g. prevent "a=1,b"
 	if equals == 0 || commas != equals-1 {
-		return i, buf[start:i], fmt.Errorf("invalid field format")
+		return i, buf[start:i], errExpected("invalid field format", "field key=value")
 	}
 
+	if dup != nil {
+		return i, buf[start:i], dup
+	}
 	return i, buf[start:i], nil
 }
 
+// duplicateFieldError is returned by scanFields for a field set more than once.
+type duplicateFieldError struct {
+	name string
+	pos  int // The start of the second occurrence of the field.
+}
+
+func (e *duplicateFieldError) Error() string { return fmt.Sprintf("duplicate field %q", e.name) }
+
+// dedupeFields returns the encoded fields of buf with a single value for each
+// field, the first or the last one it's set to, in the order the fields are
+// first set.
+func dedupeFields(buf []byte, first bool) []byte {
+	var names []string
+	values := make(map[string][]byte)
+	it := FieldIterator{buf: buf}
+	for it.Next() {
+		name := string(it.Name())
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		} else if first {
+			continue
+		}
+		values[name] = it.Value()
+	}
+
+	b := make([]byte, 0, len(buf))
+	for i, name := range names {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, escapeString(name)...)
+		b = append(b, '=')
+		b = append(b, values[name]...)
+	}
+	return b
+}
+
+// applyNonFinite applies policy to the NaN and infinite float fields encoded
+// in buf. buf is returned as is if it has none.
+func applyNonFinite(buf []byte, policy NonFinitePolicy) ([]byte, error) {
+	if policy == NonFiniteKeep || !hasNonFinite(buf) {
+		return buf, nil
+	}
+
+	b := make([]byte, 0, len(buf))
+	it := FieldIterator{buf: buf}
+	for it.Next() {
+		value := it.Value()
+		if f, ok := nonFiniteValue(&it); ok {
+			f, ok, err := policy.finite(string(it.Name()), f)
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+			value = appendFloat(nil, f)
+		}
+
+		if len(b) > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, escapeString(string(it.Name()))...)
+		b = append(b, '=')
+		b = append(b, value...)
+	}
+	return b, nil
+}
+
+// hasNonFinite returns true if any float field encoded in buf is NaN or infinite.
+func hasNonFinite(buf []byte) bool {
+	it := FieldIterator{buf: buf}
+	for it.Next() {
+		if _, ok := nonFiniteValue(&it); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nonFiniteValue returns the value of the current field of it if it's a NaN
+// or infinite float.
+func nonFiniteValue(it *FieldIterator) (float64, bool) {
+	value := it.Value()
+	if it.Type() != influxql.Float || len(value) == 0 {
+		return 0, false
+	}
+
+	// Finite floats start with a digit or a decimal point after the sign.
+	if c := value[0]; (c == '-' || c == '+') && len(value) > 1 {
+		value = value[1:]
+	}
+	if c := value[0]; c != 'N' && c != 'n' && c != 'I' && c != 'i' {
+		return 0, false
+	}
+
+	f, err := it.FloatValue()
+	if err != nil || (!math.IsNaN(f) && !math.IsInf(f, 0)) {
+		return 0, false
+	}
+	return f, true
+}
+
 // scanTime scans buf, starting at i for the time section of a point.  It returns
 // the ending position and the byte slice of the fields within buf and error if the
 // timestamp is not in the correct numeric format
 func scanTime(buf []byte, i int) (int, []byte, error) {
 	start := skipWhitespace(buf, i)
 	i = start
+
+	// Timestamps before the epoch are negative.
+	if i < len(buf) && buf[i] == '-' {
+		i += 1
+		if i >= len(buf) || buf[i] < '0' || buf[i] > '9' {
+			return i, buf[start:i], errExpected("bad timestamp", "integer timestamp")
+		}
+	}
+
 	for {
 		// reached the end of buf?
 		if i >= len(buf) {
@@ -543,7 +1069,7 @@ func scanTime(buf []byte, i int) (int, []byte, error) {
 		// Timestamps should integers, make sure they are so we don't need to actually
 		// parse the timestamp until needed
 		if buf[i] < '0' || buf[i] > '9' {
-			return i, buf[start:i], fmt.Errorf("bad timestamp")
+			return i, buf[start:i], errExpected("bad timestamp", "integer timestamp")
 		}
 
 		// reached end of block?
@@ -559,6 +1085,26 @@ func isNumeric(b byte) bool {
 	return (b >= '0' && b <= '9') || b == '.'
 }
 
+// scanNonFinite returns the length of the NaN, Inf, +Inf or -Inf value at
+// buf[i], in any case, or 0 if there's none.
+func scanNonFinite(buf []byte, i int) int {
+	start := i
+	if i < len(buf) && (buf[i] == '-' || buf[i] == '+') {
+		i++
+	}
+	if i+3 > len(buf) {
+		return 0
+	}
+
+	if word := buf[i : i+3]; !bytes.EqualFold(word, []byte("inf")) && !bytes.EqualFold(word, []byte("nan")) {
+		return 0
+	}
+	if i += 3; i < len(buf) && buf[i] != ',' && buf[i] != ' ' {
+		return 0
+	}
+	return i - start
+}
+
 // scanNumber returns the end position within buf, start at i after
 // scanning over buf for an integer, or float.  It returns an
 // error if a invalid number is scanned.
@@ -566,6 +1112,12 @@ func scanNumber(buf []byte, i int) (int, error) {
 	start := i
 	var isInt bool
 
+	// NaN and infinite values are parsed so the NonFinitePolicy of the
+	// parser decides what happens to them.
+	if n := scanNonFinite(buf, i); n > 0 {
+		return i + n, nil
+	}
+
 	// Is negative number?
 	if i < len(buf) && buf[i] == '-' {
 		i += 1
@@ -598,7 +1150,7 @@ func scanNumber(buf []byte, i int) (int, error) {
 
 		// Can't have more than 1 decimal (e.g. 1.1.1 should fail)
 		if decimals > 1 {
-			return i, fmt.Errorf("invalid number")
+			return i, errExpected("invalid number", "number")
 		}
 
 		// `e` is valid for floats but not as the first char
@@ -620,15 +1172,15 @@ func scanNumber(buf []byte, i int) (int, error) {
 				i += 3
 				continue
 			}
-			return i, fmt.Errorf("invalid number")
+			return i, errExpected("invalid number", "number")
 		}
 		if !isNumeric(buf[i]) {
-			return i, fmt.Errorf("invalid number")
+			return i, errExpected("invalid number", "number")
 		}
 		i += 1
 	}
 	if isInt && (decimals > 0 || scientific) {
-		return i, fmt.Errorf("invalid number")
+		return i, errExpected("invalid number", "number")
 	}
 
 	// It's more common that numbers will be within min/max range for their type but we need to prevent
@@ -638,20 +1190,20 @@ func scanNumber(buf []byte, i int) (int, error) {
 	if isInt {
 		// Make sure the last char is an 'i' for integers (e.g. 9i10 is not valid)
 		if buf[i-1] != 'i' {
-			return i, fmt.Errorf("invalid number")
+			return i, errExpected("invalid number", "number")
 		}
 		// Parse the int to check bounds the number of digits could be larger than the max range
 		// We subtract 1 from the index to remove the `i` from our tests
 		if len(buf[start:i-1]) >= maxInt64Digits || len(buf[start:i-1]) >= minInt64Digits {
 			if _, err := strconv.ParseInt(string(buf[start:i-1]), 10, 64); err != nil {
-				return i, fmt.Errorf("unable to parse integer %s: %s", buf[start:i-1], err)
+				return i, errExpected(fmt.Sprintf("unable to parse integer %s: %s", buf[start:i-1], err), "integer")
 			}
 		}
 	} else {
 		// Parse the float to check bounds if it's scientific or the number of digits could be larger than the max range
 		if scientific || len(buf[start:i]) >= maxFloat64Digits || len(buf[start:i]) >= minFloat64Digits {
 			if _, err := strconv.ParseFloat(string(buf[start:i]), 10); err != nil {
-				return i, fmt.Errorf("invalid float")
+				return i, errExpected("invalid float", "float")
 			}
 		}
 	}
@@ -667,7 +1219,7 @@ func scanBoolean(buf []byte, i int) (int, []byte, error) {
 	start := i
 
 	if i < len(buf) && (buf[i] != 't' && buf[i] != 'f' && buf[i] != 'T' && buf[i] != 'F') {
-		return i, buf[start:i], fmt.Errorf("invalid boolean")
+		return i, buf[start:i], errExpected("invalid boolean", "boolean")
 	}
 
 	i += 1
@@ -689,12 +1241,12 @@ func scanBoolean(buf []byte, i int) (int, []byte, error) {
 
 	// length must be 4 for true or TRUE
 	if (buf[start] == 't' || buf[start] == 'T') && i-start != 4 {
-		return i, buf[start:i], fmt.Errorf("invalid boolean")
+		return i, buf[start:i], errExpected("invalid boolean", "boolean")
 	}
 
 	// length must be 5 for false or FALSE
 	if (buf[start] == 'f' || buf[start] == 'F') && i-start != 5 {
-		return i, buf[start:i], fmt.Errorf("invalid boolean")
+		return i, buf[start:i], errExpected("invalid boolean", "boolean")
 	}
 
 	// Otherwise
@@ -711,7 +1263,7 @@ func scanBoolean(buf []byte, i int) (int, []byte, error) {
 	}
 
 	if !valid {
-		return i, buf[start:i], fmt.Errorf("invalid boolean")
+		return i, buf[start:i], errExpected("invalid boolean", "boolean")
 	}
 
 	return i, buf[start:i], nil
@@ -746,8 +1298,14 @@ func scanLine(buf []byte, i int) (int, []byte) {
 			break
 		}
 
-		// If we see a double quote, makes sure it is not escaped
-		if buf[i] == '"' && (i-1 > 0 && buf[i-1] != '\\') {
+		// Skip escaped chars, so an escaped backslash before a quote doesn't
+		// escape the quote. A trailing backslash doesn't escape the newline.
+		if buf[i] == '\\' && i+1 < len(buf) && buf[i+1] != '\n' {
+			i += 2
+			continue
+		}
+
+		if buf[i] == '"' {
 			i += 1
 			quoted = !quoted
 			continue
@@ -842,8 +1400,8 @@ func scanFieldValue(buf []byte, i int) (int, []byte) {
 			break
 		}
 
-		// Only escape char for a field value is a double-quote
-		if buf[i] == '\\' && i+1 < len(buf) && buf[i+1] == '"' {
+		// Any char is escaped within a string, otherwise only a double-quote.
+		if buf[i] == '\\' && i+1 < len(buf) && (quoted || buf[i+1] == '"') {
 			i += 2
 			continue
 		}
@@ -863,155 +1421,258 @@ func scanFieldValue(buf []byte, i int) (int, []byte) {
 	return i, buf[start:i]
 }
 
-func escapeMeasurement(in []byte) []byte {
-	for b, esc := range measurementEscapeCodes {
-		in = bytes.Replace(in, []byte{b}, esc, -1)
+// newEscapeTable returns a lookup table of the characters in chars.
+func newEscapeTable(chars string) *[256]bool {
+	var t [256]bool
+	for i := 0; i < len(chars); i++ {
+		t[chars[i]] = true
 	}
-	return in
+	return &t
 }
 
-func unescapeMeasurement(in []byte) []byte {
-	for b, esc := range measurementEscapeCodes {
-		in = bytes.Replace(in, esc, []byte{b}, -1)
+// escapeBytes returns in with a backslash added before every character in
+// table. When nothing needs escaping in is returned as is, without a copy.
+func escapeBytes(in []byte, table *[256]bool) []byte {
+	n := 0
+	for _, c := range in {
+		if table[c] {
+			n++
+		}
 	}
-	return in
+	if n == 0 {
+		return in
+	}
+
+	out := make([]byte, 0, len(in)+n)
+	start := 0
+	for i, c := range in {
+		if table[c] {
+			out = append(out, in[start:i]...)
+			out = append(out, '\\', c)
+			start = i + 1
+		}
+	}
+	return append(out, in[start:]...)
 }
 
-func escapeTag(in []byte) []byte {
-	for b, esc := range tagEscapeCodes {
-		in = bytes.Replace(in, []byte{b}, esc, -1)
+// unescapeBytes returns in with the backslash removed from every escaped
+// character in table. When nothing is escaped in is returned as is, without
+// a copy.
+func unescapeBytes(in []byte, table *[256]bool) []byte {
+	i := bytes.IndexByte(in, '\\')
+	if i == -1 {
+		return in
 	}
-	return in
+
+	out := make([]byte, 0, len(in))
+	start := 0
+	for ; i < len(in)-1; i++ {
+		if in[i] == '\\' && table[in[i+1]] {
+			out = append(out, in[start:i]...)
+			start = i + 1
+			i++
+		}
+	}
+	return append(out, in[start:]...)
+}
+
+func escapeMeasurement(in []byte) []byte {
+	return escapeBytes(in, measurementEscapeChars)
+}
+
+func unescapeMeasurement(in []byte) []byte {
+	return unescapeBytes(in, measurementEscapeChars)
+}
+
+func escapeTag(in []byte) []byte {
+	return escapeBytes(in, tagEscapeChars)
 }
 
 func unescapeTag(in []byte) []byte {
-	for b, esc := range tagEscapeCodes {
-		in = bytes.Replace(in, esc, []byte{b}, -1)
-	}
-	return in
+	return unescapeBytes(in, tagEscapeChars)
 }
 
 func escape(in []byte) []byte {
-	for b, esc := range escapeCodes {
-		in = bytes.Replace(in, []byte{b}, esc, -1)
-	}
-	return in
+	return escapeBytes(in, escapeChars)
 }
 
 func escapeString(in string) string {
-	for b, esc := range escapeCodesStr {
-		in = strings.Replace(in, b, esc, -1)
+	for i := 0; i < len(in); i++ {
+		if escapeChars[in[i]] {
+			return string(escape([]byte(in)))
+		}
 	}
 	return in
 }
 
 func unescape(in []byte) []byte {
-	i := 0
-	inLen := len(in)
-	var out []byte
+	return unescapeBytes(in, escapeChars)
+}
 
-	for {
-		if i >= inLen {
-			break
-		}
-		if in[i] == '\\' && i+1 < inLen {
-			switch in[i+1] {
-			case ',':
-				out = append(out, ',')
-				i += 2
-				continue
-			case '"':
-				out = append(out, '"')
-				i += 2
-				continue
-			case ' ':
-				out = append(out, ' ')
-				i += 2
-				continue
-			case '=':
-				out = append(out, '=')
-				i += 2
+func unescapeString(in string) string {
+	if strings.IndexByte(in, '\\') == -1 {
+		return in
+	}
+	return string(unescape([]byte(in)))
+}
+
+// unescapeStringField returns a copy of in with any escaped double quotes,
+// backslashes or newlines unescaped.
+func unescapeStringField(in string) string {
+	var out []byte
+	for i := 0; i < len(in); i++ {
+		if in[i] == '\\' && i+1 < len(in) {
+			if c, ok := stringFieldUnescapeCodes[in[i+1]]; ok {
+				out = append(out, c)
+				i++
 				continue
 			}
 		}
 		out = append(out, in[i])
-		i += 1
 	}
-	return out
+	return string(out)
 }
 
-func unescapeString(in string) string {
-	for b, esc := range escapeCodesStr {
-		in = strings.Replace(in, esc, b, -1)
+// NewPoint returns a new point with the given measurement name, tags, fields and timestamp.
+// The fields aren't checked, so NaN and infinite floats are encoded as they
+// are. Points built from values that weren't validated should be created with
+// NewPointChecked instead, which rejects them.
+func NewPoint(name string, tags Tags, fields Fields, time time.Time) Point {
+	return &point{
+		key:    MakeKey([]byte(name), tags),
+		time:   time,
+		fields: fields.MarshalBinary(),
 	}
-	return in
 }
 
-// escapeStringField returns a copy of in with any double quotes or
-// backslashes with escaped values
-func escapeStringField(in string) string {
-	var out []byte
-	i := 0
-	for {
-		if i >= len(in) {
-			break
-		}
-		// escape double-quotes
-		if in[i] == '\\' {
-			out = append(out, '\\')
-			out = append(out, '\\')
-			i += 1
-			continue
+var (
+	// ErrPointMissingMeasurement is returned by NewPointChecked for an empty
+	// measurement name.
+	ErrPointMissingMeasurement = errors.New("missing measurement")
+
+	// ErrPointMissingFields is returned by NewPointChecked for a point without fields.
+	ErrPointMissingFields = errors.New("missing fields")
+)
+
+// FieldError is returned by NewPointChecked for a field that can't be written.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string { return fmt.Sprintf("field %q: %s", e.Field, e.Reason) }
+
+// NewPointChecked returns a new point like NewPoint, or an error if the name
+// is empty, there are no fields, a field has no key, a value of a type that
+// can't be encoded or a NaN or infinite float, or the time is outside
+// MinNanoTime and MaxNanoTime. A zero time is allowed since it's replaced
+// with the time of the write.
+func NewPointChecked(name string, tags Tags, fields Fields, t time.Time) (Point, error) {
+	return NewPointWithNonFinite(name, tags, fields, t, NonFiniteReject)
+}
+
+// NewPointWithNonFinite returns a new point like NewPointChecked, with policy
+// applied to NaN and infinite float fields instead of rejecting them.
+func NewPointWithNonFinite(name string, tags Tags, fields Fields, t time.Time, policy NonFinitePolicy) (Point, error) {
+	if name == "" {
+		return nil, ErrPointMissingMeasurement
+	} else if len(fields) == 0 {
+		return nil, ErrPointMissingFields
+	}
+
+	for k, v := range fields {
+		if k == "" {
+			return nil, &FieldError{Field: k, Reason: "missing field key"}
 		}
-		// escape double-quotes
-		if in[i] == '"' {
-			out = append(out, '\\')
-			out = append(out, '"')
-			i += 1
-			continue
+		switch v.(type) {
+		case int, int32, int64, uint64, float64, bool, string, []byte:
+		default:
+			return nil, &FieldError{Field: k, Reason: fmt.Sprintf("unsupported type %T", v)}
 		}
-		out = append(out, in[i])
-		i += 1
+	}
+	fields, err := fields.Finite(policy)
+	if err != nil {
+		return nil, err
+	} else if len(fields) == 0 {
+		return nil, ErrPointMissingFields
+	}
 
+	if !t.IsZero() && (t.Before(time.Unix(0, MinNanoTime)) || t.After(time.Unix(0, MaxNanoTime))) {
+		return nil, ErrTimeOutOfRange
 	}
-	return string(out)
+
+	return NewPoint(name, tags, fields, t), nil
 }
 
-// unescapeStringField returns a copy of in with any escaped double-quotes
-// or backslashes unescaped
-func unescapeStringField(in string) string {
-	var out []byte
-	i := 0
-	for {
-		if i >= len(in) {
-			break
-		}
-		// unescape backslashes
-		if in[i] == '\\' && i+1 < len(in) && in[i+1] == '\\' {
-			out = append(out, '\\')
-			i += 2
+// MergePoints combines points that share a series key and timestamp into a
+// single point with the fields of all of them. When a field is set more than
+// once the last value wins. Points keep the order they first appeared in.
+func MergePoints(points []Point) []Point {
+	type pointKey struct {
+		key       string
+		timestamp int64
+	}
+
+	merged := make([]Point, 0, len(points))
+	fields := make(map[int]Fields)
+	index := make(map[pointKey]int, len(points))
+	for _, p := range points {
+		k := pointKey{string(p.Key()), p.UnixNano()}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(merged)
+			merged = append(merged, p)
 			continue
 		}
-		// unescape double-quotes
-		if in[i] == '\\' && i+1 < len(in) && in[i+1] == '"' {
-			out = append(out, '"')
-			i += 2
-			continue
+
+		// Copy the fields of the first point so the original isn't modified.
+		f := fields[i]
+		if f == nil {
+			f = make(Fields)
+			for name, v := range merged[i].Fields() {
+				f[name] = v
+			}
+			fields[i] = f
 		}
-		out = append(out, in[i])
-		i += 1
+		for name, v := range p.Fields() {
+			f[name] = v
+		}
+	}
 
+	for i, f := range fields {
+		merged[i] = NewPoint(merged[i].Name(), merged[i].Tags(), f, merged[i].Time())
 	}
-	return string(out)
+	return merged
 }
 
-// NewPoint returns a new point with the given measurement name, tags, fields and timestamp
-func NewPoint(name string, tags Tags, fields Fields, time time.Time) Point {
-	return &point{
-		key:    MakeKey([]byte(name), tags),
-		time:   time,
-		fields: fields.MarshalBinary(),
+// PointGroup identifies a group of points returned by SplitPoints.
+type PointGroup struct {
+	// Start is the start of the time window of the points, in UTC.
+	Start time.Time
+
+	// Shard is the BucketID of the points for the number of groups per window.
+	Shard int
+}
+
+// SplitPoints partitions points by the window of duration d their time falls
+// in, and then by their BucketID for n groups, the same way points are
+// routed to the shard groups of a retention policy and the shards within
+// them. Points keep their order within a group. A zero d puts points with
+// different times in different windows and n less than 1 is treated as 1.
+func SplitPoints(points []Point, d time.Duration, n int) map[PointGroup][]Point {
+	if n < 1 {
+		n = 1
+	}
+
+	groups := make(map[PointGroup][]Point)
+	for _, p := range points {
+		k := PointGroup{Start: p.Time().Truncate(d).UTC()}
+		if n > 1 {
+			k.Shard = p.BucketID(n)
+		}
+		groups[k] = append(groups[k], p)
 	}
+	return groups
 }
 
 func (p *point) Data() []byte {
@@ -1023,6 +1684,10 @@ func (p *point) SetData(b []byte) {
 }
 
 func (p *point) Key() []byte {
+	if p.pendingTags != nil {
+		p.key = makeKey(p.name(), p.pendingTags)
+		p.pendingTags = nil
+	}
 	return p.key
 }
 
@@ -1042,8 +1707,10 @@ func (p *point) Name() string {
 
 // SetName updates the measurement name for the point
 func (p *point) SetName(name string) {
+	tags := p.Tags()
 	p.cachedName = ""
-	p.key = MakeKey([]byte(name), p.Tags())
+	p.key = MakeKey([]byte(name), tags)
+	p.pendingTags = nil
 }
 
 // Time return the timestamp for the point
@@ -1058,237 +1725,414 @@ func (p *point) SetTime(t time.Time) {
 
 // Tags returns the tag set for the point
 func (p *point) Tags() Tags {
-	tags := map[string]string{}
+	if p.pendingTags != nil {
+		return append(Tags(nil), p.pendingTags...)
+	}
 
-	if len(p.key) != 0 {
-		pos, name := scanTo(p.key, 0, ',')
+	var tags Tags
+	p.ForEachTag(func(key, value []byte) bool {
+		tags = append(tags, Tag{Key: string(key), Value: string(value)})
+		return true
+	})
+	return tags
+}
 
-		// it's an empyt key, so there are no tags
-		if len(name) == 0 {
-			return tags
-		}
+// ForEachTag calls fn with each tag of the point, in key order, without
+// building a map. The slices are only valid during the call. Iteration stops
+// when fn returns false.
+func (p *point) ForEachTag(fn func(key, value []byte) bool) {
+	k := p.Key()
+	if len(k) == 0 {
+		return
+	}
 
-		i := pos + 1
-		var key, value []byte
-		for {
-			if i >= len(p.key) {
-				break
-			}
-			i, key = scanTo(p.key, i, '=')
-			i, value = scanTagValue(p.key, i+1)
+	pos, name := scanTo(k, 0, ',')
 
-			tags[string(unescapeTag(key))] = string(unescapeTag(value))
+	// it's an empty key, so there are no tags
+	if len(name) == 0 {
+		return
+	}
 
-			i += 1
+	i := pos + 1
+	var key, value []byte
+	for {
+		if i >= len(k) {
+			break
+		}
+		i, key = scanTo(k, i, '=')
+		i, value = scanTagValue(k, i+1)
+
+		// Only copy tags that need to be unescaped.
+		if bytes.IndexByte(key, '\\') != -1 {
+			key = unescapeTag(key)
 		}
+		if bytes.IndexByte(value, '\\') != -1 {
+			value = unescapeTag(value)
+		}
+		if !fn(key, value) {
+			return
+		}
+
+		i += 1
 	}
-	return tags
+}
+
+// ParseKey returns the measurement name and tags of a series key.
+func ParseKey(key string) (string, Tags) {
+	p := &point{key: []byte(key)}
+	return p.Name(), p.Tags()
 }
 
 func MakeKey(name []byte, tags Tags) []byte {
 	// unescape the name and then re-escape it to avoid double escaping.
 	// The key should always be stored in escaped form.
-	return append(escapeMeasurement(unescapeMeasurement(name)), tags.HashKey()...)
+	return makeKey(escapeMeasurement(unescapeMeasurement(name)), tags)
+}
+
+// makeKey returns the key of a measurement name that's already escaped, such
+// as the name of an existing key, so it isn't unescaped and escaped again.
+func makeKey(name []byte, tags Tags) []byte {
+	key := make([]byte, len(name), len(name)+tags.hashKeySize())
+	copy(key, name)
+	return tags.appendHashKey(key)
 }
 
 // SetTags replaces the tags for the point
 func (p *point) SetTags(tags Tags) {
-	p.key = MakeKey([]byte(p.Name()), tags)
+	p.key = makeKey(p.name(), tags)
+	p.pendingTags = nil
 }
 
 // AddTag adds or replaces a tag value for a point
 func (p *point) AddTag(key, value string) {
-	tags := p.Tags()
-	tags[key] = value
-	p.key = MakeKey([]byte(p.Name()), tags)
+	if p.pendingTags == nil {
+		p.pendingTags = p.Tags()
+	}
+	p.pendingTags.Set(key, value)
 }
 
-// Fields returns the fields for the point
+// AddTags adds or replaces several tag values for a point
+func (p *point) AddTags(tags Tags) {
+	if p.pendingTags == nil {
+		p.pendingTags = p.Tags()
+	}
+	for _, t := range tags {
+		p.pendingTags.Set(t.Key, t.Value)
+	}
+}
+
+// Fields returns the fields for the point. Fields that can't be decoded are
+// left out, FieldsChecked returns the error. The write path reads fields with
+// FieldIterator and reports values that can't be decoded.
 func (p *point) Fields() Fields {
+	fields, _ := p.FieldsChecked()
+	return fields
+}
+
+// FieldsChecked returns the fields of the point and an error if some of them
+// can't be decoded, such as when they were read from corrupt data. The fields
+// that could be decoded are returned along with the error.
+func (p *point) FieldsChecked() (Fields, error) {
+	// Fields read back as they were encoded, so re-encode any added fields.
+	if p.fieldsDirty {
+		p.encodedFields()
+		p.cachedFields = nil
+	}
 	if p.cachedFields != nil {
-		return p.cachedFields
+		return p.cachedFields, nil
 	}
-	p.cachedFields = p.unmarshalBinary()
-	return p.cachedFields
+
+	// Fields that fail to decode aren't cached so every call reports the error.
+	fields, err := p.unmarshalBinary()
+	if err != nil {
+		return fields, err
+	}
+	p.cachedFields = fields
+	return fields, nil
 }
 
 // AddField adds or replaces a field value for a point
 func (p *point) AddField(name string, value interface{}) {
-	fields := p.Fields()
-	fields[name] = value
-	p.fields = fields.MarshalBinary()
-	p.cachedFields = nil
+	p.pendingFields()[name] = value
+}
+
+// AddFields adds or replaces several field values for a point
+func (p *point) AddFields(fields Fields) {
+	f := p.pendingFields()
+	for name, value := range fields {
+		f[name] = value
+	}
+}
+
+// pendingFields returns the fields to add to before they're re-encoded.
+func (p *point) pendingFields() Fields {
+	if !p.fieldsDirty {
+		p.cachedFields = p.Fields()
+		p.fieldsDirty = true
+	}
+	return p.cachedFields
+}
+
+// encodedFields returns the text encoding of the fields, re-encoding them if
+// fields were added since they were last encoded.
+func (p *point) encodedFields() []byte {
+	if p.fieldsDirty {
+		p.fields = Fields(p.cachedFields).MarshalBinary()
+		p.fieldsDirty = false
+	}
+	return p.fields
 }
 
 // SetPrecision will round a time to the specified precision
-func (p *point) SetPrecision(precision string) {
-	switch precision {
-	case "n":
-	case "u":
-		p.SetTime(p.Time().Truncate(time.Microsecond))
-	case "ms":
-		p.SetTime(p.Time().Truncate(time.Millisecond))
-	case "s":
-		p.SetTime(p.Time().Truncate(time.Second))
-	case "m":
-		p.SetTime(p.Time().Truncate(time.Minute))
-	case "h":
-		p.SetTime(p.Time().Truncate(time.Hour))
+func (p *point) SetPrecision(precision Precision) {
+	if precision.Duration() > time.Nanosecond {
+		p.SetTime(precision.Truncate(p.Time()))
 	}
 }
 
 // GetPrecisionMultiplier will return a multiplier for the precision specified
-func (p *point) GetPrecisionMultiplier(precision string) int64 {
-	d := time.Nanosecond
-	switch precision {
-	case "u":
-		d = time.Microsecond
-	case "ms":
-		d = time.Millisecond
-	case "s":
-		d = time.Second
-	case "m":
-		d = time.Minute
-	case "h":
-		d = time.Hour
-	}
-	return int64(d)
+func (p *point) GetPrecisionMultiplier(precision Precision) int64 {
+	return int64(precision.Duration())
 }
 
 func (p *point) String() string {
 	if p.Time().IsZero() {
-		return fmt.Sprintf("%s %s", p.Key(), string(p.fields))
+		return fmt.Sprintf("%s %s", p.Key(), string(p.encodedFields()))
+	}
+	return fmt.Sprintf("%s %s %d", p.Key(), string(p.encodedFields()), p.UnixNano())
+}
+
+// PrecisionString returns the point in the line protocol with its timestamp in
+// precision, so it can be written back with the same precision.
+func (p *point) PrecisionString(precision Precision) string {
+	return string(p.AppendString(nil, precision))
+}
+
+// AppendString appends the point in the line protocol to dst, with its
+// timestamp in precision, and returns the extended buffer. Timestamps are
+// rounded down to the precision.
+func (p *point) AppendString(dst []byte, precision Precision) []byte {
+	dst = append(dst, p.Key()...)
+	dst = append(dst, ' ')
+	dst = append(dst, p.encodedFields()...)
+	if p.Time().IsZero() {
+		return dst
+	}
+
+	ts, d := p.UnixNano(), int64(precision.Duration())
+	if ts%d < 0 {
+		ts = ts/d - 1
+	} else {
+		ts = ts / d
 	}
-	return fmt.Sprintf("%s %s %d", p.Key(), string(p.fields), p.UnixNano())
+	dst = append(dst, ' ')
+	return strconv.AppendInt(dst, ts, 10)
 }
 
-func (p *point) unmarshalBinary() Fields {
+func (p *point) unmarshalBinary() (Fields, error) {
 	return newFieldsFromBinary(p.fields)
 }
 
+// HashID returns the hash of the point's key, FNV-1a unless another hasher
+// was set with SetHasher.
 func (p *point) HashID() uint64 {
-	h := fnv.New64a()
-	h.Write(p.key)
-	sum := h.Sum64()
-	return sum
+	if keyHasher != nil {
+		return keyHasher.Sum64(p.Key())
+	}
+	return fnv64a(p.Key())
 }
 
+// BucketID returns the bucket of n the point falls in by its HashID.
+func (p *point) BucketID(n int) int { return BucketID(p.HashID(), n) }
+
 func (p *point) UnixNano() int64 {
 	return p.Time().UnixNano()
 }
 
-type Tags map[string]string
+// Tag is a key and value of a point's tag set.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Tags is a tag set sorted by key, so series keys are built without sorting
+// or allocating a map. Use NewTags to build one from a map and Set to add
+// tags in order. NewTags, ParseKey and Point.Tags always return sorted tags.
+// Tags built as literals may be unsorted: they're searched linearly by Get
+// and sorted by Set and Delete.
+type Tags []Tag
 
-func (t Tags) HashKey() []byte {
-	// Empty maps marshal to empty bytes.
-	if len(t) == 0 {
+// NewTags returns the tags of a map, sorted by key.
+func NewTags(m map[string]string) Tags {
+	if len(m) == 0 {
 		return nil
 	}
+	a := make(Tags, 0, len(m))
+	for k, v := range m {
+		a = append(a, Tag{Key: k, Value: v})
+	}
+	sort.Sort(a)
+	return a
+}
+
+func (a Tags) Len() int           { return len(a) }
+func (a Tags) Less(i, j int) bool { return a[i].Key < a[j].Key }
+func (a Tags) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
-	escaped := Tags{}
-	for k, v := range t {
-		ek := escapeTag([]byte(k))
-		ev := escapeTag([]byte(v))
-		escaped[string(ek)] = string(ev)
+// index returns the position of key, or where it would be inserted. The tags
+// must be sorted.
+func (a Tags) index(key string) int {
+	return sort.Search(len(a), func(i int) bool { return a[i].Key >= key })
+}
+
+// Get returns the value of a tag, or an empty string if it isn't set.
+func (a Tags) Get(key string) string {
+	if i := a.index(key); i < len(a) && a[i].Key == key {
+		return a[i].Value
 	}
 
-	// Extract keys and determine final size.
-	sz := len(escaped) + (len(escaped) * 2) // separators
-	keys := make([]string, len(escaped)+1)
-	i := 0
-	for k, v := range escaped {
-		keys[i] = k
-		i += 1
-		sz += len(k) + len(v)
+	// The search can miss a key of unsorted tags.
+	if !sort.IsSorted(a) {
+		for _, t := range a {
+			if t.Key == key {
+				return t.Value
+			}
+		}
 	}
-	keys = keys[:i]
-	sort.Strings(keys)
-	// Generate marshaled bytes.
-	b := make([]byte, sz)
-	buf := b
-	idx := 0
-	for _, k := range keys {
-		buf[idx] = ','
-		idx += 1
-		copy(buf[idx:idx+len(k)], k)
-		idx += len(k)
-		buf[idx] = '='
-		idx += 1
-		v := escaped[k]
-		copy(buf[idx:idx+len(v)], v)
-		idx += len(v)
+	return ""
+}
+
+// Set sets the value of a tag, keeping the tags sorted.
+func (a *Tags) Set(key, value string) {
+	if !sort.IsSorted(*a) {
+		sort.Sort(*a)
+	}
+
+	i := a.index(key)
+	if i < len(*a) && (*a)[i].Key == key {
+		(*a)[i].Value = value
+		return
 	}
-	return b[:idx]
+	*a = append(*a, Tag{})
+	copy((*a)[i+1:], (*a)[i:])
+	(*a)[i] = Tag{Key: key, Value: value}
 }
 
-type Fields map[string]interface{}
+// Delete removes a tag if it's set, keeping the tags sorted.
+func (a *Tags) Delete(key string) {
+	if !sort.IsSorted(*a) {
+		sort.Sort(*a)
+	}
 
-func parseNumber(val []byte) (interface{}, error) {
-	if val[len(val)-1] == 'i' {
-		val = val[:len(val)-1]
-		return strconv.ParseInt(string(val), 10, 64)
+	if i := a.index(key); i < len(*a) && (*a)[i].Key == key {
+		*a = append((*a)[:i], (*a)[i+1:]...)
 	}
-	for i := 0; i < len(val); i++ {
-		// If there is a decimal or an N (NaN), I (Inf), parse as float
-		if val[i] == '.' || val[i] == 'N' || val[i] == 'n' || val[i] == 'I' || val[i] == 'i' || val[i] == 'e' {
-			return strconv.ParseFloat(string(val), 64)
-		}
-		if val[i] < '0' && val[i] > '9' {
-			return string(val), nil
-		}
+}
+
+// Map returns the tags as a map.
+func (a Tags) Map() map[string]string {
+	m := make(map[string]string, len(a))
+	for _, t := range a {
+		m[t.Key] = t.Value
 	}
-	return strconv.ParseFloat(string(val), 64)
+	return m
 }
 
-func newFieldsFromBinary(buf []byte) Fields {
-	fields := Fields{}
-	var (
-		i              int
-		name, valueBuf []byte
-		value          interface{}
-		err            error
-	)
-	for {
-		if i >= len(buf) {
-			break
-		}
+// HashKey returns the escaped tags in the form ",key1=value1,key2=value2"
+// that's appended to the measurement name of a series key.
+func (a Tags) HashKey() []byte {
+	// Empty tags marshal to empty bytes.
+	if len(a) == 0 {
+		return nil
+	}
+	return a.appendHashKey(make([]byte, 0, a.hashKeySize()))
+}
+
+// hashKeySize returns the size of the hash key if no tag needs escaping.
+func (a Tags) hashKeySize() int {
+	n := 0
+	for _, t := range a {
+		n += 2 + len(t.Key) + len(t.Value)
+	}
+	return n
+}
+
+// appendHashKey appends the hash key of the tags to b.
+func (a Tags) appendHashKey(b []byte) []byte {
+	// Tags built in order are already sorted. Sort a copy of the others.
+	if !sort.IsSorted(a) {
+		a = append(Tags(nil), a...)
+		sort.Sort(a)
+	}
+
+	for _, t := range a {
+		b = append(b, ',')
+		b = appendEscapedTag(b, t.Key)
+		b = append(b, '=')
+		b = appendEscapedTag(b, t.Value)
+	}
+	return b
+}
+
+// appendEscapedTag appends the escaped form of a tag key or value to b.
+func appendEscapedTag(b []byte, s string) []byte {
+	if strings.IndexAny(s, ", =") == -1 {
+		return append(b, s...)
+	}
+	return append(b, escapeTag([]byte(s))...)
+}
+
+type Fields map[string]interface{}
 
-		i, name = scanTo(buf, i, '=')
-		if len(name) == 0 {
+// newFieldsFromBinary decodes the text encoding of fields. Fields that can't be
+// decoded are left out and the first error is returned with the other fields.
+func newFieldsFromBinary(buf []byte) (Fields, error) {
+	fields := Fields{}
+	var firstErr error
+	it := FieldIterator{buf: buf}
+	for it.Next() {
+		value, err := it.Interface()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("field %q: %s", it.Name(), err)
+			}
 			continue
 		}
-		name = unescape(name)
+		fields[string(it.Name())] = value
+	}
+	return fields, firstErr
+}
 
-		i, valueBuf = scanFieldValue(buf, i+1)
-		if len(valueBuf) == 0 {
-			fields[string(name)] = nil
+// Finite returns the fields with policy applied to their NaN and infinite
+// float values, which can't be written in the line protocol. The fields are
+// returned as is if they have none.
+func (p Fields) Finite(policy NonFinitePolicy) (Fields, error) {
+	var fields Fields
+	for k, v := range p {
+		f, ok := v.(float64)
+		if !ok || (!math.IsNaN(f) && !math.IsInf(f, 0)) {
 			continue
 		}
 
-		// If the first char is a double-quote, then unmarshal as string
-		if valueBuf[0] == '"' {
-			value = unescapeStringField(string(valueBuf[1 : len(valueBuf)-1]))
-			// Check for numeric characters and special NaN or Inf
-		} else if (valueBuf[0] >= '0' && valueBuf[0] <= '9') || valueBuf[0] == '-' || valueBuf[0] == '+' || valueBuf[0] == '.' ||
-			valueBuf[0] == 'N' || valueBuf[0] == 'n' || // NaN
-			valueBuf[0] == 'I' || valueBuf[0] == 'i' { // Inf
-
-			value, err = parseNumber(valueBuf)
-			if err != nil {
-				panic(fmt.Sprintf("unable to parse number value '%v': %v", string(valueBuf), err))
+		f, ok, err := policy.finite(k, f)
+		if err != nil {
+			return nil, err
+		} else if fields == nil {
+			fields = make(Fields, len(p))
+			for k, v := range p {
+				fields[k] = v
 			}
-
-			// Otherwise parse it as bool
+		}
+		if ok {
+			fields[k] = f
 		} else {
-			value, err = strconv.ParseBool(string(valueBuf))
-			if err != nil {
-				panic(fmt.Sprintf("unable to parse bool value '%v': %v\n", string(valueBuf), err))
-			}
+			delete(fields, k)
 		}
-		fields[string(name)] = value
-		i += 1
 	}
-	return fields
+	if fields == nil {
+		return p, nil
+	}
+	return fields, nil
 }
 
 func (p Fields) MarshalBinary() []byte {
@@ -1305,46 +2149,7 @@ func (p Fields) MarshalBinary() []byte {
 		v := p[k]
 		b = append(b, []byte(escapeString(k))...)
 		b = append(b, '=')
-		switch t := v.(type) {
-		case int:
-			b = append(b, []byte(strconv.FormatInt(int64(t), 10))...)
-			b = append(b, 'i')
-		case int32:
-			b = append(b, []byte(strconv.FormatInt(int64(t), 10))...)
-			b = append(b, 'i')
-		case uint64:
-			b = append(b, []byte(strconv.FormatUint(t, 10))...)
-			b = append(b, 'i')
-		case int64:
-			b = append(b, []byte(strconv.FormatInt(t, 10))...)
-			b = append(b, 'i')
-		case float64:
-			// ensure there is a decimal in the encoded for
-
-			val := []byte(strconv.FormatFloat(t, 'f', -1, 64))
-			_, frac := math.Modf(t)
-			hasDecimal := frac != 0
-			b = append(b, val...)
-			if !hasDecimal {
-				b = append(b, []byte(".0")...)
-			}
-		case bool:
-			b = append(b, []byte(strconv.FormatBool(t))...)
-		case []byte:
-			b = append(b, t...)
-		case string:
-			b = append(b, '"')
-			b = append(b, []byte(escapeStringField(t))...)
-			b = append(b, '"')
-		case nil:
-			// skip
-		default:
-			// Can't determine the type, so convert to string
-			b = append(b, '"')
-			b = append(b, []byte(escapeStringField(fmt.Sprintf("%v", v)))...)
-			b = append(b, '"')
-
-		}
+		b = appendFieldValue(b, v)
 		b = append(b, ',')
 	}
 	if len(b) > 0 {
@@ -1353,6 +2158,70 @@ func (p Fields) MarshalBinary() []byte {
 	return b
 }
 
+// appendFieldValue appends the encoding of a field value to b. Values of
+// other types are written as the string of their default format, and nil
+// appends nothing.
+func appendFieldValue(b []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case int:
+		b = strconv.AppendInt(b, int64(t), 10)
+		b = append(b, 'i')
+	case int32:
+		b = strconv.AppendInt(b, int64(t), 10)
+		b = append(b, 'i')
+	case uint64:
+		b = strconv.AppendUint(b, t, 10)
+		b = append(b, 'i')
+	case int64:
+		b = strconv.AppendInt(b, t, 10)
+		b = append(b, 'i')
+	case float64:
+		b = appendFloat(b, t)
+	case bool:
+		b = strconv.AppendBool(b, t)
+	case []byte:
+		b = append(b, t...)
+	case string:
+		b = appendStringField(b, t)
+	case nil:
+		// skip
+	default:
+		// Can't determine the type, so convert to string
+		b = appendStringField(b, fmt.Sprintf("%v", v))
+	}
+	return b
+}
+
+// appendStringField appends s to b as a quoted string field value.
+func appendStringField(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		if esc, ok := stringFieldEscapeCodes[s[i]]; ok {
+			b = append(b, esc...)
+			continue
+		}
+		b = append(b, s[i])
+	}
+	return append(b, '"')
+}
+
+// appendFloat appends the canonical encoding of a float field to b: the
+// shortest digits that parse back to the same float, in decimal notation if
+// its exponent is between -4 and 21 and in scientific notation otherwise, as
+// fmt's %v does. Values such as 1e300 would otherwise be written out with
+// hundreds of digits. Integral values in decimal notation end with ".0".
+func appendFloat(b []byte, f float64) []byte {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-4 || abs >= 1e21) && !math.IsInf(f, 0) {
+		return strconv.AppendFloat(b, f, 'e', -1, 64)
+	}
+
+	b = strconv.AppendFloat(b, f, 'f', -1, 64)
+	if _, frac := math.Modf(f); frac == 0 {
+		b = append(b, ".0"...)
+	}
+	return b
+}
+
 type indexedSlice struct {
 	indices []int
 	b       []byte
@@ -0,0 +1,76 @@
+package tsdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Parser workloads. These benchmarks are compared against a stored baseline
+// by "make bench-points" so keep their names stable.
+
+func BenchmarkParsePoints_FewTags(b *testing.B) {
+	benchmarkParsePoints(b, `cpu,host=serverA value=1.5 1000000000`, 1)
+}
+
+func BenchmarkParsePoints_ManyTags(b *testing.B) {
+	benchmarkParsePoints(b, `cpu,az=us-west-2a,dc=dc1,env=prod,host=serverA,rack=r12,region=us-west,role=db,service=api,team=storage,version=0.9.4 value=1.5 1000000000`, 1)
+}
+
+func BenchmarkParsePoints_UnsortedTags(b *testing.B) {
+	benchmarkParsePoints(b, `cpu,version=0.9.4,team=storage,service=api,role=db,region=us-west,rack=r12,host=serverA,env=prod,dc=dc1,az=us-west-2a value=1.5 1000000000`, 1)
+}
+
+func BenchmarkParsePoints_ManyFields(b *testing.B) {
+	benchmarkParsePoints(b, `cpu,host=serverA idle=90.5,user=5.25,system=3.1,iowait=0.5,irq=0i,softirq=1i,steal=0i,guest=0i,nice=0i,active=true 1000000000`, 1)
+}
+
+func BenchmarkParsePoints_QuotedStrings(b *testing.B) {
+	benchmarkParsePoints(b, `syslog,host=serverA message="Connection from 10.0.0.1 closed by \"peer\", retrying in 5s",severity="warning" 1000000000`, 1)
+}
+
+func BenchmarkParsePoints_EscapedChars(b *testing.B) {
+	benchmarkParsePoints(b, `disk\ usage,path=C:\\Program\ Files,label=a\,b\=c free=1.5 1000000000`, 1)
+}
+
+func BenchmarkParsePoints_NoTimestamp(b *testing.B) {
+	benchmarkParsePoints(b, `cpu,host=serverA,region=us-west value=1.5`, 1)
+}
+
+func BenchmarkParsePoints_Batch1000(b *testing.B) {
+	benchmarkParsePoints(b, `cpu,host=server%d,region=us-west value=1.5 %d`, 1000)
+}
+
+// benchmarkParsePoints parses n lines built from format and marshals each
+// point's key, which forces the tags to be sorted. If n is greater than 1 then
+// format is passed the line number twice.
+func benchmarkParsePoints(b *testing.B, format string, n int) {
+	var buf bytes.Buffer
+	if n == 1 {
+		buf.WriteString(format)
+	} else {
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&buf, format, i, i)
+			buf.WriteByte('\n')
+		}
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pts, err := tsdb.ParsePoints(data)
+		if err != nil {
+			b.Fatal(err)
+		} else if len(pts) != n {
+			b.Fatalf("unexpected point count: %d", len(pts))
+		}
+		for _, p := range pts {
+			p.Key()
+		}
+	}
+}
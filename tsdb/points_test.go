@@ -3,18 +3,21 @@ package tsdb_test
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"math/rand"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 
 	"github.com/influxdb/influxdb/tsdb"
 )
 
 var (
-	tags       = tsdb.Tags{"foo": "bar", "apple": "orange", "host": "serverA", "region": "uswest"}
+	tags       = tsdb.NewTags(map[string]string{"foo": "bar", "apple": "orange", "host": "serverA", "region": "uswest"})
 	maxFloat64 = strconv.FormatFloat(math.MaxFloat64, 'f', 1, 64)
 	minFloat64 = strconv.FormatFloat(-math.MaxFloat64, 'f', 1, 64)
 )
@@ -93,8 +96,64 @@ func BenchmarkParsePointsTagsUnSorted10(b *testing.B) {
 	}
 }
 
+// batch5000 is a typical write body of 5000 points.
+var batch5000 = func() []byte {
+	var buf bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&buf, "cpu,env=prod,host=server%d,region=us-west idle=%d.5,user=5.25,busy=true %d\n", i%100, i%100, 1000000000+i)
+	}
+	return buf.Bytes()
+}()
+
+func BenchmarkParsePointsBatch5000(b *testing.B) {
+	b.SetBytes(int64(len(batch5000)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := tsdb.ParsePoints(batch5000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParsePointsBatch5000_Route parses a batch and reads what the
+// points writer needs to route each point to a shard.
+func BenchmarkParsePointsBatch5000_Route(b *testing.B) {
+	b.SetBytes(int64(len(batch5000)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pts, err := tsdb.ParsePoints(batch5000)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, p := range pts {
+			p.HashID()
+			p.ForEachTag(func(k, v []byte) bool { return true })
+		}
+	}
+}
+
+func BenchmarkPoint_Tags(b *testing.B) {
+	pts, _ := tsdb.ParsePoints([]byte(`cpu,env=prod,host=serverA,region=us-west value=1 1000000000`))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pts[0].Tags()
+	}
+}
+
+func BenchmarkPoint_ForEachTag(b *testing.B) {
+	pts, _ := tsdb.ParsePoints([]byte(`cpu,env=prod,host=serverA,region=us-west value=1 1000000000`))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pts[0].ForEachTag(func(k, v []byte) bool { return true })
+	}
+}
+
 func test(t *testing.T, line string, point tsdb.Point) {
-	pts, err := tsdb.ParsePointsWithPrecision([]byte(line), time.Unix(0, 0), "n")
+	testWithOptions(t, line, tsdb.ParseOptions{DefaultTime: time.Unix(0, 0), Precision: "n"}, point)
+}
+
+func testWithOptions(t *testing.T, line string, opt tsdb.ParseOptions, point tsdb.Point) {
+	pts, err := tsdb.ParsePointsWithOptions([]byte(line), opt)
 	if err != nil {
 		t.Fatalf(`ParsePoints("%s") mismatch. got %v, exp nil`, line, err)
 	}
@@ -111,9 +170,9 @@ func test(t *testing.T, line string, point tsdb.Point) {
 		t.Errorf(`ParsePoints("%s") tags mismatch. got %v, exp %v`, line, pts[0].Tags(), exp)
 	}
 
-	for tag, value := range point.Tags() {
-		if pts[0].Tags()[tag] != value {
-			t.Errorf(`ParsePoints("%s") tags mismatch. got %v, exp %v`, line, pts[0].Tags()[tag], value)
+	for _, tag := range point.Tags() {
+		if pts[0].Tags().Get(tag.Key) != tag.Value {
+			t.Errorf(`ParsePoints("%s") tags mismatch. got %v, exp %v`, line, pts[0].Tags().Get(tag.Key), tag.Value)
 		}
 	}
 
@@ -503,7 +562,7 @@ func TestParsePointUnescape(t *testing.T) {
 	test(t, `foo\,bar value=1i`,
 		tsdb.NewPoint(
 			"foo,bar", // comma in the name
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": 1,
 			},
@@ -513,9 +572,9 @@ func TestParsePointUnescape(t *testing.T) {
 	test(t, `cpu\,main,regions=east\,west value=1.0`,
 		tsdb.NewPoint(
 			"cpu,main", // comma in the name
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "east,west",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -525,9 +584,9 @@ func TestParsePointUnescape(t *testing.T) {
 	test(t, `cpu\ load,region=east value=1.0`,
 		tsdb.NewPoint(
 			"cpu load", // space in the name
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"region": "east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -536,9 +595,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// commas in tag names
 	test(t, `cpu,region\,zone=east value=1.0`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"region,zone": "east", // comma in the tag name
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -547,9 +606,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// spaces in tag names
 	test(t, `cpu,region\ zone=east value=1.0`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"region zone": "east", // comma in the tag name
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -558,9 +617,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// commas in tag values
 	test(t, `cpu,regions=east\,west value=1.0`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "east,west", // comma in the tag value
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -569,9 +628,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// spaces in tag values
 	test(t, `cpu,regions=east\ west value=1.0`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "east west", // comma in the tag value
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -580,9 +639,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// commas in field names
 	test(t, `cpu,regions=east value\,ms=1.0`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "east",
-			},
+			}),
 			tsdb.Fields{
 				"value,ms": 1.0, // comma in the field name
 			},
@@ -591,9 +650,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// spaces in field names
 	test(t, `cpu,regions=east value\ ms=1.0`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "east",
-			},
+			}),
 			tsdb.Fields{
 				"value ms": 1.0, // comma in the field name
 			},
@@ -602,9 +661,9 @@ func TestParsePointUnescape(t *testing.T) {
 	// commas in field values
 	test(t, `cpu,regions=east value="1,0"`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "east",
-			},
+			}),
 			tsdb.Fields{
 				"value": "1,0", // comma in the field value
 			},
@@ -614,9 +673,9 @@ func TestParsePointUnescape(t *testing.T) {
 	test(t, `cpu,regions=eas\t value=1.0`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"regions": "eas\\t",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -626,7 +685,7 @@ func TestParsePointUnescape(t *testing.T) {
 	test(t, `cpu \a=1i`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"\\a": 1, // Left as parsed since it's not a known escape sequence.
 			},
@@ -636,9 +695,9 @@ func TestParsePointUnescape(t *testing.T) {
 	test(t, `cpu=load,equals\=foo=tag\=value value=1i`,
 		tsdb.NewPoint(
 			"cpu=load", // Not escaped
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"equals=foo": "tag=value", // Tag and value unescaped
-			},
+			}),
 			tsdb.Fields{
 				"value": 1,
 			},
@@ -650,7 +709,7 @@ func TestParsePointWithTags(t *testing.T) {
 	test(t,
 		"cpu,host=serverA,region=us-east value=1.0 1000000000",
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{"host": "serverA", "region": "us-east"},
+			tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 			tsdb.Fields{"value": 1.0}, time.Unix(1, 0)))
 }
 
@@ -661,13 +720,76 @@ func TestParsPointWithDuplicateTags(t *testing.T) {
 	}
 }
 
+func TestParsePointWithDuplicateFields(t *testing.T) {
+	line := `cpu value=1,str="a,value=3",value=2,other=true 1000000000`
+
+	_, err := tsdb.ParsePoints([]byte(line))
+	if e, ok := err.(*tsdb.ParseError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.Kind != `duplicate field "value"` || e.Column != 29 {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+
+	for _, tt := range []struct {
+		policy tsdb.DuplicateFieldPolicy
+		exp    tsdb.Fields
+	}{
+		{tsdb.DuplicateFieldFirstWins, tsdb.Fields{"value": 1.0, "str": "a,value=3", "other": true}},
+		{tsdb.DuplicateFieldLastWins, tsdb.Fields{"value": 2.0, "str": "a,value=3", "other": true}},
+	} {
+		points, err := tsdb.ParsePointsWithOptions([]byte(line), tsdb.ParseOptions{DuplicateFields: tt.policy})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.policy, err)
+		} else if len(points) != 1 {
+			t.Fatalf("%s: unexpected point count: %d", tt.policy, len(points))
+		} else if fields := points[0].Fields(); !reflect.DeepEqual(fields, tt.exp) {
+			t.Fatalf("%s: unexpected fields: %v", tt.policy, fields)
+		}
+	}
+}
+
+func TestParsePointWithKeyLimits(t *testing.T) {
+	limits := &tsdb.KeyLimits{MaxKeySize: 8, MaxTagValueSize: 4}
+	for _, tt := range []struct {
+		line string
+		err  string
+	}{
+		{line: `cpu,host=a value=1`},
+		{line: ` cpu,host=serverA value=1`, err: "tag value is 7 bytes, limit is 4 at byte 10"},
+		{line: "cpu,host=a\xffb value=1", err: "tag value is not valid UTF-8 at byte 10"},
+		{line: "cp\xfeu value=1", err: "measurement is not valid UTF-8 at byte 2"},
+		{line: `cpu,hostnames=a value=1`, err: "tag key is 9 bytes, limit is 8 at byte 4"},
+		{line: `cpu value=1,fieldname="a" 1`, err: "field key is 9 bytes, limit is 8 at byte 12"},
+	} {
+		_, err := tsdb.ParsePointsWithOptions([]byte(tt.line), tsdb.ParseOptions{KeyLimits: limits})
+		if tt.err == "" {
+			if err != nil {
+				t.Fatalf("%q: unexpected error: %s", tt.line, err)
+			}
+			continue
+		}
+
+		if e, ok := err.(*tsdb.ParseError); !ok {
+			t.Fatalf("%q: unexpected error: %v", tt.line, err)
+		} else if e.Kind != tt.err {
+			t.Fatalf("%q: unexpected error:\n got %s\n exp %s", tt.line, e.Kind, tt.err)
+		}
+	}
+
+	// Zero limits are the default.
+	line := strings.Repeat("a", tsdb.DefaultMaxKeySize+1) + " value=1"
+	if _, err := tsdb.ParsePointsWithOptions([]byte(line), tsdb.ParseOptions{KeyLimits: &tsdb.KeyLimits{}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func TestParsePointWithStringField(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east value=1.0,str="foo",str2="bar" 1000000000`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 				"str":   "foo",
@@ -678,10 +800,10 @@ func TestParsePointWithStringField(t *testing.T) {
 
 	test(t, `cpu,host=serverA,region=us-east str="foo \" bar" 1000000000`,
 		tsdb.NewPoint("cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"str": `foo " bar`,
 			},
@@ -694,10 +816,10 @@ func TestParsePointWithStringWithSpaces(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east value=1.0,str="foo bar" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 				"str":   "foo bar", // spaces in string value
@@ -710,10 +832,10 @@ func TestParsePointWithStringWithNewline(t *testing.T) {
 	test(t, "cpu,host=serverA,region=us-east value=1.0,str=\"foo\nbar\" 1000000000",
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 				"str":   "foo\nbar", // newline in string value
@@ -722,15 +844,61 @@ func TestParsePointWithStringWithNewline(t *testing.T) {
 	)
 }
 
+func TestParsePointWithStringWithEscapedNewline(t *testing.T) {
+	test(t, `cpu value="foo\nbar\\nbaz" 1000000000`,
+		tsdb.NewPoint(
+			"cpu",
+			tsdb.Tags(nil),
+			tsdb.Fields{
+				"value": "foo\nbar\\nbaz",
+			},
+			time.Unix(1, 0)),
+	)
+}
+
+// Ensure string fields with escapable characters round-trip through the line protocol.
+func TestNewPoint_StringFieldRoundTrip(t *testing.T) {
+	for i, s := range []string{
+		`foo"bar`,
+		`foo\bar`,
+		`foo\`,
+		`foo\"`,
+		`\"foo\\"`,
+		"foo\nbar",
+		"foo\n",
+		"foo\\\n\"",
+		"foo, bar=baz",
+		`\n`,
+	} {
+		pt := tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": s, "n": 1.0}, time.Unix(1, 0))
+
+		// Follow the point with another to check the end of the line is found.
+		buf := pt.String() + "\n" + pt.String()
+		pts, err := tsdb.ParsePointsString(buf)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, buf, err)
+			continue
+		} else if len(pts) != 2 {
+			t.Errorf("%d. %q: unexpected point count: %d", i, buf, len(pts))
+			continue
+		}
+		for _, p := range pts {
+			if fields := p.Fields(); !reflect.DeepEqual(fields, pt.Fields()) {
+				t.Errorf("%d. %q: unexpected fields: %#v", i, buf, fields)
+			}
+		}
+	}
+}
+
 func TestParsePointWithStringWithCommas(t *testing.T) {
 	// escaped comma
 	test(t, `cpu,host=serverA,region=us-east value=1.0,str="foo\,bar" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 				"str":   `foo\,bar`, // commas in string value
@@ -742,10 +910,10 @@ func TestParsePointWithStringWithCommas(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east value=1.0,str="foo,bar" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 				"str":   "foo,bar", // commas in string value
@@ -759,10 +927,10 @@ func TestParsePointQuotedMeasurement(t *testing.T) {
 	test(t, `"cpu",host=serverA,region=us-east value=1.0 1000000000`,
 		tsdb.NewPoint(
 			`"cpu"`,
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -774,10 +942,10 @@ func TestParsePointQuotedTags(t *testing.T) {
 	test(t, `cpu,"host"="serverA",region=us-east value=1.0 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				`"host"`: `"serverA"`,
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -790,10 +958,10 @@ func TestParsePointEscapedStringsAndCommas(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east value="{Hello\"{,}\" World}" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": `{Hello"{,}" World}`,
 			},
@@ -804,10 +972,10 @@ func TestParsePointEscapedStringsAndCommas(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east value="{Hello\"{\,}\" World}" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": `{Hello"{\,}" World}`,
 			},
@@ -819,10 +987,10 @@ func TestParsePointWithStringWithEquals(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east str="foo=bar",value=1.0 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": 1.0,
 				"str":   "foo=bar", // spaces in string value
@@ -835,7 +1003,7 @@ func TestParsePointWithStringWithBackslash(t *testing.T) {
 	test(t, `cpu value="test\\\"" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": `test\"`,
 			},
@@ -845,7 +1013,7 @@ func TestParsePointWithStringWithBackslash(t *testing.T) {
 	test(t, `cpu value="test\\" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": `test\`,
 			},
@@ -855,7 +1023,7 @@ func TestParsePointWithStringWithBackslash(t *testing.T) {
 	test(t, `cpu value="test\\\"" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": `test\"`,
 			},
@@ -865,7 +1033,7 @@ func TestParsePointWithStringWithBackslash(t *testing.T) {
 	test(t, `cpu value="test\"" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": `test"`,
 			},
@@ -877,10 +1045,10 @@ func TestParsePointWithBoolField(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east true=true,t=t,T=T,TRUE=TRUE,True=True,false=false,f=f,F=F,FALSE=FALSE,False=False 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"t":     true,
 				"T":     true,
@@ -901,10 +1069,10 @@ func TestParsePointUnicodeString(t *testing.T) {
 	test(t, `cpu,host=serverA,region=us-east value="wè" 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{
+			tsdb.NewTags(map[string]string{
 				"host":   "serverA",
 				"region": "us-east",
-			},
+			}),
 			tsdb.Fields{
 				"value": "wè",
 			},
@@ -916,7 +1084,7 @@ func TestNewPointFloatWithoutDecimal(t *testing.T) {
 	test(t, `cpu value=1 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -927,7 +1095,7 @@ func TestNewPointNegativeFloat(t *testing.T) {
 	test(t, `cpu value=-0.64 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": -0.64,
 			},
@@ -939,7 +1107,7 @@ func TestNewPointFloatNoDecimal(t *testing.T) {
 	test(t, `cpu value=1. 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": 1.0,
 			},
@@ -951,7 +1119,7 @@ func TestNewPointFloatScientific(t *testing.T) {
 	test(t, `cpu value=6.632243e+06 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": float64(6632243),
 			},
@@ -959,11 +1127,56 @@ func TestNewPointFloatScientific(t *testing.T) {
 	)
 }
 
+// Ensure floats are encoded in their shortest form and parse back to the same value.
+func TestFields_MarshalBinary_Float(t *testing.T) {
+	third := 0.1
+	for _, tt := range []struct {
+		v   float64
+		exp string
+	}{
+		{1, "1.0"},
+		{-1.5, "-1.5"},
+		{1e20, "100000000000000000000.0"},
+		{1e21, "1e+21"},
+		{1e300, "1e+300"},
+		{0.0001, "0.0001"},
+		{-2.5e-7, "-2.5e-07"},
+		{third + 0.2, "0.30000000000000004"},
+		{math.MaxFloat64, "1.7976931348623157e+308"},
+		{math.SmallestNonzeroFloat64, "5e-324"},
+	} {
+		b := tsdb.Fields{"value": tt.v}.MarshalBinary()
+		if got, exp := string(b), "value="+tt.exp; got != exp {
+			t.Fatalf("%v: unexpected encoding:\n got %s\n exp %s", tt.v, got, exp)
+		}
+
+		// Parsing and encoding again is stable.
+		pts, err := tsdb.ParsePointsString("cpu " + string(b))
+		if err != nil {
+			t.Fatalf("%v: %s", tt.v, err)
+		}
+		fields := pts[0].Fields()
+		if v := fields["value"]; v != tt.v {
+			t.Fatalf("%v: unexpected value: %v", tt.v, v)
+		} else if got := string(fields.MarshalBinary()); got != string(b) {
+			t.Fatalf("%v: unexpected encoding: %s", tt.v, got)
+		}
+	}
+
+	// Scientific notation in line protocol is encoded in the canonical form.
+	pts, err := tsdb.ParsePointsString("cpu value=1e3,big=2.5e+30")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := string(pts[0].Fields().MarshalBinary()), "big=2.5e+30,value=1000.0"; got != exp {
+		t.Fatalf("unexpected encoding:\n got %s\n exp %s", got, exp)
+	}
+}
+
 func TestNewPointLargeInteger(t *testing.T) {
 	test(t, `cpu value=6632243i 1000000000`,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": 6632243, // if incorrectly encoded as a float, it would show up as 6.632243e+06
 			},
@@ -972,36 +1185,127 @@ func TestNewPointLargeInteger(t *testing.T) {
 }
 
 func TestNewPointNaN(t *testing.T) {
-	test(t, `cpu value=NaN 1000000000`,
+	// NaN is only parsed when the policy keeps it.
+	keep := tsdb.ParseOptions{DefaultTime: time.Unix(0, 0), Precision: "n", NonFinite: tsdb.NonFiniteKeep}
+
+	testWithOptions(t, `cpu value=NaN 1000000000`, keep,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": math.NaN(),
 			},
 			time.Unix(1, 0)),
 	)
 
-	test(t, `cpu value=nAn 1000000000`,
+	testWithOptions(t, `cpu value=nAn 1000000000`, keep,
 		tsdb.NewPoint(
 			"cpu",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": math.NaN(),
 			},
 			time.Unix(1, 0)),
 	)
 
-	test(t, `nan value=NaN`,
+	testWithOptions(t, `nan value=NaN`, keep,
 		tsdb.NewPoint(
 			"nan",
-			tsdb.Tags{},
+			tsdb.Tags(nil),
 			tsdb.Fields{
 				"value": math.NaN(),
 			},
 			time.Unix(0, 0)),
 	)
 
+	// By default NaN is rejected.
+	for _, line := range []string{
+		`cpu value=NaN 1000000000`,
+		`cpu value=nAn 1000000000`,
+		`nan value=NaN`,
+	} {
+		_, err := tsdb.ParsePointsString(line)
+		if e, ok := err.(*tsdb.ParseError); !ok {
+			t.Fatalf("%s: unexpected error: %v", line, err)
+		} else if !strings.HasSuffix(e.Kind, `"value": NaN is not a finite number`) {
+			t.Fatalf("%s: unexpected error: %s", line, e.Kind)
+		}
+	}
+}
+
+func TestParsePointWithNonFiniteFields(t *testing.T) {
+	line := `cpu value=+Inf,min=-inf,avg=NaN,n=1i 1000000000`
+
+	_, err := tsdb.ParsePoints([]byte(line))
+	if e, ok := err.(*tsdb.ParseError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.Kind != `field "value": +Inf is not a finite number` || e.Column != 5 {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+
+	for _, tt := range []struct {
+		policy tsdb.NonFinitePolicy
+		exp    tsdb.Fields
+	}{
+		{tsdb.NonFiniteDrop, tsdb.Fields{"n": int64(1)}},
+		{tsdb.NonFiniteClamp, tsdb.Fields{"value": math.MaxFloat64, "min": -math.MaxFloat64, "n": int64(1)}},
+	} {
+		points, err := tsdb.ParsePointsWithOptions([]byte(line), tsdb.ParseOptions{NonFinite: tt.policy})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.policy, err)
+		} else if len(points) != 1 {
+			t.Fatalf("%s: unexpected point count: %d", tt.policy, len(points))
+		} else if fields := points[0].Fields(); !reflect.DeepEqual(fields, tt.exp) {
+			t.Fatalf("%s: unexpected fields: %v", tt.policy, fields)
+		}
+	}
+
+	// Points left without fields are rejected.
+	if _, err := tsdb.ParsePointsWithOptions([]byte(`cpu value=NaN`), tsdb.ParseOptions{NonFinite: tsdb.NonFiniteDrop}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Kept infinite values are written back as they were parsed.
+	points, err := tsdb.ParsePointsWithOptions([]byte(`cpu min=-inf,value=+Inf 1`), tsdb.ParseOptions{NonFinite: tsdb.NonFiniteKeep})
+	if err != nil {
+		t.Fatal(err)
+	} else if fields := points[0].Fields(); !reflect.DeepEqual(fields, tsdb.Fields{"value": math.Inf(1), "min": math.Inf(-1)}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	} else if s := points[0].String(); s != `cpu min=-inf,value=+Inf 1` {
+		t.Fatalf("unexpected string: %s", s)
+	}
+
+	if p, err := tsdb.ParseNonFinitePolicy(""); err != nil || p != tsdb.NonFiniteReject {
+		t.Fatalf("unexpected policy: %s, %v", p, err)
+	} else if _, err := tsdb.ParseNonFinitePolicy("zero"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNewPointWithNonFinite(t *testing.T) {
+	fields := tsdb.Fields{"value": math.Inf(1), "n": int64(1)}
+	if _, err := tsdb.NewPointWithNonFinite("cpu", nil, fields, time.Unix(0, 0), tsdb.NonFiniteReject); err == nil {
+		t.Fatal("expected error")
+	} else if pt, err := tsdb.NewPointWithNonFinite("cpu", nil, fields, time.Unix(0, 0), tsdb.NonFiniteDrop); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(pt.Fields(), tsdb.Fields{"n": int64(1)}) {
+		t.Fatalf("unexpected fields: %v", pt.Fields())
+	} else if _, err := tsdb.NewPointWithNonFinite("cpu", nil, tsdb.Fields{"value": math.NaN()}, time.Unix(0, 0), tsdb.NonFiniteDrop); err != tsdb.ErrPointMissingFields {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFields_Finite(t *testing.T) {
+	fields := tsdb.Fields{"value": math.Inf(-1), "avg": math.NaN(), "n": int64(1)}
+	if _, err := fields.Finite(tsdb.NonFiniteReject); err == nil {
+		t.Fatal("expected error")
+	} else if got, err := fields.Finite(tsdb.NonFiniteClamp); err != nil {
+		t.Fatal(err)
+	} else if exp := (tsdb.Fields{"value": -math.MaxFloat64, "n": int64(1)}); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected fields: %v", got)
+	} else if len(fields) != 3 {
+		t.Fatalf("fields were modified: %v", fields)
+	}
 }
 
 func TestNewPointLargeNumberOfTags(t *testing.T) {
@@ -1077,7 +1381,7 @@ func TestParsePointToString(t *testing.T) {
 		t.Errorf("ParsePoint() to string mismatch:\n got %v\n exp %v", got, line)
 	}
 
-	pt = tsdb.NewPoint("cpu", tsdb.Tags{"host": "serverA", "region": "us-east"},
+	pt = tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA", "region": "us-east"}),
 		tsdb.Fields{"int": 10, "float": float64(11.0), "float2": float64(12.123), "bool": false, "str": "string val"},
 		time.Unix(1, 0))
 
@@ -1091,7 +1395,7 @@ func TestParsePointsWithPrecision(t *testing.T) {
 	tests := []struct {
 		name      string
 		line      string
-		precision string
+		precision tsdb.Precision
 		exp       string
 	}{
 		{
@@ -1154,12 +1458,94 @@ func TestParsePointsWithPrecision(t *testing.T) {
 	}
 }
 
+// Ensure timestamps at the edge of the int64 nanosecond range are parsed
+// exactly and ones past it return an overflow error.
+func TestParsePointsWithPrecision_Bounds(t *testing.T) {
+	tests := []struct {
+		ts        string
+		precision tsdb.Precision
+		exp       int64
+		overflow  bool
+	}{
+		{ts: "9223372036854775806", precision: "n", exp: math.MaxInt64 - 1},
+		{ts: "-9223372036854775807", precision: "n", exp: math.MinInt64 + 1},
+		{ts: "-9223372036854775", precision: "u", exp: -9223372036854775000},
+		{ts: "-9223372036854776", precision: "u", overflow: true},
+		{ts: "-9223372036", precision: "s", exp: -9223372036000000000},
+		{ts: "-9223372037", precision: "s", overflow: true},
+		{ts: "9223372036854775", precision: "u", exp: 9223372036854775000},
+		{ts: "9223372036854776", precision: "u", overflow: true},
+		{ts: "9223372036854", precision: "ms", exp: 9223372036854000000},
+		{ts: "9223372036855", precision: "ms", overflow: true},
+		{ts: "9223372036", precision: "s", exp: 9223372036000000000},
+		{ts: "9223372037", precision: "s", overflow: true},
+		{ts: "153722867", precision: "m", exp: 153722867 * int64(time.Minute)},
+		{ts: "153722868", precision: "m", overflow: true},
+		{ts: "2562047", precision: "h", exp: 2562047 * int64(time.Hour)},
+		{ts: "2562048", precision: "h", overflow: true},
+	}
+
+	for i, tt := range tests {
+		line := "cpu value=1 " + tt.ts
+		pts, err := tsdb.ParsePointsWithPrecision([]byte(line), time.Now().UTC(), tt.precision)
+		if tt.overflow {
+			if e, ok := err.(*tsdb.TimestampOverflowError); !ok {
+				t.Errorf("%d. %s %s: expected overflow error, got: %v", i, tt.ts, tt.precision, err)
+			} else if e.Line != line || e.Precision != tt.precision {
+				t.Errorf("%d. %s %s: unexpected error: %#v", i, tt.ts, tt.precision, e)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%d. %s %s: unexpected error: %s", i, tt.ts, tt.precision, err)
+		} else if ns := pts[0].UnixNano(); ns != tt.exp {
+			t.Errorf("%d. %s %s: unexpected time: got %d, exp %d", i, tt.ts, tt.precision, ns, tt.exp)
+		}
+	}
+}
+
+// Ensure timestamps before the epoch are parsed and rejected along with the
+// others outside the supported range.
+func TestParsePointsNegativeTime(t *testing.T) {
+	for i, tt := range []struct {
+		line string
+		exp  int64
+		err  string
+	}{
+		{line: "cpu value=1 0", exp: 0},
+		{line: "cpu value=1 -1", err: tsdb.ErrTimeOutOfRange.Error()},
+		{line: "cpu value=1 -86400000000000", err: tsdb.ErrTimeOutOfRange.Error()},
+		{line: "cpu value=1 9223372036854775807", err: tsdb.ErrTimeOutOfRange.Error()},
+		{line: "cpu value=1 -9223372036854775808", err: tsdb.ErrTimeOutOfRange.Error()},
+		{line: "cpu value=1 -", err: "bad timestamp"},
+		{line: "cpu value=1 --1", err: "bad timestamp"},
+		{line: "cpu value=1 1-1", err: "bad timestamp"},
+	} {
+		pts, err := tsdb.ParsePoints([]byte(tt.line))
+		if tt.err != "" {
+			if exp := fmt.Sprintf("unable to parse '%s': %s", tt.line, tt.err); err == nil || err.Error() != exp {
+				t.Errorf("%d. %s: unexpected error: %v", i, tt.line, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%d. %s: unexpected error: %s", i, tt.line, err)
+		} else if ns := pts[0].UnixNano(); ns != tt.exp {
+			t.Errorf("%d. %s: unexpected time: got %d, exp %d", i, tt.line, ns, tt.exp)
+		} else if s := pts[0].String(); s != tt.line {
+			t.Errorf("%d. unexpected string: %s", i, s)
+		}
+	}
+}
+
 func TestParsePointsWithPrecisionNoTime(t *testing.T) {
 	line := `cpu,host=serverA,region=us-east value=1.0`
 	tm, _ := time.Parse(time.RFC3339Nano, "2000-01-01T12:34:56.789012345Z")
 	tests := []struct {
 		name      string
-		precision string
+		precision tsdb.Precision
 		exp       string
 	}{
 		{
@@ -1216,6 +1602,69 @@ func TestParsePointsWithPrecisionNoTime(t *testing.T) {
 	}
 }
 
+func TestParsePointsWithOptions_PartialOK(t *testing.T) {
+	batch := `cpu value=1 1
+cpu value=
+# comment
+mem value=2 2
+disk,host value=3 3`
+
+	// The whole batch is rejected by default.
+	if _, err := tsdb.ParsePointsWithOptions([]byte(batch), tsdb.ParseOptions{}); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(*tsdb.PartialParseError); ok {
+		t.Fatalf("unexpected partial parse error: %s", err)
+	}
+
+	pts, err := tsdb.ParsePointsWithOptions([]byte(batch), tsdb.ParseOptions{PartialOK: true})
+	if len(pts) != 2 {
+		t.Fatalf("unexpected number of points: %d", len(pts))
+	} else if got, exp := pts[0].String(), "cpu value=1 1"; got != exp {
+		t.Fatalf("unexpected first point: got %s, exp %s", got, exp)
+	} else if got, exp := pts[1].String(), "mem value=2 2"; got != exp {
+		t.Fatalf("unexpected second point: got %s, exp %s", got, exp)
+	}
+
+	e, ok := err.(*tsdb.PartialParseError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(e.Errors) != 2 {
+		t.Fatalf("unexpected number of line errors: %d", len(e.Errors))
+	} else if e.Errors[0].Line != 2 || e.Errors[1].Line != 5 {
+		t.Fatalf("unexpected lines: %d, %d", e.Errors[0].Line, e.Errors[1].Line)
+	} else if !strings.HasPrefix(e.Error(), "partial parse: 2 lines rejected: line 2: unable to parse 'cpu value=': ") {
+		t.Fatalf("unexpected error message: %s", e)
+	}
+}
+
+// Ensure parse errors point at where the line is broken.
+func TestParsePoints_ParseError(t *testing.T) {
+	_, err := tsdb.ParsePoints([]byte("cpu value=1 1\ncpu,host=a value=1 bad"))
+	e, ok := err.(*tsdb.ParseError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := &tsdb.ParseError{Line: 2, Column: 20, Offset: 33, Kind: "bad timestamp", Expected: "integer timestamp", Snippet: "cpu,host=a value=1 bad"}
+	if e.Line != exp.Line || e.Column != exp.Column || e.Offset != exp.Offset || e.Kind != exp.Kind || e.Expected != exp.Expected || e.Snippet != exp.Snippet {
+		t.Fatalf("unexpected error:\n got %#v\n exp %#v", e, exp)
+	} else if got, exp := e.Error(), "unable to parse 'cpu,host=a value=1 bad': bad timestamp"; got != exp {
+		t.Fatalf("unexpected error message:\n got %s\n exp %s", got, exp)
+	}
+}
+
+// Ensure the scanner reports parse errors at their offset in the input.
+func TestPointScanner_ParseError(t *testing.T) {
+	s := tsdb.NewPointScanner(strings.NewReader("cpu value=1 1\ncpu,host= value=1"))
+	for s.Next() {
+	}
+	e, ok := s.Err().(*tsdb.ParseError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", s.Err())
+	} else if e.Offset != 23 || e.Column != 10 || e.Kind != "missing tag value" || e.Expected != "tag value" {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+}
+
 func TestParsePointsWithPrecisionComments(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1274,24 +1723,131 @@ cpu,host=serverA,region=us-east value=1.0 946730096789012345`,
 
 func TestNewPointEscaped(t *testing.T) {
 	// commas
-	pt := tsdb.NewPoint("cpu,main", tsdb.Tags{"tag,bar": "value"}, tsdb.Fields{"name,bar": 1.0}, time.Unix(0, 0))
+	pt := tsdb.NewPoint("cpu,main", tsdb.NewTags(map[string]string{"tag,bar": "value"}), tsdb.Fields{"name,bar": 1.0}, time.Unix(0, 0))
 	if exp := `cpu\,main,tag\,bar=value name\,bar=1.0 0`; pt.String() != exp {
 		t.Errorf("NewPoint().String() mismatch.\ngot %v\nexp %v", pt.String(), exp)
 	}
 
 	// spaces
-	pt = tsdb.NewPoint("cpu main", tsdb.Tags{"tag bar": "value"}, tsdb.Fields{"name bar": 1.0}, time.Unix(0, 0))
+	pt = tsdb.NewPoint("cpu main", tsdb.NewTags(map[string]string{"tag bar": "value"}), tsdb.Fields{"name bar": 1.0}, time.Unix(0, 0))
 	if exp := `cpu\ main,tag\ bar=value name\ bar=1.0 0`; pt.String() != exp {
 		t.Errorf("NewPoint().String() mismatch.\ngot %v\nexp %v", pt.String(), exp)
 	}
 
 	// equals
-	pt = tsdb.NewPoint("cpu=main", tsdb.Tags{"tag=bar": "value=foo"}, tsdb.Fields{"name=bar": 1.0}, time.Unix(0, 0))
+	pt = tsdb.NewPoint("cpu=main", tsdb.NewTags(map[string]string{"tag=bar": "value=foo"}), tsdb.Fields{"name=bar": 1.0}, time.Unix(0, 0))
 	if exp := `cpu=main,tag\=bar=value\=foo name\=bar=1.0 0`; pt.String() != exp {
 		t.Errorf("NewPoint().String() mismatch.\ngot %v\nexp %v", pt.String(), exp)
 	}
 }
 
+// Ensure fields that can't be decoded are reported instead of panicking.
+func TestPoint_FieldsChecked(t *testing.T) {
+	pts, err := tsdb.ParsePoints([]byte(`cpu value=-i,ok=1i`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := pts[0].FieldsChecked()
+	if err == nil || !strings.Contains(err.Error(), `field "value"`) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !reflect.DeepEqual(fields, tsdb.Fields{"ok": int64(1)}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	if fields := pts[0].Fields(); !reflect.DeepEqual(fields, tsdb.Fields{"ok": int64(1)}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	if _, err := pts[0].MarshalBinary(); err == nil {
+		t.Fatal("expected marshal error")
+	}
+}
+
+// Ensure decoding the fields of arbitrary lines never panics.
+func TestPoint_FieldsChecked_Fuzz(t *testing.T) {
+	check := func(line []byte) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("panic decoding %q: %v", line, r)
+			}
+		}()
+		pts, err := tsdb.ParsePoints(line)
+		if err != nil {
+			return
+		}
+		for _, p := range pts {
+			p.FieldsChecked()
+			p.MarshalBinary()
+		}
+	}
+
+	// Random field values.
+	if err := quick.Check(func(v []byte) bool {
+		check(append([]byte("cpu value="), v...))
+		return true
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Random mutations of valid lines.
+	seeds := []string{
+		`cpu,host=a value=1.5,count=-10i,ok=true,msg="x \"y\", z" 10`,
+		`cpu value=-1e-4,n=9223372036854775807i,b=F`,
+	}
+	alphabet := []byte(`0123456789.-+eEiIntfTF"\,= `)
+	rnd := rand.New(rand.NewSource(0))
+	for i := 0; i < 20000; i++ {
+		line := []byte(seeds[i%len(seeds)])
+		for j := rnd.Intn(4); j >= 0; j-- {
+			line[rnd.Intn(len(line))] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		check(line)
+	}
+}
+
+// Ensure points are encoded with their timestamp in any precision.
+func TestPoint_PrecisionString(t *testing.T) {
+	tags := tsdb.NewTags(map[string]string{"host": "server a", "region": "us,west"})
+	ts := time.Unix(1441065600, 123456789)
+	pt := tsdb.NewPoint("cpu load", tags, tsdb.Fields{"value": 1.5, "msg": `say "hi"`}, ts)
+
+	key := `cpu\ load,host=server\ a,region=us\,west msg="say \"hi\"",value=1.5`
+	for _, tt := range []struct {
+		precision tsdb.Precision
+		exp       string
+	}{
+		{precision: tsdb.Nanosecond, exp: key + " 1441065600123456789"},
+		{precision: tsdb.Microsecond, exp: key + " 1441065600123456"},
+		{precision: tsdb.Millisecond, exp: key + " 1441065600123"},
+		{precision: tsdb.Second, exp: key + " 1441065600"},
+		{precision: tsdb.Hour, exp: key + " 400296"},
+	} {
+		if got := pt.PrecisionString(tt.precision); got != tt.exp {
+			t.Errorf("%s: unexpected string:\n got %s\nexp %s", tt.precision, got, tt.exp)
+		}
+
+		// The string parses back to the same point at that precision.
+		pts, err := tsdb.ParsePointsWithPrecision([]byte(tt.exp), time.Now(), tt.precision)
+		if err != nil {
+			t.Fatal(err)
+		} else if exp := ts.Truncate(tt.precision.Duration()); !pts[0].Time().Equal(exp) {
+			t.Errorf("%s: unexpected time: %s", tt.precision, pts[0].Time())
+		} else if !reflect.DeepEqual(pts[0].Fields(), pt.Fields()) || !reflect.DeepEqual(pts[0].Tags(), tags) {
+			t.Errorf("%s: unexpected point: %s", tt.precision, pts[0])
+		}
+	}
+
+	// Times before the epoch are rounded down.
+	pt = tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": int64(1)}, time.Unix(-1, 500000000))
+	if got := pt.PrecisionString(tsdb.Second); got != "cpu value=1i -1" {
+		t.Errorf("unexpected string: %s", got)
+	}
+
+	// The point is appended to the buffer.
+	if got := string(pt.AppendString([]byte("x\n"), tsdb.Millisecond)); got != "x\ncpu value=1i -500" {
+		t.Errorf("unexpected buffer: %q", got)
+	}
+}
+
 func TestNewPointUnhandledType(t *testing.T) {
 	// nil value
 	pt := tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": nil}, time.Unix(0, 0))
@@ -1311,21 +1867,307 @@ func TestNewPointUnhandledType(t *testing.T) {
 	}
 }
 
+// Ensure NewPointChecked rejects points that NewPoint would encode badly.
+func TestNewPointChecked(t *testing.T) {
+	for i, tt := range []struct {
+		name   string
+		fields tsdb.Fields
+		time   time.Time
+		err    string
+	}{
+		{name: "cpu", fields: tsdb.Fields{"value": 1.0, "n": int64(1), "up": true, "s": "x"}, time: time.Unix(0, 0)},
+		{name: "cpu", fields: tsdb.Fields{"value": 1.0}},
+		{name: "", fields: tsdb.Fields{"value": 1.0}, err: "missing measurement"},
+		{name: "cpu", fields: nil, err: "missing fields"},
+		{name: "cpu", fields: tsdb.Fields{"": 1.0}, err: `field "": missing field key`},
+		{name: "cpu", fields: tsdb.Fields{"value": nil}, err: `field "value": unsupported type <nil>`},
+		{name: "cpu", fields: tsdb.Fields{"value": time.Unix(0, 0)}, err: `field "value": unsupported type time.Time`},
+		{name: "cpu", fields: tsdb.Fields{"value": math.Inf(1)}, err: `field "value": +Inf is not a finite number`},
+		{name: "cpu", fields: tsdb.Fields{"value": 1.0}, time: time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC), err: tsdb.ErrTimeOutOfRange.Error()},
+	} {
+		pt, err := tsdb.NewPointChecked(tt.name, nil, tt.fields, tt.time)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%d. unexpected error: %s", i, err)
+			} else if !reflect.DeepEqual(pt.Fields(), tt.fields) {
+				t.Errorf("%d. fields mismatch.\ngot %v\nexp %v", i, pt.Fields(), tt.fields)
+			}
+		} else if err == nil || err.Error() != tt.err {
+			t.Errorf("%d. error mismatch.\ngot %v\nexp %s", i, err, tt.err)
+		}
+	}
+}
+
+func TestPoint_AddTagsAndFields(t *testing.T) {
+	pt := tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+	pt.AddTag("region", "uswest")
+	pt.AddTags(tsdb.NewTags(map[string]string{"host": "serverB", "dc": "a"}))
+	pt.AddField("idle", 2.0)
+	pt.AddFields(tsdb.Fields{"value": 3.0, "busy": true})
+
+	if exp := "cpu,dc=a,host=serverB,region=uswest"; string(pt.Key()) != exp {
+		t.Errorf("Key() mismatch.\ngot %s\nexp %s", pt.Key(), exp)
+	}
+	if exp := tsdb.NewTags(map[string]string{"dc": "a", "host": "serverB", "region": "uswest"}); !reflect.DeepEqual(pt.Tags(), exp) {
+		t.Errorf("Tags() mismatch.\ngot %v\nexp %v", pt.Tags(), exp)
+	}
+	if exp := (tsdb.Fields{"busy": true, "idle": 2.0, "value": 3.0}); !reflect.DeepEqual(pt.Fields(), exp) {
+		t.Errorf("Fields() mismatch.\ngot %v\nexp %v", pt.Fields(), exp)
+	}
+	if exp := `cpu,dc=a,host=serverB,region=uswest busy=true,idle=2.0,value=3.0 0`; pt.String() != exp {
+		t.Errorf("String() mismatch.\ngot %v\nexp %v", pt.String(), exp)
+	}
+
+	// Fields added after they're read are still encoded.
+	pt.AddField("value", 4.0)
+	if exp := `cpu,dc=a,host=serverB,region=uswest busy=true,idle=2.0,value=4.0 0`; pt.String() != exp {
+		t.Errorf("String() mismatch.\ngot %v\nexp %v", pt.String(), exp)
+	}
+}
+
+func BenchmarkPoint_AddField(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pt := tsdb.NewPoint("cpu", nil, nil, time.Unix(0, 0))
+		for j := 0; j < 20; j++ {
+			pt.AddField("value"+strconv.Itoa(j), float64(j))
+		}
+		pt.Key()
+		pt.Fields()
+	}
+}
+
 func TestMakeKeyEscaped(t *testing.T) {
-	if exp, got := `cpu\ load`, tsdb.MakeKey([]byte(`cpu\ load`), tsdb.Tags{}); string(got) != exp {
+	if exp, got := `cpu\ load`, tsdb.MakeKey([]byte(`cpu\ load`), tsdb.Tags(nil)); string(got) != exp {
 		t.Errorf("MakeKey() mismatch.\ngot %v\nexp %v", got, exp)
 	}
 
-	if exp, got := `cpu\ load`, tsdb.MakeKey([]byte(`cpu load`), tsdb.Tags{}); string(got) != exp {
+	if exp, got := `cpu\ load`, tsdb.MakeKey([]byte(`cpu load`), tsdb.Tags(nil)); string(got) != exp {
 		t.Errorf("MakeKey() mismatch.\ngot %v\nexp %v", got, exp)
 	}
 
-	if exp, got := `cpu\,load`, tsdb.MakeKey([]byte(`cpu\,load`), tsdb.Tags{}); string(got) != exp {
+	if exp, got := `cpu\,load`, tsdb.MakeKey([]byte(`cpu\,load`), tsdb.Tags(nil)); string(got) != exp {
 		t.Errorf("MakeKey() mismatch.\ngot %v\nexp %v", got, exp)
 	}
 
-	if exp, got := `cpu\,load`, tsdb.MakeKey([]byte(`cpu,load`), tsdb.Tags{}); string(got) != exp {
+	if exp, got := `cpu\,load`, tsdb.MakeKey([]byte(`cpu,load`), tsdb.Tags(nil)); string(got) != exp {
 		t.Errorf("MakeKey() mismatch.\ngot %v\nexp %v", got, exp)
 	}
 
 }
+
+func TestParseKey(t *testing.T) {
+	key := tsdb.MakeKey([]byte("cpu load"), tsdb.NewTags(map[string]string{"host": "server a", "region": "us,west"}))
+	name, tags := tsdb.ParseKey(string(key))
+	if name != "cpu load" {
+		t.Errorf("ParseKey() name mismatch.\ngot %v\nexp %v", name, "cpu load")
+	}
+	if exp := (tsdb.NewTags(map[string]string{"host": "server a", "region": "us,west"})); !reflect.DeepEqual(tags, exp) {
+		t.Errorf("ParseKey() tags mismatch.\ngot %v\nexp %v", tags, exp)
+	}
+}
+
+// Ensure keys round trip names and tags with escaped and literal backslashes.
+func TestParseKey_Escaped(t *testing.T) {
+	tags := tsdb.NewTags(map[string]string{"a=b": `c:\dir one`, "plain": "x,y=z"})
+	key := tsdb.MakeKey([]byte(`disk\usage,free`), tags)
+	if exp := `disk\usage\,free,a\=b=c:\dir\ one,plain=x\,y\=z`; string(key) != exp {
+		t.Fatalf("MakeKey() mismatch.\ngot %v\nexp %v", string(key), exp)
+	}
+
+	name, got := tsdb.ParseKey(string(key))
+	if exp := `disk\usage,free`; name != exp {
+		t.Errorf("ParseKey() name mismatch.\ngot %v\nexp %v", name, exp)
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Errorf("ParseKey() tags mismatch.\ngot %v\nexp %v", got, tags)
+	}
+}
+
+func TestMergePoints(t *testing.T) {
+	now := time.Unix(0, 0)
+	points := tsdb.MergePoints([]tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"user": 1.0}, now),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverB"}), tsdb.Fields{"user": 2.0}, now),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"system": 3.0}, now),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"user": 4.0}, now.Add(time.Second)),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"user": 5.0}, now),
+	})
+
+	if len(points) != 3 {
+		t.Fatalf("MergePoints() len mismatch: got %d, exp %d", len(points), 3)
+	}
+	if exp := `cpu,host=serverA system=3.0,user=5.0 0`; points[0].String() != exp {
+		t.Errorf("MergePoints() mismatch.\ngot %v\nexp %v", points[0].String(), exp)
+	}
+	if exp := `cpu,host=serverB user=2.0 0`; points[1].String() != exp {
+		t.Errorf("MergePoints() mismatch.\ngot %v\nexp %v", points[1].String(), exp)
+	}
+	if exp := `cpu,host=serverA user=4.0 1000000000`; points[2].String() != exp {
+		t.Errorf("MergePoints() mismatch.\ngot %v\nexp %v", points[2].String(), exp)
+	}
+}
+
+func TestSplitPoints(t *testing.T) {
+	points := []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0)),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverB"}), tsdb.Fields{"value": 2.0}, time.Unix(10, 0)),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 3.0}, time.Unix(3600, 0)),
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 4.0}, time.Unix(20, 0)),
+	}
+
+	groups := tsdb.SplitPoints(points, time.Hour, 1)
+	if len(groups) != 2 {
+		t.Fatalf("SplitPoints() len mismatch: got %d, exp %d", len(groups), 2)
+	}
+	if exp := []tsdb.Point{points[0], points[1], points[3]}; !reflect.DeepEqual(groups[tsdb.PointGroup{Start: time.Unix(0, 0).UTC()}], exp) {
+		t.Errorf("SplitPoints() mismatch.\ngot %v\nexp %v", groups[tsdb.PointGroup{Start: time.Unix(0, 0).UTC()}], exp)
+	}
+	if exp := []tsdb.Point{points[2]}; !reflect.DeepEqual(groups[tsdb.PointGroup{Start: time.Unix(3600, 0).UTC()}], exp) {
+		t.Errorf("SplitPoints() mismatch.\ngot %v\nexp %v", groups[tsdb.PointGroup{Start: time.Unix(3600, 0).UTC()}], exp)
+	}
+
+	// Points of the same series always go to the same shard.
+	groups = tsdb.SplitPoints(points, time.Hour, 3)
+	for k, a := range groups {
+		if k.Shard < 0 || k.Shard >= 3 {
+			t.Fatalf("SplitPoints() shard out of range: %d", k.Shard)
+		}
+		for _, p := range a {
+			if exp := int(p.HashID() % 3); k.Shard != exp {
+				t.Errorf("SplitPoints() shard mismatch for %s: got %d, exp %d", p.String(), k.Shard, exp)
+			}
+			if exp := p.Time().Truncate(time.Hour); !k.Start.Equal(exp) {
+				t.Errorf("SplitPoints() window mismatch for %s: got %s, exp %s", p.String(), k.Start, exp)
+			}
+		}
+	}
+}
+
+// Ensure tags are iterated in key order and unescaped.
+func TestTags_SetGetDelete(t *testing.T) {
+	var tags tsdb.Tags
+	tags.Set("region", "uswest")
+	tags.Set("host", "serverA")
+	tags.Set("dc", "a")
+	tags.Set("host", "serverB")
+
+	exp := tsdb.Tags{{Key: "dc", Value: "a"}, {Key: "host", Value: "serverB"}, {Key: "region", Value: "uswest"}}
+	if !reflect.DeepEqual(tags, exp) {
+		t.Fatalf("unexpected tags: %v", tags)
+	} else if v := tags.Get("host"); v != "serverB" {
+		t.Fatalf("unexpected host: %q", v)
+	} else if v := tags.Get("missing"); v != "" {
+		t.Fatalf("unexpected missing tag: %q", v)
+	}
+
+	tags.Delete("host")
+	tags.Delete("missing")
+	if exp := tsdb.NewTags(map[string]string{"dc": "a", "region": "uswest"}); !reflect.DeepEqual(tags, exp) {
+		t.Fatalf("unexpected tags after delete: %v", tags)
+	}
+}
+
+// Ensure tags that aren't sorted are still found and sorted when changed.
+func TestTags_Unsorted(t *testing.T) {
+	tags := tsdb.Tags{{Key: "region", Value: "uswest"}, {Key: "host", Value: "serverA"}, {Key: "dc", Value: "a"}}
+	for _, tag := range tags {
+		if v := tags.Get(tag.Key); v != tag.Value {
+			t.Fatalf("unexpected %s: %q", tag.Key, v)
+		}
+	}
+
+	tags.Set("host", "serverB")
+	exp := tsdb.Tags{{Key: "dc", Value: "a"}, {Key: "host", Value: "serverB"}, {Key: "region", Value: "uswest"}}
+	if !reflect.DeepEqual(tags, exp) {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+
+	tags = tsdb.Tags{{Key: "region", Value: "uswest"}, {Key: "dc", Value: "a"}}
+	tags.Delete("dc")
+	if exp := tsdb.NewTags(map[string]string{"region": "uswest"}); !reflect.DeepEqual(tags, exp) {
+		t.Fatalf("unexpected tags after delete: %v", tags)
+	}
+}
+
+// Ensure tags that aren't sorted still hash to the sorted key.
+func TestTags_HashKey(t *testing.T) {
+	tags := tsdb.Tags{{Key: "region", Value: "us west"}, {Key: "host", Value: "a,b"}}
+	if got, exp := string(tags.HashKey()), `,host=a\,b,region=us\ west`; got != exp {
+		t.Fatalf("unexpected hash key: got %s, exp %s", got, exp)
+	} else if tags[0].Key != "region" {
+		t.Fatal("tags were sorted in place")
+	}
+}
+
+// Ensure an escaped measurement name isn't escaped again when tags are added.
+func TestPoint_AddTag_EscapedName(t *testing.T) {
+	pts, err := tsdb.ParsePointsString(`cpu\,load,host=a value=1 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := pts[0]
+	pt.AddTag("region", "us west")
+	if got, exp := string(pt.Key()), `cpu\,load,host=a,region=us\ west`; got != exp {
+		t.Fatalf("unexpected key: got %s, exp %s", got, exp)
+	}
+
+	pt.SetTags(tsdb.NewTags(map[string]string{"host": "b"}))
+	if got, exp := string(pt.Key()), `cpu\,load,host=b`; got != exp {
+		t.Fatalf("unexpected key: got %s, exp %s", got, exp)
+	} else if pt.Name() != "cpu,load" {
+		t.Fatalf("unexpected name: %s", pt.Name())
+	}
+}
+
+func TestPoint_ForEachTag(t *testing.T) {
+	pts, err := tsdb.ParsePointsString(`cpu,region=us\,west,host=server\ a value=1 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	pts[0].ForEachTag(func(k, v []byte) bool {
+		got = append(got, string(k)+"="+string(v))
+		return true
+	})
+	if exp := []string{"host=server a", "region=us,west"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("ForEachTag() mismatch.\ngot %v\nexp %v", got, exp)
+	}
+
+	// Iteration stops when fn returns false.
+	got = nil
+	pts[0].ForEachTag(func(k, v []byte) bool {
+		got = append(got, string(k))
+		return false
+	})
+	if exp := []string{"host"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("ForEachTag() mismatch.\ngot %v\nexp %v", got, exp)
+	}
+}
+
+// Ensure HashID is the FNV-1a hash of the key.
+func TestPoint_HashID(t *testing.T) {
+	pt := tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+	h := fnv.New64a()
+	h.Write(pt.Key())
+	if got, exp := pt.HashID(), h.Sum64(); got != exp {
+		t.Fatalf("HashID() mismatch: got %d, exp %d", got, exp)
+	}
+}
+
+// Ensure integer fields keep their type through the line protocol.
+func TestPoint_IntegerFields(t *testing.T) {
+	pt := tsdb.NewPoint("cpu", nil, tsdb.Fields{"i": 42, "i32": int32(7), "i64": int64(-1), "f": 42.0}, time.Unix(0, 0))
+	if exp := `cpu f=42.0,i=42i,i32=7i,i64=-1i 0`; pt.String() != exp {
+		t.Fatalf("String() mismatch.\ngot %v\nexp %v", pt.String(), exp)
+	}
+
+	pts, err := tsdb.ParsePointsString(pt.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := tsdb.Fields{"i": int64(42), "i32": int64(7), "i64": int64(-1), "f": 42.0}
+	if got := pts[0].Fields(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("Fields() mismatch.\ngot %#v\nexp %#v", got, exp)
+	}
+}
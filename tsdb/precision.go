@@ -0,0 +1,60 @@
+package tsdb
+
+import (
+	"fmt"
+	"time"
+)
+
+// Precision is the unit of the timestamps of a write.
+type Precision string
+
+const (
+	Nanosecond  Precision = "n"
+	Microsecond Precision = "u"
+	Millisecond Precision = "ms"
+	Second      Precision = "s"
+	Minute      Precision = "m"
+	Hour        Precision = "h"
+	Week        Precision = "w"
+)
+
+// ParsePrecision returns the precision named by s. An empty string is
+// nanoseconds.
+func ParsePrecision(s string) (Precision, error) {
+	switch p := Precision(s); p {
+	case "":
+		return Nanosecond, nil
+	case Nanosecond, Microsecond, Millisecond, Second, Minute, Hour, Week:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown precision %q: must be one of n, u, ms, s, m, h or w", s)
+}
+
+// Duration returns the length of a unit of the precision. The zero value is
+// nanoseconds.
+func (p Precision) Duration() time.Duration {
+	switch p {
+	case Microsecond:
+		return time.Microsecond
+	case Millisecond:
+		return time.Millisecond
+	case Second:
+		return time.Second
+	case Minute:
+		return time.Minute
+	case Hour:
+		return time.Hour
+	case Week:
+		return 7 * 24 * time.Hour
+	}
+	return time.Nanosecond
+}
+
+// Truncate returns t rounded down to a multiple of the precision. Weeks are
+// truncated to midnight UTC of the preceding Monday.
+func (p Precision) Truncate(t time.Time) time.Time {
+	if d := p.Duration(); d > time.Nanosecond {
+		return t.Truncate(d)
+	}
+	return t
+}
@@ -0,0 +1,72 @@
+package tsdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure precisions are parsed and unknown ones are rejected.
+func TestParsePrecision(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		exp tsdb.Precision
+		err string
+	}{
+		{s: "", exp: tsdb.Nanosecond},
+		{s: "n", exp: tsdb.Nanosecond},
+		{s: "u", exp: tsdb.Microsecond},
+		{s: "ms", exp: tsdb.Millisecond},
+		{s: "s", exp: tsdb.Second},
+		{s: "m", exp: tsdb.Minute},
+		{s: "h", exp: tsdb.Hour},
+		{s: "w", exp: tsdb.Week},
+		{s: "ns", err: `unknown precision "ns": must be one of n, u, ms, s, m, h or w`},
+		{s: "S", err: `unknown precision "S": must be one of n, u, ms, s, m, h or w`},
+	} {
+		p, err := tsdb.ParsePrecision(tt.s)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%d. %q: unexpected error: %v", i, tt.s, err)
+			}
+		} else if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+		} else if p != tt.exp {
+			t.Errorf("%d. %q: unexpected precision: %s", i, tt.s, p)
+		}
+	}
+}
+
+// Ensure times are truncated to the precision, weeks starting on Mondays.
+func TestPrecision_Truncate(t *testing.T) {
+	now := time.Date(2015, 9, 17, 13, 14, 15, 16171819, time.UTC) // a Thursday
+	for i, tt := range []struct {
+		p   tsdb.Precision
+		exp time.Time
+	}{
+		{p: tsdb.Nanosecond, exp: now},
+		{p: tsdb.Microsecond, exp: time.Date(2015, 9, 17, 13, 14, 15, 16171000, time.UTC)},
+		{p: tsdb.Second, exp: time.Date(2015, 9, 17, 13, 14, 15, 0, time.UTC)},
+		{p: tsdb.Hour, exp: time.Date(2015, 9, 17, 13, 0, 0, 0, time.UTC)},
+		{p: tsdb.Week, exp: time.Date(2015, 9, 14, 0, 0, 0, 0, time.UTC)},
+	} {
+		if got := tt.p.Truncate(now); !got.Equal(tt.exp) {
+			t.Errorf("%d. %s: unexpected time: %s", i, tt.p, got)
+		}
+	}
+}
+
+// Ensure parsing fails with an unknown precision and week timestamps are scaled.
+func TestParsePointsWithPrecision_Week(t *testing.T) {
+	if _, err := tsdb.ParsePointsWithPrecision([]byte("cpu value=1 1"), time.Now(), "x"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	pts, err := tsdb.ParsePointsWithPrecision([]byte("cpu value=1 2"), time.Now(), tsdb.Week)
+	if err != nil {
+		t.Fatal(err)
+	} else if ns := pts[0].UnixNano(); ns != int64(14*24*time.Hour) {
+		t.Fatalf("unexpected time: %d", ns)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/influxdb/influxdb/clock"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 )
@@ -41,6 +42,19 @@ type QueryExecutor struct {
 		CreateMapper(shard meta.ShardInfo, stmt string, chunkSize int) (Mapper, error)
 	}
 
+	// MaxRowLimit is the number of values a SELECT statement returns before
+	// the rest are dropped and the result is marked partial. Zero is no limit.
+	MaxRowLimit int
+
+	// NumericTagOrder sorts numeric tag values by their number instead of as
+	// strings in GROUP BY results and SHOW TAG VALUES, so "9" comes before
+	// "10". The rows of a GROUP BY are buffered to be sorted.
+	NumericTagOrder bool
+
+	// Clock is the time of now() in queries and the end of the time range
+	// of queries without one, which selects the shard groups they read.
+	Clock clock.Clock
+
 	Logger *log.Logger
 
 	// the local data store
@@ -51,6 +65,7 @@ type QueryExecutor struct {
 func NewQueryExecutor(store *Store) *QueryExecutor {
 	return &QueryExecutor{
 		Store:  store,
+		Clock:  clock.New(),
 		Logger: log.New(os.Stderr, "[query] ", log.LstdFlags),
 	}
 }
@@ -117,6 +132,13 @@ func (q *QueryExecutor) Authorize(u *meta.UserInfo, query *influxql.Query, datab
 // It sends results down the passed in chan and closes it when done. It will close the chan
 // on the first statement that throws an error.
 func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+	return q.ExecuteQueryWithTrace(query, database, chunkSize, "")
+}
+
+// ExecuteQueryWithTrace executes an InfluxQL query like ExecuteQuery. The
+// trace ID is passed on to remote nodes mapping shards for the query so their
+// log lines can be correlated with the request.
+func (q *QueryExecutor) ExecuteQueryWithTrace(query *influxql.Query, database string, chunkSize int, traceID string) (<-chan *influxql.Result, error) {
 	// Execute each statement. Keep the iterator external so we can
 	// track how many of the statements were executed
 	results := make(chan *influxql.Result)
@@ -143,7 +165,7 @@ func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chu
 			var res *influxql.Result
 			switch stmt := stmt.(type) {
 			case *influxql.SelectStatement:
-				if err := q.executeSelectStatement(i, stmt, results, chunkSize); err != nil {
+				if err := q.executeSelectStatement(i, stmt, results, chunkSize, traceID); err != nil {
 					results <- &influxql.Result{Err: err}
 					break
 				}
@@ -152,6 +174,7 @@ func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chu
 				res = q.executeDropSeriesStatement(stmt, database)
 			case *influxql.ShowSeriesStatement:
 				res = q.executeShowSeriesStatement(stmt, database)
+				q.renameRows(res.Series, database)
 			case *influxql.DropMeasurementStatement:
 				// TODO: handle this in a cluster
 				res = q.executeDropMeasurementStatement(stmt, database)
@@ -159,10 +182,37 @@ func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chu
 				res = q.executeShowMeasurementsStatement(stmt, database)
 			case *influxql.ShowTagKeysStatement:
 				res = q.executeShowTagKeysStatement(stmt, database)
+				q.renameTagKeys(res.Series, database)
+				q.renameRows(res.Series, database)
 			case *influxql.ShowTagValuesStatement:
 				res = q.executeShowTagValuesStatement(stmt, database)
 			case *influxql.ShowFieldKeysStatement:
 				res = q.executeShowFieldKeysStatement(stmt, database)
+				q.renameRows(res.Series, database)
+			case *influxql.RenameMeasurementStatement:
+				res = q.executeRenameMeasurementStatement(stmt, database)
+			case *influxql.RenameTagKeyStatement:
+				if stmt.Database == "" {
+					stmt.Database = database
+				}
+				res = q.MetaStatementExecutor.ExecuteStatement(stmt)
+			case *influxql.RenameTagValueStatement:
+				if stmt.Database == "" {
+					stmt.Database = database
+				}
+				res = q.MetaStatementExecutor.ExecuteStatement(stmt)
+			case *influxql.CreateTagAliasStatement:
+				if stmt.Database == "" {
+					stmt.Database = database
+				}
+				res = q.MetaStatementExecutor.ExecuteStatement(stmt)
+			case *influxql.DropTagAliasStatement:
+				if stmt.Database == "" {
+					stmt.Database = database
+				}
+				res = q.MetaStatementExecutor.ExecuteStatement(stmt)
+			case *influxql.ShowStatsStatement:
+				res = q.executeShowStatsStatement(stmt)
 			case *influxql.ShowDiagnosticsStatement:
 				res = q.executeShowDiagnosticsStatement(stmt)
 			case *influxql.DeleteStatement:
@@ -170,6 +220,8 @@ func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chu
 			case *influxql.DropDatabaseStatement:
 				// TODO: handle this in a cluster
 				res = q.executeDropDatabaseStatement(stmt)
+			case *influxql.RestoreDatabaseStatement:
+				res = q.executeRestoreDatabaseStatement(stmt)
 			default:
 				// Delegate all other meta statements to a separate executor. They don't hit tsdb storage.
 				res = q.MetaStatementExecutor.ExecuteStatement(stmt)
@@ -200,13 +252,30 @@ func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chu
 
 // Plan creates an execution plan for the given SelectStatement and returns an Executor.
 func (q *QueryExecutor) Plan(stmt *influxql.SelectStatement, chunkSize int) (*Executor, error) {
+	return q.plan(stmt, chunkSize, "")
+}
+
+// traceShardMapper is implemented by shard mappers that can pass a trace ID to
+// remote nodes.
+type traceShardMapper interface {
+	CreateMapperWithTrace(shard meta.ShardInfo, stmt string, chunkSize int, traceID string) (Mapper, error)
+}
+
+// dataShardMapper is implemented by shard mappers that can cheaply check
+// whether a shard has data for a statement before mapping it.
+type dataShardMapper interface {
+	ShardHasData(shard meta.ShardInfo, stmt string) (bool, error)
+}
+
+func (q *QueryExecutor) plan(stmt *influxql.SelectStatement, chunkSize int, traceID string) (*Executor, error) {
 	shards := map[uint64]meta.ShardInfo{} // Shards requiring mappers.
 
 	// Replace instances of "now()" with the current time, and check the resultant times.
-	stmt.Condition = influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: time.Now().UTC()})
+	now := q.Clock.Now()
+	stmt.Condition = influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: now.UTC()})
 	tmin, tmax := influxql.TimeRange(stmt.Condition)
 	if tmax.IsZero() {
-		tmax = time.Now()
+		tmax = now
 	}
 	if tmin.IsZero() {
 		tmin = time.Unix(0, 0)
@@ -226,6 +295,10 @@ func (q *QueryExecutor) Plan(stmt *influxql.SelectStatement, chunkSize int) (*Ex
 		}
 		for _, g := range shardGroups {
 			for _, sh := range g.Shards {
+				// Skip shards without points in the time range.
+				if !sh.Overlaps(tmin, tmax) {
+					continue
+				}
 				shards[sh.ID] = sh
 			}
 		}
@@ -234,7 +307,22 @@ func (q *QueryExecutor) Plan(stmt *influxql.SelectStatement, chunkSize int) (*Ex
 	// Build the Mappers, one per shard.
 	mappers := []Mapper{}
 	for _, sh := range shards {
-		m, err := q.ShardMapper.CreateMapper(sh, stmt.String(), chunkSize)
+		// Skip shards without any of the measurements before paying for a mapper.
+		if dm, ok := q.ShardMapper.(dataShardMapper); ok {
+			if ok, err := dm.ShardHasData(sh, stmt.String()); err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+		}
+
+		var m Mapper
+		var err error
+		if tm, ok := q.ShardMapper.(traceShardMapper); ok && traceID != "" {
+			m, err = tm.CreateMapperWithTrace(sh, stmt.String(), chunkSize, traceID)
+		} else {
+			m, err = q.ShardMapper.CreateMapper(sh, stmt.String(), chunkSize)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -250,23 +338,91 @@ func (q *QueryExecutor) Plan(stmt *influxql.SelectStatement, chunkSize int) (*Ex
 }
 
 // executeSelectStatement plans and executes a select statement against a database.
-func (q *QueryExecutor) executeSelectStatement(statementID int, stmt *influxql.SelectStatement, results chan *influxql.Result, chunkSize int) error {
-	// Plan statement execution.
-	e, err := q.Plan(stmt, chunkSize)
+func (q *QueryExecutor) executeSelectStatement(statementID int, stmt *influxql.SelectStatement, results chan *influxql.Result, chunkSize int, traceID string) error {
+	// Replace deprecated tag names before looking up renamed tags, since
+	// aliases map to the current names.
+	aliases := q.selectTagAliases(stmt)
+	if aliases != nil {
+		aliases.rewriteStatement(stmt)
+	}
+
+	// Query renamed tags by the names they're stored with.
+	tags := q.selectTagRenames(stmt)
+	if tags != nil {
+		tags.rewriteStatement(stmt)
+	}
+
+	// Answer queries for the last values of a field from the cache, if
+	// possible, instead of planning them.
+	rows, ok, err := q.lastValueRows(stmt)
 	if err != nil {
 		return err
 	}
+	var e *Executor
+	if !ok {
+		if e, err = q.plan(stmt, chunkSize, traceID); err != nil {
+			return err
+		}
+	}
+
+	// Rows are named after the stored measurement so find the new names of
+	// any renamed measurements.
+	var databases []string
+	for _, src := range stmt.Sources {
+		if m, ok := src.(*influxql.Measurement); ok {
+			databases = append(databases, m.Database)
+		}
+	}
 
 	// Execute plan.
-	ch := e.Execute()
+	var ch <-chan *influxql.Row
+	if e != nil {
+		ch = e.Execute()
+	} else {
+		c := make(chan *influxql.Row, len(rows))
+		for _, row := range rows {
+			c <- row
+		}
+		close(c)
+		ch = c
+	}
+	if q.NumericTagOrder {
+		if _, keys, err := stmt.Dimensions.Normalize(); err == nil && len(keys) > 0 {
+			ch = sortRows(ch, keys)
+		}
+	}
 
 	// Stream results from the channel. We should send an empty result if nothing comes through.
 	resultSent := false
+	var n int
 	for row := range ch {
 		if row.Err != nil {
 			return row.Err
 		}
+		if tags != nil {
+			tags.renameRow(row)
+		}
+		if aliases != nil {
+			aliases.renameRow(row)
+		}
+		q.renameRows([]*influxql.Row{row}, databases...)
 		resultSent = true
+
+		// Truncate the results once over the row limit. The rest of the
+		// rows are drained so the executor can release its mappers.
+		n += len(row.Values)
+		if q.MaxRowLimit > 0 && n > q.MaxRowLimit {
+			res := &influxql.Result{StatementID: statementID, Series: make([]*influxql.Row, 0), Partial: true}
+			if row.Values = row.Values[:len(row.Values)-(n-q.MaxRowLimit)]; len(row.Values) > 0 {
+				res.Series = append(res.Series, row)
+			}
+			results <- res
+			go func() {
+				for _ = range ch {
+				}
+			}()
+			return nil
+		}
 		results <- &influxql.Result{StatementID: statementID, Series: []*influxql.Row{row}}
 	}
 
@@ -277,6 +433,124 @@ func (q *QueryExecutor) executeSelectStatement(statementID int, stmt *influxql.S
 	return nil
 }
 
+// lastValueRows returns the rows of a statement selecting the last value of a
+// field, such as SELECT last(value) FROM cpu GROUP BY *, from the store's last
+// value cache. It returns false if the statement can't be answered from the
+// cache and has to be planned: the statement may only have tags in its WHERE
+// and GROUP BY clauses and no limits, all shards must be local, and the last
+// values must be unambiguous and not expired or in the future.
+func (q *QueryExecutor) lastValueRows(stmt *influxql.SelectStatement) ([]*influxql.Row, bool, error) {
+	if q.Store == nil || q.Store.LastValues == nil {
+		return nil, false, nil
+	}
+
+	if len(stmt.Fields) != 1 || len(stmt.Sources) != 1 || stmt.Target != nil ||
+		stmt.Limit > 0 || stmt.Offset > 0 || stmt.SLimit > 0 || stmt.SOffset > 0 {
+		return nil, false, nil
+	}
+	call, ok := stmt.Fields[0].Expr.(*influxql.Call)
+	if !ok || call.Name != "last" || len(call.Args) != 1 {
+		return nil, false, nil
+	}
+	ref, ok := call.Args[0].(*influxql.VarRef)
+	if !ok {
+		return nil, false, nil
+	}
+	mm, ok := stmt.Sources[0].(*influxql.Measurement)
+	if !ok || mm.Regex != nil {
+		return nil, false, nil
+	}
+
+	db := q.Store.DatabaseIndex(mm.Database)
+	if db == nil {
+		return nil, false, nil
+	}
+	m := db.Measurement(mm.Name)
+	if m == nil || !m.HasField(ref.Val) {
+		return nil, false, nil
+	}
+
+	if stmt.HasDimensionWildcard() {
+		keys, err := m.TagKeys()
+		if err != nil {
+			return nil, false, err
+		}
+		var dimensions influxql.Dimensions
+		for _, k := range keys {
+			dimensions = append(dimensions, &influxql.Dimension{Expr: &influxql.VarRef{Val: k}})
+		}
+		stmt = stmt.RewriteWildcards(nil, dimensions)
+	}
+	d, tagKeys, err := stmt.Dimensions.Normalize()
+	if err != nil || d != 0 {
+		return nil, false, nil
+	}
+	for _, n := range append(tagKeys, stmt.NamesInWhere()...) {
+		if ok, err := m.HasTagKey(n); err != nil {
+			return nil, false, err
+		} else if !ok {
+			return nil, false, nil
+		}
+	}
+
+	// Read the values of the same shards the statement would be mapped to.
+	now := q.Clock.Now()
+	shardGroups, err := q.MetaStore.ShardGroupsByTimeRange(mm.Database, mm.RetentionPolicy, time.Unix(0, 0), now)
+	if err != nil {
+		return nil, false, err
+	}
+	var sets []*lastValueSet
+	for _, g := range shardGroups {
+		for _, sh := range g.Shards {
+			if !sh.OwnedBy(q.MetaStore.NodeID()) {
+				return nil, false, nil
+			}
+			set, err := q.Store.lastValues(sh.ID, m.Name)
+			if err != nil {
+				return nil, false, err
+			} else if set == nil {
+				return nil, false, nil
+			}
+			sets = append(sets, set)
+		}
+	}
+
+	tagSets, err := m.TagSets(stmt, tagKeys)
+	if err != nil {
+		return nil, false, err
+	}
+
+	columns := []string{"time", stmt.Fields[0].Name()}
+	rows := make([]*influxql.Row, 0, len(tagSets))
+	for _, t := range tagSets {
+		for _, f := range t.Filters {
+			if f != nil {
+				return nil, false, nil
+			}
+		}
+
+		v, ok := lastValueOf(sets, t.SeriesKeys, ref.Val)
+		if !ok {
+			// Empty tag sets are left out of the results but a single
+			// one is still returned so it's left to the executor.
+			if len(tagSets) == 1 {
+				return nil, false, nil
+			}
+			continue
+		} else if v.time < 0 || v.time > now.UnixNano() || (v.expires != 0 && v.expires <= now.UnixNano()) {
+			return nil, false, nil
+		}
+
+		rows = append(rows, &influxql.Row{
+			Name:    m.Name,
+			Tags:    t.Tags,
+			Columns: columns,
+			Values:  [][]interface{}{{time.Unix(0, v.time).UTC(), v.value}},
+		})
+	}
+	return rows, true, nil
+}
+
 // expandSources expands regex sources and removes duplicates.
 // NOTE: sources must be normalized (db and rp set) before calling this function.
 func (q *QueryExecutor) expandSources(sources influxql.Sources) (influxql.Sources, error) {
@@ -356,7 +630,12 @@ func (q *QueryExecutor) executeDropDatabaseStatement(stmt *influxql.DropDatabase
 		}
 	}
 
-	err = q.Store.DeleteDatabase(stmt.Name, shardIDs)
+	// Keep the database in the trash if dropped databases can be restored.
+	if q.Store.EngineOptions.Config.TrashRetention > 0 {
+		err = q.Store.TrashDatabase(stmt.Name, shardIDs)
+	} else {
+		err = q.Store.DeleteDatabase(stmt.Name, shardIDs)
+	}
 	if err != nil {
 		return &influxql.Result{Err: err}
 	}
@@ -364,6 +643,30 @@ func (q *QueryExecutor) executeDropDatabaseStatement(stmt *influxql.DropDatabase
 	return q.MetaStatementExecutor.ExecuteStatement(stmt)
 }
 
+// executeRestoreDatabaseStatement moves the local shards of a dropped database
+// out of the trash and opens them. It then calls to the metastore to restore
+// the database there, moving the shards back to the trash if that fails.
+func (q *QueryExecutor) executeRestoreDatabaseStatement(stmt *influxql.RestoreDatabaseStatement) *influxql.Result {
+	dbi, err := q.MetaStore.Database(stmt.Name)
+	if err != nil {
+		return &influxql.Result{Err: err}
+	} else if dbi != nil {
+		return &influxql.Result{Err: meta.ErrDatabaseExists}
+	}
+
+	if err := q.Store.RestoreDatabase(stmt.Name); err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	res := q.MetaStatementExecutor.ExecuteStatement(stmt)
+	if res.Err != nil {
+		if err := q.Store.TrashDatabase(stmt.Name, q.Store.databaseShardIDs(stmt.Name)); err != nil {
+			return &influxql.Result{Err: fmt.Errorf("%s; moving database back to trash: %s", res.Err, err)}
+		}
+	}
+	return res
+}
+
 // executeDropMeasurementStatement removes the measurement and all series data from the local store for the given measurement
 func (q *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasurementStatement, database string) *influxql.Result {
 	// Find the database.
@@ -372,7 +675,7 @@ func (q *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasu
 		return &influxql.Result{}
 	}
 
-	m := db.Measurement(stmt.Name)
+	m := db.Measurement(q.storedMeasurementName(database, stmt.Name))
 	if m == nil {
 		return &influxql.Result{Err: ErrMeasurementNotFound(stmt.Name)}
 	}
@@ -381,7 +684,7 @@ func (q *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasu
 	db.DropMeasurement(m.Name)
 
 	// now drop the raw data
-	if err := q.Store.deleteMeasurement(m.Name, m.SeriesKeys()); err != nil {
+	if err := q.Store.deleteMeasurement(database, m.Name, m.SeriesKeys()); err != nil {
 		return &influxql.Result{Err: err}
 	}
 
@@ -409,11 +712,17 @@ func (q *QueryExecutor) executeDropSeriesStatement(stmt *influxql.DropSeriesStat
 
 	var seriesKeys []string
 	for _, m := range measurements {
+		// Hold the lock so the tag index can't be evicted while it's read.
+		if err := m.rlockLoaded(); err != nil {
+			return &influxql.Result{Err: err}
+		}
+
 		var ids SeriesIDs
 		if stmt.Condition != nil {
 			// Get series IDs that match the WHERE clause.
 			ids, _, err = m.walkWhereForSeriesIds(stmt.Condition)
 			if err != nil {
+				m.mu.RUnlock()
 				return &influxql.Result{Err: err}
 			}
 		} else {
@@ -424,14 +733,13 @@ func (q *QueryExecutor) executeDropSeriesStatement(stmt *influxql.DropSeriesStat
 		for _, id := range ids {
 			seriesKeys = append(seriesKeys, m.seriesByID[id].Key)
 		}
+		m.mu.RUnlock()
 	}
 
-	// delete the raw series data
-	if err := q.Store.deleteSeries(seriesKeys); err != nil {
+	// delete the raw series data and remove them from the index
+	if err := q.Store.DeleteSeries(database, seriesKeys); err != nil {
 		return &influxql.Result{Err: err}
 	}
-	// remove them from the index
-	db.DropSeries(seriesKeys)
 
 	return &influxql.Result{}
 }
@@ -462,17 +770,23 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 
 	// Loop through measurements to build result. One result row / measurement.
 	for _, m := range measurements {
+		if err := m.rlockLoaded(); err != nil {
+			return &influxql.Result{Err: err}
+		}
+
 		var ids SeriesIDs
 
 		if stmt.Condition != nil {
 			// Get series IDs that match the WHERE clause.
 			ids, _, err = m.walkWhereForSeriesIds(stmt.Condition)
 			if err != nil {
+				m.mu.RUnlock()
 				return &influxql.Result{Err: err}
 			}
 
 			// If no series matched, then go to the next measurement.
 			if len(ids) == 0 {
+				m.mu.RUnlock()
 				continue
 			}
 
@@ -485,7 +799,7 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 		// Make a new row for this measurement.
 		r := &influxql.Row{
 			Name:    m.Name,
-			Columns: m.TagKeys(),
+			Columns: m.tagKeys(),
 		}
 
 		// Loop through series IDs getting matching tag sets.
@@ -504,6 +818,8 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 				r.Values = append(r.Values, values)
 			}
 		}
+		m.mu.RUnlock()
+
 		// make the id the first column
 		r.Columns = append([]string{"_key"}, r.Columns...)
 
@@ -567,18 +883,28 @@ func (q *QueryExecutor) executeShowMeasurementsStatement(stmt *influxql.ShowMeas
 		// Otherwise, get all measurements from the database.
 		measurements = db.Measurements()
 	}
-	sort.Sort(measurements)
+
+	// List renamed measurements by their new name.
+	di, _ := q.MetaStore.Database(database)
+	names := make([]string, len(measurements))
+	for i, m := range measurements {
+		names[i] = m.Name
+		if di != nil {
+			names[i] = di.MeasurementName(m.Name)
+		}
+	}
+	sort.Strings(names)
 
 	offset := stmt.Offset
 	limit := stmt.Limit
 
 	// If OFFSET is past the end of the array, return empty results.
-	if offset > len(measurements)-1 {
+	if offset > len(names)-1 {
 		return &influxql.Result{}
 	}
 
 	// Calculate last index based on LIMIT.
-	end := len(measurements)
+	end := len(names)
 	if limit > 0 && offset+limit < end {
 		limit = offset + limit
 	} else {
@@ -593,8 +919,7 @@ func (q *QueryExecutor) executeShowMeasurementsStatement(stmt *influxql.ShowMeas
 
 	// Add one value to the row for each measurement name.
 	for i := offset; i < limit; i++ {
-		m := measurements[i]
-		v := interface{}(m.Name)
+		v := interface{}(names[i])
 		row.Values = append(row.Values, []interface{}{v})
 	}
 
@@ -635,10 +960,13 @@ func (q *QueryExecutor) executeShowTagKeysStatement(stmt *influxql.ShowTagKeysSt
 		// TODO: filter tag keys by stmt.Condition
 
 		// Get the tag keys in sorted order.
-		keys := m.TagKeys()
+		keys, err := m.TagKeys()
+		if err != nil {
+			return &influxql.Result{Err: err}
+		}
 
 		// Convert keys to an [][]interface{}.
-		values := make([][]interface{}, 0, len(m.seriesByTagKeyValue))
+		values := make([][]interface{}, 0, len(keys))
 		for _, k := range keys {
 			v := interface{}(k)
 			values = append(values, []interface{}{v})
@@ -685,17 +1013,23 @@ func (q *QueryExecutor) executeShowTagValuesStatement(stmt *influxql.ShowTagValu
 
 	tagValues := make(map[string]stringSet)
 	for _, m := range measurements {
+		if err := m.rlockLoaded(); err != nil {
+			return &influxql.Result{Err: err}
+		}
+
 		var ids SeriesIDs
 
 		if stmt.Condition != nil {
 			// Get series IDs that match the WHERE clause.
 			ids, _, err = m.walkWhereForSeriesIds(stmt.Condition)
 			if err != nil {
+				m.mu.RUnlock()
 				return &influxql.Result{Err: err}
 			}
 
 			// If no series matched, then go to the next measurement.
 			if len(ids) == 0 {
+				m.mu.RUnlock()
 				continue
 			}
 
@@ -712,6 +1046,7 @@ func (q *QueryExecutor) executeShowTagValuesStatement(stmt *influxql.ShowTagValu
 			}
 			tagValues[k] = tagValues[k].union(v)
 		}
+		m.mu.RUnlock()
 	}
 
 	for k, v := range tagValues {
@@ -721,7 +1056,11 @@ func (q *QueryExecutor) executeShowTagValuesStatement(stmt *influxql.ShowTagValu
 		}
 
 		vals := v.list()
-		sort.Strings(vals)
+		if q.NumericTagOrder {
+			sort.Sort(numericStrings(vals))
+		} else {
+			sort.Strings(vals)
+		}
 
 		for _, val := range vals {
 			v := interface{}(val)
@@ -828,12 +1167,13 @@ func (q *QueryExecutor) normalizeStatement(stmt influxql.Statement, defaultDatab
 		}
 		switch n := n.(type) {
 		case *influxql.Measurement:
+			name := n.Name
 			e := q.normalizeMeasurement(n, defaultDatabase)
 			if e != nil {
 				err = e
 				return
 			}
-			prefixes[n.Name] = n.Name
+			prefixes[name] = n.Name
 		}
 	})
 	if err != nil {
@@ -888,9 +1228,138 @@ func (q *QueryExecutor) normalizeMeasurement(m *influxql.Measurement, defaultDat
 		m.RetentionPolicy = di.DefaultRetentionPolicy
 	}
 
+	// Points of a renamed measurement are stored under its original name.
+	if m.Name != "" {
+		m.Name = di.StoredMeasurementName(m.Name)
+	}
+
+	return nil
+}
+
+// executeRenameMeasurementStatement renames a measurement in the meta store
+// so the new name is used by every node.
+func (q *QueryExecutor) executeRenameMeasurementStatement(stmt *influxql.RenameMeasurementStatement, database string) *influxql.Result {
+	if stmt.Database == "" {
+		stmt.Database = database
+	}
+	if stmt.Database == "" {
+		return &influxql.Result{Err: errors.New("database name required")}
+	}
+
+	// The meta store only knows about renamed measurements so check that the
+	// new name isn't already used by stored points.
+	if db := q.Store.DatabaseIndex(stmt.Database); db != nil && db.Measurement(stmt.NewName) != nil {
+		if q.storedMeasurementName(stmt.Database, stmt.Name) != stmt.NewName {
+			return &influxql.Result{Err: meta.ErrMeasurementExists}
+		}
+	}
+
+	return q.MetaStatementExecutor.ExecuteStatement(stmt)
+}
+
+// storedMeasurementName returns the name the points of a measurement are
+// stored under in database.
+func (q *QueryExecutor) storedMeasurementName(database, name string) string {
+	di, err := q.MetaStore.Database(database)
+	if err != nil || di == nil {
+		return name
+	}
+	return di.StoredMeasurementName(name)
+}
+
+// selectTagRenames returns the renamed tags of the measurements queried by
+// stmt. Returns nil if no tags were renamed.
+func (q *QueryExecutor) selectTagRenames(stmt *influxql.SelectStatement) tagRenames {
+	var a tagRenames
+	for _, src := range stmt.Sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok || m.Name == "" {
+			continue
+		}
+
+		di, err := q.MetaStore.Database(m.Database)
+		if err != nil || di == nil || !di.HasTagRenames(m.Name) {
+			continue
+		}
+		if a == nil {
+			a = make(tagRenames)
+		}
+		a.add(di, m.Name)
+	}
+	return a
+}
+
+// selectTagAliases returns the tag aliases of the database queried by stmt.
+// Returns nil if it has none.
+func (q *QueryExecutor) selectTagAliases(stmt *influxql.SelectStatement) *tagAliases {
+	for _, src := range stmt.Sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok {
+			continue
+		}
+
+		di, err := q.MetaStore.Database(m.Database)
+		if err != nil || di == nil {
+			return nil
+		}
+		return newTagAliases(di)
+	}
 	return nil
 }
 
+// renameTagKeys replaces the stored names of renamed tag keys in the rows of
+// a SHOW TAG KEYS result with their new names.
+func (q *QueryExecutor) renameTagKeys(rows []*influxql.Row, database string) {
+	di, err := q.MetaStore.Database(database)
+	if err != nil || di == nil || len(di.TagRenames) == 0 {
+		return
+	}
+
+	for _, row := range rows {
+		for _, values := range row.Values {
+			if key, ok := values[0].(string); ok {
+				values[0] = di.TagKey(row.Name, key)
+			}
+		}
+	}
+}
+
+// renameRows replaces the stored names of renamed measurements in the
+// databases with their new names.
+func (q *QueryExecutor) renameRows(rows []*influxql.Row, databases ...string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	for _, name := range databases {
+		di, err := q.MetaStore.Database(name)
+		if err != nil || di == nil || len(di.MeasurementRenames) == 0 {
+			continue
+		}
+		for _, row := range rows {
+			if newName, ok := di.MeasurementRenames[row.Name]; ok {
+				row.Name = newName
+			}
+		}
+	}
+}
+
+func (q *QueryExecutor) executeShowStatsStatement(stmt *influxql.ShowStatsStatement) *influxql.Result {
+	var rows influxql.Rows
+
+	// The most written measurements are part of the tsdb stats.
+	if q.Store.WriteStats != nil && (stmt.Module == "" || stmt.Module == "tsdb") {
+		row := &influxql.Row{Name: "write", Columns: []string{"database", "measurement", "points", "bytes", "error"}}
+		for _, st := range q.Store.WriteStats.Top(0) {
+			row.Values = append(row.Values, []interface{}{st.Database, st.Measurement, st.Points, st.Bytes, st.Error})
+		}
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, StatisticsRows(stmt.Module)...)
+	return &influxql.Result{Series: rows}
+}
+
 func (q *QueryExecutor) executeShowDiagnosticsStatement(stmt *influxql.ShowDiagnosticsStatement) *influxql.Result {
 	return &influxql.Result{Err: fmt.Errorf("SHOW DIAGNOSTICS is not implemented yet")}
 }
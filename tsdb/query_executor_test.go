@@ -24,21 +24,21 @@ func TestWritePointsAndExecuteQuery(t *testing.T) {
 	// Write first point.
 	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	// Write second point.
 	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(2, 3),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	got := executeAndGetJSON("SELECT * FROM cpu", executor)
@@ -58,7 +58,7 @@ func TestWritePointsAndExecuteQuery(t *testing.T) {
 	store = tsdb.NewStore(store.Path())
 	store.EngineOptions.Config = conf
 	if err := store.Open(); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	executor.Store = store
 	executor.ShardMapper = &testShardMapper{store: store}
@@ -69,6 +69,144 @@ func TestWritePointsAndExecuteQuery(t *testing.T) {
 	}
 }
 
+// Ensure a renamed measurement is queried and listed by its new name.
+func TestRenamedMeasurement(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu_typo",
+		tsdb.NewTags(map[string]string{"host": "server"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+	executor.MetaStore.(*testMetastore).measurementRenames = map[string]string{"cpu_typo": "cpu"}
+
+	got := executeAndGetJSON("SELECT value FROM cpu", executor)
+	expected := `[{"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+	if expected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", expected, got)
+	}
+
+	got = executeAndGetJSON("SHOW MEASUREMENTS", executor)
+	expected = `[{"series":[{"name":"measurements","columns":["name"],"values":[["cpu"]]}]}]`
+	if expected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", expected, got)
+	}
+}
+
+// Ensure renamed tag keys and values are queried and returned by their new names.
+func TestRenamedTags(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		tsdb.NewTags(map[string]string{"host": "serverA"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+	executor.MetaStore.(*testMetastore).tagRenames = []meta.TagRenameInfo{
+		{Measurement: "cpu", Key: "host", NewName: "hostname"},
+		{Measurement: "cpu", Key: "host", Value: "serverA", NewName: "server-a"},
+	}
+
+	got := executeAndGetJSON("SELECT value FROM cpu WHERE hostname = 'server-a' GROUP BY hostname", executor)
+	expected := `[{"series":[{"name":"cpu","tags":{"hostname":"server-a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+	if expected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", expected, got)
+	}
+
+	// Renamed values are matched in IN lists and regular expressions.
+	for _, cond := range []string{"hostname IN ('server-a', 'server-b')", "hostname =~ /^server-a$/"} {
+		got = executeAndGetJSON("SELECT value FROM cpu WHERE "+cond, executor)
+		expected = `[{"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+		if expected != got {
+			t.Fatalf("%s\nexp: %s\ngot: %s", cond, expected, got)
+		}
+	}
+
+	got = executeAndGetJSON("SHOW TAG KEYS FROM cpu", executor)
+	expected = `[{"series":[{"name":"cpu","columns":["tagKey"],"values":[["hostname"]]}]}]`
+	if expected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", expected, got)
+	}
+}
+
+// Ensure tag aliases are queried by their current names and dimensions keep
+// the alias they were grouped by.
+func TestTagAliases(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		tsdb.NewTags(map[string]string{"datacenter": "us-west-1"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+	executor.MetaStore.(*testMetastore).tagAliases = []meta.TagAliasInfo{
+		{Key: "datacenter", Alias: "dc"},
+		{Key: "datacenter", Value: "us-west-1", Alias: "usw1"},
+	}
+
+	got := executeAndGetJSON("SELECT value FROM cpu WHERE dc = 'usw1' GROUP BY dc", executor)
+	expected := `[{"series":[{"name":"cpu","tags":{"dc":"us-west-1"},"columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+	if expected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", expected, got)
+	}
+
+	got = executeAndGetJSON("SELECT value FROM cpu WHERE datacenter = 'usw1' GROUP BY datacenter", executor)
+	expected = `[{"series":[{"name":"cpu","tags":{"datacenter":"us-west-1"},"columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+	if expected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", expected, got)
+	}
+
+	// Aliased values are matched in IN lists and regular expressions.
+	for _, cond := range []string{"dc IN ('usw1')", "dc =~ /^usw/"} {
+		got = executeAndGetJSON("SELECT value FROM cpu WHERE "+cond, executor)
+		expected = `[{"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+		if expected != got {
+			t.Fatalf("%s\nexp: %s\ngot: %s", cond, expected, got)
+		}
+	}
+}
+
+// Ensure SHOW STATS returns the statistics of a single module.
+func TestShowStats_Module(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		tsdb.NewTags(map[string]string{"host": "serverA"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := executeAndGetJSON("SHOW STATS FOR 'tsdb'", executor)
+	if exp := `{"name":"shard","tags":{"database":"foo","id":"1"},"columns":["fieldsCreated","pointsWritten","seriesCreated","writeReq"]`; !strings.Contains(got, exp) {
+		t.Fatalf("expected %s in: %s", exp, got)
+	} else if strings.Contains(got, `"name":"wal"`) {
+		t.Fatalf("unexpected wal stats: %s", got)
+	}
+
+	got = executeAndGetJSON("SHOW STATS FOR 'wal'", executor)
+	if !strings.Contains(got, `"name":"wal"`) {
+		t.Fatalf("expected wal stats: %s", got)
+	} else if strings.Contains(got, `"name":"shard"`) {
+		t.Fatalf("unexpected shard stats: %s", got)
+	}
+}
+
 // Ensure writing a point and updating it results in only a single point.
 func TestWritePointsAndExecuteQuery_Update(t *testing.T) {
 	store, executor := testStoreAndExecutor()
@@ -77,11 +215,11 @@ func TestWritePointsAndExecuteQuery_Update(t *testing.T) {
 	// Write original point.
 	if err := store.WriteToShard(1, []tsdb.Point{tsdb.NewPoint(
 		"temperature",
-		map[string]string{},
+		tsdb.NewTags(map[string]string{}),
 		map[string]interface{}{"value": 100.0},
 		time.Unix(0, 0),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	// Restart store.
@@ -90,7 +228,7 @@ func TestWritePointsAndExecuteQuery_Update(t *testing.T) {
 	store = tsdb.NewStore(store.Path())
 	store.EngineOptions.Config = conf
 	if err := store.Open(); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 	executor.Store = store
 	executor.ShardMapper = &testShardMapper{store: store}
@@ -98,11 +236,11 @@ func TestWritePointsAndExecuteQuery_Update(t *testing.T) {
 	// Rewrite point with new value.
 	if err := store.WriteToShard(1, []tsdb.Point{tsdb.NewPoint(
 		"temperature",
-		map[string]string{},
+		tsdb.NewTags(map[string]string{}),
 		map[string]interface{}{"value": 200.0},
 		time.Unix(0, 0),
 	)}); err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	got := executeAndGetJSON("select * from temperature", executor)
@@ -112,20 +250,193 @@ func TestWritePointsAndExecuteQuery_Update(t *testing.T) {
 	}
 }
 
+// Ensure shards without points in the time range of a query aren't mapped.
+func TestWritePointsAndExecuteQuery_ShardTimeRange(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		tsdb.NewTags(map[string]string{"host": "server"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(100, 0),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+
+	executor.MetaStore = &testMetastore{shardMinTime: time.Unix(100, 0), shardMaxTime: time.Unix(200, 0)}
+	got := executeAndGetJSON("SELECT value FROM cpu WHERE time >= 100s", executor)
+	exepected := `[{"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:01:40Z",1]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	// The shard is skipped if its recorded time range is outside the query.
+	executor.MetaStore = &testMetastore{shardMinTime: time.Unix(150, 0), shardMaxTime: time.Unix(200, 0)}
+	got = executeAndGetJSON("SELECT value FROM cpu WHERE time < 120s", executor)
+	exepected = `[{}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+}
+
+// Ensure results over the row limit are truncated and marked partial.
+func TestWritePointsAndExecuteQuery_MaxRowLimit(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+	executor.MaxRowLimit = 3
+
+	for _, host := range []string{"serverA", "serverB"} {
+		for i := 1; i <= 2; i++ {
+			if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+				"cpu",
+				tsdb.NewTags(map[string]string{"host": host}),
+				map[string]interface{}{"value": float64(i)},
+				time.Unix(int64(i), 0),
+			)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got := executeAndGetJSON("SELECT value FROM cpu GROUP BY host", executor)
+	exepected := `[{"series":[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1],["1970-01-01T00:00:02Z",2]]}]},{"series":[{"name":"cpu","tags":{"host":"serverB"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}],"partial":true}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	// Results within the limit aren't partial.
+	got = executeAndGetJSON("SELECT value FROM cpu WHERE host = 'serverA'", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1],["1970-01-01T00:00:02Z",2]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+}
+
+// Ensure numeric tag values are ordered as numbers with NumericTagOrder.
+// Ensure a WHERE clause can select series by a list of tag values.
+func TestWritePointsAndExecuteQuery_TagIn(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	for _, host := range []string{"serverA", "serverB", "serverC"} {
+		if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+			"cpu",
+			tsdb.NewTags(map[string]string{"host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := executeAndGetJSON("SELECT value FROM cpu WHERE host IN ('serverA', 'serverC', 'serverD') GROUP BY host", executor)
+	exepected := `[{"series":[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}]},{"series":[{"name":"cpu","tags":{"host":"serverC"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+}
+
+// Ensure last values are read from the shard the first time they're queried
+// and kept up to date by writes when the last value cache is enabled.
+func TestWritePointsAndExecuteQuery_LastValueCache(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	write := func(host string, value float64, ts time.Time) {
+		if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+			"cpu",
+			tsdb.NewTags(map[string]string{"host": host}),
+			map[string]interface{}{"value": value},
+			ts,
+		)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("serverA", 1, time.Unix(2, 0))
+	write("serverA", 2, time.Unix(1, 0))
+	write("serverB", 3, time.Unix(1, 0))
+
+	store.LastValues = tsdb.NewLastValueCache()
+	write("serverB", 4, time.Unix(3, 0))
+
+	got := executeAndGetJSON("SELECT last(value) FROM cpu GROUP BY *", executor)
+	exepected := `[{"series":[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","last"],"values":[["1970-01-01T00:00:02Z",1]]}]},{"series":[{"name":"cpu","tags":{"host":"serverB"},"columns":["time","last"],"values":[["1970-01-01T00:00:03Z",4]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	write("serverA", 5, time.Unix(4, 0))
+	got = executeAndGetJSON("SELECT last(value) AS v FROM cpu WHERE host = 'serverA'", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["time","v"],"values":[["1970-01-01T00:00:04Z",5]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	got = executeAndGetJSON("SELECT last(value) FROM cpu", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["time","last"],"values":[["1970-01-01T00:00:04Z",5]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	executeAndGetJSON("DROP SERIES FROM cpu WHERE host = 'serverA'", executor)
+	got = executeAndGetJSON("SELECT last(value) FROM cpu GROUP BY *", executor)
+	exepected = `[{"series":[{"name":"cpu","tags":{"host":"serverB"},"columns":["time","last"],"values":[["1970-01-01T00:00:03Z",4]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+}
+
+func TestWritePointsAndExecuteQuery_NumericTagOrder(t *testing.T) {
+	store, executor := testStoreAndExecutor()
+	defer os.RemoveAll(store.Path())
+
+	for i, host := range []string{"a", "10", "9"} {
+		if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+			"cpu",
+			tsdb.NewTags(map[string]string{"host": host}),
+			map[string]interface{}{"value": float64(i)},
+			time.Unix(1, 0),
+		)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := executeAndGetJSON("SHOW TAG VALUES FROM cpu WITH KEY = host", executor)
+	exepected := `[{"series":[{"name":"hostTagValues","columns":["host"],"values":[["10"],["9"],["a"]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	executor.NumericTagOrder = true
+	got = executeAndGetJSON("SELECT value FROM cpu GROUP BY host", executor)
+	exepected = `[{"series":[{"name":"cpu","tags":{"host":"9"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",2]]}]},{"series":[{"name":"cpu","tags":{"host":"10"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}]},{"series":[{"name":"cpu","tags":{"host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",0]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+
+	got = executeAndGetJSON("SHOW TAG VALUES FROM cpu WITH KEY = host", executor)
+	exepected = `[{"series":[{"name":"hostTagValues","columns":["host"],"values":[["9"],["10"],["a"]]}]}]`
+	if exepected != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exepected, got)
+	}
+}
+
 func TestDropSeriesStatement(t *testing.T) {
 	store, executor := testStoreAndExecutor()
 	defer os.RemoveAll(store.Path())
 
 	pt := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)
 
 	err := store.WriteToShard(shardID, []tsdb.Point{pt})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	got := executeAndGetJSON("SELECT * FROM cpu GROUP BY *", executor)
@@ -174,13 +485,13 @@ func TestDropMeasurementStatement(t *testing.T) {
 
 	pt := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)
 	pt2 := tsdb.NewPoint(
 		"memory",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)
@@ -244,7 +555,7 @@ func TestDropDatabase(t *testing.T) {
 
 	pt := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)
@@ -391,13 +702,23 @@ func executeAndGetJSON(query string, executor *tsdb.QueryExecutor) string {
 }
 
 type testMetastore struct {
-	userCount int
+	userCount          int
+	measurementRenames map[string]string
+	tagRenames         []meta.TagRenameInfo
+	tagAliases         []meta.TagAliasInfo
+
+	// time range of the points in the shard, if set
+	shardMinTime time.Time
+	shardMaxTime time.Time
 }
 
 func (t *testMetastore) Database(name string) (*meta.DatabaseInfo, error) {
 	return &meta.DatabaseInfo{
 		Name: name,
 		DefaultRetentionPolicy: "foo",
+		MeasurementRenames:     t.measurementRenames,
+		TagRenames:             t.tagRenames,
+		TagAliases:             t.tagAliases,
 		RetentionPolicies: []meta.RetentionPolicyInfo{
 			{
 				Name: "bar",
@@ -463,8 +784,11 @@ func (t *testMetastore) ShardGroupsByTimeRange(database, policy string, min, max
 			EndTime:   time.Now().Add(time.Hour),
 			Shards: []meta.ShardInfo{
 				{
-					ID:       uint64(1),
-					OwnerIDs: []uint64{1},
+					ID:           uint64(1),
+					OwnerIDs:     []uint64{1},
+					HasTimeRange: !t.shardMaxTime.IsZero(),
+					MinTime:      t.shardMinTime,
+					MaxTime:      t.shardMaxTime,
 				},
 			},
 		},
@@ -484,6 +808,10 @@ func (t *testShardMapper) CreateMapper(shard meta.ShardInfo, stmt string, chunkS
 	return m, err
 }
 
+func (t *testShardMapper) ShardHasData(shard meta.ShardInfo, stmt string) (bool, error) {
+	return t.store.ShardHasData(shard.ID, stmt)
+}
+
 // MustParseQuery parses an InfluxQL query. Panic on error.
 func mustParseQuery(s string) *influxql.Query {
 	q, err := influxql.NewParser(strings.NewReader(s)).ParseQuery()
@@ -6,9 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math"
 	"os"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/tsdb/internal"
@@ -32,6 +36,14 @@ var (
 	ErrFieldUnmappedID = errors.New("field ID not mapped")
 )
 
+// PointsRejectedError is returned when points are rejected for a reason that
+// retrying the write won't fix, such as a field type conflict.
+type PointsRejectedError struct {
+	Err error
+}
+
+func (e *PointsRejectedError) Error() string { return e.Err.Error() }
+
 // Shard represents a self-contained time series database. An inverted index of
 // the measurement and tag data is kept along with the raw time series data.
 // Data can be split across many shards. The query engine in TSDB is responsible
@@ -43,12 +55,27 @@ type Shard struct {
 	walPath string
 	id      uint64
 
+	// database is the name of the database the shard belongs to, if known.
+	database string
+
 	engine  Engine
 	options EngineOptions
+	stats   *Statistics
 
 	mu                sync.RWMutex
 	measurementFields map[string]*MeasurementFields // measurement name to their fields
 
+	// The engine is closed while the shard is idle to stay within the store's
+	// file budget. refs counts the callers using the engine.
+	budget   *fileBudget
+	openMu   sync.Mutex
+	idle     bool
+	refs     int
+	lastUsed int64
+
+	// queryStats, if set, counts the series read by queries.
+	queryStats *QueryStats
+
 	// The writer used by the logger.
 	LogOutput io.Writer
 }
@@ -104,29 +131,154 @@ func (s *Shard) Open() error {
 			return fmt.Errorf("load metadata index: %s", err)
 		}
 
+		tags := map[string]string{"id": strconv.FormatUint(s.id, 10)}
+		if s.database != "" {
+			tags["database"] = s.database
+		}
+		s.stats = NewStatistics("tsdb", "shard", tags)
+
 		return nil
 	}(); err != nil {
 		s.close()
 		return err
 	}
 
-	return nil
+	return s.index.EvictIndexes()
 }
 
 // Close shuts down the shard's store.
 func (s *Shard) Close() error {
+	s.budget.remove(s)
+
+	s.openMu.Lock()
+	defer s.openMu.Unlock()
+	s.idle = false
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.close()
 }
 
 func (s *Shard) close() error {
+	if s.stats != nil {
+		UnregisterStatistics(s.stats)
+	}
 	if s.engine != nil {
 		return s.engine.Close()
 	}
 	return nil
 }
 
+// WarmUp loads the tag indexes of the series' measurements and reads the
+// first block of each series so queries don't wait on disk for them.
+func (s *Shard) WarmUp(keys []string) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+
+	for _, key := range keys {
+		if ss := s.index.Series(key); ss != nil && ss.measurement != nil {
+			if err := ss.measurement.ensureLoaded(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.index.EvictIndexes(); err != nil {
+		return err
+	}
+
+	tx, err := s.engine.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, key := range keys {
+		if c := tx.Cursor(key); c != nil {
+			c.Seek(u64tob(0))
+		}
+	}
+	return nil
+}
+
+// SeriesIterFunc is called by IterateSeries for each point of a series.
+// Returning an error stops the iteration and the error is returned.
+type SeriesIterFunc func(key string, t time.Time, fields map[string]interface{}) error
+
+// IterateSeries calls fn for every point in the shard, ordered by series key
+// and then time. It reads a snapshot of the shard so writes made while it's
+// iterating aren't seen.
+func (s *Shard) IterateSeries(fn SeriesIterFunc) error {
+	return s.iterateSeries(s.series(), fn)
+}
+
+// iterateSeries calls fn for every point of a list of series in the shard.
+func (s *Shard) iterateSeries(series []*Series, fn SeriesIterFunc) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+
+	tx, err := s.engine.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, ss := range series {
+		c := tx.Cursor(ss.Key)
+		if c == nil {
+			continue
+		}
+
+		codec := s.FieldCodec(ss.measurement.Name)
+		for k, v := c.Seek(u64tob(0)); k != nil; k, v = c.Next() {
+			fields, err := codec.DecodeFieldsWithNames(v)
+			if err != nil {
+				return fmt.Errorf("decode %s: %s", ss.Key, err)
+			}
+			if err := fn(ss.Key, time.Unix(0, int64(btou64(k))).UTC(), fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// series returns the series written to the shard, sorted by key.
+func (s *Shard) series() []*Series {
+	s.index.mu.RLock()
+	defer s.index.mu.RUnlock()
+
+	var a []*Series
+	for _, ss := range s.index.series {
+		if ss.shardIDs[s.id] {
+			a = append(a, ss)
+		}
+	}
+	sort.Sort(seriesByKey(a))
+	return a
+}
+
+// measurementSeries returns the series of a measurement written to the shard,
+// sorted by key.
+func (s *Shard) measurementSeries(name string) []*Series {
+	var a []*Series
+	for _, ss := range s.series() {
+		if ss.measurement.Name == name {
+			a = append(a, ss)
+		}
+	}
+	return a
+}
+
+type seriesByKey []*Series
+
+func (a seriesByKey) Len() int           { return len(a) }
+func (a seriesByKey) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a seriesByKey) Less(i, j int) bool { return a[i].Key < a[j].Key }
+
 // TODO: this is temporarily exported to make tx.go work. When the query engine gets refactored
 // into the tsdb package this should be removed. No one outside tsdb should know the underlying field encoding scheme.
 func (s *Shard) FieldCodec(measurementName string) *FieldCodec {
@@ -139,6 +291,32 @@ func (s *Shard) FieldCodec(measurementName string) *FieldCodec {
 	return m.Codec
 }
 
+// hasSources returns true if points of one of the measurements of sources
+// were written to the shard.
+func (s *Shard) hasSources(sources influxql.Sources) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, src := range sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok {
+			return true
+		}
+
+		if m.Regex == nil {
+			if s.measurementFields[m.Name] != nil {
+				return true
+			}
+			continue
+		}
+		for name := range s.measurementFields {
+			if m.Regex.Val.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // struct to hold information for a field to create on a measurement
 type FieldCreate struct {
 	Measurement string
@@ -153,9 +331,31 @@ type SeriesCreate struct {
 
 // WritePoints will write the raw data points and any new metadata to the index in the shard
 func (s *Shard) WritePoints(points []Point) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+
+	if err := s.writePoints(points); err != nil {
+		return err
+	}
+
+	// New series may have pushed the index over its memory limit. The points
+	// are already written so failing to evict doesn't fail the write.
+	if err := s.index.EvictIndexes(); err != nil {
+		log.New(s.LogOutput, "[shard] ", log.LstdFlags).Printf("evict index: %s", err)
+	}
+	return nil
+}
+
+func (s *Shard) writePoints(points []Point) error {
+	// Keep new series from being evicted before the engine has saved them.
+	s.index.evictMu.RLock()
+	defer s.index.evictMu.RUnlock()
+
 	seriesToCreate, fieldsToCreate, seriesToAddShardTo, err := s.validateSeriesAndFields(points)
 	if err != nil {
-		return err
+		return &PointsRejectedError{Err: err}
 	}
 
 	// add any new series to the in-memory index
@@ -201,7 +401,7 @@ func (s *Shard) WritePoints(points []Point) error {
 			return ErrFieldNotFound
 		}
 
-		data, err := mf.Codec.EncodeFields(p.Fields())
+		data, err := mf.Codec.EncodeFieldIterator(p.FieldIterator())
 		if err != nil {
 			return err
 		}
@@ -213,6 +413,11 @@ func (s *Shard) WritePoints(points []Point) error {
 		return fmt.Errorf("engine: %s", err)
 	}
 
+	s.stats.Add("writeReq", 1)
+	s.stats.Add("pointsWritten", int64(len(points)))
+	s.stats.Add("seriesCreated", int64(len(seriesToCreate)))
+	s.stats.Add("fieldsCreated", int64(len(fieldsToCreate)))
+
 	return nil
 }
 
@@ -270,11 +475,20 @@ func (s *Shard) ValidateAggregateFieldsInStatement(measurementName string, stmt
 
 // DeleteSeries deletes a list of series.
 func (s *Shard) DeleteSeries(keys []string) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
 	return s.engine.DeleteSeries(keys)
 }
 
 // DeleteMeasurement deletes a measurement and all underlying series.
 func (s *Shard) DeleteMeasurement(name string, seriesKeys []string) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -340,10 +554,14 @@ func (s *Shard) validateSeriesAndFields(points []Point) ([]*SeriesCreate, []*Fie
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	// types of the fields created by this batch, keyed by measurement and
+	// field name, so its points can't create a field with different types
+	var newFieldTypes map[string]influxql.DataType
+
 	for _, p := range points {
 		// see if the series should be added to the index
 		if ss := s.index.series[string(p.Key())]; ss == nil {
-			series := NewSeries(string(p.Key()), p.Tags())
+			series := NewSeries(string(p.Key()), p.Tags().Map())
 			seriesToCreate = append(seriesToCreate, &SeriesCreate{p.Name(), series})
 			seriesToAddShardTo = append(seriesToAddShardTo, series.Key)
 		} else if !ss.shardIDs[s.id] {
@@ -354,25 +572,48 @@ func (s *Shard) validateSeriesAndFields(points []Point) ([]*SeriesCreate, []*Fie
 
 		// see if the field definitions need to be saved to the shard
 		mf := s.measurementFields[p.Name()]
-		if mf == nil {
-			for name, value := range p.Fields() {
-				fieldsToCreate = append(fieldsToCreate, &FieldCreate{p.Name(), &Field{Name: name, Type: influxql.InspectDataType(value)}})
-			}
-			continue // skip validation since all fields are new
-		}
 
-		// validate field types and encode data
-		for name, value := range p.Fields() {
-			if f := mf.Fields[name]; f != nil {
-				// Field present in shard metadata, make sure there is no type conflict.
-				if f.Type != influxql.InspectDataType(value) {
-					return nil, nil, nil, fmt.Errorf("field type conflict: input field \"%s\" on measurement \"%s\" is type %T, already exists as type %s", name, p.Name(), value, f.Type)
+		// validate field types and encode data. The fields are read with an
+		// iterator so they're only decoded when there's a type conflict.
+		it := p.FieldIterator()
+		for it.Next() {
+			name, typ := string(it.Name()), it.Type()
+			if mf != nil {
+				if f := mf.Fields[name]; f != nil {
+					// Field present in shard metadata, make sure there is no type conflict.
+					if f.Type != typ {
+						value, err := it.Interface()
+						if err != nil {
+							return nil, nil, nil, fmt.Errorf("field %q: %s", it.Name(), err)
+						}
+						return nil, nil, nil, fmt.Errorf("field type conflict: input field \"%s\" on measurement \"%s\" is type %T, already exists as type %s", name, p.Name(), value, f.Type)
+					}
+
+					continue // Field is present, and it's of the same type. Nothing more to do.
 				}
+			}
+
+			// The field is new, so make sure its value decodes before the
+			// field is created with its type.
+			if _, err := it.Interface(); err != nil {
+				return nil, nil, nil, fmt.Errorf("field %q: %s", it.Name(), err)
+			}
 
-				continue // Field is present, and it's of the same type. Nothing more to do.
+			// Make sure an earlier point didn't create it with another type,
+			// such as value=1i and value=1.
+			key := p.Name() + "\x00" + name
+			if t, ok := newFieldTypes[key]; ok {
+				if t != typ {
+					return nil, nil, nil, fmt.Errorf("field type conflict: input field \"%s\" on measurement \"%s\" is type %s and %s in the same batch", name, p.Name(), t, typ)
+				}
+				continue
+			}
+			if newFieldTypes == nil {
+				newFieldTypes = make(map[string]influxql.DataType)
 			}
+			newFieldTypes[key] = typ
 
-			fieldsToCreate = append(fieldsToCreate, &FieldCreate{p.Name(), &Field{Name: name, Type: influxql.InspectDataType(value)}})
+			fieldsToCreate = append(fieldsToCreate, &FieldCreate{p.Name(), &Field{Name: name, Type: typ}})
 		}
 	}
 
@@ -380,7 +621,13 @@ func (s *Shard) validateSeriesAndFields(points []Point) ([]*SeriesCreate, []*Fie
 }
 
 // SeriesCount returns the number of series buckets on the shard.
-func (s *Shard) SeriesCount() (int, error) { return s.engine.SeriesCount() }
+func (s *Shard) SeriesCount() (int, error) {
+	if err := s.acquire(); err != nil {
+		return 0, err
+	}
+	defer s.release()
+	return s.engine.SeriesCount()
+}
 
 type MeasurementFields struct {
 	Fields map[string]*Field `json:"fields"`
@@ -545,6 +792,66 @@ func (f *FieldCodec) EncodeFields(values map[string]interface{}) ([]byte, error)
 	return b, nil
 }
 
+// EncodeFieldIterator encodes the fields read from an iterator like
+// EncodeFields, without decoding them into a map first.
+func (f *FieldCodec) EncodeFieldIterator(it FieldIterator) ([]byte, error) {
+	b := make([]byte, 0, 10)
+	var buf [8]byte
+
+	for it.Next() {
+		field := f.fieldsByName[string(it.Name())]
+		if field == nil {
+			panic(fmt.Sprintf("field does not exist for %s", it.Name()))
+		} else if it.Type() != field.Type {
+			v, err := it.Interface()
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %s", it.Name(), err)
+			}
+			return nil, fmt.Errorf("field \"%s\" is type %T, mapped as type %s", it.Name(), v, field.Type)
+		}
+
+		// Always set the field ID as the leading byte.
+		b = append(b, field.ID)
+
+		switch field.Type {
+		case influxql.Float:
+			value, err := it.FloatValue()
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %s", it.Name(), err)
+			}
+			binary.BigEndian.PutUint64(buf[:], math.Float64bits(value))
+			b = append(b, buf[:]...)
+		case influxql.Integer:
+			value, err := it.IntegerValue()
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %s", it.Name(), err)
+			}
+			binary.BigEndian.PutUint64(buf[:], uint64(value))
+			b = append(b, buf[:]...)
+		case influxql.Boolean:
+			value, err := it.BooleanValue()
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %s", it.Name(), err)
+			}
+			if value {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		case influxql.String:
+			value := it.StringValue()
+			if len(value) > maxStringLength {
+				value = value[:maxStringLength]
+			}
+			binary.BigEndian.PutUint16(buf[:2], uint16(len(value)))
+			b = append(b, buf[:2]...)
+			b = append(b, value...)
+		}
+	}
+
+	return b, nil
+}
+
 // TODO: this shouldn't be exported. remove when tx.go and engine.go get refactored into tsdb
 func (f *FieldCodec) FieldIDByName(s string) (uint8, error) {
 	fi := f.fieldsByName[s]
@@ -628,6 +935,68 @@ func (f *FieldCodec) DecodeFieldsWithNames(b []byte) (map[string]interface{}, er
 	return m, nil
 }
 
+// DecodeFieldsByNames decodes only the named fields from a byte slice into a set of
+// field names and values. Other fields are skipped without being decoded.
+func (f *FieldCodec) DecodeFieldsByNames(names []string, b []byte) (map[string]interface{}, error) {
+	// Look up the IDs of the requested fields. Fields unknown to the codec can't be in the data.
+	ids := make([]uint8, 0, len(names))
+	for _, name := range names {
+		if fi := f.fieldByName(name); fi != nil {
+			ids = append(ids, fi.ID)
+		}
+	}
+
+	m := make(map[string]interface{}, len(ids))
+	for len(b) > 0 && len(m) < len(ids) {
+		field := f.fieldsByID[b[0]]
+		if field == nil {
+			// See note in DecodeByID() regarding field-mapping failures.
+			return nil, ErrFieldUnmappedID
+		}
+
+		// Determine the size of the field value.
+		var n int
+		switch field.Type {
+		case influxql.Float, influxql.Integer:
+			n = 9
+		case influxql.Boolean:
+			n = 2
+		case influxql.String:
+			n = int(binary.BigEndian.Uint16(b[1:3])) + 3
+		default:
+			panic(fmt.Sprintf("unsupported value type during decode fields by names: %T", field.Type))
+		}
+
+		if containsFieldID(ids, field.ID) {
+			switch field.Type {
+			case influxql.Float:
+				m[field.Name] = math.Float64frombits(binary.BigEndian.Uint64(b[1:9]))
+			case influxql.Integer:
+				m[field.Name] = int64(binary.BigEndian.Uint64(b[1:9]))
+			case influxql.Boolean:
+				m[field.Name] = b[1] == 1
+			case influxql.String:
+				m[field.Name] = string(b[3:n])
+			}
+		}
+
+		// Move bytes forward.
+		b = b[n:]
+	}
+
+	return m, nil
+}
+
+// containsFieldID returns true if id is in a.
+func containsFieldID(a []uint8, id uint8) bool {
+	for _, v := range a {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
 // DecodeByID scans a byte slice for a field with the given ID, converts it to its
 // expected type, and return that value.
 // TODO: shouldn't be exported. refactor engine
@@ -7,9 +7,11 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/tsdb"
 	"github.com/influxdb/influxdb/tsdb/engine/b1"
 )
@@ -31,20 +33,20 @@ func TestShardWriteAndIndex(t *testing.T) {
 
 	pt := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)
 
 	err := sh.WritePoints([]tsdb.Point{pt})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	pt.SetTime(time.Unix(2, 3))
 	err = sh.WritePoints([]tsdb.Point{pt})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	validateIndex := func() {
@@ -56,10 +58,12 @@ func TestShardWriteAndIndex(t *testing.T) {
 		}
 
 		seriesTags := index.Series(string(pt.Key())).Tags
-		if len(seriesTags) != len(pt.Tags()) || pt.Tags()["host"] != seriesTags["host"] {
+		if len(seriesTags) != len(pt.Tags()) || pt.Tags().Get("host") != seriesTags["host"] {
 			t.Fatalf("tags weren't properly saved to series index: %v, %v", pt.Tags(), seriesTags)
 		}
-		if !reflect.DeepEqual(index.Measurement("cpu").TagKeys(), []string{"host"}) {
+		if keys, err := index.Measurement("cpu").TagKeys(); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(keys, []string{"host"}) {
 			t.Fatalf("tag key wasn't saved to measurement index")
 		}
 	}
@@ -81,7 +85,7 @@ func TestShardWriteAndIndex(t *testing.T) {
 	pt.SetTime(time.Unix(2, 6))
 	err = sh.WritePoints([]tsdb.Point{pt})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 }
 
@@ -103,26 +107,26 @@ func TestShardWriteAddNewField(t *testing.T) {
 
 	pt := tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0},
 		time.Unix(1, 2),
 	)
 
 	err := sh.WritePoints([]tsdb.Point{pt})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	pt = tsdb.NewPoint(
 		"cpu",
-		map[string]string{"host": "server"},
+		tsdb.NewTags(map[string]string{"host": "server"}),
 		map[string]interface{}{"value": 1.0, "value2": 2.0},
 		time.Unix(1, 2),
 	)
 
 	err = sh.WritePoints([]tsdb.Point{pt})
 	if err != nil {
-		t.Fatalf(err.Error())
+		t.Fatal(err)
 	}
 
 	if !reflect.DeepEqual(index.Names(), []string{"cpu"}) {
@@ -132,10 +136,12 @@ func TestShardWriteAddNewField(t *testing.T) {
 		t.Fatalf("series wasn't in index")
 	}
 	seriesTags := index.Series(string(pt.Key())).Tags
-	if len(seriesTags) != len(pt.Tags()) || pt.Tags()["host"] != seriesTags["host"] {
+	if len(seriesTags) != len(pt.Tags()) || pt.Tags().Get("host") != seriesTags["host"] {
 		t.Fatalf("tags weren't properly saved to series index: %v, %v", pt.Tags(), seriesTags)
 	}
-	if !reflect.DeepEqual(index.Measurement("cpu").TagKeys(), []string{"host"}) {
+	if keys, err := index.Measurement("cpu").TagKeys(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(keys, []string{"host"}) {
 		t.Fatalf("tag key wasn't saved to measurement index")
 	}
 
@@ -146,6 +152,76 @@ func TestShardWriteAddNewField(t *testing.T) {
 }
 
 // Ensure the shard will automatically flush the WAL after a threshold has been reached.
+// Ensure a batch can't create the same field with an integer and a float type.
+func TestShardWrite_FieldTypeConflictInBatch(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+
+	index := tsdb.NewDatabaseIndex()
+	sh := tsdb.NewShard(1, index, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	points, err := tsdb.ParsePointsString("cpu value=1i 1\ncpu value=2 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.WritePoints(points); err == nil || !strings.Contains(err.Error(), "field type conflict") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if tsdb.IsRetryable(err) {
+		t.Fatalf("expected rejection: %v", err)
+	}
+
+	// Nothing was created by the rejected batch.
+	if m := index.Measurement("cpu"); m != nil && len(m.FieldNames()) != 0 {
+		t.Fatalf("unexpected fields: %v", m.FieldNames())
+	}
+
+	// Integers are kept as integers.
+	points, err = tsdb.ParsePointsString("cpu value=1i 1\ncpu value=9223372036854775807i 2")
+	if err != nil {
+		t.Fatal(err)
+	} else if err := sh.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+	if v := points[1].Fields()["value"]; v != int64(9223372036854775807) {
+		t.Fatalf("unexpected value: %#v", v)
+	}
+}
+
+// Ensure a field value that can't be decoded rejects the write instead of
+// creating the field.
+func TestShardWrite_UndecodableField(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+
+	index := tsdb.NewDatabaseIndex()
+	sh := tsdb.NewShard(1, index, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	points, err := tsdb.ParsePointsString("cpu value=-i 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.WritePoints(points); err == nil || !strings.Contains(err.Error(), `field "value"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := index.Measurement("cpu"); m != nil && len(m.FieldNames()) != 0 {
+		t.Fatalf("unexpected fields: %v", m.FieldNames())
+	}
+}
+
 func TestShard_Autoflush(t *testing.T) {
 	path, _ := ioutil.TempDir("", "shard_test")
 	defer os.RemoveAll(path)
@@ -166,7 +242,7 @@ func TestShard_Autoflush(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		if err := sh.WritePoints([]tsdb.Point{tsdb.NewPoint(
 			fmt.Sprintf("cpu%d", i),
-			map[string]string{"host": "server"},
+			tsdb.NewTags(map[string]string{"host": "server"}),
 			map[string]interface{}{"value": 1.0},
 			time.Unix(1, 2),
 		)}); err != nil {
@@ -206,7 +282,7 @@ func TestShard_Autoflush_FlushInterval(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		if err := sh.WritePoints([]tsdb.Point{tsdb.NewPoint(
 			fmt.Sprintf("cpu%d", i),
-			map[string]string{"host": "server"},
+			tsdb.NewTags(map[string]string{"host": "server"}),
 			map[string]interface{}{"value": 1.0},
 			time.Unix(1, 2),
 		)}); err != nil {
@@ -261,7 +337,7 @@ func benchmarkWritePoints(b *testing.B, mCnt, tkCnt, tvCnt, pntCnt int) {
 	points := []tsdb.Point{}
 	for _, s := range series {
 		for val := 0.0; val < float64(pntCnt); val++ {
-			p := tsdb.NewPoint(s.Measurement, s.Series.Tags, map[string]interface{}{"value": val}, time.Now())
+			p := tsdb.NewPoint(s.Measurement, tsdb.NewTags(s.Series.Tags), map[string]interface{}{"value": val}, time.Now())
 			points = append(points, p)
 		}
 	}
@@ -302,7 +378,7 @@ func benchmarkWritePointsExistingSeries(b *testing.B, mCnt, tkCnt, tvCnt, pntCnt
 	points := []tsdb.Point{}
 	for _, s := range series {
 		for val := 0.0; val < float64(pntCnt); val++ {
-			p := tsdb.NewPoint(s.Measurement, s.Series.Tags, map[string]interface{}{"value": val}, time.Now())
+			p := tsdb.NewPoint(s.Measurement, tsdb.NewTags(s.Series.Tags), map[string]interface{}{"value": val}, time.Now())
 			points = append(points, p)
 		}
 	}
@@ -351,3 +427,163 @@ func chunkedWrite(shard *tsdb.Shard, points []tsdb.Point) {
 		end += chunkSz
 	}
 }
+
+// Ensure the codec only decodes the requested fields.
+func TestFieldCodec_DecodeFieldsByNames(t *testing.T) {
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {ID: uint8(1), Name: "value", Type: influxql.Float},
+		"host":  {ID: uint8(2), Name: "host", Type: influxql.String},
+		"ok":    {ID: uint8(3), Name: "ok", Type: influxql.Boolean},
+		"count": {ID: uint8(4), Name: "count", Type: influxql.Integer},
+	})
+
+	b, err := codec.EncodeFields(map[string]interface{}{"value": 1.5, "host": "server01", "ok": true, "count": int64(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := codec.DecodeFieldsByNames([]string{"count", "host", "missing"}, b)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(m, map[string]interface{}{"count": int64(10), "host": "server01"}) {
+		t.Fatalf("unexpected fields: %#v", m)
+	}
+}
+
+// Ensure the codec encodes the fields of an iterator like a map of the same fields.
+func TestFieldCodec_EncodeFieldIterator(t *testing.T) {
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {ID: uint8(1), Name: "value", Type: influxql.Float},
+		"host":  {ID: uint8(2), Name: "host", Type: influxql.String},
+		"ok":    {ID: uint8(3), Name: "ok", Type: influxql.Boolean},
+		"count": {ID: uint8(4), Name: "count", Type: influxql.Integer},
+	})
+
+	fields := map[string]interface{}{"value": 1.5, "host": "server01", "ok": true, "count": int64(-10)}
+	p := tsdb.NewPoint("cpu", nil, fields, time.Unix(0, 0))
+	b, err := codec.EncodeFieldIterator(p.FieldIterator())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err := codec.DecodeFields(b); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(m, map[uint8]interface{}{1: 1.5, 2: "server01", 3: true, 4: int64(-10)}) {
+		t.Fatalf("unexpected fields: %#v", m)
+	}
+
+	// A field with another type is rejected.
+	p = tsdb.NewPoint("cpu", nil, map[string]interface{}{"value": "x"}, time.Unix(0, 0))
+	if _, err := codec.EncodeFieldIterator(p.FieldIterator()); err == nil || err.Error() != `field "value" is type string, mapped as type float` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure cold tag indexes are evicted once over the memory limit and loaded back when used.
+func TestShard_MaxIndexMemory(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	index := tsdb.NewDatabaseIndex()
+	index.SetMaxIndexMemory(1000, filepath.Join(tmpDir, "index"))
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+
+	sh := tsdb.NewShard(1, index, filepath.Join(tmpDir, "shard"), filepath.Join(tmpDir, "wal"), opts)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	// Write enough series to go over the limit.
+	var points []tsdb.Point
+	for i := 0; i < 10; i++ {
+		for _, name := range []string{"cpu", "mem"} {
+			points = append(points, tsdb.NewPoint(name, tsdb.NewTags(map[string]string{"host": fmt.Sprintf("server%d", i)}), tsdb.Fields{"value": 1.0}, time.Unix(1, 0)))
+		}
+	}
+	if err := sh.WritePoints(points[:10]); err != nil {
+		t.Fatal(err)
+	} else if err := sh.WritePoints(points[10:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := index.IndexSize(); n > 1000 {
+		t.Fatalf("index over memory limit: %d", n)
+	} else if files, _ := ioutil.ReadDir(filepath.Join(tmpDir, "index")); len(files) == 0 {
+		t.Fatal("expected evicted index on disk")
+	}
+
+	// The evicted measurements should still be fully queryable.
+	for _, name := range []string{"cpu", "mem"} {
+		if keys, err := index.Measurement(name).TagKeys(); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(keys, []string{"host"}) {
+			t.Fatalf("unexpected tag keys for %s: %v", name, keys)
+		}
+		key := fmt.Sprintf("%s,host=server9", name)
+		if tags, err := index.TagsForSeries(key); err != nil {
+			t.Fatal(err)
+		} else if tags["host"] != "server9" {
+			t.Fatalf("unexpected tags for %s: %v", key, tags)
+		}
+	}
+}
+
+// Ensure a corrupt evicted tag index is reported as an error and doesn't block writes.
+func TestShard_MaxIndexMemory_LoadError(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	index := tsdb.NewDatabaseIndex()
+	index.SetMaxIndexMemory(1000, filepath.Join(tmpDir, "index"))
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+
+	sh := tsdb.NewShard(1, index, filepath.Join(tmpDir, "shard"), filepath.Join(tmpDir, "wal"), opts)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	var points []tsdb.Point
+	for i := 0; i < 10; i++ {
+		for _, name := range []string{"cpu", "mem"} {
+			points = append(points, tsdb.NewPoint(name, tsdb.NewTags(map[string]string{"host": fmt.Sprintf("server%d", i)}), tsdb.Fields{"value": 1.0}, time.Unix(1, 0)))
+		}
+	}
+	if err := sh.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncate the evicted indexes.
+	files, _ := ioutil.ReadDir(filepath.Join(tmpDir, "index"))
+	if len(files) == 0 {
+		t.Fatal("expected evicted index on disk")
+	}
+	for _, fi := range files {
+		if err := os.Truncate(filepath.Join(tmpDir, "index", fi.Name()), 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// New series are still accepted while the index is unreadable.
+	if err := sh.WritePoints([]tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(2, 0)),
+		tsdb.NewPoint("mem", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0}, time.Unix(2, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var failed int
+	for _, name := range []string{"cpu", "mem"} {
+		if _, err := index.Measurement(name).TagKeys(); err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		t.Fatal("expected an error loading the truncated index")
+	}
+}
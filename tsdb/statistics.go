@@ -0,0 +1,122 @@
+package tsdb
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// Statistics is a set of counters kept by a module of the server, such as
+// "httpd" or "wal". Registered statistics are returned by SHOW STATS.
+type Statistics struct {
+	Module string
+	Name   string
+	Tags   map[string]string
+
+	mu     sync.RWMutex
+	values map[string]int64
+}
+
+// Add adds delta to the counter named key. Nil statistics ignore updates so
+// components built without a constructor don't need to check.
+func (s *Statistics) Add(key string, delta int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.values[key] += delta
+	s.mu.Unlock()
+}
+
+// Get returns the value of the counter named key.
+func (s *Statistics) Get(key string) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[key]
+}
+
+// row returns the counters as a single row with a column per counter.
+func (s *Statistics) row() *influxql.Row {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	row := &influxql.Row{Name: s.Name, Tags: s.Tags}
+	for k := range s.values {
+		row.Columns = append(row.Columns, k)
+	}
+	sort.Strings(row.Columns)
+
+	values := make([]interface{}, len(row.Columns))
+	for i, k := range row.Columns {
+		values[i] = s.values[k]
+	}
+	row.Values = [][]interface{}{values}
+	return row
+}
+
+// statistics is the registry of statistics by module, name and tags.
+var statistics = struct {
+	mu sync.Mutex
+	m  map[string]*Statistics
+}{m: make(map[string]*Statistics)}
+
+// NewStatistics returns the statistics registered for a module with the given
+// name and tags. They're created and registered if they don't exist yet.
+func NewStatistics(module, name string, tags map[string]string) *Statistics {
+	key := statisticsKey(module, name, tags)
+
+	statistics.mu.Lock()
+	defer statistics.mu.Unlock()
+
+	if s := statistics.m[key]; s != nil {
+		return s
+	}
+	s := &Statistics{
+		Module: module,
+		Name:   name,
+		Tags:   tags,
+		values: make(map[string]int64),
+	}
+	statistics.m[key] = s
+	return s
+}
+
+// UnregisterStatistics removes statistics from the registry, such as when the
+// shard or WAL they belong to is closed.
+func UnregisterStatistics(s *Statistics) {
+	statistics.mu.Lock()
+	defer statistics.mu.Unlock()
+	delete(statistics.m, statisticsKey(s.Module, s.Name, s.Tags))
+}
+
+// StatisticsRows returns a row for each registered statistics of a module,
+// sorted by name and tags. Statistics of all modules are returned if module
+// is blank.
+func StatisticsRows(module string) influxql.Rows {
+	statistics.mu.Lock()
+	keys := make([]string, 0, len(statistics.m))
+	for k, s := range statistics.m {
+		if module == "" || s.Module == module {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	a := make([]*Statistics, len(keys))
+	for i, k := range keys {
+		a[i] = statistics.m[k]
+	}
+	statistics.mu.Unlock()
+
+	rows := make(influxql.Rows, len(a))
+	for i, s := range a {
+		rows[i] = s.row()
+	}
+	return rows
+}
+
+// statisticsKey returns the registry key for statistics.
+func statisticsKey(module, name string, tags map[string]string) string {
+	return module + "\x00" + string(MakeKey([]byte(name), NewTags(tags)))
+}
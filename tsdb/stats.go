@@ -0,0 +1,270 @@
+package tsdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	// DefaultWriteStatsSize is the default number of measurements tracked by WriteStats.
+	DefaultWriteStatsSize = 100
+
+	// DefaultQueryStatsSize is the default number of series tracked by QueryStats.
+	DefaultQueryStatsSize = 10000
+)
+
+// MeasurementWriteStat holds the write counters for a single measurement.
+type MeasurementWriteStat struct {
+	Database    string
+	Measurement string
+	Points      int64
+	Bytes       int64 // size of the points in the line protocol
+
+	// Error is the maximum amount Points may be overcounted by. It is non-zero
+	// when the measurement replaced another one that was evicted.
+	Error int64
+}
+
+// WriteStats tracks the approximate number of points and bytes written to the
+// most written measurements. It keeps a fixed number of counters and evicts the
+// least written measurement when a new one arrives, so heavy writers are always
+// reported while the memory used stays bounded.
+type WriteStats struct {
+	mu    sync.Mutex
+	size  int
+	stats map[writeStatsKey]*MeasurementWriteStat
+}
+
+type writeStatsKey struct {
+	database    string
+	measurement string
+}
+
+// NewWriteStats returns a new instance of WriteStats that tracks at most size measurements.
+func NewWriteStats(size int) *WriteStats {
+	return &WriteStats{
+		size:  size,
+		stats: make(map[writeStatsKey]*MeasurementWriteStat),
+	}
+}
+
+// Add records points written to database.
+func (s *WriteStats) Add(database string, points []Point) {
+	// Size the points before taking the lock so writers don't wait on
+	// each other's encoding.
+	sizes := make([]int64, len(points))
+	var buf []byte
+	for i, p := range points {
+		buf = p.AppendString(buf[:0], Nanosecond)
+		sizes[i] = int64(len(buf))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range points {
+		s.add(database, p.Name(), 1, sizes[i])
+	}
+}
+
+func (s *WriteStats) add(database, measurement string, points, bytes int64) {
+	k := writeStatsKey{database: database, measurement: measurement}
+	if st := s.stats[k]; st != nil {
+		st.Points += points
+		st.Bytes += bytes
+		return
+	}
+
+	st := &MeasurementWriteStat{Database: database, Measurement: measurement, Points: points, Bytes: bytes}
+
+	// if we're full, the new measurement takes over the counters of the least written one
+	if len(s.stats) >= s.size {
+		var minKey writeStatsKey
+		var min *MeasurementWriteStat
+		for k, v := range s.stats {
+			if min == nil || v.Points < min.Points {
+				minKey, min = k, v
+			}
+		}
+		delete(s.stats, minKey)
+
+		st.Points += min.Points
+		st.Bytes += min.Bytes
+		st.Error = min.Points
+	}
+
+	s.stats[k] = st
+}
+
+// Top returns the n most written measurements, ordered by points written.
+// If n is zero then all tracked measurements are returned.
+func (s *WriteStats) Top(n int) []MeasurementWriteStat {
+	s.mu.Lock()
+	a := make([]MeasurementWriteStat, 0, len(s.stats))
+	for _, st := range s.stats {
+		a = append(a, *st)
+	}
+	s.mu.Unlock()
+
+	sort.Sort(measurementWriteStats(a))
+	if n > 0 && n < len(a) {
+		a = a[:n]
+	}
+	return a
+}
+
+// Reset clears all tracked measurements.
+func (s *WriteStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = make(map[writeStatsKey]*MeasurementWriteStat)
+}
+
+type measurementWriteStats []MeasurementWriteStat
+
+func (a measurementWriteStats) Len() int      { return len(a) }
+func (a measurementWriteStats) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a measurementWriteStats) Less(i, j int) bool {
+	if a[i].Points != a[j].Points {
+		return a[i].Points > a[j].Points
+	}
+	if a[i].Database != a[j].Database {
+		return a[i].Database < a[j].Database
+	}
+	return a[i].Measurement < a[j].Measurement
+}
+
+// SeriesQueryStat holds the query counter for a single series.
+type SeriesQueryStat struct {
+	Database string `json:"database"`
+	Key      string `json:"key"`
+	Queries  int64  `json:"queries"`
+}
+
+// QueryStats tracks the approximate number of queries that read the most
+// queried series. Like WriteStats, it keeps a fixed number of counters and a
+// new series takes over the counter of the least queried one.
+type QueryStats struct {
+	mu    sync.Mutex
+	size  int
+	stats map[queryStatsKey]int64
+}
+
+type queryStatsKey struct {
+	database string
+	key      string
+}
+
+// NewQueryStats returns a new instance of QueryStats that tracks at most size series.
+func NewQueryStats(size int) *QueryStats {
+	return &QueryStats{
+		size:  size,
+		stats: make(map[queryStatsKey]int64),
+	}
+}
+
+// Add records a query reading the series keys of database.
+func (s *QueryStats) Add(database string, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		s.add(database, key, 1)
+	}
+}
+
+func (s *QueryStats) add(database, key string, n int64) {
+	k := queryStatsKey{database: database, key: key}
+	if _, ok := s.stats[k]; ok || len(s.stats) < s.size {
+		s.stats[k] += n
+		return
+	}
+
+	// we're full so the new series takes over the counter of the least queried one
+	var minKey queryStatsKey
+	min := int64(-1)
+	for k, v := range s.stats {
+		if min == -1 || v < min {
+			minKey, min = k, v
+		}
+	}
+	delete(s.stats, minKey)
+	s.stats[k] = min + n
+}
+
+// Top returns the n most queried series of database, ordered by queries.
+// If n is zero then all tracked series of the database are returned.
+func (s *QueryStats) Top(database string, n int) []SeriesQueryStat {
+	s.mu.Lock()
+	a := make([]SeriesQueryStat, 0)
+	for k, v := range s.stats {
+		if k.database == database {
+			a = append(a, SeriesQueryStat{Database: k.database, Key: k.key, Queries: v})
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Sort(seriesQueryStats(a))
+	if n > 0 && n < len(a) {
+		a = a[:n]
+	}
+	return a
+}
+
+// Save writes the tracked series to path so they can be loaded after a restart.
+func (s *QueryStats) Save(path string) error {
+	s.mu.Lock()
+	a := make([]SeriesQueryStat, 0, len(s.stats))
+	for k, v := range s.stats {
+		a = append(a, SeriesQueryStat{Database: k.database, Key: k.key, Queries: v})
+	}
+	s.mu.Unlock()
+
+	buf, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash doesn't leave a partial file.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load adds the series saved to path to the tracked series. It's not an error
+// if path doesn't exist.
+func (s *QueryStats) Load(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var a []SeriesQueryStat
+	if err := json.Unmarshal(buf, &a); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range a {
+		s.add(st.Database, st.Key, st.Queries)
+	}
+	return nil
+}
+
+type seriesQueryStats []SeriesQueryStat
+
+func (a seriesQueryStats) Len() int      { return len(a) }
+func (a seriesQueryStats) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a seriesQueryStats) Less(i, j int) bool {
+	if a[i].Queries != a[j].Queries {
+		return a[i].Queries > a[j].Queries
+	}
+	return a[i].Key < a[j].Key
+}
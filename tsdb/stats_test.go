@@ -0,0 +1,152 @@
+package tsdb_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+func TestWriteStats_Top(t *testing.T) {
+	s := tsdb.NewWriteStats(10)
+	now := time.Unix(0, 0)
+	cpu := []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.NewTags(map[string]string{"host": "serverA"}), tsdb.Fields{"value": 1.0, "count": int64(10)}, now),
+		tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 2.0, "desc": "idle"}, now),
+	}
+	s.Add("db0", append(cpu, tsdb.NewPoint("mem", nil, tsdb.Fields{"value": 1.0}, now)))
+	s.Add("db1", []tsdb.Point{tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now)})
+
+	top := s.Top(2)
+	if len(top) != 2 {
+		t.Fatalf("unexpected number of stats: %d", len(top))
+	}
+	if top[0].Database != "db0" || top[0].Measurement != "cpu" || top[0].Points != 2 {
+		t.Fatalf("unexpected top stat: %+v", top[0])
+	}
+	if exp := int64(len(cpu[0].String()) + len(cpu[1].String())); top[0].Bytes != exp {
+		t.Fatalf("unexpected bytes: %d", top[0].Bytes)
+	}
+	if top[1].Database != "db0" || top[1].Measurement != "mem" || top[1].Points != 1 {
+		t.Fatalf("unexpected second stat: %+v", top[1])
+	}
+}
+
+func TestQueryStats_Top(t *testing.T) {
+	s := tsdb.NewQueryStats(10)
+	s.Add("db0", []string{"cpu,host=a", "cpu,host=b"})
+	s.Add("db0", []string{"cpu,host=b"})
+	s.Add("db1", []string{"cpu,host=a", "cpu,host=a", "cpu,host=a"})
+
+	exp := []tsdb.SeriesQueryStat{
+		{Database: "db0", Key: "cpu,host=b", Queries: 2},
+		{Database: "db0", Key: "cpu,host=a", Queries: 1},
+	}
+	if top := s.Top("db0", 0); !reflect.DeepEqual(top, exp) {
+		t.Fatalf("unexpected top stats: %+v", top)
+	}
+	if top := s.Top("db0", 1); !reflect.DeepEqual(top, exp[:1]) {
+		t.Fatalf("unexpected top stat: %+v", top)
+	}
+}
+
+// Ensure the least queried series is evicted once the stats are full.
+func TestQueryStats_Evict(t *testing.T) {
+	s := tsdb.NewQueryStats(2)
+	s.Add("db0", []string{"cpu", "cpu", "mem", "disk"})
+
+	exp := []tsdb.SeriesQueryStat{
+		{Database: "db0", Key: "cpu", Queries: 2},
+		{Database: "db0", Key: "disk", Queries: 2},
+	}
+	if top := s.Top("db0", 0); !reflect.DeepEqual(top, exp) {
+		t.Fatalf("unexpected top stats: %+v", top)
+	}
+}
+
+// Ensure the least written measurement is evicted once the stats are full.
+func TestWriteStats_Evict(t *testing.T) {
+	s := tsdb.NewWriteStats(2)
+	now := time.Unix(0, 0)
+	s.Add("db0", []tsdb.Point{
+		tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now),
+		tsdb.NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}, now),
+		tsdb.NewPoint("mem", nil, tsdb.Fields{"value": 1.0}, now),
+		tsdb.NewPoint("disk", nil, tsdb.Fields{"value": 1.0}, now),
+	})
+
+	top := s.Top(0)
+	if len(top) != 2 {
+		t.Fatalf("unexpected number of stats: %d", len(top))
+	}
+	if top[0].Measurement != "cpu" || top[0].Points != 2 || top[0].Error != 0 {
+		t.Fatalf("unexpected top stat: %+v", top[0])
+	}
+	if top[1].Measurement != "disk" || top[1].Points != 2 || top[1].Error != 1 {
+		t.Fatalf("unexpected evicting stat: %+v", top[1])
+	}
+}
+
+// Ensure registered statistics are returned as rows filtered by module.
+func TestStatisticsRows(t *testing.T) {
+	a := tsdb.NewStatistics("test-a", "foo", map[string]string{"id": "1"})
+	defer tsdb.UnregisterStatistics(a)
+	b := tsdb.NewStatistics("test-b", "bar", nil)
+	defer tsdb.UnregisterStatistics(b)
+
+	a.Add("writes", 2)
+	a.Add("points", 10)
+	tsdb.NewStatistics("test-a", "foo", map[string]string{"id": "1"}).Add("writes", 1)
+	b.Add("reqs", 1)
+
+	rows := tsdb.StatisticsRows("test-a")
+	if len(rows) != 1 {
+		t.Fatalf("unexpected row count: %d", len(rows))
+	} else if !reflect.DeepEqual(rows[0], &influxql.Row{
+		Name:    "foo",
+		Tags:    map[string]string{"id": "1"},
+		Columns: []string{"points", "writes"},
+		Values:  [][]interface{}{{int64(10), int64(3)}},
+	}) {
+		t.Fatalf("unexpected row: %s", spew.Sdump(rows[0]))
+	}
+
+	// Unregistered statistics aren't returned.
+	tsdb.UnregisterStatistics(b)
+	if rows := tsdb.StatisticsRows("test-b"); len(rows) != 0 {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(rows))
+	}
+}
+
+// Ensure latency histograms count durations per bucket.
+func TestLatencyHistogram_Observe(t *testing.T) {
+	h := tsdb.NewLatencyHistogram("test-latency", "latency", nil)
+	defer tsdb.UnregisterStatistics(h.Statistics())
+
+	h.Observe(50 * time.Microsecond)
+	h.Observe(time.Millisecond)
+	h.Observe(2 * time.Millisecond)
+	h.Observe(time.Minute)
+
+	s := h.Statistics()
+	for key, exp := range map[string]int64{
+		"le_100us": 1,
+		"le_1ms":   1,
+		"le_10ms":  1,
+		"le_100ms": 0,
+		"le_inf":   1,
+		"count":    4,
+		"sumNs":    int64(50*time.Microsecond + 3*time.Millisecond + time.Minute),
+	} {
+		if got := s.Get(key); got != exp {
+			t.Errorf("%s: got %d, exp %d", key, got, exp)
+		}
+	}
+
+	// Nil histograms ignore durations.
+	var nilHist *tsdb.LatencyHistogram
+	nilHist.Observe(time.Second)
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdb/influxdb/influxql"
 )
@@ -20,12 +21,22 @@ func NewStore(path string) *Store {
 	return &Store{
 		path:          path,
 		EngineOptions: opts,
+		WriteStats:    NewWriteStats(DefaultWriteStatsSize),
+		QueryStats:    NewQueryStats(DefaultQueryStatsSize),
 		Logger:        log.New(os.Stderr, "[store] ", log.LstdFlags),
 	}
 }
 
+// queryStatsFile is the name of the file in the store's path the query stats
+// are saved to for the warm-up.
+const queryStatsFile = "query_stats.json"
+
 var (
 	ErrShardNotFound = fmt.Errorf("shard not found")
+
+	// ErrTrashDisabled is returned when trashing or restoring a database
+	// without a trash directory.
+	ErrTrashDisabled = fmt.Errorf("trash directory not set")
 )
 
 type Store struct {
@@ -34,9 +45,22 @@ type Store struct {
 
 	databaseIndexes map[string]*DatabaseIndex
 	shards          map[uint64]*Shard
+	files           *fileBudget
 
 	EngineOptions EngineOptions
-	Logger        *log.Logger
+
+	// WriteStats tracks the points and bytes written per measurement.
+	WriteStats *WriteStats
+
+	// QueryStats tracks the series read by queries. The most queried series
+	// are warmed up when the store is opened if Config.WarmUpSeries is set.
+	QueryStats *QueryStats
+
+	// LastValues holds the last value written to each field of each series.
+	// It's set when the store is opened if Config.LastValueCache is set.
+	LastValues *LastValueCache
+
+	Logger *log.Logger
 }
 
 // Path returns the store's root path.
@@ -86,15 +110,22 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64) er
 	// create the database index if it does not exist
 	db, ok := s.databaseIndexes[database]
 	if !ok {
-		db = NewDatabaseIndex()
+		var err error
+		if db, err = s.newDatabaseIndex(database); err != nil {
+			return err
+		}
 		s.databaseIndexes[database] = db
 	}
 
 	shardPath := filepath.Join(s.path, database, retentionPolicy, strconv.FormatUint(shardID, 10))
 	shard := NewShard(shardID, db, shardPath, walPath, s.EngineOptions)
+	shard.database = database
+	shard.budget = s.files
+	shard.queryStats = s.QueryStats
 	if err := shard.Open(); err != nil {
 		return err
 	}
+	s.files.add(shard)
 
 	s.shards[shardID] = shard
 
@@ -125,6 +156,9 @@ func (s *Store) DeleteShard(shardID uint64) error {
 	}
 
 	delete(s.shards, shardID)
+	if s.LastValues != nil {
+		s.LastValues.DeleteShard(shardID)
+	}
 
 	return nil
 }
@@ -145,7 +179,207 @@ func (s *Store) DeleteDatabase(name string, shardIDs []uint64) error {
 	if err := os.RemoveAll(filepath.Join(s.EngineOptions.Config.WALDir, name)); err != nil {
 		return err
 	}
+	if dir := s.EngineOptions.Config.IndexDir; dir != "" {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	delete(s.databaseIndexes, name)
+	s.resetLastValues(name)
+	return nil
+}
+
+// TrashDatabase closes all shards associated with a database and moves its
+// data and WAL directories into the trash directory, replacing any database
+// with the same name already in the trash, so it can be restored with
+// RestoreDatabase.
+func (s *Store) TrashDatabase(name string, shardIDs []uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trash := s.trashPath(name)
+	if trash == "" {
+		return ErrTrashDisabled
+	}
+	if err := os.RemoveAll(trash); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(trash, 0700); err != nil {
+		return err
+	}
+
+	for _, id := range shardIDs {
+		if shard := s.shards[id]; shard != nil {
+			shard.Close()
+			delete(s.shards, id)
+		}
+	}
+
+	// The data and WAL are moved together. If either can't be moved the
+	// database is moved back and its shards reopened.
+	dataDir, walDir := filepath.Join(s.path, name), filepath.Join(s.EngineOptions.Config.WALDir, name)
+	if err := moveDir(dataDir, filepath.Join(trash, "data")); err != nil {
+		return s.reopenDatabaseShards(name, err)
+	}
+	if err := moveDir(walDir, filepath.Join(trash, "wal")); err != nil {
+		if merr := moveDir(filepath.Join(trash, "data"), dataDir); merr != nil {
+			return fmt.Errorf("%s; data left in trash: %s", err, merr)
+		}
+		return s.reopenDatabaseShards(name, err)
+	}
+	if dir := s.EngineOptions.Config.IndexDir; dir != "" {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
 	delete(s.databaseIndexes, name)
+	s.resetLastValues(name)
+	return nil
+}
+
+// RestoreDatabase moves a database in the trash back into the data and WAL
+// directories and opens its shards. Restoring a database without anything in
+// the trash does nothing.
+func (s *Store) RestoreDatabase(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.resetLastValues(name)
+
+	trash := s.trashPath(name)
+	if trash == "" {
+		return ErrTrashDisabled
+	} else if _, err := os.Stat(trash); os.IsNotExist(err) {
+		return nil
+	}
+
+	if _, ok := s.databaseIndexes[name]; ok {
+		return fmt.Errorf("database already exists: %s", name)
+	}
+
+	// The data and WAL are moved back to the trash if the database can't
+	// be restored, so the restore can be retried.
+	dataDir, walDir := filepath.Join(s.path, name), filepath.Join(s.EngineOptions.Config.WALDir, name)
+	if err := moveDir(filepath.Join(trash, "data"), dataDir); err != nil {
+		return err
+	}
+	if err := moveDir(filepath.Join(trash, "wal"), walDir); err != nil {
+		return s.untrashFailed(name, err)
+	}
+
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		return os.RemoveAll(trash)
+	}
+
+	index, err := s.newDatabaseIndex(name)
+	if err != nil {
+		return s.untrashFailed(name, err)
+	}
+	s.databaseIndexes[name] = index
+	if err := s.loadDatabaseShards(name); err != nil {
+		for id, sh := range s.shards {
+			if sh.database == name {
+				sh.Close()
+				delete(s.shards, id)
+			}
+		}
+		delete(s.databaseIndexes, name)
+		return s.untrashFailed(name, err)
+	}
+
+	return os.RemoveAll(trash)
+}
+
+// untrashFailed moves the data and WAL of a database that couldn't be restored
+// back to the trash and returns err.
+func (s *Store) untrashFailed(name string, err error) error {
+	trash := s.trashPath(name)
+	if merr := moveDir(filepath.Join(s.path, name), filepath.Join(trash, "data")); merr != nil {
+		return fmt.Errorf("%s; moving data back to trash: %s", err, merr)
+	}
+	if merr := moveDir(filepath.Join(s.EngineOptions.Config.WALDir, name), filepath.Join(trash, "wal")); merr != nil {
+		return fmt.Errorf("%s; moving wal back to trash: %s", err, merr)
+	}
+	return err
+}
+
+// reopenDatabaseShards removes the trash of a database that couldn't be moved
+// there, reopens its shards and returns err.
+func (s *Store) reopenDatabaseShards(name string, err error) error {
+	if rerr := os.RemoveAll(s.trashPath(name)); rerr != nil {
+		return fmt.Errorf("%s; removing trash: %s", err, rerr)
+	}
+	if _, serr := os.Stat(filepath.Join(s.path, name)); os.IsNotExist(serr) {
+		return err
+	}
+	if lerr := s.loadDatabaseShards(name); lerr != nil {
+		return fmt.Errorf("%s; reopening shards: %s", err, lerr)
+	}
+	return err
+}
+
+// databaseShardIDs returns the IDs of the open shards of a database.
+func (s *Store) databaseShardIDs(name string) []uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []uint64
+	for id, sh := range s.shards {
+		if sh.database == name {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// TrashedDatabases returns the names of the databases in the trash.
+func (s *Store) TrashedDatabases() ([]string, error) {
+	dir := s.EngineOptions.Config.TrashDir
+	if dir == "" {
+		return nil, nil
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, fi := range fis {
+		if fi.IsDir() {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// PurgeTrash permanently removes a database from the trash.
+func (s *Store) PurgeTrash(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trash := s.trashPath(name)
+	if trash == "" {
+		return nil
+	}
+	return os.RemoveAll(trash)
+}
+
+// trashPath returns the directory a database is moved to when it's trashed,
+// or a blank string if there is no trash directory.
+func (s *Store) trashPath(name string) string {
+	if s.EngineOptions.Config.TrashDir == "" {
+		return ""
+	}
+	return filepath.Join(s.EngineOptions.Config.TrashDir, name)
+}
+
+// moveDir renames a directory, doing nothing if it doesn't exist.
+func moveDir(src, dst string) error {
+	if err := os.Rename(src, dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
@@ -184,20 +418,33 @@ func (s *Store) Measurement(database, name string) *Measurement {
 	return db.Measurement(name)
 }
 
-// deleteSeries loops through the local shards and deletes the series data and metadata for the passed in series keys
-func (s *Store) deleteSeries(keys []string) error {
+// DeleteSeries deletes the series data for the passed in series keys from the
+// local shards of a database and removes the series from the database index.
+func (s *Store) DeleteSeries(database string, keys []string) error {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	db := s.databaseIndexes[database]
+	if db == nil {
+		s.mu.RUnlock()
+		return nil
+	}
 	for _, sh := range s.shards {
+		if sh.index != db {
+			continue
+		}
 		if err := sh.DeleteSeries(keys); err != nil {
+			s.mu.RUnlock()
 			return err
 		}
 	}
+	s.mu.RUnlock()
+
+	db.DropSeries(keys)
+	s.resetLastValues(database)
 	return nil
 }
 
 // deleteMeasurement loops through the local shards and removes the measurement field encodings from each shard
-func (s *Store) deleteMeasurement(name string, seriesKeys []string) error {
+func (s *Store) deleteMeasurement(database, name string, seriesKeys []string) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	for _, sh := range s.shards {
@@ -205,65 +452,120 @@ func (s *Store) deleteMeasurement(name string, seriesKeys []string) error {
 			return err
 		}
 	}
+	s.resetLastValues(database)
 	return nil
 }
 
+// resetLastValues drops the cached last values of a database after points
+// were deleted from it.
+func (s *Store) resetLastValues(database string) {
+	if s.LastValues != nil {
+		s.LastValues.DeleteDatabase(database)
+	}
+}
+
 func (s *Store) loadIndexes() error {
 	dbs, err := ioutil.ReadDir(s.path)
 	if err != nil {
 		return err
 	}
 	for _, db := range dbs {
-		if !db.IsDir() {
+		if db.Name() == queryStatsFile || s.isWALDir(db.Name()) {
+			continue
+		} else if !db.IsDir() {
 			s.Logger.Printf("Skipping database dir: %s. Not a directory", db.Name())
 			continue
 		}
-		s.databaseIndexes[db.Name()] = NewDatabaseIndex()
+		index, err := s.newDatabaseIndex(db.Name())
+		if err != nil {
+			return err
+		}
+		s.databaseIndexes[db.Name()] = index
 	}
 	return nil
 }
 
+// isWALDir returns true if name in the data directory is the WAL directory.
+func (s *Store) isWALDir(name string) bool {
+	walDir := s.EngineOptions.Config.WALDir
+	return walDir != "" && filepath.Clean(walDir) == filepath.Join(s.path, name)
+}
+
+// newDatabaseIndex returns a new index for a database, limited to the
+// configured memory. Any index evicted by a previous run is removed as the
+// index is rebuilt from the shards.
+func (s *Store) newDatabaseIndex(name string) (*DatabaseIndex, error) {
+	index := NewDatabaseIndex()
+
+	c := s.EngineOptions.Config
+	if c.MaxIndexMemory > 0 && c.IndexDir != "" {
+		path := filepath.Join(c.IndexDir, name)
+		if err := os.RemoveAll(path); err != nil {
+			return nil, err
+		}
+		index.SetMaxIndexMemory(int64(c.MaxIndexMemory), path)
+	}
+	return index, nil
+}
+
 func (s *Store) loadShards() error {
 	// loop through the current database indexes
 	for db := range s.databaseIndexes {
-		rps, err := ioutil.ReadDir(filepath.Join(s.path, db))
+		if err := s.loadDatabaseShards(db); err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// loadDatabaseShards opens the shards of a database on disk.
+func (s *Store) loadDatabaseShards(db string) error {
+	rps, err := ioutil.ReadDir(filepath.Join(s.path, db))
+	if err != nil {
+		return err
+	}
+
+	for _, rp := range rps {
+		// retention policies should be directories.  Skip anything that is not a dir.
+		if !rp.IsDir() {
+			s.Logger.Printf("Skipping retention policy dir: %s. Not a directory", rp.Name())
+			continue
+		}
+
+		shards, err := ioutil.ReadDir(filepath.Join(s.path, db, rp.Name()))
 		if err != nil {
 			return err
 		}
+		for _, sh := range shards {
+			path := filepath.Join(s.path, db, rp.Name(), sh.Name())
+			walPath := filepath.Join(s.EngineOptions.Config.WALDir, db, rp.Name(), sh.Name())
 
-		for _, rp := range rps {
-			// retention policies should be directories.  Skip anything that is not a dir.
-			if !rp.IsDir() {
-				s.Logger.Printf("Skipping retention policy dir: %s. Not a directory", rp.Name())
+			// Shard file names are numeric shardIDs
+			shardID, err := strconv.ParseUint(sh.Name(), 10, 64)
+			if err != nil {
+				s.Logger.Printf("Skipping shard: %s. Not a valid path", rp.Name())
 				continue
 			}
 
-			shards, err := ioutil.ReadDir(filepath.Join(s.path, db, rp.Name()))
-			if err != nil {
-				return err
+			// Shards left open by a failed move to the trash are kept.
+			if _, ok := s.shards[shardID]; ok {
+				continue
 			}
-			for _, sh := range shards {
-				path := filepath.Join(s.path, db, rp.Name(), sh.Name())
-				walPath := filepath.Join(s.EngineOptions.Config.WALDir, db, rp.Name(), sh.Name())
-
-				// Shard file names are numeric shardIDs
-				shardID, err := strconv.ParseUint(sh.Name(), 10, 64)
-				if err != nil {
-					s.Logger.Printf("Skipping shard: %s. Not a valid path", rp.Name())
-					continue
-				}
-
-				shard := NewShard(shardID, s.databaseIndexes[db], path, walPath, s.EngineOptions)
-				err = shard.Open()
-				if err != nil {
-					return fmt.Errorf("failed to open shard %d: %s", shardID, err)
-				}
-				s.shards[shardID] = shard
+
+			shard := NewShard(shardID, s.databaseIndexes[db], path, walPath, s.EngineOptions)
+			shard.database = db
+			shard.budget = s.files
+			shard.queryStats = s.QueryStats
+			err = shard.Open()
+			if err != nil {
+				return fmt.Errorf("failed to open shard %d: %s", shardID, err)
 			}
+			s.files.add(shard)
+			s.shards[shardID] = shard
 		}
 	}
 	return nil
-
 }
 
 func (s *Store) Open() error {
@@ -272,6 +574,7 @@ func (s *Store) Open() error {
 
 	s.shards = map[uint64]*Shard{}
 	s.databaseIndexes = map[string]*DatabaseIndex{}
+	s.files = newFileBudget(s.EngineOptions.Config.MaxOpenFiles)
 
 	s.Logger.Printf("Using data dir: %v", s.Path())
 
@@ -280,6 +583,15 @@ func (s *Store) Open() error {
 		return err
 	}
 
+	// The WAL may be on another volume, so make sure it's usable before any
+	// shard is opened.
+	if walDir := s.EngineOptions.Config.WALDir; walDir != "" {
+		s.Logger.Printf("Using wal dir: %v", walDir)
+		if err := os.MkdirAll(walDir, 0700); err != nil {
+			return fmt.Errorf("create wal dir: %s", err)
+		}
+	}
+
 	// TODO: Start AE for Node
 	if err := s.loadIndexes(); err != nil {
 		return err
@@ -289,9 +601,45 @@ func (s *Store) Open() error {
 		return err
 	}
 
+	if s.EngineOptions.Config.LastValueCache {
+		s.LastValues = NewLastValueCache()
+	}
+
+	if s.EngineOptions.Config.WarmUpSeries > 0 && s.QueryStats != nil {
+		s.warmUp(s.EngineOptions.Config.WarmUpSeries)
+	}
+
 	return nil
 }
 
+// warmUp loads the n most queried series of each database, as saved when the
+// store was last closed, into memory. Failures are logged since the data is
+// still read from disk when it's queried.
+func (s *Store) warmUp(n int) {
+	if err := s.QueryStats.Load(filepath.Join(s.path, queryStatsFile)); err != nil {
+		s.Logger.Printf("failed to load query stats: %s", err)
+		return
+	}
+
+	start := time.Now()
+	keys := make(map[string][]string)
+	for db := range s.databaseIndexes {
+		for _, st := range s.QueryStats.Top(db, n) {
+			keys[db] = append(keys[db], st.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	for id, sh := range s.shards {
+		if err := sh.WarmUp(keys[sh.database]); err != nil {
+			s.Logger.Printf("failed to warm up shard %d: %s", id, err)
+		}
+	}
+	s.Logger.Printf("warmed up %d shards in %s", len(s.shards), time.Since(start))
+}
+
 func (s *Store) WriteToShard(shardID uint64, points []Point) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -300,18 +648,53 @@ func (s *Store) WriteToShard(shardID uint64, points []Point) error {
 		return ErrShardNotFound
 	}
 
-	return sh.WritePoints(points)
+	if err := sh.WritePoints(points); err != nil {
+		return err
+	}
+
+	if s.WriteStats != nil {
+		s.WriteStats.Add(sh.database, points)
+	}
+	if s.LastValues != nil {
+		s.LastValues.addPoints(sh, points)
+	}
+	return nil
+}
+
+// lastValues returns the cached last values of a measurement in a shard,
+// reading them from the shard the first time the measurement is queried. It
+// returns nil if the shard isn't in the store.
+func (s *Store) lastValues(shardID uint64, name string) (*lastValueSet, error) {
+	sh := s.Shard(shardID)
+	if sh == nil {
+		return nil, nil
+	}
+
+	set := s.LastValues.set(sh.database, shardID, name)
+	if !set.isWarm() {
+		if err := set.warmUp(sh, name); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// IterateSeries calls fn for every point in a shard of the retention policy
+// rp of database db, without going through the query engine. Points are
+// read from a snapshot of the shard, ordered by series key and then time.
+func (s *Store) IterateSeries(db, rp string, shardID uint64, fn SeriesIterFunc) error {
+	sh := s.Shard(shardID)
+	if sh == nil || sh.database != db || filepath.Base(filepath.Dir(sh.path)) != rp {
+		return ErrShardNotFound
+	}
+	return sh.IterateSeries(fn)
 }
 
 func (s *Store) CreateMapper(shardID uint64, query string, chunkSize int) (Mapper, error) {
-	q, err := influxql.NewParser(strings.NewReader(query)).ParseStatement()
+	stmt, err := parseSelectStatement(query)
 	if err != nil {
 		return nil, err
 	}
-	stmt, ok := q.(*influxql.SelectStatement)
-	if !ok {
-		return nil, fmt.Errorf("query is not a SELECT statement: %s", err.Error())
-	}
 
 	shard := s.Shard(shardID)
 	if shard == nil {
@@ -322,15 +705,53 @@ func (s *Store) CreateMapper(shardID uint64, query string, chunkSize int) (Mappe
 	return NewLocalMapper(shard, stmt, chunkSize), nil
 }
 
+// ShardHasData returns false if the shard doesn't exist locally or has none of
+// the measurements queried by the statement. It only checks the shard's index,
+// so it can be used to skip shards before creating mappers.
+func (s *Store) ShardHasData(shardID uint64, query string) (bool, error) {
+	stmt, err := parseSelectStatement(query)
+	if err != nil {
+		return false, err
+	}
+
+	shard := s.Shard(shardID)
+	if shard == nil {
+		return false, nil
+	}
+	return shard.hasSources(stmt.Sources), nil
+}
+
+// parseSelectStatement parses a query that must be a SELECT statement.
+func parseSelectStatement(query string) (*influxql.SelectStatement, error) {
+	q, err := influxql.NewParser(strings.NewReader(query)).ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+	stmt, ok := q.(*influxql.SelectStatement)
+	if !ok {
+		return nil, fmt.Errorf("query is not a SELECT statement: %s", query)
+	}
+	return stmt, nil
+}
+
 func (s *Store) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.EngineOptions.Config.WarmUpSeries > 0 && s.QueryStats != nil {
+		if err := s.QueryStats.Save(filepath.Join(s.path, queryStatsFile)); err != nil {
+			s.Logger.Printf("failed to save query stats: %s", err)
+		}
+	}
+
 	for _, sh := range s.shards {
 		if err := sh.Close(); err != nil {
 			return err
 		}
 	}
+	if s.files != nil {
+		UnregisterStatistics(s.files.stats)
+	}
 	s.shards = nil
 	s.databaseIndexes = nil
 
@@ -343,6 +764,11 @@ func IsRetryable(err error) bool {
 		return true
 	}
 
+	if _, ok := err.(*PointsRejectedError); ok {
+		return false
+	}
+
+	// Older remote nodes only report rejections in the error message.
 	if strings.Contains(err.Error(), "field type conflict") {
 		return false
 	}
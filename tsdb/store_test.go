@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -32,6 +33,37 @@ func TestStoreOpen(t *testing.T) {
 	}
 }
 
+// Ensure the WAL directory is created on open and isn't loaded as a database
+// when it's kept in the data directory.
+func TestStoreOpen_WALDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	s.Close()
+
+	if fi, err := os.Stat(filepath.Join(dir, "wal")); err != nil {
+		t.Fatal(err)
+	} else if !fi.IsDir() {
+		t.Fatal("wal dir is not a directory")
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+	if got, exp := s.DatabaseIndexN(), 0; got != exp {
+		t.Fatalf("database index count mismatch: got %v, exp %v", got, exp)
+	}
+}
+
 func TestStoreOpenShard(t *testing.T) {
 	dir, err := ioutil.TempDir("", "store_test")
 	if err != nil {
@@ -119,6 +151,99 @@ func TestStoreOpenShardCreateDelete(t *testing.T) {
 	}
 }
 
+func TestStore_TrashDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(filepath.Join(dir, "data"))
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.TrashDir = filepath.Join(dir, "trash")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1"))
+	if err := s.CreateShard("mydb", "myrp", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	} else if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	if err := s.TrashDatabase("mydb", []uint64{1}); err != nil {
+		t.Fatalf("Store.TrashDatabase() failed: %v", err)
+	} else if sh := s.Shard(1); sh != nil {
+		t.Fatal("shard ID 1 still exists")
+	} else if di := s.DatabaseIndex("mydb"); di != nil {
+		t.Fatal("database mydb still exists")
+	} else if names, err := s.TrashedDatabases(); err != nil || !reflect.DeepEqual(names, []string{"mydb"}) {
+		t.Fatalf("unexpected trashed databases: %v, %v", names, err)
+	}
+
+	if err := s.RestoreDatabase("mydb"); err != nil {
+		t.Fatalf("Store.RestoreDatabase() failed: %v", err)
+	} else if sh := s.Shard(1); sh == nil {
+		t.Fatal("shard ID 1 not restored")
+	} else if di := s.DatabaseIndex("mydb"); di == nil || di.Measurement("cpu") == nil {
+		t.Fatal("database mydb not restored")
+	} else if names, err := s.TrashedDatabases(); err != nil || len(names) != 0 {
+		t.Fatalf("unexpected trashed databases: %v, %v", names, err)
+	}
+}
+
+// Ensure a database that can't be restored is left in the trash.
+func TestStore_RestoreDatabase_MoveFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(filepath.Join(dir, "data"))
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.TrashDir = filepath.Join(dir, "trash")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1"))
+	if err := s.CreateShard("mydb", "myrp", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	} else if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	} else if err := s.TrashDatabase("mydb", []uint64{1}); err != nil {
+		t.Fatalf("Store.TrashDatabase() failed: %v", err)
+	}
+
+	// A non-empty WAL directory in the way stops the WAL being moved back.
+	blocker := filepath.Join(dir, "wal", "mydb", "blocker")
+	if err := os.MkdirAll(blocker, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RestoreDatabase("mydb"); err == nil {
+		t.Fatal("expected error")
+	} else if sh := s.Shard(1); sh != nil {
+		t.Fatal("shard ID 1 opened")
+	} else if di := s.DatabaseIndex("mydb"); di != nil {
+		t.Fatal("database mydb opened")
+	} else if _, err := os.Stat(filepath.Join(dir, "data", "mydb")); !os.IsNotExist(err) {
+		t.Fatalf("data not moved back to trash: %v", err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "wal", "mydb")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RestoreDatabase("mydb"); err != nil {
+		t.Fatalf("Store.RestoreDatabase() failed: %v", err)
+	} else if sh := s.Shard(1); sh == nil {
+		t.Fatal("shard ID 1 not restored")
+	}
+}
+
 func TestStoreOpenNotDatabaseDir(t *testing.T) {
 	dir, err := ioutil.TempDir("", "store_test")
 	if err != nil {
@@ -276,6 +401,234 @@ func TestStoreEnsureSeriesPersistedInNewShards(t *testing.T) {
 	}
 }
 
+// Ensure idle shards are closed to stay within the file budget and are
+// reopened when they're needed again.
+// Ensure the integrity check samples the series of every shard.
+func TestStore_CheckIntegrity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	p, _ := tsdb.ParsePoints([]byte("cpu,host=a val=1\ncpu,host=b val=2\nmem val=3"))
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	} else if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	reports := s.CheckIntegrity(2)
+	if len(reports) != 1 {
+		t.Fatalf("unexpected report count: %d", len(reports))
+	}
+	r := reports[0]
+	if r.ShardID != 1 || r.Database != "foo" || r.RetentionPolicy != "default" {
+		t.Fatalf("unexpected shard: %d, %s.%s", r.ShardID, r.Database, r.RetentionPolicy)
+	} else if r.SeriesChecked != 2 {
+		t.Fatalf("unexpected series checked: %d", r.SeriesChecked)
+	} else if !r.OK() {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+}
+
+// Ensure shards without the measurements of a query are reported as empty.
+func TestStore_ShardHasData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1"))
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	} else if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	for i, tt := range []struct {
+		shardID uint64
+		query   string
+		exp     bool
+	}{
+		{shardID: 1, query: "SELECT val FROM cpu", exp: true},
+		{shardID: 1, query: "SELECT val FROM mem", exp: false},
+		{shardID: 1, query: "SELECT val FROM mem, cpu", exp: true},
+		{shardID: 1, query: "SELECT val FROM /^c/", exp: true},
+		{shardID: 1, query: "SELECT val FROM /^m/", exp: false},
+		{shardID: 2, query: "SELECT val FROM cpu", exp: false},
+	} {
+		if ok, err := s.ShardHasData(tt.shardID, tt.query); err != nil {
+			t.Errorf("%d. %s: unexpected error: %s", i, tt.query, err)
+		} else if ok != tt.exp {
+			t.Errorf("%d. %s: unexpected result: %v", i, tt.query, ok)
+		}
+	}
+
+	if _, err := s.ShardHasData(1, "SHOW MEASUREMENTS"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestStore_MaxOpenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Only one shard fits in the budget.
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.MaxOpenFiles = 6
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1"))
+	for _, id := range []uint64{1, 2} {
+		if err := s.CreateShard("foo", "default", id); err != nil {
+			t.Fatalf("error creating shard: %v", err)
+		} else if err := s.WriteToShard(id, p); err != nil {
+			t.Fatalf("error writing to shard: %v", err)
+		}
+	}
+
+	stats := tsdb.NewStatistics("tsdb", "files", nil)
+	if n := stats.Get("idleClosed"); n != 1 {
+		t.Fatalf("unexpected idle closed count: %d", n)
+	} else if n := stats.Get("openFiles"); n != 6 {
+		t.Fatalf("unexpected open files: %d", n)
+	}
+
+	// Shard 1 is reopened and shard 2 is closed in its place.
+	mapper := openRawMapperOrFail(t, s.Shard(1), mustParseSelectStatement("SELECT val FROM cpu"), 0)
+	if chunk, err := mapper.NextChunk(); err != nil {
+		t.Fatal(err)
+	} else if chunk == nil {
+		t.Fatal("expected points from reopened shard")
+	}
+	mapper.Close()
+	if n := stats.Get("reopened"); n != 1 {
+		t.Fatalf("unexpected reopened count: %d", n)
+	} else if n := stats.Get("idleClosed"); n != 2 {
+		t.Fatalf("unexpected idle closed count: %d", n)
+	}
+}
+
+// Ensure the query stats are kept across restarts to warm up the shards.
+func TestStore_WarmUp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.WarmUpSeries = 1
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+
+	p, _ := tsdb.ParsePoints([]byte("cpu,host=a val=1\ncpu,host=b val=2"))
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	} else if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	mapper := openRawMapperOrFail(t, s.Shard(1), mustParseSelectStatement("SELECT val FROM cpu WHERE host = 'b'"), 0)
+	mapper.Close()
+	if err := s.Close(); err != nil {
+		t.Fatalf("Store.Close() failed: %v", err)
+	}
+
+	s = tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.WarmUpSeries = 1
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	exp := []tsdb.SeriesQueryStat{{Database: "foo", Key: "cpu,host=b", Queries: 1}}
+	if got := s.QueryStats.Top("foo", 0); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected query stats: %+v", got)
+	}
+}
+
+func TestStore_IterateSeries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	p, _ := tsdb.ParsePoints([]byte("cpu,host=b value=3 20\ncpu,host=a value=1 10\ncpu,host=a value=2 20\nmem free=4i 10"))
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	} else if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	type tuple struct {
+		key    string
+		t      int64
+		fields map[string]interface{}
+	}
+	var got []tuple
+	if err := s.IterateSeries("foo", "default", 1, func(key string, t time.Time, fields map[string]interface{}) error {
+		got = append(got, tuple{key, t.UnixNano(), fields})
+
+		// Points written while iterating aren't seen.
+		if len(got) == 1 {
+			p, _ := tsdb.ParsePoints([]byte("cpu,host=a value=5 30"))
+			return s.WriteToShard(1, p)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []tuple{
+		{"cpu,host=a", 10, map[string]interface{}{"value": 1.0}},
+		{"cpu,host=a", 20, map[string]interface{}{"value": 2.0}},
+		{"cpu,host=b", 20, map[string]interface{}{"value": 3.0}},
+		{"mem", 10, map[string]interface{}{"free": int64(4)}},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected points:\n got %v\n exp %v", got, exp)
+	}
+
+	if err := s.IterateSeries("foo", "other", 1, nil); err != tsdb.ErrShardNotFound {
+		t.Fatalf("unexpected error for wrong retention policy: %v", err)
+	}
+}
+
 func BenchmarkStoreOpen_200KSeries_100Shards(b *testing.B) { benchmarkStoreOpen(b, 64, 5, 5, 1, 100) }
 
 func benchmarkStoreOpen(b *testing.B, mCnt, tkCnt, tvCnt, pntCnt, shardCnt int) {
@@ -285,7 +638,7 @@ func benchmarkStoreOpen(b *testing.B, mCnt, tkCnt, tvCnt, pntCnt, shardCnt int)
 	points := []tsdb.Point{}
 	for _, s := range series {
 		for val := 0.0; val < float64(pntCnt); val++ {
-			p := tsdb.NewPoint(s.Measurement, s.Series.Tags, map[string]interface{}{"value": val}, time.Now())
+			p := tsdb.NewPoint(s.Measurement, tsdb.NewTags(s.Series.Tags), map[string]interface{}{"value": val}, time.Now())
 			points = append(points, p)
 		}
 	}
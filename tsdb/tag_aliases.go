@@ -0,0 +1,82 @@
+package tsdb
+
+import (
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+)
+
+// tagAliases maps the deprecated tag keys and values that queries of a
+// database may still use to their current names.
+type tagAliases struct {
+	keys   map[string]string            // alias -> key
+	values map[string]map[string]string // key -> alias -> value
+	used   map[string]string            // key -> alias, for dimensions grouped by an alias
+}
+
+// newTagAliases returns the tag aliases of di. Returns nil if it has none.
+func newTagAliases(di *meta.DatabaseInfo) *tagAliases {
+	if len(di.TagAliases) == 0 {
+		return nil
+	}
+
+	a := &tagAliases{
+		keys:   make(map[string]string),
+		values: make(map[string]map[string]string),
+		used:   make(map[string]string),
+	}
+	for _, ta := range di.TagAliases {
+		if ta.Value == "" {
+			a.keys[ta.Alias] = ta.Key
+			continue
+		}
+		if a.values[ta.Key] == nil {
+			a.values[ta.Key] = make(map[string]string)
+		}
+		a.values[ta.Key][ta.Alias] = ta.Value
+	}
+	return a
+}
+
+// key returns the current name of a tag key used in a query.
+func (a *tagAliases) key(key string) string {
+	if k, ok := a.keys[key]; ok {
+		return k
+	}
+	return key
+}
+
+// rewriteStatement replaces the aliased tag keys and values in the condition
+// and dimensions of stmt with their current names.
+func (a *tagAliases) rewriteStatement(stmt *influxql.SelectStatement) {
+	if stmt.Condition != nil {
+		rewriteTagNames(stmt.Condition, a.key, func(key string) map[string]string { return a.values[key] })
+	}
+
+	// Rows are grouped by the current key but keep the alias the query
+	// asked for.
+	influxql.WalkFunc(stmt.Dimensions, func(n influxql.Node) {
+		if ref, ok := n.(*influxql.VarRef); ok {
+			if k, ok := a.keys[ref.Val]; ok {
+				a.used[k] = ref.Val
+				ref.Val = k
+			}
+		}
+	})
+}
+
+// renameRow replaces the current tag keys of a row that the query grouped by
+// an alias with the alias.
+func (a *tagAliases) renameRow(row *influxql.Row) {
+	if len(a.used) == 0 || len(row.Tags) == 0 {
+		return
+	}
+
+	tags := make(map[string]string, len(row.Tags))
+	for k, v := range row.Tags {
+		if alias, ok := a.used[k]; ok {
+			k = alias
+		}
+		tags[k] = v
+	}
+	row.Tags = tags
+}
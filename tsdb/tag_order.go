@@ -0,0 +1,83 @@
+package tsdb
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// numericLess compares tag values numerically when both are numbers, so "9"
+// sorts before "10". Numbers sort before other values, which are compared
+// as strings.
+func numericLess(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if x != y {
+			return x < y
+		}
+		return a < b
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
+
+// rowsByTags sorts rows by name and then by the values of the tag keys,
+// compared with numericLess.
+type rowsByTags struct {
+	rows []*influxql.Row
+	keys []string
+}
+
+func (a rowsByTags) Len() int      { return len(a.rows) }
+func (a rowsByTags) Swap(i, j int) { a.rows[i], a.rows[j] = a.rows[j], a.rows[i] }
+func (a rowsByTags) Less(i, j int) bool {
+	if a.rows[i].Name != a.rows[j].Name {
+		return a.rows[i].Name < a.rows[j].Name
+	}
+	for _, k := range a.keys {
+		x, y := a.rows[i].Tags[k], a.rows[j].Tags[k]
+		if x != y {
+			return numericLess(x, y)
+		}
+	}
+	return false
+}
+
+// numericStrings sorts strings with numericLess.
+type numericStrings []string
+
+func (a numericStrings) Len() int           { return len(a) }
+func (a numericStrings) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a numericStrings) Less(i, j int) bool { return numericLess(a[i], a[j]) }
+
+// sortRows returns the rows of ch sorted by the values of the tag keys. Every
+// row is read before the first one is returned. Errors are returned right away.
+func sortRows(ch <-chan *influxql.Row, keys []string) <-chan *influxql.Row {
+	out := make(chan *influxql.Row)
+	go func() {
+		defer close(out)
+
+		var rows []*influxql.Row
+		for row := range ch {
+			if row.Err != nil {
+				out <- row
+				for _ = range ch {
+				}
+				return
+			}
+			rows = append(rows, row)
+		}
+
+		sort.Stable(rowsByTags{rows: rows, keys: keys})
+		for _, row := range rows {
+			out <- row
+		}
+	}()
+	return out
+}
@@ -0,0 +1,195 @@
+package tsdb
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+)
+
+// tagRenames maps the renamed tag keys and values of measurements between the
+// names used by queries and the names their series are stored with. It's keyed
+// by the stored measurement name.
+type tagRenames map[string]*measurementTagRenames
+
+// measurementTagRenames holds the renamed tag keys and values of a measurement.
+type measurementTagRenames struct {
+	keys      map[string]string            // new key -> stored key
+	newKeys   map[string]string            // stored key -> new key
+	values    map[string]map[string]string // stored key -> new value -> stored value
+	newValues map[string]map[string]string // stored key -> stored value -> new value
+}
+
+// add adds the renamed tags of a stored measurement.
+func (a tagRenames) add(di *meta.DatabaseInfo, measurement string) {
+	m := &measurementTagRenames{
+		keys:      make(map[string]string),
+		newKeys:   make(map[string]string),
+		values:    make(map[string]map[string]string),
+		newValues: make(map[string]map[string]string),
+	}
+	for _, r := range di.TagRenames {
+		if r.Measurement != measurement {
+			continue
+		} else if r.Value == "" {
+			m.keys[r.NewName] = r.Key
+			m.newKeys[r.Key] = r.NewName
+			continue
+		}
+
+		if m.values[r.Key] == nil {
+			m.values[r.Key] = make(map[string]string)
+			m.newValues[r.Key] = make(map[string]string)
+		}
+		m.values[r.Key][r.NewName] = r.Value
+		m.newValues[r.Key][r.Value] = r.NewName
+	}
+	a[measurement] = m
+}
+
+// storedKey returns the stored key of a tag key used in a query.
+func (a tagRenames) storedKey(key string) string {
+	for _, m := range a {
+		if k, ok := m.keys[key]; ok {
+			return k
+		}
+	}
+	return key
+}
+
+// rewriteStatement replaces the tag keys and values in the condition and
+// dimensions of stmt with their stored names.
+func (a tagRenames) rewriteStatement(stmt *influxql.SelectStatement) {
+	if stmt.Condition != nil {
+		rewriteTagNames(stmt.Condition, a.storedKey, a.storedValues)
+	}
+	rewriteTagNames(stmt.Dimensions, a.storedKey, a.storedValues)
+}
+
+// storedValues returns the stored values of a key by the values used in queries.
+func (a tagRenames) storedValues(key string) map[string]string {
+	var values map[string]string
+	for _, m := range a {
+		for v, stored := range m.values[key] {
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[v] = stored
+		}
+	}
+	return values
+}
+
+// rewriteTagNames replaces the tag keys and values that a query refers to in
+// node with the names they're looked up by. key maps a tag key and values maps
+// the values of a mapped key. Values are replaced when compared to a string,
+// listed in IN or, for regular expressions, the expression is extended to
+// also match the values whose query names it matches.
+func rewriteTagNames(node influxql.Node, key func(string) string, values func(string) map[string]string) {
+	influxql.WalkFunc(node, func(n influxql.Node) {
+		switch n := n.(type) {
+		case *influxql.BinaryExpr:
+			ref, ok := n.LHS.(*influxql.VarRef)
+			other := n.RHS
+			if !ok {
+				if ref, ok = n.RHS.(*influxql.VarRef); !ok {
+					return
+				}
+				other = n.LHS
+			}
+			m := values(key(ref.Val))
+			if len(m) == 0 {
+				return
+			}
+
+			switch lit := other.(type) {
+			case *influxql.StringLiteral:
+				if n.Op == influxql.EQ || n.Op == influxql.NEQ {
+					if v, ok := m[lit.Val]; ok {
+						lit.Val = v
+					}
+				}
+			case *influxql.ListLiteral:
+				if n.Op == influxql.IN {
+					for i, s := range lit.Vals {
+						if v, ok := m[s]; ok {
+							lit.Vals[i] = v
+						}
+					}
+				}
+			case *influxql.RegexLiteral:
+				if n.Op == influxql.EQREGEX || n.Op == influxql.NEQREGEX {
+					lit.Val = extendRegex(lit.Val, m)
+				}
+			}
+		case *influxql.VarRef:
+			n.Val = key(n.Val)
+		}
+	})
+}
+
+// extendRegex returns re extended to also match the values of m whose keys
+// it matches. Returns re if none match.
+func extendRegex(re *regexp.Regexp, m map[string]string) *regexp.Regexp {
+	var alts []string
+	for name, v := range m {
+		if re.MatchString(name) {
+			alts = append(alts, regexp.QuoteMeta(v))
+		}
+	}
+	if len(alts) == 0 {
+		return re
+	}
+	sort.Strings(alts)
+	return regexp.MustCompile("(?:" + re.String() + ")|^(?:" + strings.Join(alts, "|") + ")$")
+}
+
+// renameRow replaces the stored tag keys and values in a row with their new
+// names. The row must be named after the stored measurement.
+func (a tagRenames) renameRow(row *influxql.Row) {
+	m := a[row.Name]
+	if m == nil {
+		return
+	}
+
+	if len(row.Tags) > 0 {
+		tags := make(map[string]string, len(row.Tags))
+		for k, v := range row.Tags {
+			tags[m.newKey(k)] = m.newValue(k, v)
+		}
+		row.Tags = tags
+	}
+
+	// Columns may be shared between rows so they're copied before renaming.
+	columns := make([]string, len(row.Columns))
+	for i, c := range row.Columns {
+		columns[i] = m.newKey(c)
+		if m.newValues[c] == nil {
+			continue
+		}
+		for _, values := range row.Values {
+			if s, ok := values[i].(string); ok {
+				values[i] = m.newValue(c, s)
+			}
+		}
+	}
+	row.Columns = columns
+}
+
+// newKey returns the new name of a stored tag key.
+func (m *measurementTagRenames) newKey(key string) string {
+	if k, ok := m.newKeys[key]; ok {
+		return k
+	}
+	return key
+}
+
+// newValue returns the new value of a stored tag value.
+func (m *measurementTagRenames) newValue(key, value string) string {
+	if v, ok := m.newValues[key][value]; ok {
+		return v
+	}
+	return value
+}
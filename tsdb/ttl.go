@@ -0,0 +1,17 @@
+package tsdb
+
+import "time"
+
+// ExpiresField is the integer field holding the time, in nanoseconds since the
+// epoch, after which a point is expired. Expired points are skipped by queries
+// before their shard is dropped by the retention policy, which gives points a
+// shorter lifetime than the rest of the policy. The field isn't returned by
+// wildcard queries.
+const ExpiresField = "_expires"
+
+// SetTTL sets the points to expire ttl after their timestamp.
+func SetTTL(points []Point, ttl time.Duration) {
+	for _, p := range points {
+		p.AddField(ExpiresField, p.Time().Add(ttl).UnixNano())
+	}
+}
@@ -0,0 +1,105 @@
+package tsdb
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ValidationConfig limits what the points of a write may contain, to protect
+// the store from abusive writers. Zero values disable each check.
+type ValidationConfig struct {
+	MaxTagsPerPoint   int  `toml:"max-tags-per-point"`
+	MaxFieldKeyLength int  `toml:"max-field-key-length"`
+	MaxLineLength     int  `toml:"max-line-length"`
+	RejectNaNInf      bool `toml:"reject-nan-inf"`
+
+	// MeasurementPattern is a regular expression measurement names must match,
+	// such as "^[a-zA-Z0-9_.-]+$".
+	MeasurementPattern string `toml:"measurement-pattern"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c ValidationConfig) Validate() error {
+	_, err := NewValidator(c)
+	return err
+}
+
+// ValidationError is returned for a point that breaks a rule of a ValidationConfig.
+type ValidationError struct {
+	Rule   string // The setting that rejected the point, such as "max-tags-per-point".
+	Reason string
+}
+
+func (e *ValidationError) Error() string { return e.Rule + ": " + e.Reason }
+
+// Validator checks parsed points against a ValidationConfig. A nil Validator
+// accepts every point.
+type Validator struct {
+	c           ValidationConfig
+	measurement *regexp.Regexp
+}
+
+// NewValidator returns a validator enforcing c.
+func NewValidator(c ValidationConfig) (*Validator, error) {
+	v := &Validator{c: c}
+	if c.MeasurementPattern != "" {
+		re, err := regexp.Compile(c.MeasurementPattern)
+		if err != nil {
+			return nil, fmt.Errorf("measurement-pattern: %s", err)
+		}
+		v.measurement = re
+	}
+	return v, nil
+}
+
+// ValidateLine returns an error if a line is too long to be parsed.
+func (v *Validator) ValidateLine(line []byte) error {
+	if v == nil || v.c.MaxLineLength <= 0 || len(line) <= v.c.MaxLineLength {
+		return nil
+	}
+	return &ValidationError{Rule: "max-line-length", Reason: fmt.Sprintf("line is %d bytes, limit is %d", len(line), v.c.MaxLineLength)}
+}
+
+// nonFinite returns the policy for NaN and infinite floats of parsed points:
+// policy, unless the validator rejects them under its reject-nan-inf rule.
+func (v *Validator) nonFinite(policy NonFinitePolicy) NonFinitePolicy {
+	if v != nil && v.c.RejectNaNInf {
+		return nonFiniteRejectRule
+	}
+	return policy
+}
+
+// Validate returns an error if the point breaks one of the rules of the config.
+func (v *Validator) Validate(p Point) error {
+	if v == nil {
+		return nil
+	}
+
+	if v.measurement != nil && !v.measurement.MatchString(p.Name()) {
+		return &ValidationError{Rule: "measurement-pattern", Reason: fmt.Sprintf("measurement %q doesn't match %s", p.Name(), v.c.MeasurementPattern)}
+	}
+
+	if v.c.MaxTagsPerPoint > 0 {
+		var n int
+		p.ForEachTag(func(_, _ []byte) bool { n++; return true })
+		if n > v.c.MaxTagsPerPoint {
+			return &ValidationError{Rule: "max-tags-per-point", Reason: fmt.Sprintf("point has %d tags, limit is %d", n, v.c.MaxTagsPerPoint)}
+		}
+	}
+
+	if v.c.MaxFieldKeyLength <= 0 && !v.c.RejectNaNInf {
+		return nil
+	}
+	it := p.FieldIterator()
+	for it.Next() {
+		if v.c.MaxFieldKeyLength > 0 && len(it.Name()) > v.c.MaxFieldKeyLength {
+			return &ValidationError{Rule: "max-field-key-length", Reason: fmt.Sprintf("field key %q is %d bytes, limit is %d", it.Name(), len(it.Name()), v.c.MaxFieldKeyLength)}
+		}
+		if f, ok := nonFiniteValue(&it); ok {
+			if _, _, err := v.nonFinite(NonFiniteKeep).finite(string(it.Name()), f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tsdb_test
+
+import (
+	"testing"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the parser rejects points that break the rules of a validator.
+func TestParsePointsWithOptions_Validator(t *testing.T) {
+	v, err := tsdb.NewValidator(tsdb.ValidationConfig{
+		MaxTagsPerPoint:    2,
+		MaxFieldKeyLength:  5,
+		MaxLineLength:      40,
+		RejectNaNInf:       true,
+		MeasurementPattern: "^[a-z]+$",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range []struct {
+		line string
+		rule string
+	}{
+		{line: `cpu,a=1,b=2 value=1`},
+		{line: `cpu,a=1,b=2,c=3 value=1`, rule: "max-tags-per-point"},
+		{line: `cpu values=1`, rule: "max-field-key-length"},
+		{line: `cpu,host=serverA,region=uswest value=1 1000000000`, rule: "max-line-length"},
+		{line: `cpu value=NaN`, rule: "reject-nan-inf"},
+		{line: `CPU value=1`, rule: "measurement-pattern"},
+	} {
+		_, err := tsdb.ParsePointsWithOptions([]byte(tt.line), tsdb.ParseOptions{Validator: v})
+		if tt.rule == "" {
+			if err != nil {
+				t.Errorf("%d. unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if verr, ok := err.(*tsdb.ValidationError); !ok {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		} else if verr.Rule != tt.rule {
+			t.Errorf("%d. unexpected rule: %s", i, verr.Rule)
+		}
+	}
+
+	// The reject-nan-inf rule overrides the policy of the parser.
+	_, err = tsdb.ParsePointsWithOptions([]byte(`cpu value=+Inf`), tsdb.ParseOptions{Validator: v, NonFinite: tsdb.NonFiniteDrop})
+	if verr, ok := err.(*tsdb.ValidationError); !ok || verr.Rule != "reject-nan-inf" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a config with an invalid measurement pattern is rejected.
+func TestValidationConfig_Validate(t *testing.T) {
+	if err := (tsdb.ValidationConfig{MeasurementPattern: "("}).Validate(); err == nil {
+		t.Fatal("expected error")
+	}
+}